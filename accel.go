@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/sys/cpu"
+)
+
+// cpuFeature - возможность процессора, от которой зависит скорость хэшей и KDF
+type cpuFeature struct {
+	Name    string `json:"name"`
+	Present bool   `json:"present"`
+	// Use - что в seedgen ускоряется этой возможностью
+	Use string `json:"use"`
+}
+
+// cpuFeatures перечисляет возможности процессора, которые стандартная
+// библиотека Go и x/crypto выбирают при запуске. Без них используются
+// переносимые реализации, которые в разы медленнее.
+func cpuFeatures() []cpuFeature {
+	switch runtime.GOARCH {
+	case "amd64":
+		return []cpuFeature{
+			{Name: "AVX2+BMI2", Present: cpu.X86.HasAVX2 && cpu.X86.HasBMI1 && cpu.X86.HasBMI2, Use: tr("SHA-512: PBKDF2, финальный хэш, HMAC")},
+			{Name: "SSE4.1", Present: cpu.X86.HasSSE41, Use: "Argon2id"},
+		}
+	case "arm64":
+		return []cpuFeature{
+			{Name: "SHA512", Present: cpu.ARM64.HasSHA512, Use: tr("SHA-512: PBKDF2, финальный хэш, HMAC")},
+			{Name: "SHA2", Present: cpu.ARM64.HasSHA2, Use: tr("только SHA-256 (коммитменты протокола)")},
+			{Name: "ASIMD (NEON)", Present: cpu.ARM64.HasASIMD, Use: tr("не используется: Argon2id на arm64 собран без ассемблера")},
+		}
+	}
+	return nil
+}
+
+// measureSHA512 измеряет скорость SHA-512 на блоках 1 МиБ в МБ/с
+func measureSHA512(minDuration time.Duration) float64 {
+	block := make([]byte, 1<<20)
+	res := measure(benchCase{run: func() { sha512.Sum512(block) }}, minDuration)
+	return float64(len(block)) * float64(res.ops) / res.elapsed.Seconds() / 1e6
+}
+
+// writeCPUFeatures выводит таблицу аппаратного ускорения
+func writeCPUFeatures(w io.Writer, features []cpuFeature) error {
+	if len(features) == 0 {
+		_, err := fmt.Fprintf(w, tr("Аппаратное ускорение для %s не определяется, используются переносимые реализации\n"), runtime.GOARCH)
+		return err
+	}
+	fmt.Fprintf(w, tr("Аппаратное ускорение (%s):\n"), runtime.GOARCH)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, f := range features {
+		present := tr("нет")
+		if f.Present {
+			present = tr("есть")
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", f.Name, present, f.Use)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// Домены схемы v3
+const (
+	v3Domain     = "seedgen/v3"
+	v3MaskDomain = "seedgen/v3/mask"
+)
+
+// seedContribution растягивает один сид схемы v3. В отличие от v2, где KDF
+// растягивает весь набор сразу, в v3 каждый вклад растягивается отдельно
+// на машине держателя, и сид не покидает ее.
+func seedContribution(seed []byte, p Params) []byte {
+	return seedContributionReport(seed, p, kdfProgress)
+}
+
+// seedContributionReport растягивает сид, как seedContribution, но сообщает
+// о ходе KDF в report: вклады вычисляются параллельно, и у каждого свой ход
+func seedContributionReport(seed []byte, p Params, report func(done float64)) []byte {
+	password := newSecret(len(v3Domain) + 4 + len(seed))
+	defer wipe(password)
+	n := copy(password, v3Domain)
+	binary.BigEndian.PutUint32(password[n:], uint32(len(seed)))
+	copy(password[n+4:], seed)
+
+	salt := []byte(fmt.Sprintf("%s/epoch/%d", p.Salt, p.Epoch))
+	var key []byte
+	if p.KDF == kdfArgon2id {
+		key = argon2IDKeyReport(password, salt, uint32(p.Iterations), p.Memory, p.Parallelism, report)
+	} else {
+		key = pbkdf2SHA512Report(password, salt, p.Iterations, report)
+	}
+	lockSecret(key)
+	return key
+}
+
+// xorInto добавляет src к dst по модулю 2
+func xorInto(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+// aggregateContributions возвращает сумму (XOR) вкладов сидов. Сумма не
+// зависит от порядка, а вклады разных держателей можно сложить, не собирая
+// сиды на одной машине. Одинаковые сиды взаимно уничтожились бы, поэтому
+// повтор - ошибка, как и в v2.
+func aggregateContributions(deviceSeeds [][]byte, p Params) ([]byte, error) {
+	if len(deviceSeeds) == 0 {
+		return nil, errorf("необходим хотя бы один сид устройства")
+	}
+	sorted := sortedSeedSet(deviceSeeds)
+	for i := 1; i < len(sorted); i++ {
+		if secretEqual(sorted[i], sorted[i-1]) {
+			return nil, errorf("один и тот же сид введен несколько раз")
+		}
+	}
+
+	// Вклады не зависят друг от друга и вычисляются параллельно. Полоса хода
+	// охватывает все вызовы KDF: ход церемонии - средний ход вкладов.
+	report := kdfProgress
+	contributions := make([][]byte, len(sorted))
+	progress := make([]float64, len(sorted))
+	var mu sync.Mutex
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < contributionWorkers(len(sorted), p); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var step func(done float64)
+				if report != nil {
+					i := i
+					step = func(done float64) {
+						mu.Lock()
+						defer mu.Unlock()
+						progress[i] = done
+						total := 0.0
+						for _, d := range progress {
+							total += d
+						}
+						report(total / float64(len(progress)))
+					}
+				}
+				contributions[i] = seedContributionReport(sorted[i], p, step)
+			}
+		}()
+	}
+	for i := range sorted {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sum := newSecret(sha512.Size)
+	for _, c := range contributions {
+		xorInto(sum, c)
+		wipe(c)
+	}
+	return sum, nil
+}
+
+// contributionWorkers возвращает, сколько вкладов вычислять одновременно:
+// не больше GOMAXPROCS, а для Argon2id - не больше ядер на его потоки и
+// копий его памяти, умещающихся в свободной. Сборка lowmem рассчитана на
+// один вызов Argon2id и считает вклады по очереди.
+func contributionWorkers(seeds int, p Params) int {
+	workers := runtime.GOMAXPROCS(0)
+	if p.KDF == kdfArgon2id {
+		if lowMemoryBuild {
+			return 1
+		}
+		if p.Parallelism > 1 {
+			workers /= int(p.Parallelism)
+		}
+		if avail, ok := availableMemory(); ok && p.Memory > 0 {
+			if fit := int(avail / (uint64(p.Memory) * 1024)); fit < workers {
+				workers = fit
+			}
+		}
+	}
+	if workers > seeds {
+		workers = seeds
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
+}
+
+// maskKeyPair - одноразовая пара X25519 участника для попарных масок
+type maskKeyPair struct {
+	private []byte
+	public  string
+}
+
+// newMaskKeyPair создает одноразовую пару ключей маскирования
+func newMaskKeyPair() (*maskKeyPair, error) {
+	priv := newSecret(curve25519.ScalarSize)
+	if _, err := rand.Read(priv); err != nil {
+		wipe(priv)
+		return nil, withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		wipe(priv)
+		return nil, err
+	}
+	return &maskKeyPair{private: priv, public: hex.EncodeToString(pub)}, nil
+}
+
+// mask возвращает сумму попарных масок участника со всеми остальными.
+// Маска пары выводится из общего секрета X25519, поэтому у обоих участников
+// пары она одинакова и в общей сумме сокращается: координатор узнает сумму
+// вкладов, но не отдельный вклад. Все ключи раунда входят в вывод маски,
+// чтобы маски разных церемоний не совпадали.
+func (k *maskKeyPair) mask(keys []string) ([]byte, error) {
+	round := append([]string(nil), keys...)
+	sort.Strings(round)
+	mask := newSecret(sha512.Size)
+	self := 0
+	for _, key := range keys {
+		if key == k.public {
+			self++
+			continue
+		}
+		shared, err := k.shared(key)
+		if err != nil {
+			wipe(mask)
+			return nil, err
+		}
+		mac := hmac.New(sha512.New, shared)
+		wipe(shared)
+		mac.Write([]byte(v3MaskDomain))
+		for _, r := range round {
+			mac.Write([]byte(r))
+		}
+		pairMask := mac.Sum(nil)
+		xorInto(mask, pairMask)
+		wipe(pairMask)
+	}
+	if self != 1 {
+		wipe(mask)
+		return nil, errorf("наш ключ маскирования встречается в раунде %d раз вместо одного", self)
+	}
+	return mask, nil
+}
+
+// shared возвращает общий секрет X25519 с другим участником
+func (k *maskKeyPair) shared(key string) ([]byte, error) {
+	pub, err := hex.DecodeString(key)
+	if err != nil || len(pub) != curve25519.PointSize {
+		return nil, errorf("некорректный ключ маскирования %q", key)
+	}
+	shared, err := curve25519.X25519(k.private, pub)
+	if err != nil {
+		return nil, errorf("ключ маскирования %s недопустим: %w", key, err)
+	}
+	return shared, nil
+}
+
+// wipe затирает закрытый ключ маскирования
+func (k *maskKeyPair) wipe() {
+	wipe(k.private)
+}
+
+// uniqueStrings сообщает, что в списке нет повторов
+func uniqueStrings(list []string) bool {
+	sorted := append([]string(nil), list...)
+	sort.Strings(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// networkExposure перечисляет признаки подключения машины к сети: интерфейсы
+// с адресами, маршрут по умолчанию и включенные радиомодули
+func networkExposure() []string {
+	var signs []string
+	if ifaces := connectedInterfaces(); len(ifaces) > 0 {
+		signs = append(signs, tr("интерфейсы с адресами: ")+strings.Join(ifaces, ", "))
+	}
+	if route := defaultRoute(); route != "" {
+		signs = append(signs, tr("маршрут по умолчанию через ")+route)
+	}
+	if radios := enabledRadios(); len(radios) > 0 {
+		signs = append(signs, tr("включены радиомодули: ")+strings.Join(radios, ", "))
+	}
+	return signs
+}
+
+// connectedInterfaces возвращает поднятые интерфейсы, кроме петлевого,
+// с адресами вне link-local
+func connectedInterfaces() []string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var found []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		var ips []string
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.IsGlobalUnicast() {
+				ips = append(ips, ipnet.IP.String())
+			}
+		}
+		if len(ips) > 0 {
+			found = append(found, iface.Name+" ("+strings.Join(ips, ", ")+")")
+		}
+	}
+	return found
+}
+
+// darwinRouteInterface - интерфейс в выводе route -n get default
+var darwinRouteInterface = regexp.MustCompile(`interface:\s*(\S+)`)
+
+// defaultRoute возвращает интерфейс маршрута по умолчанию или пустую строку
+func defaultRoute() string {
+	switch runtime.GOOS {
+	case "linux":
+		if data, err := os.ReadFile("/proc/net/route"); err == nil {
+			for _, line := range strings.Split(string(data), "\n")[1:] {
+				fields := strings.Fields(line)
+				if len(fields) < 4 || fields[1] != "00000000" {
+					continue
+				}
+				// Флаг RTF_UP
+				if flags, err := strconv.ParseUint(fields[3], 16, 16); err == nil && flags&1 != 0 {
+					return fields[0]
+				}
+			}
+		}
+		if data, err := os.ReadFile("/proc/net/ipv6_route"); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) == 10 && fields[0] == strings.Repeat("0", 32) && fields[1] == "00" && fields[9] != "lo" {
+					return fields[9] + " (IPv6)"
+				}
+			}
+		}
+	case "darwin":
+		out, err := exec.Command("route", "-n", "get", "default").Output()
+		if err == nil {
+			if m := darwinRouteInterface.FindSubmatch(out); m != nil {
+				return string(m[1])
+			}
+		}
+	case "windows":
+		out, err := exec.Command("route", "print", "-4", "0.0.0.0").Output()
+		if err == nil {
+			for _, line := range strings.Split(string(out), "\n") {
+				if fields := strings.Fields(line); len(fields) >= 4 && fields[0] == "0.0.0.0" && fields[1] == "0.0.0.0" {
+					return tr("шлюз ") + fields[2]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// enabledRadios возвращает включенные радиомодули Wi-Fi и Bluetooth.
+// В Windows радиомодули без адреса не проверяются.
+func enabledRadios() []string {
+	var radios []string
+	switch runtime.GOOS {
+	case "linux":
+		dirs, _ := filepath.Glob("/sys/class/rfkill/rfkill*")
+		for _, dir := range dirs {
+			read := func(name string) string {
+				data, _ := os.ReadFile(filepath.Join(dir, name))
+				return strings.TrimSpace(string(data))
+			}
+			if read("soft") == "0" && read("hard") == "0" {
+				radios = append(radios, read("type")+" ("+read("name")+")")
+			}
+		}
+	case "darwin":
+		out, _ := exec.Command("networksetup", "-listallhardwareports").Output()
+		lines := strings.Split(string(out), "\n")
+		for i, line := range lines {
+			if strings.TrimSpace(line) != "Hardware Port: Wi-Fi" || i+1 >= len(lines) {
+				continue
+			}
+			device := strings.TrimSpace(strings.TrimPrefix(lines[i+1], "Device:"))
+			power, _ := exec.Command("networksetup", "-getairportpower", device).Output()
+			if strings.HasSuffix(strings.TrimSpace(string(power)), "On") {
+				radios = append(radios, "Wi-Fi ("+device+")")
+			}
+		}
+		state, _ := exec.Command("defaults", "read", "/Library/Preferences/com.apple.Bluetooth", "ControllerPowerState").Output()
+		if strings.TrimSpace(string(state)) == "1" {
+			radios = append(radios, "Bluetooth")
+		}
+	}
+	return radios
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+)
+
+// msv2Prefix начинает однострочное представление артефакта:
+// msv2:<base64url(компактный JSON)>
+const msv2Prefix = "msv2:"
+
+// secretFields перечисляет поля артефактов, которые содержат секретные байты
+// и выводятся только с явного согласия оператора
+var secretFields = map[string]bool{
+	"master":          true,
+	"signing_share":   true,
+	"account_private": true,
+	"wif":             true,
+	"nsec":            true,
+	"private_key":     true,
+}
+
+// kindSecretFields перечисляет секретные поля с общими именами, которые
+// секретны только в артефактах своего типа
+var kindSecretFields = map[string]map[string]bool{
+	"handoff-session": {"key": true},
+}
+
+// isSecretField сообщает, что поле key артефакта типа kind секретно
+func isSecretField(kind, key string) bool {
+	return secretFields[key] || kindSecretFields[kind][key]
+}
+
+// encodeMSV2 кодирует артефакт в однострочную форму msv2
+func encodeMSV2(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return msv2Prefix + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeMSV2 возвращает JSON артефакта из строки msv2
+func decodeMSV2(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, msv2Prefix) {
+		return nil, errorf("строка не начинается с %q", msv2Prefix)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(s[len(msv2Prefix):]))
+	if err != nil {
+		return nil, errorf("некорректная строка msv2: %w", err)
+	}
+	return data, nil
+}
+
+// readArtifact читает артефакт из файла, stdin ("-") или строки msv2
+func readArtifact(ref string) ([]byte, error) {
+	if strings.HasPrefix(ref, msv2Prefix) {
+		return decodeMSV2(ref)
+	}
+
+	var data []byte
+	var err error
+	if ref == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Результат с --timelock расшифровывается после публикации раунда drand
+	if round, chain, ok := timelockStanza(data); ok {
+		if data, err = decryptTimelock(data, round, chain); err != nil {
+			return nil, err
+		}
+	}
+
+	// Файл может содержать как JSON, так и строку msv2
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte(msv2Prefix)) {
+		return decodeMSV2(string(trimmed))
+	}
+	return trimmed, nil
+}
+
+// artifactField - поле JSON-объекта с сохранением исходного порядка
+type artifactField struct {
+	key   string
+	value json.RawMessage
+}
+
+// orderedFields разбирает JSON-объект, сохраняя порядок полей
+func orderedFields(data []byte) ([]artifactField, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, errorf("ожидался JSON-объект")
+	}
+
+	var fields []artifactField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, errorf("некорректный ключ JSON")
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, artifactField{key: key, value: value})
+	}
+	return fields, nil
+}
@@ -0,0 +1,640 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// assignStepLimit ограничивает перебор с возвратом при назначении
+const assignStepLimit = 2000000
+
+// assignMatch - матч, которому назначаются судьи и площадка
+type assignMatch struct {
+	ID          string
+	Date        string
+	Home        string
+	Away        string
+	HomeCountry string
+	AwayCountry string
+	// Venue - площадка из файла матчей; тогда она не назначается
+	Venue string
+}
+
+// official - судья
+type official struct {
+	Name        string
+	Country     string
+	unavailable map[string]bool
+}
+
+// venueRule - площадка и ее доступность
+type venueRule struct {
+	Name string
+	// dates - даты, когда площадка доступна; nil - любые
+	dates     map[string]bool
+	maxPerDay int
+}
+
+// assignRules - правила назначения из TOML-файла
+type assignRules struct {
+	perMatch      int
+	maxPerDay     int
+	noSameCountry bool
+	venues        []venueRule
+}
+
+// assignRecord - назначение на матч с объяснением каждого выбора
+type assignRecord struct {
+	Match     string   `json:"match"`
+	Date      string   `json:"date"`
+	Home      string   `json:"home"`
+	Away      string   `json:"away"`
+	Venue     string   `json:"venue,omitempty"`
+	Officials []string `json:"officials"`
+	Trace     []string `json:"trace"`
+}
+
+// assignment - результат назначения
+type assignment struct {
+	Kind        string         `json:"kind"`
+	Label       string         `json:"label"`
+	Matches     string         `json:"matches_sha256"`
+	Officials   string         `json:"officials_sha256"`
+	Rules       string         `json:"rules_sha256"`
+	Fingerprint string         `json:"master_fingerprint"`
+	Assignments []assignRecord `json:"assignments"`
+}
+
+// parseDateSet разбирает даты "2025-06-01;2025-06-03..2025-06-05":
+// отдельные даты и включительные диапазоны через точку с запятой
+func parseDateSet(s string) (map[string]bool, error) {
+	dates := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to := part, part
+		if i := strings.Index(part, ".."); i >= 0 {
+			from, to = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+2:])
+		}
+		start, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, errorf("некорректная дата %q, ожидается ГГГГ-ММ-ДД", from)
+		}
+		end, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return nil, errorf("некорректная дата %q, ожидается ГГГГ-ММ-ДД", to)
+		}
+		if end.Before(start) {
+			return nil, errorf("диапазон %q заканчивается раньше, чем начинается", part)
+		}
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			dates[d.Format("2006-01-02")] = true
+		}
+	}
+	return dates, nil
+}
+
+// readHeaderCSV читает CSV с заголовком: столбцы required обязательны,
+// optional допустимы, остальные - ошибка. Пустые строки и строки с #
+// пропускаются. Возвращает строки как столбец -> значение, номера строк
+// файла и SHA-256 файла.
+func readHeaderCSV(path string, required, optional []string) ([]map[string]string, []int, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	known := make(map[string]bool)
+	for _, c := range append(append([]string(nil), required...), optional...) {
+		known[c] = true
+	}
+	var rows []map[string]string
+	var lines []int
+	var columns []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		r := csv.NewReader(strings.NewReader(text))
+		r.TrimLeadingSpace = true
+		rec, err := r.Read()
+		if err != nil {
+			return nil, nil, "", errorf("%s:%d: %w", path, line, err)
+		}
+		if columns == nil {
+			seen := make(map[string]bool)
+			for _, c := range rec {
+				c = strings.ToLower(strings.TrimSpace(c))
+				if !known[c] {
+					return nil, nil, "", errorf("%s:%d: неизвестный столбец %q, допустимы: %s", path, line, c, strings.Join(append(append([]string(nil), required...), optional...), ", "))
+				}
+				if seen[c] {
+					return nil, nil, "", errorf("%s:%d: столбец %q указан дважды", path, line, c)
+				}
+				seen[c] = true
+				columns = append(columns, c)
+			}
+			for _, c := range required {
+				if !seen[c] {
+					return nil, nil, "", errorf("%s:%d: в заголовке обязательны столбцы %s", path, line, strings.Join(required, ", "))
+				}
+			}
+			continue
+		}
+		if len(rec) != len(columns) {
+			return nil, nil, "", errorf("%s:%d: полей %d, а в заголовке %d", path, line, len(rec), len(columns))
+		}
+		row := make(map[string]string, len(columns))
+		for i, c := range columns {
+			row[c] = strings.TrimSpace(rec[i])
+		}
+		rows = append(rows, row)
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, "", err
+	}
+	if columns == nil {
+		return nil, nil, "", errorf("%s: нет строки заголовка (%s)", path, strings.Join(required, ","))
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	auditInput("file", path, digest)
+	return rows, lines, digest, nil
+}
+
+// readAssignMatches читает матчи: id,date,home,away и необязательные
+// home_country, away_country, venue; остальные столбцы fixtures.csv из
+// schedule export пропускаются. Матчи упорядочиваются по дате, в один
+// день - в порядке файла.
+func readAssignMatches(path string) ([]assignMatch, string, error) {
+	rows, lines, digest, err := readHeaderCSV(path, []string{"id", "date", "home", "away"}, []string{"home_country", "away_country", "venue", "kickoff", "timezone", "round", "short_id", "uuid"})
+	if err != nil {
+		return nil, "", err
+	}
+	var matches []assignMatch
+	seen := make(map[string]int)
+	for i, row := range rows {
+		m := assignMatch{ID: row["id"], Date: row["date"], Home: row["home"], Away: row["away"], HomeCountry: row["home_country"], AwayCountry: row["away_country"], Venue: row["venue"]}
+		if m.ID == "" || m.Home == "" || m.Away == "" {
+			return nil, "", errorf("%s:%d: обязательны id, home и away", path, lines[i])
+		}
+		if first, ok := seen[m.ID]; ok {
+			return nil, "", errorf("%s:%d: матч %q уже указан в строке %d", path, lines[i], m.ID, first)
+		}
+		seen[m.ID] = lines[i]
+		if _, err := time.Parse("2006-01-02", m.Date); err != nil {
+			return nil, "", errorf("%s:%d: некорректная дата %q, ожидается ГГГГ-ММ-ДД", path, lines[i], m.Date)
+		}
+		matches = append(matches, m)
+	}
+	if len(matches) == 0 {
+		return nil, "", errorf("%s: нет ни одного матча", path)
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Date < matches[j].Date })
+	return matches, digest, nil
+}
+
+// readOfficials читает судей: name и необязательные country, unavailable
+// (даты в формате parseDateSet). Судьи упорядочиваются по имени, чтобы
+// порядок строк файла не влиял на жребий.
+func readOfficials(path string) ([]official, string, error) {
+	rows, lines, digest, err := readHeaderCSV(path, []string{"name"}, []string{"country", "unavailable"})
+	if err != nil {
+		return nil, "", err
+	}
+	var officials []official
+	seen := make(map[string]int)
+	for i, row := range rows {
+		o := official{Name: row["name"], Country: row["country"]}
+		if o.Name == "" {
+			return nil, "", errorf("%s:%d: не указано имя судьи", path, lines[i])
+		}
+		if first, ok := seen[o.Name]; ok {
+			return nil, "", errorf("%s:%d: судья %q уже указан в строке %d", path, lines[i], o.Name, first)
+		}
+		seen[o.Name] = lines[i]
+		if o.unavailable, err = parseDateSet(row["unavailable"]); err != nil {
+			return nil, "", errorf("%s:%d: %w", path, lines[i], err)
+		}
+		officials = append(officials, o)
+	}
+	sort.Slice(officials, func(i, j int) bool { return officials[i].Name < officials[j].Name })
+	return officials, digest, nil
+}
+
+// readAssignRules читает правила: таблицу [officials] с per_match,
+// max_per_day и no_same_country и таблицы [venues.<имя>] с dates
+// и max_per_day
+func readAssignRules(path string) (assignRules, string, error) {
+	rules := assignRules{perMatch: 1, maxPerDay: 1}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, "", err
+	}
+	entries, err := parseTOML(bytes.NewReader(data))
+	if err != nil {
+		return rules, "", errorf("%s: %w", path, err)
+	}
+	venues := make(map[string]*venueRule)
+	for _, e := range entries {
+		var err error
+		switch {
+		case len(e.table) == 1 && e.table[0] == "officials":
+			switch e.key {
+			case "per_match":
+				rules.perMatch, err = strconv.Atoi(e.value)
+				if err == nil && rules.perMatch < 1 {
+					err = errorf("per_match должно быть не меньше 1")
+				}
+			case "max_per_day":
+				rules.maxPerDay, err = strconv.Atoi(e.value)
+				if err == nil && rules.maxPerDay < 1 {
+					err = errorf("max_per_day должно быть не меньше 1")
+				}
+			case "no_same_country":
+				rules.noSameCountry, err = strconv.ParseBool(e.value)
+			default:
+				err = errorf("неизвестное правило %q, допустимы: per_match, max_per_day, no_same_country", e.key)
+			}
+		case len(e.table) == 2 && e.table[0] == "venues":
+			v := venues[e.table[1]]
+			if v == nil {
+				v = &venueRule{Name: e.table[1], maxPerDay: 1}
+				venues[e.table[1]] = v
+			}
+			switch e.key {
+			case "dates":
+				v.dates, err = parseDateSet(e.value)
+			case "max_per_day":
+				v.maxPerDay, err = strconv.Atoi(e.value)
+				if err == nil && v.maxPerDay < 1 {
+					err = errorf("max_per_day должно быть не меньше 1")
+				}
+			default:
+				err = errorf("неизвестное правило площадки %q, допустимы: dates, max_per_day", e.key)
+			}
+		default:
+			err = errorf("правила задаются только в таблицах [officials] и [venues.<имя>]")
+		}
+		if err != nil {
+			return rules, "", errorf("%s: строка %d: %w", path, e.line, err)
+		}
+	}
+	for _, v := range venues {
+		rules.venues = append(rules.venues, *v)
+	}
+	sort.Slice(rules.venues, func(i, j int) bool { return rules.venues[i].Name < rules.venues[j].Name })
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	auditInput("file", path, digest)
+	return rules, digest, nil
+}
+
+// assigner расставляет судей и площадки перебором с возвратом. Порядок,
+// в котором перебираются кандидаты матча, задан жребием заранее, поэтому
+// потребление потока не зависит от хода перебора.
+type assigner struct {
+	matches   []assignMatch
+	officials []official
+	rules     assignRules
+	// officialOrder[i] и venueOrder[i] - порядок кандидатов матча i
+	officialOrder [][]int
+	venueOrder    [][]int
+	// venue[i] - индекс площадки матча i (-1 - не назначается),
+	// picked[i] - позиции выбранных судей в officialOrder[i]
+	venue     []int
+	picked    [][]int
+	officialN map[int]map[string]int
+	venueN    map[int]map[string]int
+	steps     int
+}
+
+// newAssigner берет из потока порядок кандидатов: для каждого матча по
+// порядку перестановку судей, затем, если площадку нужно назначить,
+// перестановку площадок (Фишер-Йетс над списками по имени)
+func newAssigner(stream *drawStream, matches []assignMatch, officials []official, rules assignRules) *assigner {
+	a := &assigner{
+		matches:   matches,
+		officials: officials,
+		rules:     rules,
+		venue:     make([]int, len(matches)),
+		picked:    make([][]int, len(matches)),
+		officialN: make(map[int]map[string]int),
+		venueN:    make(map[int]map[string]int),
+	}
+	for i, m := range matches {
+		order := make([]int, len(officials))
+		for j := range order {
+			order[j] = j
+		}
+		stream.shuffle(len(order), func(x, y int) { order[x], order[y] = order[y], order[x] })
+		a.officialOrder = append(a.officialOrder, order)
+		var venues []int
+		if a.needsVenue(m) {
+			for j := range rules.venues {
+				venues = append(venues, j)
+			}
+			stream.shuffle(len(venues), func(x, y int) { venues[x], venues[y] = venues[y], venues[x] })
+		}
+		a.venueOrder = append(a.venueOrder, venues)
+		a.venue[i] = -1
+	}
+	return a
+}
+
+// needsVenue сообщает, что площадку матча нужно назначить
+func (a *assigner) needsVenue(m assignMatch) bool {
+	return m.Venue == "" && len(a.rules.venues) > 0
+}
+
+// officialConflict возвращает причину, по которой судья не может вести
+// матч, или пустую строку
+func (a *assigner) officialConflict(o int, m assignMatch) string {
+	off := a.officials[o]
+	switch {
+	case off.unavailable[m.Date]:
+		return "недоступен в этот день"
+	case a.rules.noSameCountry && off.Country != "" && (off.Country == m.HomeCountry || off.Country == m.AwayCountry):
+		return "страна " + off.Country + " как у команды"
+	case a.officialN[o][m.Date] >= a.rules.maxPerDay:
+		return fmt.Sprintf("уже матчей в этот день: %d", a.officialN[o][m.Date])
+	}
+	return ""
+}
+
+// venueConflict возвращает причину, по которой площадка не подходит, или пустую строку
+func (a *assigner) venueConflict(v int, m assignMatch) string {
+	rule := a.rules.venues[v]
+	switch {
+	case rule.dates != nil && !rule.dates[m.Date]:
+		return "недоступна в этот день"
+	case a.venueN[v][m.Date] >= rule.maxPerDay:
+		return fmt.Sprintf("уже матчей в этот день: %d", a.venueN[v][m.Date])
+	}
+	return ""
+}
+
+// addCount учитывает (delta 1) или снимает (delta -1) назначение на дату
+func addCount(n map[int]map[string]int, i int, date string, delta int) {
+	if n[i] == nil {
+		n[i] = make(map[string]int)
+	}
+	n[i][date] += delta
+}
+
+// precheck находит матч, которому не хватает кандидатов даже без учета
+// других матчей, и объясняет, кто и почему не подходит
+func (a *assigner) precheck() error {
+	for _, m := range a.matches {
+		var fit int
+		var reasons []string
+		for o := range a.officials {
+			if c := a.officialConflict(o, m); c != "" {
+				reasons = append(reasons, a.officials[o].Name+" ("+c+")")
+				continue
+			}
+			fit++
+		}
+		if fit < a.rules.perMatch {
+			return errorf("матч %s (%s): подходящих судей %d, нужно %d; не подходят: %s", m.ID, m.Date, fit, a.rules.perMatch, strings.Join(reasons, ", "))
+		}
+		if !a.needsVenue(m) {
+			continue
+		}
+		fit, reasons = 0, nil
+		for v := range a.rules.venues {
+			if c := a.venueConflict(v, m); c != "" {
+				reasons = append(reasons, a.rules.venues[v].Name+" ("+c+")")
+				continue
+			}
+			fit++
+		}
+		if fit == 0 {
+			return errorf("матч %s (%s): нет доступной площадки: %s", m.ID, m.Date, strings.Join(reasons, ", "))
+		}
+	}
+	return nil
+}
+
+// solve назначает площадку матча i (slot -1) или судью на место slot.
+// Судьи одного матча берутся в порядке жребия: каждое следующее место -
+// из кандидатов после предыдущего выбранного.
+func (a *assigner) solve(i, slot int) (bool, error) {
+	if i == len(a.matches) {
+		return true, nil
+	}
+	if a.steps++; a.steps > assignStepLimit {
+		return false, errorf("перебор превысил %d шагов: ослабьте правила или добавьте судей и площадки", assignStepLimit)
+	}
+	m := a.matches[i]
+	if slot < 0 {
+		if !a.needsVenue(m) {
+			return a.solve(i, 0)
+		}
+		for _, v := range a.venueOrder[i] {
+			if a.venueConflict(v, m) != "" {
+				continue
+			}
+			a.venue[i] = v
+			addCount(a.venueN, v, m.Date, 1)
+			if ok, err := a.solve(i, 0); ok || err != nil {
+				return ok, err
+			}
+			addCount(a.venueN, v, m.Date, -1)
+		}
+		a.venue[i] = -1
+		return false, nil
+	}
+
+	start := 0
+	if slot > 0 {
+		start = a.picked[i][slot-1] + 1
+	}
+	for pos := start; pos < len(a.officialOrder[i]); pos++ {
+		o := a.officialOrder[i][pos]
+		if a.officialConflict(o, m) != "" {
+			continue
+		}
+		a.picked[i] = append(a.picked[i][:slot], pos)
+		addCount(a.officialN, o, m.Date, 1)
+		var ok bool
+		var err error
+		if slot+1 < a.rules.perMatch {
+			ok, err = a.solve(i, slot+1)
+		} else {
+			ok, err = a.solve(i+1, -1)
+		}
+		if ok || err != nil {
+			return ok, err
+		}
+		addCount(a.officialN, o, m.Date, -1)
+	}
+	a.picked[i] = a.picked[i][:slot]
+	return false, nil
+}
+
+// records повторяет найденное назначение по порядку матчей и объясняет
+// каждый выбор: какие кандидаты, стоявшие раньше по жребию, пропущены
+// и почему
+func (a *assigner) records() []assignRecord {
+	a.officialN = make(map[int]map[string]int)
+	a.venueN = make(map[int]map[string]int)
+	var records []assignRecord
+	for i, m := range a.matches {
+		r := assignRecord{Match: m.ID, Date: m.Date, Home: m.Home, Away: m.Away, Venue: m.Venue, Officials: []string{}, Trace: []string{}}
+		if v := a.venue[i]; v >= 0 {
+			r.Venue = a.rules.venues[v].Name
+			var skipped []string
+			for _, c := range a.venueOrder[i] {
+				if c == v {
+					break
+				}
+				skipped = append(skipped, a.rules.venues[c].Name+" ("+a.reason(a.venueConflict(c, m))+")")
+			}
+			r.Trace = append(r.Trace, traceLine("площадка "+r.Venue, len(skipped)+1, skipped))
+			addCount(a.venueN, v, m.Date, 1)
+		} else if m.Venue != "" {
+			r.Trace = append(r.Trace, "площадка "+m.Venue+": указана в файле матчей")
+		}
+		start := 0
+		for _, pos := range a.picked[i] {
+			o := a.officialOrder[i][pos]
+			var skipped []string
+			for _, c := range a.officialOrder[i][start:pos] {
+				skipped = append(skipped, a.officials[c].Name+" ("+a.reason(a.officialConflict(c, m))+")")
+			}
+			r.Officials = append(r.Officials, a.officials[o].Name)
+			r.Trace = append(r.Trace, traceLine("судья "+a.officials[o].Name, pos+1, skipped))
+			addCount(a.officialN, o, m.Date, 1)
+			start = pos + 1
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// reason объясняет пропуск кандидата без нарушений: с ним не расставить
+// следующие матчи
+func (a *assigner) reason(conflict string) string {
+	if conflict == "" {
+		return "тупик в следующих матчах"
+	}
+	return conflict
+}
+
+// traceLine описывает один выбор: позицию в порядке жребия и пропущенных
+func traceLine(choice string, pos int, skipped []string) string {
+	line := fmt.Sprintf("%s: номер %d по жребию", choice, pos)
+	if len(skipped) > 0 {
+		line += ", пропущены: " + strings.Join(skipped, ", ")
+	}
+	return line
+}
+
+// runAssign назначает судей и площадки на матчи из опубликованного мастер-сида
+func runAssign(args []string) error {
+	fs := newFlagSet("assign")
+	matchesPath := fs.String("matches", "", "CSV матчей с заголовком: id,date,home,away[,home_country,away_country,venue]")
+	officialsPath := fs.String("officials", "", "CSV судей с заголовком: name[,country,unavailable]")
+	rulesPath := fs.String("constraints", "", "правила назначения в TOML: [officials] и [venues.<имя>]")
+	label := fs.String("label", "assign", "метка назначения: разные метки дают независимые назначения из одного мастер-сида")
+	format := fs.String("format", "text", "формат вывода: text, csv или json")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *matchesPath == "" || *officialsPath == "" {
+		return errorf("укажите матчи через --matches и судей через --officials")
+	}
+	if *format != "text" && *format != "csv" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	matches, matchesDigest, err := readAssignMatches(*matchesPath)
+	if err != nil {
+		return err
+	}
+	officials, officialsDigest, err := readOfficials(*officialsPath)
+	if err != nil {
+		return err
+	}
+	rules := assignRules{perMatch: 1, maxPerDay: 1}
+	rulesDigest := ""
+	if *rulesPath != "" {
+		if rules, rulesDigest, err = readAssignRules(*rulesPath); err != nil {
+			return err
+		}
+	}
+	if len(officials) < rules.perMatch {
+		return errorf("на матч нужно судей: %d, а в списке %d", rules.perMatch, len(officials))
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	a := newAssigner(newDrawStream(master, "assign", *label), matches, officials, rules)
+	if err := a.precheck(); err != nil {
+		return err
+	}
+	ok, err := a.solve(0, -1)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errorf("каждому матчу по отдельности кандидатов хватает, но вместе при этих правилах назначить судей и площадки на все матчи нельзя: проверьте max_per_day")
+	}
+	result := assignment{
+		Kind:        "assignment",
+		Label:       *label,
+		Matches:     matchesDigest,
+		Officials:   officialsDigest,
+		Rules:       rulesDigest,
+		Fingerprint: masterFingerprint(master),
+		Assignments: a.records(),
+	}
+
+	switch *format {
+	case "json":
+		return writeOutput(os.Stdout, result)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"match", "date", "home", "away", "venue", "officials"})
+		for _, r := range result.Assignments {
+			w.Write([]string{r.Match, r.Date, r.Home, r.Away, r.Venue, strings.Join(r.Officials, ";")})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	fmt.Printf(tr("Назначение %q: матчей %d, судей %d, площадок %d\n"), result.Label, len(matches), len(officials), len(rules.venues))
+	fmt.Printf(tr("Мастер-сид: %s..., матчи: SHA-256 %s, судьи: SHA-256 %s\n"), emph(result.Fingerprint), result.Matches, result.Officials)
+	if result.Rules != "" {
+		fmt.Printf(tr("Правила: SHA-256 %s\n"), result.Rules)
+	}
+	for _, r := range result.Assignments {
+		fmt.Println()
+		line := fmt.Sprintf("%s %s: %s - %s", r.Date, r.Match, r.Home, r.Away)
+		if r.Venue != "" {
+			line += ", " + r.Venue
+		}
+		fmt.Printf(tr("%s; судьи: %s\n"), line, strings.Join(r.Officials, ", "))
+		for _, t := range r.Trace {
+			fmt.Printf("  %s\n", t)
+		}
+	}
+	return nil
+}
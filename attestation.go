@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// seedAttestation - подпись участника под обязательством по своему сиду.
+// Участник подписывает ее личным ключом Ed25519 заранее, на своей машине;
+// в протоколе церемонии она доказывает, кто внес какое обязательство.
+type seedAttestation struct {
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name"`
+	Commitment string    `json:"commitment"`
+	CreatedAt  time.Time `json:"created_at"`
+	PublicKey  string    `json:"public_key"`
+	Signature  string    `json:"signature,omitempty"`
+}
+
+// signedBytes возвращает подписываемое представление подписи участника
+func (a seedAttestation) signedBytes() ([]byte, error) {
+	a.Signature = ""
+	return json.Marshal(a)
+}
+
+// sign подписывает обязательство ключом участника
+func (a *seedAttestation) sign(key ed25519.PrivateKey) error {
+	a.PublicKey = hex.EncodeToString(key.Public().(ed25519.PublicKey))
+	msg, err := a.signedBytes()
+	if err != nil {
+		return err
+	}
+	a.Signature = hex.EncodeToString(ed25519.Sign(key, msg))
+	return nil
+}
+
+// verify проверяет подпись участника и возвращает его ключ
+func (a seedAttestation) verify() (ed25519.PublicKey, error) {
+	pub, err := hex.DecodeString(a.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, errorf("некорректный открытый ключ в подписи участника %q", a.Name)
+	}
+	sig, err := hex.DecodeString(a.Signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, mismatchf("подпись участника %q отсутствует или повреждена", a.Name)
+	}
+	msg, err := a.signedBytes()
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(pub, msg, sig) {
+		return nil, mismatchf("подпись участника %q недействительна", a.Name)
+	}
+	return pub, nil
+}
+
+// describe возвращает имя участника и отпечаток его ключа
+func (a seedAttestation) describe() string {
+	pub, _ := hex.DecodeString(a.PublicKey)
+	return fmt.Sprintf("%s (%s)", a.Name, keyFingerprint(pub))
+}
+
+// loadAttestations читает и проверяет подписи участников, сопоставляя их
+// обязательствам
+func loadAttestations(paths []string) (map[string]*seedAttestation, error) {
+	byCommitment := make(map[string]*seedAttestation, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		a := &seedAttestation{}
+		if err := json.Unmarshal(data, a); err != nil {
+			return nil, errorf("не удалось разобрать %s: %w", path, err)
+		}
+		if a.Kind != "seed-attestation" {
+			return nil, errorf("%s не является подписью участника (тип %q)", path, a.Kind)
+		}
+		if _, err := a.verify(); err != nil {
+			return nil, errorf("%s: %w", path, err)
+		}
+		if prev, ok := byCommitment[a.Commitment]; ok {
+			return nil, errorf("%s: обязательство уже подписано участником %s", path, prev.describe())
+		}
+		byCommitment[a.Commitment] = a
+	}
+	return byCommitment, nil
+}
+
+// runAuditAttest подписывает обязательство по сиду участника его личным
+// ключом. Сид вводится на машине участника и никуда не сохраняется.
+func runAuditAttest(args []string) error {
+	fs := newFlagSet("audit attest")
+	keyPath := fs.String("key", "", "личный закрытый ключ участника (PEM, audit keygen)")
+	name := fs.String("name", "", "имя участника")
+	out := fs.String("out", "", "файл подписи участника")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *keyPath == "" || *name == "" || *out == "" {
+		return errorf("укажите --key, --name и --out")
+	}
+	key, err := loadSigningKey(*keyPath)
+	if err != nil {
+		return errorf("ошибка чтения ключа: %w", err)
+	}
+
+	fmt.Println(tr("Введите сид своего устройства, затем пустую строку."))
+	seeds, err := readDeviceSeeds(os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer wipeSeeds(seeds)
+	if len(seeds) != 1 {
+		return errorf("введите ровно один сид, получено: %d", len(seeds))
+	}
+
+	a := &seedAttestation{
+		Kind:       "seed-attestation",
+		Name:       *name,
+		Commitment: seedCommitment(seeds[0]),
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+	if err := a.sign(key); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(*out, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Printf(tr("Обязательство: %s\n"), a.Commitment)
+	fmt.Printf(tr("✓ Подпись участника %s сохранена: %s\n"), a.describe(), *out)
+	return nil
+}
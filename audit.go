@@ -0,0 +1,451 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// seedCommitmentDomain отделяет обязательства по сидам от других хэшей
+const seedCommitmentDomain = "seedgen/seed-commitment/v1"
+
+// seedCommitment возвращает обязательство по сиду: по нему можно проверить,
+// что предъявленный позже сид тот же, но нельзя восстановить сам сид
+func seedCommitment(seed []byte) string {
+	h := sha256.New()
+	h.Write([]byte(seedCommitmentDomain))
+	h.Write([]byte{0})
+	h.Write(seed)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// stringList - флаг, который можно указать несколько раз
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// transcriptEvent - одно событие протокола церемонии. Сырые сиды
+// и мастер-сид в протокол не попадают никогда.
+type transcriptEvent struct {
+	Time        time.Time        `json:"time"`
+	Event       string           `json:"event"`
+	Index       int              `json:"index,omitempty"`
+	Participant string           `json:"participant,omitempty"`
+	Commitment  string           `json:"commitment,omitempty"`
+	SeedCount   int              `json:"seed_count,omitempty"`
+	Fingerprint string           `json:"fingerprint,omitempty"`
+	Root        string           `json:"merkle_root,omitempty"`
+	Attestation *seedAttestation `json:"attestation,omitempty"`
+	Note        string           `json:"note,omitempty"`
+}
+
+// transcript - подписанный протокол церемонии для архива
+type transcript struct {
+	Kind      string            `json:"kind"`
+	Version   string            `json:"version"`
+	Commit    string            `json:"commit"`
+	Platform  string            `json:"platform"`
+	Operators []string          `json:"operators"`
+	Params    Params            `json:"params"`
+	Events    []transcriptEvent `json:"events"`
+	PublicKey string            `json:"public_key"`
+	Signature string            `json:"signature,omitempty"`
+
+	// Cosignatures - подписи участников, поставленные по очереди после
+	// подписи ключом церемонии (cosign.go)
+	Cosignatures []transcriptCosignature `json:"cosignatures,omitempty"`
+}
+
+// record добавляет событие с текущим временем
+func (t *transcript) record(e transcriptEvent) {
+	e.Time = time.Now().UTC()
+	t.Events = append(t.Events, e)
+}
+
+// signedBytes возвращает подписываемое представление протокола (без подписи
+// и подписей участников)
+func (t transcript) signedBytes() ([]byte, error) {
+	t.Signature = ""
+	t.Cosignatures = nil
+	return json.Marshal(t)
+}
+
+// sign подписывает протокол ключом церемонии
+func (t *transcript) sign(key ed25519.PrivateKey) error {
+	t.PublicKey = hex.EncodeToString(key.Public().(ed25519.PublicKey))
+	msg, err := t.signedBytes()
+	if err != nil {
+		return err
+	}
+	t.Signature = hex.EncodeToString(ed25519.Sign(key, msg))
+	return nil
+}
+
+// merkleRoot возвращает корень дерева обязательств по сидам из протокола
+func (t transcript) merkleRoot() string {
+	for _, e := range t.Events {
+		if e.Event == "seeds-complete" {
+			return e.Root
+		}
+	}
+	return ""
+}
+
+// verify проверяет подпись протокола и возвращает ключ, которым он подписан
+func (t transcript) verify() (ed25519.PublicKey, error) {
+	pub, err := hex.DecodeString(t.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, errorf("некорректный открытый ключ в протоколе")
+	}
+	sig, err := hex.DecodeString(t.Signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, mismatchf("протокол не подписан или подпись повреждена")
+	}
+	msg, err := t.signedBytes()
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(pub, msg, sig) {
+		return nil, mismatchf("подпись протокола недействительна, протокол изменен после подписания")
+	}
+	if err := t.verifyCosignatures(pub); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// runAudit проводит церемонию с подписанным протоколом
+func runAudit(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, tr("Использование:"))
+		fmt.Fprintln(os.Stderr, "  seedgen audit keygen --key ceremony.key")
+		fmt.Fprintln(os.Stderr, tr("  seedgen audit attest --key alice.key --name ИМЯ --out alice-attestation.json"))
+		fmt.Fprintln(os.Stderr, tr("  seedgen audit run --key ceremony.key --out transcript.json --operator ИМЯ [флаги схемы]"))
+		fmt.Fprintln(os.Stderr, tr("  seedgen audit cosign --key alice.key --name ИМЯ transcript.json"))
+		fmt.Fprintln(os.Stderr, "  seedgen audit verify transcript.json [--pubkey ceremony.key.pub] [--cosigner alice.key.pub]")
+		fmt.Fprintln(os.Stderr, "  seedgen audit proof proofs/seed-1.json [--transcript transcript.json] [--seed]")
+		fmt.Fprintln(os.Stderr, "  seedgen audit log [--file seedgen-audit.jsonl]")
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return errorf("укажите действие: keygen, attest, run, cosign, verify, proof или log")
+	}
+
+	switch args[0] {
+	case "keygen":
+		return runAuditKeygen(args[1:])
+	case "attest":
+		return runAuditAttest(args[1:])
+	case "run":
+		return runAuditRun(args[1:])
+	case "cosign":
+		return runAuditCosign(args[1:])
+	case "verify":
+		return runAuditVerify(args[1:])
+	case "proof":
+		return runAuditProof(args[1:])
+	case "log":
+		return runAuditLog(args[1:])
+	}
+	return errorf("неизвестное действие %q, доступны: keygen, attest, run, cosign, verify, proof, log", args[0])
+}
+
+// runAuditKeygen создает ключ церемонии
+func runAuditKeygen(args []string) error {
+	fs := newFlagSet("audit keygen")
+	keyPath := fs.String("key", "", "файл закрытого ключа (открытый сохраняется рядом с суффиксом .pub)")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *keyPath == "" {
+		return errorf("укажите файл ключа через --key")
+	}
+
+	pub, err := generateSigningKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(tr("✓ Закрытый ключ: %s\n"), *keyPath)
+	fmt.Printf(tr("✓ Открытый ключ: %s.pub\n"), *keyPath)
+	fmt.Printf("  %s\n", hex.EncodeToString(pub))
+	fmt.Printf(tr("  Отпечаток: %s\n"), emph(keyFingerprint(pub)))
+	return nil
+}
+
+// runAuditRun проводит церемонию и сохраняет подписанный протокол
+func runAuditRun(args []string) error {
+	fs := newFlagSet("audit run")
+	keyPath := fs.String("key", "", "закрытый ключ церемонии (PEM)")
+	out := fs.String("out", "", "файл протокола (по умолчанию - новый файл в каталоге протоколов, см. seedgen config path)")
+	seedCheck := fs.Bool("seed-check", false, "сиды вводятся с контрольным суффиксом \"-XXXX\" (newseed --check), суффикс проверяется сразу")
+	maskSeeds := fs.Bool("mask-seeds", false, "показывать при вводе сидов звездочки вместо символов")
+	strictInput := fs.Bool("strict-input", false, "отклонять сиды с невидимыми символами, табуляциями, пробелами по краям и смешением алфавитов вместо исправления")
+	proofsDir := fs.String("proofs", "", "сохранить в каталог доказательства включения для каждого сида (seed-N.json)")
+	var operators stringList
+	fs.Var(&operators, "operator", "имя оператора (флаг можно указать несколько раз)")
+	var attestationPaths stringList
+	fs.Var(&attestationPaths, "attestation", "подпись участника под обязательством по сиду (audit attest), флаг можно указать несколько раз")
+	sf := addSchemeFlags(fs)
+	rf := addRevealFlags(fs)
+	ef := addEnvFlags(fs)
+	pf := addProfileFlags(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pf.apply(fs); err != nil {
+		return err
+	}
+	if *keyPath == "" {
+		return errorf("укажите --key")
+	}
+	if len(operators) == 0 {
+		return errorf("укажите хотя бы одного оператора через --operator")
+	}
+	params, err := sf.params(fs)
+	if err != nil {
+		return err
+	}
+	if sf.compatV1() && *strictInput {
+		return errorf("--strict-input не сочетается с --compat v1: прежние версии принимали сиды как есть")
+	}
+	if err := ef.check(false); err != nil {
+		return err
+	}
+
+	// Ключ и файл протокола проверяем до ввода сидов, чтобы не повторять ввод
+	key, err := loadSigningKey(*keyPath)
+	if err != nil {
+		return errorf("ошибка чтения ключа: %w", err)
+	}
+	attestations, err := loadAttestations(attestationPaths)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		dir, err := auditDir()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return errorf("ошибка создания каталога протоколов: %w", err)
+		}
+		*out = filepath.Join(dir, "transcript-"+time.Now().UTC().Format("20060102T150405Z")+".json")
+	}
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errorf("ошибка создания протокола: %w", err)
+	}
+	defer f.Close()
+	if *proofsDir != "" {
+		if err := os.MkdirAll(*proofsDir, 0755); err != nil {
+			return errorf("ошибка создания каталога доказательств: %w", err)
+		}
+	}
+
+	t := &transcript{
+		Kind:      "ceremony-transcript",
+		Version:   buildVersion(),
+		Commit:    commit,
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Operators: operators,
+		Params:    params,
+	}
+	t.record(transcriptEvent{Event: "ceremony-start"})
+
+	fmt.Println(tr("=== Церемония с протоколом ==="))
+	fmt.Println()
+	fmt.Printf(tr("Операторы: %s\n"), operators.String())
+	fmt.Printf(tr("Ключ протокола: %s\n\n"), keyFingerprint(key.Public().(ed25519.PublicKey)))
+	fmt.Println(tr("Введите сиды от устройств (по одному на строку)."))
+	fmt.Println(tr("Для завершения ввода оставьте строку пустой и нажмите Enter."))
+	fmt.Println()
+
+	var commitments []string
+	deviceSeeds, err := readDeviceSeedsFunc(os.Stdin, os.Stdout, seedInput{checked: *seedCheck, masked: *maskSeeds, strict: *strictInput, raw: sf.compatV1()}, func(seed []byte) {
+		c := seedCommitment(seed)
+		commitments = append(commitments, c)
+		t.record(transcriptEvent{Event: "seed-received", Index: len(commitments), Commitment: c, Attestation: attestations[c]})
+	})
+	if err == nil && len(deviceSeeds) == 0 {
+		err = errorf("не введено ни одного сида")
+	}
+	if err == nil && len(attestations) > 0 {
+		err = checkAttestations(attestations, commitments)
+	}
+	defer wipeSeeds(deviceSeeds)
+
+	var masterSeed []byte
+	var tree *seedMerkleTree
+	if err == nil {
+		// Корень дерева попадает в подписанный протокол, доказательства - держателям сидов
+		tree = newSeedMerkleTree(commitments)
+		t.record(transcriptEvent{Event: "seeds-complete", SeedCount: len(deviceSeeds), Root: tree.root()})
+		stopProgress := showKDFProgress()
+		masterSeed, err = GenerateMasterSeed(deviceSeeds, params)
+		stopProgress()
+	}
+	defer wipe(masterSeed)
+	if err == nil {
+		t.record(transcriptEvent{Event: "master-derived", SeedCount: len(deviceSeeds), Fingerprint: masterFingerprint(masterSeed)})
+	} else {
+		// Неудачная церемония тоже попадает в архив
+		t.record(transcriptEvent{Event: "ceremony-failed", Note: err.Error()})
+	}
+	t.record(transcriptEvent{Event: "ceremony-end"})
+
+	if signErr := t.sign(key); signErr != nil {
+		return signErr
+	}
+	if writeErr := writeJSON(f, t); writeErr != nil {
+		return errorf("ошибка записи протокола: %w", writeErr)
+	}
+	if closeErr := f.Close(); closeErr != nil {
+		return errorf("ошибка записи протокола: %w", closeErr)
+	}
+	if err != nil {
+		fmt.Printf(tr("\n✓ Протокол неудачной церемонии сохранен: %s\n"), *out)
+		return err
+	}
+
+	fmt.Printf(tr("\n✓ Получено сидов: %d\n\n"), len(deviceSeeds))
+	if err := rf.reveal(tr("Мастер-сид (детерминированный):"), masterSeed); err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Printf(tr("SHA-512 хеш: %s...\n"), emph(masterFingerprint(masterSeed)))
+	fmt.Printf(tr("✓ Подписанный протокол сохранен: %s\n"), *out)
+	if *proofsDir != "" {
+		if err := writeInclusionProofs(*proofsDir, tree, commitments, masterFingerprint(masterSeed)); err != nil {
+			return err
+		}
+		fmt.Printf(tr("✓ Доказательства включения сидов сохранены: %s (корень %s)\n"), *proofsDir, tree.root())
+	}
+	return nil
+}
+
+// runAuditVerify проверяет подпись протокола церемонии
+func runAuditVerify(args []string) error {
+	fs := newFlagSet("audit verify")
+	pubkey := fs.String("pubkey", "", "ожидаемый открытый ключ церемонии: hex или PEM-файл")
+	var cosigners stringList
+	fs.Var(&cosigners, "cosigner", "открытый ключ участника (hex или PEM-файл), чья подпись под протоколом обязательна; флаг можно указать несколько раз")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errorf("укажите файл протокола")
+	}
+
+	t, err := readTranscript(positional[0], *pubkey)
+	if err != nil {
+		return err
+	}
+	pub, err := t.verify()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(tr("=== Протокол: %s ===\n\n"), positional[0])
+	fmt.Printf(tr("Версия: %s (коммит %s), %s\n"), t.Version, t.Commit, t.Platform)
+	fmt.Printf(tr("Операторы: %s\n"), strings.Join(t.Operators, ", "))
+	fmt.Println(describeParams(t.Params))
+	fmt.Println()
+	for _, e := range t.Events {
+		line := fmt.Sprintf("%s  %s", e.Time.Format(time.RFC3339), e.Event)
+		switch {
+		case e.Commitment != "":
+			line += fmt.Sprintf(" #%d %s", e.Index, e.Commitment)
+			if e.Participant != "" {
+				line += tr(" от ") + e.Participant
+			}
+			if a := e.Attestation; a != nil {
+				if _, err := a.verify(); err != nil {
+					return errorf("сид #%d: %w", e.Index, err)
+				}
+				if a.Commitment != e.Commitment {
+					return mismatchf("сид #%d: подпись участника %s относится к другому обязательству", e.Index, a.describe())
+				}
+				line += tr(", подписано ") + a.describe()
+			}
+		case e.Root != "":
+			line += fmt.Sprintf(tr(" сидов: %d, корень %s"), e.SeedCount, e.Root)
+		case e.Fingerprint != "":
+			line += fmt.Sprintf(tr(" сидов: %d, отпечаток %s"), e.SeedCount, e.Fingerprint)
+		case e.Participant != "":
+			line += fmt.Sprintf(tr(" %s, сидов: %d"), e.Participant, e.SeedCount)
+		case e.SeedCount != 0:
+			line += fmt.Sprintf(tr(" сидов: %d"), e.SeedCount)
+		case e.Note != "":
+			line += " " + e.Note
+		}
+		fmt.Println(line)
+	}
+	fmt.Println()
+	fmt.Printf(tr("✓ Подпись действительна, ключ %s\n"), keyFingerprint(pub))
+	if *pubkey == "" {
+		fmt.Println(tr("  Сверьте отпечаток ключа с опубликованным или укажите --pubkey."))
+	}
+	return printCosignatures(t, cosigners)
+}
+
+// readTranscript читает протокол церемонии и проверяет его подпись,
+// а при заданном pubkey - и ключ, которым он подписан
+func readTranscript(path, pubkey string) (*transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, errorf("не удалось разобрать протокол: %w", err)
+	}
+	if t.Kind != "ceremony-transcript" {
+		return nil, errorf("файл не является протоколом церемонии (тип %q)", t.Kind)
+	}
+
+	pub, err := t.verify()
+	if err != nil {
+		return nil, err
+	}
+	if pubkey != "" {
+		want, err := parsePublicKey(pubkey)
+		if err != nil {
+			return nil, err
+		}
+		if !want.Equal(pub) {
+			return nil, mismatchf("протокол подписан другим ключом: %s", keyFingerprint(pub))
+		}
+	}
+	return &t, nil
+}
+
+// checkAttestations требует, чтобы при заданных подписях участников каждый
+// сид был подписан и каждая подпись относилась к введенному сиду
+func checkAttestations(attestations map[string]*seedAttestation, commitments []string) error {
+	entered := make(map[string]bool, len(commitments))
+	for i, c := range commitments {
+		if attestations[c] == nil {
+			return mismatchf("сид #%d не подтвержден подписью участника", i+1)
+		}
+		entered[c] = true
+	}
+	for c, a := range attestations {
+		if !entered[c] {
+			return mismatchf("подпись участника %s не соответствует ни одному введенному сиду", a.describe())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procLockFileEx   = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+	procUnlockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("UnlockFileEx")
+)
+
+// lockfileExclusiveLock - флаг LOCKFILE_EXCLUSIVE_LOCK функции LockFileEx
+const lockfileExclusiveLock = 0x2
+
+// lockAuditFile берет исключительную блокировку журнала аудита, ожидая,
+// пока ее снимет другой запуск
+func lockAuditFile(f *os.File) (func(), error) {
+	var overlapped syscall.Overlapped
+	handle := f.Fd()
+	if ok, _, err := procLockFileEx.Call(handle, lockfileExclusiveLock, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped))); ok == 0 {
+		return nil, err
+	}
+	return func() {
+		procUnlockFileEx.Call(handle, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	}, nil
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// auditLogFlag - общий флаг файла журнала аудита
+const auditLogFlag = "audit-log"
+
+// auditTailSize - сколько байт с конца журнала читается в поисках последней
+// записи: записи короткие, весь журнал читать не нужно
+const auditTailSize = 64 * 1024
+
+// auditEntry - запись журнала аудита: один запуск seedgen. Записи связаны
+// в цепочку: prev - хэш предыдущей записи, hash - SHA-256 самой записи без
+// поля hash. Изменение, удаление или вставка записи ломает цепочку.
+// Секреты в журнал не попадают: вместо мастер-сида - отпечаток, вместо
+// файлов - SHA-256, значение --master в виде hex или msv2 скрыто.
+type auditEntry struct {
+	Seq     int               `json:"seq"`
+	Time    time.Time         `json:"time"`
+	Version string            `json:"version"`
+	Command string            `json:"command"`
+	Flags   map[string]string `json:"flags,omitempty"`
+	Inputs  []auditDigest     `json:"inputs,omitempty"`
+	Results []auditDigest     `json:"results,omitempty"`
+	Exit    int               `json:"exit_code"`
+	Prev    string            `json:"prev"`
+	Hash    string            `json:"hash,omitempty"`
+}
+
+// auditDigest - отпечаток входных данных или результата: kind - что это
+// (master, seed-set, file, json), name - путь файла, если он есть
+type auditDigest struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name,omitempty"`
+	Digest string `json:"digest"`
+}
+
+// auditLog - журнал аудита текущего запуска
+type auditLog struct {
+	path  string
+	entry auditEntry
+	// created - журнал создан этим запуском и был пуст
+	created bool
+}
+
+// audit - журнал аудита запуска или nil, если --audit-log не задан
+var audit *auditLog
+
+// openAuditLog проверяет до выполнения команды, что журнал доступен для
+// записи и его последняя запись не изменена
+func openAuditLog(path, command string) (*auditLog, error) {
+	_, statErr := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errorf("журнал аудита: %w", err)
+	}
+	defer f.Close()
+	if _, _, err := auditTail(f); err != nil {
+		return nil, err
+	}
+	return &auditLog{path: path, entry: auditEntry{Version: version, Command: command}, created: os.IsNotExist(statErr)}, nil
+}
+
+// dryRunRequested сообщает, что команда запущена с --dry-run
+func dryRunRequested() bool {
+	if runFlags == nil {
+		return false
+	}
+	f := runFlags.Lookup("dry-run")
+	return f != nil && f.Value.String() == "true"
+}
+
+// auditHash возвращает SHA-256 записи без поля hash
+func auditHash(e auditEntry) string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditTail возвращает номер и хэш последней записи журнала, проверив ее
+// хэш. В пустом журнале это 0 и "".
+func auditTail(f *os.File) (int, string, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, "", err
+	}
+	size := info.Size()
+	if size == 0 {
+		return 0, "", nil
+	}
+	chunk := int64(auditTailSize)
+	if chunk > size {
+		chunk = size
+	}
+	buf := make([]byte, chunk)
+	if _, err := f.ReadAt(buf, size-chunk); err != nil && err != io.EOF {
+		return 0, "", err
+	}
+	if buf[len(buf)-1] != '\n' {
+		return 0, "", mismatchf("журнал аудита %s: последняя запись оборвана", f.Name())
+	}
+	buf = buf[:len(buf)-1]
+	i := bytes.LastIndexByte(buf, '\n')
+	if i < 0 && chunk < size {
+		return 0, "", errorf("журнал аудита %s: последняя запись длиннее %d байт", f.Name(), auditTailSize)
+	}
+	var e auditEntry
+	if err := json.Unmarshal(buf[i+1:], &e); err != nil {
+		return 0, "", mismatchf("журнал аудита %s: последняя запись не разбирается: %v", f.Name(), err)
+	}
+	if e.Hash != auditHash(e) {
+		return 0, "", mismatchf("журнал аудита %s: последняя запись #%d изменена", f.Name(), e.Seq)
+	}
+	return e.Seq, e.Hash, nil
+}
+
+// finish дописывает запись запуска с кодом завершения. Файл блокируется
+// на время записи, чтобы параллельные запуски не разорвали цепочку.
+func (a *auditLog) finish(code int) error {
+	// Пробный запуск ничего не записывает, в том числе в журнал: созданный
+	// для него пустой файл удаляется
+	if dryRunRequested() {
+		if info, err := os.Stat(a.path); a.created && err == nil && info.Size() == 0 {
+			return os.Remove(a.path)
+		}
+		return nil
+	}
+	f, err := os.OpenFile(a.path, os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return errorf("журнал аудита: %w", err)
+	}
+	defer f.Close()
+	unlock, err := lockAuditFile(f)
+	if err != nil {
+		return errorf("журнал аудита: %w", err)
+	}
+	defer unlock()
+
+	seq, prev, err := auditTail(f)
+	if err != nil {
+		return err
+	}
+	e := a.entry
+	e.Seq, e.Prev, e.Exit = seq+1, prev, code
+	e.Time = time.Now().UTC().Truncate(time.Second)
+	// Флаги записываются при завершении, уже с окружением и профилем
+	if runFlags != nil {
+		e.Command = strings.TrimPrefix(runFlags.Name(), "seedgen ")
+		e.Flags = auditFlagValues(runFlags)
+	}
+	e.Hash = auditHash(e)
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return errorf("журнал аудита: %w", err)
+	}
+	return f.Sync()
+}
+
+// auditInput записывает отпечаток входных данных
+func auditInput(kind, name, digest string) {
+	if audit != nil {
+		audit.entry.Inputs = append(audit.entry.Inputs, auditDigest{kind, name, digest})
+	}
+}
+
+// auditResult записывает отпечаток результата
+func auditResult(kind, name, digest string) {
+	if audit != nil {
+		audit.entry.Results = append(audit.entry.Results, auditDigest{kind, name, digest})
+	}
+}
+
+// auditPINRE находит PIN в ссылке pkcs11:
+var auditPINRE = regexp.MustCompile(`pin-value=[^;?&]*`)
+
+// auditFlagValues возвращает явно заданные флаги команды
+func auditFlagValues(fs *flag.FlagSet) map[string]string {
+	values := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		values[f.Name] = recordedFlagValue(f)
+	})
+	return values
+}
+
+// recordedFlagValue возвращает значение флага для журнала аудита и хэша
+// конфигурации: значения, в которых может быть секрет, скрываются
+func recordedFlagValue(f *flag.Flag) string {
+	value := f.Value.String()
+	switch {
+	case f.Name == "master" && !auditMasterRef(value):
+		return "***"
+	case f.Name == "nonce" && value != "":
+		// Нонс mix вместе с сидами воспроизводит мастер-сид
+		return "***"
+	case strings.Contains(value, "pin-value="):
+		return auditPINRE.ReplaceAllString(value, "pin-value=***")
+	}
+	return value
+}
+
+// auditMasterRef сообщает, что значение --master - ссылка на хранилище
+// или файл, а не сам мастер-сид
+func auditMasterRef(ref string) bool {
+	if ref == "-" || strings.HasPrefix(ref, "op://") {
+		return true
+	}
+	if i := strings.IndexByte(ref, ':'); i > 0 {
+		if _, ok := masterSources[ref[:i]]; ok {
+			return true
+		}
+	}
+	if strings.HasPrefix(ref, msv2Prefix) {
+		return false
+	}
+	info, err := os.Stat(ref)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// runAuditLog проверяет цепочку журнала аудита от первой записи
+func runAuditLog(args []string) error {
+	fs := newFlagSet("audit log")
+	path := fs.String("file", "", "журнал аудита (по умолчанию - из --audit-log или SEEDGEN_AUDIT_LOG)")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *path == "" {
+		if audit != nil {
+			*path = audit.path
+		} else {
+			*path = os.Getenv(envName(auditLogFlag))
+		}
+	}
+	if *path == "" {
+		return errorf("укажите журнал аудита: --file")
+	}
+	f, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	prev := ""
+	var last auditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), auditTailSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var e auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return mismatchf("%s:%d: запись не разбирается: %v", *path, line, err)
+		}
+		switch {
+		case e.Hash != auditHash(e):
+			return mismatchf("%s:%d: запись #%d изменена: хэш не совпадает", *path, line, e.Seq)
+		case e.Prev != prev:
+			return mismatchf("%s:%d: цепочка разорвана перед записью #%d: запись удалена, вставлена или переставлена", *path, line, e.Seq)
+		case e.Seq != last.Seq+1:
+			return mismatchf("%s:%d: запись #%d, а ожидалась #%d", *path, line, e.Seq, last.Seq+1)
+		}
+		prev, last = e.Hash, e
+	}
+	if err := scanner.Err(); err != nil {
+		return errorf("%s: %w", *path, err)
+	}
+	if line == 0 {
+		fmt.Printf(tr("Журнал аудита %s пуст\n"), *path)
+		return nil
+	}
+	fmt.Printf(tr("✓ Цепочка журнала аудита цела: записей %d, последняя #%d (%s, %s) с хэшем %s\n"),
+		line, last.Seq, last.Command, last.Time.Format(time.RFC3339), last.Hash)
+	return nil
+}
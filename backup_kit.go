@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Схема комплекта хранителей: любые два листа из трех
+const (
+	kitThreshold = 2
+	kitShares    = 3
+)
+
+// kitSheetSize - емкость буфера листа комплекта: лист с долей собирается
+// в закрепленном буфере, который затирается после записи
+const kitSheetSize = 16 * 1024
+
+// runBackupKit делит мастер-сид между хранителями и собирает его из долей
+func runBackupKit(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, tr("Использование:"))
+		fmt.Fprintln(os.Stderr, "  seedgen backup-kit create --master master.json [--out backup-kit] [--format text|html]")
+		fmt.Fprintln(os.Stderr, tr("  seedgen backup-kit combine [листы или файлы долей...]"))
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return errorf("укажите действие: create или combine")
+	}
+
+	switch args[0] {
+	case "create":
+		return runBackupKitCreate(args[1:])
+	case "combine":
+		return runBackupKitCombine(args[1:])
+	default:
+		return errorf("неизвестное действие %q, доступны: create, combine", args[0])
+	}
+}
+
+// kitSheet - лист комплекта: доля и файл листа
+type kitSheet struct {
+	share *shamirShare
+	path  string
+}
+
+// kitActivation описывает для листов, где лежит ключ активации
+type kitActivation struct {
+	// round - раунд drand, до которого ключ заперт tlock; 0 - ключ
+	// записан листом для доверенного лица
+	round uint64
+	path  string
+}
+
+// runBackupKitCreate делит мастер-сид по схеме 2 из 3 и записывает три
+// листа для печати: у каждого хранителя свой лист с долей, ее контролем,
+// инструкцией по восстановлению и отпечатком мастер-сида. С --executors
+// комплект дополняется листами исполнителей и ключом активации на случай,
+// если хранители станут недоступны.
+func runBackupKitCreate(args []string) error {
+	fs := newFlagSet("backup-kit create")
+	masterRef := addMasterFlag(fs)
+	out := fs.String("out", "backup-kit", "каталог для листов комплекта")
+	format := fs.String("format", "text", "формат листов: text или html")
+	executors := fs.Int("executors", 0, "число листов исполнителей на случай недоступности хранителей; 0 - без исполнителей")
+	executorThreshold := fs.Int("executor-threshold", 3, "сколько листов исполнителей нужно вместе с ключом активации")
+	activation := &timelockFlags{at: fs.String("activation", "", "момент активации ГГГГ-ММ-ДДTЧЧ:ММZ: ключ активации запирается tlock до него (нужен tle); без флага ключ записывается листом для доверенного лица")}
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	ext := map[string]string{"text": ".txt", "html": ".html"}[*format]
+	if ext == "" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	if *executors == 0 && activation.enabled() {
+		return errorf("--activation применим только вместе с --executors")
+	}
+	if *executors != 0 {
+		if *executorThreshold < 2 || *executorThreshold > *executors || *executors > 255 {
+			return errorf("некорректная схема исполнителей: --executor-threshold %d из --executors %d (нужно 2 <= порог <= число <= 255)", *executorThreshold, *executors)
+		}
+		if activation.enabled() {
+			if err := activation.checkRound("activation"); err != nil {
+				return err
+			}
+		}
+	}
+	var sheets []kitSheet
+	for i := 0; i < kitShares; i++ {
+		sheets = append(sheets, kitSheet{
+			share: &shamirShare{threshold: kitThreshold, total: kitShares, index: i + 1},
+			path:  filepath.Join(*out, fmt.Sprintf("sheet-%d%s", i+1, ext)),
+		})
+	}
+	var act kitActivation
+	if *executors != 0 {
+		for i := 0; i < *executors; i++ {
+			sheets = append(sheets, kitSheet{
+				share: &shamirShare{threshold: *executorThreshold, total: *executors, index: i + 1, set: shareSetExecutor},
+				path:  filepath.Join(*out, fmt.Sprintf("executor-%d%s", i+1, ext)),
+			})
+		}
+		act.path = filepath.Join(*out, "activation"+ext)
+		if activation.enabled() {
+			// Под замком лист не печатается, поэтому он всегда текстовый
+			act.round, _ = activation.round()
+			act.path = filepath.Join(*out, "activation.tlock")
+		}
+		sheets = append(sheets, kitSheet{
+			share: &shamirShare{threshold: 1, total: 1, index: 1, set: shareSetActivation},
+			path:  act.path,
+		})
+	}
+	for _, sh := range sheets {
+		if _, err := os.Lstat(sh.path); err == nil {
+			return errorf("%s уже существует: листы прежнего комплекта не перезаписываются", sh.path)
+		}
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	fingerprint := masterFingerprint(master)
+
+	data, err := splitKit(master, *executors, *executorThreshold)
+	if err != nil {
+		return err
+	}
+	defer wipeSeeds(data)
+	for i, sh := range sheets {
+		sh.share.fingerprint, sh.share.data = fingerprint, data[i]
+	}
+
+	if err := os.MkdirAll(*out, 0700); err != nil {
+		return err
+	}
+	created := time.Now().UTC().Format("2006-01-02")
+	for _, sh := range sheets {
+		sheet := bytes.NewBuffer(newSecret(kitSheetSize)[:0])
+		sealed := sh.share.set == shareSetActivation && act.round != 0
+		err := writeKitSheet(sheet, sh.share, created, act, *format == "html" && !sealed)
+		content := sheet.Bytes()
+		if err == nil && sealed {
+			content, err = sealTimelock(sheet.Bytes(), act.round)
+		}
+		if err == nil {
+			err = writeNewFile(sh.path, content, 0600)
+		}
+		sum := sha256.Sum256(content)
+		b := sheet.Bytes()
+		wipe(b[:cap(b)])
+		if err != nil {
+			return err
+		}
+		auditResult("file", sh.path, hex.EncodeToString(sum[:]))
+	}
+
+	logInfof("✓ Комплект %d из %d для мастер-сида с отпечатком %s:\n", kitThreshold, kitShares, fingerprint)
+	paths := make([]string, 0, len(sheets))
+	for _, sh := range sheets {
+		paths = append(paths, sh.path)
+		switch sh.share.set {
+		case shareSetCustodian:
+			fmt.Printf(tr("  %s: доля %d, контроль %s\n"), sh.path, sh.share.index, sh.share.check())
+		case shareSetExecutor:
+			fmt.Printf(tr("  %s: доля исполнителя %d из %d, контроль %s\n"), sh.path, sh.share.index, sh.share.total, sh.share.check())
+		case shareSetActivation:
+			if act.round != 0 {
+				fmt.Printf(tr("  %s: ключ активации под замком tlock до раунда %d drand (%s)\n"), sh.path, act.round, timelockRoundTime(act.round).Format(time.RFC3339))
+			} else {
+				fmt.Printf(tr("  %s: ключ активации для доверенного лица, контроль %s\n"), sh.path, sh.share.check())
+			}
+		}
+	}
+	if *executors == 0 {
+		fmt.Printf(tr("Раздайте листы разным хранителям, а после печати затрите файлы: seedgen wipe %s\n"), strings.Join(paths, " "))
+	} else {
+		fmt.Printf(tr("Раздайте листы разным хранителям и исполнителям, ключ активации - доверенному лицу, а после печати затрите файлы: seedgen wipe %s\n"), strings.Join(paths, " "))
+	}
+	return nil
+}
+
+// splitKit возвращает данные листов: доли хранителей, затем, если есть
+// исполнители, их доли значения мастер-сид ⊕ ключ активации и сам ключ.
+// До записи листов каждая пара долей хранителей и каждые threshold идущих
+// подряд долей исполнителей с ключом проверяются сборкой.
+func splitKit(master []byte, executors, threshold int) ([][]byte, error) {
+	data, err := shamirSplit(master, kitThreshold, kitShares)
+	if err != nil {
+		return nil, err
+	}
+	verify := func(xs []byte, shares [][]byte, key []byte) error {
+		got := shamirCombine(xs, shares)
+		defer wipe(got)
+		for i := range key {
+			got[i] ^= key[i]
+		}
+		if !bytes.Equal(got, master) {
+			return errorf("доли %v не собирают мастер-сид", xs)
+		}
+		return nil
+	}
+	for i := range data {
+		for j := i + 1; j < len(data); j++ {
+			if err := verify([]byte{byte(i + 1), byte(j + 1)}, [][]byte{data[i], data[j]}, nil); err != nil {
+				wipeSeeds(data)
+				return nil, err
+			}
+		}
+	}
+	if executors == 0 {
+		return data, nil
+	}
+
+	key := newSecret(len(master))
+	if _, err := rand.Read(key); err != nil {
+		wipeSeeds(data)
+		return nil, err
+	}
+	value := newSecret(len(master))
+	defer wipe(value)
+	for i := range value {
+		value[i] = master[i] ^ key[i]
+	}
+	shares, err := shamirSplit(value, threshold, executors)
+	if err != nil {
+		wipeSeeds(data)
+		return nil, err
+	}
+	for i := 0; i+threshold <= executors; i++ {
+		xs := make([]byte, threshold)
+		for j := range xs {
+			xs[j] = byte(i + j + 1)
+		}
+		if err := verify(xs, shares[i:i+threshold], key); err != nil {
+			wipeSeeds(append(append(data, shares...), key))
+			return nil, err
+		}
+	}
+	return append(append(data, shares...), key), nil
+}
+
+// writeKitSheet выводит лист комплекта. Доля записывается блоком ss1, как
+// ее читает backup-kit combine; html - тот же текст на странице для печати.
+func writeKitSheet(w io.Writer, s *shamirShare, created string, act kitActivation, asHTML bool) error {
+	if asHTML {
+		fmt.Fprintln(w, "<!DOCTYPE html>")
+		fmt.Fprintln(w, "<html lang=\"ru\">")
+		fmt.Fprintln(w, "<head>")
+		fmt.Fprintln(w, "<meta charset=\"utf-8\">")
+		fmt.Fprintf(w, "<title>%s</title>\n", html.EscapeString(fmt.Sprintf(tr("Лист %d из %d комплекта %s"), s.index, s.total, s.fingerprint)))
+		fmt.Fprintln(w, "<style>body{font:12pt monospace;margin:2cm}pre{white-space:pre-wrap}.share{font-size:14pt;border:1px solid #000;padding:1em}@page{size:A4}</style>")
+		fmt.Fprintln(w, "</head>")
+		fmt.Fprintln(w, "<body>")
+		fmt.Fprint(w, "<pre>")
+	}
+	emit := func(line string) {
+		if asHTML {
+			line = html.EscapeString(line)
+		}
+		io.WriteString(w, line)
+	}
+
+	switch s.set {
+	case shareSetCustodian:
+		emit(fmt.Sprintf(tr("КОМПЛЕКТ ХРАНИТЕЛЕЙ МАСТЕР-СИДА SEEDGEN: ЛИСТ %d ИЗ %d\n"), s.index, s.total))
+		emit("\n")
+		emit(fmt.Sprintf(tr("Схема: любые %d листа из %d (разделение секрета Шамира)\n"), s.threshold, s.total))
+	case shareSetExecutor:
+		emit(fmt.Sprintf(tr("КОМПЛЕКТ МАСТЕР-СИДА SEEDGEN: ЛИСТ ИСПОЛНИТЕЛЯ %d ИЗ %d\n"), s.index, s.total))
+		emit("\n")
+		emit(fmt.Sprintf(tr("Схема: %d листа исполнителей из %d и ключ активации; хранителям\nисполнители не нужны: им достаточно %d листов хранителей из %d\n"), s.threshold, s.total, kitThreshold, kitShares))
+	case shareSetActivation:
+		emit(tr("КОМПЛЕКТ МАСТЕР-СИДА SEEDGEN: КЛЮЧ АКТИВАЦИИ ИСПОЛНИТЕЛЕЙ\n"))
+		emit("\n")
+	}
+	emit(fmt.Sprintf(tr("Отпечаток мастер-сида: %s\n"), s.fingerprint))
+	if s.set == shareSetActivation {
+		emit(fmt.Sprintf(tr("Контроль ключа активации: %s\n"), s.check()))
+	} else {
+		emit(fmt.Sprintf(tr("Контроль доли %d: %s\n"), s.index, s.check()))
+	}
+	emit(fmt.Sprintf(tr("Создан: %s, seedgen %s\n"), created, buildVersion()))
+	emit("\n")
+	switch s.set {
+	case shareSetCustodian:
+		emit(tr("Хранитель: ______________________   Подпись: ____________\n"))
+	case shareSetExecutor:
+		emit(tr("Исполнитель: ____________________   Подпись: ____________\n"))
+	case shareSetActivation:
+		if act.round == 0 {
+			emit(tr("Доверенное лицо: ________________   Подпись: ____________\n"))
+		}
+	}
+	emit("\n")
+	if asHTML {
+		// Перевод строки сразу после <pre> браузер не показывает, зато
+		// строка ss1: остается в начале строки и лист читает combine
+		fmt.Fprint(w, "</pre>\n<pre class=\"share\">\n")
+	}
+	if err := writeShare(w, s); err != nil {
+		return err
+	}
+	if asHTML {
+		fmt.Fprint(w, "</pre>\n<pre>")
+	}
+	emit("\n")
+	switch s.set {
+	case shareSetCustodian:
+		emit(tr("Восстановление мастер-сида\n"))
+		emit(fmt.Sprintf(tr("1. Соберите любые %d листа из %d с отпечатком %s. Листы других\n   комплектов, даже того же мастер-сида, с этим не сочетаются.\n"), s.threshold, s.total, s.fingerprint))
+		emit(tr("2. На офлайн-машине выполните seedgen backup-kit combine и перепишите\n   доли с листов - от строки ss1: до строки check: включительно - либо\n   передайте файлы листов: seedgen backup-kit combine sheet-1.txt sheet-3.txt\n"))
+		emit(fmt.Sprintf(tr("3. Контроль доли выявит ошибку переписывания, а собранный\n   мастер-сид будет сверен с отпечатком %s.\n"), s.fingerprint))
+		emit(fmt.Sprintf(tr("4. Один лист ничего не сообщает о мастер-сиде, но храните его как\n   секрет: %d листа в одних руках раскрывают мастер-сид.\n"), s.threshold))
+		emit(tr("5. Если лист утрачен или раскрыт, восстановите мастер-сид оставшимися,\n   создайте новый комплект и уничтожьте все листы прежнего.\n"))
+	case shareSetExecutor:
+		emit(tr("Порядок для исполнителей\n"))
+		emit(tr("1. Лист нужен, только если хранители недоступны. Пока они на связи,\n   мастер-сид восстанавливают они, а этот лист просто хранится.\n"))
+		if act.round != 0 {
+			emit(fmt.Sprintf(tr("2. Активация наступает %s (раунд %d drand): тогда ключ активации\n   в файле %s расшифровывается сам. Хранители продлевают срок,\n   выпуская новый комплект до этого момента.\n"), timelockRoundTime(act.round).Format(time.RFC3339), act.round, filepath.Base(act.path)))
+		} else {
+			emit(fmt.Sprintf(tr("2. Ключ активации (%s) хранится у доверенного лица и выдается\n   только после объявленной активации по регламенту организации.\n"), filepath.Base(act.path)))
+		}
+		emit(fmt.Sprintf(tr("3. Соберите %d листа исполнителей из %d с отпечатком %s и ключ\n   активации и выполните на офлайн-машине:\n   seedgen backup-kit combine executor-1.txt executor-2.txt ... %s\n"), s.threshold, s.total, s.fingerprint, filepath.Base(act.path)))
+		emit(tr("4. Без ключа активации листы исполнителей не сообщают о мастер-сиде\n   ничего, даже все вместе.\n"))
+		emit(tr("5. Получив лист нового комплекта, уничтожьте прежний: с ключом\n   активации прежнего комплекта он по-прежнему собирает мастер-сид.\n"))
+	case shareSetActivation:
+		emit(tr("Порядок для держателя ключа активации\n"))
+		emit(tr("1. Ключ сам по себе и вместе с одним листом хранителя не сообщает\n   о мастер-сиде ничего. Он нужен исполнителям вместе с их листами.\n"))
+		emit(tr("2. Выдайте ключ исполнителям только после объявленной активации\n   по регламенту организации: хранители недоступны, и это подтверждено.\n"))
+		emit(tr("3. Если хранители выпустили новый комплект, уничтожьте этот ключ.\n"))
+	}
+
+	if asHTML {
+		fmt.Fprintln(w, "</pre>")
+		fmt.Fprintln(w, "</body>")
+		fmt.Fprintln(w, "</html>")
+	}
+	return nil
+}
+
+// kitSharesFrom разбирает доли из текста листа или файла ref и затирает
+// текст. Ключ активации под замком tlock расшифровывается после раунда.
+func kitSharesFrom(ref string, text []byte) ([]*shamirShare, error) {
+	if round, chain, ok := timelockStanza(text); ok {
+		plain, err := decryptTimelock(text, round, chain)
+		wipe(text)
+		if err != nil {
+			return nil, errorf("%s: %w", ref, err)
+		}
+		text = plain
+	}
+	lockSecret(text)
+	found, err := parseShares(text)
+	wipe(text)
+	if err != nil {
+		return nil, errorf("%s: %w", ref, err)
+	}
+	if len(found) == 0 {
+		return nil, errorf("%s: доля не найдена: запись доли начинается со строки %s", ref, sharePrefix)
+	}
+	for _, s := range found {
+		switch s.set {
+		case shareSetCustodian:
+			logInfof("✓ Доля %d из %d (%s): контроль %s совпадает\n", s.index, s.total, ref, s.check())
+		case shareSetExecutor:
+			logInfof("✓ Доля исполнителя %d из %d (%s): контроль %s совпадает\n", s.index, s.total, ref, s.check())
+		case shareSetActivation:
+			logInfof("✓ Ключ активации (%s): контроль %s совпадает\n", ref, s.check())
+		}
+	}
+	return found, nil
+}
+
+// runBackupKitCombine собирает мастер-сид из долей комплекта: из листов,
+// файлов с переписанными долями или из stdin
+func runBackupKitCombine(args []string) error {
+	fs := newFlagSet("backup-kit combine")
+	format := fs.String("format", "hex", "формат вывода: hex или rs (копия rs1)")
+	rf := addRevealFlags(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *format != "hex" && *format != "rs" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	if err := rf.checkGroups(*format == "hex"); err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		positional = []string{"-"}
+	}
+
+	var shares []*shamirShare
+	defer func() {
+		for _, s := range shares {
+			wipe(s.data)
+		}
+	}()
+	for _, ref := range positional {
+		var text []byte
+		if ref == "-" {
+			if isTerminal(os.Stdin) {
+				fmt.Fprintf(os.Stderr, tr("Перепишите доли с %d листов, каждую от строки ss1: до строки check:, затем нажмите Ctrl-D:\n"), kitThreshold)
+			}
+			text, err = io.ReadAll(os.Stdin)
+		} else {
+			text, err = os.ReadFile(ref)
+		}
+		if err != nil {
+			return err
+		}
+		found, err := kitSharesFrom(ref, text)
+		if err != nil {
+			return err
+		}
+		shares = append(shares, found...)
+	}
+
+	master, err := combineShares(shares)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	logInfof("✓ Мастер-сид собран, отпечаток совпадает: %s\n", masterFingerprint(master))
+	auditResult("master", "", masterFingerprint(master))
+
+	if *format == "rs" {
+		return writeRSBackup(os.Stdout, master)
+	}
+	if !isTerminal(os.Stdout) {
+		return writeSecretLine(os.Stdout, master)
+	}
+	return rf.reveal(tr("Мастер-сид (собран из долей):"), master)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+)
+
+// base58Alphabet - алфавит Base58 в варианте Bitcoin
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Encode кодирует байты в Base58, сохраняя ведущие нули как "1"
+func base58Encode(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58Decode декодирует строку Base58
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		digit := bytes.IndexByte([]byte(base58Alphabet), s[i])
+		if digit < 0 {
+			return nil, errorf("символ %q в позиции %d не входит в алфавит Base58", s[i], i+1)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	leading := 0
+	for leading < len(s) && s[leading] == base58Alphabet[0] {
+		leading++
+	}
+	return append(make([]byte, leading), n.Bytes()...), nil
+}
+
+// base58CheckEncode добавляет 4 байта двойного SHA-256 и кодирует в Base58
+func base58CheckEncode(data []byte) string {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return base58Encode(append(append([]byte{}, data...), second[:4]...))
+}
+
+// base58CheckDecode декодирует Base58Check и проверяет контрольную сумму
+func base58CheckDecode(s string) ([]byte, error) {
+	raw, err := base58Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < 4 {
+		return nil, errorf("строка Base58Check слишком короткая")
+	}
+	data, sum := raw[:len(raw)-4], raw[len(raw)-4:]
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	if !secretEqual(sum, second[:4]) {
+		return nil, errorf("неверная контрольная сумма Base58Check")
+	}
+	return data, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strings"
+)
+
+// bech32Charset - алфавит данных Bech32 (BIP-173)
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Polymod вычисляет контрольный полином BCH-кода Bech32
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			// Маска вместо ветвления: данные могут быть секретом
+			chk ^= -(top >> uint(i) & 1) & gen[i]
+		}
+	}
+	return chk
+}
+
+// bech32HRPExpand подготавливает человекочитаемую часть для контрольной суммы
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// convertBits перегруппировывает биты между основаниями 2^from и 2^to
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	acc, bits := uint32(0), uint(0)
+	maxv := uint32(1)<<to - 1
+	var out []byte
+	for _, b := range data {
+		if uint32(b)>>from != 0 {
+			return nil, errorf("значение %d не помещается в %d бит", b, from)
+		}
+		acc = acc<<from | uint32(b)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, errorf("некорректное дополнение битами")
+	}
+	return out, nil
+}
+
+// bech32Encode кодирует байты в Bech32 с заданной человекочитаемой частью.
+// Ограничение BIP-173 в 90 символов не применяется: оно нужно только адресам.
+func bech32Encode(hrp string, data []byte) (string, error) {
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return bech32EncodeValues(hrp, values), nil
+}
+
+// segwitAddress кодирует адрес SegWit v0 (BIP-173) из программы свидетеля
+func segwitAddress(hrp string, program []byte) (string, error) {
+	values, err := convertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return bech32EncodeValues(hrp, append([]byte{0}, values...)), nil
+}
+
+// bech32EncodeValues кодирует уже разбитые на 5-битные группы значения
+func bech32EncodeValues(hrp string, values []byte) string {
+	check := append(bech32HRPExpand(hrp), values...)
+	check = append(check, 0, 0, 0, 0, 0, 0)
+	mod := bech32Polymod(check) ^ 1
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range values {
+		sb.WriteByte(bech32Char(v))
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Char(byte(mod>>uint(5*(5-i))) & 31))
+	}
+	return sb.String()
+}
+
+// bech32Decode декодирует строку Bech32 и проверяет контрольную сумму
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, errorf("строка Bech32 не может смешивать регистры")
+	}
+	s = strings.ToLower(s)
+
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, errorf("некорректная строка Bech32")
+	}
+	hrp := s[:sep]
+
+	values := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		v, ok := bech32Value(s[i])
+		if !ok {
+			return "", nil, errorf("символ %q в позиции %d не входит в алфавит Bech32", s[i], i+1)
+		}
+		values = append(values, v)
+	}
+
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != 1 {
+		return "", nil, errorf("неверная контрольная сумма Bech32")
+	}
+
+	data, err := convertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// benchCase описывает один замер производительности
+type benchCase struct {
+	name   string
+	params string
+	// bytes - объем данных за операцию для расчета МБ/с (0 для KDF)
+	bytes int
+	run   func()
+}
+
+// benchResult содержит результат одного замера
+type benchResult struct {
+	ops      int
+	elapsed  time.Duration
+	peakHeap uint64
+}
+
+// benchCases возвращает набор замеров для всех поддерживаемых KDF и хэшей
+func benchCases(iterations int) []benchCase {
+	password := []byte("device-alpha-123device-beta-456device-gamma-789")
+	salt := []byte("master-seed-salt-v1")
+	block := make([]byte, 1<<20)
+	v2 := V2Params(1)
+	v2.Iterations = iterations
+
+	cases := []benchCase{
+		{
+			name:   "PBKDF2-HMAC-SHA512",
+			params: fmt.Sprintf(tr("%d итераций"), iterations),
+			run:    func() { pbkdf2.Key(password, salt, iterations, 64, sha512.New) },
+		},
+		{
+			name:   "Argon2id",
+			params: fmt.Sprintf("t=%d, m=%s, p=%d", argon2DefaultTime, formatBytes(argon2FlagMemory*1024), argon2DefaultParallelism),
+			run: func() {
+				argon2.IDKey(password, salt, argon2DefaultTime, argon2FlagMemory, argon2DefaultParallelism, 64)
+			},
+		},
+		{
+			name:   "SHA-512",
+			params: "блок 1 МиБ",
+			bytes:  len(block),
+			run:    func() { sha512.Sum512(block) },
+		},
+		{
+			name:   "newseed --count",
+			params: "1000 сидов hex по 256 бит",
+			bytes:  1000 * 65,
+			run:    func() { writeRandomSeeds(io.Discard, 1000, 32, "hex", false) },
+		},
+	}
+	// Набор сидов собирается в заранее выделенный буфер одним проходом:
+	// время объединения растет линейно с числом сидов, а не квадратично
+	for _, n := range []int{1000, 10000} {
+		seeds := make([][]byte, n)
+		size := 0
+		for i := range seeds {
+			seeds[i] = []byte(fmt.Sprintf("device-%05d-seed", i))
+			size += len(seeds[i])
+		}
+		cases = append(cases, benchCase{
+			name:   "Объединение сидов v2",
+			params: fmt.Sprintf(tr("сидов: %d"), n),
+			bytes:  size,
+			run: func() {
+				combined, _ := canonicalSeedSet(seeds)
+				wipe(combined)
+			},
+		})
+	}
+	// Схема v2 выполняет один KDF над всем набором сидов: время не должно
+	// зависеть от числа устройств, и эти замеры это показывают
+	if v2.Validate() != nil {
+		return cases
+	}
+	for _, n := range []int{3, 30} {
+		seeds := make([][]byte, n)
+		for i := range seeds {
+			seeds[i] = []byte(fmt.Sprintf("device-%02d-seed", i))
+		}
+		cases = append(cases, benchCase{
+			name:   "Мастер-сид v2",
+			params: fmt.Sprintf(tr("сидов: %d, %d итераций"), n, iterations),
+			run:    func() { GenerateMasterSeed(seeds, v2) },
+		})
+	}
+	return cases
+}
+
+// measure выполняет замер не короче minDuration и отслеживает пик кучи
+func measure(bc benchCase, minDuration time.Duration) benchResult {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	baseline := ms.HeapInuse
+
+	// Пик памяти снимаем периодическим опросом в отдельной горутине
+	var peak uint64
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		var s runtime.MemStats
+		for {
+			runtime.ReadMemStats(&s)
+			if s.HeapInuse > baseline && s.HeapInuse-baseline > peak {
+				peak = s.HeapInuse - baseline
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	start := time.Now()
+	ops := 0
+	for ops == 0 || time.Since(start) < minDuration {
+		bc.run()
+		ops++
+	}
+	elapsed := time.Since(start)
+
+	close(done)
+	wg.Wait()
+	return benchResult{ops: ops, elapsed: elapsed, peakHeap: peak}
+}
+
+// formatBytes выводит размер в человекочитаемом виде
+func formatBytes(n uint64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf(tr("%.1f ГиБ"), float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf(tr("%.1f МиБ"), float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf(tr("%.1f КиБ"), float64(n)/(1<<10))
+	}
+	return fmt.Sprintf(tr("%d Б"), n)
+}
+
+// runBench измеряет скорость и память каждой комбинации KDF/хэша
+func runBench(args []string) error {
+	fs := newFlagSet("bench")
+	minDuration := fs.Duration("time", time.Second, "минимальная длительность каждого замера")
+	iterations := fs.Int("iterations", 100000, "число итераций PBKDF2")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *iterations < 1 {
+		return errorf("число итераций должно быть положительным")
+	}
+
+	fmt.Println(tr("=== Замер производительности ==="))
+	fmt.Printf(tr("Платформа: %s/%s, %s, ядер: %d\n"), runtime.GOOS, runtime.GOARCH, runtime.Version(), runtime.NumCPU())
+	if err := writeCPUFeatures(os.Stdout, cpuFeatures()); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, tr("Алгоритм\tПараметры\tВремя/операция\tСкорость\tПик кучи"))
+	for _, bc := range benchCases(*iterations) {
+		res := measure(bc, *minDuration)
+		perOp := res.elapsed / time.Duration(res.ops)
+
+		var speed string
+		if bc.bytes > 0 {
+			mbps := float64(bc.bytes) * float64(res.ops) / res.elapsed.Seconds() / 1e6
+			speed = fmt.Sprintf(tr("%.1f МБ/с"), mbps)
+		} else {
+			speed = fmt.Sprintf(tr("%.2f оп/с"), float64(res.ops)/res.elapsed.Seconds())
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%s\t%s\n", tr(bc.name), tr(bc.params), perOp.Round(time.Microsecond), speed, formatBytes(res.peakHeap))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// drawStream - детерминированный поток случайных чисел жеребьевки:
+// блоки HMAC-SHA512 ключом, выведенным из мастер-сида для метки, от номера
+// блока (8 байт big-endian). Кто знает опубликованный мастер-сид и метку,
+// получит тот же поток любой реализацией HMAC.
+type drawStream struct {
+	key     []byte
+	counter uint64
+	buf     []byte
+}
+
+// newDrawStream создает поток для типа жеребьевки kind и метки label
+func newDrawStream(master []byte, kind, label string) *drawStream {
+	logDebug("поток жребия", "kind", kind, "label", label)
+	return &drawStream{key: deriveKindKey(master, kind, label)}
+}
+
+// uint64 возвращает следующие 8 байт потока как число big-endian
+func (s *drawStream) uint64() uint64 {
+	if len(s.buf) < 8 {
+		mac := hmac.New(sha512.New, s.key)
+		var block [8]byte
+		binary.BigEndian.PutUint64(block[:], s.counter)
+		mac.Write(block[:])
+		s.buf = mac.Sum(nil)
+		s.counter++
+	}
+	v := binary.BigEndian.Uint64(s.buf[:8])
+	s.buf = s.buf[8:]
+	return v
+}
+
+// intn возвращает равномерное число из [0, n) отбрасыванием: значения
+// из неполного последнего интервала пропускаются, смещения по модулю нет
+func (s *drawStream) intn(n int) int {
+	// rem = 2^64 mod n; отбрасываются значения не меньше 2^64 - rem
+	rem := (^uint64(0)%uint64(n) + 1) % uint64(n)
+	for {
+		if v := s.uint64(); rem == 0 || v < -rem {
+			return int(v % uint64(n))
+		}
+	}
+}
+
+// shuffle перемешивает n элементов алгоритмом Фишера-Йетса от конца к началу
+func (s *drawStream) shuffle(n int, swap func(i, j int)) {
+	for i := n - 1; i > 0; i-- {
+		swap(i, s.intn(i+1))
+	}
+}
+
+// readNameList читает список имен по одному на строку; пустые строки
+// и строки с # пропускаются, повторы запрещены. Возвращает и SHA-256
+// списка в каноническом виде (имена через \n), чтобы результат можно
+// было сверить с тем же списком.
+func readNameList(path string) ([]string, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	var names []string
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		if first, ok := seen[name]; ok {
+			return nil, "", errorf("%s:%d: %q уже указан в строке %d", path, n, name, first)
+		}
+		seen[name] = n
+		names = append(names, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256([]byte(strings.Join(names, "\n")))
+	digest := hex.EncodeToString(sum[:])
+	auditInput("file", path, digest)
+	return names, digest, nil
+}
+
+// bracketOrder возвращает стандартную расстановку сеяных номеров по слотам
+// сетки размера size (степень двойки): в первом круге номер r встречается
+// с номером size+1-r, а первый и второй номера могут встретиться только
+// в финале
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for n := 2; n <= size; n *= 2 {
+		next := make([]int, 0, n)
+		for _, r := range order {
+			next = append(next, r, n+1-r)
+		}
+		order = next
+	}
+	return order
+}
+
+// bracketMatch - матч сетки. Участник с пропуском (bye) проходит дальше
+// без игры; в следующих кругах вместо имени указывается, из какого матча
+// придет победитель.
+type bracketMatch struct {
+	ID   string `json:"id"`
+	Home string `json:"home"`
+	Away string `json:"away,omitempty"`
+	Bye  bool   `json:"bye,omitempty"`
+	// IfNeeded - переигровка суперфинала: играется, только если матч Reset
+	// выиграл участник из сетки проигравших
+	IfNeeded bool   `json:"if_needed,omitempty"`
+	Reset    string `json:"reset_of,omitempty"`
+	matchRef
+}
+
+// bracket - сетка на выбывание
+type bracket struct {
+	Kind         string `json:"kind"`
+	Label        string `json:"label"`
+	Participants string `json:"participants_sha256"`
+	Count        int    `json:"participants"`
+	Size         int    `json:"size"`
+	Seeded       int    `json:"seeded"`
+	Byes         int    `json:"byes"`
+	Fingerprint  string `json:"master_fingerprint"`
+	// Double - двойное выбывание: Rounds - сетка победителей, Losers - сетка
+	// проигравших, Final - суперфинал и его переигровка
+	Double bool             `json:"double,omitempty"`
+	Rounds [][]bracketMatch `json:"rounds"`
+	Losers [][]bracketMatch `json:"losers,omitempty"`
+	Final  []bracketMatch   `json:"grand_final,omitempty"`
+}
+
+// bracketNumbersDraw распределяет номера. Первые seeded участников списка
+// сеяные и занимают номера 1..seeded, остальные получают оставшиеся номера
+// жеребьевкой. Возвращает участников по номерам.
+func bracketNumbersDraw(stream *drawStream, names []string, seeded int) []string {
+	ranked := append([]string(nil), names...)
+	rest := ranked[seeded:]
+	stream.shuffle(len(rest), func(i, j int) { rest[i], rest[j] = rest[j], rest[i] })
+	return ranked
+}
+
+// build строит круги сетки по номерам: ranked[i] занимает номер i+1.
+// Номера больше числа участников - пропуски: по стандартной расстановке
+// они достаются сильнейшим номерам и никогда не встречаются друг с другом.
+func (b *bracket) build(ranked []string) {
+	b.Size = 1
+	for b.Size < len(ranked) {
+		b.Size *= 2
+	}
+	b.Count = len(ranked)
+	b.Byes = b.Size - len(ranked)
+	if !b.Double {
+		b.Rounds = bracketRounds(ranked, b.Size, "R")
+		return
+	}
+	b.Rounds = bracketRounds(ranked, b.Size, "W")
+	b.Losers, b.Final = doubleElimination(b.Rounds)
+}
+
+// bracketRounds строит круги сетки размера size: ranked[i] занимает номер i+1,
+// номера матчей начинаются с prefix
+func bracketRounds(ranked []string, size int, prefix string) [][]bracketMatch {
+	order := bracketOrder(size)
+	var rounds [][]bracketMatch
+	var first []bracketMatch
+	// advance - кто выходит из каждого матча в следующий круг
+	var advance []string
+	for m := 0; m < size/2; m++ {
+		home, away := order[2*m], order[2*m+1]
+		if home > away {
+			home, away = away, home
+		}
+		match := bracketMatch{ID: fmt.Sprintf("%s1-M%d", prefix, m+1), Home: ranked[home-1]}
+		if away <= len(ranked) {
+			match.Away = ranked[away-1]
+			advance = append(advance, "победитель "+match.ID)
+		} else {
+			match.Bye = true
+			advance = append(advance, match.Home)
+		}
+		first = append(first, match)
+	}
+	rounds = append(rounds, first)
+
+	for r := 2; len(advance) > 1; r++ {
+		var round []bracketMatch
+		var next []string
+		for m := 0; m < len(advance)/2; m++ {
+			match := bracketMatch{ID: fmt.Sprintf("%s%d-M%d", prefix, r, m+1), Home: advance[2*m], Away: advance[2*m+1]}
+			round = append(round, match)
+			next = append(next, "победитель "+match.ID)
+		}
+		rounds = append(rounds, round)
+		advance = next
+	}
+	return rounds
+}
+
+// roundName возвращает название круга по числу матчей в нем
+func roundName(r, matches int) string {
+	switch matches {
+	case 1:
+		return tr("Финал")
+	case 2:
+		return tr("Полуфинал")
+	case 4, 8, 16, 32, 64:
+		return fmt.Sprintf(tr("1/%d финала"), matches)
+	}
+	return fmt.Sprintf(tr("Круг %d"), r)
+}
+
+// newBracketDraw проводит жеребьевку сетки и присваивает матчам идентификаторы
+func newBracketDraw(master []byte, label string, names []string, digest string, seeded int, double bool) bracket {
+	b := bracket{
+		Kind:         "bracket",
+		Label:        label,
+		Participants: digest,
+		Seeded:       seeded,
+		Fingerprint:  masterFingerprint(master),
+		Double:       double,
+	}
+	b.build(bracketNumbersDraw(newDrawStream(master, "bracket", label), names, seeded))
+	b.assignMatchRefs(uuidNamespace(master))
+	return b
+}
+
+// runBracket строит сетку турнира на выбывание из опубликованного мастер-сида
+func runBracket(args []string) error {
+	fs := newFlagSet("bracket")
+	participantsPath := fs.String("participants", "", "файл участников по одному на строку; при --seeded N первые N строк - сеяные по порядку силы")
+	seeded := fs.Int("seeded", 0, "число сеяных участников в начале списка: они получают номера по порядку и пропуски первого круга")
+	label := fs.String("label", "bracket", "метка сетки: разные метки дают независимые жеребьевки из одного мастер-сида")
+	double := fs.Bool("double", false, "двойное выбывание: сетка проигравших и суперфинал с переигровкой")
+	format := fs.String("format", "text", "формат вывода: text или json")
+	render := fs.String("render", "", "вывести схему сетки для печати и публикации: svg или html")
+	rosterPath := addRosterFlag(fs, "participants")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := oneSource(*participantsPath, *rosterPath, "participants"); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	if err := checkRender(*render, *format); err != nil {
+		return err
+	}
+	names, digest, err := readParticipants(*participantsPath, *rosterPath)
+	if err != nil {
+		return err
+	}
+	if len(names) < 2 || len(names) > 1024 {
+		return errorf("в сетке должно быть от 2 до 1024 участников, указано %d", len(names))
+	}
+	if *seeded < 0 || *seeded > len(names) {
+		return errorf("--seeded должно быть от 0 до числа участников (%d)", len(names))
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	b := newBracketDraw(master, *label, names, digest, *seeded, *double)
+	if *format == "json" {
+		return writeOutput(os.Stdout, b)
+	}
+	if *render != "" {
+		h := renderHeader{
+			title: fmt.Sprintf(tr("Сетка %q"), b.Label),
+			lines: []string{
+				fmt.Sprintf(tr("Участников %d, сеяных %d, пропусков %d"), b.Count, b.Seeded, b.Byes),
+				fmt.Sprintf(tr("Мастер-сид: %s..., список участников: SHA-256 %s"), b.Fingerprint, b.Participants),
+			},
+			build: provenanceLine(),
+		}
+		return writeRender(os.Stdout, *render, h, func(w io.Writer) { renderBracketSVG(w, b, h) })
+	}
+
+	fmt.Printf(tr("Сетка %q: участников %d, сеяных %d, пропусков %d\n"), b.Label, b.Count, b.Seeded, b.Byes)
+	fmt.Printf(tr("Мастер-сид: %s..., список участников: SHA-256 %s\n"), emph(b.Fingerprint), b.Participants)
+	printBracket(b)
+	return nil
+}
+
+// printBracket выводит матчи сетки по кругам
+func printBracket(b bracket) {
+	for r, round := range b.Rounds {
+		title := roundName(r+1, len(round))
+		if b.Double {
+			title = fmt.Sprintf(tr("Сетка победителей, круг %d (%s)"), r+1, title)
+		}
+		printBracketMatches(title, round)
+	}
+	for r, round := range b.Losers {
+		printBracketMatches(fmt.Sprintf(tr("Сетка проигравших, круг %d"), r+1), round)
+	}
+	if len(b.Final) > 0 {
+		printBracketMatches(tr("Суперфинал"), b.Final)
+	}
+}
+
+// entrantName возвращает участника матча для вывода: имена остаются как
+// есть, а заготовки "победитель M1" из протокола переводятся
+func entrantName(name string) string {
+	for _, prefix := range []string{"победитель ", "проигравший "} {
+		if strings.HasPrefix(name, prefix) {
+			return tr(prefix) + strings.TrimPrefix(name, prefix)
+		}
+	}
+	return name
+}
+
+// printBracketMatches выводит матчи одного круга
+func printBracketMatches(title string, matches []bracketMatch) {
+	fmt.Println()
+	fmt.Printf("%s:\n", title)
+	for _, m := range matches {
+		switch {
+		case m.Bye:
+			fmt.Printf(tr("  %-7s %s - проходит без игры\n"), m.ID, entrantName(m.Home))
+		case m.IfNeeded:
+			fmt.Printf(tr("  %-7s %s - %s (если %s выиграет участник из сетки проигравших)\n"), m.ID, entrantName(m.Home), entrantName(m.Away), m.Reset)
+		default:
+			fmt.Printf("  %-7s %s - %s\n", m.ID, entrantName(m.Home), entrantName(m.Away))
+		}
+	}
+}
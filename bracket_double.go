@@ -0,0 +1,104 @@
+package main
+
+import "fmt"
+
+// losersMatch составляет матч сетки проигравших. Пустая строка - участника
+// не будет (проигравшего в матче с пропуском нет): с одним участником матч
+// проходит без игры, без обоих матча нет. Возвращает матч, есть ли он,
+// и кто из него выходит дальше.
+func losersMatch(id, home, away string) (bracketMatch, bool, string) {
+	switch {
+	case home == "" && away == "":
+		return bracketMatch{}, false, ""
+	case home == "":
+		return bracketMatch{ID: id, Home: away, Bye: true}, true, away
+	case away == "":
+		return bracketMatch{ID: id, Home: home, Bye: true}, true, home
+	}
+	return bracketMatch{ID: id, Home: home, Away: away}, true, "победитель " + id
+}
+
+// dropOrder задает порядок, в котором проигравшие круга r сетки победителей
+// (r >= 2) встречают участников сетки проигравших: в четных кругах
+// в обратном порядке, в нечетных - половинами наоборот. Так проигравший
+// не встречает сразу соперника из той же части сетки.
+func dropOrder(losers []string, r int) []string {
+	n := len(losers)
+	ordered := make([]string, n)
+	for i, l := range losers {
+		if r%2 == 0 {
+			ordered[n-1-i] = l
+		} else {
+			ordered[(i+n/2)%n] = l
+		}
+	}
+	return ordered
+}
+
+// doubleElimination строит по сетке победителей сетку проигравших
+// и суперфинал. Проигравшие первого круга играют между собой попарно,
+// затем круги сетки проигравших чередуются: в нечетных (кроме первого)
+// победители предыдущего круга встречают проигравших очередного круга
+// сетки победителей, в четных играют между собой. Победитель сетки
+// проигравших встречает в суперфинале победителя сетки победителей,
+// и если выиграет, сыгранный суперфинал переигрывается: у каждого
+// тогда по одному поражению.
+func doubleElimination(winners [][]bracketMatch) ([][]bracketMatch, []bracketMatch) {
+	drops := make([][]string, len(winners))
+	for r, round := range winners {
+		for _, m := range round {
+			loser := ""
+			if !m.Bye {
+				loser = "проигравший " + m.ID
+			}
+			drops[r] = append(drops[r], loser)
+		}
+	}
+
+	var losers [][]bracketMatch
+	// addRound добавляет круг из пар и возвращает, кто из него выходит
+	addRound := func(pairs [][2]string) []string {
+		var round []bracketMatch
+		var advance []string
+		for m, p := range pairs {
+			match, ok, next := losersMatch(fmt.Sprintf("L%d-M%d", len(losers)+1, m+1), p[0], p[1])
+			if ok {
+				round = append(round, match)
+			}
+			advance = append(advance, next)
+		}
+		losers = append(losers, round)
+		return advance
+	}
+
+	advance := drops[0]
+	if len(winners) > 1 {
+		var pairs [][2]string
+		for i := 0; i < len(advance); i += 2 {
+			pairs = append(pairs, [2]string{advance[i], advance[i+1]})
+		}
+		advance = addRound(pairs)
+		for r := 2; r <= len(winners); r++ {
+			drop := dropOrder(drops[r-1], r)
+			pairs = pairs[:0]
+			for i := range advance {
+				pairs = append(pairs, [2]string{advance[i], drop[i]})
+			}
+			advance = addRound(pairs)
+			if len(advance) > 1 {
+				pairs = pairs[:0]
+				for i := 0; i < len(advance); i += 2 {
+					pairs = append(pairs, [2]string{advance[i], advance[i+1]})
+				}
+				advance = addRound(pairs)
+			}
+		}
+	}
+
+	champion := "победитель " + winners[len(winners)-1][0].ID
+	final := []bracketMatch{
+		{ID: "GF-1", Home: champion, Away: advance[0]},
+		{ID: "GF-2", Home: champion, Away: advance[0], IfNeeded: true, Reset: "GF-1"},
+	}
+	return losers, final
+}
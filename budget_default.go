@@ -0,0 +1,13 @@
+//go:build !lowmem
+// +build !lowmem
+
+package main
+
+// lowMemoryBuild сообщает, что бинарник собран с тегом lowmem
+const lowMemoryBuild = false
+
+// argon2FlagMemory - память Argon2id в КиБ, которую подставляют флаги по умолчанию
+const argon2FlagMemory = argon2DefaultMemory
+
+// tuneMemory настраивает сборщик мусора под бюджет памяти сборки
+func tuneMemory() {}
@@ -0,0 +1,20 @@
+//go:build lowmem
+// +build lowmem
+
+package main
+
+import "runtime/debug"
+
+// lowMemoryBuild сообщает, что бинарник собран с тегом lowmem для
+// одноплатных компьютеров с 64-128 МБ памяти
+const lowMemoryBuild = true
+
+// argon2FlagMemory - память Argon2id в КиБ, которую подставляют флаги по
+// умолчанию: 16 МиБ оставляют место системе даже на плате с 64 МБ
+const argon2FlagMemory = 16 * 1024
+
+// tuneMemory заставляет сборщик мусора освобождать память раньше: куча
+// растет не более чем на 20% сверх живых данных вместо 100%
+func tuneMemory() {
+	debug.SetGCPercent(20)
+}
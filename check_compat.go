@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// Пути Bitcoin, которые приложения кошельков создают при импорте мнемоники,
+// в порядке их показа: Native SegWit, Nested SegWit, Legacy
+var compatBTCPurposes = []uint32{84, 49, 44}
+
+// compatDevice описывает, как приложение аппаратного кошелька выводит счета
+type compatDevice struct {
+	name string
+	app  string
+	// SLIP-132 (xpub/ypub/zpub) или, как Ledger Live, всегда версия xpub
+	slip132 bool
+	// Ledger Live заводит счет Ethereum на каждый N в m/44'/60'/N'/0/0
+	ethLedgerLive bool
+}
+
+// compatDevices перечисляет поддерживаемые устройства
+var compatDevices = map[string]compatDevice{
+	"trezor":   {name: "trezor", app: "Trezor Suite", slip132: true},
+	"ledger":   {name: "ledger", app: "Ledger Live", ethLedgerLive: true},
+	"keystone": {name: "keystone", app: "Keystone", slip132: true},
+}
+
+// compatDeviceNames возвращает имена устройств для сообщений и дополнения
+func compatDeviceNames() []string {
+	return []string{"trezor", "ledger", "keystone"}
+}
+
+// publicHint подсказывает, где приложение показывает открытый ключ счета
+func (d compatDevice) publicHint() string {
+	switch d.name {
+	case "trezor":
+		return tr("Trezor Suite: счет → «Сведения» → «Показать открытый ключ», адреса - «Получить»")
+	case "ledger":
+		return tr("Ledger Live: счет → «Изменить счет» → «Дополнительно», поле xpub; Ledger Live показывает версию xpub для всех типов счетов")
+	default:
+		return tr("Keystone: отпечаток мастер-ключа - в сведениях о кошельке на устройстве, zpub/ypub/xpub и адреса - в Sparrow или BlueWallet после подключения по QR")
+	}
+}
+
+// ethHint подсказывает, какие адреса Ethereum показывает приложение
+func (d compatDevice) ethHint() string {
+	switch d.name {
+	case "ledger":
+		return tr("Ledger Live заводит по счету на каждый N в пути m/44'/60'/N'/0/0")
+	case "trezor":
+		return tr("Trezor Suite заводит счета по адресам m/44'/60'/0'/0/N по порядку")
+	default:
+		return tr("Keystone по умолчанию - путь BIP44 m/44'/60'/0'/0/N; пути Ledger Live и Ledger Legacy выбираются отдельно")
+	}
+}
+
+// compatAddress - адрес с путем для сравнения с экраном кошелька
+type compatAddress struct {
+	Path    string `json:"path"`
+	Address string `json:"address"`
+}
+
+// compatAccount - счет в том виде, в каком его показывает приложение
+type compatAccount struct {
+	Coin          string          `json:"coin"`
+	Standard      string          `json:"standard"`
+	AccountPath   string          `json:"account_path,omitempty"`
+	AccountPublic string          `json:"account_public,omitempty"`
+	Addresses     []compatAddress `json:"addresses"`
+}
+
+// compatReport - результат check-compat
+type compatReport struct {
+	Kind              string          `json:"kind"`
+	Device            string          `json:"device"`
+	App               string          `json:"app"`
+	Network           string          `json:"network"`
+	MasterFingerprint string          `json:"master_fingerprint"`
+	Accounts          []compatAccount `json:"accounts"`
+}
+
+// runCheckCompat выводит стандартные счета и первые адреса для мнемоники
+// `derive bip39` так, как их покажет аппаратный кошелек после импорта той
+// же мнемоники. Сверка до перевода средств ловит расхождение путей вывода.
+func runCheckCompat(args []string) error {
+	fs := newFlagSet("check-compat")
+	deviceName := fs.String("device", "", "аппаратный кошелек: trezor, ledger или keystone")
+	account := fs.Uint("account", 0, "номер счета Bitcoin и первого счета Ethereum")
+	count := fs.Int("count", 3, "адресов на счет Bitcoin и счетов Ethereum")
+	testnet := fs.Bool("testnet", false, "счета Bitcoin тестовой сети")
+	format := fs.String("format", "text", "формат вывода: text или json")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	device, ok := compatDevices[*deviceName]
+	if !ok {
+		if *deviceName == "" {
+			return errorf("укажите --device: trezor, ledger или keystone")
+		}
+		return errorf("неизвестное устройство %q, поддерживаются trezor, ledger и keystone", *deviceName)
+	}
+	if *count < 1 {
+		return errorf("количество адресов должно быть положительным")
+	}
+	if uint64(*account)+uint64(*count) > uint64(hardenedOffset) {
+		return errorf("номер счета вне допустимого диапазона")
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	seed, err := walletSeed(master)
+	if err != nil {
+		return err
+	}
+	defer wipe(seed)
+	root, err := newHDKey(curveSecp256k1, seed)
+	if err != nil {
+		return err
+	}
+
+	net, coin, netName := 0, uint32(0), "mainnet"
+	if *testnet {
+		net, coin, netName = 1, 1, "testnet"
+	}
+	fp := root.fingerprint()
+	report := compatReport{
+		Kind:              "compat-check",
+		Device:            device.name,
+		App:               device.app,
+		Network:           netName,
+		MasterFingerprint: fmt.Sprintf("%x", fp[:]),
+	}
+
+	for _, purpose := range compatBTCPurposes {
+		std := btcPurposes[purpose]
+		accountPath := derivationPath{hardened(purpose), hardened(coin), hardened(uint32(*account))}
+		acct, err := root.derivePath(accountPath)
+		if err != nil {
+			return err
+		}
+		version := std.versions[net][1]
+		if !device.slip132 {
+			version = btcPurposes[44].versions[net][1]
+		}
+		entry := compatAccount{
+			Coin:          "btc",
+			Standard:      std.name,
+			AccountPath:   accountPath.String(),
+			AccountPublic: acct.serialize(version, false),
+		}
+		branch, err := acct.child(0)
+		if err != nil {
+			return err
+		}
+		for i := uint32(0); i < uint32(*count); i++ {
+			key, err := branch.child(i)
+			if err != nil {
+				return err
+			}
+			addr, err := std.address(key.publicKey(), *testnet)
+			if err != nil {
+				return err
+			}
+			entry.Addresses = append(entry.Addresses, compatAddress{Path: accountPath.child(0, i).String(), Address: addr})
+		}
+		report.Accounts = append(report.Accounts, entry)
+	}
+
+	eth := compatAccount{Coin: "eth", Standard: "BIP44"}
+	for i := uint32(*account); i < uint32(*account)+uint32(*count); i++ {
+		path := derivationPath{hardened(44), hardened(60), hardened(0), 0, i}
+		if device.ethLedgerLive {
+			path = derivationPath{hardened(44), hardened(60), hardened(i), 0, 0}
+		}
+		key, err := root.derivePath(path)
+		if err != nil {
+			return err
+		}
+		eth.Addresses = append(eth.Addresses, compatAddress{Path: path.String(), Address: ethAddress(secpScalarBaseMult(key.key))})
+	}
+	if device.ethLedgerLive {
+		eth.Standard = "Ledger Live"
+	} else {
+		eth.AccountPath = derivationPath{hardened(44), hardened(60), hardened(0), 0}.String()
+	}
+	report.Accounts = append(report.Accounts, eth)
+
+	if *format == "json" {
+		return writeOutput(os.Stdout, report)
+	}
+
+	fmt.Printf(tr("Сверка с %s (%s)\n"), report.App, report.Network)
+	fmt.Printf(tr("Отпечаток мастер-ключа: %s\n"), emph(report.MasterFingerprint))
+	fmt.Println(device.publicHint())
+	for _, a := range report.Accounts {
+		fmt.Println()
+		if a.Coin == "btc" {
+			fmt.Printf(tr("Bitcoin, %s, счет %s\n"), a.Standard, a.AccountPath)
+			fmt.Println(a.AccountPublic)
+		} else {
+			fmt.Printf(tr("Ethereum, %s\n"), a.Standard)
+			fmt.Println(device.ethHint())
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, addr := range a.Addresses {
+			fmt.Fprintf(tw, "  %s\t%s\n", addr.Path, addr.Address)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	fmt.Println()
+	fmt.Println(tr("Импортируйте в устройство мнемонику `seedgen derive bip39` (24 слова) без пароля BIP39."))
+	fmt.Println(tr("Если хотя бы один ключ или адрес отличается, не переводите средства: пути вывода не совпадают."))
+	fmt.Println(tr("Счета Taproot (BIP86) не сверяются."))
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// crockfordAlphabet - алфавит Base32 Крокфорда: без I, L, O и U,
+// которые на бумаге путаются с 1, 0 и V
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// hexCheckSize - длина контрольного суффикса после дефиса
+const hexCheckSize = 2
+
+// seedCheckSize - длина контрольного суффикса сида устройства
+const seedCheckSize = 4
+
+// crockfordCheck возвращает n контрольных символов данных - первые 5n бит
+// SHA-256 в алфавите Крокфорда (n не больше 50)
+func crockfordCheck(data []byte, n int) []byte {
+	sum := sha256.Sum256(data)
+	defer wipe(sum[:])
+	check := make([]byte, n)
+	for i := range check {
+		bit := 5 * i
+		v := int(sum[bit/8])<<8 | int(sum[bit/8+1])
+		check[i] = crockfordAlphabet[v>>(11-bit%8)&31]
+	}
+	return check
+}
+
+// hexCheck возвращает два контрольных символа секрета - первые 10 бит
+// SHA-256 в алфавите Крокфорда. Ошибка переписывания любого символа hex
+// остается незамеченной с вероятностью 1/1024.
+func hexCheck(secret []byte) [hexCheckSize]byte {
+	var check [hexCheckSize]byte
+	copy(check[:], crockfordCheck(secret, hexCheckSize))
+	return check
+}
+
+// splitSeedCheck отделяет от строки сида устройства контрольный суффикс
+// "-XXXX" и сверяет его с SHA-256 самой строки. Сид - это строка до дефиса,
+// поэтому мастер-сид не зависит от того, вводились ли сиды с суффиксом.
+func splitSeedCheck(line []byte) ([]byte, error) {
+	i := len(line) - seedCheckSize - 1
+	if i < 1 || line[i] != '-' {
+		return nil, errorf("нет контрольного суффикса из %d символов после дефиса", seedCheckSize)
+	}
+	seed, check := line[:i], line[i+1:]
+	got := make([]byte, seedCheckSize)
+	for j, c := range check {
+		got[j] = normalizeCrockford(c)
+	}
+	if !bytes.Equal(got, crockfordCheck(seed, seedCheckSize)) {
+		return nil, errorf("контрольные символы %s не совпадают, сид введен с ошибкой", check)
+	}
+	return seed, nil
+}
+
+// normalizeCrockford приводит символ к алфавиту Крокфорда: регистр не
+// важен, O читается как 0, а I и L - как 1
+func normalizeCrockford(c byte) byte {
+	switch c {
+	case 'o', 'O':
+		return '0'
+	case 'i', 'I', 'l', 'L':
+		return '1'
+	}
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+// writeCheckedSecretLine выводит секрет в hex с контрольным суффиксом
+// "-XY" для записи на бумагу, минуя строки fmt
+func writeCheckedSecretLine(w io.Writer, secret []byte) error {
+	n := hex.EncodedLen(len(secret))
+	line := newSecret(n + 1 + hexCheckSize + 1)
+	defer wipe(line)
+	encodeHex(line, secret)
+	check := hexCheck(secret)
+	line[n] = '-'
+	copy(line[n+1:], check[:])
+	line[len(line)-1] = '\n'
+	_, err := w.Write(line)
+	return err
+}
+
+// decodeMasterHex разбирает мастер-сид в hex без суффикса или с суффиксом
+// "-XY" из writeCheckedSecretLine. ok сообщает, что вход похож на hex
+// мастер-сида; несовпадение суффикса - ошибка, а не повод пробовать другие форматы.
+func decodeMasterHex(s []byte) (master []byte, ok bool, err error) {
+	size := hex.EncodedLen(masterSeedSize)
+	var check []byte
+	if len(s) == size+1+hexCheckSize && s[size] == '-' {
+		s, check = s[:size], s[size+1:]
+	}
+	if len(s) != size {
+		return nil, false, nil
+	}
+	master = newSecret(masterSeedSize)
+	if _, err := decodeHex(master, s); err != nil {
+		wipe(master)
+		return nil, false, nil
+	}
+	if check == nil {
+		return master, true, nil
+	}
+	want := hexCheck(master)
+	got := []byte{normalizeCrockford(check[0]), normalizeCrockford(check[1])}
+	if !bytes.Equal(got, want[:]) {
+		wipe(master)
+		return nil, true, errorf("контрольные символы %s не совпадают: мастер-сид переписан с ошибкой", check)
+	}
+	return master, true, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// clipboardMarker - файл сеанса с хэшем значения, помещенного в буфер обмена.
+// По нему wipe очищает буфер, только если там все еще наше значение.
+const clipboardMarker = "clipboard.sha256"
+
+// clipboardTool описывает внешние команды работы с буфером обмена
+type clipboardTool struct {
+	copy  []string
+	paste []string
+	clear []string
+}
+
+// clipboardTools возвращает подходящие для платформы команды в порядке предпочтения
+func clipboardTools() []clipboardTool {
+	switch runtime.GOOS {
+	case "darwin":
+		return []clipboardTool{{copy: []string{"pbcopy"}, paste: []string{"pbpaste"}}}
+	case "windows":
+		ps := []string{"powershell", "-NoProfile", "-NonInteractive", "-Command"}
+		return []clipboardTool{{
+			copy:  append(ps, "Set-Clipboard -Value ([Console]::In.ReadToEnd())"),
+			paste: append(ps, "Get-Clipboard -Raw"),
+			clear: append(ps, "Set-Clipboard -Value $null"),
+		}}
+	}
+	var tools []clipboardTool
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		tools = append(tools, clipboardTool{
+			copy:  []string{"wl-copy"},
+			paste: []string{"wl-paste", "--no-newline"},
+			clear: []string{"wl-copy", "--clear"},
+		})
+	}
+	return append(tools,
+		clipboardTool{
+			copy:  []string{"xclip", "-selection", "clipboard"},
+			paste: []string{"xclip", "-selection", "clipboard", "-o"},
+		},
+		clipboardTool{
+			copy:  []string{"xsel", "--clipboard", "--input"},
+			paste: []string{"xsel", "--clipboard", "--output"},
+			clear: []string{"xsel", "--clipboard", "--clear"},
+		},
+	)
+}
+
+// findClipboardTool возвращает первый установленный инструмент
+func findClipboardTool() (clipboardTool, error) {
+	for _, t := range clipboardTools() {
+		if _, err := exec.LookPath(t.copy[0]); err == nil {
+			return t, nil
+		}
+	}
+	return clipboardTool{}, errorf("не найдена программа для работы с буфером обмена (pbcopy, wl-copy, xclip или xsel)")
+}
+
+// runClipboard выполняет команду, передавая input на stdin
+func runClipboard(args []string, input []byte) ([]byte, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, errorf("%s: %w", args[0], err)
+	}
+	return out.Bytes(), nil
+}
+
+// clipboardHash возвращает хэш значения буфера без учета перевода строки в конце
+func clipboardHash(b []byte) string {
+	sum := sha256.Sum256(bytes.TrimRight(b, "\r\n"))
+	return hex.EncodeToString(sum[:])
+}
+
+// copyToClipboard помещает значение в буфер обмена и запоминает это в сеансе
+func copyToClipboard(value []byte) error {
+	tool, err := findClipboardTool()
+	if err != nil {
+		return err
+	}
+	if _, err := runClipboard(tool.copy, value); err != nil {
+		return err
+	}
+	return writeSessionFile(clipboardMarker, []byte(clipboardHash(value)))
+}
+
+// clearOwnClipboard очищает буфер обмена, если в нем значение, помещенное
+// seedgen. Возвращает false, если очищать было нечего.
+func clearOwnClipboard() (bool, error) {
+	dir, err := sessionDir()
+	if err != nil {
+		return false, err
+	}
+	marker := filepath.Join(dir, clipboardMarker)
+	want, err := os.ReadFile(marker)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	tool, err := findClipboardTool()
+	if err != nil {
+		return false, err
+	}
+	current, err := runClipboard(tool.paste, nil)
+	if err != nil {
+		return false, err
+	}
+	defer wipe(current)
+	if !secretEqualString(clipboardHash(current), strings.TrimSpace(string(want))) {
+		// Пользователь уже скопировал что-то свое - не трогаем
+		return false, os.Remove(marker)
+	}
+
+	if tool.clear != nil {
+		_, err = runClipboard(tool.clear, nil)
+	} else {
+		_, err = runClipboard(tool.copy, nil)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, os.Remove(marker)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// noColorFlag - общий флаг, отключающий цвет
+const noColorFlag = "no-color"
+
+// Стили выделения (параметры SGR)
+const (
+	styleBold  = "1"
+	styleOK    = "32"
+	styleWarn  = "33"
+	styleError = "1;31"
+)
+
+// colors сообщает, выделяется ли цветом вывод в stdout и в stderr. Цвет
+// только дублирует смысл: предупреждения и ошибки остаются со значками
+// ⚠ и ❌, отпечатки - с подписью, поэтому без цвета ничего не теряется.
+var colors struct {
+	stdout, stderr bool
+}
+
+// setColor включает цвет для потоков, подключенных к терминалу. Цвет
+// отключают --no-color, SEEDGEN_NO_COLOR, непустая NO_COLOR
+// (https://no-color.org), TERM=dumb и журнал в формате JSON.
+func setColor(disabled bool) {
+	if disabled || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return
+	}
+	colors.stdout = isTerminal(os.Stdout) && enableColor(os.Stdout)
+	colors.stderr = !logger.json && isTerminal(os.Stderr) && enableColor(os.Stderr)
+}
+
+// paint выделяет текст стилем style, если on. Переводы строк по краям
+// остаются снаружи выделения, чтобы оно не переходило на соседние строки.
+func paint(on bool, style, s string) string {
+	body := strings.TrimRight(s, "\n")
+	start := len(body) - len(strings.TrimLeft(body, "\n"))
+	if !on || start == len(body) {
+		return s
+	}
+	return body[:start] + "\x1b[" + style + "m" + body[start:] + "\x1b[0m" + s[len(body):]
+}
+
+// emph выделяет жирным значение в stdout: отпечатки и хеши для сверки
+func emph(s string) string {
+	return paint(colors.stdout, styleBold, s)
+}
+
+// emphErr выделяет жирным значение в уведомлении в stderr
+func emphErr(s string) string {
+	return paint(colors.stderr, styleBold, s)
+}
+
+// writeEmphSecretLine выводит мастер-сид с контрольным суффиксом жирным.
+// Строка собирается в затираемом буфере, поэтому выделение пишется отдельно.
+func writeEmphSecretLine(w io.Writer, secret []byte) error {
+	if !colors.stdout {
+		return writeCheckedSecretLine(w, secret)
+	}
+	io.WriteString(w, "\x1b["+styleBold+"m")
+	err := writeCheckedSecretLine(w, secret)
+	io.WriteString(w, "\x1b[0m")
+	return err
+}
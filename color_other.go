@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import "os"
+
+// enableColor сообщает, что терминал понимает escape-последовательности:
+// вне Windows для этого ничего включать не нужно
+func enableColor(f *os.File) bool {
+	return true
+}
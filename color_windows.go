@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"unsafe"
+)
+
+// consoleVirtualTerminal - флаг ENABLE_VIRTUAL_TERMINAL_PROCESSING режима консоли
+const consoleVirtualTerminal = 0x4
+
+// enableColor включает в консоли Windows разбор escape-последовательностей.
+// Старые консоли его не поддерживают - тогда вывод остается без цвета.
+func enableColor(f *os.File) bool {
+	handle := f.Fd()
+	var mode uint32
+	if ok, _, _ := procGetConsoleMode.Call(handle, uintptr(unsafe.Pointer(&mode))); ok == 0 {
+		return false
+	}
+	if mode&consoleVirtualTerminal != 0 {
+		return true
+	}
+	ok, _, _ := procSetConsoleMode.Call(handle, uintptr(mode|consoleVirtualTerminal))
+	return ok != 0
+}
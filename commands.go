@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// command описывает подкоманду seedgen
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands перечисляет подкоманды в порядке вывода в справке.
+// Список заполняется в init, потому что часть команд сама к нему обращается.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"generate", "интерактивный ввод сидов и вывод мастер-сида (по умолчанию)", runGenerate},
+		{"selftest", "проверка реализации на встроенных тестовых векторах", runSelftest},
+		{"bench", "замер скорости и памяти KDF и хэшей", runBench},
+		{"newseed", "генерация новых сидов устройств", runNewSeed},
+		{"mix", "мастер-сид с добавлением системной энтропии", runMix},
+		{"inspect", "просмотр метаданных артефактов без раскрытия секретов", runInspect},
+		{"convert", "перекодирование сида между представлениями", runConvert},
+		{"rotate", "ротация мастер-сида на новую эпоху с картой замены ключей", runRotate},
+		{"completion", "скрипт дополнения для bash, zsh, fish или powershell", runCompletion},
+		{"version", "версия сборки и идентификаторы алгоритмов", runVersion},
+		{"config", "заготовка файла конфигурации и каталоги seedgen", runConfig},
+		{"audit", "церемония с подписанным протоколом для архива", runAudit},
+		{"ceremony", "распределенная церемония по сети с взаимной аутентификацией TLS", runCeremony},
+		{"verify-receipt", "проверка подписанной квитанции о результате", runVerifyReceipt},
+		{"timestamp", "отправка запроса метки времени RFC 3161 и проверка метки", runTimestamp},
+		{"operators", "реестр операторов, подтверждающих вывод результата", runOperators},
+		{"handoff", "перенос запроса и результата между машиной с сетью и офлайн-машиной через QR-коды", runHandoff},
+		{"integrity", "запечатывание бинарника и проверка его целостности", runIntegrity},
+		{"unseal", "распечатывание мастер-сида из TPM", runUnseal},
+		{"recover", "восстановление мастер-сида из поврежденной бумажной копии rs1", runRecover},
+		{"backup-kit", "комплект хранителей: доли мастер-сида 2 из 3 на листах для печати и их сборка", runBackupKit},
+		{"drill", "учебное восстановление из долей или сидов со сверкой только по отпечатку, без показа секретов", runDrill},
+		{"seed-xor", "XOR-доли сида устройства для хранения в разных местах и сборка сида из них", runSeedXOR},
+		{"wipe", "удаление файлов сеанса, очистка буфера обмена и затирание файлов", runWipe},
+		{"derive", "вывод ключей и идентификаторов из мастер-сида", runDerive},
+		{"check-compat", "счета и адреса для сверки с аппаратным кошельком trezor, ledger или keystone", runCheckCompat},
+		{"bracket", "детерминированная сетка турнира на выбывание из мастер-сида", runBracket},
+		{"redraw", "пережеребьевка опубликованной сетки после снятия участников", runRedraw},
+		{"draw", "жеребьевка групп по корзинам и взвешенная лотерея из мастер-сида", runDraw},
+		{"verify-draw", "независимая проверка опубликованной жеребьевки: повтор команд из раскрытия", runVerifyDraw},
+		{"schedule", "календарь кругового турнира из мастер-сида", runSchedule},
+		{"pair", "пары тура по швейцарской системе из мастер-сида", runPair},
+		{"assign", "назначение судей и площадок на матчи с ограничениями из мастер-сида", runAssign},
+		{"shuffle", "проверяемое перемешивание списка участников из мастер-сида", runShuffle},
+		{"tournament", "многоэтапный турнир по описанию: группы, календари и плей-офф из мастер-сида", runTournament},
+		{"tiebreak", "жребий вместо монеты при равенстве, привязанный к поводу, из мастер-сида", runTiebreak},
+		{"seed-order", "посев по рейтингу с жребием при равенстве из мастер-сида", runSeedOrder},
+	}
+}
+
+// findCommand ищет подкоманду по имени
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// printUsage выводит список подкоманд
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, tr("Использование: seedgen [команда] [флаги]"))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, tr("Без команды запускается интерактивный ввод сидов."))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, tr("Команды:"))
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, tr(cmd.summary))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, tr("Общие флаги:"))
+	fmt.Fprintf(w, "  --%-18s %s\n", allowCoreDumpsFlag, tr("разрешить дампы памяти и отладчик (только для отладки)"))
+	fmt.Fprintf(w, "  --%-18s %s\n", auditLogFlag, tr("дописывать запуски в файл журнала аудита с цепочкой хэшей; секреты не пишутся"))
+	fmt.Fprintf(w, "  --%-18s %s\n", langFlag+" ru|en", tr("язык сообщений; по умолчанию из LC_ALL, LC_MESSAGES или LANG, иначе ru"))
+	fmt.Fprintf(w, "  --%-18s %s\n", verboseFlag, tr("подробный журнал диагностики в stderr"))
+	fmt.Fprintf(w, "  --%-18s %s\n", quietFlag, tr("только предупреждения и ошибки в stderr"))
+	fmt.Fprintf(w, "  --%-18s %s\n", logFormatFlag, tr("формат журнала: text или json; секреты в журнал не пишутся"))
+	fmt.Fprintf(w, "  --%-18s %s\n", noColorFlag, tr("без цвета; также NO_COLOR или вывод не в терминал"))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, tr("Справка по команде: seedgen <команда> -h"))
+}
+
+// runCommand выполняет подкоманду и возвращает код завершения
+func runCommand(args []string) int {
+	name := args[0]
+	switch name {
+	case "help", "-h", "--help":
+		printUsage(os.Stdout)
+		return 0
+	case completeCommand:
+		// Скрытая команда, которую вызывают скрипты дополнения
+		for _, c := range complete(args[1:]) {
+			fmt.Println(c)
+		}
+		return 0
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, tr("Неизвестная команда: %s\n\n"), name)
+		printUsage(os.Stderr)
+		return int(exitInput)
+	}
+
+	logDebug("запуск команды", "command", name, "version", version, "lang", lang)
+	if err := cmd.run(args[1:]); err != nil {
+		// Справка по флагам уже выведена пакетом flag
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		code := classifyError(err)
+		logFailure(err, code)
+		return int(code)
+	}
+	return int(exitOK)
+}
+
+// flagSetHook, если задан, получает каждый созданный набор флагов.
+// Через него дополнение узнает флаги команды, не выполняя ее.
+var flagSetHook func(fs *flag.FlagSet)
+
+// newFlagSet создает набор флагов подкоманды
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("seedgen "+name, flag.ContinueOnError)
+	if flagSetHook != nil {
+		fs.SetOutput(io.Discard)
+		flagSetHook(fs)
+	}
+	// Подсказки флагов переводятся при выводе справки: к этому моменту
+	// команда уже объявила все флаги
+	fs.Usage = func() {
+		flagUsageShown = true
+		fs.VisitAll(func(f *flag.Flag) { f.Usage = tr(f.Usage) })
+		fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags разбирает флаги, допуская их после позиционных аргументов
+// (seedgen inspect result.json --reveal)
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, applyEnv(fs)
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// commitmentWords - число слов BIP39 в обязательстве (по 11 бит)
+const commitmentWords = 4
+
+// seedSetCommitment возвращает обязательство набора сидов для сверки вслух:
+// слова BIP39 и 16 hex-символов. Оно вычисляется из мастер-сида, то есть
+// после растягивания: хэш самих сидов позволил бы перебирать слабые сиды
+// в обход KDF. Мастер-сид зависит от соли и эпохи церемонии, поэтому и
+// обязательство у каждой церемонии свое; сиды и мастер-сид по нему не восстановить.
+func seedSetCommitment(master []byte) (words []string, digest string) {
+	mac := hmac.New(sha256.New, master)
+	mac.Write([]byte("seedgen/seed-set-commitment"))
+	sum := mac.Sum(nil)
+
+	var acc, bits uint
+	for _, b := range sum {
+		acc = acc<<8 | uint(b)
+		bits += 8
+		if bits >= 11 {
+			bits -= 11
+			words = append(words, bip39Words[acc>>bits&0x7ff])
+			if len(words) == commitmentWords {
+				break
+			}
+		}
+	}
+	return words, hex.EncodeToString(sum[:8])
+}
+
+// printSeedCommitment выводит обязательство до показа мастер-сида, чтобы
+// операторы на разных машинах убедились, что ввели одинаковые сиды
+func printSeedCommitment(w io.Writer, master []byte) {
+	words, digest := seedSetCommitment(master)
+	auditResult("seed-set", "", digest)
+	fmt.Fprintln(w, tr("Обязательство набора сидов (сверьте вслух с другими операторами до показа результата):"))
+	fmt.Fprintf(w, "  %s  (%s)\n\n", strings.Join(words, " "), digest)
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// completeCommand - скрытая команда, возвращающая варианты дополнения.
+// Скрипты передают ей слова командной строки после "seedgen", а текущее
+// (возможно, пустое) слово - последним аргументом с префиксом "+".
+const completeCommand = "__complete"
+
+// completeFiles в ответе просит скрипт дополнить имя файла средствами оболочки
+const completeFiles = ":files"
+
+// completionScripts содержит скрипты дополнения для поддерживаемых оболочек
+var completionScripts = map[string]string{
+	"bash": `# bash-дополнение для seedgen. Подключение: source <(seedgen completion bash)
+_seedgen() {
+    local IFS=$'\n' cur="${COMP_WORDS[COMP_CWORD]}"
+    local out=($(seedgen __complete "${COMP_WORDS[@]:1:COMP_CWORD-1}" "+${cur}" 2>/dev/null))
+    if [[ "${out[0]}" == ":files" ]]; then
+        [[ "$cur" == "=" ]] && cur=""
+        COMPREPLY=($(compgen -f -- "$cur"))
+    else
+        COMPREPLY=("${out[@]}")
+    fi
+}
+complete -o filenames -F _seedgen seedgen
+`,
+	"zsh": `#compdef seedgen
+# zsh-дополнение для seedgen. Подключение: source <(seedgen completion zsh)
+_seedgen() {
+    local -a out
+    out=(${(f)"$(seedgen __complete "${(@)words[2,CURRENT-1]}" "+${words[CURRENT]}" 2>/dev/null)"})
+    if [[ "${out[1]}" == ":files" ]]; then
+        _files
+    else
+        compadd -a out
+    fi
+}
+compdef _seedgen seedgen
+`,
+	"fish": `# fish-дополнение для seedgen. Подключение: seedgen completion fish | source
+function __seedgen_complete
+    set -l args (commandline -opc)
+    set -e args[1]
+    set -l cur (commandline -ct)
+    set -l out (seedgen __complete $args "+$cur" 2>/dev/null)
+    if test "$out[1]" = ":files"
+        __fish_complete_path $cur
+    else
+        printf '%s\n' $out
+    end
+end
+complete -c seedgen -f -a '(__seedgen_complete)'
+`,
+	"powershell": `# Дополнение PowerShell для seedgen. Подключение: seedgen completion powershell | Out-String | Invoke-Expression
+Register-ArgumentCompleter -Native -CommandName seedgen -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -ne '' -and $words.Count -gt 0) { $words = @($words | Select-Object -SkipLast 1) }
+    $out = @(seedgen __complete @words "+$wordToComplete" 2>$null)
+    if ($out.Count -gt 0 -and $out[0] -eq ':files') {
+        Get-ChildItem -Path "$wordToComplete*" -ErrorAction SilentlyContinue | ForEach-Object {
+            [System.Management.Automation.CompletionResult]::new($_.Name, $_.Name, 'ProviderItem', $_.Name)
+        }
+        return
+    }
+    $out | ForEach-Object { [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_) }
+}
+`,
+}
+
+// completionShells возвращает поддерживаемые оболочки в алфавитном порядке
+func completionShells() []string {
+	shells := make([]string, 0, len(completionScripts))
+	for name := range completionScripts {
+		shells = append(shells, name)
+	}
+	sort.Strings(shells)
+	return shells
+}
+
+// runCompletion выводит скрипт дополнения для оболочки
+func runCompletion(args []string) error {
+	fs := newFlagSet("completion")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errorf("укажите оболочку: %s", strings.Join(completionShells(), ", "))
+	}
+	script, ok := completionScripts[positional[0]]
+	if !ok {
+		return errorf("неизвестная оболочка %q, поддерживаются: %s", positional[0], strings.Join(completionShells(), ", "))
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// subcommands перечисляет действия команд, которые их поддерживают
+var subcommands = map[string][]string{
+	"audit":      {"keygen", "attest", "run", "cosign", "verify", "proof", "log"},
+	"ceremony":   {"coordinate", "join"},
+	"derive":     deriveKindNames(),
+	"draw":       {"groups", "lottery", "commit", "reveal", "verify"},
+	"schedule":   {"roundrobin", "export"},
+	"pair":       {"swiss"},
+	"handoff":    {"export-request", "respond", "import-response"},
+	"integrity":  {"seal", "verify"},
+	"config":     {"init", "path"},
+	"timestamp":  {"submit", "verify"},
+	"operators":  {"add", "remove", "list"},
+	"backup-kit": {"create", "combine"},
+	"seed-xor":   {"split", "join"},
+}
+
+// commandFlags возвращает набор флагов команды (и действия, если есть).
+// Команда запускается с -h: все команды разбирают флаги до любых действий,
+// поэтому она лишь регистрирует флаги и сразу завершается с flag.ErrHelp.
+func commandFlags(cmd command, action []string) *flag.FlagSet {
+	var captured *flag.FlagSet
+	flagSetHook = func(fs *flag.FlagSet) {
+		if captured == nil {
+			captured = fs
+		}
+	}
+	defer func() { flagSetHook = nil }()
+	cmd.run(append(append([]string{}, action...), "-h"))
+	return captured
+}
+
+// isBoolFlag сообщает, что флаг не принимает значения
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// fileFlags - флаги, значением которых является путь к файлу или каталогу
+var fileFlags = map[string]bool{
+	"registry": true, "config": true, "key": true, "out": true, "pubkey": true, "master": true,
+	"keystore": true, "password-file": true, "manifest": true, "sign-with": true, "receipt": true,
+	"params-file": true, "params-out": true, "minisign-key": true, "signature": true,
+	"seal-tpm": true, "store-dpapi": true, "seed-file": true, "transcript": true, "proofs": true,
+	"cert": true, "ca": true, "attestation": true, "frost-share": true, "timestamp": true,
+	"request": true, "drand": true, "nist-pulse": true, "operators": true, "ssh-key": true,
+	"transcript-key": true, "cosigner": true, "session": true, "commitments": true,
+	"participants": true, "pots": true, "standings": true, "in": true, "bundle": true, "verify": true,
+	"weights": true, "rankings": true, "input": true, "result": true, "commitment": true,
+	"reveal": true, "extract": true, "roster": true, "matches": true, "officials": true,
+	"constraints": true, "events": true, "schedule": true, "venues": true, "definition": true,
+	"file": true,
+}
+
+// flagChoices возвращает допустимые значения флага команды;
+// files означает, что значением флага является путь к файлу
+func flagChoices(cmdName, flagName string, words []string) (choices []string, files bool) {
+	if fileFlags[flagName] {
+		return nil, true
+	}
+	switch flagName {
+	case "scheme":
+		return []string{"v1", "v2", "v3"}, false
+	case "compat":
+		return []string{"v1"}, false
+	case "kdf":
+		return []string{kdfPBKDF2, kdfArgon2id}, false
+	case "sides":
+		return []string{"6", "20"}, false
+	case "purpose":
+		return []string{"44", "49", "84"}, false
+	case "from", "to":
+		return seedEncodingNames(), false
+	case "profile":
+		return profileNames(words), false
+	case "constraint":
+		return drawConstraintNames(), false
+	case "render":
+		return []string{"svg", "html"}, false
+	case "dpapi-scope":
+		return []string{"user", "machine"}, false
+	case "keyring":
+		return []string{"session", "user"}, false
+	case "method":
+		return []string{"shares", "seeds"}, false
+	case "device":
+		return compatDeviceNames(), false
+	case "preset":
+		return kdfPresetNames(), false
+	case "group-size":
+		return []string{"4", "5", "6", "7", "8"}, false
+	case "format":
+		switch cmdName {
+		case "newseed":
+			return seedEncodingNames(), false
+		case "generate", "mix":
+			return []string{"text", "json", "msv2", "rs", k8sSecretFormat}, false
+		case "recover", "backup-kit combine":
+			return []string{"hex", "rs"}, false
+		case "backup-kit create":
+			return []string{"text", "html"}, false
+		case "schedule roundrobin", "pair swiss", "assign":
+			return []string{"text", "csv", "json"}, false
+		case "seed-order":
+			return []string{"text", "csv", "json", "list"}, false
+		case "derive ed25519":
+			return []string{"pem", "hex"}, false
+		case "derive key":
+			return []string{"hex", "base64"}, false
+		case "derive tournament":
+			return []string{"hex", "json", "rs"}, false
+		case "rotate", "drill", "derive btc", "derive eth", "check-compat", "derive wireguard", "derive nostr", "bracket", "redraw", "draw groups", "draw lottery", "shuffle", "tiebreak":
+			return []string{"text", "json"}, false
+		}
+	}
+	return nil, false
+}
+
+// profileNames возвращает имена профилей из конфигурации, указанной
+// в командной строке через --config, или из файла по умолчанию
+func profileNames(words []string) []string {
+	path := ""
+	for i, w := range words {
+		switch {
+		case (w == "--config" || w == "-config") && i+1 < len(words):
+			path = words[i+1]
+		case strings.HasPrefix(w, "--config="):
+			path = strings.TrimPrefix(w, "--config=")
+		}
+	}
+	if path == "" {
+		var err error
+		if path, err = configPath(); err != nil {
+			return nil
+		}
+	}
+	profiles, err := loadProfiles(path)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withPrefix оставляет варианты, начинающиеся с prefix
+func withPrefix(candidates []string, prefix string) []string {
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// complete возвращает варианты дополнения для текущего слова
+func complete(args []string) []string {
+	if len(args) == 0 || !strings.HasPrefix(args[len(args)-1], "+") {
+		return nil
+	}
+	cur := strings.TrimPrefix(args[len(args)-1], "+")
+	words := args[:len(args)-1]
+
+	if len(words) == 0 {
+		names := []string{"help"}
+		for _, cmd := range commands {
+			names = append(names, cmd.name)
+		}
+		return withPrefix(names, cur)
+	}
+
+	cmd, ok := findCommand(words[0])
+	if !ok {
+		return nil
+	}
+	var action []string
+	if actions, ok := subcommands[cmd.name]; ok {
+		if len(words) == 1 {
+			return withPrefix(actions, cur)
+		}
+		action = words[1:2]
+	}
+	fs := commandFlags(cmd, action)
+	if fs == nil {
+		return nil
+	}
+
+	values := func(name, prefix, valuePrefix string) []string {
+		choices, files := flagChoices(strings.Join(append([]string{cmd.name}, action...), " "), name, words)
+		if files {
+			return []string{completeFiles}
+		}
+		var out []string
+		for _, c := range withPrefix(choices, valuePrefix) {
+			out = append(out, prefix+c)
+		}
+		return out
+	}
+	// lookupValueFlag находит флаг, ожидающий значение, по слову вида --name
+	lookupValueFlag := func(w string) *flag.Flag {
+		if !strings.HasPrefix(w, "-") || strings.Contains(w, "=") {
+			return nil
+		}
+		f := fs.Lookup(strings.TrimLeft(w, "-"))
+		if f == nil || isBoolFlag(f) {
+			return nil
+		}
+		return f
+	}
+
+	// bash отделяет "=" в "--format=json" в отдельное слово
+	if n := len(words); cur == "=" && n >= 2 {
+		if f := lookupValueFlag(words[n-1]); f != nil {
+			return values(f.Name, "", "")
+		}
+	}
+	if n := len(words); n >= 3 && words[n-1] == "=" {
+		if f := lookupValueFlag(words[n-2]); f != nil {
+			return values(f.Name, "", cur)
+		}
+	}
+	if n := len(words); n >= 2 {
+		if f := lookupValueFlag(words[n-1]); f != nil {
+			return values(f.Name, "", cur)
+		}
+	}
+
+	if strings.HasPrefix(cur, "-") {
+		if eq := strings.IndexByte(cur, '='); eq >= 0 {
+			return values(strings.TrimLeft(cur[:eq], "-"), cur[:eq+1], cur[eq+1:])
+		}
+		var names []string
+		fs.VisitAll(func(f *flag.Flag) { names = append(names, "--"+f.Name) })
+		return withPrefix(names, cur)
+	}
+
+	// Позиционные аргументы
+	switch cmd.name {
+	case "completion":
+		return withPrefix(completionShells(), cur)
+	case "inspect", "wipe", "verify-receipt", "unseal", "recover", "drill":
+		return []string{completeFiles}
+	case "audit":
+		if action[0] == "verify" || action[0] == "proof" {
+			return []string{completeFiles}
+		}
+	case "backup-kit":
+		if action[0] == "combine" {
+			return []string{completeFiles}
+		}
+	case "seed-xor":
+		if action[0] == "join" {
+			return []string{completeFiles}
+		}
+	case "integrity":
+		if action[0] == "seal" {
+			return []string{completeFiles}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// sharedProfileKeys перечисляет параметры, которые профиль задает сразу всем
+// командам с таким флагом. Остальные флаги (например, --format, чье значение
+// у каждой команды свое) задаются в таблице команды [profiles.<имя>.<команда>].
+var sharedProfileKeys = map[string]bool{
+	"scheme":         true,
+	"epoch":          true,
+	"kdf":            true,
+	"iterations":     true,
+	"memory":         true,
+	"parallelism":    true,
+	"argon2-time":    true,
+	"argon2-memory":  true,
+	"argon2-threads": true,
+	"salt":           true,
+	"registry":       true,
+}
+
+// profile - именованный набор значений флагов
+type profile struct {
+	values   map[string]string
+	commands map[string]map[string]string
+}
+
+// configPath возвращает путь к файлу конфигурации: $SEEDGEN_CONFIG или
+// config.toml в каталоге конфигурации. В Windows и macOS прежний
+// ~/.config/seedgen/config.toml используется, пока нового файла нет.
+func configPath() (string, error) {
+	if path := os.Getenv("SEEDGEN_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "config.toml")
+	if os.Getenv("XDG_CONFIG_HOME") == "" && runtime.GOOS != "linux" {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if home, err := os.UserHomeDir(); err == nil {
+				legacy := filepath.Join(home, ".config", appDir, "config.toml")
+				if _, err := os.Stat(legacy); err == nil {
+					return legacy, nil
+				}
+			}
+		}
+	}
+	return path, nil
+}
+
+// loadProfiles читает профили из файла конфигурации
+func loadProfiles(path string) (map[string]*profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := parseTOML(f)
+	if err != nil {
+		return nil, errorf("%s: %w", path, err)
+	}
+
+	profiles := make(map[string]*profile)
+	for _, e := range entries {
+		if len(e.table) < 2 || len(e.table) > 3 || e.table[0] != "profiles" {
+			return nil, errorf("%s: строка %d: параметры задаются только в таблицах [profiles.<имя>] и [profiles.<имя>.<команда>]", path, e.line)
+		}
+		p := profiles[e.table[1]]
+		if p == nil {
+			p = &profile{values: make(map[string]string), commands: make(map[string]map[string]string)}
+			profiles[e.table[1]] = p
+		}
+
+		if len(e.table) == 2 {
+			if !sharedProfileKeys[e.key] {
+				return nil, errorf("%s: строка %d: параметр %q нельзя задать для всех команд, укажите его в таблице [profiles.%s.<команда>]", path, e.line, e.key, e.table[1])
+			}
+			p.values[e.key] = e.value
+			continue
+		}
+
+		name := e.table[2]
+		if _, ok := findCommand(name); !ok {
+			return nil, errorf("%s: строка %d: неизвестная команда %q", path, e.line, name)
+		}
+		if p.commands[name] == nil {
+			p.commands[name] = make(map[string]string)
+		}
+		p.commands[name][e.key] = e.value
+	}
+	return profiles, nil
+}
+
+// profileFlags - флаги выбора профиля
+type profileFlags struct {
+	name   *string
+	config *string
+}
+
+// addProfileFlags регистрирует флаги --profile и --config в наборе
+func addProfileFlags(fs *flag.FlagSet) *profileFlags {
+	return &profileFlags{
+		name:   fs.String("profile", "", "именованный профиль из файла конфигурации"),
+		config: fs.String("config", "", "файл конфигурации (по умолчанию - см. seedgen config path)"),
+	}
+}
+
+// apply подставляет значения профиля во флаги, не заданные в командной строке
+func (pf *profileFlags) apply(fs *flag.FlagSet) error {
+	if *pf.name == "" {
+		return nil
+	}
+
+	path := *pf.config
+	if path == "" {
+		var err error
+		if path, err = configPath(); err != nil {
+			return err
+		}
+	}
+	profiles, err := loadProfiles(path)
+	if err != nil {
+		return errorf("ошибка чтения конфигурации: %w", err)
+	}
+	p, ok := profiles[*pf.name]
+	if !ok {
+		return errorf("профиль %q не найден в %s", *pf.name, path)
+	}
+
+	// Флаги командной строки и окружения важнее профиля, таблица команды важнее общих параметров
+	explicit := setFlags(fs)
+	cmdName := strings.TrimPrefix(fs.Name(), "seedgen ")
+	set := func(values map[string]string, strict bool) error {
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if fs.Lookup(k) == nil {
+				if strict {
+					return errorf("профиль %q: у команды %s нет флага --%s", *pf.name, cmdName, k)
+				}
+				continue
+			}
+			if explicit[k] {
+				continue
+			}
+			if err := fs.Set(k, values[k]); err != nil {
+				return errorf("профиль %q: --%s: %w", *pf.name, k, err)
+			}
+		}
+		return nil
+	}
+	if err := set(p.values, false); err != nil {
+		return err
+	}
+	if err := set(p.commands[cmdName], true); err != nil {
+		return err
+	}
+
+	logInfof("✓ Профиль %q из %s\n", *pf.name, path)
+	return nil
+}
+
+// defaultConfig - заготовка файла конфигурации для config init
+const defaultConfig = `# Конфигурация seedgen: именованные профили флагов.
+#
+# Профиль выбирается флагом --profile ИМЯ и подставляет значения во флаги,
+# не указанные в командной строке. Поддерживается подмножество TOML:
+# таблицы, строки, целые числа и true/false. Неизвестные параметры и
+# команды считаются ошибкой.
+#
+# В таблице [profiles.ИМЯ] задаются параметры, общие для всех команд:
+# scheme, epoch, kdf, iterations, memory, parallelism, argon2-time,
+# argon2-memory, argon2-threads, salt, registry.
+#
+# [profiles.ceremony]
+# scheme = "v2"
+# epoch = 1
+# kdf = "argon2id"
+# iterations = 4
+# memory = 262_144   # 256 МиБ
+#
+# Флаги, значение которых у команд разное, задаются в таблице команды
+# [profiles.ИМЯ.КОМАНДА]:
+#
+# [profiles.ceremony.generate]
+# format = "json"
+#
+# [profiles.ceremony.newseed]
+# format = "mnemonic"
+`
+
+// runConfig управляет файлом конфигурации
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, tr("Использование:"))
+		fmt.Fprintln(os.Stderr, "  seedgen config init [--config FILE] [--force]")
+		fmt.Fprintln(os.Stderr, "  seedgen config path")
+		return errorf("укажите действие: init или path")
+	}
+	switch args[0] {
+	case "init":
+		return runConfigInit(args[1:])
+	case "path":
+		return runConfigPath(args[1:])
+	}
+	return errorf("неизвестное действие %q, доступны: init, path", args[0])
+}
+
+// runConfigInit записывает заготовку конфигурации с комментариями
+func runConfigInit(args []string) error {
+	fs := newFlagSet("config init")
+	config := fs.String("config", "", "файл конфигурации (по умолчанию - см. seedgen config path)")
+	force := fs.Bool("force", false, "перезаписать существующий файл")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	path := *config
+	if path == "" {
+		var err error
+		if path, err = configPath(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errorf("ошибка создания каталога конфигурации: %w", err)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !*force {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return errorf("%s уже существует, укажите --force, чтобы перезаписать", path)
+	}
+	if err != nil {
+		return errorf("ошибка создания конфигурации: %w", err)
+	}
+	if _, err := f.WriteString(defaultConfig); err != nil {
+		f.Close()
+		return errorf("ошибка записи конфигурации: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return errorf("ошибка записи конфигурации: %w", err)
+	}
+	fmt.Printf(tr("✓ Заготовка конфигурации записана: %s\n"), path)
+	return nil
+}
+
+// runConfigPath выводит каталоги, которые использует seedgen
+func runConfigPath(args []string) error {
+	fs := newFlagSet("config path")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	config, err := configPath()
+	if err != nil {
+		return err
+	}
+	session, err := sessionDir()
+	if err != nil {
+		return err
+	}
+	audit, err := auditDir()
+	if err != nil {
+		return err
+	}
+	fmt.Printf(tr("Конфигурация: %s\n"), config)
+	fmt.Printf(tr("Файлы сеанса: %s\n"), session)
+	fmt.Printf(tr("Протоколы audit run: %s\n"), audit)
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+// setupConsole вне Windows ничего не делает: терминал сам задает кодировку
+func setupConsole() func() {
+	return func() {}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "syscall"
+
+// consoleUTF8 - кодовая страница UTF-8 консоли Windows
+const consoleUTF8 = 65001
+
+var (
+	procGetConsoleCP       = syscall.NewLazyDLL("kernel32.dll").NewProc("GetConsoleCP")
+	procSetConsoleCP       = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleCP")
+	procGetConsoleOutputCP = syscall.NewLazyDLL("kernel32.dll").NewProc("GetConsoleOutputCP")
+	procSetConsoleOutputCP = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleOutputCP")
+)
+
+// setupConsole переключает консоль на UTF-8. С кодовой страницей OEM
+// (866 или 437) кириллица и значки ✓, ⚠ и ❌ выводятся кракозябрами.
+// Кодовая страница принадлежит консоли, а не процессу, поэтому
+// возвращаемая функция восстанавливает прежнюю перед выходом.
+func setupConsole() func() {
+	input, _, _ := procGetConsoleCP.Call()
+	output, _, _ := procGetConsoleOutputCP.Call()
+	if input == 0 || output == 0 {
+		// Консоли нет: вывод перенаправлен, Go и так пишет UTF-8
+		return func() {}
+	}
+	procSetConsoleCP.Call(consoleUTF8)
+	procSetConsoleOutputCP.Call(consoleUTF8)
+	return func() {
+		procSetConsoleCP.Call(input)
+		procSetConsoleOutputCP.Call(output)
+	}
+}
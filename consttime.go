@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/subtle"
+)
+
+// Функции этого файла работают с секретами за время, не зависящее от их
+// значений: без ветвлений по данным и без обращений к таблицам по индексу,
+// который мог бы утечь через кэш процессора.
+
+// secretEqual сравнивает секреты или проверочные значения (отпечатки,
+// контрольные суммы) за время, зависящее только от их длины
+func secretEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// secretEqualString - secretEqual для строк, например отпечатков в hex
+func secretEqualString(a, b string) bool {
+	return secretEqual([]byte(a), []byte(b))
+}
+
+// encodeHex кодирует src в dst строчными hex-цифрами и возвращает число
+// записанных байт. В dst должно быть не меньше 2*len(src) байт.
+func encodeHex(dst, src []byte) int {
+	for i, b := range src {
+		dst[2*i] = hexDigit(int(b >> 4))
+		dst[2*i+1] = hexDigit(int(b & 0x0f))
+	}
+	return 2 * len(src)
+}
+
+// decodeHex разбирает hex в любом регистре из src в dst и возвращает
+// число записанных байт. В dst должно быть не меньше len(src)/2 байт.
+// Позиция некорректного символа не сообщается: ее поиск зависел бы от данных.
+func decodeHex(dst, src []byte) (int, error) {
+	if len(src)%2 != 0 {
+		return 0, errorf("некорректная hex-строка: нечетная длина")
+	}
+	valid := 0xff
+	for i := 0; i < len(src)/2; i++ {
+		hi, hiOK := hexValue(src[2*i])
+		lo, loOK := hexValue(src[2*i+1])
+		valid &= hiOK & loOK
+		dst[i] = byte(hi<<4 | lo)
+	}
+	if valid == 0 {
+		return 0, errorf("некорректная hex-строка")
+	}
+	return len(src) / 2, nil
+}
+
+// hexString кодирует секрет в строку hex без таблиц
+func hexString(b []byte) string {
+	out := make([]byte, 2*len(b))
+	encodeHex(out, b)
+	return string(out)
+}
+
+// hexDigit переводит полубайт в '0'-'9' или 'a'-'f'. (9-n)>>8 равно -1
+// только для n > 9, и тогда к '0'+n добавляется разрыв до 'a'.
+func hexDigit(n int) byte {
+	return byte('0' + n + ((9-n)>>8)&('a'-'0'-10))
+}
+
+// hexValue возвращает значение hex-цифры и маску 0xff, если c - цифра, иначе 0
+func hexValue(c byte) (value, ok int) {
+	num := int(c) ^ '0'
+	numOK := ((num - 10) >> 8) & 0xff
+	alpha := int(c&^0x20) - 'A' + 10
+	alphaOK := (((alpha - 10) ^ (alpha - 16)) >> 8) & 0xff
+	return num&numOK | alpha&alphaOK, numOK | alphaOK
+}
+
+// bech32Char возвращает символ алфавита Bech32 для 5-битного значения,
+// просматривая весь алфавит вместо обращения по индексу
+func bech32Char(v byte) byte {
+	var c byte
+	for i := 0; i < len(bech32Charset); i++ {
+		c |= bech32Charset[i] & byte(-subtle.ConstantTimeByteEq(byte(i), v))
+	}
+	return c
+}
+
+// bech32Value находит значение символа Bech32, просматривая весь алфавит
+func bech32Value(c byte) (byte, bool) {
+	var v, found byte
+	for i := 0; i < len(bech32Charset); i++ {
+		mask := byte(-subtle.ConstantTimeByteEq(bech32Charset[i], c))
+		v |= byte(i) & mask
+		found |= mask
+	}
+	return v, found != 0
+}
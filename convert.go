@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// seedBech32HRP - человекочитаемая часть Bech32 для сидов
+const seedBech32HRP = "seed"
+
+// seedEncoding описывает одно представление сида
+type seedEncoding struct {
+	encode func([]byte) (string, error)
+	decode func(string) ([]byte, error)
+	// binary - двоичные данные, а не строка текста: не обрезаются и не
+	// выводятся в терминал
+	binary bool
+}
+
+// seedEncodings перечисляет представления, между которыми работает convert.
+// Все форматы, кроме hex, содержат контрольную сумму и проверяют ее при разборе.
+var seedEncodings = map[string]seedEncoding{
+	"hex": {
+		encode: func(b []byte) (string, error) { return hexString(b), nil },
+		decode: func(s string) ([]byte, error) {
+			b := make([]byte, len(s)/2)
+			if _, err := decodeHex(b, []byte(s)); err != nil {
+				return nil, err
+			}
+			return b, nil
+		},
+	},
+	"mnemonic": {
+		encode: entropyToMnemonic,
+		decode: mnemonicToEntropy,
+	},
+	"base58": {
+		encode: func(b []byte) (string, error) { return base58CheckEncode(b), nil },
+		decode: base58CheckDecode,
+	},
+	"bech32": {
+		encode: func(b []byte) (string, error) { return bech32Encode(seedBech32HRP, b) },
+		decode: func(s string) ([]byte, error) {
+			hrp, data, err := bech32Decode(s)
+			if err != nil {
+				return nil, err
+			}
+			if hrp != seedBech32HRP {
+				return nil, errorf("ожидался префикс %q, получен %q", seedBech32HRP, hrp)
+			}
+			return data, nil
+		},
+	},
+	"seedqr": {
+		encode: encodeSeedQR,
+		decode: decodeSeedQR,
+	},
+	"compactseedqr": {
+		encode: encodeCompactSeedQR,
+		decode: decodeCompactSeedQR,
+		binary: true,
+	},
+}
+
+// seedEncodingNames возвращает отсортированный список форматов
+func seedEncodingNames() []string {
+	names := make([]string, 0, len(seedEncodings))
+	for name := range seedEncodings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// encodeSeed кодирует байты сида в выбранный формат
+func encodeSeed(seed []byte, format string) (string, error) {
+	enc, ok := seedEncodings[format]
+	if !ok {
+		return "", errorf("неизвестный формат %q (доступны: %s)", format, strings.Join(seedEncodingNames(), ", "))
+	}
+	return enc.encode(seed)
+}
+
+// decodeSeed разбирает сид из выбранного формата
+func decodeSeed(s, format string) ([]byte, error) {
+	enc, ok := seedEncodings[format]
+	if !ok {
+		return nil, errorf("неизвестный формат %q (доступны: %s)", format, strings.Join(seedEncodingNames(), ", "))
+	}
+	if enc.binary {
+		return enc.decode(s)
+	}
+	return enc.decode(strings.TrimSpace(s))
+}
+
+// binarySeedEncoding сообщает, что формат двоичный
+func binarySeedEncoding(format string) bool {
+	return seedEncodings[format].binary
+}
+
+// encodeSeedQR строит цифровую полезную нагрузку Standard SeedQR (SeedSigner):
+// индексы слов BIP39 по 4 десятичные цифры подряд
+func encodeSeedQR(entropy []byte) (string, error) {
+	if len(entropy) != 16 && len(entropy) != 32 {
+		return "", errorf("SeedQR поддерживает только 12 или 24 слова (16 или 32 байта), получено %d байт", len(entropy))
+	}
+	phrase, err := entropyToMnemonic(entropy)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, w := range strings.Fields(phrase) {
+		fmt.Fprintf(&sb, "%04d", bip39Index[w])
+	}
+	return sb.String(), nil
+}
+
+// decodeSeedQR разбирает цифровую полезную нагрузку Standard SeedQR
+func decodeSeedQR(s string) ([]byte, error) {
+	if len(s) != 48 && len(s) != 96 {
+		return nil, errorf("SeedQR должен содержать 48 или 96 цифр, получено %d символов", len(s))
+	}
+
+	words := make([]string, 0, len(s)/4)
+	for i := 0; i < len(s); i += 4 {
+		index, err := strconv.Atoi(s[i : i+4])
+		if err != nil || index >= len(bip39Words) {
+			return nil, errorf("группа %q в позиции %d не является индексом слова", s[i:i+4], i+1)
+		}
+		words = append(words, bip39Words[index])
+	}
+	return mnemonicToEntropy(strings.Join(words, " "))
+}
+
+// encodeCompactSeedQR строит полезную нагрузку CompactSeedQR (SeedSigner):
+// сама энтропия BIP39 без контрольной суммы, для QR-кода в байтовом режиме
+func encodeCompactSeedQR(entropy []byte) (string, error) {
+	if len(entropy) != 16 && len(entropy) != 32 {
+		return "", errorf("CompactSeedQR поддерживает только 12 или 24 слова (16 или 32 байта), получено %d байт", len(entropy))
+	}
+	return string(entropy), nil
+}
+
+// decodeCompactSeedQR разбирает двоичную нагрузку CompactSeedQR. Перевод
+// строки, который сканеры вроде zbarimg дописывают после данных, снимается.
+func decodeCompactSeedQR(s string) ([]byte, error) {
+	if (len(s) == 17 || len(s) == 33) && s[len(s)-1] == '\n' {
+		s = s[:len(s)-1]
+	}
+	if len(s) != 16 && len(s) != 32 {
+		return nil, errorf("CompactSeedQR должен содержать 16 или 32 байта, получено %d", len(s))
+	}
+	return []byte(s), nil
+}
+
+// seedQRCode строит QR-код SeedQR так же, как SeedSigner: Standard SeedQR -
+// в цифровом режиме, CompactSeedQR - в байтовом, оба с уровнем коррекции L.
+// Получаются коды 25x25 и 29x29 или 21x21 и 25x25 для 12 и 24 слов.
+func seedQRCode(payload, format string) (*qrCode, error) {
+	switch format {
+	case "seedqr":
+		return encodeQRLevel([]byte(payload), true, qrLevelL)
+	case "compactseedqr":
+		return encodeQRLevel([]byte(payload), false, qrLevelL)
+	}
+	return nil, errorf("QR-код выводится только для форматов seedqr и compactseedqr")
+}
+
+// writeSeedPayload выводит сид в формате: с qr - QR-кодом SeedQR, двоичный
+// формат - как есть и только не в терминал, остальные - строкой
+func writeSeedPayload(payload, format string, qr bool) error {
+	if qr {
+		q, err := seedQRCode(payload, format)
+		if err != nil {
+			return err
+		}
+		q.render(os.Stdout)
+		return nil
+	}
+	if !binarySeedEncoding(format) {
+		fmt.Println(payload)
+		return nil
+	}
+	if isTerminal(os.Stdout) {
+		return errorf("%s - двоичные данные: добавьте --qr или перенаправьте вывод в файл", format)
+	}
+	_, err := io.WriteString(os.Stdout, payload)
+	return err
+}
+
+// runConvert перекодирует сид между представлениями без повторного вывода
+func runConvert(args []string) error {
+	fs := newFlagSet("convert")
+	from := fs.String("from", "hex", tr("исходный формат: ")+strings.Join(seedEncodingNames(), ", "))
+	to := fs.String("to", "mnemonic", tr("целевой формат: ")+strings.Join(seedEncodingNames(), ", "))
+	copyResult := fs.Bool("copy", false, "скопировать результат в буфер обмена вместо вывода")
+	quiz := fs.Bool("quiz", false, "после вывода мнемоники спросить 3 случайных слова, чтобы проверить запись")
+	showQR := fs.Bool("qr", false, "вывести QR-код SeedQR для сканирования устройством (с --to seedqr или compactseedqr)")
+	groups := addGroupSizeFlag(fs)
+	pf := addProfileFlags(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := pf.apply(fs); err != nil {
+		return err
+	}
+	if *quiz && (*to != "mnemonic" || *copyResult) {
+		return errorf("флаг --quiz применим только к выводу мнемоники (--to mnemonic без --copy)")
+	}
+	if *showQR && (*to != "seedqr" && *to != "compactseedqr" || *copyResult) {
+		return errorf("флаг --qr применим только к --to seedqr и compactseedqr без --copy")
+	}
+	if *groups != 0 && (*to != "hex" && *to != "bech32" || *copyResult) {
+		return errorf("флаг --group-size применим только к --to hex и bech32 без --copy")
+	}
+	if *copyResult && binarySeedEncoding(*to) {
+		return errorf("%s - двоичные данные и в буфер обмена не копируются", *to)
+	}
+
+	// Значение лучше передавать через stdin, чтобы оно не осталось в истории shell
+	var input string
+	switch {
+	case binarySeedEncoding(*from):
+		// Двоичная нагрузка может содержать любые байты, в том числе переводы строк
+		if len(positional) > 0 {
+			return errorf("%s - двоичные данные: передайте их через stdin, например из zbarimg --raw -Sbinary", *from)
+		}
+		data, err := io.ReadAll(io.LimitReader(os.Stdin, 64))
+		if err != nil {
+			return errorf("ошибка чтения ввода: %w", err)
+		}
+		input = string(data)
+		wipe(data)
+	case len(positional) == 0:
+		scanner := bufio.NewScanner(os.Stdin)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return errorf("ошибка чтения ввода: %w", err)
+			}
+			return errorf("не передано значение для преобразования")
+		}
+		input = scanner.Text()
+		// Копия, переписанная группами (--group-size), сверяется построчно
+		if *from == "hex" || *from == "bech32" {
+			if input, err = readGroupedInput(scanner, input); err != nil {
+				return err
+			}
+		}
+	case len(positional) == 1:
+		input = positional[0]
+	default:
+		// Мнемоника без кавычек приходит отдельными аргументами
+		input = strings.Join(positional, " ")
+	}
+
+	seed, err := decodeSeed(input, *from)
+	if err != nil {
+		return errorf("ошибка разбора %s: %w", *from, err)
+	}
+
+	out, err := encodeSeed(seed, *to)
+	if err != nil {
+		return err
+	}
+	if *copyResult {
+		if err := copyToClipboard([]byte(out)); err != nil {
+			return errorf("ошибка копирования в буфер обмена: %w", err)
+		}
+		logInfof("✓ Результат скопирован в буфер обмена, после использования выполните seedgen wipe\n")
+		return nil
+	}
+	if *groups != 0 {
+		return writeGrouped(os.Stdout, []byte(out), *groups)
+	}
+	if err := writeSeedPayload(out, *to, *showQR); err != nil {
+		return err
+	}
+	if *quiz {
+		return quizMnemonic(out)
+	}
+	return nil
+}
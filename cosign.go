@@ -0,0 +1,324 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// transcriptCosignDomain отделяет подписи участников под протоколом
+// от подписи ключом церемонии
+const transcriptCosignDomain = "seedgen/transcript-cosignature/v1"
+
+// transcriptCosignature - подпись участника под протоколом церемонии.
+// Участники подписывают протокол по очереди: каждая подпись покрывает
+// протокол с подписью ключом церемонии и все подписи перед ней, поэтому
+// из протокола нельзя незаметно убрать или переставить подпись.
+type transcriptCosignature struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	SignedAt  time.Time `json:"signed_at"`
+	PublicKey string    `json:"public_key"`
+	Signature string    `json:"signature,omitempty"`
+}
+
+// cosignedBytes возвращает подписываемое представление i-й подписи участника
+func (t transcript) cosignedBytes(i int, c transcriptCosignature) ([]byte, error) {
+	t.Cosignatures = t.Cosignatures[:i]
+	c.Signature = ""
+	body, err := json.Marshal(struct {
+		Transcript  transcript            `json:"transcript"`
+		Cosignature transcriptCosignature `json:"cosignature"`
+	}{t, c})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(transcriptCosignDomain+"\x00"), body...), nil
+}
+
+// cosign добавляет подпись участника в конец очереди подписей
+func (t *transcript) cosign(key ed25519.PrivateKey, name string) error {
+	pub := key.Public().(ed25519.PublicKey)
+	if hex.EncodeToString(pub) == t.PublicKey {
+		return errorf("протокол уже подписан этим ключом как ключом церемонии")
+	}
+	for _, c := range t.Cosignatures {
+		if c.PublicKey == hex.EncodeToString(pub) {
+			return errorf("протокол уже подписан этим ключом (%s)", c.Name)
+		}
+	}
+	c := transcriptCosignature{
+		Name:      name,
+		Version:   buildVersion(),
+		SignedAt:  time.Now().UTC(),
+		PublicKey: hex.EncodeToString(pub),
+	}
+	msg, err := t.cosignedBytes(len(t.Cosignatures), c)
+	if err != nil {
+		return err
+	}
+	c.Signature = hex.EncodeToString(ed25519.Sign(key, msg))
+	t.Cosignatures = append(t.Cosignatures, c)
+	return nil
+}
+
+// verifyCosignatures проверяет подписи участников по порядку; ключи
+// подписавших не должны повторяться и совпадать с ключом церемонии
+func (t transcript) verifyCosignatures(main ed25519.PublicKey) error {
+	seen := map[string]bool{hex.EncodeToString(main): true}
+	for i, c := range t.Cosignatures {
+		pub, err := hex.DecodeString(c.PublicKey)
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			return errorf("некорректный открытый ключ в подписи участника %q", c.Name)
+		}
+		if seen[c.PublicKey] {
+			return errorf("ключ участника %q уже подписал протокол", c.Name)
+		}
+		seen[c.PublicKey] = true
+		sig, err := hex.DecodeString(c.Signature)
+		if err != nil || len(sig) != ed25519.SignatureSize {
+			return mismatchf("подпись участника %q отсутствует или повреждена", c.Name)
+		}
+		msg, err := t.cosignedBytes(i, c)
+		if err != nil {
+			return err
+		}
+		if !ed25519.Verify(pub, msg, sig) {
+			return mismatchf("подпись участника %q под протоколом недействительна", c.Name)
+		}
+	}
+	return nil
+}
+
+// printCosignatures выводит подписи участников и требует подписи каждого
+// ключа из required. Подписи к этому моменту уже проверены verify.
+func printCosignatures(t *transcript, required []string) error {
+	if len(t.Cosignatures) > 0 {
+		fmt.Println()
+		fmt.Printf(tr("Подписи участников (%d, по порядку):\n"), len(t.Cosignatures))
+	}
+	signed := make(map[string]bool)
+	for i, c := range t.Cosignatures {
+		pub := mustHex(c.PublicKey)
+		signed[c.PublicKey] = true
+		fmt.Printf(tr("  %d. %s, ключ %s, %s, seedgen %s\n"), i+1, c.Name, keyFingerprint(pub), c.SignedAt.Format(time.RFC3339), c.Version)
+	}
+	for _, r := range required {
+		want, err := parsePublicKey(r)
+		if err != nil {
+			return err
+		}
+		if !signed[hex.EncodeToString(want)] {
+			return errorf("протокол не подписан участником с ключом %s", keyFingerprint(want))
+		}
+	}
+	if len(t.Cosignatures) > 0 {
+		fmt.Println(tr("✓ Подписи участников действительны"))
+	}
+	return nil
+}
+
+// runAuditCosign добавляет подпись участника под протоколом локальной
+// церемонии: протокол передают по кругу, и каждый подписывает его на своей
+// машине после того, как сверил содержимое
+func runAuditCosign(args []string) error {
+	fs := newFlagSet("audit cosign")
+	keyPath := fs.String("key", "", "личный ключ участника (PEM, audit keygen)")
+	name := fs.String("name", "", "имя участника")
+	pubkey := fs.String("pubkey", "", "ожидаемый открытый ключ церемонии: hex или PEM-файл")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errorf("укажите файл протокола")
+	}
+	if *keyPath == "" || *name == "" {
+		return errorf("укажите --key и --name")
+	}
+	key, err := loadSigningKey(*keyPath)
+	if err != nil {
+		return errorf("ошибка чтения ключа: %w", err)
+	}
+	t, err := readTranscript(positional[0], *pubkey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(tr("=== Протокол: %s ===\n\n"), positional[0])
+	fmt.Printf(tr("Версия: %s (коммит %s), %s\n"), t.Version, t.Commit, t.Platform)
+	fmt.Printf(tr("Операторы: %s\n"), strings.Join(t.Operators, ", "))
+	fmt.Println(describeParams(t.Params))
+	fmt.Printf(tr("Ключ церемонии: %s\n"), keyFingerprint(mustHex(t.PublicKey)))
+	for _, e := range t.Events {
+		if e.Fingerprint != "" {
+			fmt.Printf(tr("Отпечаток мастер-сида: %s\n"), emph(e.Fingerprint))
+		}
+	}
+	for _, c := range t.Cosignatures {
+		fmt.Printf(tr("Уже подписал: %s (%s)\n"), c.Name, keyFingerprint(mustHex(c.PublicKey)))
+	}
+
+	if err := t.cosign(key, *name); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := positional[0] + ".tmp"
+	if err := writeNewFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, positional[0]); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	fmt.Println()
+	fmt.Printf(tr("✓ Протокол подписан участником %s (ключ %s), подписей участников: %d\n"), *name, keyFingerprint(key.Public().(ed25519.PublicKey)), len(t.Cosignatures))
+	return nil
+}
+
+// checkSessionTranscript сверяет протокол распределенной церемонии, который
+// координатор прислал на подпись, с тем, что видел сам участник: подпись
+// ключом церемонии, параметры, состав участников, свои обязательства,
+// корень их дерева и отпечаток мастер-сида
+func checkSessionTranscript(t *transcript, reveal, result ceremonyMessage, self string, commitments []string) error {
+	if t == nil || t.Kind != "ceremony-transcript" {
+		return errorf("координатор не прислал протокол церемонии")
+	}
+	if _, err := t.verify(); err != nil {
+		return err
+	}
+	if t.Params != *reveal.Params {
+		return mismatchf("параметры в протоколе не совпадают с параметрами церемонии")
+	}
+	if strings.Join(t.Operators, "\n") != strings.Join(reveal.Participants, "\n") {
+		return mismatchf("состав участников в протоколе не совпадает с церемонией")
+	}
+
+	own := make(map[string]bool, len(commitments))
+	for _, c := range commitments {
+		own[c] = true
+	}
+	var all []string
+	var root, fingerprint string
+	for _, e := range t.Events {
+		switch e.Event {
+		case "seed-received":
+			all = append(all, e.Commitment)
+			if own[e.Commitment] {
+				if e.Participant != self {
+					return errorf("в протоколе наше обязательство %s приписано %q", e.Commitment, e.Participant)
+				}
+				delete(own, e.Commitment)
+			}
+		case "seeds-complete":
+			root = e.Root
+		case "master-derived":
+			fingerprint = e.Fingerprint
+		}
+	}
+	for _, c := range commitments {
+		if own[c] {
+			return errorf("в протоколе нет нашего обязательства %s", c)
+		}
+	}
+	if root != newSeedMerkleTree(all).root() {
+		return mismatchf("корень обязательств в протоколе не соответствует его событиям")
+	}
+	if fingerprint != result.Fingerprint {
+		return mismatchf("отпечаток мастер-сида в протоколе не совпадает с разосланным")
+	}
+	return nil
+}
+
+// collectCosignatures передает протокол участникам по очереди: каждый
+// сверяет его, подписывает и возвращает, координатор проверяет новую подпись
+// и передает протокол следующему
+func collectCosignatures(participants []*ceremonyParticipant, t *transcript) error {
+	for _, p := range participants {
+		if err := p.send(ceremonyMessage{Type: "transcript", Transcript: t}); err != nil {
+			return err
+		}
+		m, err := p.receive("transcript-signature")
+		if err == nil && m.Cosignature == nil {
+			err = errorf("%s не прислал подпись под протоколом", p.name)
+		}
+		if err != nil {
+			return err
+		}
+		if m.Cosignature.Name != p.name {
+			return errorf("%s подписал протокол под именем %q", p.name, m.Cosignature.Name)
+		}
+		t.Cosignatures = append(t.Cosignatures, *m.Cosignature)
+		if _, err := t.verify(); err != nil {
+			return errorf("%s: %w", p.name, err)
+		}
+		fmt.Printf(tr("✓ Протокол подписан: %s (ключ %s)\n"), p.name, keyFingerprint(mustHex(m.Cosignature.PublicKey)))
+	}
+	final := ceremonyMessage{Type: "transcript-final", Transcript: t}
+	for _, p := range participants {
+		if err := p.send(final); err != nil {
+			logWarnf("⚠ %s\n", err)
+		}
+	}
+	return nil
+}
+
+// cosignSessionTranscript сверяет присланный координатором протокол,
+// подписывает его своим ключом и ждет итоговый протокол со всеми подписями.
+// С непустым out копия итогового протокола сохраняется у участника.
+func cosignSessionTranscript(conn *ceremonyConn, key ed25519.PrivateKey, reveal, result ceremonyMessage, self string, commitments []string, out string) error {
+	m, err := conn.receive("transcript")
+	if err != nil {
+		return err
+	}
+	t := m.Transcript
+	if err := checkSessionTranscript(t, reveal, result, self, commitments); err != nil {
+		conn.send(ceremonyMessage{Type: "error", Error: "участник отказался подписать протокол: " + err.Error()})
+		return errorf("протокол не подписан: %w", err)
+	}
+	if err := t.cosign(key, self); err != nil {
+		return err
+	}
+	ours := t.Cosignatures[len(t.Cosignatures)-1]
+	if err := conn.send(ceremonyMessage{Type: "transcript-signature", Cosignature: &ours}); err != nil {
+		return err
+	}
+	fmt.Printf(tr("\n✓ Протокол церемонии подписан ключом %s\n"), keyFingerprint(key.Public().(ed25519.PublicKey)))
+
+	// В итоговом протоколе должна остаться наша подпись над тем же содержимым
+	m, err = conn.receive("transcript-final")
+	if err != nil {
+		return err
+	}
+	final := m.Transcript
+	if final == nil {
+		return errorf("координатор не прислал итоговый протокол")
+	}
+	if _, err := final.verify(); err != nil {
+		return errorf("итоговый протокол: %w", err)
+	}
+	i := len(t.Cosignatures) - 1
+	if len(final.Cosignatures) <= i || final.Cosignatures[i].Signature != ours.Signature || final.Signature != t.Signature {
+		return errorf("в итоговом протоколе нет нашей подписи")
+	}
+	fmt.Printf(tr("✓ Итоговый протокол подписали участники: %d\n"), len(final.Cosignatures))
+	if out == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(final, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(out, append(data, '\n'), 0644); err != nil {
+		return errorf("ошибка записи протокола: %w", err)
+	}
+	fmt.Printf(tr("✓ Копия протокола сохранена: %s\n"), out)
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// credentialPath возвращает путь к учетным данным systemd с именем name
+// в каталоге $CREDENTIALS_DIRECTORY, который systemd создает для службы
+func credentialPath(name string) (string, error) {
+	dir := os.Getenv("CREDENTIALS_DIRECTORY")
+	if dir == "" {
+		return "", errorf("переменная CREDENTIALS_DIRECTORY не задана: учетные данные передает systemd через LoadCredential= или LoadCredentialEncrypted=")
+	}
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", errorf("некорректное имя учетных данных %q", name)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// readSeedCredentials читает сиды из учетных данных systemd, по строке на сид
+func readSeedCredentials(names []string) ([][]byte, error) {
+	var seeds [][]byte
+	for _, name := range names {
+		path, err := credentialPath(name)
+		if err != nil {
+			wipeSeeds(seeds)
+			return nil, err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			wipeSeeds(seeds)
+			return nil, err
+		}
+		read, err := readDeviceSeeds(f, io.Discard)
+		f.Close()
+		if err != nil {
+			wipeSeeds(seeds)
+			return nil, errorf("учетные данные %s: %w", name, err)
+		}
+		if len(read) == 0 {
+			wipeSeeds(seeds)
+			return nil, errorf("учетные данные %s не содержат сидов", name)
+		}
+		seeds = append(seeds, read...)
+	}
+	return seeds, nil
+}
+
+// loadCredential читает мастер-сид в hex из учетных данных systemd службы,
+// записанных --store-credential
+func loadCredential(name string) ([]byte, error) {
+	path, err := credentialPath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lockSecret(data)
+	defer wipe(data)
+	trimmed := bytes.TrimSpace(data)
+	master := newSecret(len(trimmed) / 2)
+	if _, err := decodeHex(master, trimmed); err != nil || len(trimmed) != len(master)*2 {
+		wipe(master)
+		return nil, errorf("учетные данные %s не содержат мастер-сид в hex", name)
+	}
+	return master, nil
+}
+
+// storeCredential шифрует мастер-сид командой systemd-creds в файл path.
+// Имя учетных данных - имя файла: под ним их подключает LoadCredentialEncrypted=.
+func storeCredential(path string, master []byte) error {
+	if _, err := os.Lstat(path); err == nil {
+		return errorf("%s уже существует", path)
+	}
+	line := newSecret(len(master)*2 + 1)
+	defer wipe(line)
+	encodeHex(line, master)
+	line[len(line)-1] = '\n'
+	cmd := exec.Command("systemd-creds", "encrypt", "--name="+filepath.Base(path), "-", path)
+	cmd.Stdin = bytes.NewReader(line)
+	var errOut bytes.Buffer
+	cmd.Stderr = &errOut
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(errOut.String()); msg != "" {
+			return errorf("systemd-creds: %s", msg)
+		}
+		return errorf("systemd-creds: %w", err)
+	}
+	return nil
+}
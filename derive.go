@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// masterSeedSize - размер мастер-сида в байтах
+const masterSeedSize = 64
+
+// deriveKind описывает тип ключа, выводимого из мастер-сида
+type deriveKind struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// deriveKinds перечисляет поддерживаемые типы выводимых ключей
+var deriveKinds = []deriveKind{
+	{"ed25519", "ключ подписи Ed25519 по пути SLIP-0010", runDeriveEd25519},
+	{"bip39", "мнемоника BIP39 кошелька для аппаратного кошелька", runDeriveBIP39},
+	{"btc", "ключи и адреса Bitcoin по путям BIP44/49/84", runDeriveBTC},
+	{"eth", "счета Ethereum по пути m/44'/60'/0'/0/N", runDeriveETH},
+	{"ssh", "ключ OpenSSH Ed25519 и строка authorized_keys", runDeriveSSH},
+	{"age", "идентичность и получатель age (X25519)", runDeriveAge},
+	{"wireguard", "ключи WireGuard для именованных узлов", runDeriveWireGuard},
+	{"ca", "корневой сертификат X.509 с ключом Ed25519", runDeriveCA},
+	{"pgp", "ключ OpenPGP Ed25519/X25519", runDerivePGP},
+	{"totp", "секрет TOTP для сервиса: URI otpauth:// и QR-код", runDeriveTOTP},
+	{"password", "пароль для сайта по политике длины и классов символов", runDerivePassword},
+	{"key", "симметричный ключ сервиса по метке (HKDF) с учетом в манифесте", runDeriveKey},
+	{"nostr", "ключи Nostr nsec/npub по NIP-06", runDeriveNostr},
+	{"solana", "ключ Solana по пути m/44'/501'/N'/0' в формате solana-keygen", runDeriveSolana},
+	{"uuid", "стабильные идентификаторы UUIDv5 для имен", runDeriveUUID},
+	{"tournament", "независимый мастер-сид турнира или мероприятия по --id", runDeriveTournament},
+}
+
+// deriveKindNames возвращает имена типов ключей
+func deriveKindNames() []string {
+	names := make([]string, 0, len(deriveKinds))
+	for _, k := range deriveKinds {
+		names = append(names, k.name)
+	}
+	return names
+}
+
+// addMasterFlag регистрирует флаг источника мастер-сида
+func addMasterFlag(fs *flag.FlagSet) *string {
+	return fs.String("master", "-", "мастер-сид: файл артефакта, строка msv2:..., hex, keychain:имя, keyring:имя, credential:имя, vault:путь, pass:путь, op://хранилище/запись, pkcs11:... (только derive key) или \"-\" для stdin")
+}
+
+// readMaster читает мастер-сид из хранилища (keychain:имя, keyring:имя, credential:имя, vault:путь, pass:путь, op://хранилище/запись), из артефакта (JSON, в том числе зашифрованного SOPS, или msv2 с полем master)
+// или из сида в любом поддерживаемом представлении. Отпечаток прочитанного мастер-сида попадает в журнал аудита.
+func readMaster(ref string) ([]byte, error) {
+	master, err := readMasterRef(ref)
+	if err == nil {
+		auditInput("master", "", masterFingerprint(master))
+	}
+	return master, err
+}
+
+// readMasterRef читает мастер-сид по ссылке для readMaster
+func readMasterRef(ref string) ([]byte, error) {
+	if master, ok, err := readMasterSource(ref); ok {
+		return master, err
+	}
+	if ref == "-" {
+		if isTerminal(os.Stdin) {
+			fmt.Fprintln(os.Stderr, tr("Введите мастер-сид или вставьте артефакт, затем нажмите Ctrl-D:"))
+		}
+	}
+
+	// Hex мастер-сида в аргументе разбирается сразу, без чтения файла
+	if master, ok, err := decodeMasterHex([]byte(ref)); ok {
+		return master, err
+	}
+
+	data, err := readArtifact(ref)
+	if err != nil {
+		return nil, err
+	}
+	lockSecret(data)
+	defer wipe(data)
+
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		if isSOPSFile(data) {
+			plain, err := decryptSOPS(data)
+			if err != nil {
+				return nil, err
+			}
+			lockSecret(plain)
+			defer wipe(plain)
+			data = plain
+		}
+		var rec struct {
+			Kind   string    `json:"kind"`
+			Master secretHex `json:"master"`
+		}
+		if err := json.Unmarshal(data, &rec); err != nil {
+			wipe(rec.Master)
+			return nil, errorf("не удалось разобрать артефакт: %w", err)
+		}
+		logDebug("артефакт мастер-сида", "kind", rec.Kind, "bytes", len(data))
+		if open, ok := masterArtifacts[rec.Kind]; ok {
+			return open(data)
+		}
+		if len(rec.Master) == 0 {
+			return nil, errorf("артефакт не содержит мастер-сида")
+		}
+		return checkMasterSize(rec.Master)
+	}
+
+	// Мастер-сид, переписанный группами (--group-size), сверяется построчно
+	if text, ok, err := parseGroupedLines(trimmed); ok {
+		if err != nil {
+			return nil, err
+		}
+		defer wipe(text)
+		trimmed = text
+	}
+
+	// Hex разбирается прямо из буфера, без промежуточной строки
+	if master, ok, err := decodeMasterHex(trimmed); ok {
+		return master, err
+	}
+
+	text := string(trimmed)
+	for _, format := range []string{"hex", "mnemonic", "bech32", "base58"} {
+		master, err := decodeSeed(text, format)
+		if err != nil {
+			continue
+		}
+		return checkMasterSize(master)
+	}
+	return nil, errorf("не удалось распознать мастер-сид")
+}
+
+// checkMasterSize проверяет длину мастер-сида и затирает неподходящий
+func checkMasterSize(master []byte) ([]byte, error) {
+	if len(master) != masterSeedSize {
+		wipe(master)
+		return nil, errorf("мастер-сид должен занимать %d байта, получено %d", masterSeedSize, len(master))
+	}
+	lockSecret(master)
+	return master, nil
+}
+
+// deriveKindKey выводит 32-байтовый ключ для типа kind и метки label.
+// Домен отличается от путей реестра, поэтому ключи не совпадают с ними.
+func deriveKindKey(master []byte, kind, label string) []byte {
+	mac := hmac.New(sha512.New, master)
+	mac.Write([]byte("seedgen/derive-kind/" + kind + "/"))
+	mac.Write([]byte(label))
+	return mac.Sum(nil)[:32]
+}
+
+// runDerive выводит ключи указанного типа из мастер-сида
+func runDerive(args []string) error {
+	names := strings.Join(deriveKindNames(), ", ")
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, tr("Использование: seedgen derive <тип> [флаги]"))
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, tr("Типы:"))
+		for _, k := range deriveKinds {
+			fmt.Fprintf(os.Stderr, "  %-12s %s\n", k.name, tr(k.summary))
+		}
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return errorf("укажите тип ключа: %s", names)
+	}
+
+	for _, k := range deriveKinds {
+		if k.name == args[0] {
+			return k.run(args[1:])
+		}
+	}
+	return errorf("неизвестный тип ключа %q, доступны: %s", args[0], names)
+}
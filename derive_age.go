@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// Префиксы Bech32 ключей age
+const (
+	ageIdentityHRP  = "age-secret-key-"
+	ageRecipientHRP = "age"
+)
+
+// ageKeyPair возвращает идентичность (AGE-SECRET-KEY-1...) и получателя (age1...)
+func ageKeyPair(scalar []byte) (identity, recipient string, err error) {
+	pub, err := curve25519.X25519(scalar, curve25519.Basepoint)
+	if err != nil {
+		return "", "", err
+	}
+	if identity, err = bech32Encode(ageIdentityHRP, scalar); err != nil {
+		return "", "", err
+	}
+	if recipient, err = bech32Encode(ageRecipientHRP, pub); err != nil {
+		return "", "", err
+	}
+	return strings.ToUpper(identity), recipient, nil
+}
+
+// runDeriveAge выводит идентичность age для метки
+func runDeriveAge(args []string) error {
+	fs := newFlagSet("derive age")
+	label := fs.String("label", "ceremony", "метка идентичности: разные метки дают независимые ключи")
+	out := fs.String("out", "", "сохранить файл идентичности (как age-keygen -o)")
+	recipientOnly := fs.Bool("recipient", false, "вывести только получателя")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *label == "" {
+		return errorf("метка не может быть пустой")
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	identity, recipient, err := ageKeyPair(deriveKindKey(master, "age", *label))
+	if err != nil {
+		return err
+	}
+
+	if *recipientOnly {
+		fmt.Println(recipient)
+		return nil
+	}
+	// Строки даты создания нет, чтобы повторный вывод давал тот же файл
+	file := fmt.Sprintf("# seedgen derive age --label %q\n# public key: %s\n%s\n", *label, recipient, identity)
+	if *out != "" {
+		if err := writeNewFile(*out, []byte(file), 0600); err != nil {
+			return err
+		}
+		logInfof("✓ Идентичность сохранена: %s\n", *out)
+		fmt.Println(recipient)
+		return nil
+	}
+	fmt.Print(file)
+	return nil
+}
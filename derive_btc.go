@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// btcPurpose описывает стандарт пути Bitcoin и тип его адресов
+type btcPurpose struct {
+	name string
+	// Версии расширенных ключей: [сеть][закрытый/открытый], сеть 0 - mainnet
+	versions [2][2]uint32
+	prefix   [2]string
+	address  func(pub []byte, testnet bool) (string, error)
+}
+
+// btcPurposes перечисляет поддерживаемые стандарты путей
+var btcPurposes = map[uint32]btcPurpose{
+	44: {
+		name:     "BIP44, P2PKH",
+		versions: [2][2]uint32{{0x0488ade4, 0x0488b21e}, {0x04358394, 0x043587cf}},
+		prefix:   [2]string{"xpub", "tpub"},
+		address: func(pub []byte, testnet bool) (string, error) {
+			version := byte(0x00)
+			if testnet {
+				version = 0x6f
+			}
+			return base58CheckEncode(append([]byte{version}, hash160(pub)...)), nil
+		},
+	},
+	49: {
+		name:     "BIP49, P2SH-P2WPKH",
+		versions: [2][2]uint32{{0x049d7878, 0x049d7cb2}, {0x044a4e28, 0x044a5262}},
+		prefix:   [2]string{"ypub", "upub"},
+		address: func(pub []byte, testnet bool) (string, error) {
+			version := byte(0x05)
+			if testnet {
+				version = 0xc4
+			}
+			redeem := append([]byte{0x00, 0x14}, hash160(pub)...)
+			return base58CheckEncode(append([]byte{version}, hash160(redeem)...)), nil
+		},
+	},
+	84: {
+		name:     "BIP84, P2WPKH",
+		versions: [2][2]uint32{{0x04b2430c, 0x04b24746}, {0x045f18bc, 0x045f1cf6}},
+		prefix:   [2]string{"zpub", "vpub"},
+		address: func(pub []byte, testnet bool) (string, error) {
+			hrp := "bc"
+			if testnet {
+				hrp = "tb"
+			}
+			return segwitAddress(hrp, hash160(pub))
+		},
+	},
+}
+
+// wifEncode кодирует закрытый ключ в WIF для сжатого открытого ключа
+func wifEncode(key []byte, testnet bool) string {
+	version := byte(0x80)
+	if testnet {
+		version = 0xef
+	}
+	buf := append([]byte{version}, key...)
+	return base58CheckEncode(append(buf, 0x01))
+}
+
+// btcAddress - выведенный адрес с путем и, при запросе, закрытым ключом
+type btcAddress struct {
+	Path    string `json:"path"`
+	Address string `json:"address"`
+	WIF     string `json:"wif,omitempty"`
+}
+
+// btcAccount - результат вывода счета Bitcoin
+type btcAccount struct {
+	Kind              string       `json:"kind"`
+	Standard          string       `json:"standard"`
+	Network           string       `json:"network"`
+	MasterFingerprint string       `json:"master_fingerprint"`
+	AccountPath       string       `json:"account_path"`
+	AccountPublic     string       `json:"account_public"`
+	AccountPrivate    string       `json:"account_private,omitempty"`
+	Addresses         []btcAddress `json:"addresses"`
+}
+
+// runDeriveBTC выводит ключи и адреса Bitcoin по путям BIP44/49/84
+func runDeriveBTC(args []string) error {
+	fs := newFlagSet("derive btc")
+	purpose := fs.Uint("purpose", 84, "стандарт пути: 44 (P2PKH), 49 (P2SH-P2WPKH) или 84 (P2WPKH)")
+	account := fs.Uint("account", 0, "номер счета")
+	change := fs.Uint("change", 0, "0 - адреса получения, 1 - адреса сдачи")
+	index := fs.Uint("index", 0, "номер первого адреса")
+	count := fs.Int("count", 5, "количество адресов")
+	testnet := fs.Bool("testnet", false, "адреса тестовой сети")
+	private := fs.Bool("private", false, "вывести также закрытые ключи (WIF и xprv счета)")
+	format := fs.String("format", "text", "формат вывода: text или json")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	std, ok := btcPurposes[uint32(*purpose)]
+	if !ok {
+		return errorf("неизвестный стандарт пути %d, поддерживаются 44, 49 и 84", *purpose)
+	}
+	if *change > 1 {
+		return errorf("--change может быть только 0 или 1")
+	}
+	if *count < 1 {
+		return errorf("количество адресов должно быть положительным")
+	}
+	if *account >= uint(hardenedOffset) || uint64(*index)+uint64(*count) > uint64(hardenedOffset) {
+		return errorf("номер счета или адреса вне допустимого диапазона")
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	seed, err := walletSeed(master)
+	if err != nil {
+		return err
+	}
+	defer wipe(seed)
+	root, err := newHDKey(curveSecp256k1, seed)
+	if err != nil {
+		return err
+	}
+
+	net, coin, netName := 0, uint32(0), "mainnet"
+	if *testnet {
+		net, coin, netName = 1, 1, "testnet"
+	}
+	accountPath := derivationPath{hardened(uint32(*purpose)), hardened(coin), hardened(uint32(*account))}
+	acct, err := root.derivePath(accountPath)
+	if err != nil {
+		return err
+	}
+	fp := root.fingerprint()
+
+	result := btcAccount{
+		Kind:              "btc-account",
+		Standard:          std.name,
+		Network:           netName,
+		MasterFingerprint: fmt.Sprintf("%x", fp[:]),
+		AccountPath:       accountPath.String(),
+		AccountPublic:     acct.serialize(std.versions[net][1], false),
+	}
+	if *private {
+		result.AccountPrivate = acct.serialize(std.versions[net][0], true)
+	}
+
+	branch, err := acct.child(uint32(*change))
+	if err != nil {
+		return err
+	}
+	for i := uint32(*index); i < uint32(*index)+uint32(*count); i++ {
+		key, err := branch.child(i)
+		if err != nil {
+			return err
+		}
+		addr, err := std.address(key.publicKey(), *testnet)
+		if err != nil {
+			return err
+		}
+		entry := btcAddress{
+			Path:    accountPath.child(uint32(*change), i).String(),
+			Address: addr,
+		}
+		if *private {
+			entry.WIF = wifEncode(key.key, *testnet)
+		}
+		result.Addresses = append(result.Addresses, entry)
+	}
+
+	if *format == "json" {
+		return writeOutput(os.Stdout, result)
+	}
+
+	fmt.Printf(tr("Стандарт: %s (%s)\n"), result.Standard, result.Network)
+	fmt.Printf(tr("Отпечаток мастер-ключа: %s\n"), emph(result.MasterFingerprint))
+	fmt.Printf(tr("Счет: %s\n"), result.AccountPath)
+	fmt.Printf("%s: %s\n", std.prefix[net], result.AccountPublic)
+	if *private {
+		fmt.Printf(tr("Закрытый ключ счета: %s\n"), result.AccountPrivate)
+	}
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if *private {
+		fmt.Fprintln(tw, tr("Путь\tАдрес\tWIF"))
+	} else {
+		fmt.Fprintln(tw, tr("Путь\tАдрес"))
+	}
+	for _, a := range result.Addresses {
+		if *private {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", a.Path, a.Address, a.WIF)
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\n", a.Path, a.Address)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Println(tr("Адреса выведены из мнемоники `seedgen derive bip39` без пароля BIP39:"))
+	fmt.Println(tr("импортируйте ее в аппаратный кошелек и сверьте адреса перед переводом средств."))
+	return nil
+}
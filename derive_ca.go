@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"time"
+)
+
+// caDateLayout - формат дат срока действия сертификата
+const caDateLayout = "2006-01-02"
+
+// caCertificate выпускает самоподписанный корневой сертификат. Ed25519
+// подписывает детерминированно, а серийный номер выводится из ключа и имени,
+// поэтому при тех же входных данных получается побайтно тот же сертификат.
+func caCertificate(priv ed25519.PrivateKey, cn string, notBefore, notAfter time.Time) ([]byte, error) {
+	pub := priv.Public().(ed25519.PublicKey)
+	serial := sha256.Sum256(append(append([]byte("seedgen/ca/serial/"), pub...), cn...))
+	serial[0] &= 0x7f
+
+	template := &x509.Certificate{
+		SerialNumber:          new(big.Int).SetBytes(serial[:16]),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	// Идентификатор ключа задается явно, чтобы не зависеть от версии Go
+	ski := sha256.Sum256(pub)
+	template.SubjectKeyId = ski[:20]
+	return x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+}
+
+// parseCADate разбирает дату ГГГГ-ММ-ДД как полночь UTC
+func parseCADate(name, value string) (time.Time, error) {
+	t, err := time.Parse(caDateLayout, value)
+	if err != nil {
+		return time.Time{}, errorf("--%s: ожидалась дата ГГГГ-ММ-ДД, получено %q", name, value)
+	}
+	return t, nil
+}
+
+// runDeriveCA выводит ключ и самоподписанный сертификат корневого центра
+func runDeriveCA(args []string) error {
+	fs := newFlagSet("derive ca")
+	cn := fs.String("cn", "", "имя центра сертификации (Common Name); от него зависит ключ")
+	notBefore := fs.String("not-before", "2025-01-01", "начало срока действия, ГГГГ-ММ-ДД (UTC)")
+	notAfter := fs.String("not-after", "2035-01-01", "конец срока действия, ГГГГ-ММ-ДД (UTC)")
+	out := fs.String("out", "", "сохранить ключ в PREFIX.key, а сертификат в PREFIX.crt")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *cn == "" {
+		return errorf("укажите имя центра сертификации через --cn")
+	}
+	from, err := parseCADate("not-before", *notBefore)
+	if err != nil {
+		return err
+	}
+	until, err := parseCADate("not-after", *notAfter)
+	if err != nil {
+		return err
+	}
+	if !until.After(from) {
+		return errorf("--not-after должна быть позже --not-before")
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	priv := ed25519.NewKeyFromSeed(deriveKindKey(master, "ca", *cn))
+	der, err := caCertificate(priv, *cn, from, until)
+	if err != nil {
+		return errorf("ошибка выпуска сертификата: %w", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM, _, err := marshalKeyPairPEM(priv)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(der)
+	logInfof("Центр сертификации: %s (%s - %s)\n", *cn, *notBefore, *notAfter)
+	logInfof("Отпечаток сертификата SHA-256: %s\n", emphErr(hex.EncodeToString(sum[:])))
+
+	if *out != "" {
+		if err := writeNewFile(*out+".key", keyPEM, 0600); err != nil {
+			return err
+		}
+		if err := writeNewFile(*out+".crt", certPEM, 0644); err != nil {
+			return err
+		}
+		logInfof("✓ Закрытый ключ: %s.key\n✓ Сертификат: %s.crt\n", *out, *out)
+		return nil
+	}
+	os.Stdout.Write(keyPEM)
+	os.Stdout.Write(certPEM)
+	return nil
+}
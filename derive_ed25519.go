@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// runDeriveEd25519 выводит ключ подписи Ed25519 по пути SLIP-0010
+func runDeriveEd25519(args []string) error {
+	fs := newFlagSet("derive ed25519")
+	path := fs.String("path", "m/0'", "путь SLIP-0010 (только усиленные индексы)")
+	format := fs.String("format", "pem", "формат ключей: pem или hex")
+	out := fs.String("out", "", "сохранить закрытый ключ в файл (открытый - рядом с суффиксом .pub)")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *format != "pem" && *format != "hex" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	if *out != "" && *format != "pem" {
+		return errorf("флаг --out сохраняет ключи только в формате pem")
+	}
+
+	hdPath, err := parseDerivationPath(*path)
+	if err != nil {
+		return err
+	}
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	key, err := deriveHDKey(curveEd25519, master, hdPath)
+	if err != nil {
+		return err
+	}
+	priv := key.ed25519()
+	pub := priv.Public().(ed25519.PublicKey)
+
+	logInfof("Путь: %s\n", hdPath.String())
+	logInfof("Отпечаток открытого ключа: %s\n", emphErr(keyFingerprint(pub)))
+
+	if *out != "" {
+		if err := writeKeyPair(*out, priv); err != nil {
+			return err
+		}
+		logInfof("✓ Закрытый ключ: %s\n✓ Открытый ключ: %s.pub\n", *out, *out)
+		fmt.Println(hex.EncodeToString(pub))
+		return nil
+	}
+
+	if *format == "hex" {
+		fmt.Printf("private: %s\n", hexString(key.key))
+		fmt.Printf("public:  %s\n", hex.EncodeToString(pub))
+		return nil
+	}
+	privPEM, pubPEM, err := marshalKeyPairPEM(priv)
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(privPEM)
+	os.Stdout.Write(pubPEM)
+	return nil
+}
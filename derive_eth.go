@@ -0,0 +1,229 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+)
+
+// Параметры scrypt хранилища ключей, как у geth по умолчанию
+const (
+	keystoreScryptN = 1 << 18
+	keystoreScryptR = 8
+	keystoreScryptP = 1
+)
+
+// keccak256 вычисляет Keccak-256 в варианте Ethereum (до стандартизации SHA-3)
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// ethAddress возвращает адрес Ethereum с контрольной суммой EIP-55
+func ethAddress(pub secpPoint) string {
+	addr := hex.EncodeToString(keccak256(pub.uncompressed()[1:])[12:])
+	hash := hex.EncodeToString(keccak256([]byte(addr)))
+
+	var b strings.Builder
+	b.WriteString("0x")
+	for i, c := range addr {
+		// Буква пишется заглавной, если соответствующий полубайт хэша >= 8
+		if c >= 'a' && hash[i] >= '8' {
+			c -= 'a' - 'A'
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// formatUUID записывает 16 байт в каноническом виде UUID
+func formatUUID(b []byte) string {
+	h := hex.EncodeToString(b)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// ethKeystore - файл хранилища ключей Web3 Secret Storage v3
+type ethKeystore struct {
+	Address string `json:"address"`
+	Crypto  struct {
+		Cipher       string `json:"cipher"`
+		CipherText   string `json:"ciphertext"`
+		CipherParams struct {
+			IV string `json:"iv"`
+		} `json:"cipherparams"`
+		KDF       string `json:"kdf"`
+		KDFParams struct {
+			DKLen int    `json:"dklen"`
+			N     int    `json:"n"`
+			R     int    `json:"r"`
+			P     int    `json:"p"`
+			Salt  string `json:"salt"`
+		} `json:"kdfparams"`
+		MAC string `json:"mac"`
+	} `json:"crypto"`
+	ID      string `json:"id"`
+	Version int    `json:"version"`
+}
+
+// newEthKeystore шифрует закрытый ключ паролем в формате keystore v3
+func newEthKeystore(key []byte, address, password string) (*ethKeystore, error) {
+	random := make([]byte, 32+aes.BlockSize+16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	salt, iv, id := random[:32], random[32:32+aes.BlockSize], random[32+aes.BlockSize:]
+	// UUID версии 4
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	derived, err := scrypt.Key([]byte(password), salt, keystoreScryptN, keystoreScryptR, keystoreScryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived[:16])
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := make([]byte, len(key))
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext, key)
+
+	ks := &ethKeystore{
+		Address: strings.ToLower(strings.TrimPrefix(address, "0x")),
+		ID:      formatUUID(id),
+		Version: 3,
+	}
+	ks.Crypto.Cipher = "aes-128-ctr"
+	ks.Crypto.CipherText = hex.EncodeToString(ciphertext)
+	ks.Crypto.CipherParams.IV = hex.EncodeToString(iv)
+	ks.Crypto.KDF = "scrypt"
+	ks.Crypto.KDFParams.DKLen = 32
+	ks.Crypto.KDFParams.N = keystoreScryptN
+	ks.Crypto.KDFParams.R = keystoreScryptR
+	ks.Crypto.KDFParams.P = keystoreScryptP
+	ks.Crypto.KDFParams.Salt = hex.EncodeToString(salt)
+	ks.Crypto.MAC = hex.EncodeToString(keccak256(derived[16:32], ciphertext))
+	return ks, nil
+}
+
+// readPasswordFile читает пароль из файла без завершающего перевода строки
+func readPasswordFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	password := strings.TrimRight(string(data), "\r\n")
+	if password == "" {
+		return "", errorf("%s: пустой пароль", path)
+	}
+	return password, nil
+}
+
+// ethAccount - выведенный счет Ethereum
+type ethAccount struct {
+	Path       string `json:"path"`
+	Address    string `json:"address"`
+	PrivateKey string `json:"private_key"`
+}
+
+// runDeriveETH выводит счета Ethereum по пути m/44'/60'/0'/0/N
+func runDeriveETH(args []string) error {
+	fs := newFlagSet("derive eth")
+	index := fs.Uint("index", 0, "номер первого счета N в пути m/44'/60'/0'/0/N")
+	count := fs.Int("count", 1, "количество счетов")
+	keystore := fs.String("keystore", "", "сохранить счет в файл keystore v3 (только для одного счета)")
+	passwordFile := fs.String("password-file", "", "файл с паролем для --keystore")
+	format := fs.String("format", "text", "формат вывода: text или json")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *count < 1 || uint64(*index)+uint64(*count) > uint64(hardenedOffset) {
+		return errorf("номер или количество счетов вне допустимого диапазона")
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	if *keystore != "" && (*count != 1 || *passwordFile == "") {
+		return errorf("--keystore сохраняет один счет и требует --password-file")
+	}
+
+	var password string
+	if *keystore != "" {
+		var err error
+		if password, err = readPasswordFile(*passwordFile); err != nil {
+			return err
+		}
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	seed, err := walletSeed(master)
+	if err != nil {
+		return err
+	}
+	defer wipe(seed)
+	root, err := newHDKey(curveSecp256k1, seed)
+	if err != nil {
+		return err
+	}
+	basePath := derivationPath{hardened(44), hardened(60), hardened(0), 0}
+	branch, err := root.derivePath(basePath)
+	if err != nil {
+		return err
+	}
+
+	var accounts []ethAccount
+	var keys [][]byte
+	for i := uint32(*index); i < uint32(*index)+uint32(*count); i++ {
+		key, err := branch.child(i)
+		if err != nil {
+			return err
+		}
+		accounts = append(accounts, ethAccount{
+			Path:       basePath.child(i).String(),
+			Address:    ethAddress(secpScalarBaseMult(key.key)),
+			PrivateKey: "0x" + hexString(key.key),
+		})
+		keys = append(keys, key.key)
+	}
+
+	if *keystore != "" {
+		ks, err := newEthKeystore(keys[0], accounts[0].Address, password)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(ks, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeNewFile(*keystore, append(data, '\n'), 0600); err != nil {
+			return err
+		}
+		logInfof("✓ Хранилище ключей сохранено: %s\n", *keystore)
+	}
+
+	if *format == "json" {
+		return writeOutput(os.Stdout, accounts)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, tr("Путь\tАдрес\tЗакрытый ключ"))
+	for _, a := range accounts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", a.Path, a.Address, a.PrivateKey)
+	}
+	return tw.Flush()
+}
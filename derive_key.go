@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// keyHKDFSalt - соль HKDF для симметричных ключей сервисов
+const keyHKDFSalt = "seedgen/derive/key/v1"
+
+// keyLabelPattern - допустимые метки: строчные латинские буквы, цифры и
+// разделители ".-_", без похожих символов Unicode и различий в регистре
+var keyLabelPattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9._-]{0,62}[a-z0-9])?$`)
+
+// keyManifestEntry - запись о выданном ключе; сам ключ не сохраняется
+type keyManifestEntry struct {
+	Label       string    `json:"label"`
+	Bytes       int       `json:"bytes"`
+	Fingerprint string    `json:"fingerprint"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// keyManifest - учет меток, для которых уже выводились ключи
+type keyManifest struct {
+	Kind              string             `json:"kind"`
+	MasterFingerprint string             `json:"master_fingerprint"`
+	Keys              []keyManifestEntry `json:"keys"`
+}
+
+// skeletonLabel приводит метку к виду без разделителей для поиска похожих меток
+func skeletonLabel(label string) string {
+	return strings.NewReplacer(".", "", "-", "", "_", "").Replace(label)
+}
+
+// loadKeyManifest читает манифест; отсутствующий файл означает пустой манифест
+func loadKeyManifest(path string) (*keyManifest, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &keyManifest{Kind: "key-manifest"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m keyManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errorf("%s: %w", path, err)
+	}
+	if m.Kind != "key-manifest" {
+		return nil, errorf("%s: ожидался манифест ключей, получен %q", path, m.Kind)
+	}
+	return &m, nil
+}
+
+// check сверяет запрос с манифестом. Повторный вывод под той же меткой
+// разрешен и проверяет отпечаток; другая длина под той же меткой или
+// метка, отличающаяся только разделителями, считаются коллизией.
+func (m *keyManifest) check(entry keyManifestEntry) (known bool, err error) {
+	for _, e := range m.Keys {
+		switch {
+		case e.Label == entry.Label && e.Bytes != entry.Bytes:
+			// Выход HKDF меньшей длины - префикс большей, ключи не были бы независимы
+			return false, errorf("метка %q уже выдана с длиной %d байт", e.Label, e.Bytes)
+		case e.Label == entry.Label && !secretEqualString(e.Fingerprint, entry.Fingerprint):
+			return false, mismatchf("отпечаток ключа %q не совпадает с манифестом", e.Label)
+		case e.Label == entry.Label:
+			return true, nil
+		case skeletonLabel(e.Label) == skeletonLabel(entry.Label):
+			return false, errorf("метка %q слишком похожа на уже выданную %q", entry.Label, e.Label)
+		}
+	}
+	return false, nil
+}
+
+// save записывает манифест через временный файл, чтобы не повредить его при сбое
+func (m *keyManifest) save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// deriveServiceKey выводит симметричный ключ сервиса через HKDF-SHA512
+func deriveServiceKey(master []byte, label string, size int) ([]byte, error) {
+	key := make([]byte, size)
+	if _, err := io.ReadFull(hkdf.New(sha512.New, master, []byte(keyHKDFSalt), []byte(label)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// deriveServiceKeyFrom выводит ключ сервиса из мастер-сида по ссылке и возвращает
+// его вместе с отпечатком мастер-сида. Для токена PKCS#11 HKDF выполняет сам
+// токен через C_DeriveKey, и мастер-сид его не покидает.
+func deriveServiceKeyFrom(ref, label string, size int) ([]byte, string, error) {
+	if strings.HasPrefix(ref, "pkcs11:") {
+		u, err := parsePKCS11URI(ref)
+		if err != nil {
+			return nil, "", err
+		}
+		defer wipe(u.pin)
+		key, fingerprint, err := derivePKCS11(u, []byte(keyHKDFSalt), []byte(label), size)
+		if err != nil {
+			return nil, "", errorf("токен PKCS#11: %w", err)
+		}
+		return key, fingerprint, nil
+	}
+
+	master, err := readMaster(ref)
+	if err != nil {
+		return nil, "", err
+	}
+	defer wipe(master)
+	key, err := deriveServiceKey(master, label, size)
+	return key, masterFingerprint(master), err
+}
+
+// serviceKeyFingerprint возвращает публикуемый отпечаток ключа сервиса
+func serviceKeyFingerprint(key []byte) string {
+	sum := sha256.Sum256(append([]byte("seedgen/key-fingerprint/"), key...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// runDeriveKey выводит симметричный ключ для сервиса по метке
+func runDeriveKey(args []string) error {
+	fs := newFlagSet("derive key")
+	label := fs.String("label", "", "метка ключа: a-z, 0-9 и .-_, например db-encryption")
+	size := fs.Int("bytes", 32, "длина ключа в байтах (16-64)")
+	format := fs.String("format", "hex", "формат вывода: hex или base64")
+	manifestPath := fs.String("manifest", "key-manifest.json", "манифест выданных меток (без самих ключей)")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if !keyLabelPattern.MatchString(*label) {
+		return errorf("метка %q недопустима: нужны строчные латинские буквы, цифры и .-_ (до 64 символов, разделитель не в начале и не в конце)", *label)
+	}
+	if *size < 16 || *size > 64 {
+		return errorf("длина ключа должна быть от 16 до 64 байт")
+	}
+	if *format != "hex" && *format != "base64" {
+		return errorf("неизвестный формат %q", *format)
+	}
+
+	manifest, err := loadKeyManifest(*manifestPath)
+	if err != nil {
+		return err
+	}
+	key, fingerprint, err := deriveServiceKeyFrom(*masterRef, *label, *size)
+	if err != nil {
+		return err
+	}
+	defer wipe(key)
+	if manifest.MasterFingerprint == "" {
+		manifest.MasterFingerprint = fingerprint
+	} else if !secretEqualString(manifest.MasterFingerprint, fingerprint) {
+		return errorf("манифест %s относится к другому мастер-сиду (%s)", *manifestPath, manifest.MasterFingerprint)
+	}
+
+	entry := keyManifestEntry{
+		Label:       *label,
+		Bytes:       *size,
+		Fingerprint: serviceKeyFingerprint(key),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+	known, err := manifest.check(entry)
+	if err != nil {
+		return errorf("коллизия меток в %s: %w", *manifestPath, err)
+	}
+	if known {
+		logInfof("✓ Ключ %q уже есть в манифесте, отпечаток совпадает: %s\n", entry.Label, entry.Fingerprint)
+	} else {
+		manifest.Keys = append(manifest.Keys, entry)
+		if err := manifest.save(*manifestPath); err != nil {
+			return errorf("ошибка записи манифеста: %w", err)
+		}
+		logInfof("✓ Метка %q записана в %s, отпечаток: %s\n", entry.Label, *manifestPath, entry.Fingerprint)
+	}
+
+	if *format == "base64" {
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+	} else {
+		fmt.Println(hexString(key))
+	}
+	return nil
+}
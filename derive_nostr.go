@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// nostrKey - ключи Nostr в кодировке NIP-19
+type nostrKey struct {
+	Path   string `json:"path"`
+	Npub   string `json:"npub"`
+	Pubkey string `json:"pubkey"`
+	Nsec   string `json:"nsec,omitempty"`
+}
+
+// nostrKeyFromSeed выводит ключ по пути NIP-06 m/44'/1237'/<account>'/0/0
+// из сида BIP39; открытым ключом служит x-координата точки (BIP-340)
+func nostrKeyFromSeed(seed []byte, account uint32) (nostrKey, error) {
+	path := derivationPath{hardened(44), hardened(1237), hardened(account), 0, 0}
+	key, err := deriveHDKey(curveSecp256k1, seed, path)
+	if err != nil {
+		return nostrKey{}, err
+	}
+	x := secpScalarBaseMult(key.key).compressed()[1:]
+	nsec, err := bech32Encode("nsec", key.key)
+	if err != nil {
+		return nostrKey{}, err
+	}
+	npub, err := bech32Encode("npub", x)
+	if err != nil {
+		return nostrKey{}, err
+	}
+	return nostrKey{Path: path.String(), Npub: npub, Pubkey: hex.EncodeToString(x), Nsec: nsec}, nil
+}
+
+// runDeriveNostr выводит ключи Nostr по NIP-06 из мнемоники кошелька
+func runDeriveNostr(args []string) error {
+	fs := newFlagSet("derive nostr")
+	account := fs.Uint("account", 0, "номер счета NIP-06")
+	public := fs.Bool("public", false, "вывести только открытый ключ")
+	format := fs.String("format", "text", "формат вывода: text или json")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if uint64(*account) >= uint64(hardenedOffset) {
+		return errorf("номер счета должен быть меньше %d", hardenedOffset)
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	seed, err := walletSeed(master)
+	if err != nil {
+		return err
+	}
+	defer wipe(seed)
+	key, err := nostrKeyFromSeed(seed, uint32(*account))
+	if err != nil {
+		return err
+	}
+	if *public {
+		key.Nsec = ""
+	}
+
+	if *format == "json" {
+		return writeOutput(os.Stdout, key)
+	}
+	fmt.Printf(tr("Путь:   %s\n"), key.Path)
+	fmt.Printf("npub:   %s\n", key.Npub)
+	fmt.Printf("pubkey: %s\n", key.Pubkey)
+	if key.Nsec != "" {
+		fmt.Printf("nsec:   %s\n", key.Nsec)
+	}
+	return nil
+}
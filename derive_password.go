@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// passwordClasses - классы символов в порядке включения политикой classes=N
+var passwordClasses = []string{
+	"abcdefghijklmnopqrstuvwxyz",
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"0123456789",
+	// Без кавычек, обратной косой черты и пробела, которые ломают формы и shell
+	"!#$%&*+-=?@^_",
+}
+
+// passwordPolicy - длина пароля и число обязательных классов символов
+type passwordPolicy struct {
+	length  int
+	classes int
+}
+
+// parsePasswordPolicy разбирает политику вида len=20,classes=4
+func parsePasswordPolicy(s string) (passwordPolicy, error) {
+	p := passwordPolicy{length: 20, classes: 4}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return p, errorf("ожидалось ключ=значение, получено %q", part)
+		}
+		n, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return p, errorf("%s: ожидалось число, получено %q", kv[0], kv[1])
+		}
+		switch kv[0] {
+		case "len":
+			p.length = n
+		case "classes":
+			p.classes = n
+		default:
+			return p, errorf("неизвестный параметр политики %q (доступны: len, classes)", kv[0])
+		}
+	}
+	if p.length < 8 || p.length > 128 {
+		return p, errorf("длина пароля должна быть от 8 до 128")
+	}
+	if p.classes < 1 || p.classes > len(passwordClasses) {
+		return p, errorf("число классов символов должно быть от 1 до %d", len(passwordClasses))
+	}
+	return p, nil
+}
+
+// keyStream - детерминированный поток байтов HMAC-SHA512 в режиме счетчика
+type keyStream struct {
+	key     []byte
+	counter uint32
+	buf     []byte
+}
+
+// next возвращает следующий байт потока
+func (s *keyStream) next() byte {
+	if len(s.buf) == 0 {
+		var block [4]byte
+		binary.BigEndian.PutUint32(block[:], s.counter)
+		s.counter++
+		mac := hmac.New(sha512.New, s.key)
+		mac.Write(block[:])
+		s.buf = mac.Sum(nil)
+	}
+	b := s.buf[0]
+	s.buf = s.buf[1:]
+	return b
+}
+
+// uniform возвращает равномерно распределенное число из [0, n), n <= 256;
+// отбрасывание лишних значений устраняет смещение остатка от деления
+func (s *keyStream) uniform(n int) int {
+	limit := 256 - 256%n
+	for {
+		if b := int(s.next()); b < limit {
+			return b % n
+		}
+	}
+}
+
+// derivePassword строит пароль политики: по одному символу каждого
+// обязательного класса, остальные из их объединения, затем перемешивание
+func derivePassword(key []byte, p passwordPolicy) string {
+	s := &keyStream{key: key}
+	alphabet := strings.Join(passwordClasses[:p.classes], "")
+	out := make([]byte, 0, p.length)
+	for _, class := range passwordClasses[:p.classes] {
+		out = append(out, class[s.uniform(len(class))])
+	}
+	for len(out) < p.length {
+		out = append(out, alphabet[s.uniform(len(alphabet))])
+	}
+	for i := len(out) - 1; i > 0; i-- {
+		j := s.uniform(i + 1)
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// runDerivePassword выводит пароль для сайта
+func runDerivePassword(args []string) error {
+	fs := newFlagSet("derive password")
+	site := fs.String("site", "", "сайт или сервис, например example.com")
+	policyFlag := fs.String("policy", "len=20,classes=4", "политика: len - длина, classes - число классов (строчные, заглавные, цифры, символы)")
+	counter := fs.Uint("counter", 1, "номер пароля: увеличьте, чтобы сменить пароль сайта")
+	copyResult := fs.Bool("copy", false, "скопировать пароль в буфер обмена вместо вывода")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	name := strings.ToLower(strings.TrimSpace(*site))
+	if name == "" {
+		return errorf("укажите сайт через --site")
+	}
+	policy, err := parsePasswordPolicy(*policyFlag)
+	if err != nil {
+		return errorf("политика пароля: %w", err)
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	// Политика входит в метку: иначе короткий пароль был бы префиксом длинного
+	label := fmt.Sprintf("%s/%d/len=%d,classes=%d", name, *counter, policy.length, policy.classes)
+	password := derivePassword(deriveKindKey(master, "password", label), policy)
+
+	if *copyResult {
+		if err := copyToClipboard([]byte(password)); err != nil {
+			return errorf("ошибка копирования в буфер обмена: %w", err)
+		}
+		logInfof("✓ Пароль скопирован в буфер обмена, после использования выполните seedgen wipe\n")
+		return nil
+	}
+	fmt.Println(password)
+	return nil
+}
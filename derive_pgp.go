@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// Теги пакетов и алгоритмы OpenPGP (RFC 4880, RFC 6637)
+const (
+	pgpTagSignature    = 2
+	pgpTagSecretKey    = 5
+	pgpTagPublicKey    = 6
+	pgpTagSecretSubkey = 7
+	pgpTagUserID       = 13
+	pgpTagPublicSubkey = 14
+
+	pgpAlgoECDH   = 18
+	pgpAlgoEdDSA  = 22
+	pgpHashSHA256 = 8
+
+	pgpSigPositiveCert  = 0x13
+	pgpSigSubkeyBinding = 0x18
+)
+
+// OID кривых Ed25519 и Curve25519 в кодировке OpenPGP
+var (
+	pgpOIDEd25519    = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0xda, 0x47, 0x0f, 0x01}
+	pgpOIDCurve25519 = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x97, 0x55, 0x01, 0x05, 0x01}
+)
+
+// pgpMPI кодирует целое без знака в формате MPI: число бит и байты без ведущих нулей
+func pgpMPI(b []byte) []byte {
+	for len(b) > 0 && b[0] == 0 {
+		b = b[1:]
+	}
+	bits := len(b) * 8
+	if len(b) > 0 {
+		for top := b[0]; top&0x80 == 0; top <<= 1 {
+			bits--
+		}
+	}
+	return append([]byte{byte(bits >> 8), byte(bits)}, b...)
+}
+
+// pgpPacket оборачивает тело пакета заголовком нового формата
+func pgpPacket(tag byte, body []byte) []byte {
+	out := []byte{0xc0 | tag}
+	switch n := len(body); {
+	case n < 192:
+		out = append(out, byte(n))
+	case n < 8384:
+		n -= 192
+		out = append(out, byte(n>>8)+192, byte(n))
+	default:
+		out = append(out, 0xff, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(out, body...)
+}
+
+// pgpSubpacket кодирует подпакет подписи
+func pgpSubpacket(typ byte, data ...byte) []byte {
+	return append([]byte{byte(len(data) + 1), typ}, data...)
+}
+
+// pgpKey - открытая часть ключа OpenPGP версии 4
+type pgpKey struct {
+	body []byte
+}
+
+// newPGPKey кодирует тело пакета открытого ключа
+func newPGPKey(created time.Time, algo byte, oid, point, extra []byte) pgpKey {
+	body := []byte{4, 0, 0, 0, 0, algo, byte(len(oid))}
+	binary.BigEndian.PutUint32(body[1:5], uint32(created.Unix()))
+	body = append(body, oid...)
+	body = append(body, pgpMPI(append([]byte{0x40}, point...))...)
+	return pgpKey{body: append(body, extra...)}
+}
+
+// hashPrefix возвращает ключ в виде, который входит в хэши отпечатка и подписей
+func (k pgpKey) hashPrefix() []byte {
+	return append([]byte{0x99, byte(len(k.body) >> 8), byte(len(k.body))}, k.body...)
+}
+
+// fingerprint возвращает отпечаток ключа версии 4
+func (k pgpKey) fingerprint() []byte {
+	sum := sha1.Sum(k.hashPrefix())
+	return sum[:]
+}
+
+// pgpSign создает подпись типа sigType; hashed - данные, предшествующие
+// самой подписи в хэше (ключ, идентификатор пользователя или подключ)
+func pgpSign(priv ed25519.PrivateKey, issuer pgpKey, sigType byte, created time.Time, hashed []byte, subpackets ...[]byte) []byte {
+	var ts [4]byte
+	binary.BigEndian.PutUint32(ts[:], uint32(created.Unix()))
+	fp := issuer.fingerprint()
+
+	hashedSub := pgpSubpacket(2, ts[:]...)
+	hashedSub = append(hashedSub, pgpSubpacket(33, append([]byte{4}, fp...)...)...)
+	for _, sp := range subpackets {
+		hashedSub = append(hashedSub, sp...)
+	}
+	head := []byte{4, sigType, pgpAlgoEdDSA, pgpHashSHA256, byte(len(hashedSub) >> 8), byte(len(hashedSub))}
+	head = append(head, hashedSub...)
+
+	h := sha256.New()
+	h.Write(hashed)
+	h.Write(head)
+	trailer := []byte{4, 0xff, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(trailer[2:], uint32(len(head)))
+	h.Write(trailer)
+	digest := h.Sum(nil)
+	sig := ed25519.Sign(priv, digest)
+
+	unhashed := pgpSubpacket(16, fp[12:]...)
+	body := append(head, byte(len(unhashed)>>8), byte(len(unhashed)))
+	body = append(body, unhashed...)
+	body = append(body, digest[:2]...)
+	body = append(body, pgpMPI(sig[:32])...)
+	body = append(body, pgpMPI(sig[32:])...)
+	return pgpPacket(pgpTagSignature, body)
+}
+
+// pgpSecretBody дописывает к открытому ключу незашифрованную секретную часть
+func pgpSecretBody(pub pgpKey, secret []byte) []byte {
+	mpi := pgpMPI(secret)
+	var sum uint16
+	for _, b := range mpi {
+		sum += uint16(b)
+	}
+	body := append(append([]byte{}, pub.body...), 0)
+	body = append(body, mpi...)
+	return append(body, byte(sum>>8), byte(sum))
+}
+
+// pgpCRC24 вычисляет контрольную сумму ASCII Armor
+func pgpCRC24(data []byte) uint32 {
+	crc := uint32(0xb704ce)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864cfb
+			}
+		}
+	}
+	return crc & 0xffffff
+}
+
+// pgpArmor кодирует пакеты в ASCII Armor
+func pgpArmor(blockType string, data []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "-----BEGIN PGP %s-----\n\n", blockType)
+	text := base64.StdEncoding.EncodeToString(data)
+	for len(text) > 64 {
+		buf.WriteString(text[:64] + "\n")
+		text = text[64:]
+	}
+	buf.WriteString(text + "\n")
+	crc := pgpCRC24(data)
+	buf.WriteString("=" + base64.StdEncoding.EncodeToString([]byte{byte(crc >> 16), byte(crc >> 8), byte(crc)}) + "\n")
+	fmt.Fprintf(&buf, "-----END PGP %s-----\n", blockType)
+	return buf.Bytes()
+}
+
+// pgpCertificate строит открытый и секретный ключи OpenPGP: основной ключ
+// Ed25519 для подписи и подключ X25519 для шифрования. Подписи EdDSA
+// детерминированы, поэтому при той же дате создания результат совпадает.
+func pgpCertificate(signSeed, encryptSecret []byte, uid string, created time.Time) (public, secret []byte, fingerprint string, err error) {
+	priv := ed25519.NewKeyFromSeed(signSeed)
+	primary := newPGPKey(created, pgpAlgoEdDSA, pgpOIDEd25519, priv.Public().(ed25519.PublicKey), nil)
+
+	scalar := append([]byte{}, encryptSecret...)
+	scalar[0] &= 248
+	scalar[31] = scalar[31]&127 | 64
+	point, err := curve25519.X25519(scalar, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	// Параметры KDF ECDH: SHA-256 и AES-128
+	subkey := newPGPKey(created, pgpAlgoECDH, pgpOIDCurve25519, point, []byte{3, 1, pgpHashSHA256, 7})
+
+	uidHash := append(primary.hashPrefix(), 0xb4, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(uidHash[len(uidHash)-4:], uint32(len(uid)))
+	uidHash = append(uidHash, uid...)
+	certSig := pgpSign(priv, primary, pgpSigPositiveCert, created, uidHash,
+		pgpSubpacket(27, 0x03),     // сертификация и подпись
+		pgpSubpacket(11, 9, 8, 7),  // AES-256, AES-192, AES-128
+		pgpSubpacket(21, 10, 9, 8), // SHA-512, SHA-384, SHA-256
+		pgpSubpacket(22, 2, 3, 1),  // ZLIB, BZip2, ZIP
+		pgpSubpacket(30, 0x01),     // MDC
+		pgpSubpacket(23, 0x80))     // без изменений на сервере ключей
+	bindSig := pgpSign(priv, primary, pgpSigSubkeyBinding, created,
+		append(primary.hashPrefix(), subkey.hashPrefix()...),
+		pgpSubpacket(27, 0x0c)) // шифрование связи и хранения
+
+	uidPacket := pgpPacket(pgpTagUserID, []byte(uid))
+	var pub, sec bytes.Buffer
+	pub.Write(pgpPacket(pgpTagPublicKey, primary.body))
+	sec.Write(pgpPacket(pgpTagSecretKey, pgpSecretBody(primary, signSeed)))
+	for _, b := range []*bytes.Buffer{&pub, &sec} {
+		b.Write(uidPacket)
+		b.Write(certSig)
+	}
+	pub.Write(pgpPacket(pgpTagPublicSubkey, subkey.body))
+	pub.Write(bindSig)
+
+	// Секрет X25519 хранится в OpenPGP в обратном порядке байтов
+	reversed := make([]byte, len(scalar))
+	for i, b := range scalar {
+		reversed[len(scalar)-1-i] = b
+	}
+	sec.Write(pgpPacket(pgpTagSecretSubkey, pgpSecretBody(subkey, reversed)))
+	sec.Write(bindSig)
+
+	return pgpArmor("PUBLIC KEY BLOCK", pub.Bytes()), pgpArmor("PRIVATE KEY BLOCK", sec.Bytes()),
+		strings.ToUpper(hex.EncodeToString(primary.fingerprint())), nil
+}
+
+// runDerivePGP выводит ключ OpenPGP с фиксированной датой создания
+func runDerivePGP(args []string) error {
+	fs := newFlagSet("derive pgp")
+	uid := fs.String("uid", "", "идентификатор пользователя, например \"Имя <mail@example.com>\"; от него зависит ключ")
+	createdFlag := fs.String("created", "2025-01-01", "дата создания ключа, ГГГГ-ММ-ДД (UTC)")
+	withSecret := fs.Bool("secret", false, "вывести секретный ключ вместо открытого")
+	out := fs.String("out", "", "сохранить открытый ключ в PREFIX.asc, а секретный в PREFIX.sec.asc")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *uid == "" {
+		return errorf("укажите идентификатор пользователя через --uid")
+	}
+	created, err := parseCADate("created", *createdFlag)
+	if err != nil {
+		return err
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	public, secret, fingerprint, err := pgpCertificate(
+		deriveKindKey(master, "pgp-sign", *uid), deriveKindKey(master, "pgp-encrypt", *uid), *uid, created)
+	if err != nil {
+		return err
+	}
+	logInfof("Отпечаток ключа: %s\n", emphErr(fingerprint))
+
+	if *out != "" {
+		if err := writeNewFile(*out+".sec.asc", secret, 0600); err != nil {
+			return err
+		}
+		if err := writeNewFile(*out+".asc", public, 0644); err != nil {
+			return err
+		}
+		logInfof("✓ Открытый ключ: %s.asc\n✓ Секретный ключ: %s.sec.asc\n", *out, *out)
+		return nil
+	}
+	if *withSecret {
+		os.Stdout.Write(secret)
+	} else {
+		os.Stdout.Write(public)
+	}
+	return nil
+}
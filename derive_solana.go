@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+)
+
+// solanaKeyFromSeed выводит ключ Solana по пути m/44'/501'/<index>'/0',
+// который используют solana-keygen (--derivation-path) и Phantom
+func solanaKeyFromSeed(seed []byte, index uint32) (ed25519.PrivateKey, derivationPath, error) {
+	path := derivationPath{hardened(44), hardened(501), hardened(index), hardened(0)}
+	key, err := deriveHDKey(curveEd25519, seed, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key.ed25519(), path, nil
+}
+
+// solanaKeypairJSON кодирует ключ в формате файла solana-keygen:
+// массив из 64 чисел (закрытый ключ и открытый ключ)
+func solanaKeypairJSON(priv ed25519.PrivateKey) ([]byte, error) {
+	values := make([]int, len(priv))
+	for i, b := range priv {
+		values[i] = int(b)
+	}
+	return json.Marshal(values)
+}
+
+// runDeriveSolana выводит ключ Solana из мнемоники кошелька
+func runDeriveSolana(args []string) error {
+	fs := newFlagSet("derive solana")
+	index := fs.Uint("index", 0, "номер счета N в пути m/44'/501'/N'/0'")
+	out := fs.String("out", "", "сохранить ключ в файл формата solana-keygen")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if uint64(*index) >= uint64(hardenedOffset) {
+		return errorf("номер счета должен быть меньше %d", hardenedOffset)
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	seed, err := walletSeed(master)
+	if err != nil {
+		return err
+	}
+	defer wipe(seed)
+	priv, path, err := solanaKeyFromSeed(seed, uint32(*index))
+	if err != nil {
+		return err
+	}
+	address := base58Encode(priv.Public().(ed25519.PublicKey))
+	keypair, err := solanaKeypairJSON(priv)
+	if err != nil {
+		return err
+	}
+
+	logInfof("Путь: %s\n", path.String())
+	if *out != "" {
+		if err := writeNewFile(*out, append(keypair, '\n'), 0600); err != nil {
+			return err
+		}
+		logInfof("✓ Ключ сохранен: %s\n", *out)
+		fmt.Println(address)
+		return nil
+	}
+	logInfof("Адрес: %s\n", address)
+	fmt.Println(string(keypair))
+	return nil
+}
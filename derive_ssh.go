@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// sshKeyType - тип ключа OpenSSH для Ed25519
+const sshKeyType = "ssh-ed25519"
+
+// sshString дописывает строку в формате SSH: длина uint32 и байты
+func sshString(buf *bytes.Buffer, s []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(s)))
+	buf.Write(length[:])
+	buf.Write(s)
+}
+
+// sshPublicKeyBlob кодирует открытый ключ в формате проводного протокола SSH
+func sshPublicKeyBlob(pub ed25519.PublicKey) []byte {
+	var buf bytes.Buffer
+	sshString(&buf, []byte(sshKeyType))
+	sshString(&buf, pub)
+	return buf.Bytes()
+}
+
+// sshAuthorizedKey возвращает строку для authorized_keys
+func sshAuthorizedKey(pub ed25519.PublicKey, comment string) string {
+	line := sshKeyType + " " + base64.StdEncoding.EncodeToString(sshPublicKeyBlob(pub))
+	if comment != "" {
+		line += " " + comment
+	}
+	return line
+}
+
+// sshFingerprint возвращает отпечаток SHA256 в виде, выводимом ssh-keygen -l
+func sshFingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(sshPublicKeyBlob(pub))
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// marshalOpenSSHPrivateKey кодирует ключ в формате openssh-key-v1 без шифрования.
+// Контрольное число обычно случайное; здесь оно выводится из ключа,
+// чтобы повторный вывод давал побайтно тот же файл.
+func marshalOpenSSHPrivateKey(priv ed25519.PrivateKey, comment string) []byte {
+	pub := priv.Public().(ed25519.PublicKey)
+	check := sha256.Sum256(append([]byte("seedgen/ssh/checkint"), priv.Seed()...))
+
+	var private bytes.Buffer
+	private.Write(check[:4])
+	private.Write(check[:4])
+	sshString(&private, []byte(sshKeyType))
+	sshString(&private, pub)
+	sshString(&private, priv)
+	sshString(&private, []byte(comment))
+	// Дополнение до размера блока 8 байтами 1, 2, 3, ...
+	for i := byte(1); private.Len()%8 != 0; i++ {
+		private.WriteByte(i)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("openssh-key-v1\x00")
+	sshString(&buf, []byte("none"))
+	sshString(&buf, []byte("none"))
+	sshString(&buf, nil)
+	buf.Write([]byte{0, 0, 0, 1})
+	sshString(&buf, sshPublicKeyBlob(pub))
+	sshString(&buf, private.Bytes())
+	return pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: buf.Bytes()})
+}
+
+// runDeriveSSH выводит ключ OpenSSH Ed25519 по пути SLIP-0010
+func runDeriveSSH(args []string) error {
+	fs := newFlagSet("derive ssh")
+	path := fs.String("path", "m/22'/0'", "путь SLIP-0010 (только усиленные индексы)")
+	comment := fs.String("comment", "seedgen", "комментарий ключа")
+	out := fs.String("out", "", "сохранить закрытый ключ в файл (открытый - рядом с суффиксом .pub)")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	hdPath, err := parseDerivationPath(*path)
+	if err != nil {
+		return err
+	}
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	key, err := deriveHDKey(curveEd25519, master, hdPath)
+	if err != nil {
+		return err
+	}
+	priv := key.ed25519()
+	pub := priv.Public().(ed25519.PublicKey)
+	privPEM := marshalOpenSSHPrivateKey(priv, *comment)
+	authorized := sshAuthorizedKey(pub, *comment)
+
+	logInfof("Путь: %s\n", hdPath.String())
+	logInfof("Отпечаток: %s\n", emphErr(sshFingerprint(pub)))
+
+	if *out != "" {
+		if err := writeNewFile(*out, privPEM, 0600); err != nil {
+			return err
+		}
+		if err := writeNewFile(*out+".pub", []byte(authorized+"\n"), 0644); err != nil {
+			return err
+		}
+		logInfof("✓ Закрытый ключ: %s\n✓ Открытый ключ: %s.pub\n", *out, *out)
+		fmt.Println(authorized)
+		return nil
+	}
+	os.Stdout.Write(privPEM)
+	fmt.Println(authorized)
+	return nil
+}
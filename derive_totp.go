@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Параметры TOTP, которые понимают все приложения-аутентификаторы
+const (
+	totpSecretSize = 20
+	totpDigits     = 6
+	totpPeriod     = 30
+)
+
+// totpCode вычисляет одноразовый код HOTP (RFC 4226) для шага времени TOTP (RFC 6238)
+func totpCode(secret []byte, t time.Time, digits int) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(t.Unix())/totpPeriod)
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// totpURI строит URI otpauth:// в формате Key Uri Format
+func totpURI(secret []byte, issuer, account string) string {
+	q := url.Values{}
+	q.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret))
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	u := url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + issuer + ":" + account, RawQuery: q.Encode()}
+	return u.String()
+}
+
+// runDeriveTOTP выводит секрет TOTP для сервиса в виде URI и QR-кода
+func runDeriveTOTP(args []string) error {
+	fs := newFlagSet("derive totp")
+	service := fs.String("service", "", "имя сервиса; от него зависит секрет")
+	account := fs.String("account", "seedgen", "имя учетной записи в приложении-аутентификаторе")
+	issuer := fs.String("issuer", "", "издатель в приложении-аутентификаторе (по умолчанию - имя сервиса)")
+	showQR := fs.Bool("qr", true, "вывести QR-код для сканирования")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *service == "" {
+		return errorf("укажите сервис через --service")
+	}
+	if *issuer == "" {
+		*issuer = *service
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	secret := deriveKindKey(master, "totp", *service)[:totpSecretSize]
+	uri := totpURI(secret, *issuer, *account)
+
+	fmt.Println(uri)
+	if *showQR {
+		q, err := encodeQR([]byte(uri))
+		if err != nil {
+			return err
+		}
+		q.render(os.Stdout)
+	}
+	logInfof("Текущий код: %s (для проверки после сканирования)\n", totpCode(secret, time.Now(), totpDigits))
+	return nil
+}
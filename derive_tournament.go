@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// tournamentHKDFSalt - соль HKDF для мастер-сидов турниров; отличается от
+// соли derive key, поэтому мастер-сид турнира не совпадет с ключом сервиса
+// под той же меткой
+const tournamentHKDFSalt = "seedgen/derive/tournament/v1"
+
+// tournamentRecord - артефакт мастер-сида турнира. Его принимает --master
+// любой команды, как результат generate.
+type tournamentRecord struct {
+	Kind              string    `json:"kind"`
+	Tournament        string    `json:"tournament"`
+	ParentFingerprint string    `json:"parent_fingerprint"`
+	Master            secretHex `json:"master"`
+	Fingerprint       string    `json:"fingerprint"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// deriveTournamentSeed выводит 64-байтовый мастер-сид турнира id через
+// HKDF-SHA512. Мастер-сиды разных турниров независимы: по одному нельзя
+// узнать ни другие, ни мастер-сид организации.
+func deriveTournamentSeed(master []byte, id string) ([]byte, error) {
+	seed := newSecret(masterSeedSize)
+	if _, err := io.ReadFull(hkdf.New(sha512.New, master, []byte(tournamentHKDFSalt), []byte(id)), seed); err != nil {
+		wipe(seed)
+		return nil, err
+	}
+	return seed, nil
+}
+
+// runDeriveTournament выводит мастер-сид турнира или мероприятия из мастер-сида
+// организации. Жеребьевки и ключи турнира выводятся уже из него, поэтому
+// раскрытие данных одного турнира не затрагивает остальные, а все они
+// восстанавливаются из мастер-сида церемонии.
+func runDeriveTournament(args []string) error {
+	fs := newFlagSet("derive tournament")
+	id := fs.String("id", "", "идентификатор турнира, например champ-2025-regional-kazan")
+	format := fs.String("format", "hex", "формат вывода: hex, json (артефакт для --master) или rs (бумажная копия)")
+	out := fs.String("out", "", "сохранить артефакт JSON в новый файл (права 0600)")
+	rf := addRevealFlags(fs)
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return errorf("укажите идентификатор турнира через --id")
+	}
+	if !keyLabelPattern.MatchString(*id) {
+		return errorf("идентификатор %q: допустимы строчные латинские буквы, цифры и \".-_\" внутри, до 64 символов", *id)
+	}
+	if *format != "hex" && *format != "json" && *format != "rs" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	if *out != "" && setFlags(fs)["format"] && *format != "json" {
+		return errorf("--out сохраняет только артефакт JSON")
+	}
+	if err := rf.checkGroups(*format == "hex" && *out == ""); err != nil {
+		return err
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	seed, err := deriveTournamentSeed(master, *id)
+	if err != nil {
+		return err
+	}
+	defer wipe(seed)
+
+	record := tournamentRecord{
+		Kind:              "tournament-seed",
+		Tournament:        *id,
+		ParentFingerprint: masterFingerprint(master),
+		Master:            seed,
+		Fingerprint:       masterFingerprint(seed),
+		CreatedAt:         time.Now().UTC().Truncate(time.Second),
+	}
+	auditResult("master", *id, record.Fingerprint)
+	logInfof("Турнир: %s\n", *id)
+	logInfof("Отпечаток мастер-сида организации: %s\n", record.ParentFingerprint)
+	logInfof("Отпечаток мастер-сида турнира: %s\n", record.Fingerprint)
+
+	switch {
+	case *out != "":
+		data, err := stampedJSON(record)
+		if err != nil {
+			return err
+		}
+		defer wipe(data)
+		if err := writeNewFile(*out, data, 0600); err != nil {
+			return err
+		}
+		logInfof("✓ Мастер-сид турнира сохранен: %s\n", *out)
+		return nil
+	case *format == "json":
+		return writeOutput(os.Stdout, record)
+	case *format == "rs":
+		return writeRSBackup(os.Stdout, seed)
+	case !isTerminal(os.Stdout) && !rf.grouped():
+		return writeSecretLine(os.Stdout, seed)
+	}
+	return rf.reveal(fmt.Sprintf(tr("Мастер-сид турнира %s:"), *id), seed)
+}
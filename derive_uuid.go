@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// uuidNamespace выводит пространство имен UUID из мастер-сида. Его можно
+// опубликовать: сервисы вычислят те же идентификаторы стандартным UUIDv5,
+// не имея доступа к мастер-сиду.
+func uuidNamespace(master []byte) []byte {
+	ns := deriveKindKey(master, "uuid", "namespace")[:16]
+	ns[6] = ns[6]&0x0f | 0x40
+	ns[8] = ns[8]&0x3f | 0x80
+	return ns
+}
+
+// uuidV5 вычисляет UUID версии 5 (RFC 4122) для имени в пространстве ns
+func uuidV5(ns []byte, name string) string {
+	return formatUUID(uuidV5Bytes(ns, name))
+}
+
+// uuidV5Bytes вычисляет 16 байт UUID версии 5
+func uuidV5Bytes(ns []byte, name string) []byte {
+	h := sha1.New()
+	h.Write(ns)
+	h.Write([]byte(name))
+	u := h.Sum(nil)[:16]
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	return u
+}
+
+// runDeriveUUID выводит стабильные идентификаторы для имен
+func runDeriveUUID(args []string) error {
+	fs := newFlagSet("derive uuid")
+	var names stringList
+	fs.Var(&names, "name", "имя объекта, например team:falcons (флаг можно указать несколько раз)")
+	showNamespace := fs.Bool("namespace", false, "вывести пространство имен UUIDv5 для сервисов")
+	short := fs.Bool("short", false, "вывести и короткий идентификатор: первые 40 бит UUID в Base32 Крокфорда")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if len(names) == 0 && !*showNamespace {
+		return errorf("укажите хотя бы одно имя через --name или флаг --namespace")
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	ns := uuidNamespace(master)
+	if *showNamespace {
+		fmt.Println(formatUUID(ns))
+		if len(names) == 0 {
+			return nil
+		}
+	}
+	if len(names) == 1 && !*short {
+		fmt.Println(uuidV5(ns, names[0]))
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		if *short {
+			ref := newMatchRef(ns, name)
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", ref.UUID, ref.Short, name)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", uuidV5(ns, name), name)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// wireguardPeer - ключи одного узла WireGuard
+type wireguardPeer struct {
+	Peer       string `json:"peer"`
+	PrivateKey string `json:"private_key"`
+	PublicKey  string `json:"public_key"`
+}
+
+// wireguardKeyPair выводит ключи узла; закрытый ключ ограничивается
+// (clamping) так же, как это делает wg genkey
+func wireguardKeyPair(master []byte, peer string) (wireguardPeer, error) {
+	priv := deriveKindKey(master, "wireguard", peer)
+	priv[0] &= 248
+	priv[31] = priv[31]&127 | 64
+	pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		return wireguardPeer{}, err
+	}
+	return wireguardPeer{
+		Peer:       peer,
+		PrivateKey: base64.StdEncoding.EncodeToString(priv),
+		PublicKey:  base64.StdEncoding.EncodeToString(pub),
+	}, nil
+}
+
+// runDeriveWireGuard выводит ключи WireGuard для именованных узлов
+func runDeriveWireGuard(args []string) error {
+	fs := newFlagSet("derive wireguard")
+	var peers stringList
+	fs.Var(&peers, "peer", "имя узла (флаг можно указать несколько раз)")
+	format := fs.String("format", "text", "формат вывода: text или json")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if len(peers) == 0 {
+		return errorf("укажите хотя бы один узел через --peer")
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	seen := make(map[string]bool)
+	for _, p := range peers {
+		if p == "" {
+			return errorf("имя узла не может быть пустым")
+		}
+		if seen[p] {
+			return errorf("узел %q указан несколько раз", p)
+		}
+		seen[p] = true
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	var keys []wireguardPeer
+	for _, p := range peers {
+		k, err := wireguardKeyPair(master, p)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, k)
+	}
+
+	if *format == "json" {
+		return writeOutput(os.Stdout, keys)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, tr("Узел\tЗакрытый ключ\tОткрытый ключ"))
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", k.Peer, k.PrivateKey, k.PublicKey)
+	}
+	return tw.Flush()
+}
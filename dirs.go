@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// appDir - имя каталога seedgen в каталогах пользователя
+const appDir = "seedgen"
+
+// platformDir возвращает каталог seedgen по соглашениям системы: в
+// Windows - из переменной windowsVar (%APPDATA% или %LOCALAPPDATA%), в macOS
+// - ~/Library/Application Support/seedgen, иначе ~/xdgDefault/seedgen.
+// Переменная XDG, если задана, важнее на любой системе.
+func platformDir(xdgVar, windowsVar, xdgDefault string) (string, error) {
+	if dir := os.Getenv(xdgVar); dir != "" {
+		return filepath.Join(dir, appDir), nil
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv(windowsVar); dir != "" {
+			return filepath.Join(dir, appDir), nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errorf("не удалось определить домашний каталог: %w", err)
+	}
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(home, "Library", "Application Support", appDir), nil
+	}
+	return filepath.Join(home, filepath.FromSlash(xdgDefault), appDir), nil
+}
+
+// configDir возвращает каталог конфигурации: $XDG_CONFIG_HOME/seedgen,
+// %APPDATA%\seedgen, ~/Library/Application Support/seedgen или
+// ~/.config/seedgen
+func configDir() (string, error) {
+	return platformDir("XDG_CONFIG_HOME", "APPDATA", ".config")
+}
+
+// stateDir возвращает каталог состояния seedgen: $XDG_STATE_HOME/seedgen,
+// %LOCALAPPDATA%\seedgen, ~/Library/Application Support/seedgen или
+// ~/.local/state/seedgen
+func stateDir() (string, error) {
+	return platformDir("XDG_STATE_HOME", "LOCALAPPDATA", ".local/state")
+}
+
+// auditDir возвращает каталог протоколов audit run, сохраняемых без --out
+func auditDir() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "audit"), nil
+}
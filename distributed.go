@@ -0,0 +1,740 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ceremonyDefaultAddr - адрес координатора распределенной церемонии по умолчанию
+const ceremonyDefaultAddr = ":7465"
+
+// ceremonyMessage - сообщение протокола распределенной церемонии. Стороны
+// обмениваются строками JSON поверх TLS 1.3 с взаимной аутентификацией:
+//
+//	участник -> координатор: commit  (обязательства по своим сидам)
+//	координатор -> участник: reveal  (параметры и обязательства всех участников)
+//	участник -> координатор: seeds   (сиды, соответствующие обязательствам)
+//	координатор -> участник: result  (отпечаток мастер-сида и обязательство набора)
+//
+// Сиды передаются только после того, как собраны обязательства всех
+// участников, поэтому никто не может подобрать свой сид под чужие.
+// В схеме v3 вместо seeds участник отправляет masked: сумму растянутых
+// вкладов своих сидов под попарными масками (aggregate.go), и координатор
+// не получает ни сидов, ни отдельных вкладов - только их общую сумму.
+// Мастер-сид по сети не передается никогда.
+//
+// С порогом FROST после result следует DKG (frost.go): frost-commit,
+// frost-commitments, frost-shares в обе стороны, frost-done и frost-result.
+// Если координатор собирает протокол, в конце он по очереди отправляет
+// каждому участнику transcript и ждет transcript-signature, а подписанный
+// всеми протокол рассылает в transcript-final (cosign.go).
+type ceremonyMessage struct {
+	Type           string         `json:"type"`
+	Commitments    []string       `json:"commitments,omitempty"`
+	Seeds          []secretHex    `json:"seeds,omitempty"`
+	MaskKey        string         `json:"mask_key,omitempty"`
+	MaskKeys       []string       `json:"mask_keys,omitempty"`
+	Masked         secretHex      `json:"masked,omitempty"`
+	Params         *Params        `json:"params,omitempty"`
+	Participants   []string       `json:"participants,omitempty"`
+	Fingerprint    string         `json:"fingerprint,omitempty"`
+	Words          string         `json:"words,omitempty"`
+	FrostThreshold int            `json:"frost_threshold,omitempty"`
+	Frost          []frostPackage `json:"frost,omitempty"`
+	FrostShares    []string       `json:"frost_shares,omitempty"`
+	GroupKey       string         `json:"group_key,omitempty"`
+	// TranscriptSigning в reveal предупреждает, что участникам предстоит
+	// подписать протокол
+	TranscriptSigning bool                   `json:"transcript_signing,omitempty"`
+	Transcript        *transcript            `json:"transcript,omitempty"`
+	Cosignature       *transcriptCosignature `json:"cosignature,omitempty"`
+	Error             string                 `json:"error,omitempty"`
+}
+
+// tlsFlags - общие флаги сертификатов распределенной церемонии
+type tlsFlags struct {
+	cert *string
+	key  *string
+	ca   *string
+}
+
+// addTLSFlags регистрирует флаги сертификатов в наборе
+func addTLSFlags(fs *flag.FlagSet) *tlsFlags {
+	return &tlsFlags{
+		cert: fs.String("cert", "", "сертификат этой стороны (PEM)"),
+		key:  fs.String("key", "", "закрытый ключ сертификата (PEM)"),
+		ca:   fs.String("ca", "", "сертификат центра, которым подписаны сертификаты всех сторон (PEM)"),
+	}
+}
+
+// config собирает конфигурацию TLS 1.3: своя пара ключей и пул с одним
+// центром сертификации, которому доверяют обе стороны
+func (tf *tlsFlags) config() (*tls.Config, error) {
+	if *tf.cert == "" || *tf.key == "" || *tf.ca == "" {
+		return nil, errorf("укажите --cert, --key и --ca: стороны церемонии аутентифицируют друг друга")
+	}
+	pair, err := tls.LoadX509KeyPair(*tf.cert, *tf.key)
+	if err != nil {
+		return nil, errorf("ошибка чтения сертификата: %w", err)
+	}
+	caPEM, err := os.ReadFile(*tf.ca)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errorf("%s не содержит сертификата центра в PEM", *tf.ca)
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{pair},
+		RootCAs:      pool,
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
+
+// peerName возвращает имя (CN) и отпечаток сертификата другой стороны
+func peerName(conn *tls.Conn) string {
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "?"
+	}
+	return certName(certs[0])
+}
+
+// certName возвращает имя стороны так, как его видит другая сторона
+func certName(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return fmt.Sprintf("%s (%s)", cert.Subject.CommonName, hex.EncodeToString(sum[:8]))
+}
+
+// ownName возвращает имя этой стороны по ее сертификату
+func ownName(config *tls.Config) (string, error) {
+	cert, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	if err != nil {
+		return "", errorf("ошибка разбора своего сертификата: %w", err)
+	}
+	return certName(cert), nil
+}
+
+// ceremonyConn - соединение с другой стороной церемонии
+type ceremonyConn struct {
+	conn    *tls.Conn
+	name    string
+	enc     *json.Encoder
+	dec     *json.Decoder
+	timeout time.Duration
+}
+
+// newCeremonyConn завершает рукопожатие TLS и готовит соединение к обмену
+func newCeremonyConn(conn *tls.Conn, timeout time.Duration) (*ceremonyConn, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := conn.Handshake(); err != nil {
+		conn.Close()
+		return nil, errorf("ошибка рукопожатия TLS с %s: %w", conn.RemoteAddr(), err)
+	}
+	return &ceremonyConn{conn: conn, name: peerName(conn), enc: json.NewEncoder(conn), dec: json.NewDecoder(conn), timeout: timeout}, nil
+}
+
+// send отправляет сообщение
+func (c *ceremonyConn) send(m ceremonyMessage) error {
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if err := c.enc.Encode(m); err != nil {
+		return errorf("ошибка отправки %s: %w", c.name, err)
+	}
+	return nil
+}
+
+// receive ждет сообщение типа want; сообщение об ошибке другой стороны
+// возвращается как ошибка
+func (c *ceremonyConn) receive(want string) (ceremonyMessage, error) {
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	var m ceremonyMessage
+	if err := c.dec.Decode(&m); err != nil {
+		return m, errorf("ошибка приема от %s: %w", c.name, err)
+	}
+	if m.Type == "error" {
+		return m, errorf("%s: %s", c.name, m.Error)
+	}
+	if m.Type != want {
+		wipeSeeds(secretHexSeeds(m.Seeds))
+		return m, errorf("%s: ожидалось сообщение %q, получено %q", c.name, want, m.Type)
+	}
+	return m, nil
+}
+
+// secretHexSeeds приводит сиды из сообщения к [][]byte
+func secretHexSeeds(seeds []secretHex) [][]byte {
+	out := make([][]byte, len(seeds))
+	for i, s := range seeds {
+		out[i] = s
+	}
+	return out
+}
+
+// runCeremony проводит распределенную церемонию по сети
+func runCeremony(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, tr("Использование:"))
+		fmt.Fprintln(os.Stderr, tr("  seedgen ceremony coordinate --participants N --cert c.crt --key c.key --ca ca.crt [флаги схемы]"))
+		fmt.Fprintln(os.Stderr, "  seedgen ceremony join --connect host:7465 --cert p.crt --key p.key --ca ca.crt")
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return errorf("укажите действие: coordinate или join")
+	}
+
+	switch args[0] {
+	case "coordinate":
+		return runCeremonyCoordinate(args[1:])
+	case "join":
+		return runCeremonyJoin(args[1:])
+	}
+	return errorf("неизвестное действие %q, доступны: coordinate, join", args[0])
+}
+
+// ceremonyParticipant - участник, приславший обязательства
+type ceremonyParticipant struct {
+	*ceremonyConn
+	commitments []string
+	maskKey     string
+}
+
+// runCeremonyCoordinate принимает участников, собирает обязательства, затем
+// сиды, выводит мастер-сид и рассылает участникам только его отпечаток
+func runCeremonyCoordinate(args []string) error {
+	fs := newFlagSet("ceremony coordinate")
+	listen := fs.String("listen", ceremonyDefaultAddr, "адрес, на котором ожидать участников")
+	count := fs.Int("participants", 0, "число участников церемонии")
+	timeout := fs.Duration("timeout", 10*time.Minute, "сколько ждать каждого шага участника")
+	frost := fs.Int("frost", 0, "порог t: после церемонии провести DKG FROST и выдать участникам доли ключа подписи Ed25519, t из n (только схема v3)")
+	transcriptPath := fs.String("transcript", "", "сохранить протокол церемонии, подписанный ключом --transcript-key и по очереди всеми участниками")
+	transcriptKey := fs.String("transcript-key", "", "ключ Ed25519 протокола церемонии (audit keygen)")
+	tf := addTLSFlags(fs)
+	sf := addSchemeFlags(fs)
+	rf := addRevealFlags(fs)
+	ef := addEnvFlags(fs)
+	pf := addProfileFlags(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pf.apply(fs); err != nil {
+		return err
+	}
+	if *count < 1 {
+		return errorf("укажите число участников через --participants")
+	}
+	params, err := sf.params(fs)
+	if err != nil {
+		return err
+	}
+	if *frost != 0 {
+		if params.Scheme != "v3" {
+			return errorf("DKG FROST требует схемы v3: ключ выводится из вкладов, которых координатор не видит")
+		}
+		if *frost < 2 || *frost > *count {
+			return errorf("порог FROST должен быть от 2 до числа участников (%d)", *count)
+		}
+	}
+	if (*transcriptPath == "") != (*transcriptKey == "") {
+		return errorf("флаги --transcript и --transcript-key указываются вместе")
+	}
+	config, err := tf.config()
+	if err != nil {
+		return err
+	}
+	self, err := ownName(config)
+	if err != nil {
+		return err
+	}
+	if err := ef.check(false); err != nil {
+		return err
+	}
+
+	// Ключ и файл протокола проверяем до начала церемонии, как в audit run
+	var key ed25519.PrivateKey
+	var transcriptFile *os.File
+	transcriptSaved := false
+	if *transcriptPath != "" {
+		if key, err = loadSigningKey(*transcriptKey); err != nil {
+			return errorf("ошибка чтения ключа: %w", err)
+		}
+		transcriptFile, err = os.OpenFile(*transcriptPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return errorf("ошибка создания протокола: %w", err)
+		}
+		// Протокол без подписей участников в архив не попадает
+		defer func() {
+			transcriptFile.Close()
+			if !transcriptSaved {
+				os.Remove(*transcriptPath)
+			}
+		}()
+	}
+	t := &transcript{
+		Kind:     "ceremony-transcript",
+		Version:  buildVersion(),
+		Commit:   commit,
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Params:   params,
+	}
+	t.record(transcriptEvent{Event: "ceremony-start", Note: "координатор " + self})
+	var commitmentOrder []string
+
+	ln, err := tls.Listen("tcp", *listen, config)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	fmt.Println(tr("=== Распределенная церемония: координатор ==="))
+	fmt.Println()
+	fmt.Println(describeParams(params))
+	fmt.Printf(tr("Ожидание участников на %s: %d\n\n"), ln.Addr(), *count)
+
+	var participants []*ceremonyParticipant
+	defer func() {
+		for _, p := range participants {
+			p.conn.Close()
+		}
+	}()
+	// fail сообщает причину отказа всем подключенным участникам
+	fail := func(err error) error {
+		for _, p := range participants {
+			p.send(ceremonyMessage{Type: "error", Error: err.Error()})
+		}
+		return err
+	}
+
+	seen := make(map[string]string)
+	for len(participants) < *count {
+		raw, err := ln.Accept()
+		if err != nil {
+			return fail(err)
+		}
+		logDebug("подключение участника", "addr", raw.RemoteAddr())
+		conn, err := newCeremonyConn(raw.(*tls.Conn), *timeout)
+		if err != nil {
+			// Чужой сертификат не срывает церемонию: ждем следующего подключения
+			logWarnf("⚠ %s\n", err)
+			continue
+		}
+		m, err := conn.receive("commit")
+		if err == nil && len(m.Commitments) == 0 {
+			err = errorf("%s не прислал обязательств", conn.name)
+		}
+		if err == nil && params.Scheme == "v3" && m.MaskKey == "" {
+			err = errorf("%s не прислал ключ маскирования, нужный для схемы v3", conn.name)
+		}
+		for _, c := range m.Commitments {
+			if err == nil && seen[c] != "" {
+				err = errorf("%s прислал обязательство, уже полученное от %s: один и тот же сид дважды", conn.name, seen[c])
+			}
+			seen[c] = conn.name
+		}
+		if err != nil {
+			conn.send(ceremonyMessage{Type: "error", Error: err.Error()})
+			conn.conn.Close()
+			return fail(err)
+		}
+		participants = append(participants, &ceremonyParticipant{ceremonyConn: conn, commitments: m.Commitments, maskKey: m.MaskKey})
+		t.record(transcriptEvent{Event: "participant-joined", Participant: conn.name, SeedCount: len(m.Commitments)})
+		for _, c := range m.Commitments {
+			commitmentOrder = append(commitmentOrder, c)
+			t.record(transcriptEvent{Event: "seed-received", Index: len(commitmentOrder), Commitment: c, Participant: conn.name})
+		}
+		fmt.Printf(tr("✓ Участник %d/%d: %s, сидов: %d\n"), len(participants), *count, conn.name, len(m.Commitments))
+	}
+
+	// Все обязательства собраны - только теперь участники раскрывают сиды
+	reveal := ceremonyMessage{Type: "reveal", Params: &params, FrostThreshold: *frost, TranscriptSigning: *transcriptPath != ""}
+	for _, p := range participants {
+		reveal.Participants = append(reveal.Participants, p.name)
+		reveal.Commitments = append(reveal.Commitments, p.commitments...)
+		if params.Scheme == "v3" {
+			reveal.MaskKeys = append(reveal.MaskKeys, p.maskKey)
+		}
+	}
+	sort.Strings(reveal.Commitments)
+	if !uniqueStrings(reveal.MaskKeys) {
+		return fail(errorf("участники прислали одинаковые ключи маскирования"))
+	}
+	for _, p := range participants {
+		if err := p.send(reveal); err != nil {
+			return fail(err)
+		}
+	}
+
+	var masterSeed []byte
+	if params.Scheme == "v3" {
+		masterSeed, err = collectMaskedContributions(participants)
+	} else {
+		masterSeed, err = collectSeeds(participants, params)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	defer wipe(masterSeed)
+	t.record(transcriptEvent{Event: "seeds-complete", SeedCount: len(commitmentOrder), Root: newSeedMerkleTree(commitmentOrder).root()})
+	t.record(transcriptEvent{Event: "master-derived", SeedCount: len(commitmentOrder), Fingerprint: masterFingerprint(masterSeed)})
+
+	words, digest := seedSetCommitment(masterSeed)
+	result := ceremonyMessage{
+		Type:        "result",
+		Fingerprint: masterFingerprint(masterSeed),
+		Words:       strings.Join(words, " ") + "  (" + digest + ")",
+	}
+	for _, p := range participants {
+		if err := p.send(result); err != nil {
+			if *frost != 0 {
+				return fail(err)
+			}
+			logWarnf("⚠ %s\n", err)
+		}
+	}
+
+	if *frost != 0 {
+		fmt.Printf(tr("DKG FROST между участниками: порог %d из %d...\n"), *frost, *count)
+		group, err := runFrostCoordinator(participants, *frost, frostContext(result.Fingerprint, reveal.MaskKeys))
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Println()
+		printFrostGroup(group, *frost, reveal.Participants)
+		fmt.Println(tr("✓ Доли ключа подписи остались у участников, у координатора их нет"))
+		fmt.Println()
+		t.record(transcriptEvent{Event: "frost-group", Note: fmt.Sprintf("общий ключ %s, порог %d из %d", hex.EncodeToString(group.publicKey.encode()), *frost, *count)})
+	}
+
+	if transcriptFile != nil {
+		t.record(transcriptEvent{Event: "ceremony-end"})
+		t.Operators = reveal.Participants
+		if err := t.sign(key); err != nil {
+			return fail(err)
+		}
+		fmt.Println(tr("Протокол церемонии передается участникам на подпись по очереди..."))
+		if err := collectCosignatures(participants, t); err != nil {
+			return fail(err)
+		}
+		if err := writeJSON(transcriptFile, t); err != nil {
+			return errorf("ошибка записи протокола: %w", err)
+		}
+		if err := transcriptFile.Close(); err != nil {
+			return errorf("ошибка записи протокола: %w", err)
+		}
+		transcriptSaved = true
+		fmt.Printf(tr("✓ Протокол, подписанный всеми участниками, сохранен: %s\n\n"), *transcriptPath)
+	}
+
+	printSeedCommitment(os.Stdout, masterSeed)
+	if err := rf.reveal(tr("Мастер-сид (распределенная церемония):"), masterSeed); err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Printf(tr("SHA-512 хеш: %s...\n"), emph(result.Fingerprint))
+	fmt.Println(tr("✓ Участникам разослан только отпечаток мастер-сида"))
+	return nil
+}
+
+// collectSeeds принимает сиды участников, сверяет их с обязательствами
+// и выводит мастер-сид
+func collectSeeds(participants []*ceremonyParticipant, params Params) ([]byte, error) {
+	var deviceSeeds [][]byte
+	defer func() { wipeSeeds(deviceSeeds) }()
+	for _, p := range participants {
+		m, err := p.receive("seeds")
+		seeds := secretHexSeeds(m.Seeds)
+		deviceSeeds = append(deviceSeeds, seeds...)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSeedCommitments(seeds, p.commitments); err != nil {
+			return nil, errorf("%s: %w", p.name, err)
+		}
+	}
+	fmt.Printf(tr("\n✓ Получено сидов: %d от участников: %d\n\n"), len(deviceSeeds), len(participants))
+
+	stopProgress := showKDFProgress()
+	masterSeed, err := GenerateMasterSeed(deviceSeeds, params)
+	stopProgress()
+	if err != nil {
+		return nil, errorf("ошибка генерации: %w", err)
+	}
+	return masterSeed, nil
+}
+
+// collectMaskedContributions складывает замаскированные вклады участников
+// схемы v3: попарные маски сокращаются, и остается сумма вкладов всех сидов.
+// Сверить вклады с обязательствами координатор не может - это цена того,
+// что сидов он не видит.
+func collectMaskedContributions(participants []*ceremonyParticipant) ([]byte, error) {
+	sum := newSecret(sha512.Size)
+	defer wipe(sum)
+	for _, p := range participants {
+		m, err := p.receive("masked")
+		if err == nil && len(m.Masked) != sha512.Size {
+			err = errorf("%s прислал вклад длиной %d байт вместо %d", p.name, len(m.Masked), sha512.Size)
+		}
+		if err != nil {
+			wipe(m.Masked)
+			return nil, err
+		}
+		xorInto(sum, m.Masked)
+		wipe(m.Masked)
+	}
+	fmt.Printf(tr("\n✓ Получены замаскированные вклады участников: %d (сиды координатору не передавались)\n\n"), len(participants))
+	return finalMasterHash(sum), nil
+}
+
+// checkSeedCommitments проверяет, что сиды участника - ровно те, по которым
+// он прислал обязательства
+func checkSeedCommitments(seeds [][]byte, commitments []string) error {
+	if len(seeds) != len(commitments) {
+		return errorf("прислано сидов: %d, обязательств было: %d", len(seeds), len(commitments))
+	}
+	got := make([]string, len(seeds))
+	for i, s := range seeds {
+		got[i] = seedCommitment(s)
+	}
+	want := append([]string(nil), commitments...)
+	sort.Strings(got)
+	sort.Strings(want)
+	for i := range got {
+		if !secretEqualString(got[i], want[i]) {
+			return mismatchf("сиды не соответствуют присланным ранее обязательствам")
+		}
+	}
+	return nil
+}
+
+// runCeremonyJoin отправляет координатору обязательства, а после сбора
+// обязательств всех участников - сами сиды, и выводит полученный отпечаток
+func runCeremonyJoin(args []string) error {
+	fs := newFlagSet("ceremony join")
+	addr := fs.String("connect", "", "адрес координатора host:port")
+	serverName := fs.String("server-name", "", "имя в сертификате координатора (по умолчанию - хост из --connect)")
+	timeout := fs.Duration("timeout", 30*time.Minute, "сколько ждать каждого шага координатора")
+	maskedOnly := fs.Bool("masked-only", false, "отказаться от церемонии, если координатор выбрал не v3 и ждет сиды в открытом виде")
+	frostShare := fs.String("frost-share", "", "файл для доли ключа подписи FROST, если координатор проводит DKG")
+	transcriptKey := fs.String("transcript-key", "", "личный ключ Ed25519 (audit keygen) для подписи протокола, если координатор его собирает")
+	transcriptOut := fs.String("transcript", "", "сохранить копию протокола, подписанного всеми участниками")
+	tf := addTLSFlags(fs)
+	ssf := addSeedSourceFlags(fs)
+	ef := addEnvFlags(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *addr == "" {
+		return errorf("укажите адрес координатора через --connect")
+	}
+	config, err := tf.config()
+	if err != nil {
+		return err
+	}
+	config.ServerName = *serverName
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(*addr)
+		if err != nil {
+			return errorf("--connect: %w", err)
+		}
+		config.ServerName = host
+	}
+	for _, path := range []string{*frostShare, *transcriptOut} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return errorf("файл %s уже существует", path)
+		}
+	}
+	self, err := ownName(config)
+	if err != nil {
+		return err
+	}
+	var key ed25519.PrivateKey
+	if *transcriptKey != "" {
+		if key, err = loadSigningKey(*transcriptKey); err != nil {
+			return errorf("ошибка чтения ключа: %w", err)
+		}
+	}
+	if err := ef.check(false); err != nil {
+		return err
+	}
+
+	fmt.Println(tr("=== Распределенная церемония: участник ==="))
+	fmt.Println()
+	seeds, err := ssf.read(os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer wipeSeeds(seeds)
+	if len(seeds) == 0 {
+		return errorf("не введено ни одного сида")
+	}
+	commitments := make([]string, len(seeds))
+	for i, s := range seeds {
+		commitments[i] = seedCommitment(s)
+	}
+	// Ключ маскирования нужен только схеме v3, но схему сообщит координатор
+	maskKey, err := newMaskKeyPair()
+	if err != nil {
+		return err
+	}
+	defer maskKey.wipe()
+
+	logDebug("подключение к координатору", "addr", *addr)
+	raw, err := (&net.Dialer{Timeout: *timeout}).Dial("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	conn, err := newCeremonyConn(tls.Client(raw, config), *timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.conn.Close()
+	fmt.Printf(tr("\n✓ Соединение с координатором %s\n"), conn.name)
+
+	if err := conn.send(ceremonyMessage{Type: "commit", Commitments: commitments, MaskKey: maskKey.public}); err != nil {
+		return err
+	}
+	fmt.Println(tr("Обязательства отправлены, ожидание остальных участников..."))
+
+	reveal, err := conn.receive("reveal")
+	if err != nil {
+		return err
+	}
+	if reveal.Params == nil {
+		return errorf("координатор не сообщил параметры схемы")
+	}
+	// Сиды уходят, только если координатор учел все наши обязательства
+	for _, c := range commitments {
+		i := sort.SearchStrings(reveal.Commitments, c)
+		if i == len(reveal.Commitments) || reveal.Commitments[i] != c {
+			return errorf("координатор не включил наше обязательство %s в набор", c)
+		}
+	}
+	fmt.Println()
+	fmt.Println(describeParams(*reveal.Params))
+	fmt.Printf(tr("Участники: %s\n"), strings.Join(reveal.Participants, ", "))
+	fmt.Printf(tr("Сидов в наборе: %d\n"), len(reveal.Commitments))
+
+	if *maskedOnly && reveal.Params.Scheme != "v3" {
+		conn.send(ceremonyMessage{Type: "error", Error: "участник согласен только на схему v3"})
+		return errorf("координатор выбрал схему %s, а с --masked-only сиды передаются только замаскированными вкладами v3", reveal.Params.Scheme)
+	}
+	if reveal.FrostThreshold != 0 && *frostShare == "" {
+		conn.send(ceremonyMessage{Type: "error", Error: "участник не указал файл для доли FROST"})
+		return errorf("координатор проводит DKG FROST: укажите файл для своей доли через --frost-share")
+	}
+	if reveal.TranscriptSigning && key == nil {
+		conn.send(ceremonyMessage{Type: "error", Error: "участник не указал ключ для подписи протокола"})
+		return errorf("координатор собирает подписанный протокол: укажите свой ключ через --transcript-key")
+	}
+	var contribution []byte
+	defer func() { wipe(contribution) }()
+	if reveal.Params.Scheme == "v3" {
+		contribution, err = sendMaskedContribution(conn, seeds, *reveal.Params, maskKey, reveal.MaskKeys)
+		if err != nil {
+			return err
+		}
+	} else {
+		msg := ceremonyMessage{Type: "seeds", Seeds: make([]secretHex, len(seeds))}
+		for i, s := range seeds {
+			msg.Seeds[i] = s
+		}
+		if err := conn.send(msg); err != nil {
+			return err
+		}
+	}
+
+	result, err := conn.receive("result")
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Println(tr("Обязательство набора сидов (сверьте вслух с другими операторами):"))
+	fmt.Printf("  %s\n\n", result.Words)
+	fmt.Printf(tr("SHA-512 хеш мастер-сида: %s...\n"), result.Fingerprint)
+
+	if reveal.FrostThreshold != 0 {
+		if reveal.Params.Scheme != "v3" {
+			return errorf("координатор запросил DKG FROST без схемы v3")
+		}
+		fmt.Printf(tr("\nDKG FROST: порог %d из %d...\n"), reveal.FrostThreshold, len(reveal.Participants))
+		share, err := runFrostParticipant(conn, reveal, result.Fingerprint, contribution, maskKey)
+		if err != nil {
+			return err
+		}
+		defer wipe(share.SigningShare)
+		data, err := json.MarshalIndent(share, "", "  ")
+		if err != nil {
+			return err
+		}
+		defer wipe(data)
+		if err := writeNewFile(*frostShare, append(data, '\n'), 0600); err != nil {
+			return errorf("ошибка записи доли FROST: %w", err)
+		}
+		fmt.Println()
+		pub := mustHex(share.GroupPublicKey)
+		fmt.Printf(tr("Общий ключ подписи Ed25519: %s\n"), share.GroupPublicKey)
+		fmt.Printf(tr("Отпечаток ключа: %s\n"), emph(keyFingerprint(pub)))
+		fmt.Printf(tr("✓ Доля %d из %d сохранена: %s\n"), share.Identifier, len(share.Participants), *frostShare)
+	} else if *frostShare != "" {
+		fmt.Println(tr("⚠ Координатор не проводит DKG FROST, доля не создана"))
+	}
+
+	if reveal.TranscriptSigning {
+		if err := cosignSessionTranscript(conn, key, reveal, result, self, commitments, *transcriptOut); err != nil {
+			return err
+		}
+	} else if *transcriptOut != "" {
+		fmt.Println(tr("⚠ Координатор не собирает протокол церемонии, копия не сохранена"))
+	}
+	fmt.Println(tr("✓ Церемония завершена, мастер-сид остался у координатора"))
+	return nil
+}
+
+// sendMaskedContribution растягивает сиды участника на его машине, закрывает
+// сумму вкладов попарными масками и отправляет координатору. Открытая сумма
+// вкладов возвращается: из нее выводится многочлен DKG FROST.
+func sendMaskedContribution(conn *ceremonyConn, seeds [][]byte, params Params, maskKey *maskKeyPair, maskKeys []string) ([]byte, error) {
+	if err := params.Validate(); err != nil {
+		return nil, errorf("координатор прислал недопустимые параметры: %w", err)
+	}
+	mask, err := maskKey.mask(maskKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(mask)
+
+	fmt.Println(tr("\nРастягивание своих сидов (схема v3: сиды не покидают эту машину)..."))
+	stopProgress := showKDFProgress()
+	contribution, err := aggregateContributions(seeds, params)
+	stopProgress()
+	if err != nil {
+		return nil, err
+	}
+	masked := newSecret(len(contribution))
+	defer wipe(masked)
+	copy(masked, contribution)
+	xorInto(masked, mask)
+	if err := conn.send(ceremonyMessage{Type: "masked", Masked: masked}); err != nil {
+		wipe(contribution)
+		return nil, err
+	}
+	return contribution, nil
+}
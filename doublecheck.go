@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// referenceMasterSeed вычисляет мастер-сид вторым, независимо написанным
+// путем для --double-check: набор сидов сортируется и кодируется заново,
+// а PBKDF2 берется из x/crypto вместо pbkdf2SHA512 с индикатором хода.
+// Второй реализации Argon2id нет, и его повтор выявляет лишь сбои оборудования.
+func referenceMasterSeed(deviceSeeds [][]byte, p Params) ([]byte, error) {
+	order := make([]int, len(deviceSeeds))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return bytes.Compare(deviceSeeds[order[a]], deviceSeeds[order[b]]) < 0
+	})
+
+	if p.Scheme == "v3" {
+		return referenceV3MasterSeed(deviceSeeds, p)
+	}
+
+	var password []byte
+	salt := []byte(p.Salt)
+	if p.Scheme == "v1" {
+		size := 0
+		for _, seed := range deviceSeeds {
+			size += len(seed)
+		}
+		password = newSecret(size)
+		offset := 0
+		for _, i := range order {
+			offset += copy(password[offset:], deviceSeeds[i])
+		}
+	} else {
+		size := len(v2Domain) + 4
+		for _, seed := range deviceSeeds {
+			size += 4 + len(seed)
+		}
+		password = newSecret(size)
+		offset := copy(password, v2Domain)
+		binary.BigEndian.PutUint32(password[offset:], uint32(len(deviceSeeds)))
+		offset += 4
+		for _, i := range order {
+			binary.BigEndian.PutUint32(password[offset:], uint32(len(deviceSeeds[i])))
+			offset += 4
+			offset += copy(password[offset:], deviceSeeds[i])
+		}
+		salt = []byte(p.Salt + "/epoch/" + fmt.Sprint(p.Epoch))
+	}
+	defer wipe(password)
+
+	key, err := referenceKDF(password, salt, p)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(key)
+	sum := sha512.Sum512(key)
+	master := newSecret(len(sum))
+	copy(master, sum[:])
+	wipe(sum[:])
+	return master, nil
+}
+
+// referenceKDF растягивает пароль реализацией KDF из x/crypto
+func referenceKDF(password, salt []byte, p Params) ([]byte, error) {
+	var key []byte
+	switch {
+	case p.KDF == kdfArgon2id:
+		key = argon2.IDKey(password, salt, uint32(p.Iterations), p.Memory, p.Parallelism, 64)
+	case p.KDF == kdfPBKDF2:
+		key = pbkdf2.Key(password, salt, p.Iterations, 64, sha512.New)
+	default:
+		return nil, errorf("неизвестный KDF %q", p.KDF)
+	}
+	lockSecret(key)
+	return key, nil
+}
+
+// referenceV3MasterSeed повторяет схему v3: каждый сид растягивается
+// отдельно, вклады складываются побайтным XOR и хэшируются SHA-512
+func referenceV3MasterSeed(deviceSeeds [][]byte, p Params) ([]byte, error) {
+	salt := []byte(p.Salt + "/epoch/" + fmt.Sprint(p.Epoch))
+	var sum [sha512.Size]byte
+	defer wipe(sum[:])
+	for _, seed := range deviceSeeds {
+		password := newSecret(len(v3Domain) + 4 + len(seed))
+		offset := copy(password, v3Domain)
+		binary.BigEndian.PutUint32(password[offset:], uint32(len(seed)))
+		copy(password[offset+4:], seed)
+		key, err := referenceKDF(password, salt, p)
+		wipe(password)
+		if err != nil {
+			return nil, err
+		}
+		for i := range sum {
+			sum[i] ^= key[i]
+		}
+		wipe(key)
+	}
+	final := sha512.Sum512(sum[:])
+	master := newSecret(len(final))
+	copy(master, final[:])
+	wipe(final[:])
+	return master, nil
+}
+
+// doubleCheck сверяет мастер-сид с результатом referenceMasterSeed. Расхождение
+// означает ошибку сборки или сбой памяти, и такой результат нельзя использовать.
+func doubleCheck(deviceSeeds [][]byte, p Params, master []byte) error {
+	ref, err := referenceMasterSeed(deviceSeeds, p)
+	if err != nil {
+		return err
+	}
+	defer wipe(ref)
+	if !secretEqual(ref, master) {
+		return withCode(exitCrypto, errorf("независимое повторное вычисление дало другой мастер-сид: возможны сбой памяти или ошибка сборки, результат использовать нельзя"))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+// dpapiEntropy - дополнительная энтропия DPAPI: другая программа того же
+// пользователя не расшифрует запись, не зная ее
+var dpapiEntropy = []byte("seedgen master seed")
+
+// dpapiProtected - мастер-сид, зашифрованный Windows DPAPI
+type dpapiProtected struct {
+	Kind        string `json:"kind"`
+	Scope       string `json:"scope"`
+	Fingerprint string `json:"fingerprint"`
+	Data        []byte `json:"data"`
+}
+
+// protectMaster шифрует мастер-сид DPAPI в области scope: user или machine
+func protectMaster(master []byte, scope string) (*dpapiProtected, error) {
+	data, err := protectDPAPI(master, scope == "machine")
+	if err != nil {
+		return nil, err
+	}
+	return &dpapiProtected{Kind: "dpapi-protected", Scope: scope, Fingerprint: masterFingerprint(master), Data: data}, nil
+}
+
+// openDPAPIArtifact расшифровывает мастер-сид из артефакта dpapi-protected
+func openDPAPIArtifact(data []byte) ([]byte, error) {
+	var p dpapiProtected
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, errorf("не удалось разобрать артефакт: %w", err)
+	}
+	master, err := unprotectDPAPI(p.Data)
+	if err != nil {
+		return nil, errorf("ошибка расшифровки DPAPI (область %s): %w", p.Scope, err)
+	}
+	if len(master) != masterSeedSize || !secretEqual([]byte(masterFingerprint(master)), []byte(p.Fingerprint)) {
+		wipe(master)
+		return nil, mismatchf("расшифрованное значение не совпадает с отпечатком %s", p.Fingerprint)
+	}
+	return master, nil
+}
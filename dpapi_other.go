@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+// dpapiSupported сообщает, что DPAPI доступен в этой сборке
+const dpapiSupported = false
+
+// protectDPAPI недоступна: DPAPI есть только в Windows
+func protectDPAPI(secret []byte, machine bool) ([]byte, error) {
+	return nil, errorf("DPAPI доступен только в Windows")
+}
+
+// unprotectDPAPI недоступна: DPAPI есть только в Windows
+func unprotectDPAPI(data []byte) ([]byte, error) {
+	return nil, errorf("DPAPI доступен только в Windows")
+}
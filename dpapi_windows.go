@@ -0,0 +1,89 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// dpapiSupported сообщает, что DPAPI доступен в этой сборке
+const dpapiSupported = true
+
+// Флаги CryptProtectData
+const (
+	cryptProtectUIForbidden  = 0x1
+	cryptProtectLocalMachine = 0x4
+)
+
+var (
+	crypt32                = syscall.NewLazyDLL("crypt32.dll")
+	procCryptProtectData   = crypt32.NewProc("CryptProtectData")
+	procCryptUnprotectData = crypt32.NewProc("CryptUnprotectData")
+	procLocalFree          = syscall.NewLazyDLL("kernel32.dll").NewProc("LocalFree")
+)
+
+// dataBlob - структура DATA_BLOB
+type dataBlob struct {
+	size uint32
+	data *byte
+}
+
+// newBlob описывает срез как DATA_BLOB без копирования
+func newBlob(b []byte) *dataBlob {
+	if len(b) == 0 {
+		return &dataBlob{}
+	}
+	return &dataBlob{size: uint32(len(b)), data: &b[0]}
+}
+
+// takeBlob копирует вывод DPAPI в защищенный буфер, затирает
+// и освобождает память, выделенную системой
+func takeBlob(out *dataBlob) []byte {
+	defer procLocalFree.Call(uintptr(unsafe.Pointer(out.data)))
+	src := (*[1 << 30]byte)(unsafe.Pointer(out.data))[:out.size:out.size]
+	b := newSecret(len(src))
+	copy(b, src)
+	wipe(src)
+	return b
+}
+
+// protectDPAPI шифрует данные ключом текущего пользователя или,
+// если machine, ключом компьютера
+func protectDPAPI(secret []byte, machine bool) ([]byte, error) {
+	flags := uintptr(cryptProtectUIForbidden)
+	if machine {
+		flags |= cryptProtectLocalMachine
+	}
+	description, err := syscall.UTF16PtrFromString("seedgen master seed")
+	if err != nil {
+		return nil, err
+	}
+	var out dataBlob
+	r, _, err := procCryptProtectData.Call(
+		uintptr(unsafe.Pointer(newBlob(secret))),
+		uintptr(unsafe.Pointer(description)),
+		uintptr(unsafe.Pointer(newBlob(dpapiEntropy))),
+		0, 0, flags,
+		uintptr(unsafe.Pointer(&out)))
+	if r == 0 {
+		return nil, errorf("CryptProtectData: %w", err)
+	}
+	return takeBlob(&out), nil
+}
+
+// unprotectDPAPI расшифровывает данные; область хранится в самом шифротексте
+func unprotectDPAPI(data []byte) ([]byte, error) {
+	var out dataBlob
+	r, _, err := procCryptUnprotectData.Call(
+		uintptr(unsafe.Pointer(newBlob(data))),
+		0,
+		uintptr(unsafe.Pointer(newBlob(dpapiEntropy))),
+		0, 0, cryptProtectUIForbidden,
+		uintptr(unsafe.Pointer(&out)))
+	if r == 0 {
+		return nil, errorf("CryptUnprotectData: %w", err)
+	}
+	return takeBlob(&out), nil
+}
@@ -0,0 +1,551 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// drawTeam - команда жеребьевки из файла корзин
+type drawTeam struct {
+	Name    string `json:"team"`
+	Country string `json:"country,omitempty"`
+	Pot     int    `json:"pot"`
+}
+
+// drawConstraint - ограничение жеребьевки групп: можно ли добавить
+// команду в группу с уже попавшими туда командами. Ограничение смотрит
+// только на корзину и страну команд, а не на имена: перебор считает
+// команды с одинаковыми корзиной и страной взаимозаменяемыми.
+type drawConstraint struct {
+	name    string
+	summary string
+	allows  func(group []drawTeam, t drawTeam) bool
+}
+
+// drawConstraints - поддерживаемые ограничения. Команды одной корзины
+// всегда попадают в разные группы.
+var drawConstraints = []drawConstraint{
+	{"no-same-country", "команды одной страны попадают в разные группы", func(group []drawTeam, t drawTeam) bool {
+		if t.Country == "" {
+			return true
+		}
+		for _, g := range group {
+			if g.Country == t.Country {
+				return false
+			}
+		}
+		return true
+	}},
+}
+
+// drawConstraintNames возвращает имена ограничений для справки и дополнения
+func drawConstraintNames() []string {
+	names := make([]string, 0, len(drawConstraints))
+	for _, c := range drawConstraints {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// findDrawConstraint ищет ограничение по имени
+func findDrawConstraint(name string) (drawConstraint, error) {
+	for _, c := range drawConstraints {
+		if c.name == name {
+			return c, nil
+		}
+	}
+	return drawConstraint{}, errorf("неизвестное ограничение %q, доступны: %s", name, strings.Join(drawConstraintNames(), ", "))
+}
+
+// readPots читает корзины из CSV со строками "корзина,команда[,страна]".
+// Строка заголовка (первое поле "pot"), пустые строки и строки с #
+// пропускаются, корзины - положительные номера. Возвращает корзины по
+// возрастанию номера и SHA-256 канонической записи (строки
+// "корзина,команда,страна" через \n в порядке файла), чтобы результат
+// можно было сверить с тем же файлом.
+func readPots(path string) ([][]drawTeam, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	var teams []drawTeam
+	seen := make(map[string]bool)
+	var canonical []string
+	scanner := bufio.NewScanner(f)
+	for line, first := 1, true; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		// Каждая строка разбирается как запись CSV: имена с запятыми
+		// записываются в кавычках
+		r := csv.NewReader(strings.NewReader(text))
+		r.TrimLeadingSpace = true
+		rec, err := r.Read()
+		if err != nil {
+			return nil, "", errorf("%s:%d: %w", path, line, err)
+		}
+		if first {
+			first = false
+			if strings.EqualFold(strings.TrimSpace(rec[0]), "pot") {
+				continue
+			}
+		}
+		if len(rec) < 2 || len(rec) > 3 {
+			return nil, "", errorf("%s:%d: ожидается корзина,команда[,страна]", path, line)
+		}
+		pot, err := strconv.Atoi(strings.TrimSpace(rec[0]))
+		if err != nil || pot < 1 {
+			return nil, "", errorf("%s:%d: некорректный номер корзины %q", path, line, rec[0])
+		}
+		t := drawTeam{Name: strings.TrimSpace(rec[1]), Pot: pot}
+		if len(rec) == 3 {
+			t.Country = strings.TrimSpace(rec[2])
+		}
+		if t.Name == "" {
+			return nil, "", errorf("%s:%d: не указана команда", path, line)
+		}
+		if seen[t.Name] {
+			return nil, "", errorf("%s:%d: команда %q уже указана", path, line, t.Name)
+		}
+		seen[t.Name] = true
+		teams = append(teams, t)
+		canonical = append(canonical, fmt.Sprintf("%d,%s,%s", t.Pot, t.Name, t.Country))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	if len(teams) == 0 {
+		return nil, "", errorf("%s: нет ни одной команды", path)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(canonical, "\n")))
+	digest := hex.EncodeToString(sum[:])
+	auditInput("file", path, digest)
+	return groupPots(teams), digest, nil
+}
+
+// readDrawPots читает корзины из файла корзин или из выгрузки регистрации
+func readDrawPots(path, rosterPath string) ([][]drawTeam, string, error) {
+	if rosterPath == "" {
+		return readPots(path)
+	}
+	entries, digest, err := loadRoster(rosterPath)
+	if err != nil {
+		return nil, "", err
+	}
+	pots, err := rosterPots(entries)
+	if err != nil {
+		return nil, "", errorf("%s: %w", rosterPath, err)
+	}
+	return pots, digest, nil
+}
+
+// groupPots раскладывает команды по корзинам в порядке возрастания номера
+func groupPots(teams []drawTeam) [][]drawTeam {
+	byPot := make(map[int][]drawTeam)
+	for _, t := range teams {
+		byPot[t.Pot] = append(byPot[t.Pot], t)
+	}
+	numbers := make([]int, 0, len(byPot))
+	for pot := range byPot {
+		numbers = append(numbers, pot)
+	}
+	sort.Ints(numbers)
+	pots := make([][]drawTeam, 0, len(numbers))
+	for _, pot := range numbers {
+		pots = append(pots, byPot[pot])
+	}
+	return pots
+}
+
+// drawSkip - группа, пропущенная при размещении команды, и причина:
+// constraint - нарушается ограничение, infeasible - после размещения
+// оставшиеся команды нельзя было бы расставить
+type drawSkip struct {
+	Group  string `json:"group"`
+	Reason string `json:"reason"`
+}
+
+// drawPick - один шаг жеребьевки: какая команда вытянута из скольких
+// оставшихся в корзине, в какую группу попала и какие группы пропущены
+type drawPick struct {
+	Step      int        `json:"step"`
+	Team      drawTeam   `json:"team"`
+	Remaining int        `json:"remaining"`
+	Index     int        `json:"index"`
+	Group     string     `json:"group"`
+	Skipped   []drawSkip `json:"skipped,omitempty"`
+}
+
+// drawGroup - группа с командами в порядке попадания
+type drawGroup struct {
+	Name  string   `json:"name"`
+	Teams []string `json:"teams"`
+}
+
+// groupDraw - протокол жеребьевки групп
+type groupDraw struct {
+	Kind        string      `json:"kind"`
+	Label       string      `json:"label"`
+	Pots        string      `json:"pots_sha256"`
+	Constraints []string    `json:"constraints,omitempty"`
+	Fingerprint string      `json:"master_fingerprint"`
+	Picks       []drawPick  `json:"picks"`
+	Groups      []drawGroup `json:"groups"`
+}
+
+// groupDrawer хранит расстановку во время жеребьевки
+type groupDrawer struct {
+	groups      [][]drawTeam
+	constraints []drawConstraint
+	// known - уже проверенные состояния перебора и их исход
+	known map[string]bool
+}
+
+// teamKey возвращает то, что видят ограничения: корзину и страну команды
+func teamKey(t drawTeam) string {
+	return strconv.Itoa(t.Pot) + "\x00" + t.Country
+}
+
+// teamsKey возвращает ключ набора команд без учета порядка
+func teamsKey(teams []drawTeam) string {
+	keys := make([]string, len(teams))
+	for i, t := range teams {
+		keys[i] = teamKey(t)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "\x01")
+}
+
+// stateKey возвращает ключ состояния перебора: группы без учета порядка
+// и оставшиеся команды
+func (d *groupDrawer) stateKey(rest []drawTeam) string {
+	groups := make([]string, len(d.groups))
+	for g, teams := range d.groups {
+		groups[g] = teamsKey(teams)
+	}
+	sort.Strings(groups)
+	return strings.Join(groups, "\x02") + "\x03" + teamsKey(rest)
+}
+
+// hasPot сообщает, что в группе g уже есть команда корзины pot
+func (d *groupDrawer) hasPot(g, pot int) bool {
+	for _, other := range d.groups[g] {
+		if other.Pot == pot {
+			return true
+		}
+	}
+	return false
+}
+
+// allows проверяет, можно ли добавить команду в группу g
+func (d *groupDrawer) allows(g int, t drawTeam) bool {
+	if d.hasPot(g, t.Pot) {
+		return false
+	}
+	for _, c := range d.constraints {
+		if !c.allows(d.groups[g], t) {
+			return false
+		}
+	}
+	return true
+}
+
+// feasible перебором с возвратом проверяет, что оставшиеся команды можно
+// расставить при текущем заполнении групп. Первой расставляется команда
+// с наименьшим числом допустимых групп, чтобы тупики находились сразу;
+// одинаково заполненные группы пробуются один раз, а исход каждого
+// состояния запоминается.
+func (d *groupDrawer) feasible(rest []drawTeam) bool {
+	if len(rest) == 0 {
+		return true
+	}
+	key := d.stateKey(rest)
+	if ok, seen := d.known[key]; seen {
+		return ok
+	}
+	ok := d.search(rest)
+	d.known[key] = ok
+	return ok
+}
+
+// search - шаг перебора feasible
+func (d *groupDrawer) search(rest []drawTeam) bool {
+	best, bestGroups := -1, []int(nil)
+	for i, t := range rest {
+		var options []int
+		for g := range d.groups {
+			if d.allows(g, t) {
+				options = append(options, g)
+			}
+		}
+		if best < 0 || len(options) < len(bestGroups) {
+			best, bestGroups = i, options
+		}
+		if len(options) == 0 {
+			return false
+		}
+	}
+	t := rest[best]
+	others := make([]drawTeam, 0, len(rest)-1)
+	others = append(others, rest[:best]...)
+	others = append(others, rest[best+1:]...)
+	tried := make(map[string]bool)
+	for _, g := range bestGroups {
+		group := teamsKey(d.groups[g])
+		if tried[group] {
+			continue
+		}
+		tried[group] = true
+		d.groups[g] = append(d.groups[g], t)
+		ok := d.feasible(others)
+		d.groups[g] = d.groups[g][:len(d.groups[g])-1]
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// groupName возвращает букву группы
+func groupName(g int) string {
+	return string(rune('A' + g))
+}
+
+// drawGroups проводит жеребьевку по правилам УЕФА: корзины тянутся по
+// порядку, команда корзины выбирается из оставшихся равновероятно и попадает
+// в первую по алфавиту группу, где она не нарушает ограничений и после
+// которой оставшиеся команды еще можно расставить
+func drawGroups(stream *drawStream, pots [][]drawTeam, count int, constraints []drawConstraint) ([]drawPick, [][]drawTeam, error) {
+	d := &groupDrawer{groups: make([][]drawTeam, count), constraints: constraints, known: make(map[string]bool)}
+	var all []drawTeam
+	for _, pot := range pots {
+		all = append(all, pot...)
+	}
+	if !d.feasible(all) {
+		return nil, nil, errorf("ограничения невыполнимы: команды нельзя расставить по %d группам", count)
+	}
+
+	var picks []drawPick
+	end := 0
+	for _, pot := range pots {
+		end += len(pot)
+		remaining := append([]drawTeam(nil), pot...)
+		for len(remaining) > 0 {
+			index := stream.intn(len(remaining))
+			t := remaining[index]
+			pick := drawPick{Step: len(picks) + 1, Team: t, Remaining: len(remaining), Index: index}
+			remaining = append(remaining[:index], remaining[index+1:]...)
+			rest := append(append([]drawTeam(nil), remaining...), all[end:]...)
+
+			for g := range d.groups {
+				// Группы, уже получившие команду этой корзины, не в счет
+				if d.hasPot(g, t.Pot) {
+					continue
+				}
+				if !d.allows(g, t) {
+					pick.Skipped = append(pick.Skipped, drawSkip{groupName(g), "constraint"})
+					continue
+				}
+				d.groups[g] = append(d.groups[g], t)
+				if d.feasible(rest) {
+					pick.Group = groupName(g)
+					break
+				}
+				d.groups[g] = d.groups[g][:len(d.groups[g])-1]
+				pick.Skipped = append(pick.Skipped, drawSkip{groupName(g), "infeasible"})
+			}
+			if pick.Group == "" {
+				return nil, nil, errorf("команду %s не удалось разместить", t.Name)
+			}
+			picks = append(picks, pick)
+		}
+	}
+	return picks, d.groups, nil
+}
+
+// runDraw проводит жеребьевку из опубликованного мастер-сида
+func runDraw(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, tr("Использование:"))
+		fmt.Fprintln(os.Stderr, "  seedgen draw groups --master result.json --pots pots.csv --groups 8 [--constraint no-same-country] [--live --events tcp:127.0.0.1:9000]")
+		fmt.Fprintln(os.Stderr, "  seedgen draw lottery --master result.json --weights weights.csv [--balls 14 --size 4 --picks 4] [--live]")
+		fmt.Fprintln(os.Stderr, "  seedgen draw commit --master result.json --input teams.txt [--note ...] [--out draw-commitment.json]")
+		fmt.Fprintln(os.Stderr, "  seedgen draw reveal --master result.json --commitment draw-commitment.json --input teams.txt --result bracket.json")
+		fmt.Fprintln(os.Stderr, "  seedgen draw verify --reveal draw-reveal.json [--extract DIR]")
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return errorf("укажите действие: groups, lottery, commit, reveal или verify")
+	}
+
+	switch args[0] {
+	case "groups":
+		return runDrawGroups(args[1:])
+	case "lottery":
+		return runDrawLottery(args[1:])
+	case "commit":
+		return runDrawCommit(args[1:])
+	case "reveal":
+		return runDrawReveal(args[1:])
+	case "verify":
+		return runDrawVerify(args[1:])
+	default:
+		return errorf("неизвестное действие %q, доступны: groups, lottery, commit, reveal, verify", args[0])
+	}
+}
+
+// newGroupDraw проводит жеребьевку групп и составляет ее протокол
+func newGroupDraw(master []byte, label string, pots [][]drawTeam, digest string, count int, constraintNames []string, constraints []drawConstraint) (groupDraw, [][]drawTeam, error) {
+	picks, groups, err := drawGroups(newDrawStream(master, "draw-groups", label), pots, count, constraints)
+	if err != nil {
+		return groupDraw{}, nil, err
+	}
+	result := groupDraw{
+		Kind:        "group-draw",
+		Label:       label,
+		Pots:        digest,
+		Constraints: constraintNames,
+		Fingerprint: masterFingerprint(master),
+		Picks:       picks,
+	}
+	for g, teams := range groups {
+		group := drawGroup{Name: groupName(g), Teams: []string{}}
+		for _, t := range teams {
+			group.Teams = append(group.Teams, t.Name)
+		}
+		result.Groups = append(result.Groups, group)
+	}
+	return result, groups, nil
+}
+
+// runDrawGroups проводит жеребьевку групп с корзинами и ограничениями
+func runDrawGroups(args []string) error {
+	fs := newFlagSet("draw groups")
+	potsPath := fs.String("pots", "", "CSV корзин: строки корзина,команда[,страна]")
+	count := fs.Int("groups", 0, "число групп (от 2 до 26); в корзине не больше команд, чем групп")
+	var constraintNames stringList
+	fs.Var(&constraintNames, "constraint", tr("ограничение жеребьевки (можно несколько): ")+strings.Join(drawConstraintNames(), ", "))
+	label := fs.String("label", "groups", "метка жеребьевки: разные метки дают независимые жеребьевки из одного мастер-сида")
+	format := fs.String("format", "text", "формат вывода: text или json")
+	render := fs.String("render", "", "вывести схему групп для печати и публикации: svg или html")
+	rosterPath := addRosterFlag(fs, "pots")
+	lf := addLiveFlags(fs)
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := oneSource(*potsPath, *rosterPath, "pots"); err != nil {
+		return err
+	}
+	if *count < 2 || *count > 26 {
+		return errorf("укажите число групп через --groups: от 2 до 26")
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	if err := checkRender(*render, *format); err != nil {
+		return err
+	}
+	if err := lf.check(*format, *render); err != nil {
+		return err
+	}
+	var constraints []drawConstraint
+	for _, name := range constraintNames {
+		c, err := findDrawConstraint(name)
+		if err != nil {
+			return err
+		}
+		constraints = append(constraints, c)
+	}
+	pots, digest, err := readDrawPots(*potsPath, *rosterPath)
+	if err != nil {
+		return err
+	}
+	for _, pot := range pots {
+		if len(pot) > *count {
+			return errorf("в корзине %d команд: %d, а групп только %d", pot[0].Pot, len(pot), *count)
+		}
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	result, groups, err := newGroupDraw(master, *label, pots, digest, *count, constraintNames, constraints)
+	if err != nil {
+		return err
+	}
+	picks := result.Picks
+	if *format == "json" {
+		return writeOutput(os.Stdout, result)
+	}
+
+	constraintsText := trc("draw-constraints", "нет")
+	if len(constraintNames) > 0 {
+		constraintsText = strings.Join(constraintNames, ", ")
+	}
+	if *render != "" {
+		h := renderHeader{
+			title: fmt.Sprintf(tr("Жеребьевка групп %q"), result.Label),
+			lines: []string{
+				fmt.Sprintf(tr("Команд %d, корзин %d, групп %d, ограничения: %s"), len(picks), len(pots), *count, constraintsText),
+				fmt.Sprintf(tr("Мастер-сид: %s..., корзины: SHA-256 %s"), result.Fingerprint, result.Pots),
+			},
+			build: provenanceLine(),
+		}
+		return writeRender(os.Stdout, *render, h, func(w io.Writer) { renderGroupsSVG(w, groups, h) })
+	}
+	fmt.Printf(tr("Жеребьевка групп %q: команд %d, корзин %d, групп %d, ограничения: %s\n"), result.Label, len(picks), len(pots), *count, constraintsText)
+	fmt.Printf(tr("Мастер-сид: %s..., корзины: SHA-256 %s\n"), emph(result.Fingerprint), result.Pots)
+	fmt.Println()
+	show, err := lf.start(liveStart{Kind: result.Kind, Label: result.Label, Input: result.Pots, Fingerprint: result.Fingerprint, Steps: len(picks)})
+	if err != nil {
+		return err
+	}
+	fmt.Println(tr("Ход жеребьевки:"))
+	for _, p := range picks {
+		if err := show.reveal(formatGroupPick(p), p); err != nil {
+			return err
+		}
+	}
+	show.finish(result)
+	fmt.Println()
+	fmt.Println(tr("Группы:"))
+	for _, g := range result.Groups {
+		fmt.Printf("  %s: %s\n", g.Name, strings.Join(g.Teams, ", "))
+	}
+	return nil
+}
+
+// formatGroupPick записывает шаг жеребьевки групп строкой протокола
+func formatGroupPick(p drawPick) string {
+	team := p.Team.Name
+	if p.Team.Country != "" {
+		team += " (" + p.Team.Country + ")"
+	}
+	line := fmt.Sprintf(tr("  %3d. корзина %d: %s - номер %d из %d -> группа %s\n"), p.Step, p.Team.Pot, team, p.Index+1, p.Remaining, p.Group)
+	if len(p.Skipped) > 0 {
+		var skipped []string
+		for _, s := range p.Skipped {
+			reason := tr("ограничение")
+			if s.Reason == "infeasible" {
+				reason = tr("тупик")
+			}
+			skipped = append(skipped, s.Group+" ("+reason+")")
+		}
+		line += fmt.Sprintf(tr("       пропущены: %s\n"), strings.Join(skipped, ", "))
+	}
+	return line
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// liveFlags - флаги показа жеребьевки по шагам
+type liveFlags struct {
+	live     *bool
+	events   *string
+	interval *time.Duration
+}
+
+// addLiveFlags добавляет флаги показа по шагам для draw groups и draw lottery
+func addLiveFlags(fs *flag.FlagSet) *liveFlags {
+	return &liveFlags{
+		live:     fs.Bool("live", false, "показывать жеребьевку по шагам: следующий шаг по Enter"),
+		events:   fs.String("events", "", "куда отправлять события показа для графики трансляции: файл, unix:ПУТЬ, tcp:ХОСТ:ПОРТ или sse:ХОСТ:ПОРТ (лента Server-Sent Events по HTTP)"),
+		interval: fs.Duration("interval", 0, "с --live: показывать шаги автоматически с этим интервалом вместо Enter"),
+	}
+}
+
+// check проверяет сочетание флагов показа с --format и --render
+func (lf *liveFlags) check(format, render string) error {
+	if !*lf.live {
+		if *lf.events != "" || *lf.interval != 0 {
+			return errorf("--events и --interval работают только с --live")
+		}
+		return nil
+	}
+	if format != "text" || render != "" {
+		return errorf("--live не сочетается с --format json и --render: показ идет текстом в терминале")
+	}
+	if *lf.interval < 0 {
+		return errorf("--interval не может быть отрицательным")
+	}
+	return nil
+}
+
+// liveEvent - событие показа, одна строка JSON: start перед первым шагом,
+// step на каждом шаге, end с полным протоколом (как --format json)
+// или abort, если показ прерван
+type liveEvent struct {
+	Seq  int         `json:"seq"`
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Step int         `json:"step,omitempty"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// liveStart - данные события start: что разыгрывается и из чего
+type liveStart struct {
+	Kind        string `json:"kind"`
+	Label       string `json:"label"`
+	Input       string `json:"input_sha256"`
+	Fingerprint string `json:"master_fingerprint"`
+	Steps       int    `json:"steps"`
+}
+
+// liveShow показывает заранее вычисленную жеребьевку по шагам. Весь
+// результат определен мастер-сидом до показа: показ задает только темп.
+type liveShow struct {
+	in         io.Reader
+	closeInput func()
+	interval   time.Duration
+	events     io.WriteCloser
+	seq, step  int
+	steps      int
+}
+
+// openLiveEvents открывает получателя событий: файл (дописывается, можно
+// именованный канал), сокет unix:ПУТЬ или tcp:ХОСТ:ПОРТ или ленту SSE
+// sse:ХОСТ:ПОРТ, к которой подключаются сами клиенты
+func openLiveEvents(target string) (io.WriteCloser, error) {
+	logDebug("получатель событий", "target", target)
+	switch {
+	case strings.HasPrefix(target, "unix:"):
+		return net.DialTimeout("unix", strings.TrimPrefix(target, "unix:"), 5*time.Second)
+	case strings.HasPrefix(target, "tcp:"):
+		return net.DialTimeout("tcp", strings.TrimPrefix(target, "tcp:"), 5*time.Second)
+	case strings.HasPrefix(target, "sse:"):
+		return listenSSE(strings.TrimPrefix(target, "sse:"))
+	}
+	return os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+}
+
+// start открывает ввод и получателя событий и отправляет событие start.
+// Без --live возвращает nil: шаги тогда выводятся сразу.
+func (lf *liveFlags) start(info liveStart) (*liveShow, error) {
+	if !*lf.live {
+		return nil, nil
+	}
+	s := &liveShow{interval: *lf.interval, steps: info.Steps, closeInput: func() {}}
+	if s.interval == 0 {
+		in, closeInput, err := openConfirmInput()
+		if err != nil {
+			return nil, errorf("показ по Enter требует терминала, без него укажите --interval: %w", err)
+		}
+		s.in, s.closeInput = in, closeInput
+	}
+	if *lf.events != "" {
+		events, err := openLiveEvents(*lf.events)
+		if err != nil {
+			s.closeInput()
+			return nil, errorf("не удалось открыть получателя событий: %w", err)
+		}
+		s.events = events
+	}
+	s.send("start", info)
+	return s, nil
+}
+
+// send отправляет событие; если получатель отвалился, показ продолжается
+// без событий
+func (s *liveShow) send(kind string, data interface{}) {
+	if s.events == nil {
+		return
+	}
+	s.seq++
+	line, err := json.Marshal(liveEvent{Seq: s.seq, Type: kind, Time: time.Now().UTC(), Step: s.step, Data: data})
+	if err == nil {
+		_, err = s.events.Write(append(line, '\n'))
+	}
+	if err != nil {
+		logWarnf("⚠ События больше не отправляются: %v\n", err)
+		s.events.Close()
+		s.events = nil
+	}
+}
+
+// reveal ждет Enter или интервал, выводит строку шага и отправляет событие
+// step. Без --live строка выводится сразу. Если показ прерван, он закрывается.
+func (s *liveShow) reveal(text string, data interface{}) error {
+	if s == nil {
+		fmt.Print(text)
+		return nil
+	}
+	s.step++
+	if s.in != nil {
+		fmt.Printf(tr("Enter - шаг %d из %d..."), s.step, s.steps)
+		if err := readLine(s.in); err != nil {
+			fmt.Println()
+			s.send("abort", nil)
+			s.close()
+			return errorf("показ прерван на шаге %d из %d", s.step, s.steps)
+		}
+	} else {
+		time.Sleep(s.interval)
+	}
+	fmt.Print(text)
+	s.send("step", data)
+	return nil
+}
+
+// finish отправляет событие end с полным протоколом и закрывает показ
+func (s *liveShow) finish(result interface{}) {
+	if s == nil {
+		return
+	}
+	s.send("end", result)
+	s.close()
+}
+
+// close закрывает получателя событий и ввод
+func (s *liveShow) close() {
+	if s.events != nil {
+		s.events.Close()
+	}
+	s.closeInput()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ssePingInterval - период комментариев-пингов, чтобы прокси и балансировщики
+// не закрывали соединение между шагами показа
+const ssePingInterval = 15 * time.Second
+
+// sseEvents - лента событий показа по HTTP в формате Server-Sent Events для
+// графики трансляции и публичной страницы проверки. События нумеруются с 1
+// в поле id: подключившийся позже получает все прошлые события, а после
+// обрыва EventSource продолжает с Last-Event-ID.
+type sseEvents struct {
+	mu     sync.Mutex
+	lines  [][]byte
+	closed bool
+	// notify закрывается и заменяется новым при каждом событии
+	notify chan struct{}
+	server *http.Server
+}
+
+// listenSSE начинает принимать подключения к ленте на addr
+func listenSSE(addr string) (*sseEvents, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &sseEvents{notify: make(chan struct{})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", s.serve)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go s.server.Serve(ln)
+	logInfof("✓ Лента событий показа: http://%s/events\n", ln.Addr())
+	return s, nil
+}
+
+// Write добавляет в ленту одну строку JSON события
+func (s *sseEvents) Write(p []byte) (int, error) {
+	line := bytes.TrimRight(p, "\n")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, errorf("лента событий закрыта")
+	}
+	s.lines = append(s.lines, append([]byte(nil), line...))
+	close(s.notify)
+	s.notify = make(chan struct{})
+	return len(p), nil
+}
+
+// Close завершает ленту: подключенные клиенты получают оставшиеся события,
+// после чего сервер останавливается
+func (s *sseEvents) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.notify)
+	s.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// serve отправляет клиенту события ленты, начиная с Last-Event-ID
+func (s *sseEvents) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	next := 0
+	if id, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil && id > 0 {
+		next = id
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// События публичны: страницу проверки можно открыть с любого сайта
+	h.Set("Access-Control-Allow-Origin", "*")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ping := time.NewTicker(ssePingInterval)
+	defer ping.Stop()
+	for {
+		s.mu.Lock()
+		lines, closed, notify := s.lines, s.closed, s.notify
+		s.mu.Unlock()
+		for ; next < len(lines); next++ {
+			if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", next+1, lines[next]); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+		if closed {
+			return
+		}
+		select {
+		case <-notify:
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// drawCommitDomain отделяет обязательство жеребьевки от других хэшей seedgen
+const drawCommitDomain = "seedgen/draw-commitment/v1"
+
+// drawFile - файл исходных данных или результата жеребьевки.
+// Content заполняется только при раскрытии, Command - команда seedgen
+// без --master, которой получен результат.
+type drawFile struct {
+	Name    string   `json:"name"`
+	SHA256  string   `json:"sha256"`
+	Command []string `json:"command,omitempty"`
+	Content []byte   `json:"content,omitempty"`
+}
+
+// drawCommitment публикуется до жеребьевки: обязательство скрывает
+// мастер-сид, но связывает с ним и с исходными данными организаторов
+type drawCommitment struct {
+	Kind        string     `json:"kind"`
+	Version     string     `json:"version"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Label       string     `json:"label"`
+	Fingerprint string     `json:"master_fingerprint"`
+	Inputs      []drawFile `json:"inputs"`
+	Note        string     `json:"note,omitempty"`
+	Commitment  string     `json:"commitment"`
+}
+
+// drawReveal публикуется после жеребьевки: мастер-сид, исходные данные
+// и результаты, по которым любой сверит обязательство и повторит жеребьевку.
+// Lang - язык сообщений, на котором получены текстовые результаты.
+type drawReveal struct {
+	Kind       string         `json:"kind"`
+	Version    string         `json:"version"`
+	Lang       string         `json:"lang,omitempty"`
+	RevealedAt time.Time      `json:"revealed_at"`
+	Commitment drawCommitment `json:"commitment"`
+	Master     string         `json:"master"`
+	Inputs     []drawFile     `json:"inputs"`
+	Results    []drawFile     `json:"results,omitempty"`
+}
+
+// drawCommitmentHash вычисляет обязательство: SHA-256 от домена, метки,
+// мастер-сида и SHA-256 исходных файлов по порядку, разделенных нулевым байтом
+func drawCommitmentHash(label string, master []byte, inputs []drawFile) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(drawCommitDomain))
+	h.Write([]byte{0})
+	h.Write([]byte(label))
+	h.Write([]byte{0})
+	h.Write(master)
+	for _, in := range inputs {
+		sum, err := hex.DecodeString(in.SHA256)
+		if err != nil || len(sum) != sha256.Size {
+			return "", errorf("некорректный SHA-256 файла %s", in.Name)
+		}
+		h.Write([]byte{0})
+		h.Write(sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readDrawFiles читает файлы и вычисляет их SHA-256; с withContent сохраняет содержимое
+func readDrawFiles(paths []string, withContent bool) ([]drawFile, error) {
+	var files []drawFile
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		f := drawFile{Name: filepath.Base(path), SHA256: hex.EncodeToString(sum[:])}
+		if withContent {
+			f.Content = data
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// runDrawCommit публикует обязательство до жеребьевки
+func runDrawCommit(args []string) error {
+	fs := newFlagSet("draw commit")
+	var inputs stringList
+	fs.Var(&inputs, "input", "файл исходных данных жеребьевки: список участников, корзины, веса (можно несколько)")
+	label := fs.String("label", "draw", "метка жеребьевки")
+	note := fs.String("note", "", "описание жеребьевки для публикации: дата, турнир, какие команды будут запущены")
+	out := fs.String("out", "draw-commitment.json", "файл обязательства")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if len(inputs) == 0 {
+		return errorf("укажите исходные данные жеребьевки через --input")
+	}
+	files, err := readDrawFiles(inputs, false)
+	if err != nil {
+		return err
+	}
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	commitment, err := drawCommitmentHash(*label, master, files)
+	if err != nil {
+		return err
+	}
+	c := drawCommitment{
+		Kind:        "draw-commitment",
+		Version:     buildVersion(),
+		CreatedAt:   time.Now().UTC(),
+		Label:       *label,
+		Fingerprint: masterFingerprint(master),
+		Inputs:      files,
+		Note:        *note,
+		Commitment:  commitment,
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(*out, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf(tr("✓ Обязательство записано в %s\n"), *out)
+	fmt.Printf(tr("  Обязательство: %s\n"), commitment)
+	fmt.Printf(tr("  Мастер-сид:    %s...\n"), c.Fingerprint)
+	for _, f := range files {
+		fmt.Printf("  %-14s %s\n", f.Name+":", f.SHA256)
+	}
+	fmt.Println(tr("Опубликуйте файл до жеребьевки; после нее раскройте данные командой seedgen draw reveal"))
+	return nil
+}
+
+// runDrawReveal сверяет мастер-сид и исходные данные с обязательством
+// и записывает раскрытие вместе с результатами жеребьевки
+func runDrawReveal(args []string) error {
+	fs := newFlagSet("draw reveal")
+	commitmentPath := fs.String("commitment", "draw-commitment.json", "опубликованное обязательство")
+	var inputs, results stringList
+	fs.Var(&inputs, "input", "файл исходных данных в том же порядке, что и при draw commit (можно несколько)")
+	fs.Var(&results, "result", "файл результата жеребьевки, например вывод --format json (можно несколько)")
+	var commands stringList
+	fs.Var(&commands, "command", "команда seedgen без --master, которой получен --result с тем же номером, для seedgen verify-draw (можно несколько)")
+	out := fs.String("out", "draw-reveal.json", "файл раскрытия")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(*commitmentPath)
+	if err != nil {
+		return err
+	}
+	var c drawCommitment
+	if err := json.Unmarshal(data, &c); err != nil {
+		return errorf("не удалось разобрать обязательство %s: %w", *commitmentPath, err)
+	}
+	if c.Kind != "draw-commitment" {
+		return errorf("%s не является обязательством жеребьевки", *commitmentPath)
+	}
+	inFiles, err := readDrawFiles(inputs, true)
+	if err != nil {
+		return err
+	}
+	resultFiles, err := readDrawFiles(results, true)
+	if err != nil {
+		return err
+	}
+	if len(commands) > 0 && len(commands) != len(results) {
+		return errorf("укажите --command для каждого --result: результатов %d, команд %d", len(results), len(commands))
+	}
+	for i, line := range commands {
+		args, err := splitCommandLine(line)
+		if err != nil {
+			return errorf("--command %q: %w", line, err)
+		}
+		if err := checkDrawCommand(args); err != nil {
+			return errorf("--command %q: %w", line, err)
+		}
+		resultFiles[i].Command = args
+	}
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+
+	r := drawReveal{
+		Kind:       "draw-reveal",
+		Version:    buildVersion(),
+		Lang:       lang,
+		RevealedAt: time.Now().UTC(),
+		Commitment: c,
+		Master:     hex.EncodeToString(master),
+		Inputs:     inFiles,
+		Results:    resultFiles,
+	}
+	// Раскрытие проверяется так же, как его проверят другие, до записи файла
+	if err := r.verify(); err != nil {
+		return err
+	}
+	data, err = json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(*out, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf(tr("✓ Раскрытие записано в %s: обязательство %s сходится\n"), *out, c.Commitment)
+	fmt.Println(tr("⚠ Файл содержит мастер-сид: ключи, выведенные из него через derive, больше не секретны"))
+	return nil
+}
+
+// verify сверяет раскрытие с обязательством и результаты с мастер-сидом
+func (r *drawReveal) verify() error {
+	master, err := hex.DecodeString(r.Master)
+	if err != nil || len(master) != masterSeedSize {
+		return errorf("раскрытие содержит некорректный мастер-сид")
+	}
+	if fp := masterFingerprint(master); fp != r.Commitment.Fingerprint {
+		return mismatchf("отпечаток мастер-сида %s, а в обязательстве %s", fp, r.Commitment.Fingerprint)
+	}
+	if len(r.Inputs) != len(r.Commitment.Inputs) {
+		return mismatchf("в обязательстве исходных файлов %d, а раскрыто %d", len(r.Commitment.Inputs), len(r.Inputs))
+	}
+	for i, in := range r.Inputs {
+		sum := sha256.Sum256(in.Content)
+		if hex.EncodeToString(sum[:]) != in.SHA256 {
+			return mismatchf("содержимое файла %s не совпадает с его SHA-256", in.Name)
+		}
+		if in.SHA256 != r.Commitment.Inputs[i].SHA256 {
+			return mismatchf("исходный файл %d (%s) отличается от указанного в обязательстве (%s)", i+1, in.Name, r.Commitment.Inputs[i].Name)
+		}
+	}
+	commitment, err := drawCommitmentHash(r.Commitment.Label, master, r.Inputs)
+	if err != nil {
+		return err
+	}
+	if commitment != r.Commitment.Commitment {
+		return mismatchf("обязательство не сходится: вычислено %s, опубликовано %s", commitment, r.Commitment.Commitment)
+	}
+	for _, res := range r.Results {
+		sum := sha256.Sum256(res.Content)
+		if hex.EncodeToString(sum[:]) != res.SHA256 {
+			return mismatchf("содержимое результата %s не совпадает с его SHA-256", res.Name)
+		}
+		// Результаты команд жеребьевки в JSON указывают отпечаток мастер-сида
+		var rec struct {
+			Fingerprint string `json:"master_fingerprint"`
+		}
+		if json.Unmarshal(res.Content, &rec) == nil && rec.Fingerprint != "" && rec.Fingerprint != r.Commitment.Fingerprint {
+			return mismatchf("результат %s получен из мастер-сида %s, а не из объявленного", res.Name, rec.Fingerprint)
+		}
+	}
+	return nil
+}
+
+// runDrawVerify проверяет опубликованное раскрытие и при необходимости
+// извлекает мастер-сид и файлы, чтобы повторить жеребьевку
+func runDrawVerify(args []string) error {
+	fs := newFlagSet("draw verify")
+	revealPath := fs.String("reveal", "draw-reveal.json", "опубликованное раскрытие")
+	extract := fs.String("extract", "", "каталог, куда записать мастер-сид (master.hex), исходные данные и результаты")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(*revealPath)
+	if err != nil {
+		return err
+	}
+	var r drawReveal
+	if err := json.Unmarshal(data, &r); err != nil {
+		return errorf("не удалось разобрать раскрытие %s: %w", *revealPath, err)
+	}
+	if r.Kind != "draw-reveal" {
+		return errorf("%s не является раскрытием жеребьевки", *revealPath)
+	}
+	if err := r.verify(); err != nil {
+		return err
+	}
+	fmt.Printf(tr("✓ Обязательство %s сходится\n"), r.Commitment.Commitment)
+	fmt.Printf(tr("  Метка:        %s\n"), r.Commitment.Label)
+	fmt.Printf(tr("  Обязательство: %s, раскрытие: %s\n"), r.Commitment.CreatedAt.Format(time.RFC3339), r.RevealedAt.Format(time.RFC3339))
+	if r.Commitment.Note != "" {
+		fmt.Printf(tr("  Описание:     %s\n"), r.Commitment.Note)
+	}
+	fmt.Printf(tr("  Мастер-сид:   %s (%s...)\n"), r.Master, r.Commitment.Fingerprint)
+	for _, in := range r.Inputs {
+		fmt.Printf(tr("  Исходные:     %s (SHA-256 %s)\n"), in.Name, in.SHA256)
+	}
+	for _, res := range r.Results {
+		fmt.Printf(tr("  Результат:    %s (SHA-256 %s)\n"), res.Name, res.SHA256)
+	}
+	if *extract == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(*extract, 0755); err != nil {
+		return err
+	}
+	if err := writeNewFile(filepath.Join(*extract, "master.hex"), []byte(r.Master+"\n"), 0644); err != nil {
+		return err
+	}
+	for _, f := range append(append([]drawFile(nil), r.Inputs...), r.Results...) {
+		name := filepath.Base(f.Name)
+		if name == "." || name == ".." || name == string(filepath.Separator) || name == "master.hex" {
+			return errorf("недопустимое имя файла %q в раскрытии", f.Name)
+		}
+		if err := writeNewFile(filepath.Join(*extract, name), f.Content, 0644); err != nil {
+			return err
+		}
+	}
+	fmt.Printf(tr("✓ Файлы записаны в %s: повторите жеребьевку с --master %s и сравните результаты\n"), *extract, filepath.Join(*extract, "master.hex"))
+	return nil
+}
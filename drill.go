@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// drillRecord - JSON-запись учебного восстановления для отчета о
+// регулярных учениях. Секретов в ней нет: только отпечатки и время.
+type drillRecord struct {
+	Kind        string    `json:"kind"`
+	Method      string    `json:"method"`
+	Fingerprint string    `json:"fingerprint"`
+	Passed      bool      `json:"passed"`
+	Recovered   string    `json:"recovered_fingerprint,omitempty"`
+	Inputs      int       `json:"inputs"`
+	Seconds     int       `json:"duration_seconds"`
+	StartedAt   time.Time `json:"started_at"`
+}
+
+// drillSeedFlagNames перечисляет флаги источников сидов, бесполезные для долей
+var drillSeedFlagNames = []string{"seeds-credential", "seeds-from", "seed-file", "seed-check", "mask-seeds", "strict-input", "review", "resume"}
+
+// runDrill проводит учебное восстановление: оператор вводит доли комплекта
+// хранителей или сиды устройств, как при настоящем восстановлении, а
+// собранный мастер-сид только сверяется с сохраненным отпечатком и сразу
+// затирается. Ни мастер-сид, ни доли, ни сиды на экран не выводятся, ввод
+// с клавиатуры скрыт, поэтому учения можно проводить на рабочей машине.
+func runDrill(args []string) error {
+	fs := newFlagSet("drill")
+	fingerprint := fs.String("fingerprint", "", "сохраненный отпечаток мастер-сида (16 символов hex): с ним сверяется результат")
+	method := fs.String("method", "shares", "что вводится: shares - доли backup-kit, seeds - сиды устройств")
+	format := fs.String("format", "text", "формат итога: text или json (запись для отчета об учении)")
+	sf := addSchemeFlags(fs)
+	ssf := addSeedSourceFlags(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	want := strings.ToLower(*fingerprint)
+	if len(want) != 16 || strings.Trim(want, "0123456789abcdef") != "" {
+		return errorf("укажите сохраненный отпечаток мастер-сида: --fingerprint из 16 символов hex")
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	var params Params
+	switch *method {
+	case "shares":
+		set := setFlags(fs)
+		for _, name := range append(append([]string{"scheme", "params-file", "compat"}, v2FlagNames...), drillSeedFlagNames...) {
+			if set[name] {
+				return errorf("флаг --%s применим только к --method seeds", name)
+			}
+		}
+	case "seeds":
+		if len(positional) > 0 {
+			return errorf("с --method seeds сиды вводятся с клавиатуры или флагами источников, а не файлами в аргументах")
+		}
+		if params, err = sf.params(fs); err != nil {
+			return err
+		}
+		ssf.raw = sf.compatV1()
+	default:
+		return errorf("неизвестный способ %q, доступны: shares, seeds", *method)
+	}
+
+	var prompts io.Writer = os.Stdout
+	if *format != "text" {
+		prompts = os.Stderr
+	}
+	fmt.Fprintln(prompts, tr("=== Учебное восстановление ==="))
+	fmt.Fprintf(prompts, tr("Секреты не показываются: результат сверяется только с отпечатком %s\n\n"), want)
+	started := time.Now()
+
+	var master []byte
+	var inputs int
+	if *method == "shares" {
+		master, inputs, err = drillShares(prompts, positional)
+	} else {
+		master, inputs, err = drillSeeds(prompts, ssf, params)
+	}
+	if err != nil {
+		return err
+	}
+	got := masterFingerprint(master)
+	wipe(master)
+
+	record := drillRecord{
+		Kind:        "recovery-drill",
+		Method:      *method,
+		Fingerprint: want,
+		Passed:      got == want,
+		Inputs:      inputs,
+		Seconds:     int(time.Since(started).Seconds()),
+		StartedAt:   started.UTC().Truncate(time.Second),
+	}
+	if !record.Passed {
+		record.Recovered = got
+	}
+	auditResult("master", "", got)
+	elapsed := time.Duration(record.Seconds) * time.Second
+	if *format == "json" {
+		if err := writeOutput(os.Stdout, record); err != nil {
+			return err
+		}
+	} else if record.Passed {
+		fmt.Printf(tr("✓ Учение пройдено: мастер-сид восстановлен, отпечаток совпадает с %s (введено: %d, время: %s)\n"), want, inputs, elapsed)
+	}
+	if !record.Passed {
+		return mismatchf("учение не пройдено: восстановлен мастер-сид с отпечатком %s вместо %s (введено: %d, время: %s)", got, want, inputs, elapsed)
+	}
+	return nil
+}
+
+// drillShares собирает мастер-сид из долей комплекта: из файлов или со
+// скрытым вводом в терминале. Возвращает также число введенных долей.
+func drillShares(prompts io.Writer, refs []string) ([]byte, int, error) {
+	if len(refs) == 0 {
+		refs = []string{"-"}
+	}
+	var shares []*shamirShare
+	defer func() {
+		for _, s := range shares {
+			wipe(s.data)
+		}
+	}()
+	for _, ref := range refs {
+		var text []byte
+		var err error
+		switch {
+		case ref != "-":
+			text, err = os.ReadFile(ref)
+		case isTerminal(os.Stdin):
+			fmt.Fprintln(prompts, tr("Вводите доли с листов построчно, от строки ss1: до строки check: (ввод скрыт); пустая строка - конец ввода:"))
+			text, err = readMaskedLines(prompts)
+		default:
+			text, err = io.ReadAll(os.Stdin)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		found, err := kitSharesFrom(ref, text)
+		if err != nil {
+			return nil, 0, err
+		}
+		shares = append(shares, found...)
+	}
+	master, err := combineShares(shares)
+	return master, len(shares), err
+}
+
+// readMaskedLines читает из терминала строки со звездочками до пустой
+// строки. Отказ скрыть ввод - ошибка: на учениях доли не должны
+// появляться на экране.
+func readMaskedLines(prompts io.Writer) ([]byte, error) {
+	restore, err := enterMaskedMode(os.Stdin)
+	if err != nil {
+		return nil, errorf("не удалось скрыть ввод: %w", err)
+	}
+	defer restore()
+	buf := newSecret(maskedLineSize)
+	defer wipe(buf)
+	text := newSecret(kitSheetSize)[:0]
+	for {
+		fmt.Fprint(prompts, "> ")
+		line, err := readMasked(os.Stdin, prompts, buf)
+		if err == io.EOF {
+			return text, nil
+		}
+		if err != nil {
+			wipe(text[:cap(text)])
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			return text, nil
+		}
+		if len(text)+len(line)+1 > cap(text) {
+			wipe(text[:cap(text)])
+			return nil, errorf("ввод длиннее %d байт", cap(text))
+		}
+		text = append(append(text, line...), '\n')
+	}
+}
+
+// drillSeeds вычисляет мастер-сид из сидов устройств, введенных так же,
+// как на церемонии, но всегда со звездочками при вводе с клавиатуры.
+// Возвращает также число введенных сидов.
+func drillSeeds(prompts io.Writer, ssf *seedSourceFlags, params Params) ([]byte, int, error) {
+	if len(ssf.credentials) == 0 && len(ssf.managers) == 0 && len(ssf.files) == 0 {
+		*ssf.masked = true
+	}
+	// Учение ничего не сохраняет, в том числе прерванный ввод
+	ssf.dryRun = true
+	seeds, err := ssf.read(prompts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer wipeSeeds(seeds)
+	if len(seeds) == 0 {
+		return nil, 0, errorf("не введено ни одного сида")
+	}
+	fmt.Fprintf(prompts, tr("\n✓ Получено сидов: %d\n\n"), len(seeds))
+	stopProgress := showKDFProgress()
+	master, err := GenerateMasterSeed(seeds, params)
+	stopProgress()
+	if err != nil {
+		return nil, 0, errorf("ошибка генерации: %w", err)
+	}
+	return master, len(seeds), nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// plannedOutput - результат, который команда сохранила бы без --dry-run.
+// file задан, если результат пишется в новый файл: существующий файл
+// не перезаписывается, и церемония завершилась бы ошибкой уже после вычисления.
+type plannedOutput struct {
+	what string
+	file string
+}
+
+// saltSource описывает, откуда взята соль схемы
+func (f *schemeFlags) saltSource(fs *flag.FlagSet) string {
+	switch {
+	case *f.paramsFile != "":
+		return fmt.Sprintf(tr("манифест %s"), *f.paramsFile)
+	case *f.scheme == "v1":
+		return tr("фиксированная соль схемы v1")
+	case setFlags(fs)["salt"]:
+		return tr("задана явно: --salt, SEEDGEN_SALT или профиль")
+	}
+	return tr("соль по умолчанию")
+}
+
+// targets перечисляет хранилища, куда был бы сохранен мастер-сид
+func (sf *storeFlags) targets() []plannedOutput {
+	var out []plannedOutput
+	if *sf.tpm.out != "" {
+		out = append(out, plannedOutput{fmt.Sprintf(tr("запечатывание в TPM (PCR %s)"), *sf.tpm.pcrs), *sf.tpm.out})
+	}
+	if *sf.keychain != "" {
+		out = append(out, plannedOutput{"keychain:" + *sf.keychain, ""})
+	}
+	if *sf.dpapi != "" {
+		out = append(out, plannedOutput{fmt.Sprintf(tr("DPAPI (область %s)"), *sf.dpapiScope), *sf.dpapi})
+	}
+	if *sf.keyring != "" {
+		out = append(out, plannedOutput{fmt.Sprintf(tr("связка ключей ядра %s: keyring:%s"), *sf.keyringIn, *sf.keyring), ""})
+	}
+	if *sf.pkcs11 != "" {
+		out = append(out, plannedOutput{tr("токен PKCS#11 как неизвлекаемый ключ"), ""})
+	}
+	if *sf.credential != "" {
+		out = append(out, plannedOutput{tr("учетные данные systemd"), *sf.credential})
+	}
+	if *sf.kms.key != "" {
+		out = append(out, plannedOutput{fmt.Sprintf(tr("конверт AWS KMS (ключ %s)"), *sf.kms.key), *sf.kms.out})
+	}
+	if *sf.vault != "" {
+		out = append(out, plannedOutput{"Vault: vault:" + strings.Trim(*sf.vault, "/"), ""})
+	}
+	if *sf.manager != "" {
+		out = append(out, plannedOutput{tr("менеджер паролей: ") + *sf.manager, ""})
+	}
+	return out
+}
+
+// targets перечисляет файлы квитанции и запроса метки времени
+func (rf *receiptFlags) targets() []plannedOutput {
+	if rf.key == nil && *rf.timestamp == "" {
+		return nil
+	}
+	what := tr("квитанция без подписи")
+	if rf.key != nil {
+		what = tr("подписанная квитанция")
+	}
+	out := []plannedOutput{{what, *rf.out}}
+	if *rf.timestamp != "" {
+		out = append(out, plannedOutput{tr("запрос метки времени"), *rf.timestamp})
+	}
+	return out
+}
+
+// printDryRun выводит план церемонии: параметры, источник соли, результаты
+// и обязательство набора сидов. Обязательство вычисляется из мастер-сида,
+// поэтому KDF выполняется, но мастер-сид остается в памяти: он не
+// выводится, ключи из него не выводятся, и ничего не записывается.
+// Для файлов, которые уже существуют, возвращается ошибка: настоящий запуск
+// отказался бы их перезаписывать.
+func printDryRun(w io.Writer, params Params, saltSource string, outputs []plannedOutput, master []byte) error {
+	fmt.Fprintln(w, tr("Пробный запуск: мастер-сид не выводится, файлы не записываются"))
+	fmt.Fprintln(w)
+	if params.Scheme != "v1" {
+		fmt.Fprintln(w, describeParams(params))
+		fmt.Fprintf(w, tr("Соль: %q (%s), в KDF: %q\n"), params.Salt, saltSource, fmt.Sprintf("%s/epoch/%d", params.Salt, params.Epoch))
+	} else {
+		fmt.Fprintf(w, tr("Схема: v1, %s, %d итераций\n"), params.KDF, params.Iterations)
+		fmt.Fprintf(w, tr("Соль: %q (%s)\n"), params.Salt, saltSource)
+	}
+	fmt.Fprintln(w, tr("Результаты:"))
+	existing := 0
+	for _, o := range outputs {
+		if o.file == "" {
+			fmt.Fprintf(w, "  %s\n", o.what)
+			continue
+		}
+		fmt.Fprintf(w, "  %s: %s\n", o.what, o.file)
+		if _, err := os.Stat(o.file); err == nil {
+			fmt.Fprint(w, tr("    ❌ файл уже существует, запись завершится ошибкой\n"))
+			existing++
+		}
+	}
+	fmt.Fprintln(w)
+	printSeedCommitment(w, master)
+	if existing > 0 {
+		return errorf("файлов результата уже существует: %d, удалите их или выберите другие имена", existing)
+	}
+	fmt.Fprintln(w, tr("✓ Входные данные проверены, церемонию можно проводить с теми же флагами без --dry-run"))
+	return nil
+}
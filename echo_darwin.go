@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// disableEcho отключает эхо ввода терминала f и возвращает функцию,
+// восстанавливающую прежний режим
+func disableEcho(f *os.File) (func(), error) {
+	fd := int(f.Fd())
+	state, err := unix.IoctlGetTermios(fd, unix.TIOCGETA)
+	if err != nil {
+		return nil, err
+	}
+	silent := *state
+	silent.Lflag &^= unix.ECHO
+	if err := unix.IoctlSetTermios(fd, unix.TIOCSETA, &silent); err != nil {
+		return nil, err
+	}
+	return func() { unix.IoctlSetTermios(fd, unix.TIOCSETA, state) }, nil
+}
+
+// enterMaskedMode переводит терминал f в посимвольный ввод без эха и без
+// сигналов: Ctrl-C приходит байтом и обрабатывается readMasked, поэтому
+// прежний режим восстанавливается и при отмене
+func enterMaskedMode(f *os.File) (func(), error) {
+	fd := int(f.Fd())
+	state, err := unix.IoctlGetTermios(fd, unix.TIOCGETA)
+	if err != nil {
+		return nil, err
+	}
+	raw := *state
+	raw.Lflag &^= unix.ECHO | unix.ICANON | unix.ISIG
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, unix.TIOCSETA, &raw); err != nil {
+		return nil, err
+	}
+	return func() { unix.IoctlSetTermios(fd, unix.TIOCSETA, state) }, nil
+}
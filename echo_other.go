@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package main
+
+import (
+	"os"
+)
+
+// disableEcho на остальных системах не поддерживается: вызывающий
+// предупреждает, что ввод будет виден
+func disableEcho(f *os.File) (func(), error) {
+	return nil, errorf("отключение эха терминала не поддерживается на этой системе")
+}
+
+// enterMaskedMode на остальных системах не поддерживается: ввод читается
+// без эха или виден целиком
+func enterMaskedMode(f *os.File) (func(), error) {
+	return nil, errorf("посимвольный ввод терминала не поддерживается на этой системе")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Флаги режима ввода консоли
+const (
+	consoleProcessedInput = 0x1 // ENABLE_PROCESSED_INPUT: Ctrl-C как сигнал
+	consoleLineInput      = 0x2 // ENABLE_LINE_INPUT: ввод строками
+	consoleEchoInput      = 0x4 // ENABLE_ECHO_INPUT
+)
+
+var (
+	procGetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("GetConsoleMode")
+	procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+)
+
+// disableEcho отключает эхо ввода консоли f и возвращает функцию,
+// восстанавливающую прежний режим
+func disableEcho(f *os.File) (func(), error) {
+	handle := f.Fd()
+	var mode uint32
+	if ok, _, err := procGetConsoleMode.Call(handle, uintptr(unsafe.Pointer(&mode))); ok == 0 {
+		return nil, err
+	}
+	if ok, _, err := procSetConsoleMode.Call(handle, uintptr(mode&^consoleEchoInput)); ok == 0 {
+		return nil, err
+	}
+	return func() { procSetConsoleMode.Call(handle, uintptr(mode)) }, nil
+}
+
+// enterMaskedMode переводит консоль f в посимвольный ввод без эха. Ctrl-C
+// приходит символом и обрабатывается readMasked, поэтому прежний режим
+// восстанавливается и при отмене. Символы читаются через ReadConsoleW,
+// так что кириллица приходит в UTF-8 при любой кодовой странице.
+func enterMaskedMode(f *os.File) (func(), error) {
+	handle := f.Fd()
+	var mode uint32
+	if ok, _, err := procGetConsoleMode.Call(handle, uintptr(unsafe.Pointer(&mode))); ok == 0 {
+		return nil, err
+	}
+	raw := mode &^ (consoleProcessedInput | consoleLineInput | consoleEchoInput)
+	if ok, _, err := procSetConsoleMode.Call(handle, uintptr(raw)); ok == 0 {
+		return nil, err
+	}
+	return func() { procSetConsoleMode.Call(handle, uintptr(mode)) }, nil
+}
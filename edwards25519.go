@@ -0,0 +1,174 @@
+package main
+
+import (
+	"math/big"
+)
+
+// Параметры кривой edwards25519 (RFC 8032): -x² + y² = 1 + d·x²·y² над полем p,
+// L - порядок подгруппы базовой точки
+var (
+	edP, _      = new(big.Int).SetString("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed", 16)
+	edD, _      = new(big.Int).SetString("37095705934669439343138083508754565189542113879843219016388785533085940283555", 10)
+	edL, _      = new(big.Int).SetString("1000000000000000000000000000000014def9dea2f79cd65812631a5cf5d3ed", 16)
+	edBx, _     = new(big.Int).SetString("15112221349535400772501151409588531511454012693041857206046113283949847762202", 10)
+	edBy, _     = new(big.Int).SetString("46316835694926478169428394003475163141307993866256225615783033603165251855960", 10)
+	edSqrtM1, _ = new(big.Int).SetString("19681161376707505956807079304988542015446066515923890162744021073123829784752", 10)
+)
+
+// edPoint - точка кривой в аффинных координатах. Как и secpPoint, арифметика
+// на math/big не выполняется за постоянное время и годится только для
+// офлайн-вычислений церемонии.
+type edPoint struct {
+	x, y *big.Int
+}
+
+// edIdentity возвращает нейтральную точку (0, 1)
+func edIdentity() edPoint {
+	return edPoint{x: big.NewInt(0), y: big.NewInt(1)}
+}
+
+// edBase возвращает базовую точку B
+func edBase() edPoint {
+	return edPoint{x: edBx, y: edBy}
+}
+
+// edAdd складывает точки по полной формуле сложения: для edwards25519 она
+// верна и для удвоения, и для нейтральной точки
+func edAdd(a, b edPoint) edPoint {
+	x1y2 := new(big.Int).Mul(a.x, b.y)
+	y1x2 := new(big.Int).Mul(a.y, b.x)
+	y1y2 := new(big.Int).Mul(a.y, b.y)
+	x1x2 := new(big.Int).Mul(a.x, b.x)
+	t := new(big.Int).Mul(x1x2, y1y2)
+	t.Mul(t, edD).Mod(t, edP)
+
+	// x3 = (x1y2 + y1x2) / (1 + d·x1x2y1y2), y3 = (y1y2 + x1x2) / (1 - d·x1x2y1y2)
+	dx := new(big.Int).Add(big.NewInt(1), t)
+	dy := new(big.Int).Sub(big.NewInt(1), t)
+	dx.Mod(dx, edP).ModInverse(dx, edP)
+	dy.Mod(dy, edP).ModInverse(dy, edP)
+	x := x1y2.Add(x1y2, y1x2)
+	x.Mul(x, dx).Mod(x, edP)
+	y := y1y2.Add(y1y2, x1x2)
+	y.Mul(y, dy).Mod(y, edP)
+	return edPoint{x: x, y: y}
+}
+
+// edScalarMult вычисляет k·P
+func edScalarMult(k *big.Int, p edPoint) edPoint {
+	r := edIdentity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = edAdd(r, r)
+		if k.Bit(i) == 1 {
+			r = edAdd(r, p)
+		}
+	}
+	return r
+}
+
+// edScalarBaseMult вычисляет k·B
+func edScalarBaseMult(k *big.Int) edPoint {
+	return edScalarMult(k, edBase())
+}
+
+// equal сравнивает точки
+func (p edPoint) equal(q edPoint) bool {
+	return p.x.Cmp(q.x) == 0 && p.y.Cmp(q.y) == 0
+}
+
+// encode возвращает 32-байтовое представление RFC 8032: y в little-endian,
+// старший бит - четность x
+func (p edPoint) encode() []byte {
+	out := littleEndian(p.y, 32)
+	out[31] |= byte(p.x.Bit(0)) << 7
+	return out
+}
+
+// edDecode разбирает представление точки и проверяет, что она лежит
+// в подгруппе порядка L: точки малого порядка позволили бы участнику
+// незаметно исказить общий ключ
+func edDecode(b []byte) (edPoint, error) {
+	if len(b) != 32 {
+		return edPoint{}, errorf("точка кривой должна занимать 32 байта, получено %d", len(b))
+	}
+	le := append([]byte(nil), b...)
+	sign := le[31] >> 7
+	le[31] &= 0x7f
+	y := fromLittleEndian(le)
+	if y.Cmp(edP) >= 0 {
+		return edPoint{}, errorf("некорректная точка кривой")
+	}
+
+	// x² = (y² - 1) / (d·y² + 1)
+	y2 := new(big.Int).Mul(y, y)
+	u := new(big.Int).Sub(y2, big.NewInt(1))
+	v := new(big.Int).Mul(edD, y2)
+	v.Add(v, big.NewInt(1)).Mod(v, edP)
+	x2 := u.Mul(u, v.ModInverse(v, edP)).Mod(u, edP)
+
+	// Корень по RFC 8032, 5.1.3: x = x2^((p+3)/8), при необходимости умноженный на √-1
+	exp := new(big.Int).Add(edP, big.NewInt(3))
+	exp.Rsh(exp, 3)
+	x := new(big.Int).Exp(x2, exp, edP)
+	if edSquare(x).Cmp(x2) != 0 {
+		x.Mul(x, edSqrtM1).Mod(x, edP)
+	}
+	if edSquare(x).Cmp(x2) != 0 {
+		return edPoint{}, errorf("некорректная точка кривой")
+	}
+	if x.Sign() == 0 && sign == 1 {
+		return edPoint{}, errorf("некорректная точка кривой")
+	}
+	if byte(x.Bit(0)) != sign {
+		x.Sub(edP, x)
+	}
+
+	p := edPoint{x: x, y: y}
+	if !edScalarMult(edL, p).equal(edIdentity()) {
+		return edPoint{}, errorf("точка кривой не лежит в подгруппе простого порядка")
+	}
+	return p, nil
+}
+
+// edSquare возвращает x² по модулю p
+func edSquare(x *big.Int) *big.Int {
+	sq := new(big.Int).Mul(x, x)
+	return sq.Mod(sq, edP)
+}
+
+// edScalarReduce приводит строку байтов little-endian (обычно 64 байта
+// SHA-512) к скаляру по модулю L
+func edScalarReduce(b []byte) *big.Int {
+	k := fromLittleEndian(b)
+	return k.Mod(k, edL)
+}
+
+// edScalarDecode разбирает 32-байтовый скаляр, отвергая значения не меньше L
+func edScalarDecode(b []byte) (*big.Int, error) {
+	if len(b) != 32 {
+		return nil, errorf("скаляр должен занимать 32 байта, получено %d", len(b))
+	}
+	k := fromLittleEndian(b)
+	if k.Cmp(edL) >= 0 {
+		return nil, errorf("скаляр вне диапазона")
+	}
+	return k, nil
+}
+
+// littleEndian записывает число в n байт little-endian
+func littleEndian(k *big.Int, n int) []byte {
+	out := k.FillBytes(make([]byte, n))
+	for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// fromLittleEndian читает число из байтов little-endian
+func fromLittleEndian(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// symbol - один физический исход (бросок, монета, карта) с его основанием
+type symbol struct {
+	value int
+	radix int
+}
+
+// entropySource описывает физический источник энтропии
+type entropySource interface {
+	// describe возвращает подсказку для оператора
+	describe() string
+	// parse разбирает строку ввода в последовательность исходов
+	parse(line string) ([]symbol, error)
+}
+
+// extractor без смещения превращает исходы с произвольным основанием в байты.
+//
+// Накопленное значение v равномерно распределено на [0, r). Когда r >= 256,
+// при v < 256*floor(r/256) младший байт v равномерен и выдается наружу, а
+// иначе значение отбрасывается (rejection sampling) с сохранением остатка
+// v-m на диапазоне r-m, так что ни один бит смещения не попадает в результат.
+type extractor struct {
+	v, r uint64
+	out  []byte
+	bits float64
+}
+
+// newExtractor создает пустой экстрактор
+func newExtractor() *extractor {
+	return &extractor{v: 0, r: 1}
+}
+
+// add добавляет один исход
+func (e *extractor) add(s symbol) {
+	e.v = e.v*uint64(s.radix) + uint64(s.value)
+	e.r *= uint64(s.radix)
+	e.bits += math.Log2(float64(s.radix))
+
+	for e.r >= 256 {
+		m := e.r - e.r%256
+		if e.v < m {
+			e.out = append(e.out, byte(e.v%256))
+			e.v /= 256
+			e.r = m / 256
+		} else {
+			e.v -= m
+			e.r -= m
+		}
+	}
+}
+
+// diceSource принимает броски кубика с заданным числом граней
+type diceSource struct {
+	sides int
+}
+
+func (d diceSource) describe() string {
+	return fmt.Sprintf(tr("Вводите результаты бросков d%d (1-%d) через пробел"), d.sides, d.sides)
+}
+
+func (d diceSource) parse(line string) ([]symbol, error) {
+	fields := strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' })
+
+	// Для d6 допускаем слитную запись вида "351624"
+	if d.sides < 10 && len(fields) == 1 && len(fields[0]) > 1 {
+		fields = strings.Split(fields[0], "")
+	}
+
+	symbols := make([]symbol, 0, len(fields))
+	for _, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 1 || n > d.sides {
+			return nil, errorf("%q не является результатом броска d%d", f, d.sides)
+		}
+		symbols = append(symbols, symbol{value: n - 1, radix: d.sides})
+	}
+	return symbols, nil
+}
+
+// coinSource принимает броски монеты в виде строк H/T (или О/Р)
+type coinSource struct{}
+
+func (coinSource) describe() string {
+	return tr("Вводите броски монеты: H - орел, T - решка (допускаются О и Р), например HTTHTH")
+}
+
+func (coinSource) parse(line string) ([]symbol, error) {
+	var symbols []symbol
+	for _, r := range strings.ToUpper(line) {
+		switch r {
+		case 'H', 'О':
+			symbols = append(symbols, symbol{value: 1, radix: 2})
+		case 'T', 'Р':
+			symbols = append(symbols, symbol{value: 0, radix: 2})
+		case ' ', ',', '\t':
+		default:
+			return nil, errorf("символ %q не является броском монеты", r)
+		}
+	}
+	return symbols, nil
+}
+
+// cardRanks и cardSuits задают обозначения карт стандартной колоды
+const (
+	cardRanks = "A23456789TJQK"
+	cardSuits = "SHDC"
+	deckSize  = len(cardRanks) * len(cardSuits)
+)
+
+// cardSource принимает порядок карт перетасованной колоды из 52 карт.
+// Позиция каждой карты кодируется кодом Лемера: i-я карта дает значение
+// от 0 до 52-i среди еще не выложенных карт, поэтому полная колода несет
+// log2(52!) ≈ 225.6 бит. После 52 карт колоду нужно перетасовать заново.
+type cardSource struct {
+	used [deckSize]bool
+	seen int
+}
+
+func (c *cardSource) describe() string {
+	return tr("Вводите карты по порядку после тасовки: ранг A,2-9,T,J,Q,K и масть S,H,D,C, например \"AS TD 7H\"")
+}
+
+// parseCard переводит обозначение карты в номер от 0 до 51
+func parseCard(s string) (int, error) {
+	s = strings.ToUpper(s)
+	s = strings.Replace(s, "10", "T", 1)
+	if len(s) != 2 {
+		return 0, errorf("%q не является обозначением карты", s)
+	}
+	rank := strings.IndexByte(cardRanks, s[0])
+	suit := strings.IndexByte(cardSuits, s[1])
+	if rank < 0 || suit < 0 {
+		return 0, errorf("%q не является обозначением карты", s)
+	}
+	return suit*len(cardRanks) + rank, nil
+}
+
+func (c *cardSource) parse(line string) ([]symbol, error) {
+	// Разбираем строку на копии состояния, чтобы ошибка не оставила колоду наполовину учтенной
+	used, seen := c.used, c.seen
+
+	var symbols []symbol
+	for _, f := range strings.Fields(strings.Replace(line, ",", " ", -1)) {
+		card, err := parseCard(f)
+		if err != nil {
+			return nil, err
+		}
+		if used[card] {
+			return nil, errorf("карта %s уже выложена из этой колоды", strings.ToUpper(f))
+		}
+
+		// Значение - число еще не выложенных карт с меньшим номером
+		value := 0
+		for i := 0; i < card; i++ {
+			if !used[i] {
+				value++
+			}
+		}
+		symbols = append(symbols, symbol{value: value, radix: deckSize - seen})
+
+		used[card] = true
+		seen++
+		if seen == deckSize {
+			fmt.Fprintln(os.Stderr, tr("✓ Колода выложена полностью, перетасуйте ее и продолжайте ввод"))
+			used, seen = [deckSize]bool{}, 0
+		}
+	}
+
+	c.used, c.seen = used, seen
+	return symbols, nil
+}
+
+// collectEntropy собирает исходы, пока не наберется need байт без смещения.
+// Пустая строка завершает ввод, но только после достижения порога.
+func collectEntropy(src entropySource, need int, in io.Reader) ([]byte, error) {
+	ex := newExtractor()
+	scanner := bufio.NewScanner(in)
+
+	fmt.Fprintln(os.Stderr, src.describe())
+	fmt.Fprintln(os.Stderr, tr("Для завершения оставьте строку пустой."))
+	fmt.Fprintln(os.Stderr)
+
+	for {
+		fmt.Fprintf(os.Stderr, tr("[%d/%d бит] > "), len(ex.out)*8, need*8)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, errorf("ошибка чтения ввода: %w", err)
+			}
+			if len(ex.out) >= need {
+				break
+			}
+			return nil, errorf("ввод завершен раньше времени: собрано %d из %d бит", len(ex.out)*8, need*8)
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			if len(ex.out) >= need {
+				break
+			}
+			fmt.Fprintf(os.Stderr, tr("❌ Недостаточно энтропии: извлечено %d из %d бит, продолжайте ввод\n"), len(ex.out)*8, need*8)
+			continue
+		}
+
+		symbols, err := src.parse(line)
+		if err != nil {
+			// Строка отбрасывается целиком, чтобы не засчитать часть опечатки
+			fmt.Fprintf(os.Stderr, tr("❌ %v, строка не учтена\n"), err)
+			continue
+		}
+		for _, s := range symbols {
+			ex.add(s)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, tr("\n✓ Введено исходов на %.1f бит, извлечено без смещения %d бит\n\n"), ex.bits, len(ex.out)*8)
+	return ex.out[:need], nil
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Пороги оценки: 128 бит - общепринятый минимум стойкости, короче 16
+// символов сид почти наверняка придуман человеком
+const (
+	entropyTargetBits = 128
+	entropyShortSeed  = 16
+	// entropyWindow - длина повторяющегося фрагмента, который считается
+	// копией уже введенного, и насколько далеко назад он ищется
+	entropyWindow     = 4
+	entropyWindowBack = 256
+	// dicewareBits - энтропия слова, выбранного из словаря Diceware
+	dicewareBits = 12.9
+)
+
+// seedEntropy - оценка энтропии одного сида
+type seedEntropy struct {
+	Number      int      `json:"number"`
+	Kind        string   `json:"kind"`
+	Length      int      `json:"length"`
+	Bits        float64  `json:"bits"`
+	DuplicateOf int      `json:"duplicate_of,omitempty"`
+	Weak        []string `json:"weak,omitempty"`
+}
+
+// entropyReport - отчет --report об энтропии набора сидов. Мастер-сид
+// всегда 512 бит, но угадать его не труднее, чем угадать сиды: уровень
+// стойкости - сумма оценок различных сидов плюс работа KDF на каждую
+// попытку перебора, не больше 512 бит.
+type entropyReport struct {
+	Seeds           []seedEntropy `json:"seeds"`
+	DistinctSeeds   int           `json:"distinct_seeds"`
+	SeedBits        float64       `json:"seed_bits"`
+	KDFBits         float64       `json:"kdf_bits"`
+	SecurityBits    float64       `json:"security_bits"`
+	CollusionBits   float64       `json:"collusion_bits,omitempty"`
+	CollusionSeed   int           `json:"collusion_seed,omitempty"`
+	Recommendations []string      `json:"recommendations,omitempty"`
+
+	kdf     string
+	beacons int
+	files   int
+}
+
+// newEntropyReport оценивает сиды и параметры схемы. beacons - число
+// подмешанных публичных маяков, files - число сидов из --seed-file.
+// Содержимое сидов в отчет не попадает.
+func newEntropyReport(seeds [][]byte, p Params, beacons, files int) *entropyReport {
+	r := &entropyReport{kdf: describeKDFWork(p), KDFBits: roundBits(kdfWorkBits(p)), beacons: beacons, files: files}
+	human := false
+	for i, seed := range seeds {
+		e := estimateSeed(seed)
+		e.Number = i + 1
+		for j := 0; j < i; j++ {
+			if bytes.Equal(seed, seeds[j]) {
+				e.DuplicateOf = j + 1
+				break
+			}
+		}
+		if e.DuplicateOf == 0 {
+			r.DistinctSeeds++
+			r.SeedBits += e.Bits
+			if r.CollusionSeed == 0 || e.Bits < r.CollusionBits {
+				r.CollusionSeed, r.CollusionBits = e.Number, e.Bits
+			}
+			human = human || e.Kind == "phrase" || e.Kind == "text"
+		}
+		r.Seeds = append(r.Seeds, e)
+	}
+	r.SeedBits = roundBits(r.SeedBits)
+	r.SecurityBits = math.Min(r.SeedBits+r.KDFBits, float64(masterSeedSize*8))
+	if r.DistinctSeeds < 2 {
+		// Без других участников сговаривать некому
+		r.CollusionBits, r.CollusionSeed = 0, 0
+	} else {
+		r.CollusionBits = math.Min(r.CollusionBits+r.KDFBits, float64(masterSeedSize*8))
+	}
+
+	for _, e := range r.Seeds {
+		switch {
+		case e.DuplicateOf != 0:
+			r.recommend(fmt.Sprintf(tr("Сид #%d совпадает с #%d и не добавляет энтропии: возьмите сид с другого устройства"), e.Number, e.DuplicateOf))
+		case e.Bits < entropyTargetBits:
+			r.recommend(fmt.Sprintf(tr("Сид #%d дает около %.0f бит, и при сговоре остальных участников его подбирают перебором: замените его сидом из seedgen newseed (256 бит), кубика или колоды"), e.Number, e.Bits))
+		}
+	}
+	if r.DistinctSeeds == 1 {
+		r.recommend(tr("Мастер-сид зависит от одного сида: его владелец один знает результат, добавьте сиды других участников"))
+	}
+	if r.SecurityBits < entropyTargetBits {
+		r.recommend(tr("Уровень стойкости ниже 128 бит: мастер-сид подбирается перебором, не выпускайте его"))
+	}
+	if human && p.KDF == kdfPBKDF2 {
+		r.recommend(tr("Для придуманных людьми сидов выберите --scheme v2 --kdf argon2id: перебор на GPU обойдется дороже"))
+	}
+	return r
+}
+
+// recommend добавляет рекомендацию
+func (r *entropyReport) recommend(s string) {
+	r.Recommendations = append(r.Recommendations, s)
+}
+
+// estimateSeed оценивает энтропию сида сверху по его записи: алфавит
+// задает число бит на символ, а символы, продолжающие повтор или
+// последовательность, не дают ничего
+func estimateSeed(seed []byte) seedEntropy {
+	e := seedEntropy{Length: utf8.RuneCount(seed)}
+	symbols := seedSymbols(seed)
+	defer wipeInts(symbols)
+	predictable := predictableSymbols(symbols)
+	free := float64(len(symbols) - predictable)
+
+	switch {
+	case isHexSeed(seed):
+		e.Kind, e.Bits = "hex", free*4
+	case isMnemonicSeed(seed):
+		// 12 слов BIP39 несут 128 бит и 4 бита контрольной суммы
+		words := float64(len(symbols))
+		e.Kind, e.Bits = "bip39", math.Min(free*11, words*32/3)
+	case isBech32Seed(seed):
+		// Контрольная сумма - последние 6 символов данных
+		e.Kind, e.Bits = "bech32", math.Max(free-6, 0)*5
+	case isPhraseSeed(seed):
+		e.Kind, e.Bits = "phrase", free*dicewareBits
+	default:
+		e.Kind, e.Bits = "text", free*math.Log2(float64(seedAlphabetSize(seed)))
+	}
+	e.Bits = roundBits(e.Bits)
+
+	if e.Length < entropyShortSeed {
+		e.Weak = append(e.Weak, "short")
+	}
+	if predictable*4 > len(symbols) {
+		e.Weak = append(e.Weak, "pattern")
+	}
+	if e.Bits < entropyTargetBits {
+		e.Weak = append(e.Weak, "low")
+	}
+	return e
+}
+
+// seedSymbols разбивает сид на символы оценки: слова для мнемоники и
+// фразы, иначе символы Unicode
+func seedSymbols(seed []byte) []int {
+	if isMnemonicSeed(seed) {
+		var out []int
+		for _, w := range bytes.Fields(seed) {
+			out = append(out, bip39Index[string(w)])
+		}
+		return out
+	}
+	if isPhraseSeed(seed) {
+		var out []int
+		for _, w := range bytes.Fields(seed) {
+			h := 0
+			for _, b := range w {
+				h = h*31 + int(b)
+			}
+			out = append(out, h)
+		}
+		return out
+	}
+	out := make([]int, 0, len(seed))
+	for rest := seed; len(rest) > 0; {
+		r, size := utf8.DecodeRune(rest)
+		out = append(out, int(r))
+		rest = rest[size:]
+	}
+	return out
+}
+
+// predictableSymbols считает символы, которые продолжают серию с тем же
+// шагом -1, 0 или +1 ("aaaa", "1234") или повторяют уже встречавшийся
+// фрагмент из entropyWindow символов ("abcxabcx")
+func predictableSymbols(s []int) int {
+	n := 0
+	for i := range s {
+		if i >= 2 {
+			d := s[i] - s[i-1]
+			if d == s[i-1]-s[i-2] && d >= -1 && d <= 1 {
+				n++
+				continue
+			}
+		}
+		if i >= entropyWindow-1 && repeatsWindow(s, i) {
+			n++
+		}
+	}
+	return n
+}
+
+// repeatsWindow сообщает, что фрагмент, кончающийся символом i, уже
+// встречался раньше
+func repeatsWindow(s []int, i int) bool {
+	start := i - entropyWindow + 1
+	for j := i - 1; j >= entropyWindow-1 && j >= i-entropyWindowBack; j-- {
+		same := true
+		for k := 0; k < entropyWindow; k++ {
+			if s[j-entropyWindow+1+k] != s[start+k] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+	return false
+}
+
+// isHexSeed сообщает, что сид похож на hex-запись случайных байт
+func isHexSeed(seed []byte) bool {
+	if len(seed) < entropyShortSeed {
+		return false
+	}
+	for _, c := range seed {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// isMnemonicSeed сообщает, что сид - фраза BIP39: не меньше 12 слов,
+// кратно 3, все из словаря. Контрольная сумма не проверяется: ее
+// нарушение - повод для других команд, а не для оценки.
+func isMnemonicSeed(seed []byte) bool {
+	words := bytes.Fields(seed)
+	if len(words) < 12 || len(words)%3 != 0 {
+		return false
+	}
+	for _, w := range words {
+		if _, ok := bip39Index[string(w)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// isBech32Seed сообщает, что сид похож на запись Bech32: префикс, "1"
+// и данные из алфавита Bech32 с контрольной суммой
+func isBech32Seed(seed []byte) bool {
+	i := bytes.LastIndexByte(seed, '1')
+	if i < 1 || len(seed)-i-1 < entropyShortSeed {
+		return false
+	}
+	for _, c := range seed[i+1:] {
+		if strings.IndexByte(bech32Charset, c) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isPhraseSeed сообщает, что сид - фраза из нескольких слов из одних букв:
+// такую фразу подбирают по словарю, а не по буквам
+func isPhraseSeed(seed []byte) bool {
+	words := bytes.Fields(seed)
+	if len(words) < 2 {
+		return false
+	}
+	for _, w := range words {
+		for rest := w; len(rest) > 0; {
+			r, size := utf8.DecodeRune(rest)
+			if !unicode.IsLetter(r) {
+				return false
+			}
+			rest = rest[size:]
+		}
+	}
+	return true
+}
+
+// seedAlphabetSize возвращает размер алфавита, из которого, судя по
+// встреченным классам символов, набран сид
+func seedAlphabetSize(seed []byte) int {
+	const (
+		digits = 1 << iota
+		latinLower
+		latinUpper
+		cyrillicLower
+		cyrillicUpper
+		punct
+		other
+	)
+	sizes := map[int]int{digits: 10, latinLower: 26, latinUpper: 26, cyrillicLower: 33, cyrillicUpper: 33, punct: 33, other: 100}
+	classes := 0
+	for rest := seed; len(rest) > 0; {
+		r, size := utf8.DecodeRune(rest)
+		rest = rest[size:]
+		switch {
+		case '0' <= r && r <= '9':
+			classes |= digits
+		case 'a' <= r && r <= 'z':
+			classes |= latinLower
+		case 'A' <= r && r <= 'Z':
+			classes |= latinUpper
+		case unicode.Is(unicode.Cyrillic, r) && unicode.IsLower(r):
+			classes |= cyrillicLower
+		case unicode.Is(unicode.Cyrillic, r):
+			classes |= cyrillicUpper
+		case r < utf8.RuneSelf:
+			classes |= punct
+		default:
+			classes |= other
+		}
+	}
+	total := 0
+	for class, size := range sizes {
+		if classes&class != 0 {
+			total += size
+		}
+	}
+	if total < 2 {
+		return 2
+	}
+	return total
+}
+
+// kdfWorkBits оценивает, на сколько бит KDF удорожает каждую попытку
+// перебора: log2 итераций PBKDF2 или обращений Argon2id к блокам по 1 КиБ
+func kdfWorkBits(p Params) float64 {
+	work := float64(p.Iterations)
+	if p.KDF == kdfArgon2id {
+		work *= float64(p.Memory)
+	}
+	if work < 1 {
+		return 0
+	}
+	return math.Log2(work)
+}
+
+// describeKDFWork описывает KDF для отчета
+func describeKDFWork(p Params) string {
+	if p.KDF == kdfArgon2id {
+		return fmt.Sprintf(tr("%s, проходов: %d, память: %d КиБ"), p.KDF, p.Iterations, p.Memory)
+	}
+	return fmt.Sprintf(tr("%s, %d итераций"), p.KDF, p.Iterations)
+}
+
+// roundBits округляет оценку до десятых бита
+func roundBits(bits float64) float64 {
+	return math.Round(bits*10) / 10
+}
+
+// wipeInts затирает символы сида после оценки
+func wipeInts(s []int) {
+	for i := range s {
+		s[i] = 0
+	}
+}
+
+// kindName возвращает название вида записи сида для оператора
+func (e seedEntropy) kindName() string {
+	switch e.Kind {
+	case "bip39":
+		return "BIP39"
+	case "phrase":
+		return tr("фраза")
+	case "text":
+		return tr("текст")
+	}
+	return e.Kind
+}
+
+// weakNames возвращает пометки слабого сида для оператора
+func (e seedEntropy) weakNames() string {
+	names := make([]string, 0, len(e.Weak))
+	for _, weak := range e.Weak {
+		switch weak {
+		case "short":
+			names = append(names, tr("короткий"))
+		case "pattern":
+			names = append(names, tr("повторы и последовательности"))
+		case "low":
+			names = append(names, tr("мало энтропии"))
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// write выводит отчет оператору
+func (r *entropyReport) write(w io.Writer) {
+	fmt.Fprintln(w, tr("=== Оценка энтропии сидов ==="))
+	for _, e := range r.Seeds {
+		if e.DuplicateOf != 0 {
+			fmt.Fprintf(w, tr("  #%-3d совпадает с #%d: 0 бит\n"), e.Number, e.DuplicateOf)
+			continue
+		}
+		fmt.Fprintf(w, tr("  #%-3d %-7s символов: %-4d около %.0f бит"), e.Number, e.kindName(), e.Length, e.Bits)
+		if len(e.Weak) > 0 {
+			fmt.Fprintf(w, "  ⚠ %s", e.weakNames())
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, tr("Различных сидов: %d из %d, сумма оценок: около %.0f бит\n"), r.DistinctSeeds, len(r.Seeds), r.SeedBits)
+	fmt.Fprintf(w, tr("KDF %s: около +%.0f бит к каждой попытке перебора\n"), r.kdf, r.KDFBits)
+	fmt.Fprintf(w, tr("Уровень стойкости: около %.0f бит из %d бит мастер-сида\n"), r.SecurityBits, masterSeedSize*8)
+	if r.CollusionSeed != 0 {
+		fmt.Fprintf(w, tr("При сговоре всех участников, кроме одного: около %.0f бит (сид #%d)\n"), r.CollusionBits, r.CollusionSeed)
+	}
+	if r.beacons > 0 {
+		fmt.Fprintln(w, tr("Публичные маяки известны всем и в оценку не входят"))
+	}
+	fmt.Fprintln(w)
+	if len(r.Recommendations) == 0 {
+		fmt.Fprintln(w, tr("✓ Замечаний нет"))
+	} else {
+		fmt.Fprintln(w, tr("Рекомендации:"))
+		for _, s := range r.Recommendations {
+			fmt.Fprintf(w, "  - %s\n", s)
+		}
+	}
+	fmt.Fprintln(w, tr("Оценки - верхние границы по записи сида: по ней не узнать, как сид получен."))
+	if r.files > 0 {
+		fmt.Fprintln(w, tr("Сид из --seed-file - хэш файла: его энтропия не больше энтропии содержимого."))
+	}
+	fmt.Fprintln(w)
+}
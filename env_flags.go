@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envPrefix - префикс переменных окружения, которые повторяют флаги
+const envPrefix = "SEEDGEN_"
+
+// commandLineOnlyFlags - флаги, которые снимают защиту. Они действуют только
+// из командной строки, чтобы отказ от защиты был виден в самой команде,
+// а не приходил незаметно из окружения контейнера.
+var commandLineOnlyFlags = map[string]bool{
+	"i-know-what-im-doing": true,
+	allowCoreDumpsFlag:     true,
+}
+
+// envName возвращает имя переменной окружения для флага: SEEDGEN_ и слова
+// имени в верхнем регистре через _, например SEEDGEN_AUDIT_RUN_OUT
+func envName(words ...string) string {
+	name := strings.Join(words, "_")
+	name = strings.NewReplacer("-", "_", " ", "_").Replace(name)
+	return envPrefix + strings.ToUpper(name)
+}
+
+// parseArgs разбирает флаги команды и подставляет в незаданные флаги
+// значения из переменных окружения
+func parseArgs(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return applyEnv(fs)
+}
+
+// applyEnv задает флаги, не указанные в командной строке, из переменных
+// SEEDGEN_<КОМАНДА>_<ФЛАГ> или, если ее нет, SEEDGEN_<ФЛАГ>. Значения
+// окружения считаются явно заданными, поэтому профиль конфигурации их
+// не перекрывает: командная строка важнее окружения, окружение - профиля.
+func applyEnv(fs *flag.FlagSet) error {
+	runFlags = fs
+	explicit := setFlags(fs)
+	cmdName := strings.TrimPrefix(fs.Name(), "seedgen ")
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] || commandLineOnlyFlags[f.Name] {
+			return
+		}
+		for _, name := range []string{envName(cmdName, f.Name), envName(f.Name)} {
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				continue
+			}
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = withCode(exitInput, errorf("переменная %s: %w", name, setErr))
+				return
+			}
+			logDebug("флаг из окружения", "flag", f.Name, "env", name)
+			return
+		}
+	})
+	return err
+}
+
+// globalEnv возвращает значение общего флага со значением из SEEDGEN_<ФЛАГ>,
+// если флаг не указан в командной строке
+func globalEnv(name, value string, set bool) (string, bool) {
+	if set {
+		return value, true
+	}
+	return os.LookupEnv(envName(name))
+}
+
+// globalBoolEnv читает булев общий флаг из SEEDGEN_<ФЛАГ>
+func globalBoolEnv(name string) (bool, error) {
+	value, ok := os.LookupEnv(envName(name))
+	if !ok {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errorf("переменная %s: ожидается true или false, указано %q", envName(name), value)
+	}
+	return b, nil
+}
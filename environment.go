@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// envFinding - обнаруженная особенность окружения, опасная для церемонии
+type envFinding struct {
+	message string
+	// refuse означает, что без явного подтверждения работа прекращается
+	refuse bool
+}
+
+// screenCaptureTools - программы записи экрана и удаленного доступа
+// (имена процессов в нижнем регистре, без .exe)
+var screenCaptureTools = []string{
+	"obs", "obs64", "simplescreenrecorder", "kazam", "vokoscreen", "vokoscreenng",
+	"peek", "recordmydesktop", "gpu-screen-recorder", "wf-recorder", "wl-screenrec",
+	"x11vnc", "xvnc", "vncserver", "wayvnc", "xrdp", "teamviewer", "teamviewerd",
+	"anydesk", "rustdesk", "screensharingd", "screenflow", "camtasia", "sharex",
+}
+
+// envFlags - общие флаги проверки окружения
+type envFlags struct {
+	override       *bool
+	strict         *bool
+	requireOffline *bool
+}
+
+// addEnvFlags регистрирует флаги проверки окружения в наборе
+func addEnvFlags(fs *flag.FlagSet) *envFlags {
+	return &envFlags{
+		override:       fs.Bool("i-know-what-im-doing", false, "продолжить в опасном окружении (SSH, удаленный рабочий стол, запись экрана, отладчик)"),
+		strict:         fs.Bool("strict", false, "прекратить работу при любом замечании к окружению"),
+		requireOffline: fs.Bool("require-offline", false, "отказаться от работы, если машина подключена к сети"),
+	}
+}
+
+// check выводит предупреждения об окружении до ввода сидов и возвращает
+// ошибку, если найдено опасное окружение, а флаг подтверждения не указан,
+// или, в режиме --strict, при любом замечании.
+// machineOutput означает, что вывод не в терминал запрошен явно (--format).
+func (ef *envFlags) check(machineOutput bool) error {
+	findings := detectEnvironment(machineOutput, *ef.requireOffline)
+	switch manifest, err := verifySelf(); {
+	case err != nil:
+		// Измененному бинарнику сиды не доверяют даже с --i-know-what-im-doing
+		return errorf("проверка целостности: %w", err)
+	case manifest == nil:
+		findings = append(findings, envFinding{message: tr("бинарник не запечатан манифестом целостности (seedgen integrity seal при сборке)")})
+	default:
+		logInfof("✓ Целостность бинарника подтверждена, SHA-256: %s\n\n", shortHash(manifest.SHA256))
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	refuse := false
+	logWarnf("⚠ ===== ПРЕДУПРЕЖДЕНИЕ: НЕБЕЗОПАСНОЕ ОКРУЖЕНИЕ =====\n")
+	for _, f := range findings {
+		logWarnf("⚠ %s\n", f.message)
+		refuse = refuse || f.refuse
+	}
+	if *ef.strict {
+		return withCode(exitEnvironment, errorf("режим --strict: замечаний к окружению - %d, устраните их до церемонии", len(findings)))
+	}
+	if refuse && !*ef.override {
+		return withCode(exitEnvironment, errorf("церемония в таком окружении может раскрыть сиды, проведите ее локально на отключенной от сети машине или укажите --i-know-what-im-doing"))
+	}
+	if refuse {
+		logWarnf("⚠ Работа продолжается по флагу --i-know-what-im-doing\n")
+	}
+	logWarnf("\n")
+	return nil
+}
+
+// detectEnvironment собирает особенности окружения, опасные для церемонии.
+// Подключение к сети мешает работе только с requireOffline.
+func detectEnvironment(machineOutput, requireOffline bool) []envFinding {
+	var findings []envFinding
+	if !machineOutput && !isTerminal(os.Stdout) {
+		findings = append(findings, envFinding{message: tr("stdout не подключен к терминалу: мастер-сид попадет в файл, канал или журнал")})
+	}
+	if ssh := sshSession(); ssh != "" {
+		findings = append(findings, envFinding{message: ssh, refuse: true})
+	}
+	if name := containerRuntime(); name != "" {
+		findings = append(findings, envFinding{message: fmt.Sprintf(tr("запуск внутри контейнера (%s): память и ввод доступны хост-системе"), name)})
+	}
+	if os.Getenv("XRDP_SESSION") != "" || strings.HasPrefix(strings.ToUpper(os.Getenv("SESSIONNAME")), "RDP-") {
+		findings = append(findings, envFinding{message: tr("сеанс удаленного рабочего стола (RDP): экран передается по сети"), refuse: true})
+	}
+	if tools := runningScreenCaptureTools(); len(tools) > 0 {
+		findings = append(findings, envFinding{
+			message: tr("запущены программы записи экрана или удаленного доступа: ") + strings.Join(tools, ", "),
+			refuse:  true,
+		})
+	}
+	if signs := networkExposure(); len(signs) > 0 {
+		findings = append(findings, envFinding{
+			message: tr("машина не изолирована от сети: ") + strings.Join(signs, "; "),
+			refuse:  requireOffline,
+		})
+	}
+	if debuggerAttached() {
+		findings = append(findings, envFinding{message: tr("к процессу подключен отладчик: он может читать сиды из памяти"), refuse: true})
+	}
+	if swap := swapDevices(); swap != "" {
+		findings = append(findings, envFinding{message: fmt.Sprintf(tr("включен swap (%s): незакрепленные данные из памяти могут попасть на диск"), swap)})
+	}
+	for _, name := range []string{"LD_PRELOAD", "DYLD_INSERT_LIBRARIES"} {
+		if value := os.Getenv(name); value != "" {
+			findings = append(findings, envFinding{message: fmt.Sprintf(tr("задана переменная %s=%s: в запускаемые программы внедряются сторонние библиотеки"), name, value)})
+		}
+	}
+	if path := worldWritableExecutable(); path != "" {
+		findings = append(findings, envFinding{message: fmt.Sprintf(tr("%s доступен на запись всем пользователям: бинарник могли подменить"), path)})
+	}
+	return findings
+}
+
+// swapDevices описывает включенный swap на диске или возвращает пустую строку.
+// Сжатый swap в памяти (zram) не учитывается.
+func swapDevices() string {
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile("/proc/swaps")
+		if err != nil {
+			return ""
+		}
+		var devices []string
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		for _, line := range lines[1:] {
+			if fields := strings.Fields(line); len(fields) > 0 && !strings.HasPrefix(fields[0], "/dev/zram") {
+				devices = append(devices, fields[0])
+			}
+		}
+		return strings.Join(devices, ", ")
+	case "darwin":
+		out, err := exec.Command("sysctl", "-n", "vm.swapusage").Output()
+		if err != nil || strings.HasPrefix(string(out), "total = 0.00M") {
+			return ""
+		}
+		return "vm.swapusage: " + strings.Join(strings.Fields(string(out)), " ")
+	}
+	return ""
+}
+
+// worldWritableExecutable возвращает бинарник или его каталог, если любой
+// пользователь может их изменить. В Windows права устроены иначе и не проверяются.
+func worldWritableExecutable() string {
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	for _, path := range []string{exe, filepath.Dir(exe)} {
+		if info, err := os.Stat(path); err == nil && writableByOthers(info.Mode()) {
+			return path
+		}
+	}
+	return ""
+}
+
+// writableByOthers сообщает, что любой пользователь может изменить файл или
+// каталог с правами mode. В каталоге с битом sticky, как /tmp, чужой файл
+// нельзя удалить или подменить, поэтому такой каталог не считается опасным.
+func writableByOthers(mode os.FileMode) bool {
+	if mode.IsDir() && mode&os.ModeSticky != 0 {
+		return false
+	}
+	return mode.Perm()&0002 != 0
+}
+
+// sshSession описывает сеанс SSH с пробросами или возвращает пустую строку
+func sshSession() string {
+	if os.Getenv("SSH_CONNECTION") == "" && os.Getenv("SSH_CLIENT") == "" && os.Getenv("SSH_TTY") == "" {
+		return ""
+	}
+	var forwards []string
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		forwards = append(forwards, tr("агента"))
+	}
+	if os.Getenv("DISPLAY") != "" {
+		forwards = append(forwards, "X11")
+	}
+	msg := tr("запуск через SSH: ввод и вывод проходят через другую машину")
+	if len(forwards) > 0 {
+		msg += tr(", включен проброс ") + strings.Join(forwards, tr(" и "))
+	}
+	return msg
+}
+
+// containerRuntime возвращает название среды контейнера или пустую строку
+func containerRuntime() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return "docker"
+	}
+	if _, err := os.Stat("/run/.containerenv"); err == nil {
+		return "podman"
+	}
+	if name := os.Getenv("container"); name != "" {
+		return name
+	}
+	if data, err := os.ReadFile("/proc/1/cgroup"); err == nil {
+		for _, name := range []string{"docker", "kubepods", "containerd", "lxc"} {
+			if bytes.Contains(data, []byte(name)) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// runningScreenCaptureTools возвращает запущенные программы из screenCaptureTools
+func runningScreenCaptureTools() []string {
+	found := make(map[string]bool)
+	for _, proc := range processNames() {
+		for _, tool := range screenCaptureTools {
+			// В Linux имя процесса обрезано до 15 символов
+			if proc == tool || (len(proc) == 15 && strings.HasPrefix(tool, proc)) {
+				found[tool] = true
+			}
+		}
+	}
+	tools := make([]string, 0, len(found))
+	for tool := range found {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	return tools
+}
+
+// processNames возвращает имена запущенных процессов в нижнем регистре
+// без расширения .exe. При ошибке список пуст: проверка лишь подсказывает.
+func processNames() []string {
+	var names []string
+	switch runtime.GOOS {
+	case "linux":
+		paths, _ := filepath.Glob("/proc/[0-9]*/comm")
+		for _, p := range paths {
+			if data, err := os.ReadFile(p); err == nil {
+				names = append(names, strings.TrimSpace(string(data)))
+			}
+		}
+	case "darwin":
+		out, _ := exec.Command("ps", "-axo", "comm=").Output()
+		for _, line := range strings.Split(string(out), "\n") {
+			names = append(names, filepath.Base(strings.TrimSpace(line)))
+		}
+	case "windows":
+		out, _ := exec.Command("tasklist", "/fo", "csv", "/nh").Output()
+		for _, line := range strings.Split(string(out), "\n") {
+			if fields := strings.Split(line, ","); len(fields) > 0 {
+				names = append(names, strings.Trim(strings.TrimSpace(fields[0]), `"`))
+			}
+		}
+	}
+	for i, name := range names {
+		names[i] = strings.TrimSuffix(strings.ToLower(name), ".exe")
+	}
+	return names
+}
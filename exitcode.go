@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// exitCode - код завершения seedgen. Коды стабильны между версиями:
+// скрипты различают по ним причины отказа, не разбирая текст сообщений,
+// который зависит от языка.
+type exitCode int
+
+const (
+	exitOK          exitCode = 0
+	exitError       exitCode = 1 // прочие ошибки
+	exitInput       exitCode = 2 // неверные флаги, аргументы или входные данные
+	exitMismatch    exitCode = 3 // проверка не пройдена: подпись, обязательство, отпечаток, результат
+	exitCrypto      exitCode = 4 // сбой криптографии: ГСЧ, генерация ключа, самопроверка
+	exitIO          exitCode = 5 // ошибка чтения или записи файла
+	exitEnvironment exitCode = 6 // отказ работать в небезопасном окружении
+	exitNetwork     exitCode = 7 // ошибка сети: служба меток времени, церемония по сети
+	exitCancelled   exitCode = 8 // действие отменено пользователем
+)
+
+// exitCodeNames - имена кодов для поля code журнала JSON
+var exitCodeNames = map[exitCode]string{
+	exitOK:          "ok",
+	exitError:       "error",
+	exitInput:       "invalid-input",
+	exitMismatch:    "verification-failed",
+	exitCrypto:      "crypto-failure",
+	exitIO:          "io-error",
+	exitEnvironment: "unsafe-environment",
+	exitNetwork:     "network-error",
+	exitCancelled:   "cancelled",
+}
+
+// codedError - ошибка с явно указанным кодом завершения
+type codedError struct {
+	code exitCode
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// withCode задает код завершения для ошибки; nil остается nil
+func withCode(code exitCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: code, err: err}
+}
+
+// mismatchf создает ошибку проверки: данные не сходятся с подписью,
+// обязательством, отпечатком или опубликованным результатом
+func mismatchf(format string, args ...interface{}) error {
+	return withCode(exitMismatch, errorf(format, args...))
+}
+
+// flagUsageShown отмечает, что пакет flag вывел справку по флагам: так
+// ошибка разбора флагов отличается от остальных ошибок команды
+var flagUsageShown bool
+
+// classifyError выбирает код завершения для ошибки команды. Явный код
+// важнее всего, затем причина по цепочке %w: файлы, сеть, разбор данных.
+// Ошибка, которую seedgen сформулировал сам без внешней причины, - это
+// отказ принять входные данные.
+func classifyError(err error) exitCode {
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	if flagUsageShown {
+		return exitInput
+	}
+
+	// PathError тоже реализует net.Error, поэтому файлы проверяются раньше сети
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return exitIO
+	}
+	var netErr net.Error
+	var urlErr *url.Error
+	if errors.As(err, &netErr) || errors.As(err, &urlErr) {
+		return exitNetwork
+	}
+
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		numErr    *strconv.NumError
+		timeErr   *time.ParseError
+	)
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.As(err, &numErr) || errors.As(err, &timeErr) {
+		return exitInput
+	}
+	// В конце цепочки стоит первоначальная причина
+	for next := errors.Unwrap(err); next != nil; next = errors.Unwrap(err) {
+		err = next
+	}
+	if _, ok := err.(*localizedError); ok {
+		return exitInput
+	}
+	return exitError
+}
@@ -0,0 +1,493 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// frostCiphersuite - набор FROST, подписи которого проверяются как обычные
+// подписи Ed25519 (RFC 9591)
+const frostCiphersuite = "FROST-ED25519-SHA512-v1"
+
+// Домены DKG FROST
+const (
+	frostContextDomain     = "seedgen/frost/context"
+	frostCoefficientDomain = "seedgen/frost/coefficient"
+	frostNonceDomain       = "seedgen/frost/pok-nonce"
+	frostChallengeDomain   = "seedgen/frost/pok"
+	frostShareDomain       = "seedgen/frost/share"
+)
+
+// frostPackage - публичная часть первого раунда DKG: обязательства по
+// коэффициентам многочлена участника и доказательство Шнорра, что участник
+// знает свободный член (без него участник мог бы подобрать свое обязательство
+// под чужие и сократить их)
+type frostPackage struct {
+	Commitments []string `json:"commitments"`
+	ProofR      string   `json:"proof_r"`
+	ProofS      string   `json:"proof_s"`
+}
+
+// frostContext связывает DKG с конкретной церемонией: отпечатком мастер-сида
+// и одноразовыми ключами маскирования раунда
+func frostContext(fingerprint string, maskKeys []string) []byte {
+	h := sha256.New()
+	h.Write([]byte(frostContextDomain))
+	h.Write([]byte(fingerprint))
+	for _, k := range maskKeys {
+		h.Write([]byte(k))
+	}
+	return h.Sum(nil)
+}
+
+// frostDerive выводит скаляр из ключа участника для домена и номеров
+func frostDerive(key []byte, domain string, context []byte, index, k int) *big.Int {
+	mac := hmac.New(sha512.New, key)
+	mac.Write([]byte(domain))
+	mac.Write(context)
+	var n [8]byte
+	binary.BigEndian.PutUint32(n[:4], uint32(index))
+	binary.BigEndian.PutUint32(n[4:], uint32(k))
+	mac.Write(n[:])
+	sum := mac.Sum(nil)
+	defer wipe(sum)
+	return edScalarReduce(sum)
+}
+
+// wipeBig затирает машинные слова числа
+func wipeBig(k *big.Int) {
+	words := k.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+}
+
+// frostPolynomial - секретный многочлен участника степени t-1
+type frostPolynomial struct {
+	coefficients []*big.Int
+	nonce        *big.Int
+}
+
+// newFrostPolynomial выводит коэффициенты из растянутого вклада участника
+// в церемонию и свежей случайности его машины: вклад привязывает ключ
+// к церемонии, а случайность защищает его, даже если вклад стал известен
+// координатору, подменившему ключи маскирования
+func newFrostPolynomial(contribution, context []byte, index, threshold int) (*frostPolynomial, error) {
+	key := newSecret(len(contribution) + 32)
+	defer wipe(key)
+	copy(key, contribution)
+	if _, err := rand.Read(key[len(contribution):]); err != nil {
+		return nil, withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	f := &frostPolynomial{nonce: frostDerive(key, frostNonceDomain, context, index, 0)}
+	for k := 0; k < threshold; k++ {
+		f.coefficients = append(f.coefficients, frostDerive(key, frostCoefficientDomain, context, index, k))
+	}
+	return f, nil
+}
+
+// evaluate вычисляет f(x) по модулю L
+func (f *frostPolynomial) evaluate(x int) *big.Int {
+	r := new(big.Int)
+	for k := len(f.coefficients) - 1; k >= 0; k-- {
+		r.Mul(r, big.NewInt(int64(x))).Add(r, f.coefficients[k]).Mod(r, edL)
+	}
+	return r
+}
+
+// publicPackage возвращает обязательства и доказательство знания f(0)
+func (f *frostPolynomial) publicPackage(index int, context []byte) frostPackage {
+	var pkg frostPackage
+	for _, a := range f.coefficients {
+		pkg.Commitments = append(pkg.Commitments, hex.EncodeToString(edScalarBaseMult(a).encode()))
+	}
+	R := edScalarBaseMult(f.nonce).encode()
+	c := frostChallenge(index, context, mustHex(pkg.Commitments[0]), R)
+	s := new(big.Int).Mul(f.coefficients[0], c)
+	s.Add(s, f.nonce).Mod(s, edL)
+	pkg.ProofR = hex.EncodeToString(R)
+	pkg.ProofS = hex.EncodeToString(littleEndian(s, 32))
+	return pkg
+}
+
+// wipe затирает коэффициенты многочлена
+func (f *frostPolynomial) wipe() {
+	for _, a := range f.coefficients {
+		wipeBig(a)
+	}
+	wipeBig(f.nonce)
+}
+
+// frostChallenge вычисляет вызов доказательства знания
+func frostChallenge(index int, context, commitment, R []byte) *big.Int {
+	h := sha512.New()
+	h.Write([]byte(frostChallengeDomain))
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(index))
+	h.Write(n[:])
+	h.Write(context)
+	h.Write(commitment)
+	h.Write(R)
+	return edScalarReduce(h.Sum(nil))
+}
+
+// mustHex декодирует hex, записанный этой же программой
+func mustHex(s string) []byte {
+	b, _ := hex.DecodeString(s)
+	return b
+}
+
+// decode проверяет пакет участника index и возвращает точки обязательств
+func (pkg frostPackage) decode(index, threshold int, context []byte) ([]edPoint, error) {
+	if len(pkg.Commitments) != threshold {
+		return nil, errorf("обязательств FROST: %d, ожидалось по порогу: %d", len(pkg.Commitments), threshold)
+	}
+	points := make([]edPoint, threshold)
+	for k, c := range pkg.Commitments {
+		raw, err := hex.DecodeString(c)
+		if err != nil {
+			return nil, errorf("обязательство FROST %d: некорректный hex", k)
+		}
+		if points[k], err = edDecode(raw); err != nil {
+			return nil, errorf("обязательство FROST %d: %w", k, err)
+		}
+	}
+	rawR, err := hex.DecodeString(pkg.ProofR)
+	if err != nil {
+		return nil, errorf("некорректное доказательство FROST")
+	}
+	R, err := edDecode(rawR)
+	if err != nil {
+		return nil, errorf("доказательство FROST: %w", err)
+	}
+	rawS, err := hex.DecodeString(pkg.ProofS)
+	if err != nil {
+		return nil, errorf("некорректное доказательство FROST")
+	}
+	s, err := edScalarDecode(rawS)
+	if err != nil {
+		return nil, errorf("доказательство FROST: %w", err)
+	}
+	// s·B = R + c·C0
+	c := frostChallenge(index, context, mustHex(pkg.Commitments[0]), rawR)
+	if !edScalarBaseMult(s).equal(edAdd(R, edScalarMult(c, points[0]))) {
+		return nil, mismatchf("доказательство знания свободного члена FROST недействительно")
+	}
+	return points, nil
+}
+
+// frostCommitmentAt вычисляет Σ xᵏ·Cₖ - публичный образ f(x)·B
+func frostCommitmentAt(commitments []edPoint, x int) edPoint {
+	r := edIdentity()
+	for k := len(commitments) - 1; k >= 0; k-- {
+		r = edAdd(edScalarMult(big.NewInt(int64(x)), r), commitments[k])
+	}
+	return r
+}
+
+// frostGroup - публичный результат DKG: общий ключ и проверочные ключи
+// долей всех участников
+type frostGroup struct {
+	publicKey    edPoint
+	verification []edPoint
+}
+
+// newFrostGroup складывает обязательства участников: общий ключ - сумма
+// свободных членов, проверочный ключ участника j - сумма образов f_i(j)
+func newFrostGroup(commitments [][]edPoint) *frostGroup {
+	sum := make([]edPoint, len(commitments[0]))
+	for k := range sum {
+		sum[k] = edIdentity()
+		for _, c := range commitments {
+			sum[k] = edAdd(sum[k], c[k])
+		}
+	}
+	g := &frostGroup{publicKey: sum[0]}
+	for j := 1; j <= len(commitments); j++ {
+		g.verification = append(g.verification, frostCommitmentAt(sum, j))
+	}
+	return g
+}
+
+// frostShareCipher возвращает AEAD для доли, которую участник from
+// отправляет участнику to. Ключ выводится из общего секрета X25519 пары
+// и используется один раз, поэтому нулевой нонс допустим.
+func frostShareCipher(maskKey *maskKeyPair, peer string, context []byte, from, to int) (cipher.AEAD, error) {
+	shared, err := maskKey.shared(peer)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(shared)
+	mac := hmac.New(sha512.New, shared)
+	mac.Write([]byte(frostShareDomain))
+	mac.Write(context)
+	var n [8]byte
+	binary.BigEndian.PutUint32(n[:4], uint32(from))
+	binary.BigEndian.PutUint32(n[4:], uint32(to))
+	mac.Write(n[:])
+	key := mac.Sum(nil)
+	defer wipe(key)
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// frostSeal шифрует долю для участника to
+func frostSeal(maskKey *maskKeyPair, peer string, context []byte, from, to int, share *big.Int) (string, error) {
+	aead, err := frostShareCipher(maskKey, peer, context, from, to)
+	if err != nil {
+		return "", err
+	}
+	plain := littleEndian(share, 32)
+	defer wipe(plain)
+	return hex.EncodeToString(aead.Seal(nil, make([]byte, aead.NonceSize()), plain, context)), nil
+}
+
+// frostOpen расшифровывает долю от участника from
+func frostOpen(maskKey *maskKeyPair, peer string, context []byte, from, to int, sealed string) (*big.Int, error) {
+	aead, err := frostShareCipher(maskKey, peer, context, from, to)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := hex.DecodeString(sealed)
+	if err != nil {
+		return nil, errorf("некорректная зашифрованная доля")
+	}
+	plain, err := aead.Open(nil, make([]byte, aead.NonceSize()), raw, context)
+	if err != nil {
+		return nil, mismatchf("доля не расшифровывается: повреждена или подменена")
+	}
+	defer wipe(plain)
+	return edScalarDecode(plain)
+}
+
+// frostShare - файл доли ключа подписи участника. Доли с идентификаторами
+// 1..n совместимы с подписью по RFC 9591: любые threshold держателей вместе
+// подписывают общим ключом, и подпись проверяется как обычная Ed25519.
+type frostShare struct {
+	Kind               string    `json:"kind"`
+	Ciphersuite        string    `json:"ciphersuite"`
+	Identifier         int       `json:"identifier"`
+	Threshold          int       `json:"threshold"`
+	Participants       []string  `json:"participants"`
+	GroupPublicKey     string    `json:"group_public_key"`
+	VerificationShares []string  `json:"verification_shares"`
+	SigningShare       secretHex `json:"signing_share"`
+	Fingerprint        string    `json:"fingerprint"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// runFrostCoordinator проводит DKG FROST между участниками церемонии.
+// Координатор только пересылает пакеты и зашифрованные доли, проверяя
+// доказательства участников, и сам доли не получает.
+func runFrostCoordinator(participants []*ceremonyParticipant, threshold int, context []byte) (*frostGroup, error) {
+	n := len(participants)
+	packages := make([]frostPackage, n)
+	commitments := make([][]edPoint, n)
+	for i, p := range participants {
+		m, err := p.receive("frost-commit")
+		if err == nil && len(m.Frost) != 1 {
+			err = errorf("%s прислал пакетов FROST: %d вместо одного", p.name, len(m.Frost))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if commitments[i], err = m.Frost[0].decode(i+1, threshold, context); err != nil {
+			return nil, errorf("%s: %w", p.name, err)
+		}
+		packages[i] = m.Frost[0]
+	}
+	for _, p := range participants {
+		if err := p.send(ceremonyMessage{Type: "frost-commitments", Frost: packages}); err != nil {
+			return nil, err
+		}
+	}
+
+	sealed := make([][]string, n)
+	for i, p := range participants {
+		m, err := p.receive("frost-shares")
+		if err == nil && len(m.FrostShares) != n {
+			err = errorf("%s прислал долей FROST: %d вместо %d", p.name, len(m.FrostShares), n)
+		}
+		if err != nil {
+			return nil, err
+		}
+		sealed[i] = m.FrostShares
+	}
+	for j, p := range participants {
+		msg := ceremonyMessage{Type: "frost-shares", FrostShares: make([]string, n)}
+		for i := range participants {
+			if i != j {
+				msg.FrostShares[i] = sealed[i][j]
+			}
+		}
+		if err := p.send(msg); err != nil {
+			return nil, err
+		}
+	}
+
+	group := newFrostGroup(commitments)
+	groupKey := hex.EncodeToString(group.publicKey.encode())
+	for _, p := range participants {
+		m, err := p.receive("frost-done")
+		if err != nil {
+			return nil, err
+		}
+		if m.GroupKey != groupKey {
+			return nil, errorf("%s получил другой общий ключ FROST", p.name)
+		}
+	}
+	for _, p := range participants {
+		if err := p.send(ceremonyMessage{Type: "frost-result", GroupKey: groupKey}); err != nil {
+			return nil, err
+		}
+	}
+	return group, nil
+}
+
+// runFrostParticipant участвует в DKG FROST: рассылает через координатора
+// доли своего многочлена, проверяет доли остальных по их обязательствам
+// и возвращает свою долю ключа подписи
+func runFrostParticipant(conn *ceremonyConn, reveal ceremonyMessage, fingerprint string, contribution []byte, maskKey *maskKeyPair) (*frostShare, error) {
+	n, threshold := len(reveal.MaskKeys), reveal.FrostThreshold
+	if threshold < 2 || threshold > n {
+		return nil, errorf("координатор прислал недопустимый порог FROST %d из %d", threshold, n)
+	}
+	index := 0
+	for i, k := range reveal.MaskKeys {
+		if k == maskKey.public {
+			index = i + 1
+		}
+	}
+	if index == 0 {
+		return nil, errorf("координатор не включил наш ключ маскирования в раунд")
+	}
+	context := frostContext(fingerprint, reveal.MaskKeys)
+
+	poly, err := newFrostPolynomial(contribution, context, index, threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer poly.wipe()
+	own := poly.publicPackage(index, context)
+	if err := conn.send(ceremonyMessage{Type: "frost-commit", Frost: []frostPackage{own}}); err != nil {
+		return nil, err
+	}
+
+	m, err := conn.receive("frost-commitments")
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Frost) != n {
+		return nil, errorf("координатор прислал пакетов FROST: %d вместо %d", len(m.Frost), n)
+	}
+	ownJSON, _ := json.Marshal(own)
+	gotJSON, _ := json.Marshal(m.Frost[index-1])
+	if string(ownJSON) != string(gotJSON) {
+		return nil, errorf("координатор подменил наш пакет FROST")
+	}
+	commitments := make([][]edPoint, n)
+	for i, pkg := range m.Frost {
+		if commitments[i], err = pkg.decode(i+1, threshold, context); err != nil {
+			return nil, errorf("участник %s: %w", reveal.Participants[i], err)
+		}
+	}
+
+	out := ceremonyMessage{Type: "frost-shares", FrostShares: make([]string, n)}
+	for j := 1; j <= n; j++ {
+		if j == index {
+			continue
+		}
+		share := poly.evaluate(j)
+		out.FrostShares[j-1], err = frostSeal(maskKey, reveal.MaskKeys[j-1], context, index, j, share)
+		wipeBig(share)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := conn.send(out); err != nil {
+		return nil, err
+	}
+
+	m, err = conn.receive("frost-shares")
+	if err != nil {
+		return nil, err
+	}
+	if len(m.FrostShares) != n {
+		return nil, errorf("координатор прислал долей FROST: %d вместо %d", len(m.FrostShares), n)
+	}
+	signing := poly.evaluate(index)
+	defer wipeBig(signing)
+	for i := 1; i <= n; i++ {
+		if i == index {
+			continue
+		}
+		share, err := frostOpen(maskKey, reveal.MaskKeys[i-1], context, i, index, m.FrostShares[i-1])
+		if err != nil {
+			return nil, errorf("доля от участника %s: %w", reveal.Participants[i-1], err)
+		}
+		// Доля должна лежать на многочлене, по которому участник дал обязательства
+		valid := edScalarBaseMult(share).equal(frostCommitmentAt(commitments[i-1], index))
+		signing.Add(signing, share).Mod(signing, edL)
+		wipeBig(share)
+		if !valid {
+			return nil, mismatchf("доля от участника %s не соответствует его обязательствам", reveal.Participants[i-1])
+		}
+	}
+
+	group := newFrostGroup(commitments)
+	if !edScalarBaseMult(signing).equal(group.verification[index-1]) {
+		return nil, mismatchf("доля ключа не сходится с проверочным ключом")
+	}
+	groupKey := hex.EncodeToString(group.publicKey.encode())
+	if err := conn.send(ceremonyMessage{Type: "frost-done", GroupKey: groupKey}); err != nil {
+		return nil, err
+	}
+	result, err := conn.receive("frost-result")
+	if err != nil {
+		return nil, err
+	}
+	if result.GroupKey != groupKey {
+		return nil, errorf("координатор сообщил другой общий ключ FROST")
+	}
+
+	share := &frostShare{
+		Kind:           "frost-share",
+		Ciphersuite:    frostCiphersuite,
+		Identifier:     index,
+		Threshold:      threshold,
+		Participants:   reveal.Participants,
+		GroupPublicKey: groupKey,
+		SigningShare:   littleEndian(signing, 32),
+		Fingerprint:    fingerprint,
+		CreatedAt:      time.Now().UTC().Truncate(time.Second),
+	}
+	for _, v := range group.verification {
+		share.VerificationShares = append(share.VerificationShares, hex.EncodeToString(v.encode()))
+	}
+	return share, nil
+}
+
+// printFrostGroup выводит общий ключ подписи и проверочные ключи долей
+func printFrostGroup(group *frostGroup, threshold int, names []string) {
+	pub := ed25519.PublicKey(group.publicKey.encode())
+	fmt.Printf(tr("Общий ключ подписи Ed25519 (FROST, %d из %d): %s\n"), threshold, len(names), hex.EncodeToString(pub))
+	fmt.Printf(tr("Отпечаток ключа: %s\n"), emph(keyFingerprint(pub)))
+	fmt.Println(tr("Проверочные ключи долей:"))
+	for i, v := range group.verification {
+		fmt.Printf("  %d. %s: %s\n", i+1, names[i], hex.EncodeToString(v.encode()))
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+)
+
+// kdfFlags - общие флаги выбора KDF и его параметров
+type kdfFlags struct {
+	kdf         *string
+	iterations  *int
+	memory      *uint
+	parallelism *uint
+	salt        *string
+	preset      *string
+}
+
+// addKDFFlags регистрирует флаги KDF в наборе
+func addKDFFlags(fs *flag.FlagSet) *kdfFlags {
+	f := &kdfFlags{
+		kdf:         fs.String("kdf", kdfPBKDF2, "KDF: "+kdfPBKDF2+tr(" или ")+kdfArgon2id),
+		iterations:  fs.Int("iterations", 0, "число итераций PBKDF2 (по умолчанию 100000) или проходов Argon2id (по умолчанию 3)"),
+		memory:      fs.Uint("memory", argon2FlagMemory, "память Argon2id в КиБ"),
+		parallelism: fs.Uint("parallelism", argon2DefaultParallelism, "число потоков Argon2id"),
+		salt:        fs.String("salt", v2DefaultSalt, "соль церемонии"),
+		preset:      fs.String("preset", "", "готовый набор параметров KDF вместо --kdf, --iterations и параметров Argon2id: fast, standard или paranoid"),
+	}
+	// Синонимы с явным префиксом пишут в те же переменные
+	fs.IntVar(f.iterations, "argon2-time", 0, "число проходов Argon2id (синоним --iterations)")
+	fs.UintVar(f.memory, "argon2-memory", argon2FlagMemory, "память Argon2id в КиБ (синоним --memory)")
+	fs.UintVar(f.parallelism, "argon2-threads", argon2DefaultParallelism, fmt.Sprintf(tr("число потоков Argon2id (синоним --parallelism, ядер: %d)"), runtime.NumCPU()))
+	return f
+}
+
+// argon2FlagNames перечисляет флаги, применимые только к Argon2id
+var argon2FlagNames = []string{"memory", "parallelism", "argon2-time", "argon2-memory", "argon2-threads"}
+
+// v2FlagNames перечисляет флаги параметров, применимые только к схеме v2
+var v2FlagNames = append([]string{"epoch", "kdf", "iterations", "salt", "preset"}, argon2FlagNames...)
+
+// apply переносит параметры KDF из разобранных флагов в p
+func (f *kdfFlags) apply(fs *flag.FlagSet, p *Params) error {
+	set := setFlags(fs)
+	p.KDF = *f.kdf
+	p.Iterations = *f.iterations
+	p.Salt = *f.salt
+	p.Memory, p.Parallelism = 0, 0
+
+	if *f.preset != "" {
+		// Пресет задает все числа KDF сразу, смешивать его с ними нельзя
+		for _, name := range append([]string{"kdf", "iterations"}, argon2FlagNames...) {
+			if set[name] {
+				return errorf("флаг --%s нельзя сочетать с --preset: пресет задает все параметры KDF", name)
+			}
+		}
+		preset, err := findKDFPreset(*f.preset)
+		if err != nil {
+			return err
+		}
+		preset.apply(p)
+		preset.echo()
+		return checkArgon2Memory(p.Memory)
+	}
+
+	switch p.KDF {
+	case kdfArgon2id:
+		if !set["iterations"] && !set["argon2-time"] {
+			p.Iterations = argon2DefaultTime
+		}
+		if uint64(*f.memory) > math.MaxUint32 {
+			return errorf("память Argon2id не может превышать %d КиБ", uint64(math.MaxUint32))
+		}
+		if *f.parallelism > math.MaxUint8 {
+			return errorf("число потоков Argon2id должно быть от 1 до 255")
+		}
+		p.Memory = uint32(*f.memory)
+		p.Parallelism = uint8(*f.parallelism)
+		return checkArgon2Memory(p.Memory)
+	default:
+		if !set["iterations"] {
+			p.Iterations = 100000
+		}
+		if set["argon2-time"] {
+			return errorf("флаг --argon2-time применим только к %s, для %s задайте --iterations", kdfArgon2id, p.KDF)
+		}
+		for _, name := range argon2FlagNames {
+			if set[name] {
+				return errorf("флаг --%s применим только к %s", name, kdfArgon2id)
+			}
+		}
+	}
+	return nil
+}
+
+// checkArgon2Memory сверяет память Argon2id со свободной памятью машины:
+// нехватка привела бы к вытеснению в своп или к аварийному завершению
+// посреди церемонии, а не к понятной ошибке
+func checkArgon2Memory(kib uint32) error {
+	avail, ok := availableMemory()
+	if !ok {
+		return nil
+	}
+	if need := uint64(kib) * 1024; need > avail {
+		return errorf("Argon2id потребуется %s памяти, а свободно %s: уменьшите --argon2-memory или закройте другие программы",
+			formatBytes(need), formatBytes(avail))
+	}
+	return nil
+}
+
+// setFlags возвращает имена флагов, заданных явно или из профиля
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	return set
+}
+
+// schemeFlags - общие флаги выбора схемы и ее параметров
+type schemeFlags struct {
+	scheme     *string
+	epoch      *uint
+	kdf        *kdfFlags
+	paramsFile *string
+	compat     *string
+}
+
+// addSchemeFlags регистрирует флаги схемы в наборе
+func addSchemeFlags(fs *flag.FlagSet) *schemeFlags {
+	return &schemeFlags{
+		scheme:     fs.String("scheme", "v1", "схема вывода: v1, v2 или v3 (вклады сидов растягиваются по отдельности и складываются)"),
+		epoch:      fs.Uint("epoch", 1, "эпоха ротации (только v2)"),
+		kdf:        addKDFFlags(fs),
+		paramsFile: fs.String("params-file", "", "взять параметры схемы из манифеста (--params-out) или JSON-результата вместо флагов"),
+		compat:     fs.String("compat", "", "v1: побайтно повторить выходы первых версий - схема v1 и сиды без нормализации символов"),
+	}
+}
+
+// compatV1 сообщает, что задан --compat v1
+func (f *schemeFlags) compatV1() bool {
+	return *f.compat == "v1"
+}
+
+// params собирает параметры из разобранных флагов
+func (f *schemeFlags) params(fs *flag.FlagSet) (Params, error) {
+	set := setFlags(fs)
+	if *f.compat != "" {
+		// Устройства, выданные в 2025 году, зависят от прежних выходов,
+		// поэтому режим совместимости не принимает никаких параметров
+		if *f.compat != "v1" {
+			return Params{}, errorf("неизвестный режим совместимости %q, доступен: v1", *f.compat)
+		}
+		if *f.scheme != "v1" {
+			return Params{}, errorf("--compat v1 не сочетается с --scheme %s", *f.scheme)
+		}
+		for _, name := range append([]string{"params-file"}, v2FlagNames...) {
+			if set[name] {
+				return Params{}, errorf("флаг --%s нельзя сочетать с --compat v1: параметры v1 не настраиваются", name)
+			}
+		}
+		return DefaultParams(), nil
+	}
+	if *f.paramsFile != "" {
+		// Параметры из файла не смешиваются с флагами: повтор должен быть побайтным
+		for _, name := range append([]string{"scheme"}, v2FlagNames...) {
+			if set[name] {
+				return Params{}, errorf("флаг --%s нельзя сочетать с --params-file: параметры берутся только из файла", name)
+			}
+		}
+		p, err := loadParamsFile(*f.paramsFile)
+		if err != nil {
+			return Params{}, err
+		}
+		if p.KDF == kdfArgon2id {
+			return p, checkArgon2Memory(p.Memory)
+		}
+		return p, nil
+	}
+	if *f.scheme == "v1" {
+		// Явно заданные параметры v2 для v1 скорее всего ошибка оператора
+		for _, name := range v2FlagNames {
+			if set[name] {
+				return Params{}, errorf("флаг --%s применим только к схеме v2", name)
+			}
+		}
+		return DefaultParams(), nil
+	}
+
+	p := V2Params(uint32(*f.epoch))
+	p.Scheme = *f.scheme
+	if err := f.kdf.apply(fs, &p); err != nil {
+		return Params{}, err
+	}
+	return p, p.Validate()
+}
+
+// describeParams кратко описывает параметры схемы для вывода оператору
+func describeParams(p Params) string {
+	if p.KDF == kdfArgon2id {
+		return fmt.Sprintf(tr("Схема: %s, %s (проходов: %d, память: %d КиБ, потоков: %d), эпоха %d"),
+			p.Scheme, p.KDF, p.Iterations, p.Memory, p.Parallelism, p.Epoch)
+	}
+	return fmt.Sprintf(tr("Схема: %s, %s, %d итераций, эпоха %d"), p.Scheme, p.KDF, p.Iterations, p.Epoch)
+}
+
+// runGenerate запрашивает сиды у пользователя и выводит мастер-сид
+func runGenerate(args []string) error {
+	fs := newFlagSet("generate")
+	sf := addSchemeFlags(fs)
+	format := fs.String("format", "text", "формат вывода: text, json, msv2, rs (копия с кодом Рида-Соломона) или k8s-secret (манифест Secret Kubernetes)")
+	kf := addK8sSecretFlags(fs)
+	spf := addSOPSFlags(fs)
+	tlf := addTimelockFlags(fs)
+	copyResult := fs.Bool("copy", false, "скопировать мастер-сид в буфер обмена (очищается командой wipe)")
+	rf := addRevealFlags(fs)
+	ef := addEnvFlags(fs)
+	rcf := addReceiptFlags(fs)
+	stf := addStoreFlags(fs)
+	paramsOut := fs.String("params-out", "", "сохранить манифест параметров схемы (без секретов) в файл")
+	doubleCheckFlag := fs.Bool("double-check", false, "пересчитать мастер-сид независимой реализацией и прервать работу при расхождении")
+	report := fs.Bool("report", false, "оценить энтропию каждого сида и уровень стойкости с учетом повторов, слабых сидов и KDF; с --format json отчет входит в результат")
+	dryRun := fs.Bool("dry-run", false, "проверить входные данные и показать параметры, результаты и обязательство набора сидов, не выводя мастер-сид и ничего не записывая")
+	ssf := addSeedSourceFlags(fs)
+	df := addDrandFlags(fs)
+	nf := addNISTFlags(fs)
+	qf := addQuorumFlags(fs)
+	pf := addProfileFlags(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pf.apply(fs); err != nil {
+		return err
+	}
+	params, err := sf.params(fs)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "text", "json", "msv2", "rs", k8sSecretFormat:
+	default:
+		return errorf("неизвестный формат %q", *format)
+	}
+	if err := kf.check(fs, *format); err != nil {
+		return err
+	}
+	if err := spf.check(*format, kf.sealed()); err != nil {
+		return err
+	}
+	if err := tlf.check(*format, spf.enabled()); err != nil {
+		return err
+	}
+	if *copyResult && *format != "text" {
+		return errorf("флаг --copy применим только к формату text")
+	}
+	if err := rf.checkGroups(*format == "text"); err != nil {
+		return err
+	}
+	if err := ef.check(*format != "text"); err != nil {
+		return err
+	}
+	if err := rcf.load(); err != nil {
+		return err
+	}
+	if err := stf.check(); err != nil {
+		return err
+	}
+	if err := qf.load(); err != nil {
+		return err
+	}
+	ssf.dryRun = *dryRun
+	ssf.raw = sf.compatV1()
+	drand, err := df.load()
+	if err != nil {
+		return err
+	}
+	pulse, err := nf.load()
+	if err != nil {
+		return err
+	}
+	var beacons []publicBeacon
+	if drand != nil {
+		beacons = append(beacons, drand)
+	}
+	if pulse != nil {
+		beacons = append(beacons, pulse)
+	}
+	if sf.compatV1() && len(beacons) > 0 {
+		return errorf("--compat v1 не сочетается с --drand и --nist-pulse: маяк меняет набор сидов")
+	}
+
+	// Для машиночитаемых форматов приглашения уходят в stderr
+	var prompts io.Writer = os.Stdout
+	if *format != "text" {
+		prompts = os.Stderr
+	}
+
+	fmt.Fprintln(prompts, tr("=== Генератор Мастер-Сида ==="))
+	fmt.Fprintln(prompts)
+	deviceSeeds, err := ssf.read(prompts)
+	if err != nil {
+		return err
+	}
+	defer wipeSeeds(deviceSeeds)
+
+	if len(deviceSeeds) == 0 {
+		return errorf("не введено ни одного сида")
+	}
+
+	fmt.Fprintf(prompts, tr("\n✓ Получено сидов: %d\n\n"), len(deviceSeeds))
+	for _, b := range beacons {
+		fmt.Fprintf(prompts, tr("Подмешивается публичный маяк: %s\n"), b.describe())
+	}
+	if len(beacons) > 0 {
+		fmt.Fprintln(prompts)
+	}
+	var entropy *entropyReport
+	if *report {
+		entropy = newEntropyReport(deviceSeeds, params, len(beacons), len(ssf.files))
+		entropy.write(prompts)
+	}
+
+	// Генерируем мастер-сид
+	stopProgress := showKDFProgress()
+	masterSeed, err := GenerateMasterSeed(beaconSeedSet(deviceSeeds, beacons), params)
+	stopProgress()
+	if err != nil {
+		return errorf("ошибка генерации: %w", err)
+	}
+	defer wipe(masterSeed)
+	if *dryRun {
+		outputs := []plannedOutput{{fmt.Sprintf(tr("мастер-сид в stdout, формат %s"), tlf.describe(spf.describe(*format))), ""}}
+		if *copyResult {
+			outputs = append(outputs, plannedOutput{tr("буфер обмена"), ""})
+		}
+		outputs = append(outputs, stf.targets()...)
+		if *paramsOut != "" {
+			outputs = append(outputs, plannedOutput{tr("манифест параметров"), *paramsOut})
+		}
+		outputs = append(outputs, rcf.targets()...)
+		return printDryRun(prompts, params, sf.saltSource(fs), outputs, masterSeed)
+	}
+	if *doubleCheckFlag {
+		if err := doubleCheck(beaconSeedSet(deviceSeeds, beacons), params, masterSeed); err != nil {
+			return err
+		}
+		fmt.Fprintln(prompts, tr("✓ Мастер-сид подтвержден независимым повторным вычислением"))
+	}
+	printSeedCommitment(prompts, masterSeed)
+	result := newResult(masterSeed, len(deviceSeeds), params)
+	auditResult("master", "", result.Fingerprint)
+	noteProvenanceParams(params)
+	result.Drand = drand
+	result.NISTPulse = pulse
+	result.Entropy = entropy
+	result.KDFPreset = *sf.kdf.preset
+	if err := qf.confirm(prompts, result.Fingerprint); err != nil {
+		return err
+	}
+
+	if *format != "text" {
+		err = tlf.write(os.Stdout, func(w io.Writer) error {
+			return spf.write(w, *format, func(w io.Writer) error {
+				if *format == k8sSecretFormat {
+					return kf.write(w, result)
+				}
+				return writeResult(w, result, *format)
+			})
+		})
+		if err != nil {
+			return err
+		}
+		if err := stf.store(os.Stderr, masterSeed); err != nil {
+			return err
+		}
+		if *paramsOut != "" {
+			if err := writeParamsManifest(os.Stderr, *paramsOut, params, *sf.kdf.preset); err != nil {
+				return err
+			}
+		}
+		return rcf.write(os.Stderr, result)
+	}
+
+	// Вычисляем SHA-512 хеш для дополнительной информации
+	shortHash := result.Fingerprint
+
+	// Выводим результат
+	if stf.withheld(rf) {
+		fmt.Println(tr("Мастер-сид не выводится: stdout не терминал, результат сохраняется в хранилище"))
+	} else if err := rf.reveal(tr("Мастер-сид (детерминированный):"), masterSeed); err != nil {
+		return err
+	}
+	fmt.Println()
+	if params.Scheme != "v1" {
+		fmt.Println(describeParams(params))
+	}
+	if entropy != nil {
+		fmt.Printf(tr("Длина: %d символа (%d бит), уровень стойкости: около %.0f бит\n"), len(masterSeed)*2, len(masterSeed)*8, entropy.SecurityBits)
+	} else {
+		fmt.Printf(tr("Длина: %d символа (%d бит), энтропия не больше, чем у сидов вместе (оценка - --report)\n"), len(masterSeed)*2, len(masterSeed)*8)
+	}
+	fmt.Printf(tr("SHA-512 хеш: %s...\n"), emph(shortHash))
+	for _, b := range beacons {
+		fmt.Printf(tr("Публичный маяк: %s (сохраните вместе с протоколом церемонии)\n"), b.describe())
+	}
+	fmt.Println()
+	fmt.Println(tr("✓ Мастер-сид успешно сгенерирован!"))
+	if *copyResult {
+		masterHex := hexSecret(masterSeed)
+		defer wipe(masterHex)
+		if err := copyToClipboard(masterHex); err != nil {
+			return errorf("ошибка копирования в буфер обмена: %w", err)
+		}
+		fmt.Println(tr("✓ Мастер-сид скопирован в буфер обмена, после использования выполните seedgen wipe"))
+	}
+	if err := stf.store(os.Stdout, masterSeed); err != nil {
+		return err
+	}
+	if *paramsOut != "" {
+		if err := writeParamsManifest(os.Stdout, *paramsOut, params, *sf.kdf.preset); err != nil {
+			return err
+		}
+	}
+	if err := rcf.write(os.Stdout, result); err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Println(tr("Примечание: при одинаковых входных сидах"))
+	fmt.Println(tr("всегда будет получаться одинаковый мастер-сид."))
+	return nil
+}
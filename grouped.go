@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Раскладка для переписывания от руки, например на стальные пластины:
+// четыре группы в строке, столбцы подписаны буквами, строки - номерами
+const (
+	groupMinSize  = 4
+	groupMaxSize  = 8
+	groupsPerLine = 4
+	// groupedInputSize ограничивает ввод раскладки: мастер-сид в hex
+	// занимает в ней меньше 400 байт
+	groupedInputSize = 4096
+)
+
+// groupSize - размер группы для флага --group-size; 0 - вывод одной строкой.
+// Размер проверяется при разборе флагов, до ввода сидов и вычислений.
+type groupSize int
+
+// String возвращает значение флага
+func (g *groupSize) String() string {
+	return strconv.Itoa(int(*g))
+}
+
+// Set разбирает и проверяет размер группы
+func (g *groupSize) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n != 0 && (n < groupMinSize || n > groupMaxSize) {
+		return errorf("размер группы должен быть от %d до %d символов", groupMinSize, groupMaxSize)
+	}
+	*g = groupSize(n)
+	return nil
+}
+
+// addGroupSizeFlag регистрирует флаг вывода группами
+func addGroupSizeFlag(fs *flag.FlagSet) *groupSize {
+	size := new(groupSize)
+	fs.Var(size, "group-size", "вывести для переписывания от руки группами по N символов (4-8) с номерами строк и контрольным символом каждой строки")
+	return size
+}
+
+// groupCheckAlphabet - 37 контрольных символов: алфавит Крокфорда и
+// дополнительные символы его контрольной суммы по модулю 37
+const groupCheckAlphabet = crockfordAlphabet + "*~$=U"
+
+// groupLineCheck возвращает контрольный символ строки - сумму по модулю 37,
+// как в ISO/IEC 7064 MOD 37-2: цифры и буквы - числа от 0 до 35, сумма
+// удваивается перед каждым следующим символом. Так обнаруживается любая
+// замена одного символа и перестановка соседних или стоящих через один.
+// Сумма начинается с номера строки, поэтому переставленная строка тоже не
+// совпадет. Символы - только [0-9a-z], их проверяет вызывающий.
+func groupLineCheck(line int, chars []byte) byte {
+	sum := line % 37
+	for _, c := range chars {
+		sum = (sum*2 + groupCharValue(c)) % 37
+	}
+	return groupCheckAlphabet[sum]
+}
+
+// groupCharValue возвращает число символа строки или -1 для символа не из [0-9a-z]
+func groupCharValue(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'z':
+		return int(c-'a') + 10
+	}
+	return -1
+}
+
+// writeGrouped выводит текст секрета (hex или bech32) группами по size
+// символов: заголовок с буквами столбцов и пронумерованные строки, в конце
+// каждой - контрольный символ. Вывод собирается в затираемом буфере и
+// пишется одним вызовом.
+func writeGrouped(w io.Writer, text []byte, size groupSize) error {
+	lineChars := int(size) * groupsPerLine
+	lines := (len(text) + lineChars - 1) / lineChars
+	width := len(strconv.Itoa(lines))
+	if width < 2 {
+		width = 2
+	}
+	rowSize := width + 2 + groupsPerLine*(int(size)+1) + 3
+	out := newSecret(rowSize * (lines + 1))[:0]
+	defer func() { wipe(out[:cap(out)]) }()
+
+	out = append(out, bytes.Repeat([]byte(" "), width+2)...)
+	for col := 0; col < groupsPerLine && col*int(size) < len(text); col++ {
+		out = append(out, byte('A'+col))
+		out = append(out, bytes.Repeat([]byte(" "), int(size))...)
+	}
+	out = append(bytes.TrimRight(out, " "), '\n')
+
+	for line := 1; line <= lines; line++ {
+		chars := text[(line-1)*lineChars : minInt(line*lineChars, len(text))]
+		out = append(out, fmt.Sprintf("%0*d  ", width, line)...)
+		for i := 0; i < len(chars); i += int(size) {
+			out = append(out, chars[i:minInt(i+int(size), len(chars))]...)
+			out = append(out, ' ')
+		}
+		out = append(out, ' ', groupLineCheck(line, chars), '\n')
+	}
+	_, err := w.Write(out)
+	return err
+}
+
+// writeGroupedHex выводит секрет в hex группами
+func writeGroupedHex(w io.Writer, secret []byte, size groupSize) error {
+	text := hexSecret(secret)
+	defer wipe(text)
+	return writeGrouped(w, text, size)
+}
+
+// parseGroupedLines собирает текст секрета из раскладки writeGrouped и
+// сверяет контрольный символ каждой строки. Регистр букв не важен, текст
+// возвращается строчными буквами. ok ложно, если вход не похож на такую
+// раскладку: тогда его разбирают как обычно. Результат - в затираемом
+// буфере.
+func parseGroupedLines(data []byte) (text []byte, ok bool, err error) {
+	var rows [][][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fields := bytes.Fields(line)
+		if len(fields) == 0 || isGroupHeader(fields) {
+			continue
+		}
+		rows = append(rows, fields)
+	}
+	// Строка раскладки - номер, хотя бы одна группа и контрольный символ
+	if len(rows) == 0 {
+		return nil, false, nil
+	}
+	for _, fields := range rows {
+		if len(fields) < 3 || len(fields[len(fields)-1]) != 1 {
+			return nil, false, nil
+		}
+		if _, err := strconv.Atoi(string(fields[0])); err != nil {
+			return nil, false, nil
+		}
+	}
+
+	text = newSecret(len(data))[:0]
+	for i, fields := range rows {
+		number, _ := strconv.Atoi(string(fields[0]))
+		if number != i+1 {
+			wipe(text[:cap(text)])
+			return nil, true, errorf("после строки %d идет строка %d: строка пропущена или переставлена", i, number)
+		}
+		start := len(text)
+		for _, group := range fields[1 : len(fields)-1] {
+			for _, c := range group {
+				// Копию могли выбить заглавными буквами: hex и bech32 от регистра не зависят
+				if c >= 'A' && c <= 'Z' {
+					c += 'a' - 'A'
+				}
+				if groupCharValue(c) < 0 {
+					wipe(text[:cap(text)])
+					return nil, true, errorf("строка %d: недопустимый символ %q", number, c)
+				}
+				text = append(text, c)
+			}
+		}
+		if normalizeCrockford(fields[len(fields)-1][0]) != groupLineCheck(number, text[start:]) {
+			wipe(text[:cap(text)])
+			return nil, true, errorf("строка %d переписана с ошибкой: контрольный символ не совпадает", number)
+		}
+	}
+	return text, true, nil
+}
+
+// readGroupedInput дочитывает из scanner раскладку writeGrouped, если
+// строка first похожа на ее заголовок или первую строку, и возвращает
+// собранный текст. Раскладка заканчивается пустой строкой или концом
+// ввода. Любая другая строка возвращается как есть.
+func readGroupedInput(scanner *bufio.Scanner, first string) (string, error) {
+	fields := bytes.Fields([]byte(first))
+	if len(fields) == 0 || !isGroupHeader(fields) && (len(fields) < 3 || string(fields[0]) != "01") {
+		return first, nil
+	}
+	data := newSecret(groupedInputSize)[:0]
+	defer func() { wipe(data[:cap(data)]) }()
+	line := []byte(first)
+	for {
+		// Буфер не растет, чтобы в памяти не оставалось незатертых копий
+		if len(data)+len(line)+1 > cap(data) {
+			return "", errorf("ввод длиннее %d байт", cap(data))
+		}
+		data = append(append(data, line...), '\n')
+		if !scanner.Scan() {
+			break
+		}
+		if line = scanner.Bytes(); len(bytes.TrimSpace(line)) == 0 {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errorf("ошибка чтения ввода: %w", err)
+	}
+	text, ok, err := parseGroupedLines(data)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return first, nil
+	}
+	defer wipe(text)
+	return string(text), nil
+}
+
+// isGroupHeader сообщает, что строка - заголовок столбцов "A B C D"
+func isGroupHeader(fields [][]byte) bool {
+	for i, f := range fields {
+		if len(f) != 1 || f[0] != byte('A'+i) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,588 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Кадры передачи через QR-коды: SGH1:сеанс:направление:номер/всего:данные:MAC.
+// Данные - часть base64url полезной нагрузки, MAC - HMAC-SHA256 ключом сеанса
+// по всем полям кадра, усеченный до 16 байт. Кадр с данными до 108 байт
+// помещается в QR-код версии 10 с уровнем коррекции M.
+const (
+	handoffPrefix = "SGH1"
+	handoffDomain = "seedgen/handoff/v1"
+	handoffChunk  = 144
+	handoffMACLen = 16
+)
+
+// Направления кадров: запрос от подключенной к сети машины к офлайн-машине
+// и ответ обратно. Направление входит в MAC, поэтому кадр запроса нельзя
+// выдать за кадр ответа.
+const (
+	handoffToOffline = "Q"
+	handoffToOnline  = "R"
+)
+
+// handoffRequest - запрос координатора к офлайн-машине церемонии:
+// параметры схемы и обязательства по сидам, которые должны быть введены
+type handoffRequest struct {
+	Kind        string    `json:"kind"`
+	Session     string    `json:"session"`
+	CreatedAt   time.Time `json:"created_at"`
+	Params      Params    `json:"params"`
+	Commitments []string  `json:"commitments,omitempty"`
+	Note        string    `json:"note,omitempty"`
+}
+
+// handoffResponse - ответ офлайн-машины: результат без мастер-сида,
+// привязанный к запросу его хэшем
+type handoffResponse struct {
+	Kind          string    `json:"kind"`
+	Session       string    `json:"session"`
+	Request       string    `json:"request_sha256"`
+	CreatedAt     time.Time `json:"created_at"`
+	Version       string    `json:"version"`
+	Params        Params    `json:"params"`
+	SeedCount     int       `json:"seed_count"`
+	Fingerprint   string    `json:"fingerprint"`
+	SetCommitment string    `json:"set_commitment"`
+}
+
+// handoffSession - состояние сеанса на подключенной к сети машине между
+// export-request и import-response. Содержит ключ MAC и потому сохраняется
+// с правами 0600.
+type handoffSession struct {
+	Kind      string    `json:"kind"`
+	Session   string    `json:"session"`
+	Key       string    `json:"key"`
+	Request   string    `json:"request_sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handoffMAC вычисляет MAC кадра
+func handoffMAC(key []byte, session, direction string, seq, total int, chunk string) []byte {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\x00%s\x00%s\x00%d\x00%d\x00%s", handoffDomain, session, direction, seq, total, chunk)
+	return mac.Sum(nil)[:handoffMACLen]
+}
+
+// handoffFrames разбивает полезную нагрузку на кадры с номерами и MAC
+func handoffFrames(key []byte, session, direction string, payload []byte) []string {
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	total := (len(encoded) + handoffChunk - 1) / handoffChunk
+	frames := make([]string, 0, total)
+	for seq := 1; seq <= total; seq++ {
+		chunk := encoded[(seq-1)*handoffChunk:]
+		if len(chunk) > handoffChunk {
+			chunk = chunk[:handoffChunk]
+		}
+		mac := handoffMAC(key, session, direction, seq, total, chunk)
+		frames = append(frames, fmt.Sprintf("%s:%s:%s:%d/%d:%s:%s", handoffPrefix, session, direction, seq, total, chunk, hex.EncodeToString(mac)))
+	}
+	return frames
+}
+
+// errHandoffConflict - кадр с уже полученным номером, но другим содержимым:
+// при верном MAC это подмена, и прием прерывается
+var errHandoffConflict = errors.New("кадр получен повторно с другим содержимым, передача подменена")
+
+// handoffAssembler собирает кадры одного направления одного сеанса.
+// Пропущенные кадры видны по номерам, кадры чужого сеанса и поддельные
+// отвергаются по MAC, повторно отсканированный кадр пропускается, а кадр
+// с тем же номером, но другим содержимым прерывает прием.
+type handoffAssembler struct {
+	key       []byte
+	session   string
+	direction string
+	total     int
+	chunks    map[int]string
+}
+
+// add принимает кадр; duplicate сообщает, что кадр уже был принят
+func (a *handoffAssembler) add(frame string) (duplicate bool, err error) {
+	fields := strings.Split(strings.TrimSpace(frame), ":")
+	if len(fields) != 6 || fields[0] != handoffPrefix {
+		return false, errorf("строка не является кадром передачи seedgen")
+	}
+	session, direction, position, chunk := fields[1], fields[2], fields[3], fields[4]
+	if direction != a.direction {
+		return false, errorf("кадр другого направления передачи (%s вместо %s)", direction, a.direction)
+	}
+	if a.session != "" && session != a.session {
+		return false, errorf("кадр другого сеанса %s: возможно, повтор старой передачи", session)
+	}
+	parts := strings.SplitN(position, "/", 2)
+	if len(parts) != 2 {
+		return false, errorf("некорректный номер кадра %q", position)
+	}
+	seq, err1 := strconv.Atoi(parts[0])
+	total, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || total < 1 || total > 999 || seq < 1 || seq > total {
+		return false, errorf("некорректный номер кадра %q", position)
+	}
+	mac, err := hex.DecodeString(fields[5])
+	if err != nil || !hmac.Equal(mac, handoffMAC(a.key, session, direction, seq, total, chunk)) {
+		return false, mismatchf("кадр %d: MAC не сходится - неверный код сеанса, искажение или подмена", seq)
+	}
+	if a.chunks == nil {
+		a.session, a.total, a.chunks = session, total, make(map[int]string)
+	}
+	if total != a.total {
+		return false, errorf("кадр %d сообщает %d кадров вместо %d", seq, total, a.total)
+	}
+	if prev, ok := a.chunks[seq]; ok {
+		if prev != chunk {
+			return false, errorf("кадр %d: %w", seq, errHandoffConflict)
+		}
+		return true, nil
+	}
+	a.chunks[seq] = chunk
+	return false, nil
+}
+
+// missing возвращает номера еще не полученных кадров
+func (a *handoffAssembler) missing() []int {
+	if a.chunks == nil {
+		return []int{1}
+	}
+	var out []int
+	for seq := 1; seq <= a.total; seq++ {
+		if _, ok := a.chunks[seq]; !ok {
+			out = append(out, seq)
+		}
+	}
+	return out
+}
+
+// payload склеивает полученные кадры
+func (a *handoffAssembler) payload() ([]byte, error) {
+	var sb strings.Builder
+	for seq := 1; seq <= a.total; seq++ {
+		sb.WriteString(a.chunks[seq])
+	}
+	return base64.RawURLEncoding.DecodeString(sb.String())
+}
+
+// receiveHandoff читает кадры по одному на строку (сканер штрихкодов
+// в режиме клавиатуры, вставка или вывод zbarcam --raw) до получения
+// всех кадров. Пустая строка или конец ввода при неполном наборе - ошибка
+// со списком недостающих кадров.
+func receiveHandoff(in io.Reader, w io.Writer, a *handoffAssembler) ([]byte, error) {
+	for {
+		missing := a.missing()
+		if len(missing) == 0 {
+			return a.payload()
+		}
+		line, err := readAnswer(in)
+		if err != nil || line == "" {
+			if a.chunks == nil {
+				return nil, errorf("не получено ни одного кадра")
+			}
+			return nil, errorf("передача не завершена, не хватает кадров: %s", joinInts(missing))
+		}
+		duplicate, err := a.add(line)
+		switch {
+		case err != nil:
+			fmt.Fprintf(w, "❌ %v\n", err)
+			if errors.Is(err, errHandoffConflict) {
+				return nil, err
+			}
+		case duplicate:
+			fmt.Fprintln(w, tr("⚠ Кадр уже получен, пропущен"))
+		default:
+			fmt.Fprintf(w, tr("✓ Кадров получено: %d из %d\n"), a.total-len(a.missing()), a.total)
+		}
+	}
+}
+
+// joinInts перечисляет номера через запятую
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// showHandoff показывает кадры QR-кодами по одному: следующий кадр
+// выводится по нажатию Enter, чтобы его успели отсканировать. Если stdout
+// не терминал или задан text, кадры печатаются строками, например для qrencode.
+func showHandoff(frames []string, text bool) error {
+	if text || !isTerminal(os.Stdout) {
+		for _, f := range frames {
+			fmt.Println(f)
+		}
+		return nil
+	}
+	in, closeInput, err := openConfirmInput()
+	if err != nil {
+		return errorf("показ кадров требует терминала: %w", err)
+	}
+	defer closeInput()
+	for i, f := range frames {
+		q, err := encodeQR([]byte(f))
+		if err != nil {
+			return err
+		}
+		clearScreen()
+		fmt.Printf(tr("Кадр %d из %d\n"), i+1, len(frames))
+		q.render(os.Stdout)
+		if i+1 < len(frames) {
+			fmt.Print(tr("Enter - следующий кадр..."))
+		} else {
+			fmt.Print(tr("Последний кадр. Enter - завершить..."))
+		}
+		if err := readLine(in); err != nil {
+			return errorf("показ кадров прерван")
+		}
+	}
+	clearScreen()
+	return nil
+}
+
+// formatSessionCode записывает ключ сеанса группами по 4 символа
+func formatSessionCode(key []byte) string {
+	h := hex.EncodeToString(key)
+	groups := make([]string, 0, len(h)/4)
+	for i := 0; i < len(h); i += 4 {
+		groups = append(groups, h[i:i+4])
+	}
+	return strings.Join(groups, "-")
+}
+
+// parseSessionCode разбирает код сеанса, введенный оператором
+func parseSessionCode(code string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code)))
+	if err != nil || len(key) != 16 {
+		return nil, errorf("некорректный код сеанса: ожидалось 32 шестнадцатеричных символа")
+	}
+	return key, nil
+}
+
+// readCommitmentList читает обязательства по сидам, по одному на строку;
+// пустые строки и строки с # пропускаются
+func readCommitmentList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if raw, err := hex.DecodeString(line); err != nil || len(raw) != sha256.Size {
+			return nil, errorf("%s:%d: ожидалось обязательство SHA-256 в hex", path, n)
+		}
+		if seen[line] {
+			return nil, errorf("%s:%d: обязательство повторяется", path, n)
+		}
+		seen[line] = true
+		out = append(out, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// runHandoff переносит запрос и результат церемонии между машиной с сетью
+// и офлайн-машиной только через QR-коды
+func runHandoff(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, tr("Использование:"))
+		fmt.Fprintln(os.Stderr, tr("  seedgen handoff export-request --session handoff.json [--commitments commitments.txt] [флаги схемы]   # машина с сетью"))
+		fmt.Fprintln(os.Stderr, tr("  seedgen handoff respond [флаги ввода сидов]                                                       # офлайн-машина"))
+		fmt.Fprintln(os.Stderr, tr("  seedgen handoff import-response --session handoff.json [--out response.json]                      # машина с сетью"))
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return errorf("укажите действие: export-request, respond или import-response")
+	}
+
+	switch args[0] {
+	case "export-request":
+		return runHandoffExportRequest(args[1:])
+	case "respond":
+		return runHandoffRespond(args[1:])
+	case "import-response":
+		return runHandoffImportResponse(args[1:])
+	default:
+		return errorf("неизвестное действие %q, доступны: export-request, respond, import-response", args[0])
+	}
+}
+
+// runHandoffExportRequest готовит запрос к офлайн-машине и показывает его
+// кадрами QR. Код сеанса (ключ MAC) выводится отдельно: оператор вводит его
+// на офлайн-машине вручную, поэтому подделать кадры через тот же канал нельзя.
+func runHandoffExportRequest(args []string) error {
+	fs := newFlagSet("handoff export-request")
+	sessionPath := fs.String("session", "handoff.json", "файл состояния сеанса (нужен import-response, содержит ключ MAC)")
+	commitmentsPath := fs.String("commitments", "", "обязательства по сидам, которые должны быть введены на офлайн-машине, по одному на строку")
+	note := fs.String("note", "", "примечание к запросу, например название церемонии")
+	text := fs.Bool("text", false, "напечатать кадры строками вместо QR-кодов")
+	sf := addSchemeFlags(fs)
+	pf := addProfileFlags(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pf.apply(fs); err != nil {
+		return err
+	}
+	params, err := sf.params(fs)
+	if err != nil {
+		return err
+	}
+	var commitments []string
+	if *commitmentsPath != "" {
+		if commitments, err = readCommitmentList(*commitmentsPath); err != nil {
+			return err
+		}
+	}
+
+	key := make([]byte, 16)
+	id := make([]byte, 4)
+	if _, err := rand.Read(key); err != nil {
+		return withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	if _, err := rand.Read(id); err != nil {
+		return withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	req := handoffRequest{
+		Kind:        "handoff-request",
+		Session:     hex.EncodeToString(id),
+		CreatedAt:   time.Now().UTC(),
+		Params:      params,
+		Commitments: commitments,
+		Note:        *note,
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(payload)
+	session := handoffSession{
+		Kind:      "handoff-session",
+		Session:   req.Session,
+		Key:       hex.EncodeToString(key),
+		Request:   hex.EncodeToString(digest[:]),
+		CreatedAt: req.CreatedAt,
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(*sessionPath, append(data, '\n'), 0600); err != nil {
+		return errorf("ошибка записи сеанса: %w", err)
+	}
+
+	frames := handoffFrames(key, req.Session, handoffToOffline, payload)
+	fmt.Fprintf(os.Stderr, tr("Сеанс %s: запрос, кадров: %d; %s\n"), req.Session, len(frames), describeParams(params))
+	fmt.Fprintf(os.Stderr, tr("Код сеанса для офлайн-машины (продиктуйте или перепишите, не сканируйте): %s\n"), formatSessionCode(key))
+	if !*text && isTerminal(os.Stdout) {
+		fmt.Fprintln(os.Stderr, tr("Запишите код и нажмите Enter, чтобы показать кадры..."))
+		in, closeInput, err := openConfirmInput()
+		if err != nil {
+			return err
+		}
+		err = readLine(in)
+		closeInput()
+		if err != nil {
+			return errorf("показ кадров прерван")
+		}
+	}
+	if err := showHandoff(frames, *text); err != nil {
+		return err
+	}
+	logInfof("✓ Запрос показан, состояние сеанса сохранено: %s\n", *sessionPath)
+	logInfof("  Ответ офлайн-машины примите командой seedgen handoff import-response\n")
+	return nil
+}
+
+// runHandoffRespond на офлайн-машине принимает запрос, проводит церемонию
+// по его параметрам, сверяет введенные сиды с обязательствами запроса
+// и показывает ответ кадрами QR
+func runHandoffRespond(args []string) error {
+	fs := newFlagSet("handoff respond")
+	text := fs.Bool("text", false, "напечатать кадры ответа строками вместо QR-кодов")
+	ssf := addSeedSourceFlags(fs)
+	rf := addRevealFlags(fs)
+	ef := addEnvFlags(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := ef.check(false); err != nil {
+		return err
+	}
+
+	in, closeInput, err := openConfirmInput()
+	if err != nil {
+		return errorf("прием кадров требует терминала: %w", err)
+	}
+	defer closeInput()
+	fmt.Print(tr("Код сеанса с машины координатора: "))
+	code, err := readAnswer(in)
+	if err != nil {
+		return errorf("ввод кода прерван")
+	}
+	key, err := parseSessionCode(code)
+	if err != nil {
+		return err
+	}
+	fmt.Println(tr("Отсканируйте кадры запроса (по одному на строку, пустая строка - конец):"))
+	a := &handoffAssembler{key: key, direction: handoffToOffline}
+	payload, err := receiveHandoff(in, os.Stdout, a)
+	if err != nil {
+		return err
+	}
+	var req handoffRequest
+	if err := json.Unmarshal(payload, &req); err != nil || req.Kind != "handoff-request" || req.Session != a.session {
+		return errorf("кадры не содержат запроса seedgen")
+	}
+	if err := req.Params.Validate(); err != nil {
+		return errorf("запрос содержит недопустимые параметры: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Printf(tr("Запрос сеанса %s от %s\n"), req.Session, req.CreatedAt.Format(time.RFC3339))
+	if req.Note != "" {
+		fmt.Printf(tr("Примечание: %s\n"), req.Note)
+	}
+	fmt.Println(describeParams(req.Params))
+	if len(req.Commitments) > 0 {
+		fmt.Printf(tr("Ожидается сидов по обязательствам: %d\n"), len(req.Commitments))
+	}
+	fmt.Println()
+
+	seeds, err := ssf.read(os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer wipeSeeds(seeds)
+	if len(seeds) == 0 {
+		return errorf("не введено ни одного сида")
+	}
+	if len(req.Commitments) > 0 {
+		if err := checkSeedCommitments(seeds, req.Commitments); err != nil {
+			return err
+		}
+		fmt.Println(tr("✓ Сиды соответствуют обязательствам запроса"))
+	}
+
+	stopProgress := showKDFProgress()
+	masterSeed, err := GenerateMasterSeed(seeds, req.Params)
+	stopProgress()
+	if err != nil {
+		return errorf("ошибка генерации: %w", err)
+	}
+	defer wipe(masterSeed)
+	printSeedCommitment(os.Stdout, masterSeed)
+	if err := rf.reveal(tr("Мастер-сид (офлайн-машина):"), masterSeed); err != nil {
+		return err
+	}
+
+	words, setDigest := seedSetCommitment(masterSeed)
+	requestDigest := sha256.Sum256(payload)
+	resp := handoffResponse{
+		Kind:          "handoff-response",
+		Session:       req.Session,
+		Request:       hex.EncodeToString(requestDigest[:]),
+		CreatedAt:     time.Now().UTC(),
+		Version:       buildVersion(),
+		Params:        req.Params,
+		SeedCount:     len(seeds),
+		Fingerprint:   masterFingerprint(masterSeed),
+		SetCommitment: strings.Join(words, " ") + "  (" + setDigest + ")",
+	}
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	frames := handoffFrames(key, req.Session, handoffToOnline, out)
+	fmt.Printf(tr("\nОтвет: кадров %d, мастер-сид в него не входит\n"), len(frames))
+	if !*text && isTerminal(os.Stdout) {
+		fmt.Print(tr("Нажмите Enter, чтобы показать кадры..."))
+		if err := readLine(in); err != nil {
+			return errorf("показ кадров прерван")
+		}
+	}
+	if err := showHandoff(frames, *text); err != nil {
+		return err
+	}
+	fmt.Printf(tr("✓ Ответ сеанса %s показан, отпечаток мастер-сида %s\n"), req.Session, emph(resp.Fingerprint))
+	return nil
+}
+
+// runHandoffImportResponse принимает кадры ответа на машине с сетью,
+// сверяет их с сохраненным сеансом и выводит результат
+func runHandoffImportResponse(args []string) error {
+	fs := newFlagSet("handoff import-response")
+	sessionPath := fs.String("session", "handoff.json", "файл состояния сеанса из export-request")
+	out := fs.String("out", "", "сохранить ответ офлайн-машины в JSON")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(*sessionPath)
+	if err != nil {
+		return err
+	}
+	var session handoffSession
+	if err := json.Unmarshal(data, &session); err != nil || session.Kind != "handoff-session" {
+		return errorf("%s: не файл сеанса handoff", *sessionPath)
+	}
+	key, err := hex.DecodeString(session.Key)
+	if err != nil || len(key) != 16 {
+		return errorf("%s: некорректный ключ сеанса", *sessionPath)
+	}
+
+	if isTerminal(os.Stdin) {
+		fmt.Fprintln(os.Stderr, tr("Отсканируйте кадры ответа (по одному на строку, пустая строка - конец):"))
+	}
+	a := &handoffAssembler{key: key, session: session.Session, direction: handoffToOnline}
+	payload, err := receiveHandoff(os.Stdin, os.Stderr, a)
+	if err != nil {
+		return err
+	}
+	var resp handoffResponse
+	if err := json.Unmarshal(payload, &resp); err != nil || resp.Kind != "handoff-response" {
+		return errorf("кадры не содержат ответа seedgen")
+	}
+	if resp.Session != session.Session || resp.Request != session.Request {
+		return errorf("ответ относится к другому запросу")
+	}
+
+	fmt.Printf(tr("Сеанс %s, ответ от %s (seedgen %s)\n"), resp.Session, resp.CreatedAt.Format(time.RFC3339), resp.Version)
+	fmt.Println(describeParams(resp.Params))
+	fmt.Printf(tr("Сидов: %d\n"), resp.SeedCount)
+	fmt.Printf(tr("Обязательство набора сидов: %s\n"), resp.SetCommitment)
+	fmt.Printf(tr("SHA-512 хеш мастер-сида: %s\n"), resp.Fingerprint)
+	if *out != "" {
+		indented, err := json.MarshalIndent(resp, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeNewFile(*out, append(indented, '\n'), 0644); err != nil {
+			return err
+		}
+		fmt.Printf(tr("✓ Ответ сохранен: %s\n"), *out)
+	}
+	fmt.Println(tr("✓ Ответ подлинный и относится к запросу этого сеанса"))
+	return nil
+}
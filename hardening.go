@@ -0,0 +1,63 @@
+package main
+
+import "strings"
+
+// allowCoreDumpsFlag - общий флаг, отключающий запрет дампов памяти для отладки
+const allowCoreDumpsFlag = "allow-core-dumps"
+
+// stripGlobalFlag удаляет булев флаг --name (или -name) из аргументов
+// любой команды до разделителя "--" и сообщает, был ли он указан
+func stripGlobalFlag(args []string, name string) ([]string, bool) {
+	out := make([]string, 0, len(args))
+	found := false
+	for i, arg := range args {
+		if arg == "--" {
+			out = append(out, args[i:]...)
+			break
+		}
+		if arg == "--"+name || arg == "-"+name {
+			found = true
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out, found
+}
+
+// stripGlobalValue удаляет флаг со значением --name <значение> (или
+// --name=<значение>) из аргументов до разделителя "--" и возвращает
+// значение, если флаг указан
+func stripGlobalValue(args []string, name string) ([]string, string, bool) {
+	out := make([]string, 0, len(args))
+	value, found := "", false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			out = append(out, args[i:]...)
+			break
+		}
+		switch {
+		case arg == "--"+name || arg == "-"+name:
+			found = true
+			if i+1 < len(args) {
+				value = args[i+1]
+				i++
+			}
+			continue
+		case strings.HasPrefix(arg, "--"+name+"=") || strings.HasPrefix(arg, "-"+name+"="):
+			value, found = arg[strings.Index(arg, "=")+1:], true
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out, value, found
+}
+
+// hardenProcess запрещает запись дампов памяти и подключение отладчика,
+// чтобы авария во время церемонии не сохранила секреты в core-файл.
+// Ошибка не останавливает работу, а выводится предупреждением.
+func hardenProcess() {
+	if err := disableCoreDumps(); err != nil {
+		logWarnf("⚠ Не удалось запретить дампы памяти: %v\n", err)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// darwinTraced - флаг P_TRACED процесса в ядре XNU
+const darwinTraced = 0x800
+
+// disableCoreDumps обнуляет RLIMIT_CORE и запрещает подключение отладчика
+// (PT_DENY_ATTACH): уже подключенный отладчик завершит процесс
+func disableCoreDumps() error {
+	if err := unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{Cur: 0, Max: 0}); err != nil {
+		return err
+	}
+	return unix.PtraceDenyAttach()
+}
+
+// debuggerAttached сообщает, что процесс трассируется (флаг P_TRACED)
+func debuggerAttached() bool {
+	info, err := unix.SysctlKinfoProc("kern.proc.pid", os.Getpid())
+	return err == nil && info.Proc.P_flag&darwinTraced != 0
+}
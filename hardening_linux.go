@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// disableCoreDumps обнуляет RLIMIT_CORE и снимает флаг dumpable процесса:
+// ядро не пишет core-файл, а процессы того же пользователя не могут
+// подключиться через ptrace или читать /proc/<pid>/mem
+func disableCoreDumps() error {
+	if err := unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{Cur: 0, Max: 0}); err != nil {
+		return err
+	}
+	return unix.Prctl(unix.PR_SET_DUMPABLE, 0, 0, 0, 0)
+}
+
+// debuggerAttached сообщает, что к процессу подключен трассировщик (TracerPid)
+func debuggerAttached() bool {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if pid := strings.TrimPrefix(scanner.Text(), "TracerPid:"); pid != scanner.Text() {
+			return strings.TrimSpace(pid) != "0"
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package main
+
+// disableCoreDumps на остальных платформах не поддерживается
+func disableCoreDumps() error {
+	return errorf("платформа не поддерживается")
+}
+
+// debuggerAttached на остальных платформах не определяется
+func debuggerAttached() bool {
+	return false
+}
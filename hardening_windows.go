@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// disableCoreDumps проверяет, что аварии не передаются в Windows Error
+// Reporting. Среда выполнения Go сама отключает WER, если только
+// GOTRACEBACK=wer не просит обратного, - тогда WER может сохранить дамп.
+func disableCoreDumps() error {
+	if os.Getenv("GOTRACEBACK") == "wer" {
+		return errorf("GOTRACEBACK=wer передает аварии в Windows Error Reporting, который может сохранить дамп")
+	}
+	return nil
+}
+
+// debuggerAttached сообщает, что к процессу подключен отладчик (IsDebuggerPresent)
+func debuggerAttached() bool {
+	present, _, _ := syscall.NewLazyDLL("kernel32.dll").NewProc("IsDebuggerPresent").Call()
+	return present != 0
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"math/big"
+
+	"golang.org/x/crypto/ripemd160"
+)
+
+// hdCurve - кривая иерархического вывода SLIP-0010. Для secp256k1
+// SLIP-0010 совпадает с BIP32.
+type hdCurve struct {
+	name string
+	// seedKey - ключ HMAC, которым мастер-ключ выводится из сида
+	seedKey string
+	// hardenedOnly запрещает обычные индексы: у ed25519 нет вывода
+	// дочернего ключа из открытого
+	hardenedOnly bool
+}
+
+// Поддерживаемые кривые
+var (
+	curveSecp256k1 = &hdCurve{name: "secp256k1", seedKey: "Bitcoin seed"}
+	curveEd25519   = &hdCurve{name: "ed25519", seedKey: "ed25519 seed", hardenedOnly: true}
+)
+
+// hdKey - закрытый расширенный ключ BIP32/SLIP-0010
+type hdKey struct {
+	curve     *hdCurve
+	key       []byte // 32 байта
+	chainCode []byte
+	depth     byte
+	parent    [4]byte
+	index     uint32
+}
+
+// hash160 вычисляет RIPEMD-160(SHA-256(data))
+func hash160(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	h := ripemd160.New()
+	h.Write(sum[:])
+	return h.Sum(nil)
+}
+
+// newHDKey создает мастер-ключ кривой из сида
+func newHDKey(curve *hdCurve, seed []byte) (*hdKey, error) {
+	mac := hmac.New(sha512.New, []byte(curve.seedKey))
+	mac.Write(seed)
+	I := mac.Sum(nil)
+	if curve == curveSecp256k1 && !validSecpScalar(new(big.Int).SetBytes(I[:32])) {
+		return nil, errorf("сид дает недопустимый мастер-ключ BIP32")
+	}
+	return &hdKey{curve: curve, key: I[:32], chainCode: I[32:]}, nil
+}
+
+// deriveHDKey выводит ключ кривой из сида по пути
+func deriveHDKey(curve *hdCurve, seed []byte, path derivationPath) (*hdKey, error) {
+	root, err := newHDKey(curve, seed)
+	if err != nil {
+		return nil, err
+	}
+	return root.derivePath(path)
+}
+
+// publicKey возвращает открытый ключ в виде SLIP-0010: сжатая точка
+// secp256k1 или 0x00 и открытый ключ Ed25519 (33 байта в обоих случаях)
+func (k *hdKey) publicKey() []byte {
+	if k.curve == curveEd25519 {
+		return append([]byte{0}, k.ed25519().Public().(ed25519.PublicKey)...)
+	}
+	return secpScalarBaseMult(k.key).compressed()
+}
+
+// ed25519 возвращает закрытый ключ Ed25519 для ключа кривой ed25519
+func (k *hdKey) ed25519() ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(k.key)
+}
+
+// fingerprint возвращает первые 4 байта hash160 открытого ключа
+func (k *hdKey) fingerprint() [4]byte {
+	var fp [4]byte
+	copy(fp[:], hash160(k.publicKey()))
+	return fp
+}
+
+// child выводит дочерний закрытый ключ с индексом index
+func (k *hdKey) child(index uint32) (*hdKey, error) {
+	if k.curve.hardenedOnly && index < hardenedOffset {
+		return nil, errorf("%s поддерживает только усиленные индексы, укажите %d'", k.curve.name, index)
+	}
+	data := make([]byte, 0, 37)
+	if index >= hardenedOffset {
+		data = append(data, 0)
+		data = append(data, k.key...)
+	} else {
+		data = append(data, k.publicKey()...)
+	}
+	var ser [4]byte
+	binary.BigEndian.PutUint32(ser[:], index)
+	data = append(data, ser[:]...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	I := mac.Sum(nil)
+
+	next := &hdKey{
+		curve:     k.curve,
+		key:       I[:32],
+		chainCode: I[32:],
+		depth:     k.depth + 1,
+		parent:    k.fingerprint(),
+		index:     index,
+	}
+	if k.curve == curveEd25519 {
+		return next, nil
+	}
+
+	il := new(big.Int).SetBytes(I[:32])
+	child := new(big.Int).Add(il, new(big.Int).SetBytes(k.key))
+	child.Mod(child, secpN)
+	// Вероятность меньше 2^-127, но BIP32 требует перейти к следующему индексу
+	if il.Cmp(secpN) >= 0 || child.Sign() == 0 {
+		return nil, errorf("индекс %d дает недопустимый ключ, используйте следующий", index)
+	}
+	next.key = child.FillBytes(make([]byte, 32))
+	return next, nil
+}
+
+// derivePath выводит ключ по пути относительно текущего ключа
+func (k *hdKey) derivePath(path derivationPath) (*hdKey, error) {
+	key := k
+	for _, index := range path {
+		var err error
+		if key, err = key.child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// serialize кодирует ключ secp256k1 в Base58Check с версией xprv/xpub и т. п.
+// Для ed25519 общепринятой сериализации нет.
+func (k *hdKey) serialize(version uint32, private bool) string {
+	buf := make([]byte, 0, 78)
+	var ser [4]byte
+	binary.BigEndian.PutUint32(ser[:], version)
+	buf = append(buf, ser[:]...)
+	buf = append(buf, k.depth)
+	buf = append(buf, k.parent[:]...)
+	binary.BigEndian.PutUint32(ser[:], k.index)
+	buf = append(buf, ser[:]...)
+	buf = append(buf, k.chainCode...)
+	if private {
+		buf = append(buf, 0)
+		buf = append(buf, k.key...)
+	} else {
+		buf = append(buf, k.publicKey()...)
+	}
+	return base58CheckEncode(buf)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// hardenedOffset отмечает усиленные индексы пути вывода
+const hardenedOffset uint32 = 0x80000000
+
+// hardened возвращает усиленный индекс i'
+func hardened(i uint32) uint32 {
+	return i + hardenedOffset
+}
+
+// derivationPath - путь иерархического вывода BIP32/SLIP-0010:
+// последовательность индексов от мастер-ключа
+type derivationPath []uint32
+
+// parseDerivationPath разбирает путь вида m/44'/0'/0'/0/0 (допускается и 44h)
+func parseDerivationPath(path string) (derivationPath, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if parts[0] != "m" {
+		return nil, errorf("путь %q должен начинаться с m", path)
+	}
+
+	indexes := make(derivationPath, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		isHardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") || strings.HasSuffix(part, "H")
+		digits := part
+		if isHardened {
+			digits = part[:len(part)-1]
+		}
+		n, err := strconv.ParseUint(digits, 10, 32)
+		if err != nil || uint32(n) >= hardenedOffset || digits == "" || digits[0] == '+' {
+			return nil, errorf("некорректный элемент пути %q", part)
+		}
+		index := uint32(n)
+		if isHardened {
+			index = hardened(index)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// String записывает путь в каноническом виде с апострофами
+func (p derivationPath) String() string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, i := range p {
+		if i >= hardenedOffset {
+			fmt.Fprintf(&b, "/%d'", i-hardenedOffset)
+		} else {
+			fmt.Fprintf(&b, "/%d", i)
+		}
+	}
+	return b.String()
+}
+
+// child возвращает новый путь с дополнительными индексами; исходный не меняется
+func (p derivationPath) child(indexes ...uint32) derivationPath {
+	out := make(derivationPath, 0, len(p)+len(indexes))
+	out = append(out, p...)
+	return append(out, indexes...)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// langFlag - общий флаг языка сообщений, действует для любой команды
+const langFlag = "lang"
+
+// languages - поддерживаемые языки сообщений; первый используется по умолчанию
+var languages = []string{"ru", "en"}
+
+// lang - язык сообщений текущего запуска, выбирается в main до выполнения команды
+var lang = "ru"
+
+// catalogEN - английский каталог сообщений в формате gettext PO. Ключ
+// (msgid) - исходный русский текст, поэтому сообщения без перевода
+// выводятся по-русски.
+//
+//go:embed locales/en.po
+var catalogEN string
+
+// messages - загруженный каталог языка lang; для русского пуст
+var messages map[string]string
+
+// envLanguage определяет язык по переменным окружения в порядке POSIX:
+// LC_ALL, LC_MESSAGES, LANG. Значения C и POSIX язык не задают.
+func envLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		if v == "C" || v == "POSIX" || strings.HasPrefix(v, "C.") {
+			return ""
+		}
+		code := strings.ToLower(v)
+		if i := strings.IndexAny(code, "_.@-"); i >= 0 {
+			code = code[:i]
+		}
+		for _, l := range languages {
+			if code == l {
+				return l
+			}
+		}
+		// Первая непустая переменная решает, как в setlocale
+		return ""
+	}
+	return ""
+}
+
+// setLanguage выбирает язык сообщений: флаг --lang важнее окружения
+func setLanguage(flagValue string, fromFlag bool) error {
+	chosen := languages[0]
+	if fromFlag {
+		chosen = ""
+		for _, l := range languages {
+			if flagValue == l {
+				chosen = l
+			}
+		}
+		if chosen == "" {
+			return fmt.Errorf("неизвестный язык %q, доступны: %s", flagValue, strings.Join(languages, ", "))
+		}
+	} else if l := envLanguage(); l != "" {
+		chosen = l
+	}
+	lang = chosen
+	messages = nil
+	if chosen == "en" {
+		m, err := parsePO(catalogEN)
+		if err != nil {
+			return fmt.Errorf("встроенный каталог en: %w", err)
+		}
+		messages = m
+	}
+	return nil
+}
+
+// parsePO разбирает каталог gettext: пары msgid/msgstr со строками в
+// кавычках, необязательный msgctxt, продолжения на следующих строках и
+// комментарии #. Пустой msgstr означает, что перевода нет.
+func parsePO(data string) (map[string]string, error) {
+	m := make(map[string]string)
+	var msgctxt, msgid, msgstr string
+	var cur *string
+	flush := func() {
+		if msgid != "" && msgstr != "" {
+			if msgctxt != "" {
+				msgid = msgctxt + poContextSep + msgid
+			}
+			m[msgid] = msgstr
+		}
+		msgctxt, msgid, msgstr, cur = "", "", "", nil
+	}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "msgctxt "):
+			flush()
+			line, cur = strings.TrimPrefix(line, "msgctxt "), &msgctxt
+		case strings.HasPrefix(line, "msgid "):
+			if cur != &msgctxt {
+				flush()
+			}
+			line, cur = strings.TrimPrefix(line, "msgid "), &msgid
+		case strings.HasPrefix(line, "msgstr "):
+			if cur != &msgid {
+				return nil, fmt.Errorf("строка %d: msgstr без msgid", n)
+			}
+			line, cur = strings.TrimPrefix(line, "msgstr "), &msgstr
+		case strings.HasPrefix(line, `"`):
+			if cur == nil {
+				return nil, fmt.Errorf("строка %d: продолжение без msgid", n)
+			}
+		default:
+			return nil, fmt.Errorf("строка %d: неизвестная запись", n)
+		}
+		s, err := strconv.Unquote(line)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: некорректная строка в кавычках", n)
+		}
+		*cur += s
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return m, nil
+}
+
+// poContextSep отделяет контекст от сообщения в ключе каталога, как в gettext
+const poContextSep = "\x04"
+
+// trc переводит сообщение с контекстом: одно и то же короткое слово
+// в разных местах может переводиться по-разному
+func trc(context, s string) string {
+	if t, ok := messages[context+poContextSep+s]; ok {
+		return t
+	}
+	return s
+}
+
+// tr возвращает перевод сообщения на язык запуска или исходный текст,
+// если перевода нет
+func tr(s string) string {
+	if t, ok := messages[s]; ok {
+		return t
+	}
+	return s
+}
+
+// localizedError - ошибка, которую сформулировал seedgen, с текстом на
+// языке запуска; цепочка %w сохраняется для errors.Is и errors.As, а по
+// типу classifyError отличает собственные ошибки от ошибок других пакетов
+type localizedError struct {
+	msg string
+	err error
+}
+
+func (e *localizedError) Error() string { return e.msg }
+func (e *localizedError) Unwrap() error { return errors.Unwrap(e.err) }
+
+// errorf создает ошибку как fmt.Errorf, но с текстом на языке запуска.
+// Вложенные ошибки других пакетов тоже ищутся в каталоге, так что
+// переводятся и сигнальные ошибки вроде errHandoffConflict.
+func errorf(format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	if messages == nil {
+		return &localizedError{msg: err.Error(), err: err}
+	}
+	targs := make([]interface{}, len(args))
+	for i, a := range args {
+		if e, ok := a.(error); ok {
+			if _, local := e.(*localizedError); !local {
+				a = tr(e.Error())
+			}
+		}
+		targs[i] = a
+	}
+	return &localizedError{msg: fmt.Sprintf(strings.ReplaceAll(tr(format), "%w", "%v"), targs...), err: err}
+}
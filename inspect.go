@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// fieldLabels задает подписи известных полей артефактов
+var fieldLabels = map[string]string{
+	"kind":               "Тип",
+	"scheme":             "Схема",
+	"kdf":                "KDF",
+	"iterations":         "Итерации",
+	"salt":               "Соль",
+	"epoch":              "Эпоха",
+	"memory":             "Память Argon2id, КиБ",
+	"parallelism":        "Потоки Argon2id",
+	"kdf_preset":         "Пресет KDF",
+	"from_epoch":         "Исходная эпоха",
+	"to_epoch":           "Новая эпоха",
+	"paths":              "Пути",
+	"seed_count":         "Количество сидов",
+	"nonce":              "Нонс",
+	"drand":              "Маяк drand",
+	"chain_hash":         "Цепочка",
+	"round":              "Раунд",
+	"randomness":         "Случайность",
+	"nist_pulse":         "Импульс NIST",
+	"master":             "Мастер-сид",
+	"fingerprint":        "Отпечаток",
+	"created_at":         "Создан",
+	"keys":               "Ключи",
+	"master_fingerprint": "Отпечаток мастер-сида",
+}
+
+// printArtifact выводит поля артефакта, скрывая секретные значения
+func printArtifact(w io.Writer, data []byte, reveal bool, indent string) error {
+	return printFields(w, data, "", reveal, indent)
+}
+
+// printFields выводит поля объекта; kind - тип артефакта, которому
+// принадлежит объект, для вложенных объектов - тип внешнего
+func printFields(w io.Writer, data []byte, kind string, reveal bool, indent string) error {
+	fields, err := orderedFields(data)
+	if err != nil {
+		return errorf("не удалось разобрать артефакт: %w", err)
+	}
+	for _, f := range fields {
+		if f.key == "kind" && kind == "" {
+			json.Unmarshal(f.value, &kind)
+		}
+	}
+
+	for _, f := range fields {
+		label := tr(fieldLabels[f.key])
+		if label == "" {
+			label = f.key
+		}
+
+		trimmed := bytes.TrimSpace(f.value)
+		switch {
+		case isSecretField(kind, f.key) && !reveal:
+			fmt.Fprintf(w, tr("%s%s: [скрыто, используйте --reveal]\n"), indent, label)
+		case len(trimmed) > 0 && trimmed[0] == '{':
+			fmt.Fprintf(w, "%s%s:\n", indent, label)
+			if err := printFields(w, trimmed, kind, reveal, indent+"  "); err != nil {
+				return err
+			}
+		case len(trimmed) > 0 && trimmed[0] == '[' && hasObjects(trimmed):
+			// Массив объектов выводим поэлементно, чтобы скрыть секретные
+			// поля внутри элементов, например адресов BTC
+			var items []json.RawMessage
+			json.Unmarshal(trimmed, &items)
+			fmt.Fprintf(w, "%s%s:\n", indent, label)
+			for i, item := range items {
+				item = bytes.TrimSpace(item)
+				if len(item) == 0 || item[0] != '{' {
+					fmt.Fprintf(w, "%s  [%d]: %s\n", indent, i, item)
+					continue
+				}
+				fmt.Fprintf(w, "%s  [%d]:\n", indent, i)
+				if err := printFields(w, item, kind, reveal, indent+"    "); err != nil {
+					return err
+				}
+			}
+		default:
+			// Строки выводим без кавычек, остальное - как в JSON
+			var s string
+			if json.Unmarshal(trimmed, &s) == nil {
+				fmt.Fprintf(w, "%s%s: %s\n", indent, label, s)
+			} else {
+				fmt.Fprintf(w, "%s%s: %s\n", indent, label, trimmed)
+			}
+		}
+	}
+	return nil
+}
+
+// hasObjects сообщает, что JSON-массив содержит хотя бы один объект
+func hasObjects(data []byte) bool {
+	var items []json.RawMessage
+	if json.Unmarshal(data, &items) != nil {
+		return false
+	}
+	for _, item := range items {
+		if item = bytes.TrimSpace(item); len(item) > 0 && item[0] == '{' {
+			return true
+		}
+	}
+	return false
+}
+
+// knownArtifact сообщает, что JSON-объект похож на артефакт seedgen: в нем
+// есть kind, секретное поле, поле с подписью или provenance. Ошибка разбора
+// не скрывается: ее покажет printArtifact.
+func knownArtifact(data []byte) bool {
+	fields, err := orderedFields(data)
+	if err != nil {
+		return true
+	}
+	for _, f := range fields {
+		if f.key == "provenance" || secretFields[f.key] || fieldLabels[f.key] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// textArtifactPrefix возвращает префикс текстового артефакта - доли ss1,
+// копии rs1 или XOR-доли xs1 - по первой строке, которая с него начинается;
+// лист комплекта содержит пояснения до записи доли
+func textArtifactPrefix(data []byte) string {
+	if len(data) > 0 && (data[0] == '{' || data[0] == '[') {
+		return ""
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		for _, prefix := range []string{sharePrefix, rsBackupPrefix, seedXORPrefix} {
+			if hasPrefixFold(line, prefix) {
+				return prefix
+			}
+		}
+	}
+	return ""
+}
+
+// printTextArtifact выводит заголовок, отпечаток и результат проверки
+// текстового артефакта; сами байты доли или копии выводятся только с --reveal
+func printTextArtifact(w io.Writer, data []byte, prefix string, reveal bool) error {
+	lockSecret(data)
+	defer wipe(data)
+	switch prefix {
+	case sharePrefix:
+		shares, err := parseShares(data)
+		if err != nil {
+			return err
+		}
+		if len(shares) == 0 {
+			return errorf("доля не найдена: запись доли начинается со строки %s", sharePrefix)
+		}
+		for i, s := range shares {
+			defer wipe(s.data)
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			set := tr("хранители")
+			switch s.set {
+			case shareSetExecutor:
+				set = tr("исполнители")
+			case shareSetActivation:
+				set = tr("ключ активации")
+			}
+			fmt.Fprintf(w, tr("Тип: доля мастер-сида (%s)\n"), sharePrefix)
+			fmt.Fprintf(w, tr("Заголовок: %s\n"), s.header())
+			fmt.Fprintf(w, tr("Порог: %d из %d\n"), s.threshold, s.total)
+			fmt.Fprintf(w, tr("Номер доли: %d\n"), s.index)
+			fmt.Fprintf(w, tr("Набор: %s\n"), set)
+			fmt.Fprintf(w, tr("Отпечаток мастер-сида: %s\n"), s.fingerprint)
+			printTextPayload(w, tr("Доля"), s.data, reveal)
+			fmt.Fprintf(w, tr("✓ Контроль %s совпадает\n"), s.check())
+		}
+		return nil
+	case seedXORPrefix:
+		shares, err := parseSeedXORShares(data)
+		if err != nil {
+			return err
+		}
+		if len(shares) == 0 {
+			return errorf("доли не найдены: запись доли начинается со строки %s", seedXORPrefix)
+		}
+		for i, s := range shares {
+			defer wipe(s.data)
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, tr("Тип: XOR-доля сида устройства (%s)\n"), seedXORPrefix)
+			fmt.Fprintf(w, tr("Заголовок: %s\n"), s.header())
+			fmt.Fprintf(w, tr("Номер доли: %d из %d\n"), s.index, s.total)
+			fmt.Fprintf(w, tr("Разделение: %s\n"), s.id)
+			printTextPayload(w, tr("Доля"), s.data, reveal)
+			fmt.Fprintf(w, tr("✓ Контроль %s совпадает\n"), s.check())
+		}
+		return nil
+	}
+
+	// Копия rs1 может начинаться не с первой строки файла
+	start := bytes.Index(bytes.ToLower(data), []byte(rsBackupPrefix))
+	code, erasures, err := parseRSBackup(data[start:])
+	if err != nil {
+		return err
+	}
+	defer wipe(code)
+	fixed, err := rsDecode(code, rsParity, erasures)
+	if err != nil {
+		return errorf("копию не удалось восстановить: %w (исправимо 2·ошибки + нечитаемые байты <= %d)", err, rsParity)
+	}
+	master := code[:masterSeedSize]
+	fmt.Fprintf(w, tr("Тип: бумажная копия мастер-сида (%s)\n"), rsBackupPrefix)
+	fmt.Fprintf(w, tr("Проверочных байт: %d\n"), rsParity)
+	fmt.Fprintf(w, tr("Отпечаток мастер-сида: %s\n"), masterFingerprint(master))
+	printTextPayload(w, tr("Мастер-сид"), master, reveal)
+	if len(fixed) == 0 {
+		fmt.Fprintln(w, tr("✓ Копия прочитана без ошибок"))
+	} else {
+		fmt.Fprintf(w, tr("⚠ Исправлено байт: %d, перепишите копию заново\n"), len(fixed))
+	}
+	return nil
+}
+
+// printTextPayload выводит секретные байты текстового артефакта в hex или
+// отметку о скрытии
+func printTextPayload(w io.Writer, label string, data []byte, reveal bool) {
+	if !reveal {
+		fmt.Fprintf(w, tr("%s%s: [скрыто, используйте --reveal]\n"), "", label)
+		return
+	}
+	h := hexSecret(data)
+	defer wipe(h)
+	fmt.Fprintf(w, "%s: %s\n", label, h)
+}
+
+// checkFingerprint сверяет отпечаток артефакта с его мастер-сидом
+func checkFingerprint(data []byte) (checked bool, ok bool) {
+	var rec struct {
+		Master      string `json:"master"`
+		Fingerprint string `json:"fingerprint"`
+	}
+	if json.Unmarshal(data, &rec) != nil || rec.Master == "" || rec.Fingerprint == "" {
+		return false, false
+	}
+	return true, secretEqualString(shortFingerprint(rec.Master), rec.Fingerprint)
+}
+
+// runInspect выводит метаданные артефакта без раскрытия секретов
+func runInspect(args []string) error {
+	fs := newFlagSet("inspect")
+	reveal := fs.Bool("reveal", false, "показать секретные значения")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errorf("укажите файл артефакта, \"-\" для stdin или строку msv2:...")
+	}
+
+	ref := positional[0]
+	data, err := readArtifact(ref)
+	if err != nil {
+		return err
+	}
+
+	source := ref
+	if strings.HasPrefix(ref, msv2Prefix) {
+		source = tr("строка msv2")
+	}
+	if prefix := textArtifactPrefix(data); prefix != "" {
+		fmt.Printf(tr("=== Артефакт: %s ===\n\n"), source)
+		return printTextArtifact(os.Stdout, data, prefix, *reveal)
+	}
+	if !knownArtifact(data) {
+		return errorf("неизвестный артефакт: нет поля kind и известных полей")
+	}
+	fmt.Printf(tr("=== Артефакт: %s ===\n\n"), source)
+	if err := printArtifact(os.Stdout, data, *reveal, ""); err != nil {
+		return err
+	}
+
+	if checked, ok := checkFingerprint(data); checked {
+		fmt.Println()
+		if !ok {
+			return mismatchf("отпечаток не соответствует мастер-сиду, артефакт поврежден или изменен")
+		}
+		fmt.Println(tr("✓ Отпечаток соответствует мастер-сиду"))
+	}
+	return nil
+}
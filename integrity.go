@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// integrityMagic завершает бинарник, запечатанный манифестом целостности.
+// Перед ним записаны JSON манифеста и его длина (4 байта, big-endian).
+const integrityMagic = "\nseedgen-integrity-manifest-v1\n"
+
+// integrityManifest - хэш исполняемой части бинарника, дописываемый
+// в его конец при сборке командой integrity seal
+type integrityManifest struct {
+	Kind   string `json:"kind"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// splitSealed отделяет исполняемую часть бинарника от манифеста.
+// Для незапечатанного бинарника манифест равен nil.
+func splitSealed(data []byte) ([]byte, *integrityManifest, error) {
+	if !bytes.HasSuffix(data, []byte(integrityMagic)) {
+		return data, nil, nil
+	}
+	rest := data[:len(data)-len(integrityMagic)]
+	if len(rest) < 4 {
+		return nil, nil, mismatchf("манифест целостности поврежден")
+	}
+	n := int64(binary.BigEndian.Uint32(rest[len(rest)-4:]))
+	rest = rest[:len(rest)-4]
+	if n > int64(len(rest)) {
+		return nil, nil, mismatchf("манифест целостности поврежден")
+	}
+	body, raw := rest[:int64(len(rest))-n], rest[int64(len(rest))-n:]
+	var m integrityManifest
+	if err := json.Unmarshal(raw, &m); err != nil || m.Kind != "integrity-manifest" {
+		return nil, nil, mismatchf("манифест целостности поврежден")
+	}
+	return body, &m, nil
+}
+
+// sealBinary дописывает манифест целостности в конец бинарника. Файл
+// заменяется переименованием: запущенный бинарник нельзя открыть на запись.
+func sealBinary(path string) (*integrityManifest, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, m, err := splitSealed(data); err != nil || m != nil {
+		return nil, errorf("%s уже запечатан", path)
+	}
+
+	sum := sha256.Sum256(data)
+	m := &integrityManifest{Kind: "integrity-manifest", Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(raw)))
+	sealed := append(append(append(data, raw...), length[:]...), integrityMagic...)
+
+	tmp := path + ".tmp"
+	if err := writeNewFile(tmp, sealed, info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return m, nil
+}
+
+// executablePath возвращает путь к запущенному бинарнику без символических ссылок
+func executablePath() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return exe, nil
+}
+
+// verifySelf сверяет запущенный бинарник с его манифестом целостности.
+// Для незапечатанного бинарника возвращает nil без ошибки.
+func verifySelf() (*integrityManifest, error) {
+	exe, err := executablePath()
+	if err != nil {
+		return nil, errorf("не удалось найти бинарник для проверки целостности: %w", err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		return nil, errorf("не удалось прочитать бинарник для проверки целостности: %w", err)
+	}
+	body, m, err := splitSealed(data)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	if int64(len(body)) != m.Size || !secretEqualString(hex.EncodeToString(sum[:]), m.SHA256) {
+		return nil, mismatchf("бинарник изменен после сборки: SHA-256 %s не совпадает с манифестом %s", shortHash(hex.EncodeToString(sum[:])), shortHash(m.SHA256))
+	}
+	return m, nil
+}
+
+// shortHash сокращает hex-хэш для вывода оператору
+func shortHash(h string) string {
+	if len(h) > 16 {
+		return h[:16] + "..."
+	}
+	return h
+}
+
+// runIntegrity запечатывает бинарник или проверяет его целостность
+func runIntegrity(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, tr("Использование:"))
+		fmt.Fprintln(os.Stderr, "  seedgen integrity seal seedgen")
+		fmt.Fprintln(os.Stderr, "  seedgen integrity verify [--minisign-key release.pub] [--signature seedgen.minisig]")
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return errorf("укажите действие: seal или verify")
+	}
+
+	switch args[0] {
+	case "seal":
+		return runIntegritySeal(args[1:])
+	case "verify":
+		return runIntegrityVerify(args[1:])
+	default:
+		return errorf("неизвестное действие %q, доступны: seal, verify", args[0])
+	}
+}
+
+// runIntegritySeal дописывает манифест целостности в собранный бинарник
+func runIntegritySeal(args []string) error {
+	fs := newFlagSet("integrity seal")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errorf("укажите файл бинарника")
+	}
+	m, err := sealBinary(positional[0])
+	if err != nil {
+		return errorf("ошибка запечатывания: %w", err)
+	}
+	fmt.Printf(tr("✓ %s запечатан, SHA-256: %s\n"), positional[0], m.SHA256)
+	return nil
+}
+
+// runIntegrityVerify проверяет запущенный бинарник по манифесту
+// и, если указан ключ, отделенную подпись minisign файла на диске
+func runIntegrityVerify(args []string) error {
+	fs := newFlagSet("integrity verify")
+	keyRef := fs.String("minisign-key", "", "открытый ключ minisign: файл или строка base64")
+	sigPath := fs.String("signature", "", "файл подписи minisign (по умолчанию <бинарник>.minisig)")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	exe, err := executablePath()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(tr("=== Целостность бинарника: %s ===\n\n"), exe)
+	m, err := verifySelf()
+	if err != nil {
+		return err
+	}
+	if m != nil {
+		fmt.Printf(tr("✓ Бинарник совпадает с манифестом, SHA-256: %s\n"), m.SHA256)
+	} else {
+		fmt.Println(tr("⚠ Бинарник не запечатан манифестом целостности (seedgen integrity seal при сборке)"))
+	}
+
+	if *keyRef == "" {
+		if m == nil {
+			return errorf("проверить нечего: бинарник не запечатан, а ключ minisign не указан")
+		}
+		return nil
+	}
+	pub, err := readMinisignPublicKey(*keyRef)
+	if err != nil {
+		return err
+	}
+	if *sigPath == "" {
+		*sigPath = exe + ".minisig"
+	}
+	sig, err := os.ReadFile(*sigPath)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		return err
+	}
+	trusted, err := verifyMinisign(pub, data, string(sig))
+	if err != nil {
+		return errorf("подпись minisign %s: %w", *sigPath, err)
+	}
+	fmt.Printf(tr("✓ Подпись minisign действительна, ключ %s\n"), pub.id())
+	fmt.Printf(tr("  Доверенный комментарий: %s\n"), trusted)
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// k8sSecretFormat - формат вывода результата манифестом Secret Kubernetes
+const k8sSecretFormat = "k8s-secret"
+
+// k8sNameRE - имя объекта Kubernetes (поддомен DNS-1123), k8sNamespaceRE -
+// имя пространства имен (метка DNS-1123)
+var (
+	k8sNameRE      = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	k8sNamespaceRE = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+// k8sSecretFlags - флаги манифеста Secret
+type k8sSecretFlags struct {
+	name       *string
+	namespace  *string
+	kubeseal   *bool
+	sealedCert *string
+}
+
+// addK8sSecretFlags регистрирует флаги манифеста Secret в наборе
+func addK8sSecretFlags(fs *flag.FlagSet) *k8sSecretFlags {
+	return &k8sSecretFlags{
+		name:       fs.String("secret-name", "master-seed", "имя Secret для --format k8s-secret"),
+		namespace:  fs.String("namespace", "", "пространство имен Secret для --format k8s-secret"),
+		kubeseal:   fs.Bool("kubeseal", false, "зашифровать Secret командой kubeseal в SealedSecret (сертификат контроллера из текущего контекста kubectl)"),
+		sealedCert: fs.String("kubeseal-cert", "", "зашифровать Secret в SealedSecret открытым сертификатом контроллера из файла, без обращения к кластеру"),
+	}
+}
+
+// sealed сообщает, что Secret шифруется в SealedSecret
+func (kf *k8sSecretFlags) sealed() bool {
+	return *kf.kubeseal || *kf.sealedCert != ""
+}
+
+// check до ввода сидов проверяет имена и доступность kubeseal
+func (kf *k8sSecretFlags) check(fs *flag.FlagSet, format string) error {
+	if format != k8sSecretFormat {
+		set := setFlags(fs)
+		for _, name := range []string{"secret-name", "namespace", "kubeseal", "kubeseal-cert"} {
+			if set[name] {
+				return errorf("флаг --%s применим только к --format %s", name, k8sSecretFormat)
+			}
+		}
+		return nil
+	}
+	if len(*kf.name) > 253 || !k8sNameRE.MatchString(*kf.name) {
+		return errorf("некорректное имя Secret %q: строчные латинские буквы, цифры, '-' и '.'", *kf.name)
+	}
+	if *kf.namespace != "" && (len(*kf.namespace) > 63 || !k8sNamespaceRE.MatchString(*kf.namespace)) {
+		return errorf("некорректное пространство имен %q: строчные латинские буквы, цифры и '-', до 63 символов", *kf.namespace)
+	}
+	if kf.sealed() {
+		if _, err := exec.LookPath("kubeseal"); err != nil {
+			return errorf("--kubeseal требует kubeseal из Sealed Secrets")
+		}
+	}
+	return nil
+}
+
+// manifest собирает манифест Secret в затираемом буфере. Мастер-сид лежит
+// в ключе master в hex, как в --store-credential: смонтированный файл
+// читается через --master /путь/master. Отпечаток и схема записываются
+// в аннотации, чтобы сверить Secret с протоколом церемонии по kubectl get.
+func (kf *k8sSecretFlags) manifest(result resultRecord) []byte {
+	masterHex := hexSecret(result.Master)
+	defer wipe(masterHex)
+	var head strings.Builder
+	head.WriteString("apiVersion: v1\nkind: Secret\nmetadata:\n")
+	fmt.Fprintf(&head, "  name: %s\n", *kf.name)
+	if *kf.namespace != "" {
+		fmt.Fprintf(&head, "  namespace: %s\n", *kf.namespace)
+	}
+	head.WriteString("  labels:\n    app.kubernetes.io/managed-by: seedgen\n")
+	head.WriteString("  annotations:\n")
+	fmt.Fprintf(&head, "    seedgen/fingerprint: %q\n", result.Fingerprint)
+	fmt.Fprintf(&head, "    seedgen/scheme: %q\n", result.Scheme)
+	fmt.Fprintf(&head, "    seedgen/created-at: %q\n", result.CreatedAt.Format("2006-01-02T15:04:05Z"))
+	head.WriteString("type: Opaque\ndata:\n  master: ")
+
+	out := newSecret(head.Len() + base64.StdEncoding.EncodedLen(len(masterHex)) + 1)
+	n := copy(out, head.String())
+	base64.StdEncoding.Encode(out[n:], masterHex)
+	out[len(out)-1] = '\n'
+	return out
+}
+
+// write выводит манифест Secret или, с --kubeseal, SealedSecret
+func (kf *k8sSecretFlags) write(w io.Writer, result resultRecord) error {
+	manifest := kf.manifest(result)
+	defer wipe(manifest)
+	if !kf.sealed() {
+		_, err := w.Write(manifest)
+		return err
+	}
+	args := []string{"--format", "yaml"}
+	if *kf.sealedCert != "" {
+		args = append(args, "--cert", *kf.sealedCert)
+	}
+	cmd := exec.Command("kubeseal", args...)
+	cmd.Stdin = bytes.NewReader(manifest)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errorf("kubeseal: %s", msg)
+		}
+		return errorf("kubeseal: %w", err)
+	}
+	_, err := w.Write(stdout.Bytes())
+	return err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// kdfPreset - проверенный набор параметров KDF, чтобы на церемонии не
+// подбирать числа Argon2id вручную. Наборы одинаковы во всех сборках, в
+// том числе с тегом lowmem: иначе один и тот же пресет давал бы на разных
+// машинах разные мастер-сиды. Нехватку памяти ловит checkArgon2Memory.
+type kdfPreset struct {
+	name        string
+	kdf         string
+	iterations  int
+	memory      uint32 // КиБ
+	parallelism uint8
+}
+
+// kdfPresets перечисляет наборы от быстрого к самому стойкому
+var kdfPresets = []kdfPreset{
+	// Второй рекомендованный набор RFC 9106 для машин с малым объемом памяти
+	{name: "fast", kdf: kdfArgon2id, iterations: 3, memory: 64 * 1024, parallelism: 4},
+	{name: "standard", kdf: kdfArgon2id, iterations: 3, memory: 512 * 1024, parallelism: 4},
+	// Память первого рекомендованного набора RFC 9106 и дополнительные проходы
+	{name: "paranoid", kdf: kdfArgon2id, iterations: 4, memory: 2 * 1024 * 1024, parallelism: 4},
+}
+
+// kdfPresetNames возвращает имена пресетов для сообщений и дополнения
+func kdfPresetNames() []string {
+	names := make([]string, len(kdfPresets))
+	for i, p := range kdfPresets {
+		names[i] = p.name
+	}
+	return names
+}
+
+// findKDFPreset ищет пресет по имени
+func findKDFPreset(name string) (kdfPreset, error) {
+	for _, p := range kdfPresets {
+		if p.name == name {
+			return p, nil
+		}
+	}
+	return kdfPreset{}, errorf("неизвестный пресет KDF %q, доступны: %s", name, strings.Join(kdfPresetNames(), ", "))
+}
+
+// apply переносит параметры пресета в p; соль и эпоха не меняются
+func (k kdfPreset) apply(p *Params) {
+	p.KDF = k.kdf
+	p.Iterations = k.iterations
+	p.Memory = k.memory
+	p.Parallelism = k.parallelism
+}
+
+// describe перечисляет итоговые значения пресета для оператора
+func (k kdfPreset) describe() string {
+	return fmt.Sprintf(tr("Пресет KDF %s: %s, проходов: %d, память: %d КиБ (%s), потоков: %d"),
+		k.name, k.kdf, k.iterations, k.memory, formatBytes(uint64(k.memory)*1024), k.parallelism)
+}
+
+// echo выводит итоговые значения пресета в stderr всегда, даже с --quiet:
+// оператор должен видеть, какие числа на самом деле ушли в KDF
+func (k kdfPreset) echo() {
+	fmt.Fprintln(os.Stderr, k.describe())
+}
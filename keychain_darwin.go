@@ -0,0 +1,124 @@
+//go:build darwin && cgo
+// +build darwin,cgo
+
+package main
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+#include <stdlib.h>
+
+static CFStringRef cfString(const char *s) {
+	return CFStringCreateWithCString(kCFAllocatorDefault, s, kCFStringEncodingUTF8);
+}
+
+// keychainStore добавляет запись, доступную только на этом устройстве
+// и только после подтверждения Touch ID
+static OSStatus keychainStore(const char *service, const char *account, const void *data, size_t len) {
+	CFErrorRef error = NULL;
+	SecAccessControlRef access = SecAccessControlCreateWithFlags(kCFAllocatorDefault,
+		kSecAttrAccessibleWhenUnlockedThisDeviceOnly, kSecAccessControlBiometryAny, &error);
+	if (access == NULL) {
+		OSStatus status = error != NULL ? (OSStatus)CFErrorGetCode(error) : errSecParam;
+		if (error != NULL) CFRelease(error);
+		return status;
+	}
+	CFStringRef svc = cfString(service);
+	CFStringRef acc = cfString(account);
+	CFDataRef value = CFDataCreate(kCFAllocatorDefault, data, (CFIndex)len);
+	const void *keys[] = {kSecClass, kSecAttrService, kSecAttrAccount, kSecValueData, kSecAttrAccessControl, kSecUseDataProtectionKeychain};
+	const void *values[] = {kSecClassGenericPassword, svc, acc, value, access, kCFBooleanTrue};
+	CFDictionaryRef query = CFDictionaryCreate(kCFAllocatorDefault, keys, values, 6,
+		&kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	OSStatus status = SecItemAdd(query, NULL);
+	CFRelease(query);
+	CFRelease(value);
+	CFRelease(acc);
+	CFRelease(svc);
+	CFRelease(access);
+	return status;
+}
+
+// keychainLoad читает запись в out; система сама запрашивает Touch ID
+static OSStatus keychainLoad(const char *service, const char *account, const char *prompt, void *out, size_t cap, size_t *len) {
+	CFStringRef svc = cfString(service);
+	CFStringRef acc = cfString(account);
+	CFStringRef reason = cfString(prompt);
+	const void *keys[] = {kSecClass, kSecAttrService, kSecAttrAccount, kSecReturnData, kSecMatchLimit, kSecUseDataProtectionKeychain, kSecUseOperationPrompt};
+	const void *values[] = {kSecClassGenericPassword, svc, acc, kCFBooleanTrue, kSecMatchLimitOne, kCFBooleanTrue, reason};
+	CFDictionaryRef query = CFDictionaryCreate(kCFAllocatorDefault, keys, values, 7,
+		&kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	CFTypeRef result = NULL;
+	OSStatus status = SecItemCopyMatching(query, &result);
+	if (status == errSecSuccess) {
+		CFIndex n = CFDataGetLength((CFDataRef)result);
+		if ((size_t)n > cap) {
+			status = errSecBufferTooSmall;
+		} else {
+			CFDataGetBytes((CFDataRef)result, CFRangeMake(0, n), (UInt8 *)out);
+			*len = (size_t)n;
+		}
+		CFRelease(result);
+	}
+	CFRelease(query);
+	CFRelease(reason);
+	CFRelease(acc);
+	CFRelease(svc);
+	return status;
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// keychainSupported сообщает, что связка ключей доступна в этой сборке
+const keychainSupported = true
+
+// keychainService - сервис, под которым seedgen хранит записи в связке ключей
+const keychainService = "seedgen"
+
+// keychainError переводит код OSStatus в сообщение для оператора
+func keychainError(status C.OSStatus) error {
+	switch status {
+	case C.errSecItemNotFound:
+		return errorf("запись не найдена")
+	case C.errSecDuplicateItem:
+		return errorf("запись с таким именем уже существует")
+	case C.errSecUserCanceled, C.errSecAuthFailed:
+		return withCode(exitCancelled, errorf("подтверждение Touch ID отменено или не пройдено"))
+	case C.errSecMissingEntitlement:
+		return errorf("бинарник не подписан с правом keychain-access-groups, без него защищенная связка ключей недоступна")
+	}
+	return errorf("ошибка Security.framework %d", int(status))
+}
+
+// storeKeychain сохраняет мастер-сид в связку ключей с доступом по Touch ID
+func storeKeychain(name string, master []byte) error {
+	service, account := C.CString(keychainService), C.CString(name)
+	defer C.free(unsafe.Pointer(service))
+	defer C.free(unsafe.Pointer(account))
+	if status := C.keychainStore(service, account, unsafe.Pointer(&master[0]), C.size_t(len(master))); status != C.errSecSuccess {
+		return keychainError(status)
+	}
+	return nil
+}
+
+// loadKeychain читает мастер-сид из связки ключей после подтверждения Touch ID
+func loadKeychain(name string) ([]byte, error) {
+	service, account := C.CString(keychainService), C.CString(name)
+	defer C.free(unsafe.Pointer(service))
+	defer C.free(unsafe.Pointer(account))
+	prompt := C.CString(tr("seedgen: доступ к мастер-сиду ") + name)
+	defer C.free(unsafe.Pointer(prompt))
+
+	master := newSecret(masterSeedSize)
+	var n C.size_t
+	if status := C.keychainLoad(service, account, prompt, unsafe.Pointer(&master[0]), C.size_t(len(master)), &n); status != C.errSecSuccess {
+		wipe(master)
+		return nil, errorf("связка ключей, запись %q: %w", name, keychainError(status))
+	}
+	return master[:n], nil
+}
@@ -0,0 +1,17 @@
+//go:build !darwin || !cgo
+// +build !darwin !cgo
+
+package main
+
+// keychainSupported сообщает, что связка ключей доступна в этой сборке
+const keychainSupported = false
+
+// storeKeychain недоступна: связка ключей есть только в macOS (и требует cgo)
+func storeKeychain(name string, master []byte) error {
+	return errorf("связка ключей доступна только в macOS при сборке с cgo")
+}
+
+// loadKeychain недоступна: связка ключей есть только в macOS (и требует cgo)
+func loadKeychain(name string) ([]byte, error) {
+	return nil, errorf("связка ключей доступна только в macOS при сборке с cgo")
+}
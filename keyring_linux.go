@@ -0,0 +1,89 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// keyringSupported сообщает, что связка ключей ядра доступна в этой сборке
+const keyringSupported = true
+
+// keyringIDs сопоставляет связкам ключей их специальные идентификаторы
+var keyringIDs = map[string]int{
+	"session": unix.KEY_SPEC_SESSION_KEYRING,
+	"user":    unix.KEY_SPEC_USER_KEYRING,
+}
+
+// keyringError переводит ошибку keyctl в сообщение для оператора
+func keyringError(err error) error {
+	switch {
+	case errors.Is(err, unix.ENOKEY):
+		return errorf("запись не найдена")
+	case errors.Is(err, unix.EKEYEXPIRED):
+		return errorf("срок хранения записи истек")
+	case errors.Is(err, unix.EKEYREVOKED):
+		return errorf("запись отозвана")
+	case errors.Is(err, unix.EACCES):
+		return errorf("нет доступа к записи")
+	}
+	return err
+}
+
+// storeKeyring помещает мастер-сид в связку ключей ядра ring (session или user)
+// с ограниченным сроком хранения; ttl 0 - без срока
+func storeKeyring(name, ring string, ttl time.Duration, master []byte) error {
+	description := keyringPrefix + name
+	// Без create ядро не заводит процессу новую связку сеанса, которая
+	// исчезла бы вместе с ним, а возвращает связку сеанса пользователя
+	ringID, err := unix.KeyctlGetKeyringID(keyringIDs[ring], false)
+	if err != nil {
+		return keyringError(err)
+	}
+	if _, err := unix.KeyctlSearch(ringID, "user", description, 0); err == nil {
+		return errorf("запись %q уже есть в связке %s", name, ring)
+	}
+	id, err := unix.AddKey("user", description, master, ringID)
+	if err != nil {
+		return keyringError(err)
+	}
+	if ttl > 0 {
+		if _, err := unix.KeyctlInt(unix.KEYCTL_SET_TIMEOUT, id, int(ttl/time.Second), 0, 0); err != nil {
+			unix.KeyctlInt(unix.KEYCTL_INVALIDATE, id, 0, 0, 0)
+			return errorf("не удалось задать срок хранения: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadKeyring читает мастер-сид из связки сеанса или, если там его нет,
+// из связки пользователя
+func loadKeyring(name string) ([]byte, error) {
+	description := keyringPrefix + name
+	var id int
+	var err error
+	for _, ring := range []string{"session", "user"} {
+		if id, err = unix.KeyctlSearch(keyringIDs[ring], "user", description, 0); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, errorf("связка ключей ядра, запись %q: %w", name, keyringError(err))
+	}
+
+	master := newSecret(masterSeedSize)
+	n, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, master, 0)
+	if err != nil {
+		wipe(master)
+		return nil, errorf("связка ключей ядра, запись %q: %w", name, keyringError(err))
+	}
+	if n > len(master) {
+		wipe(master)
+		return nil, errorf("связка ключей ядра, запись %q: неверная длина мастер-сида (%d байт)", name, n)
+	}
+	return master[:n], nil
+}
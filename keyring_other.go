@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"time"
+)
+
+// keyringSupported сообщает, что связка ключей ядра доступна в этой сборке
+const keyringSupported = false
+
+// storeKeyring недоступна: связка ключей ядра есть только в Linux
+func storeKeyring(name, ring string, ttl time.Duration, master []byte) error {
+	return errorf("связка ключей ядра доступна только в Linux")
+}
+
+// loadKeyring недоступна: связка ключей ядра есть только в Linux
+func loadKeyring(name string) ([]byte, error) {
+	return nil, errorf("связка ключей ядра доступна только в Linux")
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// kmsEnvelopeKind - тип артефакта мастер-сида в конверте облачного KMS
+const kmsEnvelopeKind = "kms-envelope"
+
+// kmsEnvelope - мастер-сид, зашифрованный AES-256-GCM на ключе данных.
+// Ключ данных хранится рядом, зашифрованный ключом KMS, поэтому артефакт
+// можно держать в S3 или другом облачном хранилище: расшифровать его может
+// только тот, кому политика ключа это разрешает, и каждая расшифровка
+// попадает в журнал аудита облака.
+type kmsEnvelope struct {
+	Kind string `json:"kind"`
+	// Provider - облако ключа: aws, gcp или azure; в конвертах, записанных
+	// до появления других облаков, поле пустое и означает aws
+	Provider     string            `json:"provider,omitempty"`
+	KeyID        string            `json:"key_id"`
+	Context      map[string]string `json:"encryption_context"`
+	Fingerprint  string            `json:"fingerprint"`
+	EncryptedKey []byte            `json:"encrypted_key"`
+	Nonce        []byte            `json:"nonce"`
+	Sealed       []byte            `json:"sealed"`
+}
+
+// keyWrapper - ключ облачного KMS, которым шифруется ключ данных конверта
+type keyWrapper interface {
+	// provider возвращает имя облака для поля provider конверта
+	provider() string
+	// check до ввода сидов проверяет, что ключ доступен и годится для шифрования
+	check() error
+	// dataKey возвращает новый 256-битный ключ данных, его шифротекст и
+	// полный идентификатор ключа, которым он зашифрован
+	dataKey(context map[string]string) (plain, wrapped []byte, keyID string, err error)
+	// unwrap расшифровывает ключ данных
+	unwrap(wrapped []byte, context map[string]string) ([]byte, error)
+}
+
+// keyWrapperFor выбирает облако по виду ссылки на ключ: ресурс Cloud KMS
+// projects/.../cryptoKeys/..., адрес ключа Azure Key Vault
+// https://....vault.azure.net/keys/..., иначе ARN, идентификатор или
+// псевдоним AWS KMS
+func keyWrapperFor(keyID string) (keyWrapper, error) {
+	switch {
+	case strings.HasPrefix(keyID, "projects/"):
+		return newGCPKMS(keyID)
+	case strings.HasPrefix(keyID, "https://"):
+		return newAzureKeyVault(keyID)
+	}
+	return awsKMS{keyID}, nil
+}
+
+// wrapper возвращает ключ, которым зашифрован конверт
+func (e *kmsEnvelope) wrapper() (keyWrapper, error) {
+	switch e.Provider {
+	case "", "aws":
+		return awsKMS{e.KeyID}, nil
+	case "gcp":
+		return newGCPKMS(e.KeyID)
+	case "azure":
+		return newAzureKeyVault(e.KeyID)
+	}
+	return nil, errorf("неизвестное облако конверта KMS %q", e.Provider)
+}
+
+// newLocalDataKey создает ключ данных на этой машине для облаков, где KMS
+// только шифрует переданный ключ
+func newLocalDataKey() ([]byte, error) {
+	key := newSecret(32)
+	if _, err := rand.Read(key); err != nil {
+		wipe(key)
+		return nil, withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	return key, nil
+}
+
+// secretBase64 - секрет, который облачный CLI или API выводит в JSON
+// строкой base64. Разбор идет напрямую в затираемый буфер, без
+// промежуточной строки.
+type secretBase64 []byte
+
+// UnmarshalJSON разбирает строку base64, обычную или URL-безопасную
+func (s *secretBase64) UnmarshalJSON(data []byte) error {
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return errorf("ожидалась строка base64")
+	}
+	enc := base64.StdEncoding
+	if bytes.IndexAny(data, "-_") >= 0 {
+		enc = base64.URLEncoding
+	}
+	raw := data[1 : len(data)-1]
+	if len(raw)%4 != 0 {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+	out := newSecret(enc.DecodedLen(len(raw)))
+	n, err := enc.Decode(out, raw)
+	if err != nil {
+		wipe(out)
+		return err
+	}
+	*s = out[:n]
+	return nil
+}
+
+// kmsContext возвращает контекст шифрования ключа данных. Он не секретен,
+// записывается в журнал аудита при каждом обращении к ключу и должен
+// совпасть при расшифровке.
+func kmsContext(fingerprint string) map[string]string {
+	return map[string]string{"purpose": "seedgen-master", "fingerprint": fingerprint}
+}
+
+// kmsContextArg записывает контекст шифрования в JSON с ключами по порядку
+func kmsContextArg(context map[string]string) string {
+	data, _ := json.Marshal(context)
+	return string(data)
+}
+
+// aad возвращает связанные данные шифра: ключ, отпечаток и контекст
+func (e *kmsEnvelope) aad() []byte {
+	return []byte(kmsEnvelopeKind + "\x00" + e.KeyID + "\x00" + e.Fingerprint + "\x00" + kmsContextArg(e.Context))
+}
+
+// sealKMS шифрует мастер-сид на новом ключе данных, защищенном ключом kw
+func sealKMS(master []byte, kw keyWrapper) (*kmsEnvelope, error) {
+	e := &kmsEnvelope{Kind: kmsEnvelopeKind, Provider: kw.provider(), Fingerprint: masterFingerprint(master), Nonce: make([]byte, 12)}
+	e.Context = kmsContext(e.Fingerprint)
+	plain, wrapped, keyID, err := kw.dataKey(e.Context)
+	defer wipe(plain)
+	if err != nil {
+		return nil, err
+	}
+	if len(plain) != 32 || len(wrapped) == 0 {
+		return nil, errorf("KMS вернул ключ данных неожиданного размера")
+	}
+	// В артефакте - полный идентификатор ключа, даже если он задан псевдонимом
+	e.KeyID, e.EncryptedKey = keyID, wrapped
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return nil, withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	aead, err := kmsCipher(plain)
+	if err != nil {
+		return nil, err
+	}
+	e.Sealed = aead.Seal(nil, e.Nonce, master, e.aad())
+	return e, nil
+}
+
+// kmsCipher возвращает AEAD на ключе данных
+func kmsCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// open расшифровывает ключ данных в KMS и мастер-сид на нем
+func (e *kmsEnvelope) open() ([]byte, error) {
+	if e.KeyID == "" || len(e.EncryptedKey) == 0 || len(e.Nonce) != 12 {
+		return nil, errorf("артефакт KMS поврежден")
+	}
+	kw, err := e.wrapper()
+	if err != nil {
+		return nil, err
+	}
+	key, err := kw.unwrap(e.EncryptedKey, e.Context)
+	defer wipe(key)
+	if err != nil {
+		return nil, errorf("ошибка расшифровки в KMS: %w", err)
+	}
+	aead, err := kmsCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	master, err := aead.Open(newSecret(len(e.Sealed))[:0], e.Nonce, e.Sealed, e.aad())
+	if err != nil {
+		return nil, mismatchf("артефакт KMS не расшифровывается: файл поврежден или изменен")
+	}
+	if len(master) != masterSeedSize || !secretEqual([]byte(masterFingerprint(master)), []byte(e.Fingerprint)) {
+		wipe(master)
+		return nil, mismatchf("расшифрованное значение не совпадает с отпечатком %s", e.Fingerprint)
+	}
+	return master, nil
+}
+
+// openKMSArtifact расшифровывает мастер-сид из артефакта kms-envelope
+func openKMSArtifact(data []byte) ([]byte, error) {
+	var e kmsEnvelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, errorf("не удалось разобрать артефакт: %w", err)
+	}
+	return e.open()
+}
+
+// kmsFlags - флаги шифрования результата в конверт KMS
+type kmsFlags struct {
+	key *string
+	out *string
+	// wrapper - ключ, который check проверил до ввода сидов
+	wrapper keyWrapper
+}
+
+// addKMSFlags регистрирует флаги KMS в наборе
+func addKMSFlags(fs *flag.FlagSet) *kmsFlags {
+	return &kmsFlags{
+		key: fs.String("kms-key", "", "зашифровать мастер-сид на ключе данных облачного KMS: ARN или alias/имя AWS KMS, projects/.../cryptoKeys/... GCP или https://....vault.azure.net/keys/... Azure"),
+		out: fs.String("kms-out", "master.kms.json", "файл конверта KMS"),
+	}
+}
+
+// check до ввода сидов выбирает облако по --kms-key и проверяет ключ
+func (kf *kmsFlags) check() error {
+	if *kf.key == "" {
+		return nil
+	}
+	kw, err := keyWrapperFor(*kf.key)
+	if err == nil {
+		err = kw.check()
+	}
+	if err != nil {
+		return errorf("--kms-key: %w", err)
+	}
+	kf.wrapper = kw
+	return nil
+}
+
+// seal шифрует мастер-сид, если задан --kms-key, и сообщает об этом в w
+func (kf *kmsFlags) seal(w io.Writer, master []byte) error {
+	if *kf.key == "" {
+		return nil
+	}
+	e, err := sealKMS(master, kf.wrapper)
+	if err != nil {
+		return errorf("ошибка шифрования в KMS: %w", err)
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(*kf.out, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, tr("✓ Мастер-сид зашифрован ключом KMS %s: %s\n"), e.KeyID, *kf.out)
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// awsKMS - ключ AWS KMS: ARN, идентификатор или alias/имя. KMS вызывается
+// через aws CLI, поэтому работают все его способы входа: переменные,
+// профили, SSO и роль экземпляра.
+type awsKMS struct {
+	keyID string
+}
+
+func (k awsKMS) provider() string { return "aws" }
+
+// region возвращает регион из ARN ключа или "", если ключ задан
+// идентификатором или псевдонимом: тогда регион берется из настроек aws
+func (k awsKMS) region() string {
+	parts := strings.SplitN(k.keyID, ":", 6)
+	if len(parts) == 6 && parts[0] == "arn" && parts[2] == "kms" {
+		return parts[3]
+	}
+	return ""
+}
+
+// run запускает aws kms с этим ключом и разбирает JSON ответа в out.
+// Ответ с ключом данных затирается после разбора.
+func (k awsKMS) run(out interface{}, args ...string) error {
+	if _, err := exec.LookPath("aws"); err != nil {
+		return errorf("не найден aws CLI: он нужен для обращения к AWS KMS")
+	}
+	args = append([]string{"kms"}, args...)
+	args = append(args, "--key-id", k.keyID, "--output", "json")
+	if region := k.region(); region != "" {
+		args = append(args, "--region", region)
+	}
+	logDebug("запрос к AWS KMS", "command", args[1], "key", k.keyID)
+	cmd := exec.Command("aws", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	defer wipe(stdout.Bytes())
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errorf("aws kms %s: %s", args[1], msg)
+		}
+		return errorf("aws kms %s: %w", args[1], err)
+	}
+	if err := json.Unmarshal(stdout.Bytes(), out); err != nil {
+		return errorf("не удалось разобрать ответ aws kms %s: %w", args[1], err)
+	}
+	return nil
+}
+
+// check проверяет, что ключ включен и это симметричный ключ шифрования
+func (k awsKMS) check() error {
+	var resp struct {
+		KeyMetadata struct {
+			Arn      string `json:"Arn"`
+			Enabled  bool   `json:"Enabled"`
+			KeyUsage string `json:"KeyUsage"`
+			KeySpec  string `json:"KeySpec"`
+		} `json:"KeyMetadata"`
+	}
+	if err := k.run(&resp, "describe-key"); err != nil {
+		return err
+	}
+	m := resp.KeyMetadata
+	if !m.Enabled {
+		return errorf("ключ KMS %s отключен", m.Arn)
+	}
+	if m.KeyUsage != "ENCRYPT_DECRYPT" || m.KeySpec != "SYMMETRIC_DEFAULT" {
+		return errorf("ключ KMS %s (%s, %s) не годится: нужен симметричный ключ шифрования", m.Arn, m.KeySpec, m.KeyUsage)
+	}
+	return nil
+}
+
+// dataKey получает ключ данных от KMS: GenerateDataKey возвращает его
+// вместе с шифротекстом, и ключ в открытом виде не проходит через файлы
+func (k awsKMS) dataKey(context map[string]string) ([]byte, []byte, string, error) {
+	var resp struct {
+		KeyID          string       `json:"KeyId"`
+		CiphertextBlob []byte       `json:"CiphertextBlob"`
+		Plaintext      secretBase64 `json:"Plaintext"`
+	}
+	if err := k.run(&resp, "generate-data-key", "--key-spec", "AES_256", "--encryption-context", kmsContextArg(context)); err != nil {
+		wipe(resp.Plaintext)
+		return nil, nil, "", err
+	}
+	return resp.Plaintext, resp.CiphertextBlob, resp.KeyID, nil
+}
+
+// unwrap расшифровывает ключ данных. Шифротекст не секретен, aws CLI
+// читает его из временного файла.
+func (k awsKMS) unwrap(wrapped []byte, context map[string]string) ([]byte, error) {
+	blob, err := os.CreateTemp("", "seedgen-kms-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(blob.Name())
+	_, err = blob.Write(wrapped)
+	if cerr := blob.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Plaintext secretBase64 `json:"Plaintext"`
+	}
+	if err := k.run(&resp, "decrypt", "--ciphertext-blob", "fileb://"+blob.Name(), "--encryption-context", kmsContextArg(context)); err != nil {
+		wipe(resp.Plaintext)
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
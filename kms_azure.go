@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// azureKeyVaultAPI - версия REST API Azure Key Vault
+const azureKeyVaultAPI = "7.4"
+
+// azureKeyVault - ключ RSA в Azure Key Vault по адресу
+// https://ХРАНИЛИЩЕ.vault.azure.net/keys/КЛЮЧ[/ВЕРСИЯ]. Ключ данных
+// создается здесь и оборачивается операцией wrapKey (RSA-OAEP-256); токен
+// доступа выдает az CLI, так что работают вход пользователя, субъект-служба
+// и управляемое удостоверение. У RSA-OAEP нет дополнительных данных,
+// поэтому контекст конверта защищает только AES-GCM самого конверта.
+type azureKeyVault struct {
+	key  string
+	host string
+}
+
+// newAzureKeyVault проверяет адрес ключа
+func newAzureKeyVault(key string) (azureKeyVault, error) {
+	u, err := url.Parse(key)
+	parts := []string{}
+	if err == nil {
+		parts = strings.Split(strings.Trim(u.Path, "/"), "/")
+	}
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.RawQuery != "" || len(parts) < 2 || len(parts) > 3 || parts[0] != "keys" || parts[1] == "" {
+		return azureKeyVault{}, errorf("ключ Azure Key Vault %q должен иметь вид https://ХРАНИЛИЩЕ.vault.azure.net/keys/КЛЮЧ[/ВЕРСИЯ]", key)
+	}
+	return azureKeyVault{key: strings.TrimRight(key, "/"), host: u.Host}, nil
+}
+
+func (k azureKeyVault) provider() string { return "azure" }
+
+// token получает у az CLI токен доступа к Key Vault
+func (k azureKeyVault) token() (string, error) {
+	if _, err := exec.LookPath("az"); err != nil {
+		return "", errorf("не найден az CLI: он нужен для обращения к Azure Key Vault")
+	}
+	// Ресурс токена - облако хранилища без имени самого хранилища:
+	// https://vault.azure.net, https://vault.azure.cn и т. д.
+	resource := "https://" + k.host
+	if i := strings.IndexByte(k.host, '.'); i >= 0 {
+		resource = "https://" + k.host[i+1:]
+	}
+	cmd := exec.Command("az", "account", "get-access-token", "--resource", resource, "--output", "json")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errorf("az account get-access-token: %s", msg)
+		}
+		return "", errorf("az account get-access-token: %w", err)
+	}
+	var resp struct {
+		AccessToken string `json:"accessToken"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil || resp.AccessToken == "" {
+		return "", errorf("az account get-access-token не выдал токен")
+	}
+	return resp.AccessToken, nil
+}
+
+// call выполняет запрос к REST API ключа и разбирает ответ в out.
+// Тело запроса затирается после отправки, ответ - после разбора.
+func (k azureKeyVault) call(method, keyURL, op string, body []byte, out interface{}) error {
+	token, err := k.token()
+	if err != nil {
+		return err
+	}
+	target := keyURL
+	if op != "" {
+		target += "/" + op
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, target+"?api-version="+azureKeyVaultAPI, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	logDebug("запрос к Azure Key Vault", "method", method, "key", keyURL, "operation", op)
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errorf("ошибка запроса к Azure Key Vault: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	defer wipe(data)
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &e) == nil && e.Error.Message != "" {
+			return errorf("Azure Key Vault ответил %s: %s", resp.Status, e.Error.Message)
+		}
+		return errorf("Azure Key Vault ответил %s", resp.Status)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return errorf("не удалось разобрать ответ Azure Key Vault: %w", err)
+	}
+	return nil
+}
+
+// check проверяет, что ключ включен и разрешает wrapKey и unwrapKey
+func (k azureKeyVault) check() error {
+	var resp struct {
+		Key struct {
+			Kid    string   `json:"kid"`
+			Kty    string   `json:"kty"`
+			KeyOps []string `json:"key_ops"`
+		} `json:"key"`
+		Attributes struct {
+			Enabled bool `json:"enabled"`
+		} `json:"attributes"`
+	}
+	if err := k.call(http.MethodGet, k.key, "", nil, &resp); err != nil {
+		return err
+	}
+	if !resp.Attributes.Enabled {
+		return errorf("ключ Azure Key Vault %s отключен", k.key)
+	}
+	if !strings.HasPrefix(resp.Key.Kty, "RSA") {
+		return errorf("ключ Azure Key Vault %s (%s) не годится: нужен ключ RSA", k.key, resp.Key.Kty)
+	}
+	ops := strings.Join(resp.Key.KeyOps, ",")
+	if !strings.Contains(ops, "wrapKey") || !strings.Contains(ops, "unwrapKey") {
+		return errorf("ключу Azure Key Vault %s не разрешены операции wrapKey и unwrapKey", k.key)
+	}
+	return nil
+}
+
+// keyOpBody записывает тело запроса wrapKey или unwrapKey в затираемый буфер
+func keyOpBody(value []byte) []byte {
+	const head, tail = `{"alg":"RSA-OAEP-256","value":"`, `"}`
+	body := newSecret(len(head) + base64.RawURLEncoding.EncodedLen(len(value)) + len(tail))
+	n := copy(body, head)
+	base64.RawURLEncoding.Encode(body[n:], value)
+	copy(body[len(body)-len(tail):], tail)
+	return body
+}
+
+// dataKey создает ключ данных и оборачивает его ключом хранилища. В
+// конверт записывается адрес с версией ключа, которой он обернут.
+func (k azureKeyVault) dataKey(context map[string]string) ([]byte, []byte, string, error) {
+	plain, err := newLocalDataKey()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	body := keyOpBody(plain)
+	defer wipe(body)
+	var resp struct {
+		Kid   string       `json:"kid"`
+		Value secretBase64 `json:"value"`
+	}
+	if err := k.call(http.MethodPost, k.key, "wrapkey", body, &resp); err != nil {
+		wipe(plain)
+		return nil, nil, "", err
+	}
+	keyID := k.key
+	if resp.Kid != "" {
+		keyID = resp.Kid
+	}
+	return plain, resp.Value, keyID, nil
+}
+
+// unwrap разворачивает ключ данных в хранилище
+func (k azureKeyVault) unwrap(wrapped []byte, context map[string]string) ([]byte, error) {
+	body := keyOpBody(wrapped)
+	defer wipe(body)
+	var resp struct {
+		Value secretBase64 `json:"value"`
+	}
+	if err := k.call(http.MethodPost, k.key, "unwrapkey", body, &resp); err != nil {
+		wipe(resp.Value)
+		return nil, err
+	}
+	return resp.Value, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// gcpKMS - ключ GCP Cloud KMS projects/П/locations/Р/keyRings/К/cryptoKeys/И.
+// KMS вызывается через gcloud с его учетной записью или учетными данными
+// приложения. Ключ данных создается здесь, а Cloud KMS шифрует его,
+// связывая шифротекст с контекстом конверта как с дополнительными данными.
+type gcpKMS struct {
+	key string
+}
+
+// newGCPKMS проверяет имя ресурса ключа
+func newGCPKMS(key string) (gcpKMS, error) {
+	parts := strings.Split(key, "/")
+	if len(parts) != 8 || parts[0] != "projects" || parts[2] != "locations" || parts[4] != "keyRings" || parts[6] != "cryptoKeys" {
+		return gcpKMS{}, errorf("ключ Cloud KMS %q должен иметь вид projects/ПРОЕКТ/locations/РЕГИОН/keyRings/КОЛЬЦО/cryptoKeys/КЛЮЧ", key)
+	}
+	for _, p := range parts {
+		if p == "" {
+			return gcpKMS{}, errorf("ключ Cloud KMS %q должен иметь вид projects/ПРОЕКТ/locations/РЕГИОН/keyRings/КОЛЬЦО/cryptoKeys/КЛЮЧ", key)
+		}
+	}
+	return gcpKMS{key}, nil
+}
+
+func (k gcpKMS) provider() string { return "gcp" }
+
+// run запускает gcloud kms, передает stdin и возвращает stdout.
+// Ключ данных идет только через stdin и stdout, не через файлы.
+func (k gcpKMS) run(stdin []byte, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("gcloud"); err != nil {
+		return nil, errorf("не найден gcloud: он нужен для обращения к GCP Cloud KMS")
+	}
+	args = append([]string{"kms"}, args...)
+	logDebug("запрос к GCP Cloud KMS", "command", args[1], "key", k.key)
+	cmd := exec.Command("gcloud", args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		wipe(stdout.Bytes())
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errorf("gcloud kms %s: %s", args[1], msg)
+		}
+		return nil, errorf("gcloud kms %s: %w", args[1], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// withContextFile записывает контекст конверта во временный файл для
+// --additional-authenticated-data-file: он не секретен
+func withContextFile(context map[string]string, fn func(path string) error) error {
+	f, err := os.CreateTemp("", "seedgen-kms-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(kmsContextArg(context))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return fn(f.Name())
+}
+
+// check проверяет, что ключ предназначен для шифрования и его основная
+// версия включена
+func (k gcpKMS) check() error {
+	out, err := k.run(nil, "keys", "describe", k.key, "--format", "json")
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Purpose string `json:"purpose"`
+		Primary struct {
+			State string `json:"state"`
+		} `json:"primary"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return errorf("не удалось разобрать ответ gcloud kms keys describe: %w", err)
+	}
+	if resp.Purpose != "ENCRYPT_DECRYPT" {
+		return errorf("ключ Cloud KMS %s (%s) не годится: нужен симметричный ключ шифрования", k.key, resp.Purpose)
+	}
+	if resp.Primary.State != "ENABLED" {
+		return errorf("основная версия ключа Cloud KMS %s не включена (%s)", k.key, resp.Primary.State)
+	}
+	return nil
+}
+
+// dataKey создает ключ данных и шифрует его ключом Cloud KMS
+func (k gcpKMS) dataKey(context map[string]string) ([]byte, []byte, string, error) {
+	plain, err := newLocalDataKey()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	var wrapped []byte
+	err = withContextFile(context, func(aad string) error {
+		wrapped, err = k.run(plain, "encrypt", "--key", k.key, "--plaintext-file", "-", "--ciphertext-file", "-", "--additional-authenticated-data-file", aad)
+		return err
+	})
+	if err != nil {
+		wipe(plain)
+		return nil, nil, "", err
+	}
+	return plain, wrapped, k.key, nil
+}
+
+// unwrap расшифровывает ключ данных в Cloud KMS
+func (k gcpKMS) unwrap(wrapped []byte, context map[string]string) ([]byte, error) {
+	var plain []byte
+	err := withContextFile(context, func(aad string) error {
+		out, err := k.run(wrapped, "decrypt", "--key", k.key, "--ciphertext-file", "-", "--plaintext-file", "-", "--additional-authenticated-data-file", aad)
+		if err != nil {
+			return err
+		}
+		plain = newSecret(len(out))
+		copy(plain, out)
+		wipe(out)
+		return nil
+	})
+	return plain, err
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Общие флаги журнала диагностики
+const (
+	verboseFlag   = "verbose"
+	quietFlag     = "quiet"
+	logFormatFlag = "log-format"
+)
+
+// logLevel - уровень сообщения журнала
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var levelNames = map[logLevel]string{
+	levelDebug: "debug",
+	levelInfo:  "info",
+	levelWarn:  "warn",
+	levelError: "error",
+}
+
+// logFormats - форматы журнала; первый используется по умолчанию
+var logFormats = []string{"text", "json"}
+
+// redacted заменяет в журнале значения, которые могут содержать секреты
+const redacted = "[скрыто]"
+
+// secretKeyParts - части имен полей, значения которых никогда не пишутся
+// в журнал, даже если это строки
+var secretKeyParts = []string{"seed", "master", "mnemonic", "secret", "passphrase", "password", "pin", "key", "nonce", "token"}
+
+// logger - журнал диагностики. Он пишет только в stderr и отделен от
+// результатов команд в stdout, поэтому уровень и формат журнала не
+// меняют вывод, который сравнивают или разбирают скрипты.
+var logger = struct {
+	sync.Mutex
+	out   io.Writer
+	level logLevel
+	json  bool
+}{out: os.Stderr, level: levelInfo}
+
+// setLogging настраивает журнал по общим флагам: --verbose добавляет
+// отладочные сообщения, --quiet оставляет только предупреждения и ошибки
+func setLogging(verbose, quiet bool, format string) error {
+	if verbose && quiet {
+		return errorf("--%s и --%s нельзя указать вместе", verboseFlag, quietFlag)
+	}
+	switch {
+	case verbose:
+		logger.level = levelDebug
+	case quiet:
+		logger.level = levelWarn
+	}
+	switch format {
+	case "", "text":
+	case "json":
+		logger.json = true
+	default:
+		return errorf("неизвестный формат журнала %q, доступны: %s", format, strings.Join(logFormats, ", "))
+	}
+	return nil
+}
+
+// logVerbose сообщает, включены ли отладочные сообщения
+func logVerbose() bool {
+	return logger.level <= levelDebug
+}
+
+// logDebug записывает отладочное сообщение с полями ключ-значение
+func logDebug(msg string, kv ...interface{}) {
+	logRecord(levelDebug, tr(msg), kv)
+}
+
+// logInfof выводит уведомление для человека: что сохранено и куда.
+// Формат остается прежним, в JSON каждая строка становится записью.
+func logInfof(format string, args ...interface{}) {
+	logMessage(levelInfo, format, args)
+}
+
+// logWarnf выводит предупреждение; --quiet его не скрывает
+func logWarnf(format string, args ...interface{}) {
+	logMessage(levelWarn, format, args)
+}
+
+// logFailure выводит ошибку, с которой завершилась команда. В JSON
+// запись содержит код завершения и его имя, чтобы скрипты не разбирали
+// текст сообщения.
+func logFailure(err error, code exitCode) {
+	if !logger.json {
+		logMessage(levelError, "\n❌ Ошибка: %v\n", []interface{}{err})
+		return
+	}
+	logRecord(levelError, err.Error(), []interface{}{"code", exitCodeNames[code], "exit", int(code)})
+}
+
+// logMessage форматирует сообщение с заменой секретных аргументов. В
+// текстовом формате оно выводится как есть, в JSON - по записи на строку
+// без значков ✓, ⚠ и ❌.
+func logMessage(level logLevel, format string, args []interface{}) {
+	if level < logger.level {
+		return
+	}
+	safe := make([]interface{}, len(args))
+	for i, a := range args {
+		safe[i] = redactSecret(a)
+	}
+	text := fmt.Sprintf(tr(format), safe...)
+	if !logger.json {
+		switch level {
+		case levelWarn:
+			text = paint(colors.stderr, styleWarn, text)
+		case levelError:
+			text = paint(colors.stderr, styleError, text)
+		}
+		logger.Lock()
+		defer logger.Unlock()
+		io.WriteString(logger.out, text)
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		for _, mark := range []string{"✓", "⚠", "❌"} {
+			line = strings.TrimSpace(strings.TrimPrefix(line, mark))
+		}
+		if line != "" {
+			logRecord(level, line, nil)
+		}
+	}
+}
+
+// logRecord записывает одну запись журнала. Поля передаются парами
+// ключ-значение, как в log/slog, и проходят через redact.
+func logRecord(level logLevel, msg string, kv []interface{}) {
+	if level < logger.level {
+		return
+	}
+	type field struct {
+		key   string
+		value interface{}
+	}
+	fields := make([]field, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		key := fmt.Sprint(kv[i])
+		fields = append(fields, field{key, redact(key, kv[i+1])})
+	}
+
+	var b strings.Builder
+	if logger.json {
+		b.WriteString(`{"time":`)
+		writeJSONValue(&b, time.Now().UTC().Format(time.RFC3339Nano))
+		b.WriteString(`,"level":`)
+		writeJSONValue(&b, levelNames[level])
+		b.WriteString(`,"msg":`)
+		writeJSONValue(&b, msg)
+		for _, f := range fields {
+			b.WriteByte(',')
+			writeJSONValue(&b, f.key)
+			b.WriteByte(':')
+			writeJSONValue(&b, f.value)
+		}
+		b.WriteString("}\n")
+	} else {
+		b.WriteString("· ")
+		b.WriteString(msg)
+		for _, f := range fields {
+			v := fmt.Sprint(f.value)
+			if v == "" || strings.ContainsAny(v, " \t\"=") {
+				v = fmt.Sprintf("%q", v)
+			}
+			fmt.Fprintf(&b, " %s=%s", f.key, v)
+		}
+		b.WriteByte('\n')
+	}
+	logger.Lock()
+	defer logger.Unlock()
+	io.WriteString(logger.out, b.String())
+}
+
+// writeJSONValue дописывает значение в JSON; то, что JSON не кодирует,
+// записывается строкой
+func writeJSONValue(b *strings.Builder, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(data)
+}
+
+// redact готовит значение поля для журнала: скрывает значение поля, имя
+// которого похоже на секрет, и секретные типы, а ошибки, длительности и
+// прочие fmt.Stringer превращает в строки
+func redact(key string, v interface{}) interface{} {
+	lower := strings.ToLower(key)
+	for _, part := range secretKeyParts {
+		if strings.Contains(lower, part) {
+			return redacted
+		}
+	}
+	v = redactSecret(v)
+	switch x := v.(type) {
+	case error:
+		return x.Error()
+	case fmt.Stringer:
+		return x.String()
+	}
+	return v
+}
+
+// redactSecret скрывает байтовые срезы и secretHex: в этих типах программа
+// держит сиды, мастер-сид и ключи, поэтому они не выводятся никогда
+func redactSecret(v interface{}) interface{} {
+	switch v.(type) {
+	case []byte, secretHex, [][]byte:
+		return redacted
+	}
+	return v
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxLotteryCombinations ограничивает число комбинаций шаров в протоколе
+const maxLotteryCombinations = 100000
+
+// lotteryTeam - участник лотереи с числом комбинаций; порядок в файле -
+// порядок выбора после розыгрыша (обычно от худшего результата сезона)
+type lotteryTeam struct {
+	Name         string   `json:"team"`
+	Weight       int      `json:"weight"`
+	Combinations []string `json:"combinations"`
+}
+
+// lotteryDraw - одна выемка шаров
+type lotteryDraw struct {
+	Attempt     int    `json:"attempt"`
+	Balls       []int  `json:"balls"`
+	Combination string `json:"combination"`
+	Team        string `json:"team,omitempty"`
+	// Result - pick (команда получает выбор), unassigned (комбинация
+	// никому не принадлежит) или repeat (команда уже выиграла выбор)
+	Result string `json:"result"`
+	Pick   int    `json:"pick,omitempty"`
+}
+
+// weightedLottery - протокол лотереи
+type weightedLottery struct {
+	Kind        string        `json:"kind"`
+	Label       string        `json:"label"`
+	Weights     string        `json:"weights_sha256"`
+	Balls       int           `json:"balls"`
+	Drawn       int           `json:"drawn"`
+	Fingerprint string        `json:"master_fingerprint"`
+	Teams       []lotteryTeam `json:"teams"`
+	Unassigned  []string      `json:"unassigned,omitempty"`
+	Draws       []lotteryDraw `json:"draws"`
+	Order       []string      `json:"order"`
+}
+
+// readWeights читает CSV со строками "команда,комбинаций"; заголовок
+// (первое поле "team"), пустые строки и строки с # пропускаются.
+// Возвращает команды в порядке файла и SHA-256 канонической записи
+// (строки "команда,комбинаций" через \n).
+func readWeights(path string) ([]lotteryTeam, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	var teams []lotteryTeam
+	seen := make(map[string]bool)
+	var canonical []string
+	scanner := bufio.NewScanner(f)
+	for line, first := 1, true; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		r := csv.NewReader(strings.NewReader(text))
+		r.TrimLeadingSpace = true
+		rec, err := r.Read()
+		if err != nil {
+			return nil, "", errorf("%s:%d: %w", path, line, err)
+		}
+		if first {
+			first = false
+			if strings.EqualFold(strings.TrimSpace(rec[0]), "team") {
+				continue
+			}
+		}
+		if len(rec) != 2 {
+			return nil, "", errorf("%s:%d: ожидается команда,комбинаций", path, line)
+		}
+		t := lotteryTeam{Name: strings.TrimSpace(rec[0])}
+		if t.Name == "" {
+			return nil, "", errorf("%s:%d: не указана команда", path, line)
+		}
+		if seen[t.Name] {
+			return nil, "", errorf("%s:%d: команда %q уже указана", path, line, t.Name)
+		}
+		seen[t.Name] = true
+		if t.Weight, err = strconv.Atoi(strings.TrimSpace(rec[1])); err != nil || t.Weight < 0 {
+			return nil, "", errorf("%s:%d: некорректное число комбинаций %q", path, line, rec[1])
+		}
+		teams = append(teams, t)
+		canonical = append(canonical, t.Name+","+strconv.Itoa(t.Weight))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256([]byte(strings.Join(canonical, "\n")))
+	digest := hex.EncodeToString(sum[:])
+	auditInput("file", path, digest)
+	return teams, digest, nil
+}
+
+// binomial возвращает число сочетаний из n по k или -1, если оно больше limit
+func binomial(n, k, limit int) int {
+	c := 1
+	for i := 1; i <= k; i++ {
+		c = c * (n - k + i) / i
+		if c > limit {
+			return -1
+		}
+	}
+	return c
+}
+
+// ballCombinations перечисляет сочетания номеров шаров 1..balls по size
+// в лексикографическом порядке как строки "1-2-3-4"
+func ballCombinations(balls, size int) []string {
+	var combos []string
+	current := make([]int, size)
+	var walk func(pos, from int)
+	walk = func(pos, from int) {
+		if pos == size {
+			combos = append(combos, formatBalls(current))
+			return
+		}
+		for b := from; b <= balls-(size-pos)+1; b++ {
+			current[pos] = b
+			walk(pos+1, b+1)
+		}
+	}
+	walk(0, 1)
+	return combos
+}
+
+// formatBalls записывает номера шаров через дефис
+func formatBalls(balls []int) string {
+	parts := make([]string, len(balls))
+	for i, b := range balls {
+		parts[i] = strconv.Itoa(b)
+	}
+	return strings.Join(parts, "-")
+}
+
+// drawLottery проводит лотерею по правилам драфта НБА: комбинации шаров
+// перемешиваются и раздаются командам по весам, затем для каждого выбора
+// вынимаются шары без возвращения; ничейная комбинация или комбинация уже
+// выигравшей команды означает повторную выемку
+func drawLottery(stream *drawStream, teams []lotteryTeam, balls, size, picks int) ([]string, []lotteryDraw, []string) {
+	combos := ballCombinations(balls, size)
+	rank := make(map[string]int, len(combos))
+	for i, c := range combos {
+		rank[c] = i
+	}
+	// Протокол перечисляет комбинации команды по порядку, чтобы его было легко сверять
+	byBalls := func(list []string) {
+		sort.Slice(list, func(i, j int) bool { return rank[list[i]] < rank[list[j]] })
+	}
+	stream.shuffle(len(combos), func(i, j int) { combos[i], combos[j] = combos[j], combos[i] })
+	owner := make(map[string]int, len(combos))
+	next := 0
+	for i := range teams {
+		teams[i].Combinations = append([]string{}, combos[next:next+teams[i].Weight]...)
+		for _, c := range teams[i].Combinations {
+			owner[c] = i
+		}
+		byBalls(teams[i].Combinations)
+		next += teams[i].Weight
+	}
+	unassigned := append([]string(nil), combos[next:]...)
+	byBalls(unassigned)
+
+	won := make([]bool, len(teams))
+	var draws []lotteryDraw
+	var order []string
+	for len(order) < picks {
+		pool := make([]int, balls)
+		for i := range pool {
+			pool[i] = i + 1
+		}
+		drawn := make([]int, 0, size)
+		for len(drawn) < size {
+			k := stream.intn(len(pool))
+			drawn = append(drawn, pool[k])
+			pool = append(pool[:k], pool[k+1:]...)
+		}
+		sorted := append([]int(nil), drawn...)
+		sort.Ints(sorted)
+		d := lotteryDraw{Attempt: len(draws) + 1, Balls: drawn, Combination: formatBalls(sorted)}
+		i, ok := owner[d.Combination]
+		switch {
+		case !ok:
+			d.Result = "unassigned"
+		case won[i]:
+			d.Team, d.Result = teams[i].Name, "repeat"
+		default:
+			won[i] = true
+			order = append(order, teams[i].Name)
+			d.Team, d.Result, d.Pick = teams[i].Name, "pick", len(order)
+		}
+		draws = append(draws, d)
+	}
+	for i, t := range teams {
+		if !won[i] {
+			order = append(order, t.Name)
+		}
+	}
+	return order, draws, unassigned
+}
+
+// runDrawLottery проводит взвешенную лотерею драфта
+func runDrawLottery(args []string) error {
+	fs := newFlagSet("draw lottery")
+	weightsPath := fs.String("weights", "", "CSV весов: строки команда,комбинаций в порядке выбора после лотереи")
+	balls := fs.Int("balls", 14, "число шаров в барабане")
+	size := fs.Int("size", 4, "число шаров в одной выемке")
+	picks := fs.Int("picks", 4, "число выборов, разыгрываемых в лотерее")
+	label := fs.String("label", "lottery", "метка лотереи: разные метки дают независимые лотереи из одного мастер-сида")
+	format := fs.String("format", "text", "формат вывода: text или json")
+	lf := addLiveFlags(fs)
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *weightsPath == "" {
+		return errorf("укажите файл весов через --weights")
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	if err := lf.check(*format, ""); err != nil {
+		return err
+	}
+	if *size < 1 || *balls <= *size {
+		return errorf("шаров в барабане (--balls) должно быть больше, чем в выемке (--size)")
+	}
+	total := binomial(*balls, *size, maxLotteryCombinations)
+	if total < 0 {
+		return errorf("слишком много комбинаций шаров: не больше %d", maxLotteryCombinations)
+	}
+	teams, digest, err := readWeights(*weightsPath)
+	if err != nil {
+		return err
+	}
+	if len(teams) < 2 {
+		return errorf("в лотерее должно быть хотя бы 2 команды")
+	}
+	weight, withWeight := 0, 0
+	for _, t := range teams {
+		weight += t.Weight
+		if t.Weight > 0 {
+			withWeight++
+		}
+	}
+	if weight > total {
+		return errorf("командам назначено %d комбинаций, а в барабане только %d", weight, total)
+	}
+	if *picks < 1 || *picks > withWeight {
+		return errorf("--picks должно быть от 1 до числа команд с комбинациями (%d)", withWeight)
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	order, draws, unassigned := drawLottery(newDrawStream(master, "draw-lottery", *label), teams, *balls, *size, *picks)
+	result := weightedLottery{
+		Kind:        "weighted-lottery",
+		Label:       *label,
+		Weights:     digest,
+		Balls:       *balls,
+		Drawn:       *size,
+		Fingerprint: masterFingerprint(master),
+		Teams:       teams,
+		Unassigned:  unassigned,
+		Draws:       draws,
+		Order:       order,
+	}
+	if *format == "json" {
+		return writeOutput(os.Stdout, result)
+	}
+
+	fmt.Printf(tr("Лотерея %q: команд %d, шаров %d, в выемке %d, комбинаций %d, из них без владельца %d\n"), result.Label, len(teams), *balls, *size, total, len(unassigned))
+	fmt.Printf(tr("Мастер-сид: %s..., веса: SHA-256 %s\n"), emph(result.Fingerprint), result.Weights)
+	fmt.Println()
+	show, err := lf.start(liveStart{Kind: result.Kind, Label: result.Label, Input: result.Weights, Fingerprint: result.Fingerprint, Steps: len(draws)})
+	if err != nil {
+		return err
+	}
+	fmt.Println(tr("Выемки:"))
+	for _, d := range draws {
+		if err := show.reveal(formatLotteryDraw(d), d); err != nil {
+			return err
+		}
+	}
+	show.finish(result)
+	fmt.Println()
+	fmt.Println(tr("Порядок выбора:"))
+	for i, name := range order {
+		fmt.Printf("  %3d. %s\n", i+1, name)
+	}
+	fmt.Println()
+	fmt.Println(tr("Комбинации команд:"))
+	for _, t := range teams {
+		fmt.Printf("  %s (%d): %s\n", t.Name, t.Weight, strings.Join(t.Combinations, " "))
+	}
+	if len(unassigned) > 0 {
+		fmt.Printf(tr("  без владельца (%d): %s\n"), len(unassigned), strings.Join(unassigned, " "))
+	}
+	return nil
+}
+
+// formatLotteryDraw записывает выемку строкой протокола
+func formatLotteryDraw(d lotteryDraw) string {
+	line := fmt.Sprintf(tr("  %3d. шары %s -> %s"), d.Attempt, formatBalls(d.Balls), d.Combination)
+	switch d.Result {
+	case "pick":
+		line += fmt.Sprintf(tr(": %s, выбор %d"), d.Team, d.Pick)
+	case "repeat":
+		line += fmt.Sprintf(tr(": %s уже выиграла выбор, повтор"), d.Team)
+	default:
+		line += tr(": комбинация без владельца, повтор")
+	}
+	return line + "\n"
+}
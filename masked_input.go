@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// maskChar показывается вместо каждого введенного символа
+const maskChar = "*"
+
+// maskedLineSize - предел длины строки, вводимой со звездочками
+const maskedLineSize = 4096
+
+// readMasked читает строку из терминала f, уже переведенного в
+// enterMaskedMode, и показывает вместо каждого символа звездочку, чтобы
+// оператор видел, сколько введено. Строка собирается в buf, поэтому
+// вызывающий может ее затереть; не поместившиеся символы отбрасываются.
+// Backspace стирает последний символ, Ctrl-C отменяет ввод, Ctrl-D или
+// Ctrl-Z в пустой строке означают конец ввода (io.EOF).
+func readMasked(f *os.File, w io.Writer, buf []byte) ([]byte, error) {
+	line := buf[:0]
+	b := make([]byte, 1)
+	escape, csi := false, false
+	for {
+		if _, err := f.Read(b); err != nil {
+			fmt.Fprintln(w)
+			return nil, err
+		}
+		c := b[0]
+		// Стрелки и прочие клавиши приходят escape-последовательностями, они пропускаются
+		switch {
+		case csi:
+			csi = c < 0x40 || c > 0x7e
+			continue
+		case escape:
+			escape, csi = false, c == '[' || c == 'O'
+			continue
+		}
+		switch {
+		case c == '\r' || c == '\n':
+			fmt.Fprintln(w)
+			return line, nil
+		case c == 0x03:
+			fmt.Fprintln(w)
+			return nil, withCode(exitCancelled, errorf("ввод отменен"))
+		case (c == 0x04 || c == 0x1a) && len(line) == 0:
+			fmt.Fprintln(w)
+			return nil, io.EOF
+		case c == 0x7f || c == 0x08:
+			if len(line) == 0 {
+				continue
+			}
+			// Стирается последний символ UTF-8 целиком
+			n := len(line) - 1
+			for n > 0 && line[n]&0xc0 == 0x80 {
+				n--
+			}
+			wipe(line[n:])
+			line = line[:n]
+			fmt.Fprint(w, "\b \b")
+		case c == 0x1b:
+			escape = true
+		case c < 0x20:
+		case len(line) < cap(line):
+			line = append(line, c)
+			if c&0xc0 != 0x80 {
+				fmt.Fprint(w, maskChar)
+			}
+		}
+	}
+}
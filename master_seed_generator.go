@@ -2,112 +2,262 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
+)
 
-	"golang.org/x/crypto/pbkdf2"
+// Фиксированные параметры схемы v1
+const (
+	v1Salt       = "master-seed-salt-v1"
+	v1Iterations = 100000
 )
 
 // GenerateMasterSeedDeterministic создает детерминированный мастер-сид
-func GenerateMasterSeedDeterministic(deviceSeeds []string) (string, error) {
+// (64 байта). Промежуточные буферы затираются после вывода ключа.
+func GenerateMasterSeedDeterministic(deviceSeeds [][]byte) ([]byte, error) {
 	if len(deviceSeeds) == 0 {
-		return "", fmt.Errorf("необходим хотя бы один сид устройства")
+		return nil, errorf("необходим хотя бы один сид устройства")
 	}
 
 	// Сортируем для детерминированности
-	sortedSeeds := make([]string, len(deviceSeeds))
-	copy(sortedSeeds, deviceSeeds)
-	sort.Strings(sortedSeeds)
+	sortedSeeds := sortedSeedSet(deviceSeeds)
 
 	// Объединяем все сиды
-	combined := ""
+	size := 0
+	for _, seed := range sortedSeeds {
+		size += len(seed)
+	}
+	combined := newSecret(size)[:0]
 	for _, seed := range sortedSeeds {
-		combined += seed
+		combined = append(combined, seed...)
 	}
+	defer wipe(combined)
 
 	// Статичная соль для детерминированности
-	salt := []byte("master-seed-salt-v1")
+	salt := []byte(v1Salt)
 
 	// PBKDF2 с фиксированными параметрами
-	derivedKey := pbkdf2.Key(
-		[]byte(combined),
-		salt,
-		100000,
-		64,
-		sha512.New,
-	)
+	derivedKey := pbkdf2SHA512(combined, salt, v1Iterations)
+	lockSecret(derivedKey)
+	defer wipe(derivedKey)
 
 	// Финальное хэширование
+	return finalMasterHash(derivedKey), nil
+}
+
+// sortedSeedSet возвращает сиды в побайтовом порядке, не копируя их содержимое
+func sortedSeedSet(deviceSeeds [][]byte) [][]byte {
+	sorted := make([][]byte, len(deviceSeeds))
+	copy(sorted, deviceSeeds)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+	return sorted
+}
+
+// finalMasterHash вычисляет мастер-сид SHA-512(derivedKey), затирая копию на стеке
+func finalMasterHash(derivedKey []byte) []byte {
 	finalHash := sha512.Sum512(derivedKey)
+	master := newSecret(len(finalHash))
+	copy(master, finalHash[:])
+	wipe(finalHash[:])
+	return master
+}
 
-	return hex.EncodeToString(finalHash[:]), nil
+// shortFingerprint возвращает короткий отпечаток мастер-сида (в hex) для сверки
+func shortFingerprint(masterSeed string) string {
+	return fingerprintHex([]byte(masterSeed))
 }
 
-func main() {
-	fmt.Println("=== Генератор Мастер-Сида ===")
-	fmt.Println()
-	fmt.Println("Введите сиды от устройств (по одному на строку).")
-	fmt.Println("Для завершения ввода оставьте строку пустой и нажмите Enter.")
-	fmt.Println()
-
-	scanner := bufio.NewScanner(os.Stdin)
-	var deviceSeeds []string
+// masterFingerprint возвращает отпечаток мастер-сида без создания строки hex
+func masterFingerprint(master []byte) string {
+	h := hexSecret(master)
+	defer wipe(h)
+	return fingerprintHex(h)
+}
+
+// fingerprintHex вычисляет отпечаток по hex-записи мастер-сида
+func fingerprintHex(masterHex []byte) string {
+	hash := sha512.Sum512(masterHex)
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// readDeviceSeeds построчно читает сиды устройств до пустой строки.
+// Приглашения выводятся в prompts.
+func readDeviceSeeds(in io.Reader, prompts io.Writer) ([][]byte, error) {
+	return readDeviceSeedsFunc(in, prompts, seedInput{}, nil)
+}
+
+// readDeviceSeedsFunc читает сиды, как readDeviceSeeds, и вызывает onSeed
+// для каждого сида сразу после ввода. Невидимые символы и лишние пробелы
+// убираются с предупреждением, а со strict сид с ними отклоняется (см.
+// cleanSeed), с raw сид берется как есть (rawSeed). С checked каждый сид вводится с контрольным суффиксом "-XXXX",
+// который проверяется сразу: в терминале сид с ошибкой запрашивается
+// повторно, при вводе из файла это ошибка. С masked в терминале вместо
+// символов сида видны звездочки.
+// Сиды возвращаются в []byte, а буфер чтения затирается, поэтому вызывающий
+// может затереть все копии.
+func readDeviceSeedsFunc(in io.Reader, prompts io.Writer, opts seedInput, onSeed func(seed []byte)) ([][]byte, error) {
+	f, ok := in.(*os.File)
+	interactive := ok && isTerminal(f)
+	scanner := bufio.NewScanner(in)
+	// Буфер максимального размера: сканер не заменит его новым, который нельзя затереть
+	buf := newSecret(bufio.MaxScanTokenSize)
+	defer wipe(buf)
+	scanner.Buffer(buf, len(buf))
+	masked := opts.masked && interactive
+	if masked {
+		restore, err := enterMaskedMode(f)
+		if err != nil {
+			logWarnf("⚠ Не удалось скрыть ввод (%v), сиды будут видны на экране\n", err)
+			masked = false
+		} else {
+			defer restore()
+		}
+	}
+	var deviceSeeds [][]byte
 	seedNumber := 1
 
 	for {
-		fmt.Printf("Сид #%d: ", seedNumber)
+		fmt.Fprintf(prompts, tr("Сид #%d: "), seedNumber)
 
-		if !scanner.Scan() {
-			break
+		var input []byte
+		if masked {
+			line, err := readMasked(f, prompts, buf)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				wipeSeeds(deviceSeeds)
+				return nil, err
+			}
+			input = line
+		} else {
+			if !scanner.Scan() {
+				break
+			}
+			input = scanner.Bytes()
 		}
 
-		input := strings.TrimSpace(scanner.Text())
-
 		// Пустая строка - конец ввода
-		if input == "" {
+		if len(bytes.TrimSpace(input)) == 0 {
 			break
 		}
+		var err error
+		if input, err = prepareSeed(input, opts, seedNumber); err != nil {
+			if !interactive {
+				wipeSeeds(deviceSeeds)
+				return nil, errorf("сид #%d: %w", seedNumber, err)
+			}
+			fmt.Fprintf(prompts, tr("❌ %s, введите сид #%d заново\n"), err, seedNumber)
+			continue
+		}
+		if opts.checked {
+			if input, err = splitSeedCheck(input); err != nil {
+				if !interactive {
+					wipeSeeds(deviceSeeds)
+					return nil, errorf("сид #%d: %w", seedNumber, err)
+				}
+				fmt.Fprintf(prompts, tr("❌ %s, введите сид #%d заново\n"), err, seedNumber)
+				continue
+			}
+		}
 
-		deviceSeeds = append(deviceSeeds, input)
+		seed := newSecret(len(input))
+		copy(seed, input)
+		deviceSeeds = append(deviceSeeds, seed)
+		if onSeed != nil {
+			onSeed(seed)
+		}
 		seedNumber++
 	}
 
 	if err := scanner.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "Ошибка чтения ввода: %v\n", err)
-		os.Exit(1)
+		wipeSeeds(deviceSeeds)
+		return nil, errorf("ошибка чтения ввода: %w", err)
 	}
+	return deviceSeeds, nil
+}
 
-	if len(deviceSeeds) == 0 {
-		fmt.Println("\n❌ Не введено ни одного сида!")
-		os.Exit(1)
+// setupLogging забирает из аргументов общие флаги журнала и настраивает
+// его; --verbose или --quiet в командной строке отменяют оба из окружения
+func setupLogging(args *[]string) error {
+	rest, verbose := stripGlobalFlag(*args, verboseFlag)
+	rest, quiet := stripGlobalFlag(rest, quietFlag)
+	rest, logFormat, formatSet := stripGlobalValue(rest, logFormatFlag)
+	*args = rest
+	if !verbose && !quiet {
+		var err error
+		if verbose, err = globalBoolEnv(verboseFlag); err != nil {
+			return err
+		}
+		if quiet, err = globalBoolEnv(quietFlag); err != nil {
+			return err
+		}
+	}
+	logFormat, _ = globalEnv(logFormatFlag, logFormat, formatSet)
+	return setLogging(verbose, quiet, logFormat)
+}
+
+func main() {
+	restoreConsole := setupConsole()
+	exit := func(code exitCode) {
+		restoreConsole()
+		os.Exit(int(code))
 	}
 
-	fmt.Printf("\n✓ Получено сидов: %d\n\n", len(deviceSeeds))
+	args, langValue, langSet := stripGlobalValue(os.Args[1:], langFlag)
+	langValue, langSet = globalEnv(langFlag, langValue, langSet)
+	if err := setLanguage(langValue, langSet); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Ошибка: %v\n", err)
+		exit(exitInput)
+	}
+	if err := setupLogging(&args); err != nil {
+		fmt.Fprintf(os.Stderr, tr("❌ Ошибка: %v\n"), err)
+		exit(exitInput)
+	}
+	args, noColor := stripGlobalFlag(args, noColorFlag)
+	if !noColor {
+		var err error
+		if noColor, err = globalBoolEnv(noColorFlag); err != nil {
+			fmt.Fprintf(os.Stderr, tr("❌ Ошибка: %v\n"), err)
+			exit(exitInput)
+		}
+	}
+	setColor(noColor)
+	args, allowCoreDumps := stripGlobalFlag(args, allowCoreDumpsFlag)
+	if !allowCoreDumps {
+		hardenProcess()
+	}
+	tuneMemory()
+	args, auditPath, auditSet := stripGlobalValue(args, auditLogFlag)
+	auditPath, auditSet = globalEnv(auditLogFlag, auditPath, auditSet)
 
-	// Генерируем мастер-сид
-	masterSeed, err := GenerateMasterSeedDeterministic(deviceSeeds)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Ошибка генерации: %v\n", err)
-		os.Exit(1)
+	// Без аргументов или с одними флагами работаем как раньше - интерактивный ввод сидов
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help") {
+		args = append([]string{"generate"}, args...)
 	}
 
-	// Вычисляем SHA-256 хеш для дополнительной информации
-	hash := sha512.Sum512([]byte(masterSeed))
-	shortHash := hex.EncodeToString(hash[:])[:16]
-
-	// Выводим результат
-	fmt.Println("Мастер-сид (детерминированный):")
-	fmt.Println(masterSeed)
-	fmt.Println()
-	fmt.Printf("Длина: %d символа (%d бит энтропии)\n", len(masterSeed), len(masterSeed)*4)
-	fmt.Printf("SHA-512 хеш: %s...\n", shortHash)
-	fmt.Println()
-	fmt.Println("✓ Мастер-сид успешно сгенерирован!")
-	fmt.Println()
-	fmt.Println("Примечание: при одинаковых входных сидах")
-	fmt.Println("всегда будет получаться одинаковый мастер-сид.")
+	if auditSet && auditPath != "" && args[0] != completeCommand {
+		var err error
+		if audit, err = openAuditLog(auditPath, args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, tr("❌ Ошибка: %v\n"), err)
+			exit(exitCode(classifyError(err)))
+		}
+	}
+
+	code := runCommand(args)
+	if audit != nil {
+		if err := audit.finish(code); err != nil {
+			fmt.Fprintf(os.Stderr, tr("❌ Ошибка: %v\n"), err)
+			if code == int(exitOK) {
+				code = int(exitIO)
+			}
+		}
+	}
+	exit(exitCode(code))
 }
@@ -0,0 +1,40 @@
+package main
+
+// matchRef - идентификаторы матча для внешних систем: билетов, трансляций,
+// базы результатов. Они выводятся из пространства имен derive uuid, поэтому
+// любая система, которой передано пространство имен, вычислит их сама.
+type matchRef struct {
+	UUID  string `json:"uuid,omitempty"`
+	Short string `json:"short_id,omitempty"`
+}
+
+// matchName возвращает имя матча для UUIDv5: "match:" + этап (метка
+// команды) + "/" + номер матча, например match:bracket/R1-M3
+func matchName(stage, id string) string {
+	return "match:" + stage + "/" + id
+}
+
+// newMatchRef вычисляет UUIDv5 имени в пространстве ns и короткий
+// идентификатор - первые 40 бит UUID в Base32 Крокфорда (8 символов)
+func newMatchRef(ns []byte, name string) matchRef {
+	u := uuidV5Bytes(ns, name)
+	v := uint64(u[0])<<32 | uint64(u[1])<<24 | uint64(u[2])<<16 | uint64(u[3])<<8 | uint64(u[4])
+	short := make([]byte, 8)
+	for i := range short {
+		short[i] = crockfordAlphabet[v>>(35-5*uint(i))&31]
+	}
+	return matchRef{UUID: formatUUID(u), Short: string(short)}
+}
+
+// assignMatchRefs присваивает идентификаторы матчам сетки; проход без игры
+// матчем не считается
+func (b *bracket) assignMatchRefs(ns []byte) {
+	stages := append(append(append([][]bracketMatch(nil), b.Rounds...), b.Losers...), b.Final)
+	for _, round := range stages {
+		for i := range round {
+			if !round[i].Bye {
+				round[i].matchRef = newMatchRef(ns, matchName(b.Label, round[i].ID))
+			}
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import "golang.org/x/sys/unix"
+
+// availableMemory возвращает объем физической памяти в байтах. macOS не сообщает
+// свободную память одним числом, поэтому проверяется лишь верхняя граница.
+func availableMemory() (uint64, bool) {
+	size, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return 0, false
+	}
+	return size, true
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package main
+
+// availableMemory на остальных платформах неизвестен
+func availableMemory() (uint64, bool) {
+	return 0, false
+}
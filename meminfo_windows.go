@@ -0,0 +1,30 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGlobalMemoryStatusEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GlobalMemoryStatusEx")
+
+// memoryStatusEx - структура MEMORYSTATUSEX
+type memoryStatusEx struct {
+	length               uint32
+	memoryLoad           uint32
+	totalPhys            uint64
+	availPhys            uint64
+	totalPageFile        uint64
+	availPageFile        uint64
+	totalVirtual         uint64
+	availVirtual         uint64
+	availExtendedVirtual uint64
+}
+
+// availableMemory возвращает объем свободной физической памяти в байтах
+func availableMemory() (uint64, bool) {
+	status := memoryStatusEx{length: uint32(unsafe.Sizeof(memoryStatusEx{}))}
+	if ok, _, _ := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status))); ok == 0 {
+		return 0, false
+	}
+	return status.availPhys, true
+}
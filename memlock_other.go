@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package main
+
+// mlock на остальных платформах не поддерживается: буфер остается как есть
+func mlock(b []byte) error {
+	return nil
+}
+
+// memlockLimit на остальных платформах неизвестен
+func memlockLimit() (uint64, bool) {
+	return 0, false
+}
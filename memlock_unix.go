@@ -0,0 +1,20 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// mlock закрепляет страницы буфера в оперативной памяти
+func mlock(b []byte) error {
+	return unix.Mlock(b)
+}
+
+// memlockLimit возвращает текущий лимит RLIMIT_MEMLOCK в байтах
+func memlockLimit() (uint64, bool) {
+	var limit unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_MEMLOCK, &limit); err != nil {
+		return 0, false
+	}
+	return uint64(limit.Cur), true
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Префиксы листа и узла по RFC 6962: лист нельзя выдать за внутренний узел
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// merkleLeafHash возвращает хэш листа дерева
+func merkleLeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// merkleNodeHash возвращает хэш внутреннего узла
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleSplit возвращает наибольшую степень двойки, меньшую n (n > 1)
+func merkleSplit(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+	return k
+}
+
+// merkleRoot вычисляет корень дерева над хэшами листьев по RFC 6962
+func merkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := merkleSplit(len(leaves))
+	return merkleNodeHash(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// merklePath возвращает путь включения листа index: хэши соседних
+// поддеревьев от листа к корню
+func merklePath(leaves [][]byte, index int) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := merkleSplit(len(leaves))
+	if index < k {
+		return append(merklePath(leaves[:k], index), merkleRoot(leaves[k:]))
+	}
+	return append(merklePath(leaves[k:], index-k), merkleRoot(leaves[:k]))
+}
+
+// merkleVerify проверяет путь включения листа по алгоритму RFC 9162, 2.1.3.2
+func merkleVerify(leaf []byte, index, count int, path [][]byte, root []byte) bool {
+	if index < 0 || index >= count {
+		return false
+	}
+	fn, sn := index, count-1
+	r := leaf
+	for _, p := range path {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = merkleNodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = merkleNodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && bytes.Equal(r, root)
+}
+
+// seedMerkleTree - дерево Меркла над обязательствами по сидам церемонии.
+// Листья упорядочены по обязательствам, поэтому корень, как и мастер-сид,
+// не зависит от порядка ввода.
+type seedMerkleTree struct {
+	commitments []string
+	leaves      [][]byte
+}
+
+// newSeedMerkleTree строит дерево над обязательствами по сидам
+func newSeedMerkleTree(commitments []string) *seedMerkleTree {
+	sorted := append([]string(nil), commitments...)
+	sort.Strings(sorted)
+	t := &seedMerkleTree{commitments: sorted}
+	for _, c := range sorted {
+		raw, _ := hex.DecodeString(c)
+		t.leaves = append(t.leaves, merkleLeafHash(raw))
+	}
+	return t
+}
+
+// root возвращает корень дерева в hex
+func (t *seedMerkleTree) root() string {
+	return hex.EncodeToString(merkleRoot(t.leaves))
+}
+
+// proof возвращает доказательство включения сида с обязательством commitment
+func (t *seedMerkleTree) proof(commitment, fingerprint string) *inclusionProof {
+	index := sort.SearchStrings(t.commitments, commitment)
+	p := &inclusionProof{
+		Kind:        "seed-inclusion-proof",
+		Root:        t.root(),
+		LeafCount:   len(t.leaves),
+		LeafIndex:   index,
+		Commitment:  commitment,
+		Fingerprint: fingerprint,
+	}
+	for _, h := range merklePath(t.leaves, index) {
+		p.Path = append(p.Path, hex.EncodeToString(h))
+	}
+	return p
+}
+
+// inclusionProof - доказательство того, что сид участвовал в церемонии.
+// В нем только обязательство по сиду, поэтому держатель может предъявить
+// его кому угодно, не раскрывая сам сид.
+type inclusionProof struct {
+	Kind        string   `json:"kind"`
+	Root        string   `json:"merkle_root"`
+	LeafCount   int      `json:"leaf_count"`
+	LeafIndex   int      `json:"leaf_index"`
+	Commitment  string   `json:"commitment"`
+	Path        []string `json:"path"`
+	Fingerprint string   `json:"fingerprint"`
+}
+
+// verify сверяет обязательство из доказательства с корнем дерева
+func (p *inclusionProof) verify() error {
+	commitment, err := hex.DecodeString(p.Commitment)
+	if err != nil || len(commitment) != sha256.Size {
+		return errorf("некорректное обязательство в доказательстве")
+	}
+	root, err := hex.DecodeString(p.Root)
+	if err != nil || len(root) != sha256.Size {
+		return errorf("некорректный корень в доказательстве")
+	}
+	path := make([][]byte, len(p.Path))
+	for i, s := range p.Path {
+		if path[i], err = hex.DecodeString(s); err != nil || len(path[i]) != sha256.Size {
+			return errorf("некорректный узел %d пути в доказательстве", i+1)
+		}
+	}
+	if !merkleVerify(merkleLeafHash(commitment), p.LeafIndex, p.LeafCount, path, root) {
+		return mismatchf("путь доказательства не ведет к корню %s", p.Root)
+	}
+	return nil
+}
+
+// writeInclusionProofs сохраняет в dir доказательство для каждого сида:
+// seed-N.json, где N - номер сида в протоколе
+func writeInclusionProofs(dir string, tree *seedMerkleTree, commitments []string, fingerprint string) error {
+	for i, c := range commitments {
+		data, err := json.MarshalIndent(tree.proof(c, fingerprint), "", "  ")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, fmt.Sprintf("seed-%d.json", i+1))
+		if err := writeNewFile(path, append(data, '\n'), 0644); err != nil {
+			return errorf("ошибка записи доказательства: %w", err)
+		}
+	}
+	return nil
+}
+
+// runAuditProof проверяет доказательство включения сида: путь к корню,
+// при --transcript - совпадение корня с подписанным протоколом, при --seed -
+// соответствие обязательства сиду, введенному держателем
+func runAuditProof(args []string) error {
+	fs := newFlagSet("audit proof")
+	transcriptPath := fs.String("transcript", "", "протокол церемонии, корень которого должен совпасть с доказательством")
+	pubkey := fs.String("pubkey", "", "ожидаемый открытый ключ церемонии: hex или PEM-файл (с --transcript)")
+	checkSeed := fs.Bool("seed", false, "запросить сид и проверить, что доказательство относится к нему")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errorf("укажите файл доказательства")
+	}
+	if *pubkey != "" && *transcriptPath == "" {
+		return errorf("флаг --pubkey применим только с --transcript")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return err
+	}
+	var p inclusionProof
+	if err := json.Unmarshal(data, &p); err != nil {
+		return errorf("не удалось разобрать доказательство: %w", err)
+	}
+	if p.Kind != "seed-inclusion-proof" {
+		return errorf("файл не является доказательством включения сида (тип %q)", p.Kind)
+	}
+	if err := p.verify(); err != nil {
+		return err
+	}
+
+	if *transcriptPath != "" {
+		t, err := readTranscript(*transcriptPath, *pubkey)
+		if err != nil {
+			return err
+		}
+		if t.merkleRoot() != p.Root {
+			return mismatchf("корень доказательства не совпадает с корнем в протоколе %s", *transcriptPath)
+		}
+	}
+
+	if *checkSeed {
+		fmt.Println(tr("Введите свой сид, затем пустую строку."))
+		seeds, err := readDeviceSeeds(os.Stdin, os.Stdout)
+		if err != nil {
+			return err
+		}
+		defer wipeSeeds(seeds)
+		if len(seeds) != 1 {
+			return errorf("введите ровно один сид, получено: %d", len(seeds))
+		}
+		if !secretEqualString(seedCommitment(seeds[0]), p.Commitment) {
+			return mismatchf("доказательство относится к другому сиду")
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf(tr("=== Доказательство: %s ===\n\n"), positional[0])
+	fmt.Printf(tr("Обязательство: %s\n"), p.Commitment)
+	fmt.Printf(tr("Корень дерева: %s (сидов: %d)\n"), p.Root, p.LeafCount)
+	fmt.Printf(tr("SHA-512 хеш мастер-сида: %s...\n"), p.Fingerprint)
+	fmt.Println()
+	fmt.Println(tr("✓ Обязательство входит в дерево церемонии"))
+	if *transcriptPath != "" {
+		fmt.Println(tr("✓ Корень совпадает с подписанным протоколом"))
+	} else {
+		fmt.Println(tr("  Сверьте корень с протоколом церемонии или укажите --transcript."))
+	}
+	if *checkSeed {
+		fmt.Println(tr("✓ Доказательство относится к введенному сиду"))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisignPublicKey - открытый ключ minisign: идентификатор и ключ Ed25519
+type minisignPublicKey struct {
+	keyID [8]byte
+	key   ed25519.PublicKey
+}
+
+// id возвращает идентификатор ключа в том виде, в каком его печатает minisign
+func (k *minisignPublicKey) id() string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(k.keyID[:]))
+}
+
+// readMinisignPublicKey читает ключ из файла minisign или из строки base64
+// (как в minisign -P)
+func readMinisignPublicKey(ref string) (*minisignPublicKey, error) {
+	text := ref
+	if data, err := os.ReadFile(ref); err == nil {
+		text = string(data)
+	}
+	var encoded string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			encoded = line
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return nil, errorf("некорректный открытый ключ minisign")
+	}
+	k := &minisignPublicKey{key: ed25519.PublicKey(raw[10:])}
+	copy(k.keyID[:], raw[2:10])
+	return k, nil
+}
+
+// verifyMinisign проверяет отделенную подпись minisign данных и возвращает
+// ее доверенный комментарий. Поддерживаются подписи Ed (сами данные)
+// и ED (хэш BLAKE2b-512 данных, формат по умолчанию).
+func verifyMinisign(pub *minisignPublicKey, data []byte, sigText string) (string, error) {
+	lines := strings.Split(strings.ReplaceAll(sigText, "\r", ""), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], "untrusted comment:") || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return "", errorf("некорректный формат файла подписи")
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return "", errorf("некорректная подпись")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return "", errorf("некорректная подпись доверенного комментария")
+	}
+
+	msg := data
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		sum := blake2b.Sum512(data)
+		msg = sum[:]
+	default:
+		return "", errorf("неизвестный алгоритм подписи %q", sig[:2])
+	}
+	if string(sig[2:10]) != string(pub.keyID[:]) {
+		return "", mismatchf("подпись сделана другим ключом (%016X)", binary.LittleEndian.Uint64(sig[2:10]))
+	}
+	if !ed25519.Verify(pub.key, msg, sig[10:]) {
+		return "", mismatchf("подпись недействительна, файл изменен")
+	}
+	trusted := strings.TrimPrefix(lines[2], "trusted comment: ")
+	if !ed25519.Verify(pub.key, append(append([]byte{}, sig[10:]...), trusted...), global) {
+		return "", errorf("доверенный комментарий изменен")
+	}
+	return trusted, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// mixNoncePrefix отличает нонс от сидов устройств в общем наборе
+const mixNoncePrefix = "mix-nonce:"
+
+// mixMasterSeed объединяет сиды устройств со случайным нонсом.
+// Нонс добавляется в набор как еще один сид, поэтому результат
+// воспроизводится тем же алгоритмом при известном нонсе.
+func mixMasterSeed(deviceSeeds [][]byte, nonce []byte) ([]byte, error) {
+	if len(nonce) == 0 {
+		return nil, errorf("нонс не может быть пустым")
+	}
+
+	return GenerateMasterSeedDeterministic(mixSeedSet(deviceSeeds, nonce))
+}
+
+// mixSeedSet возвращает набор сидов устройств с нонсом в роли еще одного сида
+func mixSeedSet(deviceSeeds [][]byte, nonce []byte) [][]byte {
+	seeds := make([][]byte, 0, len(deviceSeeds)+1)
+	seeds = append(seeds, deviceSeeds...)
+	return append(seeds, []byte(mixNoncePrefix+hex.EncodeToString(nonce)))
+}
+
+// runMix генерирует недетерминированный мастер-сид с системной энтропией
+func runMix(args []string) error {
+	fs := newFlagSet("mix")
+	nonceHex := fs.String("nonce", "", "нонс прошлого запуска в hex для воспроизведения результата")
+	nonceBytes := fs.Int("nonce-bytes", 32, "размер нового нонса в байтах")
+	format := fs.String("format", "text", "формат вывода: text, json, msv2, rs (копия с кодом Рида-Соломона) или k8s-secret (манифест Secret Kubernetes)")
+	kf := addK8sSecretFlags(fs)
+	spf := addSOPSFlags(fs)
+	tlf := addTimelockFlags(fs)
+	rf := addRevealFlags(fs)
+	ef := addEnvFlags(fs)
+	rcf := addReceiptFlags(fs)
+	stf := addStoreFlags(fs)
+	doubleCheckFlag := fs.Bool("double-check", false, "пересчитать мастер-сид независимой реализацией и прервать работу при расхождении")
+	ssf := addSeedSourceFlags(fs)
+	qf := addQuorumFlags(fs)
+	pf := addProfileFlags(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pf.apply(fs); err != nil {
+		return err
+	}
+	switch *format {
+	case "text", "json", "msv2", "rs", k8sSecretFormat:
+	default:
+		return errorf("неизвестный формат %q", *format)
+	}
+	if err := kf.check(fs, *format); err != nil {
+		return err
+	}
+	if err := spf.check(*format, kf.sealed()); err != nil {
+		return err
+	}
+	if err := tlf.check(*format, spf.enabled()); err != nil {
+		return err
+	}
+	if err := rf.checkGroups(*format == "text"); err != nil {
+		return err
+	}
+	if err := ef.check(*format != "text"); err != nil {
+		return err
+	}
+	if err := rcf.load(); err != nil {
+		return err
+	}
+	if err := stf.check(); err != nil {
+		return err
+	}
+	if err := qf.load(); err != nil {
+		return err
+	}
+
+	var nonce []byte
+	if *nonceHex != "" {
+		var err error
+		if nonce, err = hex.DecodeString(*nonceHex); err != nil {
+			return errorf("некорректный нонс: %w", err)
+		}
+	} else {
+		if *nonceBytes < 16 {
+			return errorf("нонс короче 16 байт не обеспечивает непредсказуемости")
+		}
+		nonce = make([]byte, *nonceBytes)
+		if _, err := rand.Read(nonce); err != nil {
+			return withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+		}
+	}
+
+	// Для машиночитаемых форматов приглашения уходят в stderr, чтобы stdout оставался разбираемым
+	var prompts io.Writer = os.Stdout
+	if *format != "text" {
+		prompts = os.Stderr
+	}
+
+	fmt.Fprintln(prompts, tr("=== Мастер-сид с системной энтропией ==="))
+	fmt.Fprintln(prompts)
+	deviceSeeds, err := ssf.read(prompts)
+	if err != nil {
+		return err
+	}
+	defer wipeSeeds(deviceSeeds)
+	if len(deviceSeeds) == 0 {
+		return errorf("не введено ни одного сида")
+	}
+
+	stopProgress := showKDFProgress()
+	masterSeed, err := mixMasterSeed(deviceSeeds, nonce)
+	stopProgress()
+	if err != nil {
+		return err
+	}
+	defer wipe(masterSeed)
+	if *doubleCheckFlag {
+		if err := doubleCheck(mixSeedSet(deviceSeeds, nonce), DefaultParams(), masterSeed); err != nil {
+			return err
+		}
+		fmt.Fprintln(prompts, tr("✓ Мастер-сид подтвержден независимым повторным вычислением"))
+	}
+	result := newResult(masterSeed, len(deviceSeeds), DefaultParams())
+	auditResult("master", "", result.Fingerprint)
+	noteProvenanceParams(result.Params)
+	result.Kind = "mixed-master-seed"
+	result.Nonce = hex.EncodeToString(nonce)
+	if err := qf.confirm(prompts, result.Fingerprint); err != nil {
+		return err
+	}
+
+	if *format != "text" {
+		err = tlf.write(os.Stdout, func(w io.Writer) error {
+			return spf.write(w, *format, func(w io.Writer) error {
+				if *format == k8sSecretFormat {
+					return kf.write(w, result)
+				}
+				return writeResult(w, result, *format)
+			})
+		})
+		if err != nil {
+			return err
+		}
+		if err := stf.store(os.Stderr, masterSeed); err != nil {
+			return err
+		}
+		return rcf.write(os.Stderr, result)
+	}
+
+	fmt.Printf(tr("\n✓ Получено сидов: %d\n\n"), len(deviceSeeds))
+	if stf.withheld(rf) {
+		fmt.Println(tr("Мастер-сид не выводится: stdout не терминал, результат сохраняется в хранилище"))
+	} else if err := rf.reveal(tr("Мастер-сид (с системной энтропией):"), masterSeed); err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Println(tr("Нонс (сохраните вместе с протоколом церемонии):"))
+	fmt.Println(hex.EncodeToString(nonce))
+	fmt.Println()
+	fmt.Printf(tr("SHA-512 хеш: %s...\n"), emph(result.Fingerprint))
+	if err := stf.store(os.Stdout, masterSeed); err != nil {
+		return err
+	}
+	if err := rcf.write(os.Stdout, result); err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Println(tr("Примечание: без нонса этот мастер-сид невозможно воспроизвести."))
+	fmt.Println(tr("Для повтора: seedgen mix --nonce <нонс> и те же сиды."))
+	return nil
+}
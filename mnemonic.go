@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"sort"
+	"strings"
+)
+
+// englishWordlist - официальный английский словарь BIP39 (2048 слов)
+//
+//go:embed wordlists/english.txt
+var englishWordlist string
+
+// bip39Words содержит слова словаря в порядке индексов
+var bip39Words = strings.Fields(englishWordlist)
+
+// entropyToMnemonic кодирует энтропию в мнемоническую фразу по BIP39.
+// Длина энтропии должна быть кратна 4 байтам.
+func entropyToMnemonic(entropy []byte) (string, error) {
+	if len(entropy) == 0 || len(entropy)%4 != 0 {
+		return "", errorf("длина энтропии %d байт не кратна 32 битам", len(entropy))
+	}
+
+	// Контрольная сумма - первые ENT/32 бит SHA-256 от энтропии
+	checksum := sha256.Sum256(entropy)
+	checksumBits := len(entropy) * 8 / 32
+	totalBits := len(entropy)*8 + checksumBits
+
+	bit := func(i int) int {
+		if i < len(entropy)*8 {
+			return int(entropy[i/8]>>(7-uint(i%8))) & 1
+		}
+		i -= len(entropy) * 8
+		return int(checksum[i/8]>>(7-uint(i%8))) & 1
+	}
+
+	words := make([]string, 0, totalBits/11)
+	for i := 0; i < totalBits; i += 11 {
+		index := 0
+		for j := 0; j < 11; j++ {
+			index = index<<1 | bit(i+j)
+		}
+		words = append(words, bip39Words[index])
+	}
+	return strings.Join(words, " "), nil
+}
+
+// bip39Index сопоставляет слово словаря его индексу
+var bip39Index = func() map[string]int {
+	m := make(map[string]int, len(bip39Words))
+	for i, w := range bip39Words {
+		m[w] = i
+	}
+	return m
+}()
+
+// bip39PrefixLen - длина префикса, однозначно задающего слово BIP39
+const bip39PrefixLen = 4
+
+// bip39Lookup возвращает индекс слова. Как и аппаратные кошельки, принимает
+// первые четыре буквы вместо слова целиком; для слова не из словаря
+// предлагает ближайшие по расстоянию редактирования.
+func bip39Lookup(w string) (int, error) {
+	if index, ok := bip39Index[w]; ok {
+		return index, nil
+	}
+	if len(w) >= bip39PrefixLen {
+		// Словарь отсортирован, и первые четыре буквы в нем не повторяются
+		i := sort.SearchStrings(bip39Words, w)
+		if i < len(bip39Words) && strings.HasPrefix(bip39Words[i], w) {
+			return i, nil
+		}
+	}
+	if near := bip39Suggestions(w); len(near) > 0 {
+		return 0, errorf("%q отсутствует в словаре BIP39, возможно: %s", w, strings.Join(near, ", "))
+	}
+	return 0, errorf("%q отсутствует в словаре BIP39", w)
+}
+
+// bip39MaxSuggestions ограничивает число подсказок для опечатки
+const bip39MaxSuggestions = 3
+
+// bip39Suggestions возвращает слова словаря на минимальном расстоянии
+// редактирования от w: не дальше двух правок, а для коротких слов - одной,
+// иначе подсказки для бессмыслицы были бы случайными словами
+func bip39Suggestions(w string) []string {
+	best := 3
+	if len(w) <= bip39PrefixLen {
+		best = 2
+	}
+	var near []string
+	for _, candidate := range bip39Words {
+		d := editDistance(w, candidate)
+		switch {
+		case d < best:
+			best, near = d, []string{candidate}
+		case d == best && len(near) < bip39MaxSuggestions:
+			near = append(near, candidate)
+		}
+	}
+	return near
+}
+
+// editDistance возвращает расстояние Дамерау-Левенштейна (вставка, удаление,
+// замена и перестановка соседних букв) между a и b
+func editDistance(a, b string) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = minInt(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// minInt возвращает меньшее из двух чисел
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// mnemonicToEntropy декодирует фразу BIP39 и проверяет ее контрольную сумму
+func mnemonicToEntropy(phrase string) ([]byte, error) {
+	words := strings.Fields(strings.ToLower(phrase))
+	if len(words) < 12 || len(words)%3 != 0 {
+		return nil, errorf("фраза из %d слов: ожидается 12, 15, 18, 21, 24 ... слов", len(words))
+	}
+
+	totalBits := len(words) * 11
+	checksumBits := totalBits / 33
+	entropyBits := totalBits - checksumBits
+
+	bits := make([]byte, 0, totalBits)
+	for i, w := range words {
+		index, err := bip39Lookup(w)
+		if err != nil {
+			return nil, errorf("слово #%d: %w", i+1, err)
+		}
+		for j := 10; j >= 0; j-- {
+			bits = append(bits, byte(index>>uint(j))&1)
+		}
+	}
+
+	entropy := make([]byte, entropyBits/8)
+	for i := 0; i < entropyBits; i++ {
+		entropy[i/8] |= bits[i] << (7 - uint(i%8))
+	}
+
+	// Биты сравниваются все сразу, без выхода на первом расхождении
+	checksum := sha256.Sum256(entropy)
+	var diff byte
+	for i := 0; i < checksumBits; i++ {
+		diff |= bits[entropyBits+i] ^ (checksum[i/8]>>(7-uint(i%8)))&1
+	}
+	if diff != 0 {
+		return nil, errorf("неверная контрольная сумма фразы BIP39")
+	}
+	return entropy, nil
+}
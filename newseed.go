@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/rand"
+	"io"
+	"os"
+	"strings"
+)
+
+// validateSeedBits проверяет размер сида для выбранного формата
+func validateSeedBits(bits int, format string) error {
+	if bits < 128 {
+		return errorf("сид короче 128 бит не обеспечивает достаточной стойкости")
+	}
+	if bits%8 != 0 {
+		return errorf("размер сида должен быть кратен 8 битам")
+	}
+	// BIP39 определяет фразы только для 128-256 бит с шагом 32
+	if format == "mnemonic" && (bits > 256 || bits%32 != 0) {
+		return errorf("мнемоника поддерживает 128, 160, 192, 224 или 256 бит")
+	}
+	if (format == "seedqr" || format == "compactseedqr") && bits != 128 && bits != 256 {
+		return errorf("SeedQR поддерживает 128 или 256 бит")
+	}
+	return nil
+}
+
+// runNewSeed генерирует новые сиды устройств из crypto/rand
+func runNewSeed(args []string) error {
+	fs := newFlagSet("newseed")
+	bits := fs.Int("bits", 256, "размер сида в битах")
+	format := fs.String("format", "hex", tr("формат вывода: ")+strings.Join(seedEncodingNames(), ", "))
+	count := fs.Int("count", 1, "количество сидов")
+	dice := fs.Bool("dice", false, "собрать энтропию из бросков кубика вместо crypto/rand")
+	sides := fs.Int("sides", 6, "число граней кубика: 6 или 20")
+	coins := fs.Bool("coins", false, "собрать энтропию из бросков монеты")
+	cards := fs.Bool("cards", false, "собрать энтропию из порядка карт перетасованной колоды")
+	withCheck := fs.Bool("check", false, "добавить к каждому сиду в hex контрольный суффикс \"-XXXX\" для ввода с --seed-check")
+	quiz := fs.Bool("quiz", false, "после вывода мнемоники спросить 3 случайных слова, чтобы проверить запись")
+	showQR := fs.Bool("qr", false, "вывести QR-код SeedQR для сканирования устройством (с --format seedqr или compactseedqr)")
+	groups := addGroupSizeFlag(fs)
+	pf := addProfileFlags(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pf.apply(fs); err != nil {
+		return err
+	}
+
+	if err := validateSeedBits(*bits, *format); err != nil {
+		return err
+	}
+	if *count < 1 {
+		return errorf("количество сидов должно быть положительным")
+	}
+	if *withCheck && *format != "hex" {
+		return errorf("флаг --check применим только к формату hex: остальные форматы содержат свою контрольную сумму")
+	}
+	if *quiz && *format != "mnemonic" {
+		return errorf("флаг --quiz применим только к формату mnemonic")
+	}
+	if *quiz && *count != 1 {
+		return errorf("флаг --quiz проверяет запись одного сида за запуск")
+	}
+	if *showQR && *format != "seedqr" && *format != "compactseedqr" {
+		return errorf("флаг --qr применим только к форматам seedqr и compactseedqr")
+	}
+	if *groups != 0 && *format != "hex" && *format != "bech32" {
+		return errorf("флаг --group-size применим только к форматам hex и bech32")
+	}
+	if *groups != 0 && *withCheck {
+		return errorf("флаг --check не сочетается с --group-size: у каждой строки свой контрольный символ")
+	}
+	single := *showQR || binarySeedEncoding(*format)
+	if single && *count != 1 {
+		return errorf("QR-код и двоичный формат выводятся для одного сида за запуск")
+	}
+	// convert --from читает одну раскладку, поэтому и выводится она для одного сида
+	if *groups != 0 && *count != 1 {
+		return errorf("группами выводится один сид за запуск")
+	}
+
+	var src entropySource
+	sources := 0
+	if *dice {
+		if *sides != 6 && *sides != 20 {
+			return errorf("поддерживаются только кубики d6 и d20")
+		}
+		src = diceSource{sides: *sides}
+		sources++
+	}
+	if *coins {
+		src = coinSource{}
+		sources++
+	}
+	if *cards {
+		src = &cardSource{}
+		sources++
+	}
+	if sources > 1 {
+		return errorf("флаги --dice, --coins и --cards взаимоисключающие")
+	}
+	if src != nil && *count != 1 {
+		return errorf("физическая энтропия собирается для одного сида за запуск")
+	}
+
+	if src != nil || *quiz || single || *groups != 0 {
+		var seed []byte
+		var err error
+		if src != nil {
+			if seed, err = collectEntropy(src, *bits/8, os.Stdin); err != nil {
+				return err
+			}
+		} else {
+			seed = newSecret(*bits / 8)
+			if _, err = rand.Read(seed); err != nil {
+				wipe(seed)
+				return withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+			}
+		}
+		defer wipe(seed)
+		encoded, err := encodeSeed(seed, *format)
+		if err != nil {
+			return err
+		}
+		if *withCheck {
+			encoded += "-" + string(crockfordCheck([]byte(encoded), seedCheckSize))
+		}
+		if *groups != 0 {
+			return writeGrouped(os.Stdout, []byte(encoded), *groups)
+		}
+		if err := writeSeedPayload(encoded, *format, *showQR); err != nil {
+			return err
+		}
+		if *quiz {
+			return quizMnemonic(encoded)
+		}
+		return nil
+	}
+	return writeRandomSeeds(os.Stdout, *count, *bits/8, *format, *withCheck)
+}
+
+// seedBatchSize - размер буфера, которым writeRandomSeeds выводит сиды
+const seedBatchSize = 32 * 1024
+
+// writeRandomSeeds выводит count сидов из crypto/rand по одному на строку.
+// Сид и его строка hex пишутся в одни и те же буферы, а вывод копится в
+// затираемом буфере и уходит блоками: построчный fmt.Println выделял бы
+// строки на каждый сид и делал бы системный вызов на каждую строку.
+// С check к строке hex добавляется контрольный суффикс сида устройства.
+func writeRandomSeeds(w io.Writer, count, size int, format string, check bool) error {
+	seed := newSecret(size)
+	defer wipe(seed)
+	batch := newSecret(seedBatchSize)[:0]
+	defer func() { wipe(batch[:cap(batch)]) }()
+
+	flush := func() error {
+		_, err := w.Write(batch)
+		batch = batch[:0]
+		return err
+	}
+	for i := 0; i < count; i++ {
+		if _, err := rand.Read(seed); err != nil {
+			return withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+		}
+
+		need := 2*size + 1
+		if check {
+			need += 1 + seedCheckSize
+		}
+		var encoded string
+		if format != "hex" {
+			var err error
+			if encoded, err = encodeSeed(seed, format); err != nil {
+				return err
+			}
+			need = len(encoded) + 1
+		}
+		if cap(batch)-len(batch) < need {
+			if err := flush(); err != nil {
+				return err
+			}
+			// Сид длиннее буфера: новый буфер тоже должен быть затираемым
+			if cap(batch) < need {
+				wipe(batch[:cap(batch)])
+				batch = newSecret(need)[:0]
+			}
+		}
+		if format == "hex" {
+			n := len(batch)
+			batch = batch[:n+2*size]
+			encodeHex(batch[n:], seed)
+			if check {
+				batch = append(batch, '-')
+				batch = append(batch, crockfordCheck(batch[n:n+2*size], seedCheckSize)...)
+			}
+		} else {
+			batch = append(batch, encoded...)
+		}
+		batch = append(batch, '\n')
+	}
+	return flush()
+}
@@ -0,0 +1,549 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// operatorNamespace - пространство имен подписей ssh-keygen -Y для
+// подтверждений: подпись из другого контекста (git, файлы) не подойдет
+const operatorNamespace = "seedgen-operator"
+
+// Параметры Argon2id для проверочного значения пароля оператора
+const (
+	operatorArgonPasses  = 3
+	operatorArgonMemory  = 64 * 1024
+	operatorArgonThreads = 4
+)
+
+// operatorMaxFailures - сколько неудачных подтверждений допускается,
+// прежде чем вывод результата будет отменен
+const operatorMaxFailures = 3
+
+// operatorPassphrase - проверочное значение пароля оператора: Argon2id
+// от пароля с солью. Сам пароль в реестре не хранится.
+type operatorPassphrase struct {
+	Salt    string `json:"salt"`
+	Hash    string `json:"hash"`
+	Passes  uint32 `json:"passes"`
+	Memory  uint32 `json:"memory_kib"`
+	Threads uint8  `json:"threads"`
+}
+
+// operatorEntry - оператор реестра. Подтверждает вывод паролем или
+// касанием аппаратного токена через ключ OpenSSH (sk-ssh-ed25519 и т.п.).
+type operatorEntry struct {
+	Name       string              `json:"name"`
+	Passphrase *operatorPassphrase `json:"passphrase,omitempty"`
+	SSHKey     string              `json:"ssh_public_key,omitempty"`
+	SSHKeyFile string              `json:"ssh_key_file,omitempty"`
+	AddedAt    string              `json:"added_at"`
+}
+
+// method возвращает способ подтверждения для вывода
+func (e operatorEntry) method() string {
+	if e.SSHKey != "" {
+		return tr("ключ ") + strings.Fields(e.SSHKey)[0]
+	}
+	return tr("пароль")
+}
+
+// operatorRegistry - реестр операторов, подтверждающих вывод результата
+type operatorRegistry struct {
+	Kind      string          `json:"kind"`
+	Operators []operatorEntry `json:"operators"`
+}
+
+// loadOperatorRegistry читает реестр; отсутствующий файл означает пустой реестр
+func loadOperatorRegistry(path string) (*operatorRegistry, []byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &operatorRegistry{Kind: "operator-registry"}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var r operatorRegistry
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, nil, errorf("%s: %w", path, err)
+	}
+	if r.Kind != "operator-registry" {
+		return nil, nil, errorf("%s: ожидался реестр операторов, получен %q", path, r.Kind)
+	}
+	return &r, data, nil
+}
+
+// save записывает реестр через временный файл, чтобы не повредить его при сбое
+func (r *operatorRegistry) save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// find возвращает оператора по имени
+func (r *operatorRegistry) find(name string) *operatorEntry {
+	for i := range r.Operators {
+		if r.Operators[i].Name == name {
+			return &r.Operators[i]
+		}
+	}
+	return nil
+}
+
+// registryFingerprint возвращает SHA-256 файла реестра: его закрепляют
+// в профиле или регламенте через --operators-sha256, чтобы реестр нельзя
+// было незаметно дополнить своими записями
+func registryFingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// validOperatorName проверяет имя: оно становится принципалом в списке
+// allowed_signers ssh-keygen и не должно содержать пробелов и кавычек
+func validOperatorName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// readHidden читает строку из терминала без эха. Перевод строки в конце
+// отбрасывается, остальные символы, включая пробелы, входят в пароль.
+// Где терминал позволяет посимвольный ввод, вместо символов видны звездочки.
+func readHidden(in io.Reader, w io.Writer, prompt string) ([]byte, error) {
+	fmt.Fprint(w, prompt)
+	if f, ok := in.(*os.File); ok && isTerminal(f) {
+		if restore, err := enterMaskedMode(f); err == nil {
+			defer restore()
+			buf := newSecret(maskedLineSize)
+			line, err := readMasked(f, w, buf)
+			if err != nil {
+				wipe(buf)
+				return nil, err
+			}
+			return line, nil
+		}
+	}
+	if f, ok := in.(*os.File); ok {
+		restore, err := disableEcho(f)
+		if err != nil {
+			fmt.Fprintf(w, tr("\n⚠ Не удалось скрыть ввод (%v), пароль будет виден на экране\n"), err)
+		} else {
+			defer restore()
+		}
+	}
+	defer fmt.Fprintln(w)
+
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := in.Read(b); err != nil {
+			wipe(line)
+			return nil, err
+		}
+		if b[0] == '\n' {
+			break
+		}
+		line = append(line, b[0])
+	}
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line, nil
+}
+
+// newOperatorPassphrase вычисляет проверочное значение пароля
+func newOperatorPassphrase(passphrase []byte) (*operatorPassphrase, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	hash := argon2IDKey(passphrase, salt, operatorArgonPasses, operatorArgonMemory, operatorArgonThreads)
+	return &operatorPassphrase{
+		Salt:    hex.EncodeToString(salt),
+		Hash:    hex.EncodeToString(hash),
+		Passes:  operatorArgonPasses,
+		Memory:  operatorArgonMemory,
+		Threads: operatorArgonThreads,
+	}, nil
+}
+
+// check сверяет пароль с проверочным значением за постоянное время
+func (p *operatorPassphrase) check(passphrase []byte) bool {
+	salt, err := hex.DecodeString(p.Salt)
+	if err != nil {
+		return false
+	}
+	want, err := hex.DecodeString(p.Hash)
+	if err != nil {
+		return false
+	}
+	got := argon2IDKey(passphrase, salt, p.Passes, p.Memory, p.Threads)
+	defer wipe(got)
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// sshKeygen запускает ssh-keygen в каталоге dir. Stderr подключен
+// к терминалу: ssh-keygen сам просит пароль ключа и касание токена.
+func sshKeygen(dir string, stdin io.Reader, args ...string) error {
+	cmd := exec.Command("ssh-keygen", args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	cmd.Stdout = io.Discard
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if _, lookErr := exec.LookPath("ssh-keygen"); lookErr != nil {
+			return errorf("ssh-keygen не найден: подтверждение ключом требует OpenSSH 8.2 или новее")
+		}
+		return err
+	}
+	return nil
+}
+
+// confirmSSH просит оператора подписать вызов ключом entry и проверяет
+// подпись по открытому ключу из реестра, а не по файлу рядом с ключом:
+// подмена файла ключа не поможет подтвердить вывод
+func confirmSSH(entry *operatorEntry, challenge []byte) error {
+	dir, err := os.MkdirTemp("", "seedgen-operator-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	msg := filepath.Join(dir, "challenge")
+	if err := os.WriteFile(msg, challenge, 0600); err != nil {
+		return err
+	}
+	if err := sshKeygen(dir, nil, "-Y", "sign", "-q", "-n", operatorNamespace, "-f", entry.SSHKeyFile, msg); err != nil {
+		return errorf("подпись вызова не получена: %w", err)
+	}
+
+	signers := filepath.Join(dir, "allowed_signers")
+	line := fmt.Sprintf("%s namespaces=%q %s\n", entry.Name, operatorNamespace, entry.SSHKey)
+	if err := os.WriteFile(signers, []byte(line), 0600); err != nil {
+		return err
+	}
+	err = sshKeygen(dir, strings.NewReader(string(challenge)), "-Y", "verify", "-f", signers, "-I", entry.Name, "-n", operatorNamespace, "-s", msg+".sig")
+	if err != nil {
+		return mismatchf("подпись не соответствует ключу оператора %s", entry.Name)
+	}
+	return nil
+}
+
+// confirm получает подтверждение оператора: пароль или подпись вызова
+func (e *operatorEntry) confirm(in io.Reader, w io.Writer, challenge []byte) error {
+	if e.SSHKey != "" {
+		fmt.Fprintf(w, tr("Подпишите вызов ключом %s (коснитесь токена, если он попросит)\n"), e.SSHKeyFile)
+		return confirmSSH(e, challenge)
+	}
+	passphrase, err := readHidden(in, w, fmt.Sprintf(tr("Пароль оператора %s: "), e.Name))
+	if err != nil {
+		return errorf("ввод пароля прерван")
+	}
+	defer wipe(passphrase)
+	if !e.Passphrase.check(passphrase) {
+		return errorf("неверный пароль оператора %s", e.Name)
+	}
+	return nil
+}
+
+// quorumFlags - флаги подтверждения вывода несколькими операторами
+type quorumFlags struct {
+	path   *string
+	quorum *int
+	pin    *string
+
+	registry    *operatorRegistry
+	fingerprint string
+}
+
+// addQuorumFlags регистрирует флаги подтверждения в наборе
+func addQuorumFlags(fs *flag.FlagSet) *quorumFlags {
+	return &quorumFlags{
+		path:   fs.String("operators", "", "реестр операторов (seedgen operators add): вывод результата только после подтверждения --quorum из них"),
+		quorum: fs.Int("quorum", 2, "сколько разных операторов реестра должны подтвердить вывод"),
+		pin:    fs.String("operators-sha256", "", "ожидаемый SHA-256 файла реестра операторов (seedgen operators list)"),
+	}
+}
+
+// load читает и проверяет реестр до ввода сидов, чтобы ошибка в нем
+// не обнаружилась после церемонии
+func (qf *quorumFlags) load() error {
+	if *qf.path == "" {
+		return nil
+	}
+	r, data, err := loadOperatorRegistry(*qf.path)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return errorf("реестр операторов %s не найден", *qf.path)
+	}
+	qf.fingerprint = registryFingerprint(data)
+	if *qf.pin != "" && !strings.EqualFold(*qf.pin, qf.fingerprint) {
+		return mismatchf("SHA-256 реестра операторов %s не совпадает с --operators-sha256: реестр изменен", qf.fingerprint)
+	}
+	if *qf.quorum < 2 {
+		return errorf("двойной контроль требует --quorum не меньше 2")
+	}
+	if *qf.quorum > len(r.Operators) {
+		return errorf("--quorum %d больше числа операторов в реестре (%d)", *qf.quorum, len(r.Operators))
+	}
+	qf.registry = r
+	return nil
+}
+
+// confirm требует подтверждений --quorum разных операторов, прежде чем
+// результат будет показан, записан или сохранен. Каждый вызов уникален
+// и привязан к отпечатку результата, поэтому подпись прошлого запуска
+// не подойдет. Без --operators ничего не делает.
+func (qf *quorumFlags) confirm(w io.Writer, fingerprint string) error {
+	if qf.registry == nil {
+		return nil
+	}
+	in, closeInput, err := openConfirmInput()
+	if err != nil {
+		return errorf("подтверждение операторов требует терминала: %w", err)
+	}
+	defer closeInput()
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	challenge := []byte(fmt.Sprintf("seedgen: вывод результата %s\nвызов: %x\n", fingerprint, nonce))
+
+	names := make([]string, 0, len(qf.registry.Operators))
+	for _, e := range qf.registry.Operators {
+		names = append(names, e.Name)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, tr("Вывод результата требует подтверждения %d операторов из %d: %s\n"), *qf.quorum, len(names), strings.Join(names, ", "))
+	fmt.Fprintf(w, tr("Реестр операторов: SHA-256 %s\n"), qf.fingerprint)
+
+	confirmed := make(map[string]bool)
+	failures := 0
+	for len(confirmed) < *qf.quorum {
+		fmt.Fprint(w, tr("Имя оператора: "))
+		name, err := readAnswer(in)
+		if err != nil {
+			return errorf("подтверждение прервано, результат не выведен")
+		}
+		entry := qf.registry.find(name)
+		switch {
+		case entry == nil:
+			fmt.Fprintf(w, tr("⚠ Оператора %q нет в реестре\n"), name)
+			continue
+		case confirmed[name]:
+			fmt.Fprintf(w, tr("⚠ Оператор %s уже подтвердил, нужен другой оператор\n"), name)
+			continue
+		}
+		if err := entry.confirm(in, w, challenge); err != nil {
+			failures++
+			fmt.Fprintf(w, "❌ %v\n", err)
+			if failures >= operatorMaxFailures {
+				return errorf("подтверждение не получено после %d неудачных попыток, результат не выведен", failures)
+			}
+			continue
+		}
+		confirmed[name] = true
+		fmt.Fprintf(w, tr("✓ Подтверждение оператора %s (%d из %d)\n"), name, len(confirmed), *qf.quorum)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// runOperators ведет реестр операторов
+func runOperators(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, tr("Использование:"))
+		fmt.Fprintln(os.Stderr, "  seedgen operators add --registry operators.json --name alice [--ssh-key ~/.ssh/id_ed25519_sk]")
+		fmt.Fprintln(os.Stderr, "  seedgen operators remove --registry operators.json --name alice")
+		fmt.Fprintln(os.Stderr, "  seedgen operators list --registry operators.json")
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return errorf("укажите действие: add, remove или list")
+	}
+
+	switch args[0] {
+	case "add":
+		return runOperatorsAdd(args[1:])
+	case "remove":
+		return runOperatorsRemove(args[1:])
+	case "list":
+		return runOperatorsList(args[1:])
+	default:
+		return errorf("неизвестное действие %q, доступны: add, remove, list", args[0])
+	}
+}
+
+// runOperatorsAdd добавляет оператора. Пароль вводит сам оператор;
+// владение ключом подтверждается пробной подписью при регистрации.
+func runOperatorsAdd(args []string) error {
+	fs := newFlagSet("operators add")
+	path := fs.String("registry", "operators.json", "файл реестра операторов")
+	name := fs.String("name", "", "имя оператора")
+	sshKey := fs.String("ssh-key", "", "закрытый ключ OpenSSH или дескриптор ключа токена (рядом должен лежать .pub) вместо пароля")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if !validOperatorName(*name) {
+		return errorf("укажите имя оператора через --name: латинские буквы, цифры, '-', '_' и '.'")
+	}
+	r, _, err := loadOperatorRegistry(*path)
+	if err != nil {
+		return err
+	}
+	if r.find(*name) != nil {
+		return errorf("оператор %s уже есть в реестре", *name)
+	}
+	entry := operatorEntry{Name: *name, AddedAt: time.Now().UTC().Format(time.RFC3339)}
+
+	in, closeInput, err := openConfirmInput()
+	if err != nil {
+		return errorf("регистрация оператора требует терминала: %w", err)
+	}
+	defer closeInput()
+
+	if *sshKey != "" {
+		pub, err := os.ReadFile(*sshKey + ".pub")
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(string(pub))
+		if len(fields) < 2 || !(strings.HasPrefix(fields[0], "ssh-") || strings.HasPrefix(fields[0], "sk-") || strings.HasPrefix(fields[0], "ecdsa-")) {
+			return errorf("%s.pub: не открытый ключ OpenSSH", *sshKey)
+		}
+		abs, err := filepath.Abs(*sshKey)
+		if err != nil {
+			return err
+		}
+		entry.SSHKey = fields[0] + " " + fields[1]
+		entry.SSHKeyFile = abs
+		fmt.Println(tr("Пробная подпись подтверждает, что ключ доступен оператору"))
+		if err := entry.confirm(in, os.Stdout, []byte("seedgen: регистрация оператора "+*name+"\n")); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf(tr("Оператор %s, придумайте пароль (не короче 12 символов) и не сообщайте его другим операторам\n"), *name)
+		passphrase, err := readHidden(in, os.Stdout, tr("Пароль: "))
+		if err != nil {
+			return errorf("ввод пароля прерван")
+		}
+		defer wipe(passphrase)
+		if len(passphrase) < 12 {
+			return errorf("пароль короче 12 символов")
+		}
+		repeat, err := readHidden(in, os.Stdout, tr("Повторите пароль: "))
+		if err != nil {
+			return errorf("ввод пароля прерван")
+		}
+		defer wipe(repeat)
+		if subtle.ConstantTimeCompare(passphrase, repeat) != 1 {
+			return errorf("пароли не совпадают")
+		}
+		for _, other := range r.Operators {
+			if other.Passphrase != nil && other.Passphrase.check(passphrase) {
+				return errorf("пароль совпадает с паролем оператора %s", other.Name)
+			}
+		}
+		if entry.Passphrase, err = newOperatorPassphrase(passphrase); err != nil {
+			return err
+		}
+	}
+
+	r.Operators = append(r.Operators, entry)
+	if err := r.save(*path); err != nil {
+		return err
+	}
+	fmt.Printf(tr("✓ Оператор %s добавлен в %s (%s), всего операторов: %d\n"), *name, *path, entry.method(), len(r.Operators))
+	return printRegistryFingerprint(*path)
+}
+
+// runOperatorsRemove удаляет оператора из реестра
+func runOperatorsRemove(args []string) error {
+	fs := newFlagSet("operators remove")
+	path := fs.String("registry", "operators.json", "файл реестра операторов")
+	name := fs.String("name", "", "имя оператора")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	r, data, err := loadOperatorRegistry(*path)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return errorf("реестр операторов %s не найден", *path)
+	}
+	kept := r.Operators[:0]
+	for _, e := range r.Operators {
+		if e.Name != *name {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == len(r.Operators) {
+		return errorf("оператора %q нет в реестре", *name)
+	}
+	r.Operators = kept
+	if err := r.save(*path); err != nil {
+		return err
+	}
+	fmt.Printf(tr("✓ Оператор %s удален, осталось операторов: %d\n"), *name, len(r.Operators))
+	return printRegistryFingerprint(*path)
+}
+
+// runOperatorsList выводит операторов и SHA-256 реестра
+func runOperatorsList(args []string) error {
+	fs := newFlagSet("operators list")
+	path := fs.String("registry", "operators.json", "файл реестра операторов")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	r, data, err := loadOperatorRegistry(*path)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return errorf("реестр операторов %s не найден", *path)
+	}
+	for _, e := range r.Operators {
+		fmt.Printf(tr("%-16s %-28s добавлен %s\n"), e.Name, e.method(), e.AddedAt)
+	}
+	return printRegistryFingerprint(*path)
+}
+
+// printRegistryFingerprint выводит SHA-256 реестра для --operators-sha256
+func printRegistryFingerprint(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(tr("SHA-256 реестра: %s\n"), registryFingerprint(data))
+	fmt.Println(tr("Закрепите его в профиле (operators-sha256) и храните реестр на носителе только для чтения"))
+	return nil
+}
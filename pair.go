@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// swissPlayer - участник швейцарской системы с историей прошлых туров.
+// Очки хранятся в половинках, чтобы ничьи сравнивались без дробей.
+type swissPlayer struct {
+	Name      string
+	Halves    int
+	Opponents []string
+	Colors    string
+}
+
+// hadBye сообщает, что участник уже пропускал тур
+func (p *swissPlayer) hadBye() bool {
+	return strings.Contains(p.Colors, "-")
+}
+
+// played сообщает, что участники уже встречались
+func (p *swissPlayer) played(name string) bool {
+	for _, o := range p.Opponents {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Сила цветового предпочтения по правилам FIDE
+const (
+	colorNone     = iota // еще не играл
+	colorMild            // поровну белых и черных: хочет цвет, противоположный последнему
+	colorStrong          // на одну партию одного цвета больше
+	colorAbsolute        // разница больше одной или два последних цвета одинаковы
+)
+
+// colorPreference возвращает желаемый цвет (W или B) и силу предпочтения
+func (p *swissPlayer) colorPreference() (byte, int) {
+	diff := 0
+	var played []byte
+	for i := 0; i < len(p.Colors); i++ {
+		switch p.Colors[i] {
+		case 'W':
+			diff++
+			played = append(played, 'W')
+		case 'B':
+			diff--
+			played = append(played, 'B')
+		}
+	}
+	if len(played) == 0 {
+		return 0, colorNone
+	}
+	last := played[len(played)-1]
+	opposite := byte('W')
+	if last == 'W' {
+		opposite = 'B'
+	}
+	switch {
+	case diff >= 2:
+		return 'B', colorAbsolute
+	case diff <= -2:
+		return 'W', colorAbsolute
+	case len(played) >= 2 && played[len(played)-2] == last:
+		return opposite, colorAbsolute
+	case diff == 1:
+		return 'B', colorStrong
+	case diff == -1:
+		return 'W', colorStrong
+	}
+	return opposite, colorMild
+}
+
+// formatHalves выводит очки из половинок: 3, 2.5
+func formatHalves(h int) string {
+	if h%2 == 0 {
+		return strconv.Itoa(h / 2)
+	}
+	return strconv.Itoa(h/2) + ".5"
+}
+
+// readStandings читает положение из CSV со строками
+// "участник,очки,соперники,цвета": соперники прошлых туров через ;
+// ("-" - пропуск тура), цвета - строка из W (белые или хозяева), B (черные
+// или гости) и - (пропуск) по турам. Заголовок (первое поле "player"),
+// пустые строки и строки с # пропускаются. Возвращает участников в порядке
+// файла и SHA-256 канонической записи для сверки.
+func readStandings(path string) ([]*swissPlayer, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	var players []*swissPlayer
+	seen := make(map[string]bool)
+	var canonical []string
+	scanner := bufio.NewScanner(f)
+	for line, first := 1, true; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		r := csv.NewReader(strings.NewReader(text))
+		r.TrimLeadingSpace = true
+		rec, err := r.Read()
+		if err != nil {
+			return nil, "", errorf("%s:%d: %w", path, line, err)
+		}
+		if first {
+			first = false
+			if strings.EqualFold(strings.TrimSpace(rec[0]), "player") {
+				continue
+			}
+		}
+		for len(rec) < 4 {
+			rec = append(rec, "")
+		}
+		if len(rec) > 4 {
+			return nil, "", errorf("%s:%d: ожидается участник,очки,соперники,цвета", path, line)
+		}
+		p := &swissPlayer{Name: strings.TrimSpace(rec[0]), Colors: strings.ToUpper(strings.TrimSpace(rec[3]))}
+		if p.Name == "" || p.Name == "-" {
+			return nil, "", errorf("%s:%d: некорректное имя участника %q", path, line, rec[0])
+		}
+		if seen[p.Name] {
+			return nil, "", errorf("%s:%d: участник %q уже указан", path, line, p.Name)
+		}
+		seen[p.Name] = true
+		score, err := strconv.ParseFloat(strings.TrimSpace(rec[1]), 64)
+		if err != nil || score < 0 || score*2 != float64(int(score*2)) {
+			return nil, "", errorf("%s:%d: некорректные очки %q: ожидается число, кратное 0.5", path, line, rec[1])
+		}
+		p.Halves = int(score * 2)
+		for _, o := range strings.Split(rec[2], ";") {
+			if o = strings.TrimSpace(o); o != "" {
+				p.Opponents = append(p.Opponents, o)
+			}
+		}
+		if strings.Trim(p.Colors, "WB-") != "" {
+			return nil, "", errorf("%s:%d: цвета записываются буквами W, B и -", path, line)
+		}
+		if len(p.Colors) != len(p.Opponents) {
+			return nil, "", errorf("%s:%d: соперников %d, а цветов %d", path, line, len(p.Opponents), len(p.Colors))
+		}
+		for i, o := range p.Opponents {
+			if (o == "-") != (p.Colors[i] == '-') {
+				return nil, "", errorf("%s:%d: тур %d: пропуск должен быть отмечен и в соперниках, и в цветах", path, line, i+1)
+			}
+		}
+		players = append(players, p)
+		canonical = append(canonical, strings.Join([]string{p.Name, formatHalves(p.Halves), strings.Join(p.Opponents, ";"), p.Colors}, ","))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256([]byte(strings.Join(canonical, "\n")))
+	digest := hex.EncodeToString(sum[:])
+	auditInput("file", path, digest)
+	return players, digest, nil
+}
+
+// swissPairing - пара тура; пустой Black означает пропуск тура
+type swissPairing struct {
+	Board int    `json:"board"`
+	White string `json:"white"`
+	Black string `json:"black,omitempty"`
+	matchRef
+}
+
+// swissRound - жеребьевка тура по швейцарской системе
+type swissRound struct {
+	Kind        string         `json:"kind"`
+	Label       string         `json:"label"`
+	Round       int            `json:"round"`
+	Standings   string         `json:"standings_sha256"`
+	Fingerprint string         `json:"master_fingerprint"`
+	Ranking     []string       `json:"ranking"`
+	Pairings    []swissPairing `json:"pairings"`
+	Bye         string         `json:"bye,omitempty"`
+}
+
+// swissPairer подбирает пары перебором с возвратом
+type swissPairer struct {
+	ranked []*swissPlayer
+	paired []bool
+	pairs  [][2]int
+	// failed - наборы свободных участников, которые уже не удалось разбить на пары
+	failed map[string]bool
+}
+
+// compatible проверяет, что участники не встречались и не требуют
+// безусловно одного и того же цвета
+func compatible(a, b *swissPlayer) bool {
+	if a.played(b.Name) {
+		return false
+	}
+	ca, sa := a.colorPreference()
+	cb, sb := b.colorPreference()
+	return !(sa == colorAbsolute && sb == colorAbsolute && ca == cb)
+}
+
+// stateKey возвращает ключ набора свободных участников
+func (s *swissPairer) stateKey() string {
+	key := make([]byte, len(s.paired))
+	for i, p := range s.paired {
+		if p {
+			key[i] = 1
+		}
+	}
+	return string(key)
+}
+
+// candidates возвращает возможных соперников участника i в порядке
+// предпочтения. Внутри группы с теми же очками верхняя половина играет
+// с нижней по порядку (голландская система), затем идут остальные
+// участники группы и участники с меньшими очками по рейтингу.
+func (s *swissPairer) candidates(i int) []int {
+	var group, lower []int
+	for j := i + 1; j < len(s.ranked); j++ {
+		if s.paired[j] {
+			continue
+		}
+		if s.ranked[j].Halves == s.ranked[i].Halves {
+			group = append(group, j)
+		} else {
+			lower = append(lower, j)
+		}
+	}
+	// В группе вместе с i всего len(group)+1 участников; нижняя половина
+	// начинается с позиции (len(group)+1)/2, считая i нулевым
+	half := (len(group) + 1) / 2
+	if half > 0 {
+		half--
+	}
+	order := append(append([]int(nil), group[half:]...), group[:half]...)
+	return append(order, lower...)
+}
+
+// pairFrom разбивает свободных участников на пары, начиная с сильнейшего
+func (s *swissPairer) pairFrom() bool {
+	i := 0
+	for i < len(s.ranked) && s.paired[i] {
+		i++
+	}
+	if i == len(s.ranked) {
+		return true
+	}
+	key := s.stateKey()
+	if s.failed[key] {
+		return false
+	}
+	s.paired[i] = true
+	for _, j := range s.candidates(i) {
+		if !compatible(s.ranked[i], s.ranked[j]) {
+			continue
+		}
+		s.paired[j] = true
+		s.pairs = append(s.pairs, [2]int{i, j})
+		if s.pairFrom() {
+			return true
+		}
+		s.pairs = s.pairs[:len(s.pairs)-1]
+		s.paired[j] = false
+	}
+	s.paired[i] = false
+	s.failed[key] = true
+	return false
+}
+
+// assignColors распределяет цвета в паре: каждый получает желаемый цвет,
+// если желания не совпадают; иначе цвет получает участник с более сильным
+// предпочтением, а при равных - стоящий выше. Если ни у кого нет
+// предпочтения, цвет сильнейшего определяет поток жеребьевки.
+func assignColors(stream *drawStream, a, b *swissPlayer) (white, black string) {
+	ca, sa := a.colorPreference()
+	cb, sb := b.colorPreference()
+	var aWhite bool
+	switch {
+	case sa == colorNone && sb == colorNone:
+		aWhite = stream.intn(2) == 0
+	case sb == colorNone || (ca != cb && sa != colorNone) || sa >= sb:
+		aWhite = ca == 'W'
+	default:
+		aWhite = cb == 'B'
+	}
+	if aWhite {
+		return a.Name, b.Name
+	}
+	return b.Name, a.Name
+}
+
+// pairSwiss составляет пары тура. Участники упорядочиваются по очкам,
+// равные очки - по перестановке из потока. Пропуск тура при нечетном числе
+// получает участник с наименьшим местом, еще не пропускавший тур, если
+// остальных можно разбить на пары.
+func pairSwiss(stream *drawStream, players []*swissPlayer) ([]*swissPlayer, []swissPairing, string, error) {
+	ranked := append([]*swissPlayer(nil), players...)
+	stream.shuffle(len(ranked), func(i, j int) { ranked[i], ranked[j] = ranked[j], ranked[i] })
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Halves > ranked[j].Halves })
+
+	s := &swissPairer{ranked: ranked, paired: make([]bool, len(ranked)), failed: make(map[string]bool)}
+	bye := -1
+	if len(ranked)%2 == 1 {
+		for i := len(ranked) - 1; i >= 0 && bye < 0; i-- {
+			if ranked[i].hadBye() {
+				continue
+			}
+			s.paired[i] = true
+			if s.pairFrom() {
+				bye = i
+				break
+			}
+			s.paired[i] = false
+		}
+		if bye < 0 {
+			return nil, nil, "", errorf("нельзя выбрать пропуск тура: все участники уже пропускали тур или оставшихся нельзя разбить на пары")
+		}
+	} else if !s.pairFrom() {
+		return nil, nil, "", errorf("нельзя составить пары без повторных встреч и с допустимыми цветами")
+	}
+
+	var pairings []swissPairing
+	for n, p := range s.pairs {
+		white, black := assignColors(stream, ranked[p[0]], ranked[p[1]])
+		pairings = append(pairings, swissPairing{Board: n + 1, White: white, Black: black})
+	}
+	byeName := ""
+	if bye >= 0 {
+		byeName = ranked[bye].Name
+	}
+	return ranked, pairings, byeName, nil
+}
+
+// runPair составляет пары тура из опубликованного мастер-сида
+func runPair(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, tr("Использование:"))
+		fmt.Fprintln(os.Stderr, "  seedgen pair swiss --master result.json --round 3 --standings standings.csv [--format csv]")
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return errorf("укажите систему: swiss")
+	}
+
+	switch args[0] {
+	case "swiss":
+		return runPairSwiss(args[1:])
+	default:
+		return errorf("неизвестная система %q, доступны: swiss", args[0])
+	}
+}
+
+// runPairSwiss составляет пары тура по швейцарской системе
+func runPairSwiss(args []string) error {
+	fs := newFlagSet("pair swiss")
+	round := fs.Int("round", 0, "номер тура, для которого составляются пары")
+	standingsPath := fs.String("standings", "", "CSV положения: участник,очки,соперники через ;,цвета (W, B, -)")
+	label := fs.String("label", "swiss", "метка турнира: разные метки дают независимые жеребьевки из одного мастер-сида")
+	format := fs.String("format", "text", "формат вывода: text, csv или json")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *round < 1 {
+		return errorf("укажите номер тура через --round")
+	}
+	if *standingsPath == "" {
+		return errorf("укажите файл положения через --standings")
+	}
+	if *format != "text" && *format != "csv" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	players, digest, err := readStandings(*standingsPath)
+	if err != nil {
+		return err
+	}
+	if len(players) < 2 || len(players) > 1024 {
+		return errorf("участников должно быть от 2 до 1024, указано %d", len(players))
+	}
+	for _, p := range players {
+		if len(p.Colors) != *round-1 {
+			return errorf("у участника %s история %d туров, а составляются пары тура %d", p.Name, len(p.Colors), *round)
+		}
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	// У каждого тура свой поток: порядок при равных очках не переносится между турами
+	stream := newDrawStream(master, "pair-swiss", fmt.Sprintf("%s/%d", *label, *round))
+	ranked, pairings, bye, err := pairSwiss(stream, players)
+	if err != nil {
+		return err
+	}
+	result := swissRound{
+		Kind:        "swiss-pairing",
+		Label:       *label,
+		Round:       *round,
+		Standings:   digest,
+		Fingerprint: masterFingerprint(master),
+		Pairings:    pairings,
+		Bye:         bye,
+	}
+	for _, p := range ranked {
+		result.Ranking = append(result.Ranking, p.Name)
+	}
+	ns := uuidNamespace(master)
+	for i := range pairings {
+		pairings[i].matchRef = newMatchRef(ns, matchName(*label, fmt.Sprintf("R%d-B%d", *round, pairings[i].Board)))
+	}
+
+	switch *format {
+	case "json":
+		return writeOutput(os.Stdout, result)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"board", "white", "black", "short_id", "uuid"})
+		for _, p := range pairings {
+			w.Write([]string{strconv.Itoa(p.Board), p.White, p.Black, p.Short, p.UUID})
+		}
+		if bye != "" {
+			w.Write([]string{"", bye, "", "", ""})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	halves := make(map[string]int, len(players))
+	for _, p := range players {
+		halves[p.Name] = p.Halves
+	}
+	fmt.Printf(tr("Тур %d турнира %q: участников %d\n"), result.Round, result.Label, len(players))
+	fmt.Printf(tr("Мастер-сид: %s..., положение: SHA-256 %s\n"), emph(result.Fingerprint), result.Standings)
+	fmt.Println()
+	for _, p := range pairings {
+		fmt.Printf("  %3d. %s (%s) - %s (%s)\n", p.Board, p.White, formatHalves(halves[p.White]), p.Black, formatHalves(halves[p.Black]))
+	}
+	if bye != "" {
+		fmt.Printf(tr("  пропускает тур: %s (%s)\n"), bye, formatHalves(halves[bye]))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// paramsManifest - параметры схемы без секретов, по которым церемонию можно
+// повторить с побайтно теми же настройками (--params-file)
+type paramsManifest struct {
+	Kind string `json:"kind"`
+	Params
+	KDFPreset string `json:"kdf_preset,omitempty"`
+	Version   string `json:"seedgen_version"`
+}
+
+// writeParamsManifest сохраняет манифест параметров в новый файл; preset -
+// имя пресета KDF, если параметры взяты из него
+func writeParamsManifest(w io.Writer, path string, p Params, preset string) error {
+	data, err := json.MarshalIndent(paramsManifest{Kind: "params-manifest", Params: p, KDFPreset: preset, Version: buildVersion()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(path, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, tr("✓ Манифест параметров сохранен: %s\n"), path)
+	return nil
+}
+
+// loadParamsFile читает параметры из манифеста или из JSON-результата generate:
+// результат содержит те же поля, а мастер-сид при разборе пропускается
+func loadParamsFile(path string) (Params, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Params{}, err
+	}
+	lockSecret(data)
+	defer wipe(data)
+	var m paramsManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Params{}, errorf("не удалось разобрать %s: %w", path, err)
+	}
+	switch m.Kind {
+	case "params-manifest", "master-seed":
+	default:
+		return Params{}, errorf("%s не является манифестом параметров или результатом generate", path)
+	}
+	if err := m.Params.Validate(); err != nil {
+		return Params{}, errorf("%s: %w", path, err)
+	}
+	return m.Params, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// passwordManager - запись менеджера паролей, с которой seedgen работает
+// через его CLI: pass (password-store) или 1Password (op)
+type passwordManager interface {
+	// ref возвращает ссылку на запись для --master и --seeds-from
+	ref() string
+	// checkFree до ввода сидов проверяет CLI и вход и что записи еще нет
+	checkFree() error
+	// writeMaster создает запись с мастер-сидом в hex и его отпечатком
+	writeMaster(master []byte) error
+	// read возвращает содержимое записи или поля
+	read() ([]byte, error)
+}
+
+// passwordManagerFor разбирает ссылку pass:путь или op://хранилище/запись
+func passwordManagerFor(ref string) (passwordManager, error) {
+	switch {
+	case strings.HasPrefix(ref, "pass:"):
+		return newPassEntry(strings.TrimPrefix(ref, "pass:"))
+	case strings.HasPrefix(ref, "op://"):
+		return newOnePasswordItem(strings.TrimPrefix(ref, "op://"))
+	}
+	return nil, errorf("неизвестное хранилище %q: ожидается pass:путь или op://хранилище/запись", ref)
+}
+
+// runManagerCLI запускает CLI менеджера паролей с данными stdin и
+// возвращает stdout. Ошибка содержит stderr программы.
+func runManagerCLI(stdin []byte, name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, errorf("не найден %s", name)
+	}
+	logDebug("запуск менеджера паролей", "command", name+" "+args[0])
+	cmd := exec.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		wipe(stdout.Bytes())
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errorf("%s %s: %s", name, args[0], msg)
+		}
+		return nil, errorf("%s %s: %w", name, args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseManagerMaster разбирает запись с мастер-сидом: первая строка -
+// hex, в следующих может быть строка "fingerprint: ...", как ее
+// записывает seedgen. Отпечаток, если он есть, сверяется с мастер-сидом.
+func parseManagerMaster(ref string, data []byte) ([]byte, error) {
+	first, rest := data, []byte(nil)
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		first, rest = data[:i], data[i+1:]
+	}
+	master, ok, err := decodeMasterHex(bytes.TrimSpace(first))
+	if !ok {
+		return nil, errorf("запись %s не содержит мастер-сид в hex в первой строке", ref)
+	}
+	if err != nil {
+		return nil, errorf("запись %s: %w", ref, err)
+	}
+	prefix := []byte("fingerprint: ")
+	for _, line := range bytes.Split(rest, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, prefix) && string(line[len(prefix):]) != masterFingerprint(master) {
+			wipe(master)
+			return nil, mismatchf("запись %s: отпечаток мастер-сида не совпадает с записанным", ref)
+		}
+	}
+	return master, nil
+}
+
+// managerEntry возвращает содержимое записи мастер-сида: hex, отпечаток и
+// версию seedgen по строке
+func managerEntry(master []byte) []byte {
+	tail := "\nfingerprint: " + masterFingerprint(master) + "\nseedgen: " + version + "\n"
+	entry := newSecret(len(master)*2 + len(tail))
+	encodeHex(entry, master)
+	copy(entry[len(master)*2:], tail)
+	return entry
+}
+
+// loadPass читает мастер-сид по ссылке pass:путь
+func loadPass(name string) ([]byte, error) {
+	return loadManagerMaster("pass:" + name)
+}
+
+// loadOnePassword читает мастер-сид по ссылке op://хранилище/запись[/поле]
+func loadOnePassword(name string) ([]byte, error) {
+	return loadManagerMaster("op:" + name)
+}
+
+// loadManagerMaster читает запись менеджера паролей и разбирает мастер-сид
+func loadManagerMaster(ref string) ([]byte, error) {
+	pm, err := passwordManagerFor(ref)
+	if err != nil {
+		return nil, err
+	}
+	data, err := pm.read()
+	if err != nil {
+		return nil, err
+	}
+	lockSecret(data)
+	defer wipe(data)
+	return parseManagerMaster(ref, data)
+}
+
+// readManagerSeeds читает сиды по строке из записей менеджеров паролей
+func readManagerSeeds(refs []string) ([][]byte, error) {
+	var seeds [][]byte
+	for _, ref := range refs {
+		read, err := readManagerSeedsFrom(ref)
+		if err != nil {
+			wipeSeeds(seeds)
+			return nil, err
+		}
+		seeds = append(seeds, read...)
+	}
+	return seeds, nil
+}
+
+// readManagerSeedsFrom читает сиды из одной записи
+func readManagerSeedsFrom(ref string) ([][]byte, error) {
+	pm, err := passwordManagerFor(ref)
+	if err != nil {
+		return nil, err
+	}
+	data, err := pm.read()
+	if err != nil {
+		return nil, err
+	}
+	lockSecret(data)
+	defer wipe(data)
+	seeds, err := readDeviceSeeds(bytes.NewReader(data), io.Discard)
+	if err != nil {
+		return nil, errorf("%s: %w", ref, err)
+	}
+	if len(seeds) == 0 {
+		return nil, errorf("запись %s не содержит сидов", ref)
+	}
+	return seeds, nil
+}
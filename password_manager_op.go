@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// onePasswordItem - запись 1Password. Вход - как у op: сеанс
+// приложения, op signin или OP_SERVICE_ACCOUNT_TOKEN сервисного аккаунта.
+type onePasswordItem struct {
+	vault string
+	item  string
+	// field - поле записи; по умолчанию password, в которое seedgen
+	// записывает мастер-сид
+	field string
+}
+
+// newOnePasswordItem разбирает ссылку op://хранилище/запись[/раздел]/поле
+// без префикса
+func newOnePasswordItem(path string) (*onePasswordItem, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for _, part := range parts {
+		if part == "" {
+			return nil, errorf("некорректная ссылка op://%s: ожидается op://хранилище/запись[/поле]", path)
+		}
+	}
+	switch len(parts) {
+	case 2:
+		return &onePasswordItem{vault: parts[0], item: parts[1], field: "password"}, nil
+	case 3, 4:
+		return &onePasswordItem{vault: parts[0], item: parts[1], field: strings.Join(parts[2:], "/")}, nil
+	}
+	return nil, errorf("некорректная ссылка op://%s: ожидается op://хранилище/запись[/поле]", path)
+}
+
+func (o *onePasswordItem) ref() string {
+	return "op://" + o.vault + "/" + o.item + "/" + o.field
+}
+
+// checkFree проверяет вход в 1Password и что записи с таким именем в
+// хранилище еще нет
+func (o *onePasswordItem) checkFree() error {
+	if o.field != "password" {
+		return errorf("мастер-сид записывается в поле password: укажите op://%s/%s", o.vault, o.item)
+	}
+	if _, err := runManagerCLI(nil, "op", "whoami"); err != nil {
+		return errorf("нет входа в 1Password: %w", err)
+	}
+	if _, err := runManagerCLI(nil, "op", "item", "get", o.item, "--vault", o.vault, "--format", "json"); err == nil {
+		return errorf("запись op://%s/%s уже существует", o.vault, o.item)
+	}
+	return nil
+}
+
+// onePasswordField - поле шаблона записи op item create
+type onePasswordField struct {
+	ID      string      `json:"id"`
+	Type    string      `json:"type"`
+	Purpose string      `json:"purpose,omitempty"`
+	Label   string      `json:"label"`
+	Value   interface{} `json:"value"`
+}
+
+// writeMaster создает запись категории «Пароль» командой op item create.
+// Шаблон с мастер-сидом передается в stdin, а не аргументами, которые
+// видны в списке процессов.
+func (o *onePasswordItem) writeMaster(master []byte) error {
+	template := map[string]interface{}{
+		"title":    o.item,
+		"category": "PASSWORD",
+		"tags":     []string{"seedgen"},
+		"fields": []onePasswordField{
+			{ID: "password", Type: "CONCEALED", Purpose: "PASSWORD", Label: "password", Value: secretHex(master)},
+			{ID: "fingerprint", Type: "STRING", Label: "fingerprint", Value: masterFingerprint(master)},
+			{ID: "seedgen", Type: "STRING", Label: "seedgen", Value: version},
+		},
+	}
+	data, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	defer wipe(data)
+	out, err := runManagerCLI(data, "op", "item", "create", "--vault", o.vault, "--format", "json")
+	wipe(out)
+	return err
+}
+
+// read читает поле записи командой op read
+func (o *onePasswordItem) read() ([]byte, error) {
+	value, err := runManagerCLI(nil, "op", "read", "--no-newline", o.ref())
+	if err != nil || o.field != "password" {
+		return value, err
+	}
+	// Отпечаток лежит в отдельном поле; в записях, созданных не seedgen,
+	// его может не быть
+	fp, ferr := runManagerCLI(nil, "op", "read", "--no-newline", "op://"+o.vault+"/"+o.item+"/fingerprint")
+	if ferr != nil || len(fp) == 0 {
+		return value, nil
+	}
+	entry := newSecret(len(value) + len("\nfingerprint: ") + len(fp))
+	n := copy(entry, value)
+	n += copy(entry[n:], "\nfingerprint: ")
+	copy(entry[n:], fp)
+	wipe(value)
+	return entry, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// passEntry - запись password-store. pass шифрует записи gpg для ключей
+// из .gpg-id и, если хранилище - репозиторий git, сам фиксирует изменения.
+type passEntry struct {
+	path string
+}
+
+// newPassEntry проверяет путь записи: он задается относительно корня
+// хранилища, как в pass show
+func newPassEntry(path string) (*passEntry, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, errorf("в ссылке pass: не указан путь записи")
+	}
+	for _, part := range strings.Split(path, "/") {
+		if part == "" || part == "." || part == ".." {
+			return nil, errorf("некорректный путь записи pass %q", path)
+		}
+	}
+	return &passEntry{path}, nil
+}
+
+func (p *passEntry) ref() string { return "pass:" + p.path }
+
+// storeDir возвращает каталог хранилища: PASSWORD_STORE_DIR или
+// ~/.password-store, как у pass
+func (p *passEntry) storeDir() (string, error) {
+	if dir := os.Getenv("PASSWORD_STORE_DIR"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".password-store"), nil
+}
+
+// checkFree проверяет, что хранилище инициализировано и записи еще нет:
+// иначе pass insert спросил бы о перезаписи
+func (p *passEntry) checkFree() error {
+	if _, err := runManagerCLI(nil, "pass", "version"); err != nil {
+		return err
+	}
+	dir, err := p.storeDir()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".gpg-id")); err != nil {
+		return errorf("хранилище pass %s не инициализировано: выполните pass init", dir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(p.path)+".gpg")); err == nil {
+		return errorf("запись %s уже существует", p.ref())
+	}
+	return nil
+}
+
+// writeMaster создает запись через pass insert. Мастер-сид передается
+// в stdin, как многострочная запись: hex в первой строке, по соглашению
+// pass для пароля, затем отпечаток.
+func (p *passEntry) writeMaster(master []byte) error {
+	entry := managerEntry(master)
+	defer wipe(entry)
+	_, err := runManagerCLI(entry, "pass", "insert", "--multiline", p.path)
+	return err
+}
+
+// read возвращает расшифрованную запись
+func (p *passEntry) read() ([]byte, error) {
+	return runManagerCLI(nil, "pass", "show", p.path)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// pkcs11URI - ссылка на объект токена PKCS#11 (RFC 7512):
+// pkcs11:token=seedgen;object=lottery?module-path=/usr/lib/softhsm/libsofthsm2.so&pin-source=pin.txt
+type pkcs11URI struct {
+	token  string
+	object string
+	slotID int
+	module string
+	pin    []byte
+}
+
+// parsePKCS11URI разбирает ссылку и читает PIN из pin-source или pin-value
+func parsePKCS11URI(ref string) (*pkcs11URI, error) {
+	if !strings.HasPrefix(ref, "pkcs11:") {
+		return nil, errorf("ссылка на токен должна начинаться с pkcs11:")
+	}
+	path, query := strings.TrimPrefix(ref, "pkcs11:"), ""
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path, query = path[:i], path[i+1:]
+	}
+	u := &pkcs11URI{slotID: -1}
+	attrs := func(s, sep string, set func(name, value string) error) error {
+		for _, attr := range strings.Split(s, sep) {
+			if attr == "" {
+				continue
+			}
+			eq := strings.IndexByte(attr, '=')
+			if eq < 0 {
+				return errorf("атрибут %q без значения", attr)
+			}
+			value, err := url.PathUnescape(attr[eq+1:])
+			if err != nil {
+				return errorf("атрибут %q: %w", attr[:eq], err)
+			}
+			if err := set(attr[:eq], value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := attrs(path, ";", func(name, value string) error {
+		switch name {
+		case "token":
+			u.token = value
+		case "object":
+			u.object = value
+		case "slot-id":
+			id, err := strconv.Atoi(value)
+			if err != nil || id < 0 {
+				return errorf("некорректный slot-id %q", value)
+			}
+			u.slotID = id
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = attrs(query, "&", func(name, value string) error {
+		switch name {
+		case "module-path":
+			u.module = value
+		case "pin-value":
+			u.pin = []byte(value)
+		case "pin-source":
+			data, err := os.ReadFile(strings.TrimPrefix(value, "file:"))
+			if err != nil {
+				return errorf("pin-source: %w", err)
+			}
+			u.pin = bytes.TrimRight(data, "\r\n")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case u.object == "":
+		return nil, errorf("в ссылке на токен не указан object")
+	case u.module == "":
+		return nil, errorf("в ссылке на токен не указан module-path")
+	case len(u.pin) == 0:
+		return nil, errorf("в ссылке на токен не указан pin-source или pin-value")
+	}
+	return u, nil
+}
+
+// loadPKCS11 не извлекает мастер-сид: объект в токене неизвлекаем
+func loadPKCS11(name string) ([]byte, error) {
+	return nil, errorf("мастер-сид в токене PKCS#11 неизвлекаем, через токен доступен только derive key")
+}
@@ -0,0 +1,338 @@
+//go:build (linux || darwin) && cgo
+// +build linux darwin
+// +build cgo
+
+package main
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+typedef unsigned long CK_ULONG;
+typedef CK_ULONG CK_RV;
+typedef unsigned char CK_BBOOL;
+
+typedef struct {
+	CK_ULONG type;
+	void *pValue;
+	CK_ULONG ulValueLen;
+} CK_ATTRIBUTE;
+
+typedef struct {
+	CK_ULONG mechanism;
+	void *pParameter;
+	CK_ULONG ulParameterLen;
+} CK_MECHANISM;
+
+typedef struct {
+	CK_BBOOL bExtract;
+	CK_BBOOL bExpand;
+	CK_ULONG prfHashMechanism;
+	CK_ULONG ulSaltType;
+	unsigned char *pSalt;
+	CK_ULONG ulSaltLen;
+	CK_ULONG hSaltKey;
+	unsigned char *pInfo;
+	CK_ULONG ulInfoLen;
+} CK_HKDF_PARAMS;
+
+#define CKR_OK                           0x000UL
+#define CKR_USER_ALREADY_LOGGED_IN       0x100UL
+#define CKR_CRYPTOKI_ALREADY_INITIALIZED 0x191UL
+#define CKF_RW_SESSION                   0x002UL
+#define CKF_SERIAL_SESSION               0x004UL
+#define CKU_USER                         1UL
+#define CKO_SECRET_KEY                   4UL
+#define CKK_GENERIC_SECRET               0x010UL
+#define CKA_CLASS                        0x000UL
+#define CKA_TOKEN                        0x001UL
+#define CKA_PRIVATE                      0x002UL
+#define CKA_LABEL                        0x003UL
+#define CKA_VALUE                        0x011UL
+#define CKA_KEY_TYPE                     0x100UL
+#define CKA_ID                           0x102UL
+#define CKA_SENSITIVE                    0x103UL
+#define CKA_DERIVE                       0x10CUL
+#define CKA_VALUE_LEN                    0x161UL
+#define CKA_EXTRACTABLE                  0x162UL
+#define CKM_SHA512                       0x270UL
+#define CKM_HKDF_DERIVE                  0x402AUL
+#define CKF_HKDF_SALT_DATA               2UL
+
+// Собственные коды ошибок в диапазоне CKR_VENDOR_DEFINED
+#define P11_ERR_LOAD      0x80000001UL
+#define P11_ERR_NO_TOKEN  0x80000002UL
+#define P11_ERR_EXISTS    0x80000003UL
+#define P11_ERR_NOT_FOUND 0x80000004UL
+
+typedef struct {
+	void *lib;
+	int initialized;
+	CK_ULONG session;
+	CK_RV (*Initialize)(void *);
+	CK_RV (*Finalize)(void *);
+	CK_RV (*GetSlotList)(CK_BBOOL, CK_ULONG *, CK_ULONG *);
+	CK_RV (*GetTokenInfo)(CK_ULONG, void *);
+	CK_RV (*OpenSession)(CK_ULONG, CK_ULONG, void *, void *, CK_ULONG *);
+	CK_RV (*CloseSession)(CK_ULONG);
+	CK_RV (*Login)(CK_ULONG, CK_ULONG, unsigned char *, CK_ULONG);
+	CK_RV (*Logout)(CK_ULONG);
+	CK_RV (*CreateObject)(CK_ULONG, CK_ATTRIBUTE *, CK_ULONG, CK_ULONG *);
+	CK_RV (*DestroyObject)(CK_ULONG, CK_ULONG);
+	CK_RV (*GetAttributeValue)(CK_ULONG, CK_ULONG, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*FindObjectsInit)(CK_ULONG, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*FindObjects)(CK_ULONG, CK_ULONG *, CK_ULONG, CK_ULONG *);
+	CK_RV (*FindObjectsFinal)(CK_ULONG);
+	CK_RV (*DeriveKey)(CK_ULONG, CK_MECHANISM *, CK_ULONG, CK_ATTRIBUTE *, CK_ULONG, CK_ULONG *);
+} p11;
+
+#define P11_SYM(name) \
+	if ((*(void **)&p->name = dlsym(p->lib, "C_" #name)) == NULL) { *step = "C_" #name; return P11_ERR_LOAD; }
+
+static CK_BBOOL ckTrue = 1, ckFalse = 0;
+static CK_ULONG ckSecretKey = CKO_SECRET_KEY, ckGenericSecret = CKK_GENERIC_SECRET;
+
+// p11_labelMatches сравнивает метку токена, дополненную пробелами до 32 байт
+static int p11_labelMatches(const unsigned char *label, const char *want) {
+	size_t n = strlen(want);
+	if (n > 32 || memcmp(label, want, n) != 0) return 0;
+	for (size_t i = n; i < 32; i++) {
+		if (label[i] != ' ') return 0;
+	}
+	return 1;
+}
+
+// p11_open загружает модуль, выбирает токен по метке или слоту и входит с PIN
+static CK_RV p11_open(p11 *p, const char *module, const char *token, long slot,
+		unsigned char *pin, CK_ULONG pinLen, const char **step) {
+	*step = "dlopen";
+	if ((p->lib = dlopen(module, RTLD_NOW | RTLD_LOCAL)) == NULL) return P11_ERR_LOAD;
+	P11_SYM(Initialize) P11_SYM(Finalize) P11_SYM(GetSlotList) P11_SYM(GetTokenInfo)
+	P11_SYM(OpenSession) P11_SYM(CloseSession) P11_SYM(Login) P11_SYM(Logout)
+	P11_SYM(CreateObject) P11_SYM(DestroyObject) P11_SYM(GetAttributeValue)
+	P11_SYM(FindObjectsInit) P11_SYM(FindObjects) P11_SYM(FindObjectsFinal) P11_SYM(DeriveKey)
+
+	*step = "C_Initialize";
+	CK_RV rv = p->Initialize(NULL);
+	if (rv != CKR_OK && rv != CKR_CRYPTOKI_ALREADY_INITIALIZED) return rv;
+	p->initialized = rv == CKR_OK;
+
+	*step = "C_GetSlotList";
+	CK_ULONG count = 0;
+	if ((rv = p->GetSlotList(1, NULL, &count)) != CKR_OK) return rv;
+	CK_ULONG *slots = calloc(count + 1, sizeof(CK_ULONG));
+	if ((rv = p->GetSlotList(1, slots, &count)) != CKR_OK) {
+		free(slots);
+		return rv;
+	}
+	*step = "C_GetTokenInfo";
+	long chosen = -1;
+	for (CK_ULONG i = 0; i < count && chosen < 0; i++) {
+		if (slot >= 0) {
+			if (slots[i] == (CK_ULONG)slot) chosen = (long)slots[i];
+			continue;
+		}
+		if (token[0] == 0) {
+			chosen = (long)slots[i];
+			continue;
+		}
+		unsigned char info[512];
+		if ((rv = p->GetTokenInfo(slots[i], info)) != CKR_OK) {
+			free(slots);
+			return rv;
+		}
+		if (p11_labelMatches(info, token)) chosen = (long)slots[i];
+	}
+	free(slots);
+	if (chosen < 0) return P11_ERR_NO_TOKEN;
+
+	*step = "C_OpenSession";
+	if ((rv = p->OpenSession((CK_ULONG)chosen, CKF_SERIAL_SESSION | CKF_RW_SESSION, NULL, NULL, &p->session)) != CKR_OK) return rv;
+	*step = "C_Login";
+	rv = p->Login(p->session, CKU_USER, pin, pinLen);
+	if (rv != CKR_OK && rv != CKR_USER_ALREADY_LOGGED_IN) return rv;
+	return CKR_OK;
+}
+
+// p11_close завершает сеанс и выгружает модуль
+static void p11_close(p11 *p) {
+	if (p->session != 0) {
+		p->Logout(p->session);
+		p->CloseSession(p->session);
+	}
+	if (p->initialized) p->Finalize(NULL);
+	if (p->lib != NULL) dlclose(p->lib);
+}
+
+// p11_find ищет секретный ключ по метке
+static CK_RV p11_find(p11 *p, const char *label, CK_ULONG *handle, const char **step) {
+	CK_ATTRIBUTE tmpl[] = {
+		{CKA_CLASS, &ckSecretKey, sizeof(ckSecretKey)},
+		{CKA_LABEL, (void *)label, strlen(label)},
+	};
+	CK_ULONG found = 0;
+	*step = "C_FindObjects";
+	CK_RV rv = p->FindObjectsInit(p->session, tmpl, 2);
+	if (rv != CKR_OK) return rv;
+	rv = p->FindObjects(p->session, handle, 1, &found);
+	p->FindObjectsFinal(p->session);
+	if (rv != CKR_OK) return rv;
+	return found == 0 ? P11_ERR_NOT_FOUND : CKR_OK;
+}
+
+// p11_import создает в токене неизвлекаемый секретный ключ для вывода
+static CK_RV p11_import(p11 *p, const char *label, void *id, CK_ULONG idLen,
+		void *value, CK_ULONG valueLen, const char **step) {
+	CK_ULONG handle;
+	CK_RV rv = p11_find(p, label, &handle, step);
+	if (rv == CKR_OK) return P11_ERR_EXISTS;
+	if (rv != P11_ERR_NOT_FOUND) return rv;
+
+	CK_ATTRIBUTE tmpl[] = {
+		{CKA_CLASS, &ckSecretKey, sizeof(ckSecretKey)},
+		{CKA_KEY_TYPE, &ckGenericSecret, sizeof(ckGenericSecret)},
+		{CKA_TOKEN, &ckTrue, 1},
+		{CKA_PRIVATE, &ckTrue, 1},
+		{CKA_SENSITIVE, &ckTrue, 1},
+		{CKA_EXTRACTABLE, &ckFalse, 1},
+		{CKA_DERIVE, &ckTrue, 1},
+		{CKA_LABEL, (void *)label, strlen(label)},
+		{CKA_ID, id, idLen},
+		{CKA_VALUE, value, valueLen},
+	};
+	*step = "C_CreateObject";
+	return p->CreateObject(p->session, tmpl, sizeof(tmpl) / sizeof(tmpl[0]), &handle);
+}
+
+// p11_hkdf выводит ключ HKDF-SHA512 из ключа с меткой label средствами токена
+// и возвращает его значение и CKA_ID исходного ключа
+static CK_RV p11_hkdf(p11 *p, const char *label, unsigned char *salt, CK_ULONG saltLen,
+		unsigned char *info, CK_ULONG infoLen, void *out, CK_ULONG outLen,
+		void *id, CK_ULONG *idLen, const char **step) {
+	CK_ULONG base, derived;
+	CK_RV rv = p11_find(p, label, &base, step);
+	if (rv != CKR_OK) return rv;
+
+	CK_ATTRIBUTE idAttr = {CKA_ID, id, *idLen};
+	*step = "C_GetAttributeValue";
+	if ((rv = p->GetAttributeValue(p->session, base, &idAttr, 1)) != CKR_OK) return rv;
+	*idLen = idAttr.ulValueLen;
+
+	CK_HKDF_PARAMS params = {1, 1, CKM_SHA512, CKF_HKDF_SALT_DATA, salt, saltLen, 0, info, infoLen};
+	CK_MECHANISM mech = {CKM_HKDF_DERIVE, &params, sizeof(params)};
+	CK_ATTRIBUTE tmpl[] = {
+		{CKA_CLASS, &ckSecretKey, sizeof(ckSecretKey)},
+		{CKA_KEY_TYPE, &ckGenericSecret, sizeof(ckGenericSecret)},
+		{CKA_TOKEN, &ckFalse, 1},
+		{CKA_SENSITIVE, &ckFalse, 1},
+		{CKA_EXTRACTABLE, &ckTrue, 1},
+		{CKA_VALUE_LEN, &outLen, sizeof(outLen)},
+	};
+	*step = "C_DeriveKey";
+	if ((rv = p->DeriveKey(p->session, &mech, base, tmpl, sizeof(tmpl) / sizeof(tmpl[0]), &derived)) != CKR_OK) return rv;
+
+	CK_ATTRIBUTE value = {CKA_VALUE, out, outLen};
+	*step = "C_GetAttributeValue";
+	rv = p->GetAttributeValue(p->session, derived, &value, 1);
+	p->DestroyObject(p->session, derived);
+	if (rv == CKR_OK && value.ulValueLen != outLen) rv = P11_ERR_NOT_FOUND;
+	return rv;
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// pkcs11Supported сообщает, что токены PKCS#11 доступны в этой сборке
+const pkcs11Supported = true
+
+// pkcs11Error переводит код CKR в сообщение для оператора
+func pkcs11Error(step *C.char, rv C.CK_RV) error {
+	switch rv {
+	case C.P11_ERR_LOAD:
+		return errorf("не удалось загрузить модуль PKCS#11 (%s)", C.GoString(step))
+	case C.P11_ERR_NO_TOKEN:
+		return errorf("токен не найден")
+	case C.P11_ERR_EXISTS:
+		return errorf("объект с такой меткой уже есть в токене")
+	case C.P11_ERR_NOT_FOUND:
+		return errorf("объект не найден в токене")
+	case 0x0A0:
+		return errorf("неверный PIN")
+	case 0x0A4:
+		return errorf("PIN заблокирован")
+	case 0x070:
+		return errorf("токен не поддерживает CKM_HKDF_DERIVE (нужен PKCS#11 3.0)")
+	}
+	return errorf("%s: CKR 0x%X", C.GoString(step), uint64(rv))
+}
+
+// withPKCS11 открывает сеанс с токеном из ссылки и выполняет fn
+func withPKCS11(u *pkcs11URI, fn func(p *C.p11, step **C.char) C.CK_RV) error {
+	module, token := C.CString(u.module), C.CString(u.token)
+	defer C.free(unsafe.Pointer(module))
+	defer C.free(unsafe.Pointer(token))
+
+	p := (*C.p11)(C.calloc(1, C.size_t(unsafe.Sizeof(C.p11{}))))
+	defer C.free(unsafe.Pointer(p))
+	defer C.p11_close(p)
+
+	var step *C.char
+	if rv := C.p11_open(p, module, token, C.long(u.slotID), (*C.uchar)(unsafe.Pointer(&u.pin[0])), C.CK_ULONG(len(u.pin)), &step); rv != C.CKR_OK {
+		return pkcs11Error(step, rv)
+	}
+	if rv := fn(p, &step); rv != C.CKR_OK {
+		return pkcs11Error(step, rv)
+	}
+	return nil
+}
+
+// importPKCS11 помещает мастер-сид в токен как неизвлекаемый ключ;
+// отпечаток мастер-сида сохраняется в CKA_ID
+func importPKCS11(u *pkcs11URI, master []byte, fingerprint string) error {
+	label := C.CString(u.object)
+	defer C.free(unsafe.Pointer(label))
+	id := C.CBytes([]byte(fingerprint))
+	defer C.free(id)
+	value := C.CBytes(master)
+	defer func() {
+		C.memset(value, 0, C.size_t(len(master)))
+		C.free(value)
+	}()
+	return withPKCS11(u, func(p *C.p11, step **C.char) C.CK_RV {
+		return C.p11_import(p, label, id, C.CK_ULONG(len(fingerprint)), value, C.CK_ULONG(len(master)), step)
+	})
+}
+
+// derivePKCS11 выводит ключ HKDF-SHA512 внутри токена и возвращает его
+// вместе с отпечатком мастер-сида из CKA_ID
+func derivePKCS11(u *pkcs11URI, salt, info []byte, size int) ([]byte, string, error) {
+	label := C.CString(u.object)
+	defer C.free(unsafe.Pointer(label))
+	cSalt, cInfo := C.CBytes(salt), C.CBytes(info)
+	defer C.free(cSalt)
+	defer C.free(cInfo)
+	out := C.calloc(1, C.size_t(size))
+	defer func() {
+		C.memset(out, 0, C.size_t(size))
+		C.free(out)
+	}()
+	id := C.calloc(1, 64)
+	defer C.free(id)
+	idLen := C.CK_ULONG(64)
+
+	err := withPKCS11(u, func(p *C.p11, step **C.char) C.CK_RV {
+		return C.p11_hkdf(p, label, (*C.uchar)(cSalt), C.CK_ULONG(len(salt)), (*C.uchar)(cInfo), C.CK_ULONG(len(info)),
+			out, C.CK_ULONG(size), id, &idLen, step)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	key := newSecret(size)
+	copy(key, (*[1 << 30]byte)(out)[:size:size])
+	return key, C.GoStringN((*C.char)(id), C.int(idLen)), nil
+}
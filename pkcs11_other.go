@@ -0,0 +1,17 @@
+//go:build !(linux || darwin) || !cgo
+// +build !linux,!darwin !cgo
+
+package main
+
+// pkcs11Supported сообщает, что токены PKCS#11 доступны в этой сборке
+const pkcs11Supported = false
+
+// importPKCS11 недоступна: модули PKCS#11 загружаются только через cgo
+func importPKCS11(u *pkcs11URI, master []byte, fingerprint string) error {
+	return errorf("токены PKCS#11 доступны только в Linux и macOS при сборке с cgo")
+}
+
+// derivePKCS11 недоступна: модули PKCS#11 загружаются только через cgo
+func derivePKCS11(u *pkcs11URI, salt, info []byte, size int) ([]byte, string, error) {
+	return nil, "", errorf("токены PKCS#11 доступны только в Linux и macOS при сборке с cgo")
+}
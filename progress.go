@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// kdfProgress, если задан, получает долю выполненной работы KDF от 0 до 1.
+// Команды устанавливают его через showKDFProgress.
+var kdfProgress func(done float64)
+
+// kdfProgressStep - через сколько итераций PBKDF2 сообщать о ходе вычисления
+const kdfProgressStep = 1024
+
+// argon2ProbeMemory - память пробного прогона Argon2id в КиБ
+const argon2ProbeMemory = 8 * 1024
+
+// pbkdf2SHA512 вычисляет 64-байтный ключ PBKDF2-HMAC-SHA512, как pbkdf2.Key,
+// но сообщает о ходе вычисления в kdfProgress. Ключ длиной в один выход
+// SHA-512 состоит из одного блока: T = U1 ^ U2 ^ ... ^ Uc (RFC 8018).
+func pbkdf2SHA512(password, salt []byte, iterations int) []byte {
+	return pbkdf2SHA512Report(password, salt, iterations, kdfProgress)
+}
+
+// pbkdf2SHA512Report вычисляет ключ, как pbkdf2SHA512, но сообщает о ходе в report
+func pbkdf2SHA512Report(password, salt []byte, iterations int, report func(done float64)) []byte {
+	prf := hmac.New(sha512.New, password)
+	prf.Write(salt)
+	prf.Write([]byte{0, 0, 0, 1})
+	u := prf.Sum(newSecret(sha512.Size)[:0])
+	defer wipe(u)
+	key := newSecret(sha512.Size)
+	copy(key, u)
+
+	for i := 2; i <= iterations; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+		if report != nil && i%kdfProgressStep == 0 {
+			report(float64(i) / float64(iterations))
+		}
+	}
+	if report != nil {
+		report(1)
+	}
+	return key
+}
+
+// argon2IDKey вычисляет 64-байтный ключ Argon2id. x/crypto/argon2 не сообщает
+// о промежуточных проходах, поэтому для kdfProgress длительность оценивается
+// по пробному прогону на той же машине: время растет линейно с числом
+// проходов и объемом памяти.
+func argon2IDKey(password, salt []byte, passes, memory uint32, threads uint8) []byte {
+	return argon2IDKeyReport(password, salt, passes, memory, threads, kdfProgress)
+}
+
+// argon2IDKeyReport вычисляет ключ, как argon2IDKey, но сообщает о ходе в report
+func argon2IDKeyReport(password, salt []byte, passes, memory uint32, threads uint8, report func(done float64)) []byte {
+	if report == nil {
+		return argon2.IDKey(password, salt, passes, memory, threads, 64)
+	}
+	start := time.Now()
+	argon2.IDKey([]byte("probe"), salt, 1, argon2ProbeMemory, threads, 64)
+	estimate := time.Since(start).Seconds() * float64(passes) * float64(memory) / argon2ProbeMemory
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		start := time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// Пока вычисление не закончилось, оценка не доходит до 100%
+				report(math.Min(time.Since(start).Seconds()/estimate, 0.99))
+			}
+		}
+	}()
+	key := argon2.IDKey(password, salt, passes, memory, threads, 64)
+	close(done)
+	wg.Wait()
+	report(1)
+	return key
+}
+
+// showKDFProgress выводит в stderr полосу хода KDF с оценкой оставшегося
+// времени, если stderr - терминал: на медленных ноутбуках церемонии
+// миллион итераций идет около минуты и без нее выглядит как зависание.
+// Возвращает функцию, которая убирает полосу.
+func showKDFProgress() func() {
+	// В сборке lowmem полоса отключена: пробный прогон Argon2id занял бы память
+	if lowMemoryBuild || !isTerminal(os.Stderr) {
+		return func() {}
+	}
+	const width = 30
+	var start, last time.Time
+	prev := 1.0
+	kdfProgress = func(done float64) {
+		now := time.Now()
+		// Новый вызов KDF (rotate выводит два мастер-сида) начинает отсчет заново
+		if done < prev {
+			start = now
+		}
+		prev = done
+		if done < 1 && now.Sub(last) < 100*time.Millisecond {
+			return
+		}
+		last = now
+
+		filled := int(done * width)
+		eta := ""
+		if elapsed := now.Sub(start); done > 0 && done < 1 && elapsed > time.Second {
+			remaining := time.Duration(float64(elapsed) * (1 - done) / done)
+			eta = fmt.Sprintf(tr(", осталось ~%v"), remaining.Round(time.Second))
+		}
+		fmt.Fprintf(os.Stderr, tr("\rВывод мастер-сида [%s%s] %3.0f%%%s\033[K"),
+			strings.Repeat("#", filled), strings.Repeat("-", width-filled), done*100, eta)
+	}
+	return func() {
+		kdfProgress = nil
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+}
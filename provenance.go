@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// provenance - сведения о сборке и настройках, которыми получен результат.
+// По ним архивный результат связывается с кодом и параметрами запуска:
+// версия и коммит указывают исходники, config_sha256 - значения флагов.
+type provenance struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Go      string `json:"go"`
+	// Scheme и KDF заполняются командами, которые сами вычисляют мастер-сид
+	Scheme string `json:"scheme,omitempty"`
+	KDF    string `json:"kdf,omitempty"`
+	Config string `json:"config_sha256"`
+}
+
+// runFlags - набор флагов выполняемой команды. У составных команд
+// (derive password) последним разбирается самый точный набор.
+var runFlags *flag.FlagSet
+
+// provenanceParams - параметры схемы, которыми команда вычислила мастер-сид
+var provenanceParams *Params
+
+// noteProvenanceParams запоминает параметры схемы для сведений о результате
+func noteProvenanceParams(p Params) {
+	provenanceParams = &p
+}
+
+// currentProvenance собирает сведения о сборке и настройках команды
+func currentProvenance() provenance {
+	p := provenance{
+		Version: buildVersion(),
+		Commit:  commit,
+		Go:      runtime.Version(),
+		Config:  configDigest(runFlags),
+	}
+	if provenanceParams != nil {
+		p.Scheme, p.KDF = provenanceParams.Scheme, provenanceParams.KDF
+	}
+	return p
+}
+
+// configDigest возвращает SHA-256 значений всех флагов команды, в том числе
+// по умолчанию, из окружения и профиля, в виде строк "имя=значение".
+// Пути к файлам не входят: входные файлы и так указаны в результате своим
+// SHA-256, а одна настройка не должна давать разный хэш на разных машинах.
+func configDigest(fs *flag.FlagSet) string {
+	h := sha256.New()
+	if fs != nil {
+		fs.VisitAll(func(f *flag.Flag) {
+			if fileFlags[f.Name] {
+				return
+			}
+			fmt.Fprintf(h, "%s=%s\n", f.Name, recordedFlagValue(f))
+		})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// provenanceLine возвращает строку заголовка схемы со сведениями о сборке
+func provenanceLine() string {
+	p := currentProvenance()
+	return fmt.Sprintf(tr("seedgen %s (коммит %s, %s), конфигурация: SHA-256 %s"), p.Version, p.Commit, p.Go, p.Config)
+}
+
+// writeOutput выводит результат команды в JSON со сведениями о сборке
+func writeOutput(w io.Writer, v interface{}) error {
+	data, err := stampedJSON(v)
+	if err != nil {
+		return err
+	}
+	return writeJSONBytes(w, data)
+}
+
+// stampedJSON возвращает JSON с отступами, в котором у объекта последним
+// полем идет provenance. Массивы остаются как есть: поле в них не
+// добавить, не изменив формат.
+func stampedJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, []byte("{")) {
+		stamp, err := json.Marshal(currentProvenance())
+		if err != nil {
+			return nil, err
+		}
+		data = bytes.TrimSuffix(data, []byte("}"))
+		if len(data) > 1 {
+			data = append(data, ',')
+		}
+		data = append(append(append(data, `"provenance":`...), stamp...), '}')
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// stripProvenance убирает сведения о сборке из результата JSON или схемы,
+// чтобы сравнить результаты разных сборок по существу
+func stripProvenance(data []byte) []byte {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err == nil {
+		delete(obj, "provenance")
+		if out, err := json.Marshal(obj); err == nil {
+			return out
+		}
+	}
+	var out [][]byte
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if !bytes.Contains(line, []byte(`class="meta build"`)) {
+			out = append(out, line)
+		}
+	}
+	return bytes.Join(out, nil)
+}
@@ -0,0 +1,515 @@
+package main
+
+import (
+	"io"
+	"strings"
+)
+
+// qrBlocks описывает блоки Рида-Соломона версии QR-кода для уровня коррекции:
+// число кодовых слов коррекции в блоке и размеры групп блоков данных
+type qrBlocks struct {
+	ecc            int
+	blocks1, data1 int
+	blocks2, data2 int
+}
+
+// qrVersionsM - версии 1-10 с уровнем коррекции M (около 15% ошибок)
+var qrVersionsM = []qrBlocks{
+	{10, 1, 16, 0, 0},
+	{16, 1, 28, 0, 0},
+	{26, 1, 44, 0, 0},
+	{18, 2, 32, 0, 0},
+	{24, 2, 43, 0, 0},
+	{16, 4, 27, 0, 0},
+	{18, 4, 31, 0, 0},
+	{22, 2, 38, 2, 39},
+	{22, 3, 36, 2, 37},
+	{26, 4, 43, 1, 44},
+}
+
+// qrVersionsL - версии 1-10 с уровнем коррекции L (около 7% ошибок),
+// которым SeedSigner печатает SeedQR: код получается на версию меньше
+var qrVersionsL = []qrBlocks{
+	{7, 1, 19, 0, 0},
+	{10, 1, 34, 0, 0},
+	{15, 1, 55, 0, 0},
+	{20, 1, 80, 0, 0},
+	{26, 1, 108, 0, 0},
+	{18, 2, 68, 0, 0},
+	{20, 2, 78, 0, 0},
+	{24, 2, 97, 0, 0},
+	{30, 2, 116, 0, 0},
+	{18, 2, 68, 2, 69},
+}
+
+// qrLevel - уровень коррекции ошибок; значение - его код в информации о формате
+type qrLevel int
+
+const (
+	qrLevelM qrLevel = 0
+	qrLevelL qrLevel = 1
+)
+
+// versions возвращает таблицу блоков версий для уровня
+func (l qrLevel) versions() []qrBlocks {
+	if l == qrLevelL {
+		return qrVersionsL
+	}
+	return qrVersionsM
+}
+
+// qrAlignment - координаты центров выравнивающих узоров по версиям
+var qrAlignment = [][]int{
+	nil, {6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34},
+	{6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50},
+}
+
+// dataCodewords возвращает емкость версии в кодовых словах данных
+func (b qrBlocks) dataCodewords() int {
+	return b.blocks1*b.data1 + b.blocks2*b.data2
+}
+
+// qrCode - матрица модулей QR-кода; true означает темный модуль
+type qrCode struct {
+	size     int
+	level    qrLevel
+	modules  [][]bool
+	function [][]bool
+}
+
+// gfMul умножает в поле GF(256) с образующим многочленом 0x11d
+func gfMul(x, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x1d
+		z ^= (y >> uint(i) & 1) * x
+	}
+	return z
+}
+
+// reedSolomon вычисляет кодовые слова коррекции для блока данных
+func reedSolomon(data []byte, degree int) []byte {
+	// Порождающий многочлен (x - 1)(x - a)...(x - a^(degree-1)) без старшего коэффициента
+	divisor := make([]byte, degree)
+	divisor[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			divisor[j] = gfMul(divisor[j], root)
+			if j+1 < degree {
+				divisor[j] ^= divisor[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+
+	result := make([]byte, degree)
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[degree-1] = 0
+		for i := range result {
+			result[i] ^= gfMul(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+// qrCountBits возвращает длину поля количества символов для режима и версии
+func qrCountBits(numeric bool, version int) int {
+	switch {
+	case numeric && version >= 10:
+		return 12
+	case numeric:
+		return 10
+	case version >= 10:
+		return 16
+	default:
+		return 8
+	}
+}
+
+// qrSegmentBits возвращает длину сегмента в битах: цифровой режим кодирует
+// по три цифры в 10 битах, остаток из одной или двух цифр - в 4 или 7
+func qrSegmentBits(n int, numeric bool, version int) int {
+	if numeric {
+		return 4 + qrCountBits(true, version) + 10*(n/3) + [3]int{0, 4, 7}[n%3]
+	}
+	return 4 + qrCountBits(false, version) + 8*n
+}
+
+// qrCodewords кодирует данные в байтовом или цифровом режиме, дополняет до
+// емкости версии и перемежает блоки данных и коррекции
+func qrCodewords(data []byte, numeric bool, version int, level qrLevel) []byte {
+	spec := level.versions()[version-1]
+
+	var bits []bool
+	put := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, v>>uint(i)&1 == 1)
+		}
+	}
+	if numeric {
+		put(0x1, 4)
+		put(len(data), qrCountBits(true, version))
+		for i := 0; i < len(data); i += 3 {
+			group := data[i:minInt(i+3, len(data))]
+			v := 0
+			for _, c := range group {
+				v = v*10 + int(c-'0')
+			}
+			put(v, [4]int{0, 4, 7, 10}[len(group)])
+		}
+	} else {
+		put(0x4, 4)
+		put(len(data), qrCountBits(false, version))
+		for _, b := range data {
+			put(int(b), 8)
+		}
+	}
+	capacity := spec.dataCodewords() * 8
+	for i := 0; i < 4 && len(bits) < capacity; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	for pad := 0xec; len(bits) < capacity; pad ^= 0xec ^ 0x11 {
+		put(pad, 8)
+	}
+
+	all := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			all[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+
+	var blocks, eccs [][]byte
+	for i := 0; i < spec.blocks1+spec.blocks2; i++ {
+		n := spec.data1
+		if i >= spec.blocks1 {
+			n = spec.data2
+		}
+		blocks = append(blocks, all[:n])
+		eccs = append(eccs, reedSolomon(all[:n], spec.ecc))
+		all = all[n:]
+	}
+
+	var out []byte
+	for i := 0; i < spec.data1 || i < spec.data2; i++ {
+		for _, b := range blocks {
+			if i < len(b) {
+				out = append(out, b[i])
+			}
+		}
+	}
+	for i := 0; i < spec.ecc; i++ {
+		for _, e := range eccs {
+			out = append(out, e[i])
+		}
+	}
+	return out
+}
+
+// newQRCode создает пустую матрицу версии и рисует служебные узоры
+func newQRCode(version int) *qrCode {
+	size := 17 + 4*version
+	q := &qrCode{size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for i := range q.modules {
+		q.modules[i] = make([]bool, size)
+		q.function[i] = make([]bool, size)
+	}
+
+	// Синхронизирующие линии
+	for i := 0; i < size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+	// Поисковые узоры с разделителями
+	for _, c := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x >= 0 && x < size && y >= 0 && y < size {
+					d := maxInt(absInt(dx), absInt(dy))
+					q.setFunction(x, y, d != 2 && d != 4)
+				}
+			}
+		}
+	}
+	// Выравнивающие узоры, кроме пересекающихся с поисковыми
+	pos := qrAlignment[version-1]
+	last := len(pos) - 1
+	for i := range pos {
+		for j := range pos {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.setFunction(pos[i]+dx, pos[j]+dy, maxInt(absInt(dx), absInt(dy)) != 1)
+				}
+			}
+		}
+	}
+	// Место под информацию о формате занимается до размещения данных
+	q.drawFormat(0)
+	if version >= 7 {
+		rem := version
+		for i := 0; i < 12; i++ {
+			rem = rem<<1 ^ (rem>>11)*0x1f25
+		}
+		bits := version<<12 | rem
+		for i := 0; i < 18; i++ {
+			bit := bits>>uint(i)&1 == 1
+			a, b := size-11+i%3, i/3
+			q.setFunction(a, b, bit)
+			q.setFunction(b, a, bit)
+		}
+	}
+	return q
+}
+
+// setFunction задает модуль служебного узора; x - столбец, y - строка
+func (q *qrCode) setFunction(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+// drawFormat рисует обе копии информации о формате для уровня и маски
+func (q *qrCode) drawFormat(mask int) {
+	data := int(q.level)<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>uint(i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		q.setFunction(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.size-15+i, bit(i))
+	}
+	q.setFunction(8, q.size-8, true)
+}
+
+// drawCodewords размещает кодовые слова зигзагом по парам столбцов
+func (q *qrCode) drawCodewords(data []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if !q.function[y][x] && i < len(data)*8 {
+					q.modules[y][x] = data[i/8]>>uint(7-i%8)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask инвертирует модули данных по маске; повторный вызов ее снимает
+func (q *qrCode) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !q.function[y][x] {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty оценивает матрицу по правилам выбора маски (ISO/IEC 18004, 7.8.3)
+func (q *qrCode) penalty() int {
+	n := q.size
+	at := func(x, y int, vertical bool) bool {
+		if vertical {
+			return q.modules[x][y]
+		}
+		return q.modules[y][x]
+	}
+	finder := []bool{true, false, true, true, true, false, true}
+	score := 0
+	for _, vertical := range []bool{false, true} {
+		for y := 0; y < n; y++ {
+			run := 1
+			for x := 1; x <= n; x++ {
+				if x < n && at(x, y, vertical) == at(x-1, y, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+			// Узор 1:1:3:1:1 с четырьмя светлыми модулями с одной из сторон
+			for x := 0; x+7 <= n; x++ {
+				match := true
+				for k, dark := range finder {
+					if at(x+k, y, vertical) != dark {
+						match = false
+						break
+					}
+				}
+				if match && (q.lightRun(x-4, x, y, vertical) || q.lightRun(x+7, x+11, y, vertical)) {
+					score += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				c := q.modules[y][x]
+				if c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+	total := n * n
+	score += absInt(dark*20-total*10) / total * 10
+	return score
+}
+
+// lightRun сообщает, что модули [from, to) строки светлые; модули за
+// границей матрицы относятся к светлой свободной зоне
+func (q *qrCode) lightRun(from, to, line int, vertical bool) bool {
+	for i := from; i < to; i++ {
+		if i < 0 || i >= q.size {
+			continue
+		}
+		if vertical && q.modules[i][line] || !vertical && q.modules[line][i] {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeQR строит QR-код наименьшей подходящей версии с уровнем коррекции M
+func encodeQR(data []byte) (*qrCode, error) {
+	return encodeQRLevel(data, false, qrLevelM)
+}
+
+// encodeQRLevel строит QR-код наименьшей подходящей версии с заданным
+// уровнем коррекции; numeric выбирает цифровой режим для строки из цифр
+func encodeQRLevel(data []byte, numeric bool, level qrLevel) (*qrCode, error) {
+	if numeric && strings.Trim(string(data), "0123456789") != "" {
+		return nil, errorf("цифровой режим QR-кода принимает только цифры")
+	}
+	versions := level.versions()
+	version := 0
+	for v, spec := range versions {
+		if qrSegmentBits(len(data), numeric, v+1) <= spec.dataCodewords()*8 {
+			version = v + 1
+			break
+		}
+	}
+	if version == 0 {
+		return nil, errorf("данные слишком длинны для QR-кода: %d байт, максимум %d", len(data), versions[len(versions)-1].dataCodewords()-3)
+	}
+
+	q := newQRCode(version)
+	q.level = level
+	q.drawCodewords(qrCodewords(data, numeric, version, level))
+	best, bestScore := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormat(mask)
+		if s := q.penalty(); bestScore < 0 || s < bestScore {
+			best, bestScore = mask, s
+		}
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormat(best)
+	return q, nil
+}
+
+// render выводит QR-код полублочными символами, по две строки модулей
+// в строке текста, со свободной зоной в 4 модуля. Светлые модули рисуются
+// символами, поэтому код читается в терминале с темным фоном.
+func (q *qrCode) render(w io.Writer) {
+	const quiet = 4
+	dark := func(x, y int) bool {
+		x, y = x-quiet, y-quiet
+		return x >= 0 && y >= 0 && x < q.size && y < q.size && q.modules[y][x]
+	}
+	var sb strings.Builder
+	for y := 0; y < q.size+2*quiet; y += 2 {
+		for x := 0; x < q.size+2*quiet; x++ {
+			top, bottom := !dark(x, y), !dark(x, y+1) && y+1 < q.size+2*quiet
+			switch {
+			case top && bottom:
+				sb.WriteString("█")
+			case top:
+				sb.WriteString("▀")
+			case bottom:
+				sb.WriteString("▄")
+			default:
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	io.WriteString(w, sb.String())
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
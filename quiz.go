@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+)
+
+// quizWords - сколько слов мнемоники спрашивается при проверке записи
+const quizWords = 3
+
+// quizPositions выбирает k разных случайных позиций из n в порядке возрастания
+func quizPositions(n, k int) ([]int, error) {
+	positions := make([]int, n)
+	for i := range positions {
+		positions[i] = i
+	}
+	// Частичная перетасовка Фишера-Йетса на crypto/rand
+	for i := 0; i < k; i++ {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(n-i)))
+		if err != nil {
+			return nil, withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+		}
+		r := i + int(j.Int64())
+		positions[i], positions[r] = positions[r], positions[i]
+	}
+	chosen := positions[:k]
+	sort.Ints(chosen)
+	return chosen, nil
+}
+
+// readAnswer читает строку ответа побайтно, как readLine
+func readAnswer(r io.Reader) (string, error) {
+	var sb strings.Builder
+	b := make([]byte, 1)
+	for {
+		if _, err := r.Read(b); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return strings.TrimSpace(sb.String()), nil
+		}
+		sb.WriteByte(b[0])
+	}
+}
+
+// quizWordMatches сравнивает ответ со словом; как и при вводе мнемоники,
+// достаточно первых четырех букв
+func quizWordMatches(answer, word string) bool {
+	index, err := bip39Lookup(strings.ToLower(answer))
+	return err == nil && bip39Words[index] == word
+}
+
+// quizMnemonic проверяет, что оператор записал мнемонику: очищает экран
+// и спрашивает слова на случайных позициях. Ответы читаются из терминала,
+// даже если stdin перенаправлен.
+func quizMnemonic(phrase string) error {
+	if !isTerminal(os.Stdout) {
+		return errorf("проверка записи требует вывода мнемоники в терминал")
+	}
+	in, closeInput, err := openConfirmInput()
+	if err != nil {
+		return errorf("проверка записи требует терминала для ответов: %w", err)
+	}
+	defer closeInput()
+
+	words := strings.Fields(phrase)
+	positions, err := quizPositions(len(words), quizWords)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(os.Stderr, tr("\nЗапишите мнемонику и нажмите Enter: экран будет очищен перед проверкой записи..."))
+	if err := readLine(in); err != nil {
+		fmt.Fprintln(os.Stderr)
+		return withCode(exitCancelled, errorf("проверка записи отменена"))
+	}
+	clearScreen()
+	fmt.Fprintf(os.Stderr, tr("Проверка записи: введите %d слова по своей копии.\n"), quizWords)
+	for _, pos := range positions {
+		fmt.Fprintf(os.Stderr, tr("Слово #%d: "), pos+1)
+		answer, err := readAnswer(in)
+		if err != nil {
+			fmt.Fprintln(os.Stderr)
+			return withCode(exitCancelled, errorf("проверка записи отменена"))
+		}
+		if !quizWordMatches(answer, words[pos]) {
+			return errorf("слово #%d не совпадает с показанным: копия записана с ошибкой, создайте ее заново", pos+1)
+		}
+	}
+	fmt.Fprintln(os.Stderr, tr("✓ Запись мнемоники проверена"))
+	return nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+)
+
+// resultReceipt - квитанция о результате церемонии: метаданные и отпечаток
+// мастер-сида, подписанные ключом Ed25519. Сам мастер-сид в квитанцию
+// не попадает, поэтому ее можно публиковать.
+type resultReceipt struct {
+	Kind     string `json:"kind"`
+	Version  string `json:"version"`
+	Commit   string `json:"commit"`
+	Platform string `json:"platform"`
+	Result   string `json:"result"`
+	Params
+	SeedCount   int          `json:"seed_count"`
+	Nonce       string       `json:"nonce,omitempty"`
+	Drand       *drandBeacon `json:"drand,omitempty"`
+	NISTPulse   *nistPulse   `json:"nist_pulse,omitempty"`
+	Fingerprint string       `json:"fingerprint"`
+	CreatedAt   time.Time    `json:"created_at"`
+	PublicKey   string       `json:"public_key"`
+	Signature   string       `json:"signature,omitempty"`
+}
+
+// newReceipt переносит в квитанцию все поля результата, кроме мастер-сида
+func newReceipt(result resultRecord) *resultReceipt {
+	return &resultReceipt{
+		Kind:        "result-receipt",
+		Version:     buildVersion(),
+		Commit:      commit,
+		Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+		Result:      result.Kind,
+		Params:      result.Params,
+		SeedCount:   result.SeedCount,
+		Nonce:       result.Nonce,
+		Drand:       result.Drand,
+		NISTPulse:   result.NISTPulse,
+		Fingerprint: result.Fingerprint,
+		CreatedAt:   result.CreatedAt,
+	}
+}
+
+// signedBytes возвращает подписываемое представление квитанции (без подписи)
+func (r resultReceipt) signedBytes() ([]byte, error) {
+	r.Signature = ""
+	return json.Marshal(r)
+}
+
+// sign подписывает квитанцию ключом
+func (r *resultReceipt) sign(key ed25519.PrivateKey) error {
+	r.PublicKey = hex.EncodeToString(key.Public().(ed25519.PublicKey))
+	msg, err := r.signedBytes()
+	if err != nil {
+		return err
+	}
+	r.Signature = hex.EncodeToString(ed25519.Sign(key, msg))
+	return nil
+}
+
+// verify проверяет подпись квитанции и возвращает ключ, которым она подписана
+func (r resultReceipt) verify() (ed25519.PublicKey, error) {
+	pub, err := hex.DecodeString(r.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, errorf("некорректный открытый ключ в квитанции")
+	}
+	sig, err := hex.DecodeString(r.Signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, mismatchf("квитанция не подписана или подпись повреждена")
+	}
+	msg, err := r.signedBytes()
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(pub, msg, sig) {
+		return nil, mismatchf("подпись квитанции недействительна, квитанция изменена после подписания")
+	}
+	return pub, nil
+}
+
+// receiptFlags - общие флаги подписанной квитанции
+type receiptFlags struct {
+	signWith  *string
+	out       *string
+	timestamp *string
+	key       ed25519.PrivateKey
+}
+
+// addReceiptFlags регистрирует флаги квитанции в наборе
+func addReceiptFlags(fs *flag.FlagSet) *receiptFlags {
+	return &receiptFlags{
+		signWith:  fs.String("sign-with", "", "закрытый ключ Ed25519 (PEM) для подписи квитанции о результате"),
+		out:       fs.String("receipt", "receipt.json", "файл квитанции (с --sign-with или --timestamp)"),
+		timestamp: fs.String("timestamp", "", "подготовить запрос метки времени RFC 3161 над квитанцией в файл (отправляется позже: timestamp submit)"),
+	}
+}
+
+// load читает ключ подписи до ввода сидов, чтобы ошибка не заставила
+// повторять ввод
+func (rf *receiptFlags) load() error {
+	if *rf.signWith == "" {
+		return nil
+	}
+	key, err := loadSigningKey(*rf.signWith)
+	if err != nil {
+		return errorf("ошибка чтения ключа квитанции: %w", err)
+	}
+	rf.key = key
+	return nil
+}
+
+// write подписывает и сохраняет квитанцию о результате, сообщая об этом в w.
+// С --timestamp квитанция сохраняется и без подписи, а рядом - запрос метки
+// времени над ее байтами.
+func (rf *receiptFlags) write(w io.Writer, result resultRecord) error {
+	if rf.key == nil && *rf.timestamp == "" {
+		return nil
+	}
+	r := newReceipt(result)
+	if rf.key != nil {
+		if err := r.sign(rf.key); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if err := writeNewFile(*rf.out, data, 0644); err != nil {
+		return errorf("ошибка записи квитанции: %w", err)
+	}
+	if rf.key != nil {
+		fmt.Fprintf(w, tr("✓ Подписанная квитанция сохранена: %s (ключ %s)\n"), *rf.out, keyFingerprint(rf.key.Public().(ed25519.PublicKey)))
+	} else {
+		fmt.Fprintf(w, tr("✓ Квитанция сохранена без подписи: %s\n"), *rf.out)
+	}
+
+	if *rf.timestamp == "" {
+		return nil
+	}
+	req, err := newTimestampRequest(data)
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(*rf.timestamp, req, 0644); err != nil {
+		return errorf("ошибка записи запроса метки времени: %w", err)
+	}
+	fmt.Fprintf(w, tr("✓ Запрос метки времени сохранен: %s\n"), *rf.timestamp)
+	fmt.Fprintf(w, tr("  С машины с сетью: seedgen timestamp submit --tsa URL %s\n"), *rf.timestamp)
+	fmt.Fprintf(w, tr("  Или OpenTimestamps: ots stamp %s\n"), *rf.out)
+	return nil
+}
+
+// runVerifyReceipt проверяет подпись квитанции и, при необходимости,
+// соответствие ей мастер-сида
+func runVerifyReceipt(args []string) error {
+	fs := newFlagSet("verify-receipt")
+	pubkey := fs.String("pubkey", "", "ожидаемый открытый ключ: hex или PEM-файл")
+	masterRef := fs.String("master", "", "проверить, что мастер-сид (артефакт, msv2 или hex) соответствует квитанции")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errorf("укажите файл квитанции")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return err
+	}
+	var r resultReceipt
+	if err := json.Unmarshal(data, &r); err != nil {
+		return errorf("не удалось разобрать квитанцию: %w", err)
+	}
+	if r.Kind != "result-receipt" {
+		return errorf("файл не является квитанцией о результате (тип %q)", r.Kind)
+	}
+
+	pub, err := r.verify()
+	if err != nil {
+		return err
+	}
+	if *pubkey != "" {
+		want, err := parsePublicKey(*pubkey)
+		if err != nil {
+			return err
+		}
+		if !want.Equal(pub) {
+			return mismatchf("квитанция подписана другим ключом: %s", keyFingerprint(pub))
+		}
+	}
+	if *masterRef != "" {
+		master, err := readMaster(*masterRef)
+		if err != nil {
+			return err
+		}
+		defer wipe(master)
+		if !secretEqualString(masterFingerprint(master), r.Fingerprint) {
+			return mismatchf("мастер-сид не соответствует квитанции")
+		}
+	}
+
+	fmt.Printf(tr("=== Квитанция: %s ===\n\n"), positional[0])
+	fmt.Printf(tr("Версия: %s (коммит %s), %s\n"), r.Version, r.Commit, r.Platform)
+	fmt.Printf(tr("Результат: %s, сидов: %d, создан %s\n"), r.Result, r.SeedCount, r.CreatedAt.Format(time.RFC3339))
+	fmt.Println(describeParams(r.Params))
+	if r.Nonce != "" {
+		fmt.Printf(tr("Нонс: %s\n"), r.Nonce)
+	}
+	if r.Drand != nil {
+		fmt.Printf(tr("Маяк: %s, подпись %s\n"), r.Drand.describe(), r.Drand.Signature)
+	}
+	if r.NISTPulse != nil {
+		fmt.Printf(tr("Маяк: %s, outputValue %s\n"), r.NISTPulse.describe(), r.NISTPulse.OutputValue)
+	}
+	fmt.Printf(tr("SHA-512 хеш: %s...\n"), emph(r.Fingerprint))
+	fmt.Println()
+	fmt.Printf(tr("✓ Подпись действительна, ключ %s\n"), keyFingerprint(pub))
+	if *masterRef != "" {
+		fmt.Println(tr("✓ Мастер-сид соответствует квитанции"))
+	}
+	if *pubkey == "" {
+		fmt.Println(tr("  Сверьте отпечаток ключа с опубликованным или укажите --pubkey."))
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// rsBackupPrefix начинает бумажную копию мастер-сида с кодом Рида-Соломона
+const rsBackupPrefix = "rs1:"
+
+// rsParity - число проверочных байт копии: восстанавливаются до 16
+// нечитаемых байт или до 8 прочитанных неверно (2·ошибки + нечитаемые <= 16)
+const rsParity = 16
+
+// rsGroupsPerLine - число групп по 4 символа в строке копии
+const rsGroupsPerLine = 8
+
+// writeRSBackup выводит копию мастер-сида для записи на бумагу: hex
+// мастер-сида и проверочных байт группами по 4 символа
+func writeRSBackup(w io.Writer, master []byte) error {
+	code := rsEncode(master, rsParity)
+	defer wipe(code)
+	digits := hexSecret(code)
+	defer wipe(digits)
+
+	groups := len(digits) / 4
+	out := newSecret(len(rsBackupPrefix) + 1 + groups*5)[:0]
+	defer func() { wipe(out[:cap(out)]) }()
+	out = append(out, rsBackupPrefix...)
+	out = append(out, '\n')
+	for i := 0; i < groups; i++ {
+		out = append(out, digits[4*i:4*i+4]...)
+		if (i+1)%rsGroupsPerLine == 0 || i == groups-1 {
+			out = append(out, '\n')
+		} else {
+			out = append(out, ' ')
+		}
+	}
+	_, err := w.Write(out)
+	return err
+}
+
+// parseRSBackup разбирает копию rs1. Пробелы и переводы строк не важны,
+// а символ, который нельзя прочитать, записывается как "?": байт с ним
+// считается стертым, и его восстановление обходится вдвое дешевле ошибки.
+func parseRSBackup(text []byte) (code []byte, erasures []int, err error) {
+	text = bytes.TrimSpace(text)
+	if len(text) < len(rsBackupPrefix) || !strings.EqualFold(string(text[:len(rsBackupPrefix)]), rsBackupPrefix) {
+		return nil, nil, errorf("копия должна начинаться с %q", rsBackupPrefix)
+	}
+	digits := newSecret(len(text))[:0]
+	defer func() { wipe(digits[:cap(digits)]) }()
+	for _, c := range text[len(rsBackupPrefix):] {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+		default:
+			digits = append(digits, c)
+		}
+	}
+	if want := 2 * (masterSeedSize + rsParity); len(digits) != want {
+		return nil, nil, errorf("в копии %d символов вместо %d: нечитаемые символы замените на ?, а не пропускайте", len(digits), want)
+	}
+
+	code = newSecret(masterSeedSize + rsParity)
+	for i := range code {
+		if _, err := decodeHex(code[i:i+1], digits[2*i:2*i+2]); err != nil {
+			erasures = append(erasures, i)
+		}
+	}
+	return code, erasures, nil
+}
+
+// runRecover восстанавливает мастер-сид из поврежденной копии rs1
+func runRecover(args []string) error {
+	fs := newFlagSet("recover")
+	format := fs.String("format", "hex", "формат вывода: hex или rs (исправленная копия)")
+	rf := addRevealFlags(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *format != "hex" && *format != "rs" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	if err := rf.checkGroups(*format == "hex"); err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return errorf("укажите один файл с копией или - для stdin")
+	}
+	ref := "-"
+	if len(positional) == 1 {
+		ref = positional[0]
+	}
+	if ref == "-" && isTerminal(os.Stdin) {
+		fmt.Fprintln(os.Stderr, tr("Введите копию rs1 построчно, нечитаемые символы замените на ?, затем нажмите Ctrl-D:"))
+	}
+
+	var text []byte
+	if ref == "-" {
+		text, err = io.ReadAll(os.Stdin)
+	} else {
+		text, err = os.ReadFile(ref)
+	}
+	if err != nil {
+		return err
+	}
+	lockSecret(text)
+	defer wipe(text)
+
+	code, erasures, err := parseRSBackup(text)
+	if err != nil {
+		return err
+	}
+	defer wipe(code)
+	fixed, err := rsDecode(code, rsParity, erasures)
+	if err != nil {
+		return errorf("копию не удалось восстановить: %w (исправимо 2·ошибки + нечитаемые байты <= %d)", err, rsParity)
+	}
+	master := newSecret(masterSeedSize)
+	defer wipe(master)
+	copy(master, code)
+
+	if len(fixed) == 0 {
+		logInfof("✓ Копия прочитана без ошибок\n")
+	} else {
+		sort.Ints(fixed)
+		groups := make([]string, 0, len(fixed))
+		seen := make(map[int]bool)
+		for _, pos := range fixed {
+			// Байт занимает два символа, группа - четыре
+			if g := pos/2 + 1; !seen[g] {
+				seen[g] = true
+				groups = append(groups, fmt.Sprint(g))
+			}
+		}
+		logWarnf("⚠ Исправлено байт: %d, группы: %s. Перепишите копию заново\n", len(fixed), strings.Join(groups, ", "))
+	}
+	logInfof("Отпечаток: %s\n", masterFingerprint(master))
+
+	if *format == "rs" {
+		return writeRSBackup(os.Stdout, master)
+	}
+	if !isTerminal(os.Stdout) {
+		return writeSecretLine(os.Stdout, master)
+	}
+	return rf.reveal(tr("Мастер-сид (восстановлен из копии):"), master)
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// redrawMove - участник, получивший при пережеребьевке другой номер
+type redrawMove struct {
+	Name string `json:"participant"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	// Drawn - номер назначен жребием, а не сдвигом сеяных
+	Drawn bool `json:"drawn,omitempty"`
+}
+
+// bracketRedraw - пережеребьевка сетки после снятия участников
+type bracketRedraw struct {
+	Kind        string       `json:"kind"`
+	Label       string       `json:"label"`
+	Previous    string       `json:"previous_sha256"`
+	Excluded    []string     `json:"excluded"`
+	Fixed       []string     `json:"fixed,omitempty"`
+	Fingerprint string       `json:"master_fingerprint"`
+	Moves       []redrawMove `json:"moves"`
+	Bracket     bracket      `json:"bracket"`
+}
+
+// bracketNumbers восстанавливает по первому кругу номера участников:
+// numbers[i] - участник с номером i+1
+func bracketNumbers(b bracket) ([]string, error) {
+	if b.Size < 2 || b.Size > 1024 || b.Size&(b.Size-1) != 0 || b.Count > b.Size || b.Seeded < 0 || b.Seeded > b.Count {
+		return nil, errorf("некорректная сетка: размер %d, участников %d, сеяных %d", b.Size, b.Count, b.Seeded)
+	}
+	if len(b.Rounds) == 0 || len(b.Rounds[0]) != b.Size/2 {
+		return nil, errorf("некорректная сетка: в первом круге должно быть %d матчей", b.Size/2)
+	}
+	order := bracketOrder(b.Size)
+	numbers := make([]string, b.Size)
+	for m, match := range b.Rounds[0] {
+		home, away := order[2*m], order[2*m+1]
+		if home > away {
+			home, away = away, home
+		}
+		numbers[home-1] = match.Home
+		if !match.Bye {
+			numbers[away-1] = match.Away
+		}
+	}
+	for i, name := range numbers[:b.Count] {
+		if name == "" {
+			return nil, errorf("некорректная сетка: номер %d не занят, а участников %d", i+1, b.Count)
+		}
+	}
+	return numbers[:b.Count], nil
+}
+
+// redrawBracket снимает участников excluded и перераспределяет номера:
+//  1. оставшиеся сеяные сохраняют порядок и занимают номера 1..S', кроме
+//     номеров закрепленных (fixed) сеяных;
+//  2. номера больше нового числа участников n' становятся пропусками,
+//     их владельцы перемешиваются потоком (Фишер-Йетс, по возрастанию
+//     старого номера) и по порядку занимают свободные номера до n'
+//     по возрастанию.
+//
+// Остальные участники сохраняют номера, поэтому пропуски по-прежнему
+// достаются сильнейшим номерам, а затронута только часть сетки.
+func redrawBracket(stream *drawStream, numbers []string, seeded int, excluded, fixed map[string]bool) ([]string, int, []redrawMove, error) {
+	count := len(numbers) - len(excluded)
+	if count < 2 {
+		return nil, 0, nil, errorf("после снятия в сетке остается меньше 2 участников")
+	}
+	next := make([]string, count)
+	var moves []redrawMove
+
+	// Сеяные: закрепленные на своих номерах, остальные по порядку на свободные
+	var seeds []string
+	for _, name := range numbers[:seeded] {
+		if !excluded[name] {
+			seeds = append(seeds, name)
+		}
+	}
+	for i, name := range numbers[:seeded] {
+		if !fixed[name] {
+			continue
+		}
+		if i >= len(seeds) {
+			return nil, 0, nil, errorf("сеяный %q не может сохранить номер %d: сеяных остается %d", name, i+1, len(seeds))
+		}
+		next[i] = name
+	}
+	free := 0
+	for _, name := range seeds {
+		if fixed[name] {
+			continue
+		}
+		for next[free] != "" {
+			free++
+		}
+		next[free] = name
+		if old := indexOf(numbers, name) + 1; old != free+1 {
+			moves = append(moves, redrawMove{Name: name, From: old, To: free + 1})
+		}
+	}
+
+	// Несеяные сохраняют номера до n', владельцы больших номеров перетягиваются
+	var movers []string
+	for i, name := range numbers[seeded:] {
+		n := seeded + i + 1
+		switch {
+		case excluded[name]:
+		case n <= count:
+			next[n-1] = name
+		case fixed[name]:
+			return nil, 0, nil, errorf("%q не может сохранить номер %d: участников остается %d, номер становится пропуском", name, n, count)
+		default:
+			movers = append(movers, name)
+		}
+	}
+	var targets []int
+	for i, name := range next {
+		if name == "" {
+			targets = append(targets, i+1)
+		}
+	}
+	stream.shuffle(len(movers), func(i, j int) { movers[i], movers[j] = movers[j], movers[i] })
+	for i, name := range movers {
+		next[targets[i]-1] = name
+		moves = append(moves, redrawMove{Name: name, From: indexOf(numbers, name) + 1, To: targets[i], Drawn: true})
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].To < moves[j].To })
+	return next, len(seeds), moves, nil
+}
+
+// indexOf возвращает позицию имени в списке или -1
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveParticipants переводит значения --exclude и --keep-fixed в имена:
+// значение - имя участника сетки или id из выгрузки регистрации
+func resolveParticipants(values []string, numbers []string, roster []rosterEntry) (map[string]bool, error) {
+	byID := make(map[string]string)
+	for _, e := range roster {
+		byID[e.ID] = e.Name
+	}
+	names := make(map[string]bool)
+	for _, v := range values {
+		name := v
+		if n, ok := byID[v]; ok {
+			name = n
+		}
+		if indexOf(numbers, name) < 0 {
+			return nil, errorf("участника %q нет в сетке", v)
+		}
+		names[name] = true
+	}
+	return names, nil
+}
+
+// runRedraw перестраивает опубликованную сетку после снятия участников
+func runRedraw(args []string) error {
+	fs := newFlagSet("redraw")
+	resultPath := fs.String("result", "", "опубликованная сетка: вывод seedgen bracket --format json")
+	var exclude, keep stringList
+	fs.Var(&exclude, "exclude", "снятый участник: имя или id из --roster (можно несколько)")
+	fs.Var(&keep, "keep-fixed", "участник, который должен сохранить свой номер: имя или id из --roster (можно несколько)")
+	rosterPath := fs.String("roster", "", "выгрузка участников из регистрации, чтобы указывать участников по id")
+	label := fs.String("label", "redraw", "метка пережеребьевки: для каждой следующей пережеребьевки той же сетки берите новую")
+	format := fs.String("format", "text", "формат вывода: text или json")
+	render := fs.String("render", "", "вывести схему новой сетки: svg или html")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *resultPath == "" {
+		return errorf("укажите опубликованную сетку через --result")
+	}
+	if len(exclude) == 0 {
+		return errorf("укажите снятых участников через --exclude")
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	if err := checkRender(*render, *format); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(*resultPath)
+	if err != nil {
+		return err
+	}
+	var prev bracket
+	if err := json.Unmarshal(data, &prev); err != nil {
+		return errorf("не удалось разобрать %s: %w", *resultPath, err)
+	}
+	if prev.Kind != "bracket" {
+		return errorf("%s не является сеткой seedgen bracket (kind %q)", *resultPath, prev.Kind)
+	}
+	numbers, err := bracketNumbers(prev)
+	if err != nil {
+		return errorf("%s: %w", *resultPath, err)
+	}
+	var roster []rosterEntry
+	if *rosterPath != "" {
+		if roster, _, err = loadRoster(*rosterPath); err != nil {
+			return err
+		}
+	}
+	excluded, err := resolveParticipants(exclude, numbers, roster)
+	if err != nil {
+		return err
+	}
+	fixed, err := resolveParticipants(keep, numbers, roster)
+	if err != nil {
+		return err
+	}
+	for name := range fixed {
+		if excluded[name] {
+			return errorf("%q одновременно снят и закреплен", name)
+		}
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	fingerprint := masterFingerprint(master)
+	if prev.Fingerprint != fingerprint {
+		return errorf("сетка получена из мастер-сида %s..., а указан %s...", prev.Fingerprint, fingerprint)
+	}
+	next, seeded, moves, err := redrawBracket(newDrawStream(master, "redraw", *label), numbers, prev.Seeded, excluded, fixed)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	r := bracketRedraw{
+		Kind:        "bracket-redraw",
+		Label:       *label,
+		Previous:    hex.EncodeToString(sum[:]),
+		Excluded:    sortedNames(excluded),
+		Fixed:       sortedNames(fixed),
+		Fingerprint: fingerprint,
+		Moves:       moves,
+		Bracket: bracket{
+			Kind:         "bracket",
+			Label:        prev.Label,
+			Participants: prev.Participants,
+			Seeded:       seeded,
+			Fingerprint:  fingerprint,
+			Double:       prev.Double,
+		},
+	}
+	r.Bracket.build(next)
+	// Идентификаторы привязаны к номерам матчей и после пережеребьевки не меняются
+	r.Bracket.assignMatchRefs(uuidNamespace(master))
+	if r.Moves == nil {
+		r.Moves = []redrawMove{}
+	}
+	if *format == "json" {
+		return writeOutput(os.Stdout, r)
+	}
+	b := r.Bracket
+	if *render != "" {
+		h := renderHeader{
+			title: fmt.Sprintf(tr("Сетка %q после пережеребьевки %q"), b.Label, r.Label),
+			lines: []string{
+				fmt.Sprintf(tr("Участников %d, сеяных %d, пропусков %d, сняты: %d"), b.Count, b.Seeded, b.Byes, len(r.Excluded)),
+				fmt.Sprintf(tr("Мастер-сид: %s..., прежняя сетка: SHA-256 %s"), r.Fingerprint, r.Previous),
+			},
+			build: provenanceLine(),
+		}
+		return writeRender(os.Stdout, *render, h, func(w io.Writer) { renderBracketSVG(w, b, h) })
+	}
+
+	fmt.Printf(tr("Пережеребьевка %q сетки %q: сняты %d, участников %d, сеяных %d, пропусков %d\n"), r.Label, b.Label, len(r.Excluded), b.Count, b.Seeded, b.Byes)
+	fmt.Printf(tr("Мастер-сид: %s..., прежняя сетка: SHA-256 %s\n"), emph(r.Fingerprint), r.Previous)
+	fmt.Println()
+	for _, name := range r.Excluded {
+		fmt.Printf(tr("  снят: %s (номер %d)\n"), name, indexOf(numbers, name)+1)
+	}
+	if len(r.Moves) == 0 {
+		fmt.Println(tr("  номера остальных участников не меняются"))
+	}
+	for _, m := range r.Moves {
+		how := tr("сдвиг сеяных")
+		if m.Drawn {
+			how = tr("жребий")
+		}
+		fmt.Printf(tr("  %s: номер %d -> %d (%s)\n"), m.Name, m.From, m.To, how)
+	}
+	printBracket(b)
+	return nil
+}
+
+// sortedNames возвращает имена множества по алфавиту
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
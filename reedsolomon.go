@@ -0,0 +1,238 @@
+package main
+
+// Декодер кодов Рида-Соломона к кодировщику reedSolomon из qr.go: то же поле
+// GF(256) с многочленом 0x11d и корни порождающего многочлена α^0..α^(nsym-1).
+// Многочлены хранятся от старшей степени к младшей, как байты кодового слова.
+
+// gfExp и gfLog - таблицы степеней α и логарифмов поля. gfExp продолжена
+// периодически до 512 элементов, чтобы индексы от 255 не приводить по модулю.
+var (
+	gfExp [512]byte
+	gfLog [256]int
+)
+
+// init заполняет таблицы степеней и логарифмов GF(256)
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+// gfDiv делит x на ненулевой y в GF(256)
+func gfDiv(x, y byte) byte {
+	if x == 0 {
+		return 0
+	}
+	return gfExp[(gfLog[x]+255-gfLog[y])%255]
+}
+
+// gfPow возвращает α^p для любого целого p
+func gfPow(p int) byte {
+	return gfExp[(p%255+255)%255]
+}
+
+// gfInverse возвращает обратный к ненулевому x элемент поля
+func gfInverse(x byte) byte {
+	return gfExp[255-gfLog[x]]
+}
+
+// gfPolyScale умножает многочлен на элемент поля
+func gfPolyScale(p []byte, x byte) []byte {
+	out := make([]byte, len(p))
+	for i, c := range p {
+		out[i] = gfMul(c, x)
+	}
+	return out
+}
+
+// gfPolyAdd складывает многочлены разной степени (XOR коэффициентов)
+func gfPolyAdd(p, q []byte) []byte {
+	n := len(p)
+	if len(q) > n {
+		n = len(q)
+	}
+	out := make([]byte, n)
+	copy(out[n-len(p):], p)
+	for i, c := range q {
+		out[n-len(q)+i] ^= c
+	}
+	return out
+}
+
+// gfPolyMul перемножает многочлены
+func gfPolyMul(p, q []byte) []byte {
+	out := make([]byte, len(p)+len(q)-1)
+	for j, b := range q {
+		for i, a := range p {
+			out[i+j] ^= gfMul(a, b)
+		}
+	}
+	return out
+}
+
+// gfPolyEval вычисляет значение многочлена в точке x по схеме Горнера
+func gfPolyEval(p []byte, x byte) byte {
+	y := p[0]
+	for _, c := range p[1:] {
+		y = gfMul(y, x) ^ c
+	}
+	return y
+}
+
+// rsEncode дописывает к данным nsym проверочных байт кодировщиком QR-кода.
+// Кодовое слово содержит данные, поэтому выделяется как секрет.
+func rsEncode(data []byte, nsym int) []byte {
+	parity := reedSolomon(data, nsym)
+	defer wipe(parity)
+	out := newSecret(len(data) + nsym)
+	copy(out, data)
+	copy(out[len(data):], parity)
+	return out
+}
+
+// rsSyndromes вычисляет синдромы кодового слова; все нули - ошибок нет
+func rsSyndromes(msg []byte, nsym int) ([]byte, bool) {
+	synd := make([]byte, nsym)
+	clean := true
+	for i := range synd {
+		synd[i] = gfPolyEval(msg, gfPow(i))
+		if synd[i] != 0 {
+			clean = false
+		}
+	}
+	return synd, clean
+}
+
+// rsForneySyndromes исключает из синдромов вклад известных стираний
+func rsForneySyndromes(synd []byte, erasures []int, n int) []byte {
+	fsynd := append([]byte{}, synd...)
+	for _, pos := range erasures {
+		x := gfPow(n - 1 - pos)
+		for j := 0; j < len(fsynd)-1; j++ {
+			fsynd[j] = gfMul(fsynd[j], x) ^ fsynd[j+1]
+		}
+	}
+	return fsynd
+}
+
+// rsErrorLocator находит многочлен локаторов ошибок алгоритмом Берлекэмпа-Мэсси
+func rsErrorLocator(synd []byte, nsym, erasures int) ([]byte, error) {
+	errLoc, oldLoc := []byte{1}, []byte{1}
+	for i := 0; i < nsym-erasures; i++ {
+		delta := synd[i]
+		for j := 1; j < len(errLoc); j++ {
+			delta ^= gfMul(errLoc[len(errLoc)-1-j], synd[i-j])
+		}
+		oldLoc = append(oldLoc, 0)
+		if delta != 0 {
+			if len(oldLoc) > len(errLoc) {
+				newLoc := gfPolyScale(oldLoc, delta)
+				oldLoc = gfPolyScale(errLoc, gfInverse(delta))
+				errLoc = newLoc
+			}
+			errLoc = gfPolyAdd(errLoc, gfPolyScale(oldLoc, delta))
+		}
+	}
+	for len(errLoc) > 0 && errLoc[0] == 0 {
+		errLoc = errLoc[1:]
+	}
+	if errs := len(errLoc) - 1; 2*errs+erasures > nsym {
+		return nil, errorf("слишком много ошибок")
+	}
+	return errLoc, nil
+}
+
+// rsErrorPositions находит позиции ошибок перебором корней локатора
+func rsErrorPositions(errLoc []byte, n int) ([]int, error) {
+	rev := make([]byte, len(errLoc))
+	for i, c := range errLoc {
+		rev[len(errLoc)-1-i] = c
+	}
+	var positions []int
+	for i := 0; i < n; i++ {
+		if gfPolyEval(rev, gfPow(i)) == 0 {
+			positions = append(positions, n-1-i)
+		}
+	}
+	if len(positions) != len(errLoc)-1 {
+		return nil, errorf("позиции ошибок не найдены")
+	}
+	return positions, nil
+}
+
+// rsCorrectErrata исправляет значения в известных позициях алгоритмом Форни
+func rsCorrectErrata(msg, synd []byte, positions []int) error {
+	n := len(msg)
+	errLoc := []byte{1}
+	x := make([]byte, len(positions))
+	for i, pos := range positions {
+		x[i] = gfPow(n - 1 - pos)
+		errLoc = gfPolyMul(errLoc, []byte{x[i], 1})
+	}
+
+	// Ω(x) = S(x)·Λ(x) mod x^(v+1), где S(x) = S0·x + S1·x^2 + ...
+	rsynd := make([]byte, len(synd)+1)
+	for i, c := range synd {
+		rsynd[len(synd)-1-i] = c
+	}
+	product := gfPolyMul(rsynd, errLoc)
+	eval := product[len(product)-len(errLoc):]
+
+	for i, xi := range x {
+		xiInv := gfInverse(xi)
+		prime := byte(1)
+		for j, xj := range x {
+			if j != i {
+				prime = gfMul(prime, 1^gfMul(xiInv, xj))
+			}
+		}
+		if prime == 0 {
+			return errorf("позиции ошибок совпадают")
+		}
+		y := gfMul(xi, gfPolyEval(eval, xiInv))
+		msg[positions[i]] ^= gfDiv(y, prime)
+	}
+	return nil
+}
+
+// rsDecode исправляет ошибки и стирания в кодовом слове на месте и
+// возвращает позиции исправленных байт. erasures - позиции нечитаемых байт.
+// Исправимо 2·ошибки + стирания <= nsym.
+func rsDecode(msg []byte, nsym int, erasures []int) ([]int, error) {
+	if len(erasures) > nsym {
+		return nil, errorf("нечитаемых байт %d, восстановимо не больше %d", len(erasures), nsym)
+	}
+	for _, pos := range erasures {
+		msg[pos] = 0
+	}
+	synd, clean := rsSyndromes(msg, nsym)
+	if clean {
+		return erasures, nil
+	}
+	fsynd := rsForneySyndromes(synd, erasures, len(msg))
+	errLoc, err := rsErrorLocator(fsynd, nsym, len(erasures))
+	if err != nil {
+		return nil, err
+	}
+	errPos, err := rsErrorPositions(errLoc, len(msg))
+	if err != nil {
+		return nil, err
+	}
+	positions := append(append([]int{}, erasures...), errPos...)
+	if err := rsCorrectErrata(msg, synd, positions); err != nil {
+		return nil, err
+	}
+	if _, clean := rsSyndromes(msg, nsym); !clean {
+		return nil, errorf("исправить не удалось")
+	}
+	return positions, nil
+}
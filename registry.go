@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"os"
+	"strings"
+)
+
+// readRegistry читает реестр путей вывода: по одному пути на строку,
+// пустые строки и комментарии после "#" пропускаются
+func readRegistry(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var paths []string
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if prev, ok := seen[text]; ok {
+			return nil, errorf("%s:%d: путь %q уже указан в строке %d", path, line, text, prev)
+		}
+		seen[text] = line
+		paths = append(paths, text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// deriveLabeledKey выводит независимый подключ мастер-сида для пути
+func deriveLabeledKey(master []byte, path string) []byte {
+	mac := hmac.New(sha512.New, master)
+	mac.Write([]byte("seedgen/derive/"))
+	mac.Write([]byte(path))
+	return mac.Sum(nil)
+}
+
+// pathFingerprint возвращает публикуемый отпечаток ключа по пути.
+// По отпечатку нельзя восстановить ни ключ, ни мастер-сид.
+func pathFingerprint(master []byte, path string) string {
+	key := deriveLabeledKey(master, path)
+	defer wipe(key)
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Размеры элементов схемы в пикселях SVG
+const (
+	renderMargin    = 24
+	renderTitle     = 56
+	renderBoxWidth  = 200
+	renderBoxHeight = 44
+	renderColumnGap = 48
+	renderRowGap    = 16
+	renderNameRunes = 26
+	// renderMinWidth - ширина, при которой помещается строка с SHA-256
+	renderMinWidth = 640
+)
+
+// renderHeader - заголовок схемы: название и данные для сверки
+type renderHeader struct {
+	title string
+	lines []string
+	// build - строка сведений о сборке под остальными
+	build string
+}
+
+// metaLines возвращает число строк под названием
+func (h renderHeader) metaLines() int {
+	if h.build != "" {
+		return len(h.lines) + 1
+	}
+	return len(h.lines)
+}
+
+// checkRender проверяет значение --render; вместе с ним --format должен остаться text
+func checkRender(render, format string) error {
+	switch render {
+	case "", "svg", "html":
+	default:
+		return errorf("неизвестный вид схемы %q, доступны: svg, html", render)
+	}
+	if render != "" && format != "text" {
+		return errorf("--render выводит схему вместо текста и не сочетается с --format %s", format)
+	}
+	return nil
+}
+
+// shortName укорачивает имя, чтобы оно помещалось в рамку
+func shortName(name string) string {
+	runes := []rune(name)
+	if len(runes) <= renderNameRunes {
+		return name
+	}
+	return string(runes[:renderNameRunes-1]) + "…"
+}
+
+// svgText выводит экранированную строку текста
+func svgText(w io.Writer, x, y int, class, text string) {
+	fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" class=\"%s\">%s</text>\n", x, y, class, html.EscapeString(text))
+}
+
+// writeSVGHeader открывает SVG с общими стилями и выводит заголовок
+func writeSVGHeader(w io.Writer, width, height int, h renderHeader) {
+	if width < renderMinWidth {
+		width = renderMinWidth
+	}
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"Helvetica, Arial, sans-serif\">\n", width, height, width, height)
+	fmt.Fprintln(w, "<style>.title{font-size:18px;font-weight:bold}.meta{font-size:10px;fill:#555}.head{font-size:12px;font-weight:bold;fill:#333}.name{font-size:13px}.pending{font-size:12px;fill:#888;font-style:italic}.id{font-size:9px;fill:#888;text-anchor:end}.box{fill:#fff;stroke:#333;stroke-width:1}.line{fill:none;stroke:#333;stroke-width:1}</style>")
+	fmt.Fprintf(w, "<rect width=\"%d\" height=\"%d\" fill=\"#fff\"/>\n", width, height)
+	svgText(w, renderMargin, renderMargin+4, "title", h.title)
+	for i, line := range h.lines {
+		svgText(w, renderMargin, renderMargin+20+i*12, "meta", line)
+	}
+	if h.build != "" {
+		svgText(w, renderMargin, renderMargin+20+len(h.lines)*12, "meta build", h.build)
+	}
+}
+
+// renderBracketSVG рисует сетку: круги - столбцы слева направо, матч -
+// рамка с двумя строками, от каждой пары матчей линия идет к матчу
+// следующего круга. Сетка проигравших двойного выбывания рисуется под
+// сеткой победителей, суперфинал - справа от финала.
+func renderBracketSVG(w io.Writer, b bracket, h renderHeader) {
+	first := len(b.Rounds[0])
+	unit := renderBoxHeight + renderRowGap
+	step := renderBoxWidth + renderColumnGap
+	top := renderMargin + renderTitle + h.metaLines()*12
+	columns := len(b.Rounds) + len(b.Final)/2
+	if len(b.Losers) > columns {
+		columns = len(b.Losers)
+	}
+	losersTop := top + 20 + first*unit + renderRowGap
+	height := losersTop + renderMargin
+	if len(b.Losers) > 0 {
+		height += 20 + first/2*unit
+	}
+	// Переигровка суперфинала стоит под ним и при маленькой сетке ниже остальных
+	if bottom := top + 20 + first*unit/2 + len(b.Final)*unit + renderMargin; bottom > height {
+		height = bottom
+	}
+	writeSVGHeader(w, renderMargin*2+columns*step-renderColumnGap, height, h)
+
+	centerY := func(round, match int) int {
+		span := unit << uint(round)
+		return top + 20 + match*span + span/2
+	}
+	for r, round := range b.Rounds {
+		x := renderMargin + r*step
+		title := roundName(r+1, len(round))
+		if b.Double {
+			title = fmt.Sprintf(tr("Победители, круг %d"), r+1)
+		}
+		svgText(w, x, top+8, "head", title)
+		for m, match := range round {
+			cy := centerY(r, m)
+			svgMatch(w, x, cy, match)
+			if r+1 < len(b.Rounds) {
+				svgConnector(w, x+renderBoxWidth, cy, centerY(r+1, m/2))
+			}
+		}
+	}
+
+	if len(b.Final) > 0 {
+		x := renderMargin + len(b.Rounds)*step
+		cy := centerY(len(b.Rounds)-1, 0)
+		svgText(w, x, top+8, "head", tr("Суперфинал"))
+		svgConnector(w, x-renderColumnGap, cy, cy)
+		for i, match := range b.Final {
+			svgMatch(w, x, cy+i*unit, match)
+		}
+	}
+
+	// В сетке проигравших круги попарно одного размера: в нечетном
+	// участники встречают проигравших сетки победителей, в четном играют
+	// между собой
+	losersY := func(round, match int) int {
+		span := unit << uint(round/2)
+		return losersTop + 20 + match*span + span/2
+	}
+	for r, round := range b.Losers {
+		x := renderMargin + r*step
+		svgText(w, x, losersTop+8, "head", fmt.Sprintf(tr("Проигравшие, круг %d"), r+1))
+		var next map[int]bool
+		if r+1 < len(b.Losers) {
+			next = make(map[int]bool)
+			for _, m := range b.Losers[r+1] {
+				next[matchIndex(m.ID)] = true
+			}
+		}
+		for _, match := range round {
+			m := matchIndex(match.ID)
+			cy := losersY(r, m)
+			svgMatch(w, x, cy, match)
+			to := m
+			if (r+1)%2 == 0 {
+				to = m / 2
+			}
+			if next[to] {
+				svgConnector(w, x+renderBoxWidth, cy, losersY(r+1, to))
+			}
+		}
+	}
+	fmt.Fprintln(w, "</svg>")
+}
+
+// matchIndex возвращает позицию матча в круге по его номеру (L2-M3 - 2)
+func matchIndex(id string) int {
+	n, _ := strconv.Atoi(id[strings.LastIndex(id, "-M")+2:])
+	return n - 1
+}
+
+// svgMatch рисует рамку матча с центром по вертикали cy
+func svgMatch(w io.Writer, x, cy int, match bracketMatch) {
+	y := cy - renderBoxHeight/2
+	fmt.Fprintf(w, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" rx=\"3\" class=\"box\"/>\n", x, y, renderBoxWidth, renderBoxHeight)
+	fmt.Fprintf(w, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" class=\"line\"/>\n", x, cy, x+renderBoxWidth, cy)
+	id := match.ID
+	if match.IfNeeded {
+		id += tr(", если понадобится")
+	}
+	svgText(w, x+renderBoxWidth-2, y-3, "id", id)
+	svgEntrant(w, x+8, cy-7, match.Home)
+	if match.Bye {
+		svgText(w, x+8, cy+16, "pending", tr("проходит без игры"))
+	} else {
+		svgEntrant(w, x+8, cy+16, match.Away)
+	}
+}
+
+// svgConnector ведет линию от матча к матчу следующего круга:
+// вправо, по вертикали к его центру, вправо
+func svgConnector(w io.Writer, x, from, to int) {
+	mid := x + renderColumnGap/2
+	fmt.Fprintf(w, "<polyline points=\"%d,%d %d,%d %d,%d %d,%d\" class=\"line\"/>\n", x, from, mid, from, mid, to, x+renderColumnGap, to)
+}
+
+// svgEntrant выводит участника матча; еще не известного участника - серым
+func svgEntrant(w io.Writer, x, y int, name string) {
+	if strings.HasPrefix(name, "победитель ") || strings.HasPrefix(name, "проигравший ") {
+		svgText(w, x, y, "pending", entrantName(name))
+		return
+	}
+	svgText(w, x, y, "name", shortName(name))
+}
+
+// renderGroupsSVG рисует группы карточками по четыре в ряд
+func renderGroupsSVG(w io.Writer, groups [][]drawTeam, h renderHeader) {
+	const perRow = 4
+	rows := (len(groups) + perRow - 1) / perRow
+	size := 0
+	for _, g := range groups {
+		if len(g) > size {
+			size = len(g)
+		}
+	}
+	cols := perRow
+	if len(groups) < perRow {
+		cols = len(groups)
+	}
+	cardHeight := 28 + size*22 + 8
+	top := renderMargin + renderTitle + h.metaLines()*12
+	width := renderMargin*2 + cols*renderBoxWidth + (cols-1)*renderRowGap
+	height := top + rows*(cardHeight+renderRowGap) + renderMargin
+	writeSVGHeader(w, width, height, h)
+
+	for g, teams := range groups {
+		x := renderMargin + (g%perRow)*(renderBoxWidth+renderRowGap)
+		y := top + (g/perRow)*(cardHeight+renderRowGap)
+		fmt.Fprintf(w, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" rx=\"3\" class=\"box\"/>\n", x, y, renderBoxWidth, cardHeight)
+		svgText(w, x+8, y+18, "head", tr("Группа ")+groupName(g))
+		fmt.Fprintf(w, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" class=\"line\"/>\n", x, y+26, x+renderBoxWidth, y+26)
+		for i, t := range teams {
+			name := shortName(t.Name)
+			if t.Country != "" {
+				name += " (" + t.Country + ")"
+			}
+			svgText(w, x+8, y+44+i*22, "name", name)
+		}
+	}
+	fmt.Fprintln(w, "</svg>")
+}
+
+// writeRender выводит схему в SVG или в HTML-странице для печати и встраивания
+func writeRender(out io.Writer, render string, h renderHeader, draw func(io.Writer)) error {
+	w := bufio.NewWriter(out)
+	if render == "html" {
+		fmt.Fprintln(w, "<!DOCTYPE html>")
+		fmt.Fprintln(w, "<html lang=\"ru\">")
+		fmt.Fprintln(w, "<head>")
+		fmt.Fprintln(w, "<meta charset=\"utf-8\">")
+		fmt.Fprintf(w, "<title>%s</title>\n", html.EscapeString(h.title))
+		fmt.Fprintln(w, "<style>body{margin:0;padding:16px;font-family:Helvetica,Arial,sans-serif}svg{max-width:100%;height:auto}@media print{body{padding:0}@page{size:landscape;margin:10mm}}</style>")
+		fmt.Fprintln(w, "</head>")
+		fmt.Fprintln(w, "<body>")
+		draw(w)
+		fmt.Fprintln(w, "</body>")
+		fmt.Fprintln(w, "</html>")
+	} else {
+		fmt.Fprintln(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>")
+		draw(w)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// resultRecord - JSON-представление результата генерации мастер-сида
+type resultRecord struct {
+	Kind string `json:"kind"`
+	Params
+	// KDFPreset - имя пресета --preset, из которого взяты параметры KDF
+	KDFPreset   string         `json:"kdf_preset,omitempty"`
+	SeedCount   int            `json:"seed_count"`
+	Nonce       string         `json:"nonce,omitempty"`
+	Drand       *drandBeacon   `json:"drand,omitempty"`
+	NISTPulse   *nistPulse     `json:"nist_pulse,omitempty"`
+	Entropy     *entropyReport `json:"entropy_report,omitempty"`
+	Master      secretHex      `json:"master"`
+	Fingerprint string         `json:"fingerprint"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// newResult заполняет запись результата
+func newResult(masterSeed []byte, seedCount int, p Params) resultRecord {
+	return resultRecord{
+		Kind:        "master-seed",
+		Params:      p,
+		SeedCount:   seedCount,
+		Master:      masterSeed,
+		Fingerprint: masterFingerprint(masterSeed),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// writeResult выводит результат в формате json, msv2 или rs (бумажная копия)
+func writeResult(w io.Writer, result resultRecord, format string) error {
+	if format == "rs" {
+		return writeRSBackup(w, result.Master)
+	}
+	if format == "msv2" {
+		line, err := encodeMSV2(result)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, line)
+		return err
+	}
+	return writeOutput(w, result)
+}
+
+// writeJSON выводит значение в виде JSON с отступами
+func writeJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeJSONBytes(w, append(data, '\n'))
+}
+
+// writeJSONBytes выводит готовый документ JSON. SHA-256 документа,
+// выведенного в stdout или файл, попадает в журнал аудита.
+func writeJSONBytes(w io.Writer, data []byte) error {
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if f, ok := w.(*os.File); ok {
+		sum := sha256.Sum256(data)
+		auditResult("json", f.Name(), hex.EncodeToString(sum[:]))
+	}
+	return nil
+}
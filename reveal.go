@@ -0,0 +1,231 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// revealFlags - общие флаги показа мастер-сида в терминале
+type revealFlags struct {
+	show       *bool
+	clearAfter *time.Duration
+	groupSize  *groupSize
+}
+
+// addRevealFlags регистрирует флаги показа мастер-сида в наборе
+func addRevealFlags(fs *flag.FlagSet) *revealFlags {
+	return &revealFlags{
+		show:       fs.Bool("show", false, "вывести мастер-сид сразу, без подтверждения и очистки экрана"),
+		clearAfter: fs.Duration("clear-after", 30*time.Second, "через сколько очистить экран после показа мастер-сида (0 - только по Enter)"),
+		groupSize:  addGroupSizeFlag(fs),
+	}
+}
+
+// grouped сообщает, что секрет выводится группами (--group-size)
+func (rf *revealFlags) grouped() bool {
+	return *rf.groupSize != 0
+}
+
+// checkGroups проверяет, что --group-size сочетается с форматом: группами
+// выводится только текст для переписывания, а не JSON или копия rs1
+func (rf *revealFlags) checkGroups(text bool) error {
+	if rf.grouped() && !text {
+		return errorf("флаг --group-size применим только к выводу мастер-сида текстом")
+	}
+	return nil
+}
+
+// isTerminal сообщает, что файл подключен к терминалу
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// openConfirmInput возвращает ввод для подтверждений: stdin, если это
+// терминал, иначе управляющий терминал процесса (сиды могли прийти из файла)
+func openConfirmInput() (io.Reader, func(), error) {
+	if isTerminal(os.Stdin) {
+		return os.Stdin, func() {}, nil
+	}
+	name := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		name = "CONIN$"
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { f.Close() }, nil
+}
+
+// readLine читает ввод до перевода строки побайтно, чтобы не забрать
+// из терминала лишнего
+func readLine(r io.Reader) error {
+	b := make([]byte, 1)
+	for {
+		if _, err := r.Read(b); err != nil {
+			return err
+		}
+		if b[0] == '\n' {
+			return nil
+		}
+	}
+}
+
+// reveal выводит мастер-сид под заголовком heading. В терминале он
+// показывается только после нажатия Enter, а затем экран и история
+// прокрутки очищаются по таймеру или по Enter, чтобы мастер-сид не остался
+// в scrollback терминала или tmux. Вне терминала выводится как есть.
+func (rf *revealFlags) reveal(heading string, secret []byte) error {
+	if rf.grouped() {
+		return rf.revealGrouped(heading, secret)
+	}
+	if !isTerminal(os.Stdout) {
+		fmt.Println(heading)
+		return writeSecretLine(os.Stdout, secret)
+	}
+	if *rf.show {
+		fmt.Println(emph(heading))
+		return writeEmphSecretLine(os.Stdout, secret)
+	}
+	in, closeInput, err := openConfirmInput()
+	if err != nil {
+		// Подтверждение получить неоткуда - остается вывести как раньше
+		fmt.Println(heading)
+		return writeCheckedSecretLine(os.Stdout, secret)
+	}
+	defer closeInput()
+
+	fmt.Print(emph(tr("Мастер-сид скрыт. Нажмите Enter, чтобы показать его (Ctrl-D - отмена)...")))
+	if err := readLine(in); err != nil {
+		fmt.Println()
+		return withCode(exitCancelled, errorf("показ мастер-сида отменен"))
+	}
+	fmt.Println(emph(heading))
+	if err := writeEmphSecretLine(os.Stdout, secret); err != nil {
+		return err
+	}
+	fmt.Println(tr("Два символа после дефиса - контрольные: при вводе они выявят ошибку переписывания"))
+	fmt.Println()
+	rf.clearLater(in)
+	return nil
+}
+
+// revealGrouped выводит мастер-сид в hex группами для переписывания от
+// руки. Подтверждение и очистка экрана - те же, что у reveal.
+func (rf *revealFlags) revealGrouped(heading string, secret []byte) error {
+	if !isTerminal(os.Stdout) || *rf.show {
+		fmt.Println(heading)
+		return writeGroupedHex(os.Stdout, secret, *rf.groupSize)
+	}
+	in, closeInput, err := openConfirmInput()
+	if err != nil {
+		fmt.Println(heading)
+		return writeGroupedHex(os.Stdout, secret, *rf.groupSize)
+	}
+	defer closeInput()
+
+	fmt.Print(emph(tr("Мастер-сид скрыт. Нажмите Enter, чтобы показать его (Ctrl-D - отмена)...")))
+	if err := readLine(in); err != nil {
+		fmt.Println()
+		return withCode(exitCancelled, errorf("показ мастер-сида отменен"))
+	}
+	fmt.Println(emph(heading))
+	if err := writeGroupedHex(os.Stdout, secret, *rf.groupSize); err != nil {
+		return err
+	}
+	fmt.Println(tr("Последний символ строки - контрольный: при вводе копии через --master он выявит ошибку в этой строке"))
+	fmt.Println()
+	rf.clearLater(in)
+	return nil
+}
+
+// revealSeed выводит сид устройства как есть, в том виде, в котором его
+// вводят, с тем же подтверждением и очисткой экрана, что и reveal
+func (rf *revealFlags) revealSeed(heading string, seed []byte) error {
+	line := newSecret(len(seed) + 1)
+	defer wipe(line)
+	line[copy(line, seed)] = '\n'
+	if !isTerminal(os.Stdout) {
+		// Без заголовка сид можно сразу передать в generate
+		_, err := os.Stdout.Write(line)
+		return err
+	}
+	if *rf.show {
+		fmt.Println(emph(heading))
+		_, err := os.Stdout.Write(line)
+		return err
+	}
+	in, closeInput, err := openConfirmInput()
+	if err != nil {
+		fmt.Println(heading)
+		_, err := os.Stdout.Write(line)
+		return err
+	}
+	defer closeInput()
+
+	fmt.Print(emph(tr("Сид скрыт. Нажмите Enter, чтобы показать его (Ctrl-D - отмена)...")))
+	if err := readLine(in); err != nil {
+		fmt.Println()
+		return withCode(exitCancelled, errorf("показ сида отменен"))
+	}
+	fmt.Println(emph(heading))
+	if _, err := os.Stdout.Write(line); err != nil {
+		return err
+	}
+	fmt.Println()
+	rf.clearLater(in)
+	return nil
+}
+
+// clearLater очищает экран и историю прокрутки после показа секрета
+func (rf *revealFlags) clearLater(in io.Reader) {
+	waitClear(in, *rf.clearAfter)
+	clearScreen()
+	fmt.Println(paint(colors.stdout, styleOK, tr("✓ Экран и история прокрутки очищены")))
+}
+
+// waitClear ждет нажатия Enter или истечения after, показывая обратный отсчет
+func waitClear(in io.Reader, after time.Duration) {
+	pressed := make(chan struct{})
+	go func() {
+		readLine(in)
+		close(pressed)
+	}()
+	if after <= 0 {
+		fmt.Print(tr("Нажмите Enter, чтобы очистить экран..."))
+		<-pressed
+		return
+	}
+
+	deadline := time.Now().Add(after)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		left := time.Until(deadline).Round(time.Second)
+		if left <= 0 {
+			return
+		}
+		fmt.Print(paint(colors.stdout, styleWarn, fmt.Sprintf(tr("\rЭкран будет очищен через %d с (Enter - сразу) "), int(left/time.Second))))
+		select {
+		case <-pressed:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// clearScreen очищает экран и историю прокрутки терминала
+func clearScreen() {
+	// \x1b[3J очищает историю прокрутки в xterm-совместимых терминалах
+	fmt.Print("\x1b[H\x1b[2J\x1b[3J")
+	if pane := os.Getenv("TMUX_PANE"); os.Getenv("TMUX") != "" && pane != "" {
+		// У tmux собственная история панели, escape-последовательность ее не трогает
+		exec.Command("tmux", "clear-history", "-t", pane).Run()
+	}
+}
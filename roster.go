@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rosterColumns - столбцы общего формата участников, который выгружает
+// система регистрации
+var rosterColumns = []string{"id", "name", "country", "ranking", "pot"}
+
+// rosterEntry - участник из выгрузки регистрации. Обязательны id и name,
+// остальные поля нужны только командам, которые их используют.
+type rosterEntry struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Country string   `json:"country,omitempty"`
+	Ranking *float64 `json:"ranking,omitempty"`
+	Pot     int      `json:"pot,omitempty"`
+}
+
+// loadRoster читает участников из CSV с заголовком из столбцов
+// id, name, country, ranking, pot или из JSON (массив объектов с теми же
+// полями либо объект с массивом participants). Возвращает участников
+// в порядке файла и SHA-256 канонической записи (строки
+// "id,name,country,ranking,pot" через \n), одинаковой для CSV и JSON.
+func loadRoster(path string) ([]rosterEntry, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	var entries []rosterEntry
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		entries, err = parseRosterJSON(trimmed)
+	} else {
+		entries, err = parseRosterCSV(data)
+	}
+	if err != nil {
+		return nil, "", errorf("%s: %w", path, err)
+	}
+	if len(entries) == 0 {
+		return nil, "", errorf("%s: нет ни одного участника", path)
+	}
+
+	ids := make(map[string]int)
+	names := make(map[string]int)
+	canonical := make([]string, 0, len(entries))
+	for i, e := range entries {
+		n := i + 1
+		if e.ID == "" {
+			return nil, "", errorf("%s: участник %d: не указан id", path, n)
+		}
+		if e.Name == "" {
+			return nil, "", errorf("%s: участник %d (id %s): не указано имя", path, n, e.ID)
+		}
+		if first, ok := ids[e.ID]; ok {
+			return nil, "", errorf("%s: участник %d: id %q уже у участника %d", path, n, e.ID, first)
+		}
+		if first, ok := names[e.Name]; ok {
+			return nil, "", errorf("%s: участник %d: имя %q уже у участника %d", path, n, e.Name, first)
+		}
+		if e.Pot < 0 {
+			return nil, "", errorf("%s: участник %d (id %s): номер корзины должен быть положительным", path, n, e.ID)
+		}
+		ids[e.ID], names[e.Name] = n, n
+		ranking, pot := "", ""
+		if e.Ranking != nil {
+			ranking = strconv.FormatFloat(*e.Ranking, 'g', -1, 64)
+		}
+		if e.Pot > 0 {
+			pot = strconv.Itoa(e.Pot)
+		}
+		canonical = append(canonical, strings.Join([]string{e.ID, e.Name, e.Country, ranking, pot}, ","))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(canonical, "\n")))
+	digest := hex.EncodeToString(sum[:])
+	auditInput("file", path, digest)
+	return entries, digest, nil
+}
+
+// parseRosterJSON разбирает JSON регистрации; ошибка указывает номер участника
+func parseRosterJSON(data []byte) ([]rosterEntry, error) {
+	var raw []json.RawMessage
+	if data[0] == '{' {
+		var wrapper struct {
+			Participants []json.RawMessage `json:"participants"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, errorf("не удалось разобрать JSON: %w", err)
+		}
+		if wrapper.Participants == nil {
+			return nil, errorf("в объекте JSON нет массива participants")
+		}
+		raw = wrapper.Participants
+	} else if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errorf("не удалось разобрать JSON: %w", err)
+	}
+
+	entries := make([]rosterEntry, 0, len(raw))
+	for i, r := range raw {
+		// id в выгрузке может быть числом, поэтому разбирается отдельно
+		var e struct {
+			rosterEntry
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.Unmarshal(r, &e); err != nil {
+			return nil, errorf("участник %d: %w", i+1, err)
+		}
+		entry := e.rosterEntry
+		id := strings.TrimSpace(string(e.ID))
+		if strings.HasPrefix(id, "\"") {
+			if err := json.Unmarshal(e.ID, &id); err != nil {
+				return nil, errorf("участник %d: некорректный id: %w", i+1, err)
+			}
+		} else if id != "" && id != "null" {
+			if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+				return nil, errorf("участник %d: id должен быть строкой или целым числом", i+1)
+			}
+		} else {
+			id = ""
+		}
+		entry.ID = strings.TrimSpace(id)
+		entry.Name = strings.TrimSpace(entry.Name)
+		entry.Country = strings.TrimSpace(entry.Country)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseRosterCSV разбирает CSV регистрации с заголовком; пустые строки
+// и строки с # пропускаются, ошибка указывает номер строки
+func parseRosterCSV(data []byte) ([]rosterEntry, error) {
+	var entries []rosterEntry
+	var columns []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		r := csv.NewReader(strings.NewReader(text))
+		r.TrimLeadingSpace = true
+		rec, err := r.Read()
+		if err != nil {
+			return nil, errorf("строка %d: %w", line, err)
+		}
+		if columns == nil {
+			columns, err = rosterHeader(rec)
+			if err != nil {
+				return nil, errorf("строка %d: %w", line, err)
+			}
+			continue
+		}
+		if len(rec) != len(columns) {
+			return nil, errorf("строка %d: полей %d, а в заголовке %d", line, len(rec), len(columns))
+		}
+		var e rosterEntry
+		for i, col := range columns {
+			value := strings.TrimSpace(rec[i])
+			switch col {
+			case "id":
+				e.ID = value
+			case "name":
+				e.Name = value
+			case "country":
+				e.Country = value
+			case "ranking":
+				if value == "" {
+					continue
+				}
+				ranking, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, errorf("строка %d: некорректный рейтинг %q", line, value)
+				}
+				e.Ranking = &ranking
+			case "pot":
+				if value == "" {
+					continue
+				}
+				if e.Pot, err = strconv.Atoi(value); err != nil || e.Pot < 1 {
+					return nil, errorf("строка %d: некорректный номер корзины %q", line, value)
+				}
+			}
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if columns == nil {
+		return nil, errorf("нет строки заголовка (%s)", strings.Join(rosterColumns, ","))
+	}
+	return entries, nil
+}
+
+// rosterHeader проверяет заголовок CSV: известные столбцы без повторов, id и name обязательны
+func rosterHeader(rec []string) ([]string, error) {
+	columns := make([]string, len(rec))
+	seen := make(map[string]bool)
+	for i, c := range rec {
+		c = strings.ToLower(strings.TrimSpace(c))
+		known := false
+		for _, k := range rosterColumns {
+			known = known || c == k
+		}
+		if !known {
+			return nil, errorf("неизвестный столбец %q, допустимы: %s", rec[i], strings.Join(rosterColumns, ", "))
+		}
+		if seen[c] {
+			return nil, errorf("столбец %q указан дважды", c)
+		}
+		seen[c] = true
+		columns[i] = c
+	}
+	if !seen["id"] || !seen["name"] {
+		return nil, errorf("в заголовке обязательны столбцы id и name")
+	}
+	return columns, nil
+}
+
+// rosterNames возвращает имена участников: по рейтингу (меньший выше),
+// если он указан у всех, иначе в порядке файла. При равном рейтинге
+// сохраняется порядок файла; жребий при равенстве дает seed-order.
+func rosterNames(entries []rosterEntry) []string {
+	ordered := append([]rosterEntry(nil), entries...)
+	ranked := true
+	for _, e := range ordered {
+		ranked = ranked && e.Ranking != nil
+	}
+	if ranked {
+		sort.SliceStable(ordered, func(i, j int) bool { return *ordered[i].Ranking < *ordered[j].Ranking })
+	}
+	names := make([]string, len(ordered))
+	for i, e := range ordered {
+		names[i] = e.Name
+	}
+	return names
+}
+
+// rosterPots раскладывает участников по корзинам; корзина нужна каждому
+func rosterPots(entries []rosterEntry) ([][]drawTeam, error) {
+	teams := make([]drawTeam, 0, len(entries))
+	for _, e := range entries {
+		if e.Pot == 0 {
+			return nil, errorf("у участника %s (id %s) не указана корзина (pot)", e.Name, e.ID)
+		}
+		teams = append(teams, drawTeam{Name: e.Name, Country: e.Country, Pot: e.Pot})
+	}
+	return groupPots(teams), nil
+}
+
+// rosterRankings возвращает участников с рейтингом; рейтинг нужен каждому
+func rosterRankings(entries []rosterEntry) ([]rankedTeam, error) {
+	teams := make([]rankedTeam, 0, len(entries))
+	for _, e := range entries {
+		if e.Ranking == nil {
+			return nil, errorf("у участника %s (id %s) не указан рейтинг (ranking)", e.Name, e.ID)
+		}
+		teams = append(teams, rankedTeam{Name: e.Name, Ranking: *e.Ranking})
+	}
+	return teams, nil
+}
+
+// addRosterFlag регистрирует флаг выгрузки участников, заменяющий флаг alternative
+func addRosterFlag(fs *flag.FlagSet, alternative string) *string {
+	return fs.String("roster", "", tr("выгрузка участников из регистрации (CSV или JSON: id, name, country, ranking, pot) вместо --")+alternative)
+}
+
+// oneSource проверяет, что указан ровно один источник участников
+func oneSource(path, rosterPath, alternative string) error {
+	if (path == "") == (rosterPath == "") {
+		return errorf("укажите участников через --%s или --roster, но не оба", alternative)
+	}
+	return nil
+}
+
+// readParticipants читает имена участников из списка или из выгрузки регистрации
+func readParticipants(path, rosterPath string) ([]string, string, error) {
+	if rosterPath == "" {
+		return readNameList(path)
+	}
+	entries, digest, err := loadRoster(rosterPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return rosterNames(entries), digest, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// rotationEntry - соответствие отпечатков одного пути при ротации
+type rotationEntry struct {
+	Path string `json:"path"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// rotationMap - карта миграции ключей между эпохами
+type rotationMap struct {
+	Kind      string          `json:"kind"`
+	FromEpoch uint32          `json:"from_epoch"`
+	ToEpoch   uint32          `json:"to_epoch"`
+	Master    rotationEntry   `json:"master"`
+	Paths     []rotationEntry `json:"paths"`
+}
+
+// runRotate выводит мастер-сид новой эпохи и карту замены ключей
+func runRotate(args []string) error {
+	fs := newFlagSet("rotate")
+	fromEpoch := fs.Uint("from-epoch", 0, "текущая эпоха")
+	toEpoch := fs.Uint("to-epoch", 0, "новая эпоха")
+	registry := fs.String("registry", "", "файл реестра путей вывода (по одному на строку)")
+	kf := addKDFFlags(fs)
+	format := fs.String("format", "text", "формат вывода: text или json")
+	rf := addRevealFlags(fs)
+	ef := addEnvFlags(fs)
+	doubleCheckFlag := fs.Bool("double-check", false, "пересчитать мастер-сид новой эпохи независимой реализацией и прервать работу при расхождении")
+	pf := addProfileFlags(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pf.apply(fs); err != nil {
+		return err
+	}
+
+	if *fromEpoch == 0 || *toEpoch == 0 {
+		return errorf("укажите --from-epoch и --to-epoch")
+	}
+	if *fromEpoch == *toEpoch {
+		return errorf("эпохи совпадают, ротация не требуется")
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	if err := rf.checkGroups(*format == "text"); err != nil {
+		return err
+	}
+
+	oldParams := V2Params(uint32(*fromEpoch))
+	if err := kf.apply(fs, &oldParams); err != nil {
+		return err
+	}
+	newParams := oldParams
+	newParams.Epoch = uint32(*toEpoch)
+	if err := newParams.Validate(); err != nil {
+		return err
+	}
+
+	var paths []string
+	if *registry != "" {
+		var err error
+		if paths, err = readRegistry(*registry); err != nil {
+			return errorf("ошибка чтения реестра: %w", err)
+		}
+	}
+
+	if err := ef.check(*format == "json"); err != nil {
+		return err
+	}
+
+	var prompts io.Writer = os.Stdout
+	if *format == "json" {
+		prompts = os.Stderr
+	}
+
+	fmt.Fprintf(prompts, tr("=== Ротация мастер-сида: эпоха %d → %d ===\n\n"), *fromEpoch, *toEpoch)
+	fmt.Fprintln(prompts, tr("Введите сиды от устройств (по одному на строку)."))
+	fmt.Fprintln(prompts, tr("Для завершения ввода оставьте строку пустой и нажмите Enter."))
+	fmt.Fprintln(prompts)
+
+	deviceSeeds, err := readDeviceSeeds(os.Stdin, prompts)
+	if err != nil {
+		return err
+	}
+	defer wipeSeeds(deviceSeeds)
+	if len(deviceSeeds) == 0 {
+		return errorf("не введено ни одного сида")
+	}
+
+	stopProgress := showKDFProgress()
+	oldMaster, err := GenerateMasterSeed(deviceSeeds, oldParams)
+	if err != nil {
+		stopProgress()
+		return err
+	}
+	defer wipe(oldMaster)
+	newMaster, err := GenerateMasterSeed(deviceSeeds, newParams)
+	stopProgress()
+	if err != nil {
+		return err
+	}
+	defer wipe(newMaster)
+	if *doubleCheckFlag {
+		if err := doubleCheck(deviceSeeds, newParams, newMaster); err != nil {
+			return err
+		}
+		fmt.Fprintln(prompts, tr("✓ Мастер-сид подтвержден независимым повторным вычислением"))
+	}
+
+	result := rotationMap{
+		Kind:      "rotation-map",
+		FromEpoch: oldParams.Epoch,
+		ToEpoch:   newParams.Epoch,
+		Master:    rotationEntry{Path: "master", Old: masterFingerprint(oldMaster), New: masterFingerprint(newMaster)},
+	}
+	auditResult("master", "", result.Master.New)
+	noteProvenanceParams(newParams)
+	for _, path := range paths {
+		result.Paths = append(result.Paths, rotationEntry{
+			Path: path,
+			Old:  pathFingerprint(oldMaster, path),
+			New:  pathFingerprint(newMaster, path),
+		})
+	}
+
+	// Карта миграции публикуется для сервисов, новый мастер-сид - нет
+	if *format == "json" {
+		return writeOutput(os.Stdout, result)
+	}
+
+	fmt.Println()
+	if err := rf.reveal(fmt.Sprintf(tr("Мастер-сид эпохи %d:"), newParams.Epoch), newMaster); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, tr("Путь\tЭпоха %d\tЭпоха %d\n"), oldParams.Epoch, newParams.Epoch)
+	fmt.Fprintf(tw, "%s\t%s\t%s\n", tr("(мастер-сид)"), result.Master.Old, result.Master.New)
+	for _, e := range result.Paths {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Path, e.Old, e.New)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println(tr("✓ Сервисы, использующие перечисленные пути, должны заменить ключи"))
+	fmt.Println(tr("  с отпечатками старой эпохи на ключи с отпечатками новой."))
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// savedSeedsKind - тип файла сохраненного ввода сидов
+const savedSeedsKind = "seedgen-saved-seeds"
+
+// savedSeedsMinPassphrase - минимальная длина пароля сохраненного ввода
+const savedSeedsMinPassphrase = 12
+
+// savedSeeds - введенные сиды прерванной церемонии, зашифрованные AES-256-GCM
+// на ключе Argon2id от пароля оператора. Заголовок не секретен и входит
+// в AAD, поэтому подменить команду или число сидов незаметно нельзя.
+type savedSeeds struct {
+	Kind    string       `json:"kind"`
+	Command string       `json:"command"`
+	SavedAt string       `json:"saved_at"`
+	Seeds   int          `json:"seeds"`
+	KDF     savedSeedKDF `json:"kdf"`
+	Nonce   string       `json:"nonce"`
+	Sealed  string       `json:"sealed"`
+}
+
+// savedSeedKDF - параметры Argon2id ключа сохраненного ввода
+type savedSeedKDF struct {
+	Salt    string `json:"salt"`
+	Passes  uint32 `json:"passes"`
+	Memory  uint32 `json:"memory_kib"`
+	Threads uint8  `json:"threads"`
+}
+
+// aad возвращает связанные данные шифрования: заголовок без шифротекста
+func (s *savedSeeds) aad() []byte {
+	return []byte(savedSeedsKind + "\x00" + s.Command + "\x00" + s.SavedAt + "\x00" + strconv.Itoa(s.Seeds) + "\x00" + s.KDF.Salt)
+}
+
+// cipher выводит ключ из пароля и возвращает AEAD. Параметры Argon2id в
+// файле не проверены тегом AEAD до вывода ключа, поэтому принимаются только
+// те, с которыми файл записывает saveSeeds: иначе поврежденный файл мог бы
+// потребовать терабайты памяти.
+func (s *savedSeeds) cipher(passphrase []byte) (cipher.AEAD, error) {
+	salt, err := hex.DecodeString(s.KDF.Salt)
+	if err != nil || len(salt) < 16 {
+		return nil, errorf("некорректные параметры KDF сохраненного ввода")
+	}
+	if s.KDF.Passes != operatorArgonPasses || s.KDF.Memory != operatorArgonMemory || s.KDF.Threads != operatorArgonThreads {
+		return nil, errorf("параметры KDF сохраненного ввода (проходов: %d, память: %d КиБ, потоков: %d) отличаются от записываемых этой версией",
+			s.KDF.Passes, s.KDF.Memory, s.KDF.Threads)
+	}
+	key := argon2IDKey(passphrase, salt, operatorArgonPasses, operatorArgonMemory, operatorArgonThreads)
+	defer wipe(key)
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// saveSeeds шифрует введенные сиды паролем, который оператор вводит дважды,
+// и сохраняет их в каталог сеанса. Возвращает путь к файлу.
+func saveSeeds(in io.Reader, w io.Writer, command string, seeds [][]byte) (string, error) {
+	fmt.Fprintln(w, tr("Сиды будут зашифрованы паролем. Без него продолжить ввод будет нельзя."))
+	passphrase, err := readHidden(in, w, tr("Пароль: "))
+	if err != nil {
+		return "", errorf("ввод пароля прерван")
+	}
+	defer wipe(passphrase)
+	if len(passphrase) < savedSeedsMinPassphrase {
+		return "", errorf("пароль короче %d символов", savedSeedsMinPassphrase)
+	}
+	repeat, err := readHidden(in, w, tr("Повторите пароль: "))
+	if err != nil {
+		return "", errorf("ввод пароля прерван")
+	}
+	defer wipe(repeat)
+	if subtle.ConstantTimeCompare(passphrase, repeat) != 1 {
+		return "", errorf("пароли не совпадают")
+	}
+
+	salt := make([]byte, 16)
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(salt); err != nil {
+		return "", withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return "", withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	now := time.Now().UTC()
+	s := &savedSeeds{
+		Kind:    savedSeedsKind,
+		Command: command,
+		SavedAt: now.Format(time.RFC3339),
+		Seeds:   len(seeds),
+		KDF: savedSeedKDF{
+			Salt:    hex.EncodeToString(salt),
+			Passes:  operatorArgonPasses,
+			Memory:  operatorArgonMemory,
+			Threads: operatorArgonThreads,
+		},
+		Nonce: hex.EncodeToString(nonce),
+	}
+	aead, err := s.cipher(passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	// Каждый сид записывается с длиной в 4 байта
+	size := 0
+	for _, seed := range seeds {
+		size += 4 + len(seed)
+	}
+	plain := newSecret(size)
+	defer wipe(plain)
+	off := 0
+	for _, seed := range seeds {
+		binary.BigEndian.PutUint32(plain[off:], uint32(len(seed)))
+		off += 4 + copy(plain[off+4:], seed)
+	}
+	s.Sealed = hex.EncodeToString(aead.Seal(nil, nonce, plain, s.aad()))
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("seeds-%s-%s.json", command, now.Format("20060102T150405Z"))
+	if err := writeSessionFile(name, append(data, '\n')); err != nil {
+		return "", errorf("не удалось сохранить ввод: %w", err)
+	}
+	dir, err := sessionDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// loadSavedSeeds читает сохраненный ввод, запрашивает пароль и
+// расшифровывает сиды
+func loadSavedSeeds(path string, in io.Reader, w io.Writer, command string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s savedSeeds
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, errorf("не удалось разобрать %s: %w", path, err)
+	}
+	if s.Kind != savedSeedsKind {
+		return nil, errorf("%s не является сохраненным вводом сидов", path)
+	}
+	if s.Command != command {
+		logWarnf("⚠ Ввод сохранен командой %s, а продолжается командой %s\n", s.Command, command)
+	}
+	nonce, err := hex.DecodeString(s.Nonce)
+	if err != nil || len(nonce) != 12 {
+		return nil, errorf("некорректный нонс сохраненного ввода")
+	}
+	sealed, err := hex.DecodeString(s.Sealed)
+	if err != nil {
+		return nil, errorf("некорректный шифротекст сохраненного ввода")
+	}
+
+	fmt.Fprintf(w, tr("Сохраненный ввод %s: сидов %d, сохранен %s\n"), path, s.Seeds, s.SavedAt)
+	passphrase, err := readHidden(in, w, tr("Пароль: "))
+	if err != nil {
+		return nil, errorf("ввод пароля прерван")
+	}
+	defer wipe(passphrase)
+	aead, err := s.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(newSecret(len(sealed))[:0], nonce, sealed, s.aad())
+	if err != nil {
+		return nil, mismatchf("сохраненный ввод не расшифровывается: неверный пароль или файл поврежден")
+	}
+	defer wipe(plain)
+
+	var seeds [][]byte
+	for off := 0; off < len(plain); {
+		if len(plain)-off < 4 {
+			wipeSeeds(seeds)
+			return nil, errorf("сохраненный ввод поврежден")
+		}
+		n := int(binary.BigEndian.Uint32(plain[off:]))
+		off += 4
+		if n > len(plain)-off {
+			wipeSeeds(seeds)
+			return nil, errorf("сохраненный ввод поврежден")
+		}
+		seed := newSecret(n)
+		off += copy(seed, plain[off:off+n])
+		seeds = append(seeds, seed)
+	}
+	if len(seeds) != s.Seeds {
+		wipeSeeds(seeds)
+		return nil, errorf("сохраненный ввод поврежден")
+	}
+	return seeds, nil
+}
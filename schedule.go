@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// fixture - матч календаря
+type fixture struct {
+	ID   string `json:"id"`
+	Home string `json:"home"`
+	Away string `json:"away"`
+	matchRef
+}
+
+// scheduleRound - тур календаря
+type scheduleRound struct {
+	Round   int       `json:"round"`
+	Matches []fixture `json:"matches"`
+	Rest    string    `json:"rest,omitempty"`
+}
+
+// roundRobin - календарь кругового турнира
+type roundRobin struct {
+	Kind         string          `json:"kind"`
+	Label        string          `json:"label"`
+	Participants string          `json:"participants_sha256"`
+	Double       bool            `json:"double"`
+	Order        []string        `json:"order"`
+	Mirrored     bool            `json:"mirrored"`
+	Fingerprint  string          `json:"master_fingerprint"`
+	Rounds       []scheduleRound `json:"rounds"`
+}
+
+// circleRounds строит туры круговым методом: первая команда стоит на месте,
+// остальные сдвигаются по кругу на одну позицию за тур. В туре позиция i
+// играет с позицией n-1-i; верхняя половина принимает, а первая команда
+// принимает в четных турах. Так у каждой команды число домашних матчей
+// отличается от гостевых не больше чем на один. При нечетном числе
+// команд добавляется пустое место: соперник пустого места отдыхает.
+func circleRounds(order []string) []scheduleRound {
+	slots := append([]string(nil), order...)
+	if len(slots)%2 == 1 {
+		slots = append(slots, "")
+	}
+	n := len(slots)
+	var rounds []scheduleRound
+	for r := 0; r < n-1; r++ {
+		round := scheduleRound{Round: r + 1}
+		for i := 0; i < n/2; i++ {
+			home, away := slots[i], slots[n-1-i]
+			if i == 0 && r%2 == 1 {
+				home, away = away, home
+			}
+			switch {
+			case home == "":
+				round.Rest = away
+			case away == "":
+				round.Rest = home
+			default:
+				round.Matches = append(round.Matches, fixture{Home: home, Away: away})
+			}
+		}
+		rounds = append(rounds, round)
+		// Сдвиг по кругу всех позиций, кроме первой
+		last := slots[n-1]
+		copy(slots[2:], slots[1:n-1])
+		slots[1] = last
+	}
+	return rounds
+}
+
+// runSchedule составляет календарь из опубликованного мастер-сида
+func runSchedule(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, tr("Использование:"))
+		fmt.Fprintln(os.Stderr, "  seedgen schedule roundrobin --master result.json --participants teams.txt [--double] [--format csv]")
+		fmt.Fprintln(os.Stderr, "  seedgen schedule export --schedule schedule.json --start 2025-09-06 [--slots \"15:00,+1 17:30\"] [--timezone Europe/Moscow] [--venues venues.csv]")
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return errorf("укажите вид календаря: roundrobin, или export для выгрузки")
+	}
+
+	switch args[0] {
+	case "roundrobin":
+		return runScheduleRoundRobin(args[1:])
+	case "export":
+		return runScheduleExport(args[1:])
+	default:
+		return errorf("неизвестный вид календаря %q, доступны: roundrobin, export", args[0])
+	}
+}
+
+// runScheduleRoundRobin составляет календарь кругового турнира в один или два круга
+func runScheduleRoundRobin(args []string) error {
+	fs := newFlagSet("schedule roundrobin")
+	participantsPath := fs.String("participants", "", "файл участников по одному на строку")
+	double := fs.Bool("double", false, "два круга: во втором хозяева и гости меняются местами")
+	label := fs.String("label", "roundrobin", "метка календаря: разные метки дают независимые календари из одного мастер-сида")
+	format := fs.String("format", "text", "формат вывода: text, csv или json")
+	rosterPath := addRosterFlag(fs, "participants")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := oneSource(*participantsPath, *rosterPath, "participants"); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "csv" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	names, digest, err := readParticipants(*participantsPath, *rosterPath)
+	if err != nil {
+		return err
+	}
+	if len(names) < 2 || len(names) > 256 {
+		return errorf("в круговом турнире должно быть от 2 до 256 участников, указано %d", len(names))
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	s := newRoundRobin(master, *label, names, digest, *double)
+	rounds := s.Rounds
+	switch *format {
+	case "json":
+		return writeOutput(os.Stdout, s)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"round", "home", "away", "id", "short_id", "uuid"})
+		for _, round := range rounds {
+			for _, m := range round.Matches {
+				w.Write([]string{strconv.Itoa(round.Round), m.Home, m.Away, m.ID, m.Short, m.UUID})
+			}
+			if round.Rest != "" {
+				w.Write([]string{strconv.Itoa(round.Round), round.Rest, "", "", "", ""})
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	circles := tr("один круг")
+	if s.Double {
+		circles = tr("два круга")
+	}
+	fmt.Printf(tr("Календарь %q: участников %d, %s, туров %d\n"), s.Label, len(names), circles, len(rounds))
+	fmt.Printf(tr("Мастер-сид: %s..., список участников: SHA-256 %s\n"), emph(s.Fingerprint), s.Participants)
+	for _, round := range rounds {
+		fmt.Println()
+		fmt.Printf(tr("Тур %d:\n"), round.Round)
+		for _, m := range round.Matches {
+			fmt.Printf("  %s - %s\n", m.Home, m.Away)
+		}
+		if round.Rest != "" {
+			fmt.Printf(tr("  отдыхает: %s\n"), round.Rest)
+		}
+	}
+	return nil
+}
+
+// newRoundRobin составляет календарь кругового турнира из мастер-сида
+func newRoundRobin(master []byte, label string, names []string, digest string, double bool) roundRobin {
+	// Из потока берутся сначала перестановка участников по местам круга,
+	// затем одно число: нечетное меняет хозяев и гостей во всех матчах
+	stream := newDrawStream(master, "schedule-roundrobin", label)
+	order := append([]string(nil), names...)
+	stream.shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	mirrored := stream.intn(2) == 1
+
+	rounds := circleRounds(order)
+	if mirrored {
+		for _, round := range rounds {
+			for i, m := range round.Matches {
+				round.Matches[i] = fixture{Home: m.Away, Away: m.Home}
+			}
+		}
+	}
+	if double {
+		first := len(rounds)
+		for _, round := range rounds[:first] {
+			second := scheduleRound{Round: round.Round + first, Rest: round.Rest}
+			for _, m := range round.Matches {
+				second.Matches = append(second.Matches, fixture{Home: m.Away, Away: m.Home})
+			}
+			rounds = append(rounds, second)
+		}
+	}
+	ns := uuidNamespace(master)
+	for _, round := range rounds {
+		for i := range round.Matches {
+			m := &round.Matches[i]
+			m.ID = fmt.Sprintf("T%d-M%d", round.Round, i+1)
+			m.matchRef = newMatchRef(ns, matchName(label, m.ID))
+		}
+	}
+	return roundRobin{
+		Kind:         "round-robin",
+		Label:        label,
+		Participants: digest,
+		Double:       double,
+		Order:        order,
+		Mirrored:     mirrored,
+		Fingerprint:  masterFingerprint(master),
+		Rounds:       rounds,
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// kickoffSlot - слот начала матча: сдвиг в днях от даты тура и время
+type kickoffSlot struct {
+	days         int
+	hour, minute int
+}
+
+// parseKickoffSlots разбирает шаблон слотов: через запятую ЧЧ:ММ или +N ЧЧ:ММ
+// (через N дней после даты тура)
+func parseKickoffSlots(s string) ([]kickoffSlot, error) {
+	var slots []kickoffSlot
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		var slot kickoffSlot
+		if strings.HasPrefix(part, "+") {
+			fields := strings.Fields(part)
+			if len(fields) != 2 {
+				return nil, errorf("некорректный слот %q, ожидается ЧЧ:ММ или +N ЧЧ:ММ", part)
+			}
+			days, err := strconv.Atoi(fields[0][1:])
+			if err != nil || days < 0 || days > 30 {
+				return nil, errorf("некорректный сдвиг в днях в слоте %q", part)
+			}
+			slot.days, part = days, fields[1]
+		}
+		t, err := time.Parse("15:04", part)
+		if err != nil {
+			return nil, errorf("некорректное время %q, ожидается ЧЧ:ММ", part)
+		}
+		slot.hour, slot.minute = t.Hour(), t.Minute()
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
+
+// exportMatch - матч календаря с датой, временем и площадкой
+type exportMatch struct {
+	Round   int
+	Kickoff time.Time
+	Venue   string
+	fixture
+}
+
+// readVenues читает домашние площадки: CSV с заголовком team,venue
+func readVenues(path string) (map[string]string, error) {
+	rows, lines, _, err := readHeaderCSV(path, []string{"team", "venue"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	venues := make(map[string]string)
+	for i, row := range rows {
+		if row["team"] == "" || row["venue"] == "" {
+			return nil, errorf("%s:%d: обязательны team и venue", path, lines[i])
+		}
+		if _, ok := venues[row["team"]]; ok {
+			return nil, errorf("%s:%d: площадка команды %q уже указана", path, lines[i], row["team"])
+		}
+		venues[row["team"]] = row["venue"]
+	}
+	return venues, nil
+}
+
+// fileSlug делает из имени команды или площадки имя файла: буквы и цифры
+// остаются, остальное заменяется дефисами
+func fileSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// icsEscape экранирует текстовое значение iCalendar (RFC 5545, 3.3.11)
+func icsEscape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", ";", "\\;", ",", "\\,", "\n", "\\n").Replace(s)
+}
+
+// icsLine записывает строку iCalendar, перенося ее по 75 байт без разрыва
+// символов UTF-8; строки завершаются CRLF
+func icsLine(b *bytes.Buffer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Строка продолжения начинается с пробела, он входит в 75 байт
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// buildICS составляет календарь iCalendar из матчей. Время записывается
+// в UTC, поэтому описание часового пояса не нужно; UID - UUID матча,
+// так что при повторной выгрузке приложения обновляют те же события.
+func buildICS(name, tz string, matches []exportMatch, duration time.Duration, stamp time.Time) []byte {
+	const utc = "20060102T150405Z"
+	var b bytes.Buffer
+	icsLine(&b, "BEGIN:VCALENDAR")
+	icsLine(&b, "VERSION:2.0")
+	icsLine(&b, "PRODID:-//seedgen//schedule export//RU")
+	icsLine(&b, "CALSCALE:GREGORIAN")
+	icsLine(&b, "METHOD:PUBLISH")
+	icsLine(&b, "X-WR-CALNAME:"+icsEscape(name))
+	icsLine(&b, "X-WR-TIMEZONE:"+tz)
+	for _, m := range matches {
+		icsLine(&b, "BEGIN:VEVENT")
+		icsLine(&b, "UID:"+m.UUID+"@seedgen")
+		icsLine(&b, "DTSTAMP:"+stamp.UTC().Format(utc))
+		icsLine(&b, "DTSTART:"+m.Kickoff.UTC().Format(utc))
+		icsLine(&b, "DTEND:"+m.Kickoff.Add(duration).UTC().Format(utc))
+		icsLine(&b, "SUMMARY:"+icsEscape(m.Home+" - "+m.Away))
+		if m.Venue != "" {
+			icsLine(&b, "LOCATION:"+icsEscape(m.Venue))
+		}
+		icsLine(&b, "DESCRIPTION:"+icsEscape(fmt.Sprintf(tr("Тур %d, матч %s (%s)"), m.Round, m.ID, m.Short)))
+		icsLine(&b, "END:VEVENT")
+	}
+	icsLine(&b, "END:VCALENDAR")
+	return b.Bytes()
+}
+
+// runScheduleExport выгружает календарь в iCalendar по командам и площадкам
+// и в плоский CSV
+func runScheduleExport(args []string) error {
+	fs := newFlagSet("schedule export")
+	schedulePath := fs.String("schedule", "", "календарь: вывод seedgen schedule roundrobin --format json")
+	start := fs.String("start", "", "дата первого тура ГГГГ-ММ-ДД")
+	days := fs.Int("days", 7, "дней между турами")
+	slotsFlag := fs.String("slots", "15:00", "шаблон начала матчей тура через запятую: ЧЧ:ММ или +N ЧЧ:ММ (через N дней после даты тура); матчи занимают слоты по порядку, по кругу")
+	tzName := fs.String("timezone", "UTC", "часовой пояс слотов и CSV, например Europe/Moscow")
+	duration := fs.Duration("duration", 2*time.Hour, "продолжительность матча в календаре")
+	venuesPath := fs.String("venues", "", "CSV домашних площадок team,venue: матч проходит на площадке хозяев")
+	out := fs.String("out", "schedule-export", "каталог выгрузки")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *schedulePath == "" {
+		return errorf("укажите календарь через --schedule")
+	}
+	first, err := time.Parse("2006-01-02", *start)
+	if err != nil {
+		return errorf("укажите дату первого тура через --start в формате ГГГГ-ММ-ДД")
+	}
+	if *days < 1 {
+		return errorf("--days должно быть не меньше 1")
+	}
+	if *duration <= 0 {
+		return errorf("--duration должно быть положительным")
+	}
+	slots, err := parseKickoffSlots(*slotsFlag)
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(*tzName)
+	if err != nil {
+		return errorf("неизвестный часовой пояс %q: %w", *tzName, err)
+	}
+	data, err := os.ReadFile(*schedulePath)
+	if err != nil {
+		return err
+	}
+	var s roundRobin
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errorf("не удалось разобрать %s: %w", *schedulePath, err)
+	}
+	if s.Kind != "round-robin" {
+		return errorf("%s не является календарем seedgen schedule roundrobin (kind %q)", *schedulePath, s.Kind)
+	}
+	var venues map[string]string
+	if *venuesPath != "" {
+		if venues, err = readVenues(*venuesPath); err != nil {
+			return err
+		}
+		for _, team := range s.Order {
+			if venues[team] == "" {
+				return errorf("в %s нет площадки команды %q", *venuesPath, team)
+			}
+		}
+	}
+
+	var matches []exportMatch
+	for r, round := range s.Rounds {
+		date := first.AddDate(0, 0, r**days)
+		for i, m := range round.Matches {
+			if m.UUID == "" {
+				return errorf("у матча %s нет uuid: пересоздайте календарь текущей версией seedgen", m.ID)
+			}
+			slot := slots[i%len(slots)]
+			kickoff := time.Date(date.Year(), date.Month(), date.Day()+slot.days, slot.hour, slot.minute, 0, 0, loc)
+			matches = append(matches, exportMatch{Round: round.Round, Kickoff: kickoff, Venue: venues[m.Home], fixture: m})
+		}
+	}
+
+	// Календари команд и площадок; одинаковые имена файлов - ошибка
+	files := map[string][]byte{}
+	names := map[string]string{}
+	stamp := time.Now()
+	add := func(prefix, name string, list []exportMatch) error {
+		file := prefix + "-" + fileSlug(name) + ".ics"
+		if other, ok := names[file]; ok {
+			return errorf("%q и %q дают одно имя файла %s", other, name, file)
+		}
+		names[file] = name
+		files[file] = buildICS(s.Label+": "+name, *tzName, list, *duration, stamp)
+		return nil
+	}
+	files["all.ics"] = buildICS(s.Label, *tzName, matches, *duration, stamp)
+	for _, team := range s.Order {
+		var list []exportMatch
+		for _, m := range matches {
+			if m.Home == team || m.Away == team {
+				list = append(list, m)
+			}
+		}
+		if err := add("team", team, list); err != nil {
+			return err
+		}
+	}
+	if venues != nil {
+		byVenue := map[string][]exportMatch{}
+		for _, m := range matches {
+			byVenue[m.Venue] = append(byVenue[m.Venue], m)
+		}
+		var list []string
+		for v := range byVenue {
+			list = append(list, v)
+		}
+		sort.Strings(list)
+		for _, v := range list {
+			if err := add("venue", v, byVenue[v]); err != nil {
+				return err
+			}
+		}
+	}
+
+	// CSV подходит для seedgen assign --matches: лишние столбцы он пропускает
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"id", "date", "kickoff", "timezone", "round", "home", "away", "venue", "short_id", "uuid"})
+	for _, m := range matches {
+		w.Write([]string{m.ID, m.Kickoff.Format("2006-01-02"), m.Kickoff.Format(time.RFC3339), *tzName, strconv.Itoa(m.Round), m.Home, m.Away, m.Venue, m.Short, m.UUID})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	files["fixtures.csv"] = buf.Bytes()
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		return err
+	}
+	var written []string
+	for name := range files {
+		written = append(written, name)
+	}
+	sort.Strings(written)
+	for _, name := range written {
+		if err := os.WriteFile(filepath.Join(*out, name), files[name], 0644); err != nil {
+			return err
+		}
+	}
+	fmt.Printf(tr("✓ Календарь %q выгружен в %s: матчей %d, туров %d, часовой пояс %s\n"), s.Label, *out, len(matches), len(s.Rounds), *tzName)
+	fmt.Printf(tr("  fixtures.csv, all.ics, календарей команд %d"), len(s.Order))
+	if venues != nil {
+		fmt.Printf(tr(", площадок %d"), len(written)-2-len(s.Order))
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Параметры схемы v2 по умолчанию
+const (
+	v2DefaultSalt = "master-seed-salt-v2"
+	v2Domain      = "seedgen/v2"
+)
+
+// Поддерживаемые KDF
+const (
+	kdfPBKDF2   = "pbkdf2-hmac-sha512"
+	kdfArgon2id = "argon2id"
+)
+
+// Параметры Argon2id по умолчанию: 3 прохода, 64 МиБ, 4 потока
+const (
+	argon2DefaultTime        = 3
+	argon2DefaultMemory      = 64 * 1024
+	argon2DefaultParallelism = 4
+)
+
+// Params описывает схему и параметры вывода мастер-сида
+type Params struct {
+	Scheme     string `json:"scheme"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+	Epoch      uint32 `json:"epoch,omitempty"`
+	// Memory (КиБ) и Parallelism задаются только для Argon2id
+	Memory      uint32 `json:"memory,omitempty"`
+	Parallelism uint8  `json:"parallelism,omitempty"`
+}
+
+// DefaultParams возвращает фиксированные параметры схемы v1
+func DefaultParams() Params {
+	return Params{
+		Scheme:     "v1",
+		KDF:        kdfPBKDF2,
+		Iterations: v1Iterations,
+		Salt:       v1Salt,
+	}
+}
+
+// V2Params возвращает параметры схемы v2 по умолчанию для эпохи
+func V2Params(epoch uint32) Params {
+	return Params{
+		Scheme:     "v2",
+		KDF:        kdfPBKDF2,
+		Iterations: 100000,
+		Salt:       v2DefaultSalt,
+		Epoch:      epoch,
+	}
+}
+
+// V3Params возвращает параметры схемы v3 по умолчанию для эпохи: те же,
+// что у v2, но KDF растягивает каждый сид отдельно
+func V3Params(epoch uint32) Params {
+	p := V2Params(epoch)
+	p.Scheme = "v3"
+	return p
+}
+
+// Validate проверяет согласованность параметров
+func (p Params) Validate() error {
+	switch p.Scheme {
+	case "v1":
+		// v1 не настраивается: любые отличия дали бы несовместимый результат
+		if p != DefaultParams() {
+			return errorf("схема v1 не поддерживает изменение параметров")
+		}
+		return nil
+	case "v2", "v3":
+	default:
+		return errorf("неизвестная схема %q", p.Scheme)
+	}
+
+	switch p.KDF {
+	case kdfPBKDF2:
+		if p.Iterations < 10000 {
+			return errorf("число итераций PBKDF2 меньше 10000 не обеспечивает защиты от перебора")
+		}
+		if p.Memory != 0 || p.Parallelism != 0 {
+			return errorf("память и число потоков задаются только для argon2id")
+		}
+	case kdfArgon2id:
+		if p.Iterations < 1 || int64(p.Iterations) > math.MaxUint32 {
+			return errorf("число проходов Argon2id должно быть от 1 до %d", int64(math.MaxUint32))
+		}
+		if p.Parallelism < 1 {
+			return errorf("число потоков Argon2id должно быть от 1 до 255")
+		}
+		if p.Memory < 8*1024 {
+			return errorf("память Argon2id меньше 8192 КиБ не обеспечивает защиты от перебора")
+		}
+	default:
+		return errorf("неизвестный KDF %q", p.KDF)
+	}
+	if p.Salt == "" {
+		return errorf("соль не может быть пустой")
+	}
+	if p.Epoch < 1 {
+		return errorf("эпоха нумеруется с 1")
+	}
+	return nil
+}
+
+// GenerateMasterSeed создает мастер-сид (64 байта) по выбранной схеме
+func GenerateMasterSeed(deviceSeeds [][]byte, p Params) ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	logDebug("вывод мастер-сида", "scheme", p.Scheme, "kdf", p.KDF, "iterations", p.Iterations, "epoch", p.Epoch, "devices", len(deviceSeeds))
+	if p.Scheme == "v1" {
+		return GenerateMasterSeedDeterministic(deviceSeeds)
+	}
+	if p.Scheme == "v3" {
+		sum, err := aggregateContributions(deviceSeeds, p)
+		if err != nil {
+			return nil, err
+		}
+		defer wipe(sum)
+		return finalMasterHash(sum), nil
+	}
+
+	combined, err := canonicalSeedSet(deviceSeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(combined)
+
+	// Эпоха входит в соль, поэтому каждая ротация дает независимый мастер-сид
+	salt := []byte(fmt.Sprintf("%s/epoch/%d", p.Salt, p.Epoch))
+
+	var derivedKey []byte
+	start := time.Now()
+	if p.KDF == kdfArgon2id {
+		derivedKey = argon2IDKey(combined, salt, uint32(p.Iterations), p.Memory, p.Parallelism)
+	} else {
+		derivedKey = pbkdf2SHA512(combined, salt, p.Iterations)
+	}
+	logDebug("KDF завершен", "kdf", p.KDF, "duration", time.Since(start))
+	lockSecret(derivedKey)
+	defer wipe(derivedKey)
+	return finalMasterHash(derivedKey), nil
+}
+
+// canonicalSeedSet кодирует набор сидов однозначно: в отличие от простой
+// конкатенации v1, где {"ab", "c"} и {"a", "bc"} неразличимы, каждый сид
+// предваряется своей длиной. Повторяющиеся сиды считаются ошибкой ввода.
+func canonicalSeedSet(deviceSeeds [][]byte) ([]byte, error) {
+	if len(deviceSeeds) == 0 {
+		return nil, errorf("необходим хотя бы один сид устройства")
+	}
+
+	sortedSeeds := sortedSeedSet(deviceSeeds)
+
+	size := len(v2Domain) + 4
+	for i, seed := range sortedSeeds {
+		if i > 0 && secretEqual(seed, sortedSeeds[i-1]) {
+			return nil, errorf("один и тот же сид введен несколько раз")
+		}
+		size += 4 + len(seed)
+	}
+
+	var length [4]byte
+	buf := newSecret(size)[:0]
+	buf = append(buf, v2Domain...)
+	binary.BigEndian.PutUint32(length[:], uint32(len(sortedSeeds)))
+	buf = append(buf, length[:]...)
+	for _, seed := range sortedSeeds {
+		binary.BigEndian.PutUint32(length[:], uint32(len(seed)))
+		buf = append(buf, length[:]...)
+		buf = append(buf, seed...)
+	}
+	return buf, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math/big"
+)
+
+// Параметры кривой secp256k1 (SEC 2): y² = x³ + 7 над полем p
+var (
+	secpP, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	secpN, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	secpGx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	secpGy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+)
+
+// secpPoint - точка кривой в аффинных координатах; x == nil - бесконечность.
+//
+// Арифметика math/big не выполняется за постоянное время. Для офлайн-машины
+// церемонии, где нет стороннего наблюдателя за таймингами, это допустимо,
+// но код не должен использоваться для подписи на сетевых серверах.
+type secpPoint struct {
+	x, y *big.Int
+}
+
+// secpAdd складывает две точки кривой
+func secpAdd(a, b secpPoint) secpPoint {
+	if a.x == nil {
+		return b
+	}
+	if b.x == nil {
+		return a
+	}
+
+	var lambda *big.Int
+	if a.x.Cmp(b.x) == 0 {
+		sum := new(big.Int).Add(a.y, b.y)
+		if sum.Mod(sum, secpP).Sign() == 0 {
+			return secpPoint{}
+		}
+		// Удвоение: λ = 3x² / 2y
+		num := new(big.Int).Mul(a.x, a.x)
+		num.Mul(num, big.NewInt(3))
+		den := new(big.Int).Lsh(a.y, 1)
+		lambda = num.Mul(num, den.ModInverse(den, secpP))
+	} else {
+		// Сложение: λ = (y2 - y1) / (x2 - x1)
+		num := new(big.Int).Sub(b.y, a.y)
+		den := new(big.Int).Sub(b.x, a.x)
+		den.Mod(den, secpP)
+		lambda = num.Mul(num, den.ModInverse(den, secpP))
+	}
+	lambda.Mod(lambda, secpP)
+
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, a.x).Sub(x, b.x).Mod(x, secpP)
+	y := new(big.Int).Sub(a.x, x)
+	y.Mul(y, lambda).Sub(y, a.y).Mod(y, secpP)
+	return secpPoint{x: x, y: y}
+}
+
+// secpScalarBaseMult вычисляет k·G
+func secpScalarBaseMult(k []byte) secpPoint {
+	var r secpPoint
+	p := secpPoint{x: secpGx, y: secpGy}
+	scalar := new(big.Int).SetBytes(k)
+	for i := 0; i < scalar.BitLen(); i++ {
+		if scalar.Bit(i) == 1 {
+			r = secpAdd(r, p)
+		}
+		p = secpAdd(p, p)
+	}
+	return r
+}
+
+// compressed возвращает сжатое представление SEC1 (33 байта)
+func (p secpPoint) compressed() []byte {
+	out := make([]byte, 33)
+	out[0] = 2 + byte(p.y.Bit(0))
+	p.x.FillBytes(out[1:])
+	return out
+}
+
+// uncompressed возвращает несжатое представление SEC1 (65 байт)
+func (p secpPoint) uncompressed() []byte {
+	out := make([]byte, 65)
+	out[0] = 4
+	p.x.FillBytes(out[1:33])
+	p.y.FillBytes(out[33:])
+	return out
+}
+
+// validSecpScalar сообщает, что число подходит как закрытый ключ: 0 < k < n
+func validSecpScalar(k *big.Int) bool {
+	return k.Sign() > 0 && k.Cmp(secpN) < 0
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/hex"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// wipe затирает буфер с секретом нулями. Go не дает полной гарантии:
+// сборщик мусора может оставить копии при перемещении стеков, а внутренние
+// состояния HMAC и KDF недоступны, - но секрет хотя бы не лежит в куче
+// до сборки мусора, как неизменяемые строки.
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+	runtime.KeepAlive(b)
+}
+
+// mlockWarning выводит предупреждение о неудачном mlock один раз за запуск
+var mlockWarning sync.Once
+
+// newSecret выделяет буфер для секрета и закрепляет его в памяти
+func newSecret(n int) []byte {
+	b := make([]byte, n)
+	lockSecret(b)
+	return b
+}
+
+// lockSecret закрепляет буфер с секретом в оперативной памяти, чтобы он не
+// попал в swap. Сборщик мусора Go не перемещает объекты кучи, поэтому
+// закрепление остается в силе. Страницы не открепляются до выхода:
+// munlock снял бы закрепление и с соседних секретов на той же странице.
+// Если закрепить не удалось, работа продолжается с предупреждением.
+func lockSecret(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	if err := mlock(b); err != nil {
+		mlockWarning.Do(func() {
+			logWarnf("⚠ Не удалось закрепить секреты в памяти (mlock: %v), они могут попасть в swap.\n", err)
+			if limit, ok := memlockLimit(); ok {
+				logWarnf("  Лимит RLIMIT_MEMLOCK: %d КиБ, увеличьте его (ulimit -l) или отключите swap.\n", limit/1024)
+			}
+		})
+	}
+}
+
+// wipeSeeds затирает все сиды набора
+func wipeSeeds(seeds [][]byte) {
+	for _, s := range seeds {
+		wipe(s)
+	}
+}
+
+// hexSecret кодирует секрет в hex в новом буфере; вызывающий затирает его
+func hexSecret(secret []byte) []byte {
+	out := newSecret(hex.EncodedLen(len(secret)))
+	encodeHex(out, secret)
+	return out
+}
+
+// writeSecretLine выводит секрет в hex с переводом строки, минуя строки
+// и форматирование fmt, которые оставили бы в памяти копии
+func writeSecretLine(w io.Writer, secret []byte) error {
+	line := newSecret(hex.EncodedLen(len(secret)) + 1)
+	defer wipe(line)
+	encodeHex(line, secret)
+	line[len(line)-1] = '\n'
+	_, err := w.Write(line)
+	return err
+}
+
+// secretHex - секрет, который в JSON записывается строкой hex. Кодирование
+// и разбор идут напрямую между байтами, без промежуточной строки
+// (буфер самого кодировщика JSON при этом затереть нельзя).
+type secretHex []byte
+
+// MarshalJSON кодирует секрет строкой hex
+func (s secretHex) MarshalJSON() ([]byte, error) {
+	out := make([]byte, hex.EncodedLen(len(s))+2)
+	out[0], out[len(out)-1] = '"', '"'
+	encodeHex(out[1:], s)
+	return out, nil
+}
+
+// UnmarshalJSON разбирает строку hex
+func (s *secretHex) UnmarshalJSON(data []byte) error {
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return errorf("ожидалась строка hex")
+	}
+	out := newSecret(hex.DecodedLen(len(data) - 2))
+	if _, err := decodeHex(out, data[1:len(data)-1]); err != nil {
+		wipe(out)
+		return err
+	}
+	*s = out
+	return nil
+}
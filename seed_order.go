@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rankedTeam - команда с рейтингом и жребием, если рейтинг с кем-то совпал
+type rankedTeam struct {
+	Seed    int     `json:"seed"`
+	Name    string  `json:"team"`
+	Ranking float64 `json:"ranking"`
+	// Coin - число потока, разрешившее равенство рейтингов (hex), меньшее выше
+	Coin string `json:"coin,omitempty"`
+	Tied int    `json:"tied_with,omitempty"`
+	coin uint64
+}
+
+// seedOrder - посев по рейтингу
+type seedOrder struct {
+	Kind        string       `json:"kind"`
+	Label       string       `json:"label"`
+	Rankings    string       `json:"rankings_sha256"`
+	Higher      bool         `json:"higher_is_better"`
+	Fingerprint string       `json:"master_fingerprint"`
+	Seeds       []rankedTeam `json:"seeds"`
+}
+
+// readRankings читает CSV со строками "команда,рейтинг"; заголовок
+// (первое поле "team"), пустые строки и строки с # пропускаются.
+// Возвращает команды в порядке файла и SHA-256 канонической записи.
+func readRankings(path string) ([]rankedTeam, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	var teams []rankedTeam
+	seen := make(map[string]bool)
+	var canonical []string
+	scanner := bufio.NewScanner(f)
+	for line, first := 1, true; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		r := csv.NewReader(strings.NewReader(text))
+		r.TrimLeadingSpace = true
+		rec, err := r.Read()
+		if err != nil {
+			return nil, "", errorf("%s:%d: %w", path, line, err)
+		}
+		if first {
+			first = false
+			if strings.EqualFold(strings.TrimSpace(rec[0]), "team") {
+				continue
+			}
+		}
+		if len(rec) != 2 {
+			return nil, "", errorf("%s:%d: ожидается команда,рейтинг", path, line)
+		}
+		t := rankedTeam{Name: strings.TrimSpace(rec[0])}
+		if t.Name == "" {
+			return nil, "", errorf("%s:%d: не указана команда", path, line)
+		}
+		if seen[t.Name] {
+			return nil, "", errorf("%s:%d: команда %q уже указана", path, line, t.Name)
+		}
+		seen[t.Name] = true
+		if t.Ranking, err = strconv.ParseFloat(strings.TrimSpace(rec[1]), 64); err != nil {
+			return nil, "", errorf("%s:%d: некорректный рейтинг %q", path, line, rec[1])
+		}
+		teams = append(teams, t)
+		canonical = append(canonical, t.Name+","+strconv.FormatFloat(t.Ranking, 'g', -1, 64))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256([]byte(strings.Join(canonical, "\n")))
+	digest := hex.EncodeToString(sum[:])
+	auditInput("file", path, digest)
+	return teams, digest, nil
+}
+
+// readSeedRankings читает рейтинг из файла рейтинга или из выгрузки регистрации
+func readSeedRankings(path, rosterPath string) ([]rankedTeam, string, error) {
+	if rosterPath == "" {
+		return readRankings(path)
+	}
+	entries, digest, err := loadRoster(rosterPath)
+	if err != nil {
+		return nil, "", err
+	}
+	teams, err := rosterRankings(entries)
+	if err != nil {
+		return nil, "", errorf("%s: %w", rosterPath, err)
+	}
+	return teams, digest, nil
+}
+
+// orderBySeed упорядочивает команды по рейтингу. Каждой команде из группы
+// с равным рейтингом по очереди выдается число потока: группы идут от
+// лучшего рейтинга к худшему, внутри группы - по имени (порядок байтов),
+// так что перестановка строк файла не меняет жребий. Внутри группы выше
+// команда с меньшим числом.
+func orderBySeed(stream *drawStream, teams []rankedTeam, higher bool) []rankedTeam {
+	ordered := append([]rankedTeam(nil), teams...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if a.Ranking != b.Ranking {
+			if higher {
+				return a.Ranking > b.Ranking
+			}
+			return a.Ranking < b.Ranking
+		}
+		return a.Name < b.Name
+	})
+	for start := 0; start < len(ordered); {
+		end := start + 1
+		for end < len(ordered) && ordered[end].Ranking == ordered[start].Ranking {
+			end++
+		}
+		if end-start > 1 {
+			group := ordered[start:end]
+			for i := range group {
+				group[i].coin = stream.uint64()
+				group[i].Coin = fmt.Sprintf("%016x", group[i].coin)
+				group[i].Tied = len(group) - 1
+			}
+			sort.SliceStable(group, func(i, j int) bool { return group[i].coin < group[j].coin })
+		}
+		start = end
+	}
+	for i := range ordered {
+		ordered[i].Seed = i + 1
+	}
+	return ordered
+}
+
+// runSeedOrder назначает посев по рейтингу с жребием при равенстве
+func runSeedOrder(args []string) error {
+	fs := newFlagSet("seed-order")
+	rankingsPath := fs.String("rankings", "", "CSV рейтинга: строки команда,рейтинг")
+	higher := fs.Bool("higher-is-better", false, "больший рейтинг выше (очки); по умолчанию выше меньший (место)")
+	label := fs.String("label", "seed-order", "метка посева: разные метки дают независимый жребий из одного мастер-сида")
+	format := fs.String("format", "text", "формат вывода: text, csv, json или list (имена по одному на строку для bracket --participants)")
+	rosterPath := addRosterFlag(fs, "rankings")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := oneSource(*rankingsPath, *rosterPath, "rankings"); err != nil {
+		return err
+	}
+	switch *format {
+	case "text", "csv", "json", "list":
+	default:
+		return errorf("неизвестный формат %q", *format)
+	}
+	teams, digest, err := readSeedRankings(*rankingsPath, *rosterPath)
+	if err != nil {
+		return err
+	}
+	if len(teams) < 2 {
+		return errorf("в рейтинге должно быть хотя бы 2 команды")
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	result := seedOrder{
+		Kind:        "seed-order",
+		Label:       *label,
+		Rankings:    digest,
+		Higher:      *higher,
+		Fingerprint: masterFingerprint(master),
+		Seeds:       orderBySeed(newDrawStream(master, "seed-order", *label), teams, *higher),
+	}
+
+	switch *format {
+	case "json":
+		return writeOutput(os.Stdout, result)
+	case "list":
+		for _, t := range result.Seeds {
+			fmt.Println(t.Name)
+		}
+		return nil
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"seed", "team", "ranking", "coin"})
+		for _, t := range result.Seeds {
+			w.Write([]string{strconv.Itoa(t.Seed), t.Name, strconv.FormatFloat(t.Ranking, 'g', -1, 64), t.Coin})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	fmt.Printf(tr("Посев %q: команд %d\n"), result.Label, len(result.Seeds))
+	fmt.Printf(tr("Мастер-сид: %s..., рейтинг: SHA-256 %s\n"), emph(result.Fingerprint), result.Rankings)
+	fmt.Println()
+	for _, t := range result.Seeds {
+		line := fmt.Sprintf("  %3d. %s (%s)", t.Seed, t.Name, strconv.FormatFloat(t.Ranking, 'g', -1, 64))
+		if t.Coin != "" {
+			line += fmt.Sprintf(tr(" - жребий %s среди %d равных"), t.Coin, t.Tied+1)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
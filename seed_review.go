@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// seedReview - проверка введенных сидов перед растягиванием
+type seedReview struct {
+	in    *os.File
+	w     io.Writer
+	input seedInput
+	key   []byte
+	save  func(seeds [][]byte) (string, error)
+}
+
+// reviewSeeds показывает сводку введенных сидов и дает удалить, заменить,
+// переставить или добавить сиды, пока мастер-сид не вычислен: одна строка
+// с опечаткой больше не заставляет вводить все заново. Сиды в сводке
+// скрыты, а отпечаток каждого - HMAC на случайном ключе этого запуска:
+// по нему видно совпадающие и замененные сиды, но подобрать сид по
+// отпечатку нельзя. Порядок сидов на мастер-сид не влияет, перестановка
+// нужна только для сверки сводки со списком устройств. Если задан save,
+// команда s сохраняет введенные сиды и прерывает церемонию.
+func reviewSeeds(in *os.File, w io.Writer, seeds [][]byte, input seedInput, save func(seeds [][]byte) (string, error)) ([][]byte, error) {
+	r := &seedReview{in: in, w: w, input: input, key: make([]byte, 32), save: save}
+	if _, err := rand.Read(r.key); err != nil {
+		wipeSeeds(seeds)
+		return nil, withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	for {
+		r.summary(seeds)
+		if save != nil {
+			fmt.Fprint(w, tr("Enter - продолжить, d N - удалить, r N - заменить, m N M - переставить, a - добавить, s - сохранить и прервать: "))
+		} else {
+			fmt.Fprint(w, tr("Enter - продолжить, d N - удалить, r N - заменить, m N M - переставить, a - добавить: "))
+		}
+		line, err := readCommandLine(in)
+		if err != nil {
+			fmt.Fprintln(w)
+			wipeSeeds(seeds)
+			return nil, withCode(exitCancelled, errorf("проверка сидов прервана"))
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			if len(seeds) == 0 {
+				fmt.Fprintln(w, tr("❌ Не осталось ни одного сида, добавьте сид командой a"))
+				continue
+			}
+			return seeds, nil
+		}
+		if fields[0] == "s" && len(fields) == 1 && save != nil {
+			path, err := save(seeds)
+			if err != nil {
+				fmt.Fprintf(w, tr("❌ %v\n"), err)
+				continue
+			}
+			wipeSeeds(seeds)
+			logInfof("✓ Сиды зашифрованы и сохранены в %s\n", path)
+			logInfof("Чтобы продолжить, повторите команду с --resume %s\n", path)
+			return nil, withCode(exitCancelled, errorf("церемония прервана до вычисления мастер-сида"))
+		}
+		if seeds, err = r.apply(seeds, fields); err != nil {
+			if code := classifyError(err); code == exitCancelled {
+				wipeSeeds(seeds)
+				return nil, err
+			}
+			fmt.Fprintf(w, tr("❌ %v\n"), err)
+		}
+	}
+}
+
+// summary выводит пронумерованный список сидов: длину и отпечаток вместо
+// содержимого, с пометкой совпадающих
+func (r *seedReview) summary(seeds [][]byte) {
+	fmt.Fprintln(r.w)
+	fmt.Fprintf(r.w, tr("Введено сидов: %d\n"), len(seeds))
+	seen := make(map[string]int, len(seeds))
+	for i, seed := range seeds {
+		fp := r.fingerprint(seed)
+		fmt.Fprintf(r.w, tr("  #%-3d символов: %-4d отпечаток %s"), i+1, utf8.RuneCount(seed), fp)
+		if first, ok := seen[fp]; ok {
+			fmt.Fprintf(r.w, tr("  ⚠ совпадает с #%d"), first)
+		} else {
+			seen[fp] = i + 1
+		}
+		fmt.Fprintln(r.w)
+	}
+	fmt.Fprintln(r.w)
+}
+
+// fingerprint возвращает отпечаток сида для сводки
+func (r *seedReview) fingerprint(seed []byte) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write(seed)
+	return hex.EncodeToString(mac.Sum(nil)[:3])
+}
+
+// apply выполняет одну команду проверки
+func (r *seedReview) apply(seeds [][]byte, fields []string) ([][]byte, error) {
+	want := map[string]int{"d": 1, "r": 1, "m": 2, "a": 0}
+	count, ok := want[fields[0]]
+	if !ok {
+		return seeds, errorf("неизвестная команда %q", fields[0])
+	}
+	if len(fields)-1 != count {
+		return seeds, errorf("команде %s нужно номеров сидов: %d", fields[0], count)
+	}
+	args := make([]int, 0, count)
+	for _, f := range fields[1:] {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 1 || n > len(seeds) {
+			return seeds, errorf("нет сида с номером %s", f)
+		}
+		args = append(args, n-1)
+	}
+
+	switch fields[0] {
+	case "d":
+		wipe(seeds[args[0]])
+		seeds = append(seeds[:args[0]], seeds[args[0]+1:]...)
+		fmt.Fprintf(r.w, tr("✓ Сид #%d удален\n"), args[0]+1)
+	case "r":
+		seed, err := r.readSeed(args[0]+1, fmt.Sprintf(tr("Новый сид #%d: "), args[0]+1))
+		if err != nil || seed == nil {
+			return seeds, err
+		}
+		wipe(seeds[args[0]])
+		seeds[args[0]] = seed
+		fmt.Fprintf(r.w, tr("✓ Сид #%d заменен\n"), args[0]+1)
+	case "m":
+		from, to := args[0], args[1]
+		seed := seeds[from]
+		seeds = append(seeds[:from], seeds[from+1:]...)
+		seeds = append(seeds[:to], append([][]byte{seed}, seeds[to:]...)...)
+		fmt.Fprintf(r.w, tr("✓ Сид #%d теперь #%d\n"), from+1, to+1)
+	case "a":
+		seed, err := r.readSeed(len(seeds)+1, fmt.Sprintf(tr("Сид #%d: "), len(seeds)+1))
+		if err != nil || seed == nil {
+			return seeds, err
+		}
+		seeds = append(seeds, seed)
+		fmt.Fprintf(r.w, tr("✓ Сид #%d добавлен\n"), len(seeds))
+	}
+	return seeds, nil
+}
+
+// readSeed читает один сид так же, как readDeviceSeedsFunc: с нормализацией
+// или строгой проверкой символов (с raw - как есть), со звездочками при masked и с проверкой
+// суффикса при checked. Пустая строка отменяет замену или добавление,
+// тогда возвращается nil.
+func (r *seedReview) readSeed(number int, prompt string) ([]byte, error) {
+	buf := newSecret(maskedLineSize)
+	defer wipe(buf)
+	for {
+		fmt.Fprint(r.w, prompt)
+		line, err := r.readSeedLine(buf)
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil, nil
+		}
+		input, err := prepareSeed(line, r.input, number)
+		if err != nil {
+			fmt.Fprintf(r.w, tr("❌ %s, введите сид заново\n"), err)
+			continue
+		}
+		if r.input.checked {
+			if input, err = splitSeedCheck(input); err != nil {
+				fmt.Fprintf(r.w, tr("❌ %s, введите сид заново\n"), err)
+				continue
+			}
+		}
+		seed := newSecret(len(input))
+		copy(seed, input)
+		return seed, nil
+	}
+}
+
+// readSeedLine читает строку сида в buf со звездочками или, если их
+// нельзя показать, как есть
+func (r *seedReview) readSeedLine(buf []byte) ([]byte, error) {
+	if r.input.masked {
+		if restore, err := enterMaskedMode(r.in); err == nil {
+			defer restore()
+			return readMasked(r.in, r.w, buf)
+		}
+	}
+	line := buf[:0]
+	b := make([]byte, 1)
+	for {
+		if _, err := r.in.Read(b); err != nil {
+			return nil, err
+		}
+		if b[0] == '\n' {
+			return line, nil
+		}
+		if len(line) < cap(line) {
+			line = append(line, b[0])
+		}
+	}
+}
+
+// readCommandLine читает из терминала строку команды побайтно, чтобы не
+// забрать лишнего из ввода
+func readCommandLine(in io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := in.Read(b); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return strings.TrimSpace(string(line)), nil
+		}
+		line = append(line, b[0])
+	}
+}
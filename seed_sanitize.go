@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// seedInput - как читаются сиды с клавиатуры или из stdin
+type seedInput struct {
+	checked bool // сид вводится с контрольным суффиксом
+	masked  bool // вместо символов сида видны звездочки
+	strict  bool // отклонять сиды с невидимыми символами вместо нормализации
+	raw     bool // брать сиды без нормализации, как прежние версии (--compat v1)
+}
+
+// seedIssue - символ сида, который не виден на экране или похож на другой.
+// Позиции считаются в символах с 1, как их видит оператор.
+type seedIssue struct {
+	pos int
+	msg string
+	// fixable сообщает, что символ можно убрать или заменить пробелом;
+	// смешение алфавитов исправить нельзя, о нем только предупреждают
+	fixable bool
+}
+
+func (i seedIssue) String() string {
+	return fmt.Sprintf(tr("позиция %d: %s"), i.pos, i.msg)
+}
+
+// scriptOf возвращает алфавит буквы или "", если это не буква латиницы,
+// кириллицы или греческого: их смешение в одном сиде почти всегда означает
+// похожую букву из другой раскладки или из PDF
+func scriptOf(r rune) string {
+	switch {
+	case unicode.Is(unicode.Latin, r):
+		return tr("латиница")
+	case unicode.Is(unicode.Cyrillic, r):
+		return tr("кириллица")
+	case unicode.Is(unicode.Greek, r):
+		return tr("греческий")
+	}
+	return ""
+}
+
+// sanitizeSeed ищет в строке сида символы, которые при копировании из PDF,
+// мессенджера или таблицы незаметно меняют сид: BOM, символы нулевой
+// ширины и прочие управляющие, табуляции, неразрывные и прочие пробелы
+// Юникода, пробелы по краям, буквы разных алфавитов. Строка нормализуется
+// на месте: невидимые символы удаляются, пробелы заменяются обычными,
+// края обрезаются; освободившиеся байты затираются. Возвращается
+// нормализованная строка и найденные символы.
+func sanitizeSeed(line []byte) ([]byte, []seedIssue) {
+	var issues []seedIssue
+	// origin - позиция в исходной строке для каждого оставшегося символа
+	var origin []int
+	out := 0
+	firstScript := ""
+	mixedReported := false
+	for in, pos := 0, 1; in < len(line); pos++ {
+		r, size := utf8.DecodeRune(line[in:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			// Не UTF-8: байты сида остаются как есть
+		case r == '\uFEFF':
+			issues = append(issues, seedIssue{pos, tr("метка порядка байтов (BOM)"), true})
+			in += size
+			continue
+		case r == '\t':
+			issues = append(issues, seedIssue{pos, tr("табуляция"), true})
+			line[out] = ' '
+			origin = append(origin, pos)
+			out++
+			in += size
+			continue
+		case r != ' ' && unicode.IsSpace(r):
+			issues = append(issues, seedIssue{pos, fmt.Sprintf(tr("пробел U+%04X вместо обычного"), r), true})
+			line[out] = ' '
+			origin = append(origin, pos)
+			out++
+			in += size
+			continue
+		case unicode.Is(unicode.Cf, r) || unicode.Is(unicode.Cc, r):
+			issues = append(issues, seedIssue{pos, fmt.Sprintf(tr("невидимый символ U+%04X"), r), true})
+			in += size
+			continue
+		default:
+			if script := scriptOf(r); script != "" {
+				if firstScript == "" {
+					firstScript = script
+				} else if script != firstScript && !mixedReported {
+					issues = append(issues, seedIssue{pos, fmt.Sprintf(tr("буква «%c» - %s, а до нее - %s"), r, script, firstScript), false})
+					mixedReported = true
+				}
+			}
+		}
+		copy(line[out:], line[in:in+size])
+		origin = append(origin, pos)
+		out += size
+		in += size
+	}
+	wipe(line[out:])
+	line = line[:out]
+
+	// Пробелы по краям, в том числе оставшиеся от замены табуляций
+	if trimmed := bytes.TrimRight(line, " "); len(trimmed) < len(line) {
+		issues = append(issues, seedIssue{origin[utf8.RuneCount(trimmed)], tr("пробел в конце строки"), true})
+		wipe(line[len(trimmed):])
+		line = trimmed
+	}
+	if n := len(line) - len(bytes.TrimLeft(line, " ")); n > 0 {
+		issues = append(issues, seedIssue{origin[0], tr("пробел в начале строки"), true})
+		copy(line, line[n:])
+		wipe(line[len(line)-n:])
+		line = line[:len(line)-n]
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].pos < issues[j].pos })
+	return line, issues
+}
+
+// describeSeedIssues перечисляет найденные символы через точку с запятой
+func describeSeedIssues(issues []seedIssue) string {
+	parts := make([]string, len(issues))
+	for i, issue := range issues {
+		parts[i] = issue.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// prepareSeed готовит введенную строку сида по режиму ввода
+func prepareSeed(line []byte, opts seedInput, number int) ([]byte, error) {
+	if opts.raw {
+		return rawSeed(line, number), nil
+	}
+	return cleanSeed(line, opts.strict, number)
+}
+
+// rawSeed оставляет сид как есть, убрав только пробелы по краям, как до
+// появления нормализации ввода. Невидимые символы не исправляются: с ними
+// получены выходы прежних версий. О них только предупреждают.
+func rawSeed(line []byte, number int) []byte {
+	line = bytes.TrimSpace(line)
+	check := newSecret(len(line))
+	copy(check, line)
+	_, issues := sanitizeSeed(check)
+	wipe(check)
+	if len(issues) > 0 {
+		logWarnf("⚠ Сид #%d: %s - оставлено как есть (--compat v1)\n", number, describeSeedIssues(issues))
+	}
+	return line
+}
+
+// cleanSeed нормализует строку сида. В строгом режиме любой найденный
+// символ - ошибка, в мягком исправимые символы исправляются, а обо всех
+// выводится предупреждение. Смешение алфавитов в мягком режиме остается
+// в сиде как есть: какая буква задумана, знает только оператор.
+func cleanSeed(line []byte, strict bool, number int) ([]byte, error) {
+	raw := len(line) > 0
+	line, issues := sanitizeSeed(line)
+	if strict && len(issues) > 0 {
+		return nil, errorf("%s", describeSeedIssues(issues))
+	}
+	var fixed, kept []seedIssue
+	for _, issue := range issues {
+		if issue.fixable {
+			fixed = append(fixed, issue)
+		} else {
+			kept = append(kept, issue)
+		}
+	}
+	if len(fixed) > 0 {
+		logWarnf("⚠ Сид #%d: %s - исправлено\n", number, describeSeedIssues(fixed))
+	}
+	if len(kept) > 0 {
+		logWarnf("⚠ Сид #%d: %s - проверьте раскладку, сид оставлен как есть\n", number, describeSeedIssues(kept))
+	}
+	if raw && len(line) == 0 {
+		return nil, errorf("в строке только невидимые символы и пробелы")
+	}
+	return line, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/sha512"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// seedFileChunk - размер блока чтения файла сида. Файл проходит через хэш
+// блоками, поэтому пиковая память не зависит от его размера.
+const seedFileChunk = 64 * 1024
+
+// seedSourceFlags - флаги источников сидов церемонии помимо stdin
+type seedSourceFlags struct {
+	credentials stringList
+	managers    stringList
+	files       stringList
+	checked     *bool
+	masked      *bool
+	strict      *bool
+	review      *bool
+	resume      *string
+	command     string
+	// dryRun запрещает сохранять ввод: пробный запуск ничего не записывает
+	dryRun bool
+	// raw отключает нормализацию сидов для --compat v1
+	raw bool
+}
+
+// addSeedSourceFlags регистрирует флаги источников сидов в наборе
+func addSeedSourceFlags(fs *flag.FlagSet) *seedSourceFlags {
+	sf := &seedSourceFlags{command: strings.TrimPrefix(fs.Name(), "seedgen ")}
+	fs.Var(&sf.credentials, "seeds-credential", "читать сиды из учетных данных systemd с этим именем вместо stdin (можно несколько раз)")
+	fs.Var(&sf.managers, "seeds-from", "читать сиды по строке из записи менеджера паролей pass:путь или op://хранилище/запись/поле вместо stdin (можно несколько раз)")
+	fs.Var(&sf.files, "seed-file", "взять сидом SHA-512 содержимого файла вместо ввода в stdin (можно несколько раз)")
+	sf.checked = fs.Bool("seed-check", false, "сиды вводятся с контрольным суффиксом \"-XXXX\" (newseed --check), суффикс проверяется сразу")
+	sf.masked = fs.Bool("mask-seeds", false, "показывать при вводе сидов звездочки вместо символов")
+	sf.strict = fs.Bool("strict-input", false, "отклонять сиды с невидимыми символами, табуляциями, пробелами по краям и смешением алфавитов вместо исправления")
+	sf.review = fs.Bool("review", true, "в терминале показать сводку сидов после ввода и дать исправить их до вычисления")
+	sf.resume = fs.String("resume", "", "продолжить ввод сидов, сохраненный командой s при проверке сводки")
+	return sf
+}
+
+// read читает сиды из учетных данных systemd и файлов, если они указаны,
+// иначе запрашивает их у оператора
+func (sf *seedSourceFlags) read(prompts io.Writer) ([][]byte, error) {
+	if sf.raw && *sf.strict {
+		return nil, errorf("--strict-input не сочетается с --compat v1: прежние версии принимали сиды как есть")
+	}
+	if *sf.resume != "" {
+		return sf.resumeSeeds(prompts)
+	}
+	if len(sf.credentials) == 0 && len(sf.managers) == 0 && len(sf.files) == 0 {
+		fmt.Fprintln(prompts, tr("Введите сиды от устройств (по одному на строку)."))
+		fmt.Fprintln(prompts, tr("Для завершения ввода оставьте строку пустой и нажмите Enter."))
+		fmt.Fprintln(prompts)
+		seeds, err := readDeviceSeedsFunc(os.Stdin, prompts, sf.input(), nil)
+		if err != nil || !*sf.review || len(seeds) == 0 || !isTerminal(os.Stdin) {
+			return seeds, err
+		}
+		return reviewSeeds(os.Stdin, prompts, seeds, sf.input(), sf.save(prompts))
+	}
+	if *sf.checked || *sf.masked || *sf.strict {
+		return nil, errorf("флаги --seed-check, --mask-seeds и --strict-input применимы только к вводу сидов с клавиатуры")
+	}
+
+	var seeds [][]byte
+	if len(sf.credentials) > 0 {
+		read, err := readSeedCredentials(sf.credentials)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, read...)
+		fmt.Fprintf(prompts, tr("Сиды прочитаны из учетных данных systemd: %s\n"), strings.Join(sf.credentials, ", "))
+	}
+	if len(sf.managers) > 0 {
+		read, err := readManagerSeeds(sf.managers)
+		if err != nil {
+			wipeSeeds(seeds)
+			return nil, err
+		}
+		seeds = append(seeds, read...)
+		fmt.Fprintf(prompts, tr("Сиды прочитаны из менеджера паролей: %s\n"), strings.Join(sf.managers, ", "))
+	}
+	for _, path := range sf.files {
+		seed, size, err := hashSeedFile(path)
+		if err != nil {
+			wipeSeeds(seeds)
+			return nil, err
+		}
+		seeds = append(seeds, seed)
+		fmt.Fprintf(prompts, tr("Сид из файла %s (%s)\n"), path, formatBytes(size))
+	}
+	return seeds, nil
+}
+
+// input возвращает режим ввода сидов с клавиатуры
+func (sf *seedSourceFlags) input() seedInput {
+	return seedInput{checked: *sf.checked, masked: *sf.masked, strict: *sf.strict, raw: sf.raw}
+}
+
+// save возвращает сохранение ввода для команды s проверки сводки
+func (sf *seedSourceFlags) save(prompts io.Writer) func(seeds [][]byte) (string, error) {
+	if sf.dryRun {
+		return nil
+	}
+	return func(seeds [][]byte) (string, error) {
+		return saveSeeds(os.Stdin, prompts, sf.command, seeds)
+	}
+}
+
+// resumeSeeds расшифровывает сохраненный ввод и продолжает с проверки
+// сводки, где можно добавить оставшиеся сиды
+func (sf *seedSourceFlags) resumeSeeds(prompts io.Writer) ([][]byte, error) {
+	if len(sf.credentials) > 0 || len(sf.managers) > 0 || len(sf.files) > 0 {
+		return nil, errorf("--resume не сочетается с --seeds-credential, --seeds-from и --seed-file")
+	}
+	if !isTerminal(os.Stdin) {
+		return nil, errorf("продолжение сохраненного ввода требует терминала")
+	}
+	seeds, err := loadSavedSeeds(*sf.resume, os.Stdin, prompts, sf.command)
+	if err != nil {
+		return nil, err
+	}
+	logInfof("✓ Восстановлено сидов: %d\n", len(seeds))
+	if !*sf.review {
+		return seeds, nil
+	}
+	return reviewSeeds(os.Stdin, prompts, seeds, sf.input(), sf.save(prompts))
+}
+
+// hashSeedFile возвращает сид файла: hex-запись SHA-512 его содержимого,
+// ту же, что печатает sha512sum. Запись можно ввести вручную, чтобы повторить
+// церемонию без файла. Возвращается также размер прочитанных данных.
+func hashSeedFile(path string) ([]byte, uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	buf := newSecret(seedFileChunk)
+	defer wipe(buf)
+	h := sha512.New()
+	var size uint64
+	for {
+		n, err := f.Read(buf)
+		h.Write(buf[:n])
+		size += uint64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, errorf("ошибка чтения %s: %w", path, err)
+		}
+	}
+	if size == 0 {
+		return nil, 0, errorf("файл %s пуст", path)
+	}
+
+	sum := h.Sum(newSecret(sha512.Size)[:0])
+	defer wipe(sum)
+	return hexSecret(sum), size, nil
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// seedXORPrefix начинает запись XOR-доли сида устройства
+const seedXORPrefix = "xs1:"
+
+// seedXORMaxShares - предел числа долей одного разделения
+const seedXORMaxShares = 16
+
+// seedXORShare - XOR-доля сида устройства: все доли, кроме последней,
+// случайны, а последняя - сид ⊕ остальные доли. Сид собирается только из
+// всех долей, любая их часть о нем ничего не сообщает. id - случайный
+// номер разделения, по которому видно, что доли из одного набора: отпечаток
+// сида для этого не годится, по нему перебирали бы слабые сиды.
+type seedXORShare struct {
+	index int
+	total int
+	id    string
+	data  []byte
+}
+
+// header возвращает первую строку записи доли
+func (s *seedXORShare) header() string {
+	return fmt.Sprintf("%s%d-%d:%s", seedXORPrefix, s.index, s.total, s.id)
+}
+
+// check возвращает контроль доли, как у долей backup-kit
+func (s *seedXORShare) check() string {
+	return shareCheck(s.header(), s.data)
+}
+
+// runSeedXOR - точка входа "seedgen seed-xor": разделение сида устройства
+// на XOR-доли до церемонии и сборка его из долей
+func runSeedXOR(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, tr("Использование:"))
+		fmt.Fprintln(os.Stderr, "  seedgen seed-xor split [--shares 2] [--out seed-shares]")
+		fmt.Fprintln(os.Stderr, tr("  seedgen seed-xor join [файлы долей...]"))
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return errorf("укажите действие: split или join")
+	}
+
+	switch args[0] {
+	case "split":
+		return runSeedXORSplit(args[1:])
+	case "join":
+		return runSeedXORJoin(args[1:])
+	default:
+		return errorf("неизвестное действие %q, доступны: split, join", args[0])
+	}
+}
+
+// runSeedXORSplit делит введенный сид устройства на XOR-доли, чтобы
+// хранитель держал его в нескольких местах. Сид нормализуется так же,
+// как при вводе в generate, поэтому собранный сид дает тот же мастер-сид.
+func runSeedXORSplit(args []string) error {
+	fs := newFlagSet("seed-xor split")
+	shares := fs.Int("shares", 2, "на сколько долей разделить сид; для сборки нужны все")
+	out := fs.String("out", "", "каталог для файлов долей; без флага доли выводятся в stdout")
+	strict := fs.Bool("strict-input", false, "отклонять сид с невидимыми символами, табуляциями, пробелами по краям и смешением алфавитов вместо исправления")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *shares < 2 || *shares > seedXORMaxShares {
+		return errorf("--shares должно быть от 2 до %d", seedXORMaxShares)
+	}
+	var paths []string
+	if *out != "" {
+		for i := 1; i <= *shares; i++ {
+			path := filepath.Join(*out, fmt.Sprintf("seed-share-%d.txt", i))
+			if _, err := os.Lstat(path); err == nil {
+				return errorf("%s уже существует: доли прежнего разделения не перезаписываются", path)
+			}
+			paths = append(paths, path)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, tr("Введите сид устройства, затем пустую строку."))
+	seeds, err := readDeviceSeedsFunc(os.Stdin, os.Stderr, seedInput{masked: true, strict: *strict}, nil)
+	if err != nil {
+		return err
+	}
+	defer wipeSeeds(seeds)
+	switch len(seeds) {
+	case 0:
+		return errorf("не введено ни одного сида")
+	case 1:
+	default:
+		return errorf("введено сидов: %d, а делится один: разделите каждый сид отдельно", len(seeds))
+	}
+	seed := seeds[0]
+
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	data, err := xorSplit(seed, *shares)
+	if err != nil {
+		return err
+	}
+	defer wipeSeeds(data)
+	// Сборка до вывода: доли, из которых сид не собирается, не выдаются
+	joined := xorJoin(data)
+	ok := subtle.ConstantTimeCompare(joined, seed) == 1
+	wipe(joined)
+	if !ok {
+		return withCode(exitCrypto, errorf("проверка разделения не прошла: доли не собирают сид"))
+	}
+
+	parts := make([]*seedXORShare, *shares)
+	for i := range parts {
+		parts[i] = &seedXORShare{index: i + 1, total: *shares, id: hex.EncodeToString(id), data: data[i]}
+	}
+	if *out == "" {
+		for _, s := range parts {
+			if err := writeSeedXORShare(os.Stdout, s); err != nil {
+				return err
+			}
+			fmt.Println()
+		}
+		logInfof("✓ Сид разделен на доли (%d, разделение %s): храните их в разных местах, для сборки нужны все\n", *shares, parts[0].id)
+		return nil
+	}
+
+	if err := os.MkdirAll(*out, 0700); err != nil {
+		return err
+	}
+	for i, s := range parts {
+		// Буфер заранее больше записи и не перераспределяется при записи
+		buf := bytes.NewBuffer(newSecret(3*len(s.data) + 1024)[:0])
+		err := writeSeedXORShare(buf, s)
+		if err == nil {
+			err = writeNewFile(paths[i], buf.Bytes(), 0600)
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		b := buf.Bytes()
+		wipe(b[:cap(b)])
+		if err != nil {
+			return err
+		}
+		auditResult("file", paths[i], hex.EncodeToString(sum[:]))
+	}
+	logInfof("✓ Сид разделен на доли (%d, разделение %s):\n", *shares, parts[0].id)
+	for i, s := range parts {
+		fmt.Printf(tr("  %s: доля %d из %d, контроль %s\n"), paths[i], s.index, s.total, s.check())
+	}
+	fmt.Printf(tr("Перенесите доли в разные места, а копии на этой машине затрите: seedgen wipe %s\n"), strings.Join(paths, " "))
+	return nil
+}
+
+// xorSplit делит сид на n долей: n-1 случайных и сид ⊕ все они.
+// Вызывающий затирает доли.
+func xorSplit(seed []byte, n int) ([][]byte, error) {
+	shares := make([][]byte, n)
+	last := newSecret(len(seed))
+	copy(last, seed)
+	for i := 0; i < n-1; i++ {
+		shares[i] = newSecret(len(seed))
+		if _, err := rand.Read(shares[i]); err != nil {
+			wipe(last)
+			wipeSeeds(shares[:i+1])
+			return nil, withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+		}
+		for b := range last {
+			last[b] ^= shares[i][b]
+		}
+	}
+	shares[n-1] = last
+	return shares, nil
+}
+
+// xorJoin складывает доли одной длины по XOR
+func xorJoin(shares [][]byte) []byte {
+	seed := newSecret(len(shares[0]))
+	for _, s := range shares {
+		for b := range seed {
+			seed[b] ^= s[b]
+		}
+	}
+	return seed
+}
+
+// writeSeedXORShare выводит запись доли с пояснением для хранителя
+func writeSeedXORShare(w io.Writer, s *seedXORShare) error {
+	fmt.Fprintf(w, tr("Доля сида устройства %d из %d (разделение %s)\n"), s.index, s.total, s.id)
+	fmt.Fprintf(w, tr("Для сборки нужны все доли (%d): seedgen seed-xor join; одна доля без остальных о сиде ничего не сообщает.\n\n"), s.total)
+	return writeShareBlock(w, s.header(), s.data, s.check())
+}
+
+// parseSeedXORShares находит в тексте записи XOR-долей: от строки xs1: до
+// строки check:, пропуская пояснения вокруг них
+func parseSeedXORShares(text []byte) ([]*seedXORShare, error) {
+	var shares []*seedXORShare
+	var cur *seedXORShare
+	var digits []byte
+	defer func() { wipe(digits[:cap(digits)]) }()
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		switch {
+		case hasPrefixFold(line, seedXORPrefix):
+			if cur != nil {
+				return nil, errorf("доля %d: нет строки check:", cur.index)
+			}
+			s, err := parseSeedXORHeader(strings.ToLower(string(line)))
+			if err != nil {
+				return nil, err
+			}
+			cur = s
+			digits = newSecret(2 * maskedLineSize)[:0]
+		case cur == nil:
+		case hasPrefixFold(line, "check:"):
+			if len(digits) == 0 || len(digits)%2 != 0 {
+				return nil, errorf("доля %d: в записи %d символов hex, нужно четное число", cur.index, len(digits))
+			}
+			cur.data = newSecret(len(digits) / 2)
+			if _, err := decodeHex(cur.data, digits); err != nil {
+				return nil, errorf("доля %d: запись содержит символы не hex", cur.index)
+			}
+			wipe(digits)
+			if written, ok := shareCheckMatches(line, cur.check()); !ok {
+				wipe(cur.data)
+				return nil, errorf("доля %d: контроль %s не совпадает, доля или контроль переписаны с ошибкой", cur.index, written)
+			}
+			shares = append(shares, cur)
+			cur = nil
+		default:
+			for _, c := range line {
+				if c == ' ' || c == '\t' {
+					continue
+				}
+				if len(digits) == cap(digits) {
+					return nil, errorf("доля %d: в записи больше %d символов hex", cur.index, 2*maskedLineSize)
+				}
+				digits = append(digits, c)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if cur != nil {
+		return nil, errorf("доля %d: нет строки check:", cur.index)
+	}
+	return shares, nil
+}
+
+// parseSeedXORHeader разбирает строку xs1:номер-всего:разделение
+func parseSeedXORHeader(line string) (*seedXORShare, error) {
+	parts := strings.Split(strings.TrimPrefix(line, seedXORPrefix), ":")
+	if len(parts) != 2 {
+		return nil, errorf("некорректный заголовок доли %q: ожидается %sномер-всего:разделение", line, seedXORPrefix)
+	}
+	s := &seedXORShare{id: parts[1]}
+	scheme := strings.SplitN(parts[0], "-", 2)
+	var errs [2]error
+	s.index, errs[0] = strconv.Atoi(scheme[0])
+	if len(scheme) == 2 {
+		s.total, errs[1] = strconv.Atoi(scheme[1])
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, errorf("некорректный заголовок доли %q", line)
+		}
+	}
+	if s.total < 2 || s.total > seedXORMaxShares || s.index < 1 || s.index > s.total || len(s.id) != 8 {
+		return nil, errorf("некорректный заголовок доли %q", line)
+	}
+	return s, nil
+}
+
+// combineSeedXORShares собирает сид из всех долей одного разделения;
+// повторы одной доли не считаются
+func combineSeedXORShares(shares []*seedXORShare) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errorf("доли не найдены: запись доли начинается со строки %s", seedXORPrefix)
+	}
+	first := shares[0]
+	byIndex := make(map[int]*seedXORShare)
+	for _, s := range shares {
+		if s.id != first.id || s.total != first.total {
+			return nil, errorf("доли из разных разделений: %s и %s", first.header(), s.header())
+		}
+		if len(s.data) != len(first.data) {
+			return nil, errorf("доли %d и %d разной длины: одна из них переписана не полностью", first.index, s.index)
+		}
+		byIndex[s.index] = s
+	}
+	if len(byIndex) < first.total {
+		var missing []string
+		for i := 1; i <= first.total; i++ {
+			if byIndex[i] == nil {
+				missing = append(missing, strconv.Itoa(i))
+			}
+		}
+		return nil, errorf("нужны все доли (%d), а получено разных: %d; нет долей: %s", first.total, len(byIndex), strings.Join(missing, ", "))
+	}
+	data := make([][]byte, 0, len(byIndex))
+	for i := 1; i <= first.total; i++ {
+		data = append(data, byIndex[i].data)
+	}
+	return xorJoin(data), nil
+}
+
+// runSeedXORJoin собирает сид устройства из всех его XOR-долей
+func runSeedXORJoin(args []string) error {
+	fs := newFlagSet("seed-xor join")
+	rf := addRevealFlags(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	// Сид устройства вводится одной строкой как есть, раскладку групп прочитать негде
+	if err := rf.checkGroups(false); err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		positional = []string{"-"}
+	}
+
+	var shares []*seedXORShare
+	defer func() {
+		for _, s := range shares {
+			wipe(s.data)
+		}
+	}()
+	for _, ref := range positional {
+		var text []byte
+		if ref == "-" {
+			if isTerminal(os.Stdin) {
+				fmt.Fprintln(os.Stderr, tr("Перепишите все доли сида, каждую от строки xs1: до строки check:, затем нажмите Ctrl-D:"))
+			}
+			text, err = io.ReadAll(os.Stdin)
+		} else {
+			text, err = os.ReadFile(ref)
+		}
+		if err != nil {
+			return err
+		}
+		lockSecret(text)
+		found, err := parseSeedXORShares(text)
+		wipe(text)
+		if err != nil {
+			return errorf("%s: %w", ref, err)
+		}
+		if len(found) == 0 {
+			return errorf("%s: доля не найдена: запись доли начинается со строки %s", ref, seedXORPrefix)
+		}
+		for _, s := range found {
+			logInfof("✓ Доля %d из %d (%s): контроль %s совпадает\n", s.index, s.total, ref, s.check())
+		}
+		shares = append(shares, found...)
+	}
+
+	seed, err := combineSeedXORShares(shares)
+	if err != nil {
+		return err
+	}
+	defer wipe(seed)
+	logInfof("✓ Сид собран из всех долей (%d) разделения %s\n", shares[0].total, shares[0].id)
+	return rf.revealSeed(tr("Сид устройства (собран из долей):"), seed)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// tempFilePattern - шаблон временных файлов seedgen в os.TempDir()
+const tempFilePattern = "seedgen-*"
+
+// sessionDir возвращает каталог файлов текущего сеанса, которые удаляет wipe
+func sessionDir() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "session"), nil
+}
+
+// writeSessionFile сохраняет служебный файл сеанса с правами только для владельца
+func writeSessionFile(name string, data []byte) error {
+	dir, err := sessionDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), data, 0600)
+}
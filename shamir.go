@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Разделение секрета по Шамиру побайтно в поле GF(256) из qr.go: для каждого
+// байта секрета берется случайный многочлен степени threshold-1 со
+// свободным членом - этим байтом, доля с номером x - значения многочленов
+// в точке x. Любые threshold долей восстанавливают секрет интерполяцией
+// Лагранжа в нуле, а меньшее их число не сообщает о нем ничего.
+
+// sharePrefix начинает бумажную запись доли мастер-сида
+const sharePrefix = "ss1:"
+
+// shareCheckSize - длина контроля доли в символах Крокфорда (40 бит)
+const shareCheckSize = 8
+
+// Наборы долей комплекта: доли хранителей собирают мастер-сид сами, доли
+// исполнителей - значение мастер-сид ⊕ ключ активации, поэтому им нужен
+// еще и ключ активации, который выдается только после объявленной активации
+const (
+	shareSetCustodian  = ""
+	shareSetExecutor   = "executor"
+	shareSetActivation = "activation"
+)
+
+// shamirShare - доля мастер-сида. fingerprint - отпечаток мастер-сида,
+// который собирается из долей: по нему видно, что доли из одного
+// комплекта, и проверяется результат сборки. set - набор доли.
+type shamirShare struct {
+	threshold   int
+	total       int
+	index       int
+	fingerprint string
+	set         string
+	data        []byte
+}
+
+// shamirSplit делит секрет на total долей с порогом threshold. Номера долей
+// - точки 1..total; вызывающий затирает data долей.
+func shamirSplit(secret []byte, threshold, total int) ([][]byte, error) {
+	if threshold < 2 || threshold > total || total > 255 {
+		return nil, errorf("некорректная схема %d из %d", threshold, total)
+	}
+	coeffs := newSecret(len(secret) * (threshold - 1))
+	defer wipe(coeffs)
+	if _, err := rand.Read(coeffs); err != nil {
+		return nil, err
+	}
+	shares := make([][]byte, total)
+	for i := range shares {
+		x := byte(i + 1)
+		share := newSecret(len(secret))
+		for b := range secret {
+			// Схема Горнера от старшего коэффициента к свободному члену
+			var y byte
+			for k := threshold - 2; k >= 0; k-- {
+				y = gfMul(y, x) ^ coeffs[k*len(secret)+b]
+			}
+			share[b] = gfMul(y, x) ^ secret[b]
+		}
+		shares[i] = share
+	}
+	return shares, nil
+}
+
+// shamirCombine восстанавливает секрет из долей с номерами xs. Номера
+// открыты, поэтому множители Лагранжа считаются по таблицам, а умножение
+// на байты долей - через gfMul без ветвлений по секрету.
+func shamirCombine(xs []byte, shares [][]byte) []byte {
+	secret := newSecret(len(shares[0]))
+	for i, xi := range xs {
+		// l - значение базисного многочлена Лагранжа доли i в нуле; в поле
+		// характеристики 2 вычитание совпадает со сложением
+		l := byte(1)
+		for j, xj := range xs {
+			if j != i {
+				l = gfMul(l, gfDiv(xj, xj^xi))
+			}
+		}
+		for b, y := range shares[i] {
+			secret[b] ^= gfMul(y, l)
+		}
+	}
+	return secret
+}
+
+// header возвращает первую строку записи доли; у долей хранителей набор
+// не указывается, как в комплектах без исполнителей
+func (s *shamirShare) header() string {
+	h := fmt.Sprintf("%s%d-%d:%d:%s", sharePrefix, s.threshold, s.total, s.index, s.fingerprint)
+	if s.set != shareSetCustodian {
+		h += ":" + s.set
+	}
+	return h
+}
+
+// check возвращает контроль доли
+func (s *shamirShare) check() string {
+	return shareCheck(s.header(), s.data)
+}
+
+// shareCheck возвращает контроль записи доли - первые 40 бит SHA-256 строки
+// заголовка и байт доли в алфавите Крокфорда, группами по 4 символа
+func shareCheck(header string, data []byte) string {
+	buf := newSecret(len(header) + len(data))
+	defer wipe(buf)
+	copy(buf[copy(buf, header):], data)
+	c := crockfordCheck(buf, shareCheckSize)
+	return string(c[:4]) + "-" + string(c[4:])
+}
+
+// writeShare выводит запись доли для бумаги
+func writeShare(w io.Writer, s *shamirShare) error {
+	return writeShareBlock(w, s.header(), s.data, s.check())
+}
+
+// writeShareBlock выводит запись доли: заголовок, hex доли группами по 4
+// символа, как в копии rs1, и строку контроля
+func writeShareBlock(w io.Writer, header string, data []byte, check string) error {
+	digits := hexSecret(data)
+	defer wipe(digits)
+	groups := (len(digits) + 3) / 4
+	out := newSecret(len(header) + 1 + groups*5 + 32)[:0]
+	defer func() { wipe(out[:cap(out)]) }()
+	out = append(out, header...)
+	out = append(out, '\n')
+	for i := 0; i < groups; i++ {
+		out = append(out, digits[4*i:minInt(4*i+4, len(digits))]...)
+		if (i+1)%rsGroupsPerLine == 0 || i == groups-1 {
+			out = append(out, '\n')
+		} else {
+			out = append(out, ' ')
+		}
+	}
+	out = append(out, "check: "...)
+	out = append(out, check...)
+	out = append(out, '\n')
+	_, err := w.Write(out)
+	return err
+}
+
+// parseShares находит в тексте записи долей: от строки ss1: до строки
+// check:. Текст вокруг них (остальной лист комплекта) пропускается, поэтому
+// на вход годится и сам лист, и переписанная с него доля.
+func parseShares(text []byte) ([]*shamirShare, error) {
+	var shares []*shamirShare
+	var cur *shamirShare
+	var digits []byte
+	defer func() { wipe(digits[:cap(digits)]) }()
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	for scanner.Scan() {
+		// Строки доли - секрет, поэтому разбираются байтами, без строк
+		line := bytes.TrimSpace(scanner.Bytes())
+		switch {
+		case hasPrefixFold(line, sharePrefix):
+			if cur != nil {
+				return nil, errorf("доля %d: нет строки check:", cur.index)
+			}
+			s, err := parseShareHeader(strings.ToLower(string(line)))
+			if err != nil {
+				return nil, err
+			}
+			cur = s
+			digits = newSecret(2 * masterSeedSize)[:0]
+		case cur == nil:
+		case hasPrefixFold(line, "check:"):
+			if len(digits) != 2*masterSeedSize {
+				return nil, errorf("доля %d: в записи %d символов hex вместо %d", cur.index, len(digits), 2*masterSeedSize)
+			}
+			cur.data = newSecret(masterSeedSize)
+			if _, err := decodeHex(cur.data, digits); err != nil {
+				return nil, errorf("доля %d: запись содержит символы не hex", cur.index)
+			}
+			wipe(digits)
+			if written, ok := shareCheckMatches(line, cur.check()); !ok {
+				wipe(cur.data)
+				return nil, errorf("доля %d: контроль %s не совпадает, доля или контроль переписаны с ошибкой", cur.index, written)
+			}
+			shares = append(shares, cur)
+			cur = nil
+		default:
+			for _, c := range line {
+				if c == ' ' || c == '\t' {
+					continue
+				}
+				if len(digits) == cap(digits) {
+					return nil, errorf("доля %d: в записи больше %d символов hex", cur.index, 2*masterSeedSize)
+				}
+				digits = append(digits, c)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if cur != nil {
+		return nil, errorf("доля %d: нет строки check:", cur.index)
+	}
+	return shares, nil
+}
+
+// shareCheckMatches сравнивает строку check: записи доли с контролем want
+// без учета дефисов, пробелов и спутанных символов Крокфорда и возвращает
+// записанный контроль для сообщения об ошибке
+func shareCheckMatches(line []byte, want string) (string, bool) {
+	written := string(bytes.TrimSpace(line[len("check:"):]))
+	got := make([]byte, 0, len(written))
+	for _, c := range []byte(written) {
+		if c != '-' && c != ' ' {
+			got = append(got, normalizeCrockford(c))
+		}
+	}
+	return written, string(got) == strings.Replace(want, "-", "", 1)
+}
+
+// hasPrefixFold сообщает, что строка начинается с prefix без учета регистра
+func hasPrefixFold(line []byte, prefix string) bool {
+	return len(line) >= len(prefix) && strings.EqualFold(string(line[:len(prefix)]), prefix)
+}
+
+// parseShareHeader разбирает строку ss1:порог-всего:номер:отпечаток[:набор]
+func parseShareHeader(line string) (*shamirShare, error) {
+	parts := strings.Split(strings.TrimPrefix(line, sharePrefix), ":")
+	s := &shamirShare{}
+	if len(parts) == 4 && (parts[3] == shareSetExecutor || parts[3] == shareSetActivation) {
+		s.set, parts = parts[3], parts[:3]
+	}
+	if len(parts) != 3 {
+		return nil, errorf("некорректный заголовок доли %q: ожидается %sпорог-всего:номер:отпечаток[:набор]", line, sharePrefix)
+	}
+	s.fingerprint = parts[2]
+	scheme := strings.SplitN(parts[0], "-", 2)
+	var errs [3]error
+	s.threshold, errs[0] = strconv.Atoi(scheme[0])
+	if len(scheme) == 2 {
+		s.total, errs[1] = strconv.Atoi(scheme[1])
+	}
+	s.index, errs[2] = strconv.Atoi(parts[1])
+	for _, err := range errs {
+		if err != nil {
+			return nil, errorf("некорректный заголовок доли %q", line)
+		}
+	}
+	// Ключ активации не делится: это одна запись 1 из 1
+	minThreshold := 2
+	if s.set == shareSetActivation {
+		minThreshold = 1
+	}
+	if s.threshold < minThreshold || s.threshold > s.total || s.total > 255 || s.index < 1 || s.index > s.total || len(s.fingerprint) != 16 {
+		return nil, errorf("некорректный заголовок доли %q", line)
+	}
+	return s, nil
+}
+
+// interpolateShares собирает секрет из долей одного набора. Повторы одной
+// доли не считаются; ok ложно, если разных долей меньше порога.
+func interpolateShares(shares []*shamirShare) (secret []byte, distinct int, ok bool) {
+	seen := make(map[int]bool)
+	var xs []byte
+	var data [][]byte
+	for _, s := range shares {
+		if seen[s.index] {
+			continue
+		}
+		seen[s.index] = true
+		if len(xs) < s.threshold {
+			xs = append(xs, byte(s.index))
+			data = append(data, s.data)
+		}
+	}
+	if len(shares) == 0 || len(xs) < shares[0].threshold {
+		return nil, len(seen), false
+	}
+	return shamirCombine(xs, data), len(seen), true
+}
+
+// combineShares собирает мастер-сид из долей одного комплекта: из долей
+// хранителей или, если их не хватает, из долей исполнителей и ключа
+// активации - и сверяет его отпечаток с заголовками долей
+func combineShares(shares []*shamirShare) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errorf("доли не найдены: запись доли начинается со строки %s", sharePrefix)
+	}
+	first := shares[0]
+	sets := make(map[string][]*shamirShare)
+	for _, s := range shares {
+		if s.fingerprint != first.fingerprint {
+			return nil, errorf("доли из разных комплектов: %s и %s", first.header(), s.header())
+		}
+		if prev := sets[s.set]; len(prev) > 0 && (prev[0].threshold != s.threshold || prev[0].total != s.total) {
+			return nil, errorf("доли из разных комплектов: %s и %s", prev[0].header(), s.header())
+		}
+		sets[s.set] = append(sets[s.set], s)
+	}
+
+	master, custodians, ok := interpolateShares(sets[shareSetCustodian])
+	if !ok {
+		executors := sets[shareSetExecutor]
+		var value []byte
+		var distinct int
+		if value, distinct, ok = interpolateShares(executors); !ok {
+			return nil, missingShares(sets, custodians, distinct)
+		}
+		defer wipe(value)
+		activation := sets[shareSetActivation]
+		if len(activation) == 0 {
+			return nil, errorf("доли исполнителей: %d из %d, но нет ключа активации: его выдают только после объявленной активации", distinct, executors[0].total)
+		}
+		master = newSecret(len(value))
+		for i := range master {
+			master[i] = value[i] ^ activation[0].data[i]
+		}
+	}
+	if fp := masterFingerprint(master); fp != first.fingerprint {
+		wipe(master)
+		return nil, mismatchf("собранный мастер-сид имеет отпечаток %s вместо %s: доли из разных комплектов одного мастер-сида", fp, first.fingerprint)
+	}
+	return master, nil
+}
+
+// missingShares описывает, каких долей не хватает для сборки
+func missingShares(sets map[string][]*shamirShare, custodians, executors int) error {
+	if len(sets[shareSetExecutor]) == 0 {
+		for _, s := range sets[shareSetCustodian] {
+			return errorf("нужно долей: %d из %d, а получено разных: %d", s.threshold, s.total, custodians)
+		}
+		return errorf("нет ни долей хранителей, ни долей исполнителей: ключ активации сам мастер-сид не восстанавливает")
+	}
+	threshold := kitThreshold
+	if c := sets[shareSetCustodian]; len(c) > 0 {
+		threshold = c[0].threshold
+	}
+	e := sets[shareSetExecutor][0]
+	return errorf("нужно долей хранителей: %d или долей исполнителей: %d из %d с ключом активации, а получено разных долей хранителей: %d, исполнителей: %d",
+		threshold, e.threshold, e.total, custodians, executors)
+}
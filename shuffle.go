@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// shuffleAlgorithm - версия алгоритма перемешивания: поток HMAC-SHA512
+// из bracket, числа отбрасыванием и Фишер-Йетс от конца к началу.
+// Меняется при любом изменении, которое дает другой порядок.
+const shuffleAlgorithm = "seedgen-shuffle/v1"
+
+// shuffleBundle - перемешанный список с данными для проверки: зная
+// мастер-сид и исходный файл, любой может повторить перемешивание
+type shuffleBundle struct {
+	Kind        string   `json:"kind"`
+	Algorithm   string   `json:"algorithm"`
+	Version     string   `json:"version"`
+	Label       string   `json:"label"`
+	Input       string   `json:"input_sha256"`
+	Header      string   `json:"header,omitempty"`
+	Fingerprint string   `json:"master_fingerprint"`
+	Order       []string `json:"order"`
+}
+
+// shuffleList перемешивает строки списка; заголовок, если он есть, остается первым
+func shuffleList(master []byte, label string, names []string, header bool) (string, []string) {
+	var head string
+	if header {
+		head, names = names[0], names[1:]
+	}
+	order := append([]string(nil), names...)
+	newDrawStream(master, "shuffle", label).shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	return head, order
+}
+
+// runShuffle перемешивает список участников или проверяет готовое перемешивание
+func runShuffle(args []string) error {
+	fs := newFlagSet("shuffle")
+	in := fs.String("in", "", "файл списка: одна запись на строку (например, строка CSV)")
+	header := fs.Bool("header", false, "первая строка - заголовок CSV, она не перемешивается")
+	label := fs.String("label", "shuffle", "метка перемешивания: разные метки дают независимые порядки из одного мастер-сида")
+	bundlePath := fs.String("bundle", "", "записать перемешанный список с данными для проверки в файл JSON")
+	verifyPath := fs.String("verify", "", "проверить файл, записанный --bundle, повторив перемешивание")
+	format := fs.String("format", "text", "формат вывода: text или json")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return errorf("укажите файл списка через --in")
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+
+	var expected *shuffleBundle
+	if *verifyPath != "" {
+		data, err := os.ReadFile(*verifyPath)
+		if err != nil {
+			return err
+		}
+		expected = &shuffleBundle{}
+		if err := json.Unmarshal(data, expected); err != nil {
+			return errorf("не удалось разобрать %s: %w", *verifyPath, err)
+		}
+		if expected.Kind != "shuffle" {
+			return errorf("%s не является результатом shuffle", *verifyPath)
+		}
+		if expected.Algorithm != shuffleAlgorithm {
+			return errorf("перемешивание выполнено алгоритмом %s, а эта версия поддерживает %s", expected.Algorithm, shuffleAlgorithm)
+		}
+		// Метка и заголовок берутся из проверяемого файла
+		*label = expected.Label
+		*header = expected.Header != ""
+	}
+
+	names, digest, err := readNameList(*in)
+	if err != nil {
+		return err
+	}
+	need := 2
+	if *header {
+		need = 3
+	}
+	if len(names) < need {
+		return errorf("в списке должно быть хотя бы 2 записи")
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	head, order := shuffleList(master, *label, names, *header)
+	b := shuffleBundle{
+		Kind:        "shuffle",
+		Algorithm:   shuffleAlgorithm,
+		Version:     buildVersion(),
+		Label:       *label,
+		Input:       digest,
+		Header:      head,
+		Fingerprint: masterFingerprint(master),
+		Order:       order,
+	}
+
+	if expected != nil {
+		if expected.Input != b.Input {
+			return mismatchf("список не совпадает с перемешанным: SHA-256 %s, а в файле %s", b.Input, expected.Input)
+		}
+		if expected.Fingerprint != b.Fingerprint {
+			return mismatchf("мастер-сид не совпадает: отпечаток %s, а в файле %s", b.Fingerprint, expected.Fingerprint)
+		}
+		if len(expected.Order) != len(b.Order) {
+			return mismatchf("порядок не совпадает: в файле %d записей, а получено %d", len(expected.Order), len(b.Order))
+		}
+		for i := range b.Order {
+			if expected.Order[i] != b.Order[i] {
+				return mismatchf("порядок не совпадает: место %d в файле занимает %q, а получено %q", i+1, expected.Order[i], b.Order[i])
+			}
+		}
+		fmt.Printf(tr("✓ Порядок совпадает: записей %d, метка %q, мастер-сид %s...\n"), len(b.Order), b.Label, b.Fingerprint)
+		return nil
+	}
+
+	if *bundlePath != "" {
+		data, err := stampedJSON(b)
+		if err != nil {
+			return err
+		}
+		if err := writeNewFile(*bundlePath, data, 0644); err != nil {
+			return err
+		}
+	}
+	if *format == "json" {
+		return writeOutput(os.Stdout, b)
+	}
+
+	fmt.Printf(tr("Перемешивание %q: записей %d, алгоритм %s\n"), b.Label, len(b.Order), b.Algorithm)
+	fmt.Printf(tr("Мастер-сид: %s..., список: SHA-256 %s\n"), emph(b.Fingerprint), b.Input)
+	fmt.Println()
+	if head != "" {
+		fmt.Printf("      %s\n", head)
+	}
+	for i, name := range b.Order {
+		fmt.Printf("  %3d. %s\n", i+1, name)
+	}
+	if *bundlePath != "" {
+		fmt.Println()
+		fmt.Printf(tr("✓ Данные для проверки записаны в %s: seedgen shuffle --verify %s --in %s --master ...\n"), *bundlePath, *bundlePath, *in)
+	}
+	return nil
+}
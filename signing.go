@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"strings"
+)
+
+// generateSigningKey создает ключ Ed25519 и сохраняет закрытый ключ в path
+// (PKCS#8 PEM), а открытый - в path+".pub" (PKIX PEM)
+func generateSigningKey(path string) (ed25519.PublicKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, withCode(exitCrypto, errorf("ошибка генерации ключа: %w", err))
+	}
+	if err := writeKeyPair(path, priv); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// marshalKeyPairPEM кодирует закрытый ключ в PKCS#8 PEM, а открытый - в PKIX PEM
+func marshalKeyPairPEM(priv ed25519.PrivateKey) (privPEM, pubPEM []byte, err error) {
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), nil
+}
+
+// writeKeyPair сохраняет закрытый ключ в path, а открытый - в path+".pub"
+func writeKeyPair(path string, priv ed25519.PrivateKey) error {
+	privPEM, pubPEM, err := marshalKeyPairPEM(priv)
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(path, privPEM, 0600); err != nil {
+		return err
+	}
+	return writeNewFile(path+".pub", pubPEM, 0644)
+}
+
+// writeNewFile записывает файл, отказываясь перезаписать существующий
+func writeNewFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadSigningKey читает закрытый ключ Ed25519 из PEM-файла
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errorf("%s: ожидался PEM-блок PRIVATE KEY", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errorf("%s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errorf("%s: ключ не является ключом Ed25519", path)
+	}
+	return priv, nil
+}
+
+// parsePublicKey принимает открытый ключ Ed25519 в hex или путь к PEM-файлу
+func parsePublicKey(s string) (ed25519.PublicKey, error) {
+	if raw, err := hex.DecodeString(s); err == nil {
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, errorf("открытый ключ Ed25519 должен занимать %d байта", ed25519.PublicKeySize)
+		}
+		return ed25519.PublicKey(raw), nil
+	}
+
+	data, err := os.ReadFile(s)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errorf("%s: ожидался PEM-блок PUBLIC KEY", s)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errorf("%s: %w", s, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errorf("%s: ключ не является ключом Ed25519", s)
+	}
+	return pub, nil
+}
+
+// keyFingerprint возвращает короткий отпечаток открытого ключа для сверки вслух
+func keyFingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	groups := make([]string, 0, 4)
+	for i := 0; i < 8; i += 2 {
+		groups = append(groups, hex.EncodeToString(sum[i:i+2]))
+	}
+	return strings.Join(groups, ":")
+}
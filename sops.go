@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// sopsFlags - флаги шифрования результата в файл SOPS. Шифрует сам sops,
+// поэтому файл расшифровывается обычным sops --decrypt, Flux или ArgoCD.
+type sopsFlags struct {
+	age *string
+	kms *string
+}
+
+// addSOPSFlags регистрирует флаги SOPS в наборе
+func addSOPSFlags(fs *flag.FlagSet) *sopsFlags {
+	return &sopsFlags{
+		age: fs.String("sops-age", "", "зашифровать результат SOPS для получателей age (age1..., через запятую); только --format json и k8s-secret"),
+		kms: fs.String("sops-kms", "", "зашифровать результат SOPS ключами облачного KMS через запятую: ARN AWS KMS, projects/.../cryptoKeys/... GCP или https://....vault.azure.net/keys/... Azure"),
+	}
+}
+
+// enabled сообщает, что результат шифруется SOPS
+func (sf *sopsFlags) enabled() bool {
+	return *sf.age != "" || *sf.kms != ""
+}
+
+// check до ввода сидов проверяет формат, ключи и доступность sops
+func (sf *sopsFlags) check(format string, kubeseal bool) error {
+	if !sf.enabled() {
+		return nil
+	}
+	if format != "json" && format != k8sSecretFormat {
+		return errorf("флаги --sops-age и --sops-kms применимы только к --format json и %s", k8sSecretFormat)
+	}
+	if kubeseal {
+		return errorf("--sops-age и --sops-kms не сочетаются с --kubeseal: SealedSecret уже зашифрован")
+	}
+	for _, r := range splitKeys(*sf.age) {
+		if !strings.HasPrefix(r, "age1") {
+			return errorf("некорректный получатель age %q: ожидается открытый ключ age1...", r)
+		}
+	}
+	if _, err := sf.keyArgs(); err != nil {
+		return err
+	}
+	// sops читает данные из файла, а seedgen передает их через /dev/stdin,
+	// чтобы мастер-сид не попадал на диск
+	if runtime.GOOS == "windows" {
+		return errorf("шифрование SOPS недоступно в Windows: sops получает данные через /dev/stdin")
+	}
+	if _, err := exec.LookPath("sops"); err != nil {
+		return errorf("--sops-age и --sops-kms требуют sops")
+	}
+	return nil
+}
+
+// splitKeys разбирает список ключей через запятую
+func splitKeys(list string) []string {
+	var keys []string
+	for _, k := range strings.Split(list, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// keyArgs раскладывает ключи --sops-kms по флагам sops. Облако
+// определяется по виду ключа, как в --kms-key.
+func (sf *sopsFlags) keyArgs() ([]string, error) {
+	var aws, gcp, azure []string
+	for _, k := range splitKeys(*sf.kms) {
+		switch {
+		case strings.HasPrefix(k, "projects/"):
+			gcp = append(gcp, k)
+		case strings.HasPrefix(k, "https://"):
+			azure = append(azure, k)
+		case strings.HasPrefix(k, "arn:"):
+			aws = append(aws, k)
+		default:
+			return nil, errorf("ключ AWS KMS для SOPS задается полным ARN, получено %q", k)
+		}
+	}
+	var args []string
+	if age := splitKeys(*sf.age); len(age) > 0 {
+		args = append(args, "--age", strings.Join(age, ","))
+	}
+	if len(aws) > 0 {
+		args = append(args, "--kms", strings.Join(aws, ","))
+	}
+	if len(gcp) > 0 {
+		args = append(args, "--gcp-kms", strings.Join(gcp, ","))
+	}
+	if len(azure) > 0 {
+		args = append(args, "--azure-kv", strings.Join(azure, ","))
+	}
+	return args, nil
+}
+
+// describe дополняет описание формата для --dry-run
+func (sf *sopsFlags) describe(format string) string {
+	if !sf.enabled() {
+		return format
+	}
+	return format + tr(", зашифрован SOPS")
+}
+
+// write выводит результат функцией write или, с --sops-age и --sops-kms,
+// шифрует его sops. Шифруется только мастер-сид (поле master результата
+// или data манифеста Secret): отпечаток, параметры и метаданные остаются
+// открытыми и видны в истории репозитория.
+func (sf *sopsFlags) write(w io.Writer, format string, write func(io.Writer) error) error {
+	if !sf.enabled() {
+		return write(w)
+	}
+	// Буфер заранее больше результата и не перераспределяется при записи
+	plain := bytes.NewBuffer(newSecret(16 << 10)[:0])
+	defer func() { wipe(plain.Bytes()[:plain.Cap()]) }()
+	if err := write(plain); err != nil {
+		return err
+	}
+	keys, err := sf.keyArgs()
+	if err != nil {
+		return err
+	}
+	kind, regex := "json", "^master$"
+	if format == k8sSecretFormat {
+		kind, regex = "yaml", "^(data|stringData)$"
+	}
+	args := append([]string{"--encrypt", "--input-type", kind, "--output-type", kind, "--encrypted-regex", regex}, keys...)
+	out, err := runSOPS(plain.Bytes(), append(args, "/dev/stdin")...)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// runSOPS запускает sops с данными в stdin и возвращает его вывод
+func runSOPS(data []byte, args ...string) ([]byte, error) {
+	logDebug("запуск sops", "command", args[0])
+	cmd := exec.Command("sops", args...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		wipe(stdout.Bytes())
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errorf("sops: %s", msg)
+		}
+		return nil, errorf("sops: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// isSOPSFile сообщает, что артефакт JSON зашифрован SOPS
+func isSOPSFile(data []byte) bool {
+	var probe struct {
+		Sops json.RawMessage `json:"sops"`
+	}
+	return json.Unmarshal(data, &probe) == nil && len(probe.Sops) > 0
+}
+
+// decryptSOPS расшифровывает артефакт JSON командой sops --decrypt. Ключи
+// для расшифровки sops находит сам: SOPS_AGE_KEY_FILE, учетные данные
+// облака и т. п.
+func decryptSOPS(data []byte) ([]byte, error) {
+	if _, err := exec.LookPath("sops"); err != nil {
+		return nil, errorf("артефакт зашифрован SOPS: для расшифровки нужен sops")
+	}
+	return runSOPS(data, "--decrypt", "--input-type", "json", "--output-type", "json", "/dev/stdin")
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// masterSources - хранилища, из которых readMaster берет мастер-сид
+// по ссылке вида "хранилище:имя"
+var masterSources = map[string]func(name string) ([]byte, error){
+	"keychain":   loadKeychain,
+	"keyring":    loadKeyring,
+	"pkcs11":     loadPKCS11,
+	"credential": loadCredential,
+	"vault":      loadVault,
+	"pass":       loadPass,
+	"op":         loadOnePassword,
+}
+
+// keyringPrefix предшествует имени записи в описании ключа ядра
+const keyringPrefix = "seedgen:"
+
+// masterArtifacts - артефакты, в которых мастер-сид зашифрован хранилищем.
+// readMaster открывает их по полю kind.
+var masterArtifacts = map[string]func(data []byte) ([]byte, error){
+	"tpm-sealed":      openTPMArtifact,
+	"dpapi-protected": openDPAPIArtifact,
+	kmsEnvelopeKind:   openKMSArtifact,
+}
+
+// readMasterSource читает мастер-сид из хранилища, если ref на него ссылается
+func readMasterSource(ref string) ([]byte, bool, error) {
+	i := strings.IndexByte(ref, ':')
+	if i < 0 {
+		return nil, false, nil
+	}
+	load, ok := masterSources[ref[:i]]
+	if !ok {
+		return nil, false, nil
+	}
+	if ref[i+1:] == "" {
+		return nil, true, errorf("в ссылке %q не указано имя записи", ref)
+	}
+	master, err := load(ref[i+1:])
+	if err != nil {
+		return nil, true, err
+	}
+	master, err = checkMasterSize(master)
+	if err == nil {
+		logDebug("мастер-сид из хранилища", "store", ref[:i], "fingerprint", masterFingerprint(master))
+	}
+	return master, true, err
+}
+
+// storeFlags - флаги сохранения результата во внешних хранилищах
+type storeFlags struct {
+	tpm        *tpmFlags
+	keychain   *string
+	dpapi      *string
+	dpapiScope *string
+	keyring    *string
+	keyringIn  *string
+	keyringTTL *time.Duration
+	pkcs11     *string
+	credential *string
+	vault      *string
+	kms        *kmsFlags
+	manager    *string
+	// vaultClient - клиент Vault, в который check вошел до ввода сидов
+	vaultClient *vaultClient
+	// passwordManager - запись, которую check проверил до ввода сидов
+	passwordManager passwordManager
+}
+
+// addStoreFlags регистрирует флаги хранилищ в наборе
+func addStoreFlags(fs *flag.FlagSet) *storeFlags {
+	return &storeFlags{
+		tpm:        addTPMFlags(fs),
+		keychain:   fs.String("store-keychain", "", "сохранить мастер-сид в связку ключей macOS под этим именем (доступ по Touch ID)"),
+		dpapi:      fs.String("store-dpapi", "", "зашифровать мастер-сид Windows DPAPI и сохранить в файл"),
+		dpapiScope: fs.String("dpapi-scope", "user", "область DPAPI: user (текущий пользователь) или machine (компьютер)"),
+		keyring:    fs.String("store-keyring", "", "поместить мастер-сид в связку ключей ядра Linux под этим именем"),
+		keyringIn:  fs.String("keyring", "session", "связка ключей ядра: session (сеанс) или user (пользователь)"),
+		keyringTTL: fs.Duration("keyring-ttl", time.Hour, "срок хранения в связке ключей ядра (0 - без срока)"),
+		pkcs11:     fs.String("store-pkcs11", "", "импортировать мастер-сид в токен PKCS#11 как неизвлекаемый ключ (ссылка pkcs11:...)"),
+		credential: fs.String("store-credential", "", "зашифровать мастер-сид как учетные данные systemd (systemd-creds) в файл"),
+		kms:        addKMSFlags(fs),
+		vault:      fs.String("vault-write", "", "записать мастер-сид в секрет KV HashiCorp Vault, например secret/championship/master (адрес и вход - VAULT_ADDR, VAULT_TOKEN или AppRole)"),
+		manager:    fs.String("store", "", "записать мастер-сид в менеджер паролей: pass:путь (password-store) или op://хранилище/запись (1Password)"),
+	}
+}
+
+// check до ввода сидов проверяет, что выбранные хранилища доступны,
+// чтобы церемония не завершилась без сохраненного результата
+func (sf *storeFlags) check() error {
+	if *sf.tpm.out != "" {
+		if _, err := exec.LookPath("tpm2_create"); err != nil {
+			return errorf("--seal-tpm требует tpm2-tools (tpm2_create не найден)")
+		}
+	}
+	if *sf.keychain != "" && !keychainSupported {
+		return errorf("--store-keychain: связка ключей доступна только в macOS при сборке с cgo")
+	}
+	if *sf.dpapi != "" {
+		if !dpapiSupported {
+			return errorf("--store-dpapi: DPAPI доступен только в Windows")
+		}
+		if *sf.dpapiScope != "user" && *sf.dpapiScope != "machine" {
+			return errorf("неизвестная область DPAPI %q, доступны: user, machine", *sf.dpapiScope)
+		}
+	}
+	if *sf.keyring != "" {
+		if !keyringSupported {
+			return errorf("--store-keyring: связка ключей ядра доступна только в Linux")
+		}
+		if *sf.keyringIn != "session" && *sf.keyringIn != "user" {
+			return errorf("неизвестная связка ключей %q, доступны: session, user", *sf.keyringIn)
+		}
+		if *sf.keyringTTL < 0 || *sf.keyringTTL%time.Second != 0 {
+			return errorf("--keyring-ttl задается целым числом секунд")
+		}
+	}
+	if *sf.pkcs11 != "" {
+		if !pkcs11Supported {
+			return errorf("--store-pkcs11: токены PKCS#11 доступны только в Linux и macOS при сборке с cgo")
+		}
+		u, err := parsePKCS11URI(*sf.pkcs11)
+		if err != nil {
+			return errorf("--store-pkcs11: %w", err)
+		}
+		wipe(u.pin)
+	}
+	if *sf.credential != "" {
+		if _, err := exec.LookPath("systemd-creds"); err != nil {
+			return errorf("--store-credential требует systemd-creds (systemd 250+)")
+		}
+	}
+	if err := sf.kms.check(); err != nil {
+		return err
+	}
+	if *sf.vault != "" {
+		c, err := newVaultClient()
+		if err != nil {
+			return errorf("--vault-write: %w", err)
+		}
+		if err := c.checkFree(*sf.vault); err != nil {
+			return errorf("--vault-write: %w", err)
+		}
+		sf.vaultClient = c
+	}
+	if *sf.manager != "" {
+		pm, err := passwordManagerFor(*sf.manager)
+		if err == nil {
+			err = pm.checkFree()
+		}
+		if err != nil {
+			return errorf("--store: %w", err)
+		}
+		sf.passwordManager = pm
+	}
+	return nil
+}
+
+// any сообщает, что выбрано хотя бы одно хранилище
+func (sf *storeFlags) any() bool {
+	return *sf.tpm.out != "" || *sf.keychain != "" || *sf.dpapi != "" || *sf.keyring != "" || *sf.pkcs11 != "" || *sf.credential != "" || *sf.kms.key != "" || *sf.vault != "" || *sf.manager != ""
+}
+
+// withheld сообщает, что мастер-сид не нужно печатать: он уходит в хранилище,
+// а stdout не терминал (например, журнал службы) и --show не указан
+func (sf *storeFlags) withheld(rf *revealFlags) bool {
+	return sf.any() && !*rf.show && !isTerminal(os.Stdout)
+}
+
+// store сохраняет мастер-сид во все выбранные хранилища и сообщает об этом в w
+func (sf *storeFlags) store(w io.Writer, master []byte) error {
+	if err := sf.tpm.seal(w, master); err != nil {
+		return err
+	}
+	if *sf.keychain != "" {
+		if err := storeKeychain(*sf.keychain, master); err != nil {
+			return errorf("ошибка сохранения в связку ключей: %w", err)
+		}
+		fmt.Fprintf(w, tr("✓ Мастер-сид сохранен в связку ключей: keychain:%s\n"), *sf.keychain)
+	}
+	if *sf.dpapi != "" {
+		protected, err := protectMaster(master, *sf.dpapiScope)
+		if err != nil {
+			return errorf("ошибка шифрования DPAPI: %w", err)
+		}
+		data, err := json.MarshalIndent(protected, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeNewFile(*sf.dpapi, append(data, '\n'), 0600); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, tr("✓ Мастер-сид зашифрован DPAPI (область %s): %s\n"), *sf.dpapiScope, *sf.dpapi)
+	}
+	if *sf.keyring != "" {
+		if err := storeKeyring(*sf.keyring, *sf.keyringIn, *sf.keyringTTL, master); err != nil {
+			return errorf("ошибка сохранения в связку ключей ядра: %w", err)
+		}
+		fmt.Fprintf(w, tr("✓ Мастер-сид помещен в связку ключей %s: keyring:%s"), *sf.keyringIn, *sf.keyring)
+		if *sf.keyringTTL > 0 {
+			fmt.Fprintf(w, tr(" (срок хранения %s)"), *sf.keyringTTL)
+		}
+		fmt.Fprintln(w)
+	}
+	if *sf.pkcs11 != "" {
+		u, err := parsePKCS11URI(*sf.pkcs11)
+		if err != nil {
+			return err
+		}
+		defer wipe(u.pin)
+		if err := importPKCS11(u, master, masterFingerprint(master)); err != nil {
+			return errorf("ошибка импорта в токен PKCS#11: %w", err)
+		}
+		fmt.Fprintf(w, tr("✓ Мастер-сид импортирован в токен PKCS#11 как неизвлекаемый ключ %q\n"), u.object)
+	}
+	if *sf.credential != "" {
+		if err := storeCredential(*sf.credential, master); err != nil {
+			return errorf("ошибка шифрования учетных данных: %w", err)
+		}
+		fmt.Fprintf(w, tr("✓ Мастер-сид зашифрован как учетные данные systemd: %s\n"), *sf.credential)
+	}
+	if err := sf.kms.seal(w, master); err != nil {
+		return err
+	}
+	if *sf.vault != "" {
+		if err := sf.vaultClient.writeMaster(*sf.vault, master); err != nil {
+			return errorf("ошибка записи в Vault: %w", err)
+		}
+		fmt.Fprintf(w, tr("✓ Мастер-сид записан в Vault: vault:%s\n"), strings.Trim(*sf.vault, "/"))
+	}
+	if sf.passwordManager != nil {
+		if err := sf.passwordManager.writeMaster(master); err != nil {
+			return errorf("ошибка записи в менеджер паролей: %w", err)
+		}
+		fmt.Fprintf(w, tr("✓ Мастер-сид записан в менеджер паролей: %s\n"), sf.passwordManager.ref())
+	}
+	return nil
+}
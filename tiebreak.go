@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// tiebreakEntry - участник жребия и его число потока (hex), меньшее выше
+type tiebreakEntry struct {
+	Place int    `json:"place"`
+	Name  string `json:"name"`
+	Lot   string `json:"lot"`
+	lot   uint64
+}
+
+// tiebreak - протокол жребия вместо подброшенной монеты
+type tiebreak struct {
+	Kind        string          `json:"kind"`
+	Context     string          `json:"context"`
+	Fingerprint string          `json:"master_fingerprint"`
+	Winner      string          `json:"winner"`
+	Order       []tiebreakEntry `json:"order"`
+	Audit       string          `json:"audit"`
+}
+
+// tiebreakLabel привязывает жребий к поводу и составу участников: другой
+// повод или другой набор участников дают независимый жребий
+func tiebreakLabel(context string, names []string) string {
+	return context + "\n" + strings.Join(names, "\n")
+}
+
+// drawTiebreak тянет каждому участнику по порядку имен (порядок байтов)
+// 8 байт потока; выше тот, у кого число меньше
+func drawTiebreak(master []byte, context string, names []string) tiebreak {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	stream := newDrawStream(master, "tiebreak", tiebreakLabel(context, sorted))
+	entries := make([]tiebreakEntry, len(sorted))
+	for i, name := range sorted {
+		v := stream.uint64()
+		entries[i] = tiebreakEntry{Name: name, Lot: fmt.Sprintf("%016x", v), lot: v}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].lot < entries[j].lot })
+	var lots []string
+	for i := range entries {
+		entries[i].Place = i + 1
+		lots = append(lots, entries[i].Name+"="+entries[i].Lot)
+	}
+	t := tiebreak{
+		Kind:        "tiebreak",
+		Context:     context,
+		Fingerprint: masterFingerprint(master),
+		Winner:      entries[0].Name,
+		Order:       entries,
+	}
+	t.Audit = fmt.Sprintf(tr("seedgen tiebreak %s: %q, мастер-сид %s..., жребий %s -> %s"), buildVersion(), context, t.Fingerprint, strings.Join(lots, " "), t.Winner)
+	return t
+}
+
+// splitBetween превращает "--between A B C" в повторяющийся флаг, чтобы
+// участников можно было перечислить подряд
+func splitBetween(args []string) []string {
+	var out []string
+	inList := false
+	for _, a := range args {
+		switch {
+		case a == "--between" || a == "-between":
+			inList = true
+			continue
+		case strings.HasPrefix(a, "-"):
+			inList = false
+		case inList:
+			out = append(out, "--between", a)
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// runTiebreak разрешает равенство жребием из мастер-сида, привязанным к поводу
+func runTiebreak(args []string) error {
+	fs := newFlagSet("tiebreak")
+	var between stringList
+	fs.Var(&between, "between", "участники жребия подряд: --between A B [C ...]")
+	context := fs.String("context", "", "повод жребия, например \"группа C, 2-е место\": от него зависит результат")
+	format := fs.String("format", "text", "формат вывода: text или json")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, splitBetween(args)); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errorf("лишние аргументы: %s", strings.Join(fs.Args(), " "))
+	}
+	if *format != "text" && *format != "json" {
+		return errorf("неизвестный формат %q", *format)
+	}
+	if strings.TrimSpace(*context) == "" {
+		return errorf("укажите повод жребия через --context: без него один и тот же жребий можно было бы применить к любому спору")
+	}
+	if len(between) < 2 {
+		return errorf("укажите хотя бы двух участников через --between")
+	}
+	seen := make(map[string]bool)
+	for _, name := range between {
+		if strings.TrimSpace(name) == "" || strings.Contains(name, "\n") {
+			return errorf("некорректное имя участника %q", name)
+		}
+		if seen[name] {
+			return errorf("участник %q указан дважды", name)
+		}
+		seen[name] = true
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	t := drawTiebreak(master, *context, between)
+	if *format == "json" {
+		return writeOutput(os.Stdout, t)
+	}
+	fmt.Printf(tr("Жребий %q: участников %d, мастер-сид %s...\n"), t.Context, len(t.Order), t.Fingerprint)
+	fmt.Println()
+	for _, e := range t.Order {
+		fmt.Printf(tr("  %d. %s (жребий %s)\n"), e.Place, e.Name, e.Lot)
+	}
+	fmt.Println()
+	fmt.Printf(tr("✓ Выше: %s\n"), t.Winner)
+	fmt.Println()
+	fmt.Println(tr("Строка для протокола:"))
+	fmt.Println(t.Audit)
+	return nil
+}
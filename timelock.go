@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Цепочка drand quicknet League of Entropy: раунды без связи с предыдущими
+// и подписи в G1, как требует tlock. Раунд r публикуется в момент
+// genesis + (r-1)·period.
+const (
+	timelockChain   = "52db9ba70e0cc0f6eaf7803dd07447a1f5477735fd3f661792ba94600c84e971"
+	timelockGenesis = 1692803367
+	timelockPeriod  = 3
+)
+
+// timelockArmor начинает файл age в текстовой обертке, которую выводит tle --armor
+const timelockArmor = "-----BEGIN AGE ENCRYPTED FILE-----"
+
+// timelockFlags - флаг шифрования результата tlock до раунда drand. Шифрует
+// tle: ключ раунда - его будущая подпись BLS, поэтому до публикации раунда
+// результат не расшифровать никому, в том числе тому, кто его зашифровал.
+type timelockFlags struct {
+	at *string
+}
+
+// addTimelockFlags регистрирует флаг tlock в наборе
+func addTimelockFlags(fs *flag.FlagSet) *timelockFlags {
+	return &timelockFlags{
+		at: fs.String("timelock", "", "зашифровать результат tlock до момента ГГГГ-ММ-ДДTЧЧ:ММZ или номера раунда drand quicknet; до него результат не расшифровать никому; только --format json, msv2 и rs, нужен tle"),
+	}
+}
+
+// enabled сообщает, что результат шифруется tlock
+func (tf *timelockFlags) enabled() bool {
+	return *tf.at != ""
+}
+
+// round возвращает раунд quicknet, начиная с которого результат
+// расшифровывается, - первый раунд не раньше заданного момента
+func (tf *timelockFlags) round() (uint64, error) {
+	if r, err := strconv.ParseUint(*tf.at, 10, 64); err == nil && r > 0 {
+		return r, nil
+	}
+	var at time.Time
+	var err error
+	for _, layout := range []string{"2006-01-02T15:04Z07:00", time.RFC3339} {
+		if at, err = time.Parse(layout, *tf.at); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return 0, errorf("некорректный момент %q: ожидается ГГГГ-ММ-ДДTЧЧ:ММZ, RFC 3339 или номер раунда", *tf.at)
+	}
+	elapsed := at.Unix() - timelockGenesis
+	if elapsed <= 0 {
+		return 1, nil
+	}
+	return uint64((elapsed+timelockPeriod-1)/timelockPeriod) + 1, nil
+}
+
+// timelockRoundTime возвращает момент публикации раунда quicknet
+func timelockRoundTime(round uint64) time.Time {
+	return time.Unix(timelockGenesis+int64(round-1)*timelockPeriod, 0).UTC()
+}
+
+// check до ввода сидов проверяет формат, момент и доступность tle
+func (tf *timelockFlags) check(format string, sops bool) error {
+	if !tf.enabled() {
+		return nil
+	}
+	switch format {
+	case "json", "msv2", "rs":
+	default:
+		return errorf("--timelock применим только к --format json, msv2 и rs")
+	}
+	if sops {
+		return errorf("--timelock не сочетается с --sops-age и --sops-kms: результат шифруется одним способом")
+	}
+	return tf.checkRound("timelock")
+}
+
+// checkRound проверяет, что раунд еще не опубликован и tle установлен;
+// name - имя флага для сообщений
+func (tf *timelockFlags) checkRound(name string) error {
+	round, err := tf.round()
+	if err != nil {
+		return err
+	}
+	if at := timelockRoundTime(round); !at.After(time.Now()) {
+		return errorf("раунд %d drand уже опубликован (%s): результат расшифровал бы кто угодно", round, at.Format(time.RFC3339))
+	}
+	if _, err := exec.LookPath("tle"); err != nil {
+		return errorf("--%s требует tle (github.com/drand/tlock)", name)
+	}
+	return nil
+}
+
+// describe дополняет описание формата для --dry-run
+func (tf *timelockFlags) describe(format string) string {
+	if !tf.enabled() {
+		return format
+	}
+	round, _ := tf.round()
+	return format + fmt.Sprintf(tr(", заперт tlock до раунда %d drand (%s)"), round, timelockRoundTime(round).Format(time.RFC3339))
+}
+
+// write выводит результат функцией write или, с --timelock, шифрует его
+// tle целиком: в отличие от SOPS, до раунда не видно даже отпечатка
+func (tf *timelockFlags) write(w io.Writer, write func(io.Writer) error) error {
+	if !tf.enabled() {
+		return write(w)
+	}
+	round, err := tf.round()
+	if err != nil {
+		return err
+	}
+	// Буфер заранее больше результата и не перераспределяется при записи
+	plain := bytes.NewBuffer(newSecret(16 << 10)[:0])
+	defer func() { wipe(plain.Bytes()[:plain.Cap()]) }()
+	if err := write(plain); err != nil {
+		return err
+	}
+	out, err := sealTimelock(plain.Bytes(), round)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(out); err != nil {
+		return err
+	}
+	logInfof("✓ Результат заперт tlock до раунда %d drand quicknet (%s)\n", round, timelockRoundTime(round).Format(time.RFC3339))
+	return nil
+}
+
+// sealTimelock шифрует данные tle до раунда quicknet в текстовой обертке
+func sealTimelock(plain []byte, round uint64) ([]byte, error) {
+	return runTLE(plain, "--encrypt", "--armor", "--chain", timelockChain, "--round", strconv.FormatUint(round, 10))
+}
+
+// runTLE запускает tle с данными в stdin и возвращает его вывод
+func runTLE(data []byte, args ...string) ([]byte, error) {
+	logDebug("запуск tle", "command", args[0])
+	cmd := exec.Command("tle", args...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		wipe(stdout.Bytes())
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errorf("tle: %s", msg)
+		}
+		return nil, errorf("tle: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// timelockStanza находит в файле age с оберткой получателя tlock:
+// строку "-> tlock РАУНД ЦЕПОЧКА" заголовка. ok ложно для других файлов,
+// в том числе age для обычных получателей.
+func timelockStanza(data []byte) (round uint64, chain string, ok bool) {
+	text := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(text, []byte(timelockArmor)) {
+		return 0, "", false
+	}
+	var body strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "-----") {
+			body.WriteString(line)
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(body.String())
+	if err != nil {
+		return 0, "", false
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if line == "---" || strings.HasPrefix(line, "--- ") {
+			break
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 4 && fields[0] == "->" && fields[1] == "tlock" {
+			if round, err = strconv.ParseUint(fields[2], 10, 64); err == nil {
+				return round, fields[3], true
+			}
+		}
+	}
+	return 0, "", false
+}
+
+// decryptTimelock расшифровывает результат, запертый --timelock, командой
+// tle --decrypt. До публикации раунда quicknet отказывает сразу, не
+// обращаясь к сети.
+func decryptTimelock(data []byte, round uint64, chain string) ([]byte, error) {
+	if chain == timelockChain {
+		if at := timelockRoundTime(round); at.After(time.Now()) {
+			return nil, errorf("результат заперт tlock до раунда %d drand (%s): раньше его не расшифровать", round, at.Format(time.RFC3339))
+		}
+	}
+	if _, err := exec.LookPath("tle"); err != nil {
+		return nil, errorf("результат заперт tlock: для расшифровки нужен tle (github.com/drand/tlock)")
+	}
+	return runTLE(data, "--decrypt", "--chain", chain)
+}
@@ -0,0 +1,594 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Идентификаторы ASN.1 меток времени RFC 3161 и CMS (RFC 5652)
+var (
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidRSA           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidRSASHA256     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidRSASHA384     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidRSASHA512     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECPublicKey   = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidECDSASHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSASHA384   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSASHA512   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidEd25519       = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+// tsMessageImprint - хэш отмечаемых данных
+type tsMessageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// tsRequest - запрос метки времени TimeStampReq
+type tsRequest struct {
+	Version        int
+	MessageImprint tsMessageImprint
+	Nonce          *big.Int `asn1:"optional"`
+	CertReq        bool     `asn1:"optional"`
+}
+
+// newTimestampRequest готовит запрос метки времени над SHA-256 данных.
+// Запрос не содержит секретов и создается офлайн, а отправить его в службу
+// меток времени (TSA) можно позже с подключенной к сети машины.
+func newTimestampRequest(data []byte) ([]byte, error) {
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, withCode(exitCrypto, errorf("ошибка чтения системного генератора случайных чисел: %w", err))
+	}
+	digest := sha256.Sum256(data)
+	return asn1.Marshal(tsRequest{
+		Version: 1,
+		MessageImprint: tsMessageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+			HashedMessage: digest[:],
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+}
+
+// parseTimestampRequest разбирает запрос метки времени
+func parseTimestampRequest(der []byte) (*tsRequest, error) {
+	var req tsRequest
+	if rest, err := asn1.Unmarshal(der, &req); err != nil || len(rest) != 0 {
+		return nil, errorf("файл не является запросом метки времени RFC 3161")
+	}
+	return &req, nil
+}
+
+// asn1Elements разбирает SEQUENCE или SET на элементы
+func asn1Elements(der []byte) ([]asn1.RawValue, error) {
+	var outer asn1.RawValue
+	if rest, err := asn1.Unmarshal(der, &outer); err != nil || len(rest) != 0 || !outer.IsCompound {
+		return nil, errorf("некорректная структура ASN.1")
+	}
+	var out []asn1.RawValue
+	for data := outer.Bytes; len(data) > 0; {
+		var el asn1.RawValue
+		var err error
+		if data, err = asn1.Unmarshal(data, &el); err != nil {
+			return nil, errorf("некорректная структура ASN.1: %w", err)
+		}
+		out = append(out, el)
+	}
+	return out, nil
+}
+
+// explicitContent возвращает содержимое явного контекстного тега [0]
+func explicitContent(el asn1.RawValue) ([]byte, error) {
+	if el.Class != asn1.ClassContextSpecific || el.Tag != 0 {
+		return nil, errorf("ожидался тег [0]")
+	}
+	return el.Bytes, nil
+}
+
+// timestampToken - разобранная метка времени
+type timestampToken struct {
+	Imprint tsMessageImprint
+	Serial  *big.Int
+	GenTime time.Time
+	Nonce   *big.Int
+	Signer  *x509.Certificate
+	certs   []*x509.Certificate
+}
+
+// parseTimestampResponse разбирает ответ TSA (TimeStampResp) и проверяет
+// подпись CMS сертификатом из ответа. Доверие к самому сертификату
+// проверяется отдельно (verifyChain).
+func parseTimestampResponse(der []byte) (*timestampToken, error) {
+	resp, err := asn1Elements(der)
+	if err != nil || len(resp) < 1 {
+		return nil, errorf("файл не является ответом службы меток времени RFC 3161")
+	}
+	status, err := asn1Elements(resp[0].FullBytes)
+	if err != nil || len(status) < 1 {
+		return nil, errorf("некорректный статус в ответе службы меток времени")
+	}
+	var code int
+	if _, err := asn1.Unmarshal(status[0].FullBytes, &code); err != nil {
+		return nil, errorf("некорректный статус в ответе службы меток времени")
+	}
+	// 0 - granted, 1 - grantedWithMods
+	if code > 1 {
+		text := ""
+		if len(status) > 1 {
+			var free []string
+			if _, err := asn1.Unmarshal(status[1].FullBytes, &free); err == nil {
+				text = ": " + strings.Join(free, "; ")
+			}
+		}
+		return nil, errorf("служба меток времени отклонила запрос (статус %d)%s", code, text)
+	}
+	if len(resp) < 2 {
+		return nil, errorf("в ответе службы меток времени нет метки")
+	}
+	return parseTimestampToken(resp[1].FullBytes)
+}
+
+// parseTimestampToken разбирает ContentInfo с SignedData метки времени
+func parseTimestampToken(der []byte) (*timestampToken, error) {
+	info, err := asn1Elements(der)
+	if err != nil || len(info) != 2 {
+		return nil, errorf("некорректная метка времени")
+	}
+	var contentType asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(info[0].FullBytes, &contentType); err != nil || !contentType.Equal(oidSignedData) {
+		return nil, errorf("метка времени не является SignedData CMS")
+	}
+	content, err := explicitContent(info[1])
+	if err != nil {
+		return nil, errorf("некорректная метка времени: %w", err)
+	}
+
+	// SignedData: version, digestAlgorithms, encapContentInfo, [0] certificates, [1] crls, signerInfos
+	sd, err := asn1Elements(content)
+	if err != nil || len(sd) < 4 {
+		return nil, errorf("некорректная структура SignedData")
+	}
+	tstInfo, err := encapsulatedTSTInfo(sd[2])
+	if err != nil {
+		return nil, err
+	}
+	t := &timestampToken{}
+	for _, el := range sd[3 : len(sd)-1] {
+		if el.Class == asn1.ClassContextSpecific && el.Tag == 0 {
+			if t.certs, err = x509.ParseCertificates(el.Bytes); err != nil {
+				return nil, errorf("ошибка разбора сертификатов метки времени: %w", err)
+			}
+		}
+	}
+	if err := t.parseTSTInfo(tstInfo); err != nil {
+		return nil, err
+	}
+
+	signers, err := asn1Elements(sd[len(sd)-1].FullBytes)
+	if err != nil || len(signers) != 1 {
+		return nil, errorf("метка времени должна быть подписана ровно одним подписантом")
+	}
+	if err := t.verifySigner(signers[0], tstInfo); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// encapsulatedTSTInfo извлекает DER TSTInfo из encapContentInfo
+func encapsulatedTSTInfo(el asn1.RawValue) ([]byte, error) {
+	encap, err := asn1Elements(el.FullBytes)
+	if err != nil || len(encap) != 2 {
+		return nil, errorf("метка времени не содержит TSTInfo")
+	}
+	var eType asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(encap[0].FullBytes, &eType); err != nil || !eType.Equal(oidTSTInfo) {
+		return nil, errorf("метка времени не содержит TSTInfo")
+	}
+	octets, err := explicitContent(encap[1])
+	if err != nil {
+		return nil, errorf("метка времени не содержит TSTInfo")
+	}
+	var tstInfo []byte
+	if _, err := asn1.Unmarshal(octets, &tstInfo); err != nil {
+		return nil, errorf("метка времени не содержит TSTInfo")
+	}
+	return tstInfo, nil
+}
+
+// parseTSTInfo разбирает TSTInfo: version, policy, messageImprint,
+// serialNumber, genTime и необязательные accuracy, ordering, nonce, tsa
+func (t *timestampToken) parseTSTInfo(der []byte) error {
+	els, err := asn1Elements(der)
+	if err != nil || len(els) < 5 {
+		return errorf("некорректный TSTInfo")
+	}
+	if _, err := asn1.Unmarshal(els[2].FullBytes, &t.Imprint); err != nil {
+		return errorf("некорректный хэш в TSTInfo")
+	}
+	if _, err := asn1.Unmarshal(els[3].FullBytes, &t.Serial); err != nil {
+		return errorf("некорректный серийный номер в TSTInfo")
+	}
+	if _, err := asn1.UnmarshalWithParams(els[4].FullBytes, &t.GenTime, "generalized"); err != nil {
+		return errorf("некорректное время в TSTInfo")
+	}
+	for _, el := range els[5:] {
+		if el.Class == asn1.ClassUniversal && el.Tag == asn1.TagInteger {
+			if _, err := asn1.Unmarshal(el.FullBytes, &t.Nonce); err != nil {
+				return errorf("некорректный нонс в TSTInfo")
+			}
+		}
+	}
+	return nil
+}
+
+// digestHash сопоставляет идентификатор алгоритма хэша
+func digestHash(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, nil
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, nil
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, nil
+	}
+	return 0, errorf("неподдерживаемый алгоритм хэша %s", oid)
+}
+
+// signatureAlgorithm сопоставляет алгоритм подписи CMS алгоритму x509
+func signatureAlgorithm(sig asn1.ObjectIdentifier, hash crypto.Hash) (x509.SignatureAlgorithm, error) {
+	byHash := func(sha256, sha384, sha512 x509.SignatureAlgorithm) x509.SignatureAlgorithm {
+		switch hash {
+		case crypto.SHA384:
+			return sha384
+		case crypto.SHA512:
+			return sha512
+		}
+		return sha256
+	}
+	switch {
+	case sig.Equal(oidRSA):
+		return byHash(x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA), nil
+	case sig.Equal(oidRSASHA256):
+		return x509.SHA256WithRSA, nil
+	case sig.Equal(oidRSASHA384):
+		return x509.SHA384WithRSA, nil
+	case sig.Equal(oidRSASHA512):
+		return x509.SHA512WithRSA, nil
+	case sig.Equal(oidECPublicKey):
+		return byHash(x509.ECDSAWithSHA256, x509.ECDSAWithSHA384, x509.ECDSAWithSHA512), nil
+	case sig.Equal(oidECDSASHA256):
+		return x509.ECDSAWithSHA256, nil
+	case sig.Equal(oidECDSASHA384):
+		return x509.ECDSAWithSHA384, nil
+	case sig.Equal(oidECDSASHA512):
+		return x509.ECDSAWithSHA512, nil
+	case sig.Equal(oidEd25519):
+		return x509.PureEd25519, nil
+	}
+	return 0, errorf("неподдерживаемый алгоритм подписи метки времени %s", sig)
+}
+
+// verifySigner проверяет SignerInfo: хэш TSTInfo в подписанных атрибутах
+// и подпись атрибутов сертификатом подписанта
+func (t *timestampToken) verifySigner(el asn1.RawValue, tstInfo []byte) error {
+	// SignerInfo: version, sid, digestAlgorithm, [0] signedAttrs, signatureAlgorithm, signature
+	si, err := asn1Elements(el.FullBytes)
+	if err != nil || len(si) < 6 {
+		return errorf("некорректная подпись метки времени")
+	}
+	attrs := si[3]
+	if attrs.Class != asn1.ClassContextSpecific || attrs.Tag != 0 {
+		return errorf("метка времени без подписанных атрибутов не поддерживается")
+	}
+	var digestAlg, sigAlg pkix.AlgorithmIdentifier
+	var signature []byte
+	if _, err := asn1.Unmarshal(si[2].FullBytes, &digestAlg); err != nil {
+		return errorf("некорректная подпись метки времени")
+	}
+	if _, err := asn1.Unmarshal(si[4].FullBytes, &sigAlg); err != nil {
+		return errorf("некорректная подпись метки времени")
+	}
+	if _, err := asn1.Unmarshal(si[5].FullBytes, &signature); err != nil {
+		return errorf("некорректная подпись метки времени")
+	}
+	hash, err := digestHash(digestAlg.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	// Подписанные атрибуты должны содержать хэш TSTInfo
+	h := hash.New()
+	h.Write(tstInfo)
+	var found bool
+	for data := attrs.Bytes; len(data) > 0; {
+		var attr struct {
+			Type   asn1.ObjectIdentifier
+			Values []asn1.RawValue `asn1:"set"`
+		}
+		if data, err = asn1.Unmarshal(data, &attr); err != nil {
+			return errorf("некорректные подписанные атрибуты метки времени")
+		}
+		if attr.Type.Equal(oidMessageDigest) && len(attr.Values) == 1 {
+			var digest []byte
+			if _, err := asn1.Unmarshal(attr.Values[0].FullBytes, &digest); err != nil {
+				return errorf("некорректный атрибут messageDigest")
+			}
+			if !bytes.Equal(digest, h.Sum(nil)) {
+				return mismatchf("подписанный хэш не совпадает с содержимым метки времени")
+			}
+			found = true
+		}
+	}
+	if !found {
+		return errorf("в подписанных атрибутах метки времени нет messageDigest")
+	}
+
+	t.Signer, err = t.findSigner(si[1])
+	if err != nil {
+		return err
+	}
+	algo, err := signatureAlgorithm(sigAlg.Algorithm, hash)
+	if err != nil {
+		return err
+	}
+	// Подписывается DER атрибутов с универсальным тегом SET вместо [0]
+	signed := append([]byte{0x31}, attrs.FullBytes[1:]...)
+	if err := t.Signer.CheckSignature(algo, signed, signature); err != nil {
+		return mismatchf("подпись метки времени недействительна: %w", err)
+	}
+	return nil
+}
+
+// findSigner находит сертификат подписанта по идентификатору SignerInfo:
+// издателю с серийным номером или [0] идентификатору ключа
+func (t *timestampToken) findSigner(sid asn1.RawValue) (*x509.Certificate, error) {
+	if len(t.certs) == 0 {
+		return nil, errorf("в метке времени нет сертификата службы: запрос должен требовать сертификат")
+	}
+	var issuerSerial struct {
+		Issuer asn1.RawValue
+		Serial *big.Int
+	}
+	isSKI := sid.Class == asn1.ClassContextSpecific && sid.Tag == 0
+	if !isSKI {
+		if _, err := asn1.Unmarshal(sid.FullBytes, &issuerSerial); err != nil {
+			return nil, errorf("некорректный идентификатор подписанта метки времени")
+		}
+	}
+	for _, c := range t.certs {
+		if isSKI && bytes.Equal(c.SubjectKeyId, sid.Bytes) {
+			return c, nil
+		}
+		if !isSKI && bytes.Equal(c.RawIssuer, issuerSerial.Issuer.FullBytes) && c.SerialNumber.Cmp(issuerSerial.Serial) == 0 {
+			return c, nil
+		}
+	}
+	return nil, errorf("сертификат подписанта метки времени не найден в ответе")
+}
+
+// verifyChain проверяет сертификат службы по доверенному центру на момент
+// метки; сертификат должен быть предназначен для меток времени
+func (t *timestampToken) verifyChain(roots *x509.CertPool) error {
+	intermediates := x509.NewCertPool()
+	for _, c := range t.certs {
+		intermediates.AddCert(c)
+	}
+	_, err := t.Signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   t.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+	if err != nil {
+		return mismatchf("сертификат службы меток времени не заверен указанным центром: %w", err)
+	}
+	return nil
+}
+
+// checkData сверяет метку с отмеченными данными
+func (t *timestampToken) checkData(data []byte) error {
+	hash, err := digestHash(t.Imprint.HashAlgorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+	h := hash.New()
+	h.Write(data)
+	if !bytes.Equal(h.Sum(nil), t.Imprint.HashedMessage) {
+		return mismatchf("метка времени выдана для других данных")
+	}
+	return nil
+}
+
+// checkRequest сверяет метку с запросом: хэш и нонс
+func (t *timestampToken) checkRequest(req *tsRequest) error {
+	if !bytes.Equal(t.Imprint.HashedMessage, req.MessageImprint.HashedMessage) {
+		return mismatchf("метка времени выдана для других данных, чем в запросе")
+	}
+	if req.Nonce != nil && (t.Nonce == nil || req.Nonce.Cmp(t.Nonce) != 0) {
+		return mismatchf("нонс метки не совпадает с запросом: ответ выдан на другой запрос")
+	}
+	return nil
+}
+
+// runTimestamp отправляет подготовленные офлайн запросы меток времени
+// и проверяет полученные метки
+func runTimestamp(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, tr("Использование:"))
+		fmt.Fprintln(os.Stderr, "  seedgen timestamp submit --tsa https://tsa.example/tsr receipt.tsq")
+		fmt.Fprintln(os.Stderr, "  seedgen timestamp verify --receipt receipt.json [--request receipt.tsq] [--ca tsa-ca.pem] receipt.tsr")
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return errorf("укажите действие: submit или verify")
+	}
+
+	switch args[0] {
+	case "submit":
+		return runTimestampSubmit(args[1:])
+	case "verify":
+		return runTimestampVerify(args[1:])
+	default:
+		return errorf("неизвестное действие %q, доступны: submit, verify", args[0])
+	}
+}
+
+// runTimestampSubmit отправляет запрос в службу меток времени и сохраняет
+// ответ. Запускается на машине с сетью: в запросе только хэш квитанции.
+func runTimestampSubmit(args []string) error {
+	fs := newFlagSet("timestamp submit")
+	tsa := fs.String("tsa", "", "адрес службы меток времени RFC 3161 (HTTP POST)")
+	out := fs.String("out", "", "файл ответа (по умолчанию - запрос с расширением .tsr)")
+	timeout := fs.Duration("timeout", 30*time.Second, "сколько ждать ответа службы")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errorf("укажите файл запроса метки времени")
+	}
+	if *tsa == "" {
+		return errorf("укажите адрес службы меток времени через --tsa")
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(positional[0], ".tsq") + ".tsr"
+	}
+	der, err := os.ReadFile(positional[0])
+	if err != nil {
+		return err
+	}
+	req, err := parseTimestampRequest(der)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	logDebug("запрос метки времени", "tsa", *tsa, "bytes", len(der))
+	httpResp, err := client.Post(*tsa, "application/timestamp-query", bytes.NewReader(der))
+	if err != nil {
+		return errorf("ошибка запроса к службе меток времени: %w", err)
+	}
+	defer httpResp.Body.Close()
+	logDebug("ответ службы меток времени", "status", httpResp.Status)
+	if httpResp.StatusCode != http.StatusOK {
+		return errorf("служба меток времени ответила %s", httpResp.Status)
+	}
+	resp, err := io.ReadAll(io.LimitReader(httpResp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	token, err := parseTimestampResponse(resp)
+	if err != nil {
+		return err
+	}
+	if err := token.checkRequest(req); err != nil {
+		return err
+	}
+	if err := writeNewFile(*out, resp, 0644); err != nil {
+		return errorf("ошибка записи метки времени: %w", err)
+	}
+	fmt.Printf(tr("Время метки: %s\n"), token.GenTime.UTC().Format(time.RFC3339))
+	fmt.Printf(tr("Служба: %s\n"), token.Signer.Subject)
+	fmt.Printf(tr("✓ Метка времени сохранена: %s\n"), *out)
+	return nil
+}
+
+// runTimestampVerify проверяет метку времени квитанции: хэш, нонс запроса,
+// подпись службы и, с --ca, цепочку ее сертификата
+func runTimestampVerify(args []string) error {
+	fs := newFlagSet("timestamp verify")
+	receiptPath := fs.String("receipt", "", "квитанция, для которой получена метка")
+	requestPath := fs.String("request", "", "исходный запрос: сверить нонс")
+	caPath := fs.String("ca", "", "доверенный сертификат центра службы меток времени (PEM)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errorf("укажите файл метки времени")
+	}
+	if *receiptPath == "" {
+		return errorf("укажите квитанцию через --receipt")
+	}
+	data, err := os.ReadFile(*receiptPath)
+	if err != nil {
+		return err
+	}
+	der, err := os.ReadFile(positional[0])
+	if err != nil {
+		return err
+	}
+	var req *tsRequest
+	if *requestPath != "" {
+		raw, err := os.ReadFile(*requestPath)
+		if err != nil {
+			return err
+		}
+		if req, err = parseTimestampRequest(raw); err != nil {
+			return err
+		}
+	}
+
+	token, err := parseTimestampResponse(der)
+	if err != nil {
+		return err
+	}
+	if err := token.checkData(data); err != nil {
+		return err
+	}
+	if req != nil {
+		if err := token.checkRequest(req); err != nil {
+			return err
+		}
+	}
+	if *caPath != "" {
+		caPEM, err := os.ReadFile(*caPath)
+		if err != nil {
+			return err
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caPEM) {
+			return errorf("%s не содержит сертификата центра в PEM", *caPath)
+		}
+		if err := token.verifyChain(roots); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf(tr("=== Метка времени: %s ===\n\n"), positional[0])
+	fmt.Printf(tr("Квитанция: %s\n"), *receiptPath)
+	fmt.Printf(tr("Время метки: %s\n"), token.GenTime.UTC().Format(time.RFC3339))
+	fmt.Printf(tr("Служба: %s\n"), token.Signer.Subject)
+	fmt.Printf(tr("Серийный номер: %s\n"), token.Serial)
+	fmt.Println()
+	fmt.Println(tr("✓ Метка выдана для этой квитанции и подписана службой"))
+	if req != nil {
+		fmt.Println(tr("✓ Метка получена на исходный запрос"))
+	}
+	if *caPath != "" {
+		fmt.Println(tr("✓ Сертификат службы заверен указанным центром"))
+	} else {
+		fmt.Println(tr("⚠ Сертификат службы не проверен: укажите --ca с сертификатом ее центра"))
+	}
+	return nil
+}
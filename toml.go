@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// tomlEntry - пара ключ-значение из TOML-файла с полным путем таблицы
+type tomlEntry struct {
+	table []string
+	key   string
+	value string
+	line  int
+}
+
+// parseTOML разбирает подмножество TOML, достаточное для конфигурации:
+// заголовки таблиц [a.b], ключи со строковыми, целыми и логическими
+// значениями и комментарии. Массивы, встроенные таблицы, многострочные
+// строки и даты не поддерживаются и дают ошибку, а не молчаливый пропуск.
+func parseTOML(r io.Reader) ([]tomlEntry, error) {
+	var entries []tomlEntry
+	var table []string
+	seen := make(map[string]int)
+	tables := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		if text[0] == '[' {
+			if strings.HasPrefix(text, "[[") {
+				return nil, errorf("строка %d: массивы таблиц не поддерживаются", line)
+			}
+			end := strings.LastIndexByte(text, ']')
+			if end < 0 || !isTOMLComment(text[end+1:]) {
+				return nil, errorf("строка %d: некорректный заголовок таблицы", line)
+			}
+			var err error
+			if table, err = splitTOMLKey(text[1:end]); err != nil {
+				return nil, errorf("строка %d: %w", line, err)
+			}
+			name := strings.Join(table, "\x00")
+			if prev, ok := tables[name]; ok {
+				return nil, errorf("строка %d: таблица уже объявлена в строке %d", line, prev)
+			}
+			tables[name] = line
+			continue
+		}
+
+		eq := strings.IndexByte(text, '=')
+		if eq < 0 {
+			return nil, errorf("строка %d: ожидалось \"ключ = значение\"", line)
+		}
+		key, err := splitTOMLKey(text[:eq])
+		if err != nil {
+			return nil, errorf("строка %d: %w", line, err)
+		}
+		value, err := parseTOMLValue(strings.TrimSpace(text[eq+1:]))
+		if err != nil {
+			return nil, errorf("строка %d: %w", line, err)
+		}
+
+		// Точечный ключ a.b = 1 относится к вложенной таблице
+		full := append(append([]string{}, table...), key[:len(key)-1]...)
+		name := strings.Join(append(full, key[len(key)-1]), "\x00")
+		if prev, ok := seen[name]; ok {
+			return nil, errorf("строка %d: ключ %q уже задан в строке %d", line, key[len(key)-1], prev)
+		}
+		seen[name] = line
+		entries = append(entries, tomlEntry{table: full, key: key[len(key)-1], value: value, line: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// isTOMLComment сообщает, что остаток строки пуст или является комментарием
+func isTOMLComment(rest string) bool {
+	rest = strings.TrimSpace(rest)
+	return rest == "" || rest[0] == '#'
+}
+
+// splitTOMLKey разбирает ключ вида a."b.c".d на части
+func splitTOMLKey(s string) ([]string, error) {
+	var parts []string
+	s = strings.TrimSpace(s)
+	for {
+		var part string
+		switch {
+		case s == "":
+			return nil, errorf("пустой ключ")
+		case s[0] == '"' || s[0] == '\'':
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				return nil, errorf("незакрытая кавычка в ключе")
+			}
+			part, s = s[1:end+1], s[end+2:]
+		default:
+			end := strings.IndexAny(s, ". \t")
+			if end < 0 {
+				end = len(s)
+			}
+			part, s = s[:end], s[end:]
+			for _, r := range part {
+				if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+					return nil, errorf("недопустимый символ %q в ключе", r)
+				}
+			}
+			if part == "" {
+				return nil, errorf("пустой ключ")
+			}
+		}
+		parts = append(parts, part)
+
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return parts, nil
+		}
+		if s[0] != '.' {
+			return nil, errorf("некорректный ключ")
+		}
+		s = strings.TrimSpace(s[1:])
+	}
+}
+
+// parseTOMLValue разбирает значение и возвращает его в текстовом виде,
+// пригодном для flag.Value.Set
+func parseTOMLValue(s string) (string, error) {
+	switch {
+	case s == "":
+		return "", errorf("пропущено значение")
+	case strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''"):
+		return "", errorf("многострочные строки не поддерживаются")
+	case s[0] == '"':
+		end := 1
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return "", errorf("незакрытая строка")
+		}
+		if !isTOMLComment(s[end+1:]) {
+			return "", errorf("лишние символы после строки")
+		}
+		value, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return "", errorf("некорректная строка %s", s[:end+1])
+		}
+		return value, nil
+	case s[0] == '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", errorf("незакрытая строка")
+		}
+		if !isTOMLComment(s[end+2:]) {
+			return "", errorf("лишние символы после строки")
+		}
+		return s[1 : end+1], nil
+	case s[0] == '[' || s[0] == '{':
+		return "", errorf("массивы и встроенные таблицы не поддерживаются")
+	}
+
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if s == "true" || s == "false" {
+		return s, nil
+	}
+	// В целых числах TOML допускает разделители: 1_000_000
+	digits := strings.Replace(s, "_", "", -1)
+	if _, err := strconv.ParseInt(digits, 10, 64); err == nil && !strings.HasPrefix(s, "_") && !strings.HasSuffix(s, "_") {
+		return digits, nil
+	}
+	return "", errorf("неподдерживаемое значение %q", s)
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tournamentDef - описание турнира: группы, затем плей-офф
+type tournamentDef struct {
+	label string
+	// Групповой этап
+	pots        string
+	groups      int
+	constraints []string
+	schedule    bool
+	double      bool
+	// Плей-офф: advance лучших каждой группы, wildcards лучших из
+	// следующего места, победители групп сеяные при seedWinners
+	advance     int
+	wildcards   int
+	seedWinners bool
+	koDouble    bool
+}
+
+// readTournamentDef читает описание турнира в TOML: таблицы [tournament],
+// [groups] и [knockout]. Путь корзин отсчитывается от файла описания.
+func readTournamentDef(path string) (tournamentDef, string, error) {
+	def := tournamentDef{schedule: true, advance: 2, seedWinners: true}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return def, "", err
+	}
+	entries, err := parseTOML(bytes.NewReader(data))
+	if err != nil {
+		return def, "", errorf("%s: %w", path, err)
+	}
+	table := func(e tomlEntry) string {
+		if len(e.table) != 1 {
+			return ""
+		}
+		return e.table[0]
+	}
+	for _, e := range entries {
+		var err error
+		switch table(e) + "." + e.key {
+		case "tournament.label":
+			def.label = e.value
+		case "groups.pots":
+			def.pots = e.value
+			if !filepath.IsAbs(def.pots) {
+				def.pots = filepath.Join(filepath.Dir(path), def.pots)
+			}
+		case "groups.count":
+			def.groups, err = strconv.Atoi(e.value)
+		case "groups.constraints":
+			for _, c := range strings.Split(e.value, ",") {
+				if c = strings.TrimSpace(c); c != "" {
+					def.constraints = append(def.constraints, c)
+				}
+			}
+		case "groups.schedule":
+			def.schedule, err = strconv.ParseBool(e.value)
+		case "groups.double":
+			def.double, err = strconv.ParseBool(e.value)
+		case "knockout.advance":
+			def.advance, err = strconv.Atoi(e.value)
+		case "knockout.wildcards":
+			def.wildcards, err = strconv.Atoi(e.value)
+		case "knockout.seed_winners":
+			def.seedWinners, err = strconv.ParseBool(e.value)
+		case "knockout.double":
+			def.koDouble, err = strconv.ParseBool(e.value)
+		default:
+			err = errorf("неизвестный параметр %q: допустимы [tournament] label, [groups] pots, count, constraints, schedule, double, [knockout] advance, wildcards, seed_winners, double", e.key)
+		}
+		if err != nil {
+			return def, "", errorf("%s: строка %d: %w", path, e.line, err)
+		}
+	}
+	switch {
+	case def.label == "":
+		err = errorf("не указана метка турнира: [tournament] label")
+	case def.pots == "":
+		err = errorf("не указаны корзины: [groups] pots")
+	case def.groups < 2 || def.groups > 26:
+		err = errorf("[groups] count должно быть от 2 до 26")
+	case def.advance < 1:
+		err = errorf("[knockout] advance должно быть не меньше 1")
+	case def.wildcards < 0 || def.wildcards > def.groups:
+		err = errorf("[knockout] wildcards должно быть от 0 до числа групп")
+	}
+	if err != nil {
+		return def, "", errorf("%s: %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	auditInput("file", path, digest)
+	return def, digest, nil
+}
+
+// groupStanding - место команды в группе по итогам группового этапа
+type groupStanding struct {
+	Group  string `json:"group"`
+	Rank   int    `json:"rank"`
+	Team   string `json:"team"`
+	Points int    `json:"points"`
+	Diff   int    `json:"diff"`
+	Scored int    `json:"scored"`
+	// Lot - жребий при равенстве показателей, hex
+	Lot string `json:"lot,omitempty"`
+}
+
+// readGroupStandings читает итоги групп: CSV с заголовком group,rank,team
+// и необязательными points, diff, scored. Каждая команда группы должна
+// стоять на своем месте от 1 до размера группы.
+func readGroupStandings(path string, groups []drawGroup) (map[string][]groupStanding, bool, string, error) {
+	rows, lines, digest, err := readHeaderCSV(path, []string{"group", "rank", "team"}, []string{"points", "diff", "scored"})
+	if err != nil {
+		return nil, false, "", err
+	}
+	members := make(map[string]string)
+	for _, g := range groups {
+		for _, t := range g.Teams {
+			members[t] = g.Name
+		}
+	}
+	byGroup := make(map[string][]groupStanding)
+	seen := make(map[string]int)
+	withPoints := true
+	for i, row := range rows {
+		s := groupStanding{Group: strings.ToUpper(row["group"]), Team: row["team"]}
+		if s.Rank, err = strconv.Atoi(row["rank"]); err != nil || s.Rank < 1 {
+			return nil, false, "", errorf("%s:%d: некорректное место %q", path, lines[i], row["rank"])
+		}
+		if members[s.Team] != s.Group {
+			return nil, false, "", errorf("%s:%d: команды %q нет в группе %s", path, lines[i], s.Team, s.Group)
+		}
+		if first, ok := seen[s.Team]; ok {
+			return nil, false, "", errorf("%s:%d: команда %q уже указана в строке %d", path, lines[i], s.Team, first)
+		}
+		seen[s.Team] = lines[i]
+		if row["points"] == "" {
+			withPoints = false
+		}
+		for _, f := range []struct {
+			name string
+			v    *int
+		}{{"points", &s.Points}, {"diff", &s.Diff}, {"scored", &s.Scored}} {
+			if row[f.name] == "" {
+				continue
+			}
+			if *f.v, err = strconv.Atoi(row[f.name]); err != nil {
+				return nil, false, "", errorf("%s:%d: некорректное значение %s %q", path, lines[i], f.name, row[f.name])
+			}
+		}
+		byGroup[s.Group] = append(byGroup[s.Group], s)
+	}
+	for _, g := range groups {
+		list := byGroup[g.Name]
+		if len(list) != len(g.Teams) {
+			return nil, false, "", errorf("%s: в группе %s команд %d, а в итогах %d", path, g.Name, len(g.Teams), len(list))
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Rank < list[j].Rank })
+		for i, s := range list {
+			if s.Rank != i+1 {
+				return nil, false, "", errorf("%s: места группы %s должны идти от 1 до %d без повторов", path, g.Name, len(list))
+			}
+		}
+	}
+	return byGroup, withPoints, digest, nil
+}
+
+// pickWildcards выбирает лучших из команд, занявших место place: по очкам,
+// разнице, забитым, а при полном равенстве - по жребию из потока. Жребий
+// тянется каждой команде заранее в порядке групп, поэтому потребление
+// потока не зависит от показателей.
+func pickWildcards(stream *drawStream, byGroup map[string][]groupStanding, groups []drawGroup, place, count int) []groupStanding {
+	var candidates []groupStanding
+	lots := make(map[string]uint64)
+	for _, g := range groups {
+		if list := byGroup[g.Name]; len(list) >= place {
+			s := list[place-1]
+			lots[s.Team] = stream.uint64()
+			s.Lot = fmt.Sprintf("%016x", lots[s.Team])
+			candidates = append(candidates, s)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		switch {
+		case a.Points != b.Points:
+			return a.Points > b.Points
+		case a.Diff != b.Diff:
+			return a.Diff > b.Diff
+		case a.Scored != b.Scored:
+			return a.Scored > b.Scored
+		}
+		return lots[a.Team] < lots[b.Team]
+	})
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+	return candidates[:count]
+}
+
+// tournamentFile - файл этапа и команда seedgen, которой его можно
+// получить отдельно (с --master)
+type tournamentFile struct {
+	Name    string `json:"name"`
+	SHA256  string `json:"sha256"`
+	Command string `json:"command,omitempty"`
+}
+
+// tournamentStage - этап турнира: drawn - проведен, waiting - ждет итогов
+// предыдущего этапа
+type tournamentStage struct {
+	Name   string           `json:"stage"`
+	Label  string           `json:"label"`
+	Status string           `json:"status"`
+	Files  []tournamentFile `json:"files,omitempty"`
+}
+
+// tournamentRun - протокол прогона турнира
+type tournamentRun struct {
+	Kind        string            `json:"kind"`
+	Label       string            `json:"label"`
+	Definition  string            `json:"definition_sha256"`
+	Standings   string            `json:"standings_sha256,omitempty"`
+	Fingerprint string            `json:"master_fingerprint"`
+	Wildcards   []groupStanding   `json:"wildcards,omitempty"`
+	Stages      []tournamentStage `json:"stages"`
+}
+
+// nameListFile записывает список участников по одному на строку и его
+// SHA-256 в том виде, в каком его считает readNameList
+func nameListFile(names []string) ([]byte, string) {
+	sum := sha256.Sum256([]byte(strings.Join(names, "\n")))
+	return []byte(strings.Join(names, "\n") + "\n"), hex.EncodeToString(sum[:])
+}
+
+// runTournament проводит все этапы турнира из одного мастер-сида. Каждый
+// этап тянет жребий из своего потока с меткой "<турнир>/<этап>", поэтому
+// плей-офф разыгрывается после группового этапа с --standings, а
+// групповой этап при этом не меняется.
+func runTournament(args []string) error {
+	fs := newFlagSet("tournament")
+	defPath := fs.String("definition", "tournament.toml", "описание турнира в TOML")
+	standingsPath := fs.String("standings", "", "итоги групп CSV group,rank,team[,points,diff,scored]: с ними разыгрывается плей-офф")
+	out := fs.String("out", "tournament", "каталог для файлов этапов и протокола tournament.json")
+	masterRef := addMasterFlag(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	def, defDigest, err := readTournamentDef(*defPath)
+	if err != nil {
+		return err
+	}
+	var constraints []drawConstraint
+	for _, name := range def.constraints {
+		c, err := findDrawConstraint(name)
+		if err != nil {
+			return errorf("%s: %w", *defPath, err)
+		}
+		constraints = append(constraints, c)
+	}
+	potsData, err := os.ReadFile(def.pots)
+	if err != nil {
+		return err
+	}
+	pots, potsDigest, err := readPots(def.pots)
+	if err != nil {
+		return err
+	}
+	for _, pot := range pots {
+		if len(pot) > def.groups {
+			return errorf("в корзине %d команд: %d, а групп только %d", pot[0].Pot, len(pot), def.groups)
+		}
+	}
+
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	run := tournamentRun{Kind: "tournament", Label: def.label, Definition: defDigest, Fingerprint: masterFingerprint(master)}
+	files := map[string][]byte{}
+	var order []string
+	// add запоминает файл этапа и возвращает его описание для протокола
+	add := func(name string, data []byte, command string) tournamentFile {
+		files[name] = data
+		order = append(order, name)
+		sum := sha256.Sum256(data)
+		auditResult("file", filepath.Join(*out, name), hex.EncodeToString(sum[:]))
+		return tournamentFile{Name: name, SHA256: hex.EncodeToString(sum[:]), Command: command}
+	}
+	addJSON := func(name string, v interface{}, command string) (tournamentFile, error) {
+		var buf bytes.Buffer
+		if err := writeOutput(&buf, v); err != nil {
+			return tournamentFile{}, err
+		}
+		return add(name, buf.Bytes(), command), nil
+	}
+
+	// Этап 1: жеребьевка групп
+	potsName := filepath.Base(def.pots)
+	groupsLabel := def.label + "/groups"
+	draw, _, err := newGroupDraw(master, groupsLabel, pots, potsDigest, def.groups, def.constraints, constraints)
+	if err != nil {
+		return err
+	}
+	command := fmt.Sprintf("draw groups --pots %s --groups %d", quoteArg(potsName), def.groups)
+	for _, c := range def.constraints {
+		command += " --constraint " + c
+	}
+	command += " --label " + quoteArg(groupsLabel) + " --format json"
+	stage := tournamentStage{Name: "groups", Label: groupsLabel, Status: "drawn"}
+	stage.Files = append(stage.Files, add(potsName, potsData, ""))
+	f, err := addJSON("groups.json", draw, command)
+	if err != nil {
+		return err
+	}
+	stage.Files = append(stage.Files, f)
+	run.Stages = append(run.Stages, stage)
+
+	// Этап 2: календари групп, у каждой группы свой поток
+	if def.schedule {
+		stage := tournamentStage{Name: "group-schedules", Label: def.label + "/group-*", Status: "drawn"}
+		for _, g := range draw.Groups {
+			list, digest := nameListFile(g.Teams)
+			listName := "group-" + g.Name + ".txt"
+			stage.Files = append(stage.Files, add(listName, list, ""))
+			label := def.label + "/group-" + g.Name
+			command := fmt.Sprintf("schedule roundrobin --participants %s --label %s --format json", listName, quoteArg(label))
+			if def.double {
+				command += " --double"
+			}
+			f, err := addJSON("schedule-"+g.Name+".json", newRoundRobin(master, label, g.Teams, digest, def.double), command)
+			if err != nil {
+				return err
+			}
+			stage.Files = append(stage.Files, f)
+		}
+		run.Stages = append(run.Stages, stage)
+	}
+
+	// Этап 3: плей-офф по итогам групп
+	koLabel := def.label + "/knockout"
+	ko := tournamentStage{Name: "knockout", Label: koLabel, Status: "waiting"}
+	var b bracket
+	if *standingsPath != "" {
+		byGroup, withPoints, digest, err := readGroupStandings(*standingsPath, draw.Groups)
+		if err != nil {
+			return err
+		}
+		if def.wildcards > 0 && !withPoints {
+			return errorf("%s: для выбора лучших с места %d нужен столбец points у всех команд", *standingsPath, def.advance+1)
+		}
+		standingsData, err := os.ReadFile(*standingsPath)
+		if err != nil {
+			return err
+		}
+		run.Standings = digest
+		var qualifiers []string
+		for place := 1; place <= def.advance; place++ {
+			for _, g := range draw.Groups {
+				if list := byGroup[g.Name]; len(list) >= place {
+					qualifiers = append(qualifiers, list[place-1].Team)
+				}
+			}
+		}
+		if def.wildcards > 0 {
+			picked := pickWildcards(newDrawStream(master, "tournament-wildcards", def.label+"/wildcards"), byGroup, draw.Groups, def.advance+1, def.wildcards)
+			for _, s := range picked {
+				qualifiers = append(qualifiers, s.Team)
+			}
+			run.Wildcards = picked
+		}
+		if len(qualifiers) < 2 || len(qualifiers) > 1024 {
+			return errorf("в плей-офф выходит %d команд, нужно от 2 до 1024", len(qualifiers))
+		}
+		seeded := 0
+		if def.seedWinners {
+			seeded = len(draw.Groups)
+		}
+		list, listDigest := nameListFile(qualifiers)
+		b = newBracketDraw(master, koLabel, qualifiers, listDigest, seeded, def.koDouble)
+		command := fmt.Sprintf("bracket --participants qualifiers.txt --seeded %d --label %s --format json", seeded, quoteArg(koLabel))
+		if def.koDouble {
+			command += " --double"
+		}
+		ko.Status = "drawn"
+		ko.Files = append(ko.Files, add(filepath.Base(*standingsPath), standingsData, ""), add("qualifiers.txt", list, ""))
+		f, err := addJSON("knockout.json", b, command)
+		if err != nil {
+			return err
+		}
+		ko.Files = append(ko.Files, f)
+	}
+	run.Stages = append(run.Stages, ko)
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		return err
+	}
+	for _, name := range order {
+		if err := os.WriteFile(filepath.Join(*out, name), files[name], 0644); err != nil {
+			return err
+		}
+	}
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, run); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(*out, "tournament.json"), buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	auditResult("file", filepath.Join(*out, "tournament.json"), hex.EncodeToString(sum[:]))
+
+	fmt.Printf(tr("Турнир %q: групп %d, команд %d, мастер-сид %s...\n"), run.Label, def.groups, len(draw.Picks), run.Fingerprint)
+	fmt.Println()
+	fmt.Println(tr("Группы:"))
+	for _, g := range draw.Groups {
+		fmt.Printf("  %s: %s\n", g.Name, strings.Join(g.Teams, ", "))
+	}
+	if ko.Status == "waiting" {
+		fmt.Println()
+		fmt.Println(tr("Плей-офф ждет итогов групп: запустите ту же команду с --standings"))
+	} else {
+		if len(run.Wildcards) > 0 {
+			fmt.Println()
+			fmt.Printf(tr("Лучшие команды с %d-го места:\n"), def.advance+1)
+			for _, s := range run.Wildcards {
+				fmt.Printf(tr("  %s (группа %s): очки %d, разница %d, забито %d, жребий %s\n"), s.Team, s.Group, s.Points, s.Diff, s.Scored, s.Lot)
+			}
+		}
+		fmt.Println()
+		fmt.Printf(tr("Плей-офф: участников %d, сеяных %d, пропусков %d\n"), b.Count, b.Seeded, b.Byes)
+		for _, m := range b.Rounds[0] {
+			if m.Bye {
+				fmt.Printf(tr("  %s: %s проходит без игры\n"), m.ID, m.Home)
+			} else {
+				fmt.Printf("  %s: %s - %s\n", m.ID, m.Home, m.Away)
+			}
+		}
+	}
+	fmt.Println()
+	fmt.Printf(tr("✓ Файлы этапов и протокол tournament.json записаны в %s\n"), *out)
+	return nil
+}
+
+// quoteArg берет аргумент команды в кавычки, если в нем есть пробелы
+// или кавычки, так чтобы его разобрал splitCommandLine
+func quoteArg(s string) string {
+	switch {
+	case !strings.ContainsAny(s, " \t\"'"):
+		return s
+	case strings.ContainsRune(s, '\''):
+		return "\"" + s + "\""
+	}
+	return "'" + s + "'"
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// defaultTPMPCRs - регистры PCR политики по умолчанию: прошивка и состояние
+// Secure Boot. Обновление ядра их не меняет, подмена загрузчика - меняет.
+const defaultTPMPCRs = "sha256:0,7"
+
+// tpmSealed - мастер-сид, запечатанный в TPM 2.0. Закрытая часть объекта
+// зашифрована ключом TPM и без него и выполнения политики PCR бесполезна.
+type tpmSealed struct {
+	Kind        string `json:"kind"`
+	PCRs        string `json:"pcrs"`
+	Fingerprint string `json:"fingerprint"`
+	Public      []byte `json:"public"`
+	Private     []byte `json:"private"`
+}
+
+// tpmPrimaryArgs - шаблон первичного ключа в иерархии владельца. Ключ
+// выводится из семени TPM, поэтому при распечатывании создается заново.
+var tpmPrimaryArgs = []string{"tpm2_createprimary", "-Q", "-C", "o", "-g", "sha256", "-G", "ecc", "-c", "primary.ctx"}
+
+// runTPMTool запускает утилиту tpm2-tools в рабочем каталоге и возвращает ее stdout.
+// Секреты передаются только через stdin и stdout, не через файлы.
+func runTPMTool(dir string, stdin []byte, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, errorf("не найдены tpm2-tools (%s)", args[0])
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var out, errOut bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(errOut.String()); msg != "" {
+			return nil, errorf("%s: %s", args[0], msg)
+		}
+		return nil, errorf("%s: %w", args[0], err)
+	}
+	return out.Bytes(), nil
+}
+
+// withTPMDir выполняет fn во временном каталоге для контекстов tpm2-tools
+func withTPMDir(fn func(dir string) error) error {
+	dir, err := os.MkdirTemp("", "seedgen-tpm-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	return fn(dir)
+}
+
+// sealTPM запечатывает мастер-сид в TPM с политикой по регистрам pcrs
+func sealTPM(master []byte, pcrs string) (*tpmSealed, error) {
+	sealed := &tpmSealed{Kind: "tpm-sealed", PCRs: pcrs, Fingerprint: masterFingerprint(master)}
+	err := withTPMDir(func(dir string) error {
+		if _, err := runTPMTool(dir, nil, tpmPrimaryArgs...); err != nil {
+			return err
+		}
+		if _, err := runTPMTool(dir, nil, "tpm2_createpolicy", "-Q", "--policy-pcr", "-l", pcrs, "-L", "policy.digest"); err != nil {
+			return err
+		}
+		if _, err := runTPMTool(dir, master, "tpm2_create", "-Q", "-C", "primary.ctx", "-g", "sha256",
+			"-a", "fixedtpm|fixedparent|noda", "-L", "policy.digest", "-i", "-", "-u", "seal.pub", "-r", "seal.priv"); err != nil {
+			return err
+		}
+		var err error
+		if sealed.Public, err = os.ReadFile(filepath.Join(dir, "seal.pub")); err != nil {
+			return err
+		}
+		sealed.Private, err = os.ReadFile(filepath.Join(dir, "seal.priv"))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sealed, nil
+}
+
+// unseal распечатывает мастер-сид. TPM откажет, если значения PCR
+// отличаются от тех, что были при запечатывании.
+func (s *tpmSealed) unseal() ([]byte, error) {
+	var master []byte
+	err := withTPMDir(func(dir string) error {
+		if err := os.WriteFile(filepath.Join(dir, "seal.pub"), s.Public, 0600); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "seal.priv"), s.Private, 0600); err != nil {
+			return err
+		}
+		if _, err := runTPMTool(dir, nil, tpmPrimaryArgs...); err != nil {
+			return err
+		}
+		if _, err := runTPMTool(dir, nil, "tpm2_load", "-Q", "-C", "primary.ctx", "-u", "seal.pub", "-r", "seal.priv", "-c", "seal.ctx"); err != nil {
+			return err
+		}
+		out, err := runTPMTool(dir, nil, "tpm2_unseal", "-c", "seal.ctx", "-p", "pcr:"+s.PCRs)
+		if err != nil {
+			return err
+		}
+		master = newSecret(len(out))
+		copy(master, out)
+		wipe(out)
+		return nil
+	})
+	if err != nil {
+		return nil, errorf("ошибка распечатывания в TPM: %w", err)
+	}
+	if len(master) != masterSeedSize || !secretEqual([]byte(masterFingerprint(master)), []byte(s.Fingerprint)) {
+		wipe(master)
+		return nil, mismatchf("распечатанное значение не совпадает с отпечатком %s", s.Fingerprint)
+	}
+	return master, nil
+}
+
+// openTPMArtifact распечатывает мастер-сид из артефакта tpm-sealed
+func openTPMArtifact(data []byte) ([]byte, error) {
+	var sealed tpmSealed
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, errorf("не удалось разобрать артефакт: %w", err)
+	}
+	return sealed.unseal()
+}
+
+// tpmFlags - флаги запечатывания результата в TPM
+type tpmFlags struct {
+	out  *string
+	pcrs *string
+}
+
+// addTPMFlags регистрирует флаги запечатывания в наборе
+func addTPMFlags(fs *flag.FlagSet) *tpmFlags {
+	return &tpmFlags{
+		out:  fs.String("seal-tpm", "", "запечатать мастер-сид в TPM 2.0 и сохранить объект в файл"),
+		pcrs: fs.String("tpm-pcrs", defaultTPMPCRs, "регистры PCR политики запечатывания"),
+	}
+}
+
+// seal запечатывает мастер-сид, если задан --seal-tpm, и сообщает об этом в w
+func (tf *tpmFlags) seal(w io.Writer, master []byte) error {
+	if *tf.out == "" {
+		return nil
+	}
+	sealed, err := sealTPM(master, *tf.pcrs)
+	if err != nil {
+		return errorf("ошибка запечатывания в TPM: %w", err)
+	}
+	data, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(*tf.out, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, tr("✓ Мастер-сид запечатан в TPM (PCR %s): %s\n"), *tf.pcrs, *tf.out)
+	return nil
+}
+
+// runUnseal распечатывает мастер-сид из TPM и выводит его
+func runUnseal(args []string) error {
+	fs := newFlagSet("unseal")
+	rf := addRevealFlags(fs)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errorf("укажите файл, созданный --seal-tpm")
+	}
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return err
+	}
+	var sealed tpmSealed
+	if err := json.Unmarshal(data, &sealed); err != nil || sealed.Kind != "tpm-sealed" {
+		return errorf("%s не является объектом, запечатанным в TPM", positional[0])
+	}
+	master, err := sealed.unseal()
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	// В канал выводится только мастер-сид: seedgen unseal sealed.json | seedgen derive ... --master -
+	if !isTerminal(os.Stdout) {
+		return writeSecretLine(os.Stdout, master)
+	}
+	return rf.reveal(tr("Мастер-сид (распечатан из TPM):"), master)
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// vaultClient - клиент HTTP API HashiCorp Vault. Адрес, пространство имен
+// и учетные данные берутся из тех же переменных окружения, что и у vault CLI:
+// VAULT_ADDR, VAULT_NAMESPACE, VAULT_CACERT, VAULT_TOKEN или пара
+// VAULT_ROLE_ID и VAULT_SECRET_ID для входа по AppRole.
+type vaultClient struct {
+	addr      string
+	namespace string
+	token     string
+	http      *http.Client
+}
+
+// vaultSecret - поля записи мастер-сида в хранилище KV
+type vaultSecret struct {
+	Master      secretHex `json:"master"`
+	Fingerprint string    `json:"fingerprint"`
+	Version     string    `json:"seedgen_version,omitempty"`
+}
+
+// newVaultClient читает настройки из окружения и входит в Vault
+func newVaultClient() (*vaultClient, error) {
+	addr := strings.TrimRight(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return nil, errorf("переменная VAULT_ADDR не задана: укажите адрес Vault, например https://vault.example.org:8200")
+	}
+	u, err := url.Parse(addr)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, errorf("некорректный адрес Vault %q", addr)
+	}
+	// Без TLS мастер-сид ушел бы по сети открытым текстом; исключение -
+	// локальный Vault, например dev-сервер или агент на той же машине
+	if u.Scheme == "http" {
+		if ip := net.ParseIP(u.Hostname()); u.Hostname() != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, errorf("адрес Vault %s без TLS: мастер-сид передается только по https или на локальный адрес", addr)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if ca := os.Getenv("VAULT_CACERT"); ca != "" {
+		pem, err := os.ReadFile(ca)
+		if err != nil {
+			return nil, errorf("VAULT_CACERT: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errorf("VAULT_CACERT: в %s нет сертификатов PEM", ca)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	c := &vaultClient{
+		addr:      addr,
+		namespace: strings.Trim(os.Getenv("VAULT_NAMESPACE"), "/"),
+		http:      &http.Client{Timeout: 30 * time.Second, Transport: transport},
+	}
+	if err := c.login(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// login получает токен: VAULT_TOKEN, вход по AppRole или ~/.vault-token,
+// который оставляет vault login. Токен проверяется сразу, чтобы ошибка
+// доступа обнаружилась до ввода сидов.
+func (c *vaultClient) login() error {
+	roleID, secretID := os.Getenv("VAULT_ROLE_ID"), os.Getenv("VAULT_SECRET_ID")
+	switch {
+	case os.Getenv("VAULT_TOKEN") != "":
+		c.token = os.Getenv("VAULT_TOKEN")
+	case roleID != "" || secretID != "":
+		if roleID == "" || secretID == "" {
+			return errorf("для входа по AppRole нужны обе переменные VAULT_ROLE_ID и VAULT_SECRET_ID")
+		}
+		mount := strings.Trim(os.Getenv("VAULT_APPROLE_MOUNT"), "/")
+		if mount == "" {
+			mount = "approle"
+		}
+		var resp struct {
+			Auth struct {
+				ClientToken string `json:"client_token"`
+			} `json:"auth"`
+		}
+		body := map[string]string{"role_id": roleID, "secret_id": secretID}
+		if _, err := c.call(http.MethodPost, "auth/"+mount+"/login", body, &resp); err != nil {
+			return errorf("вход в Vault по AppRole: %w", err)
+		}
+		if resp.Auth.ClientToken == "" {
+			return errorf("вход в Vault по AppRole: Vault не выдал токен")
+		}
+		c.token = resp.Auth.ClientToken
+		logDebug("вход в Vault", "method", "approle", "mount", mount)
+		return nil
+	default:
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return errorf("не заданы ни VAULT_TOKEN, ни VAULT_ROLE_ID и VAULT_SECRET_ID")
+		}
+		data, err := os.ReadFile(filepath.Join(home, ".vault-token"))
+		if err != nil || len(bytes.TrimSpace(data)) == 0 {
+			return errorf("не заданы ни VAULT_TOKEN, ни VAULT_ROLE_ID и VAULT_SECRET_ID, и нет ~/.vault-token")
+		}
+		c.token = string(bytes.TrimSpace(data))
+	}
+	if _, err := c.call(http.MethodGet, "auth/token/lookup-self", nil, nil); err != nil {
+		return errorf("токен Vault не принят: %w", err)
+	}
+	logDebug("вход в Vault", "method", "token")
+	return nil
+}
+
+// call выполняет запрос к API и разбирает ответ в out. Возвращает код
+// ответа; ошибки Vault из поля errors попадают в текст ошибки.
+func (c *vaultClient) call(method, path string, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		defer wipe(data)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.addr+"/v1/"+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	logDebug("запрос к Vault", "method", method, "path", path, "namespace", c.namespace)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, errorf("ошибка запроса к Vault: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	defer wipe(data)
+	logDebug("ответ Vault", "status", resp.Status)
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &e) == nil && len(e.Errors) > 0 {
+			return resp.StatusCode, errorf("Vault ответил %s: %s", resp.Status, strings.Join(e.Errors, "; "))
+		}
+		return resp.StatusCode, errorf("Vault ответил %s", resp.Status)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, errorf("не удалось разобрать ответ Vault: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// kvPath разбирает путь секрета на точку монтирования KV и путь внутри нее
+// и узнает версию хранилища, как vault kv: по sys/internal/ui/mounts.
+// Если токену этот запрос запрещен, точкой монтирования считается первый
+// сегмент пути, а хранилище - KV версии 2.
+func (c *vaultClient) kvPath(path string) (mount, key string, v2 bool, err error) {
+	path = strings.Trim(path, "/")
+	if !strings.Contains(path, "/") {
+		return "", "", false, errorf("путь секрета Vault %q должен включать точку монтирования, например secret/championship/master", path)
+	}
+	var resp struct {
+		Data struct {
+			Path    string `json:"path"`
+			Options struct {
+				Version string `json:"version"`
+			} `json:"options"`
+		} `json:"data"`
+	}
+	if _, err := c.call(http.MethodGet, "sys/internal/ui/mounts/"+path, nil, &resp); err == nil && resp.Data.Path != "" {
+		mount = strings.Trim(resp.Data.Path, "/")
+		if c.namespace != "" {
+			mount = strings.TrimPrefix(mount, c.namespace+"/")
+		}
+		if strings.HasPrefix(path, mount+"/") {
+			return mount, strings.TrimPrefix(path, mount+"/"), resp.Data.Options.Version == "2", nil
+		}
+	}
+	i := strings.IndexByte(path, '/')
+	return path[:i], path[i+1:], true, nil
+}
+
+// writeMaster записывает мастер-сид в секрет path. Существующий секрет не
+// перезаписывается: в KV версии 2 запись идет с cas=0, в версии 1 секрет
+// проверяется перед записью.
+func (c *vaultClient) writeMaster(path string, master []byte) error {
+	mount, key, v2, err := c.kvPath(path)
+	if err != nil {
+		return err
+	}
+	secret := vaultSecret{Master: master, Fingerprint: masterFingerprint(master), Version: version}
+	if v2 {
+		body := map[string]interface{}{"data": secret, "options": map[string]int{"cas": 0}}
+		code, err := c.call(http.MethodPost, mount+"/data/"+key, body, nil)
+		if code == http.StatusBadRequest {
+			return errorf("секрет %s уже существует или запись отклонена: %w", path, err)
+		}
+		return err
+	}
+	code, err := c.call(http.MethodGet, mount+"/"+key, nil, nil)
+	if err == nil {
+		return errorf("секрет %s уже существует", path)
+	}
+	if code != http.StatusNotFound {
+		return err
+	}
+	_, err = c.call(http.MethodPost, mount+"/"+key, secret, nil)
+	return err
+}
+
+// checkFree до ввода сидов проверяет, что секрета path еще нет и запись
+// не завершится ошибкой после церемонии. Если токену чтение запрещено,
+// проверка пропускается: остается защита при записи.
+func (c *vaultClient) checkFree(path string) error {
+	mount, key, v2, err := c.kvPath(path)
+	if err != nil {
+		return err
+	}
+	if v2 {
+		key = "metadata/" + key
+	}
+	code, err := c.call(http.MethodGet, mount+"/"+key, nil, nil)
+	switch {
+	case err == nil:
+		return errorf("секрет %s уже существует", path)
+	case code == http.StatusNotFound || code == http.StatusForbidden:
+		return nil
+	}
+	return err
+}
+
+// readMaster читает мастер-сид из секрета path и сверяет его с записанным
+// рядом отпечатком
+func (c *vaultClient) readMaster(path string) ([]byte, error) {
+	mount, key, v2, err := c.kvPath(path)
+	if err != nil {
+		return nil, err
+	}
+	var secret vaultSecret
+	var code int
+	if v2 {
+		var resp struct {
+			Data struct {
+				Data *vaultSecret `json:"data"`
+			} `json:"data"`
+		}
+		resp.Data.Data = &secret
+		code, err = c.call(http.MethodGet, mount+"/data/"+key, nil, &resp)
+	} else {
+		var resp struct {
+			Data *vaultSecret `json:"data"`
+		}
+		resp.Data = &secret
+		code, err = c.call(http.MethodGet, mount+"/"+key, nil, &resp)
+	}
+	if err != nil {
+		wipe(secret.Master)
+		if code == http.StatusNotFound {
+			return nil, errorf("секрет Vault %s не найден", path)
+		}
+		return nil, err
+	}
+	if len(secret.Master) == 0 {
+		return nil, errorf("секрет Vault %s не содержит поля master", path)
+	}
+	if secret.Fingerprint != "" && secret.Fingerprint != masterFingerprint(secret.Master) {
+		wipe(secret.Master)
+		return nil, mismatchf("секрет Vault %s: отпечаток мастер-сида не совпадает с записанным", path)
+	}
+	return secret.Master, nil
+}
+
+// loadVault читает мастер-сид по ссылке vault:путь
+func loadVault(path string) ([]byte, error) {
+	c, err := newVaultClient()
+	if err != nil {
+		return nil, err
+	}
+	return c.readMaster(path)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// drawCommands - команды, которые verify-draw может повторить: они только
+// читают файлы и выводят результат в stdout
+var drawCommands = []string{"bracket", "redraw", "draw groups", "draw lottery", "schedule roundrobin", "pair swiss", "shuffle", "seed-order", "assign"}
+
+// splitCommandLine разбивает строку команды на аргументы по пробелам;
+// аргумент с пробелами берется в двойные или одинарные кавычки
+func splitCommandLine(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			cur.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errorf("незакрытая кавычка")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	if len(args) > 0 && args[0] == "seedgen" {
+		args = args[1:]
+	}
+	return args, nil
+}
+
+// checkDrawCommand проверяет, что команду из раскрытия можно повторить:
+// это команда жеребьевки, которая только читает файлы и пишет в stdout,
+// и мастер-сид в ней не указан
+func checkDrawCommand(args []string) error {
+	name := ""
+	for _, c := range drawCommands {
+		words := strings.Fields(c)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == c {
+			name = c
+		}
+	}
+	if name == "" {
+		return errorf("повторить можно только команды: %s", strings.Join(drawCommands, ", "))
+	}
+	for _, a := range args {
+		if a == "--master" || a == "-master" || strings.HasPrefix(a, "--master=") || strings.HasPrefix(a, "-master=") {
+			return errorf("мастер-сид берется из раскрытия, --master указывать не нужно")
+		}
+	}
+	return nil
+}
+
+// localArgs заменяет в аргументах пути к раскрытым файлам их именами
+// в каталоге проверки: организатор мог запускать команду из другого каталога
+func localArgs(args []string, files map[string]bool) []string {
+	local := make([]string, len(args))
+	for i, a := range args {
+		prefix, value := "", a
+		if j := strings.IndexByte(a, '='); j >= 0 && strings.HasPrefix(a, "-") {
+			prefix, value = a[:j+1], a[j+1:]
+		}
+		if name := filepath.Base(value); value != name && files[name] {
+			value = name
+		}
+		local[i] = prefix + value
+	}
+	return local
+}
+
+// runVerifyDraw повторяет опубликованную жеребьевку: сверяет раскрытие
+// с обязательством, запускает записанные команды в отдельном каталоге
+// с раскрытым мастер-сидом и сравнивает вывод с результатами побайтно
+func runVerifyDraw(args []string) error {
+	fs := newFlagSet("verify-draw")
+	bundlePath := fs.String("bundle", "draw-reveal.json", "опубликованное раскрытие: вывод seedgen draw reveal с --command")
+	keep := fs.Bool("keep", false, "оставить каталог проверки с файлами и выводом команд")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(*bundlePath)
+	if err != nil {
+		return err
+	}
+	var r drawReveal
+	if err := json.Unmarshal(data, &r); err != nil {
+		return errorf("не удалось разобрать раскрытие %s: %w", *bundlePath, err)
+	}
+	if r.Kind != "draw-reveal" {
+		return errorf("%s не является раскрытием жеребьевки", *bundlePath)
+	}
+	if err := r.verify(); err != nil {
+		return err
+	}
+	fmt.Printf(tr("✓ Обязательство %s сходится с мастер-сидом %s... и исходными данными\n"), r.Commitment.Commitment, r.Commitment.Fingerprint)
+	if r.Version != buildVersion() {
+		fmt.Printf(tr("⚠ Раскрытие записано seedgen %s, проверка идет seedgen %s: при расхождении сравните версии\n"), r.Version, buildVersion())
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "seedgen-verify-draw-")
+	if err != nil {
+		return err
+	}
+	if *keep {
+		fmt.Printf(tr("  Каталог проверки: %s\n"), dir)
+	} else {
+		defer os.RemoveAll(dir)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "master.hex"), []byte(r.Master+"\n"), 0644); err != nil {
+		return err
+	}
+	// Результаты тоже раскладываются: redraw читает опубликованную сетку
+	files := make(map[string]bool)
+	for _, f := range append(append([]drawFile(nil), r.Inputs...), r.Results...) {
+		name := filepath.Base(f.Name)
+		if name != f.Name || name == "." || name == ".." || name == "master.hex" {
+			return errorf("недопустимое имя файла %q в раскрытии", f.Name)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), f.Content, 0644); err != nil {
+			return err
+		}
+		files[name] = true
+	}
+
+	revealLang := r.Lang
+	if revealLang == "" {
+		revealLang = languages[0]
+	}
+	checked, failed := 0, 0
+	for _, res := range r.Results {
+		if len(res.Command) == 0 {
+			fmt.Printf(tr("⚠ %s: команда не записана, повторите вручную\n"), res.Name)
+			continue
+		}
+		if err := checkDrawCommand(res.Command); err != nil {
+			return errorf("%s: %w", res.Name, err)
+		}
+		line := "seedgen " + strings.Join(res.Command, " ")
+		// Текстовый вывод зависит от языка; --lang в самой команде важнее
+		runArgs := append([]string{"--" + langFlag, revealLang}, localArgs(res.Command, files)...)
+		cmd := exec.Command(self, append(runArgs, "--master", "master.hex")...)
+		cmd.Dir = dir
+		logDebug("повтор команды", "dir", dir, "args", strings.Join(runArgs, " "))
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		checked++
+		if err := cmd.Run(); err != nil {
+			failed++
+			fmt.Printf(tr("❌ %s: %s завершилась с ошибкой: %s\n"), res.Name, line, strings.TrimSpace(stderr.String()))
+			continue
+		}
+		out := stdout.Bytes()
+		if *keep {
+			os.WriteFile(filepath.Join(dir, res.Name+".verify"), out, 0644)
+		}
+		if bytes.Equal(out, res.Content) {
+			fmt.Printf(tr("✓ %s: %s дает тот же результат байт в байт (%d байт)\n"), res.Name, line, len(out))
+			continue
+		}
+		// Другая сборка меняет только сведения о ней в поле provenance
+		if bytes.Equal(stripProvenance(out), stripProvenance(res.Content)) {
+			fmt.Printf(tr("✓ %s: %s дает тот же результат; отличаются только сведения о сборке\n"), res.Name, line)
+			continue
+		}
+		failed++
+		at := 0
+		for at < len(out) && at < len(res.Content) && out[at] == res.Content[at] {
+			at++
+		}
+		fmt.Printf(tr("❌ %s: %s дает другой результат: %d байт вместо %d, первое расхождение в байте %d\n"), res.Name, line, len(out), len(res.Content), at)
+	}
+	switch {
+	case checked == 0:
+		return errorf("в раскрытии нет команд для повторения: организатор должен указать --command в draw reveal")
+	case failed > 0:
+		return mismatchf("опубликованные результаты не воспроизводятся: расхождений %d из %d", failed, checked)
+	}
+	fmt.Printf(tr("✓ Воспроизведено результатов: %d, все совпадают с опубликованными\n"), checked)
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// version и commit задаются при сборке:
+// go build -ldflags "-X main.version=1.0.0 -X main.commit=$(git rev-parse HEAD)"
+var (
+	version = "dev"
+	commit  = "unknown"
+)
+
+// kdfInfo описывает поддерживаемый KDF и его параметры по умолчанию
+type kdfInfo struct {
+	Name        string `json:"name"`
+	Iterations  int    `json:"iterations"`
+	Memory      uint32 `json:"memory,omitempty"`
+	Parallelism uint8  `json:"parallelism,omitempty"`
+}
+
+// versionInfo - сведения о сборке для протокола церемонии
+type versionInfo struct {
+	Kind      string    `json:"kind"`
+	Version   string    `json:"version"`
+	Commit    string    `json:"commit"`
+	Go        string    `json:"go"`
+	Platform  string    `json:"platform"`
+	Schemes   []Params  `json:"schemes"`
+	KDFs      []kdfInfo `json:"kdfs"`
+	Encodings []string  `json:"encodings"`
+	LowMemory bool      `json:"low_memory,omitempty"`
+	// CPU и SHA512 заполняются только с --verbose
+	CPU    []cpuFeature `json:"cpu,omitempty"`
+	SHA512 float64      `json:"sha512_mbps,omitempty"`
+}
+
+// buildVersion возвращает версию сборки. Без -ldflags используется версия
+// модуля, если бинарник собран через go install module@version.
+func buildVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		return bi.Main.Version
+	}
+	return version
+}
+
+// currentVersion собирает сведения о бинарнике и его алгоритмах
+func currentVersion() versionInfo {
+	return versionInfo{
+		Kind:     "version",
+		Version:  buildVersion(),
+		Commit:   commit,
+		Go:       runtime.Version(),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Schemes:  []Params{DefaultParams(), V2Params(1), V3Params(1)},
+		KDFs: []kdfInfo{
+			{Name: kdfPBKDF2, Iterations: 100000},
+			{Name: kdfArgon2id, Iterations: argon2DefaultTime, Memory: argon2FlagMemory, Parallelism: argon2DefaultParallelism},
+		},
+		Encodings: seedEncodingNames(),
+		LowMemory: lowMemoryBuild,
+	}
+}
+
+// runVersion выводит версию и идентификаторы алгоритмов
+func runVersion(args []string) error {
+	fs := newFlagSet("version")
+	asJSON := fs.Bool("json", false, "вывести в формате JSON")
+	verbose := fs.Bool("verbose", false, "показать аппаратное ускорение и измеренную скорость SHA-512")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	info := currentVersion()
+	// Общий --verbose перехватывается в main раньше флагов команды
+	if *verbose || logVerbose() {
+		info.CPU = cpuFeatures()
+		info.SHA512 = measureSHA512(200 * time.Millisecond)
+	}
+	if *asJSON {
+		return writeJSON(os.Stdout, info)
+	}
+
+	fmt.Printf(tr("seedgen %s (коммит %s)\n"), info.Version, info.Commit)
+	fmt.Printf("Go: %s, %s\n", info.Go, info.Platform)
+	if info.LowMemory {
+		fmt.Println(tr("Сборка lowmem: Argon2id по умолчанию использует 16 МиБ, полоса хода отключена"))
+	}
+	fmt.Println()
+	fmt.Println(tr("Схемы (параметры по умолчанию):"))
+	for _, p := range info.Schemes {
+		fmt.Printf(tr("  %s: %s, %d итераций, соль %q\n"), p.Scheme, p.KDF, p.Iterations, p.Salt)
+	}
+	fmt.Println("KDF:")
+	for _, k := range info.KDFs {
+		if k.Memory != 0 {
+			fmt.Printf(tr("  %s: проходов %d, память %d КиБ, потоков %d\n"), k.Name, k.Iterations, k.Memory, k.Parallelism)
+		} else {
+			fmt.Printf(tr("  %s: %d итераций\n"), k.Name, k.Iterations)
+		}
+	}
+	fmt.Printf(tr("Форматы сидов: %s\n"), strings.Join(info.Encodings, ", "))
+	if *verbose {
+		fmt.Println()
+		if err := writeCPUFeatures(os.Stdout, info.CPU); err != nil {
+			return err
+		}
+		fmt.Printf(tr("SHA-512: %.1f МБ/с\n"), info.SHA512)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// walletMnemonic возвращает мнемонику BIP39 из 24 слов, однозначно выведенную
+// из мастер-сида. Ее можно импортировать в аппаратный кошелек и сверить
+// адреса, которые выводит seedgen.
+func walletMnemonic(master []byte) (string, error) {
+	mac := hmac.New(sha512.New, []byte("seedgen bip39"))
+	mac.Write(master)
+	return entropyToMnemonic(mac.Sum(nil)[:32])
+}
+
+// mnemonicToSeed вычисляет сид BIP39 по мнемонике и паролю.
+// BIP39 требует нормализации NFKD, поэтому допускаются только ASCII-пароли.
+func mnemonicToSeed(mnemonic, passphrase string) ([]byte, error) {
+	for _, r := range passphrase {
+		if r > 0x7f {
+			return nil, errorf("пароль BIP39 должен состоять из символов ASCII")
+		}
+	}
+	seed := pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"+passphrase), 2048, 64, sha512.New)
+	lockSecret(seed)
+	return seed, nil
+}
+
+// walletSeed возвращает сид BIP39 кошелька, выведенного из мастер-сида
+func walletSeed(master []byte) ([]byte, error) {
+	mnemonic, err := walletMnemonic(master)
+	if err != nil {
+		return nil, err
+	}
+	return mnemonicToSeed(mnemonic, "")
+}
+
+// runDeriveBIP39 выводит мнемонику кошелька для импорта в аппаратный кошелек
+func runDeriveBIP39(args []string) error {
+	fs := newFlagSet("derive bip39")
+	masterRef := addMasterFlag(fs)
+	quiz := fs.Bool("quiz", false, "после вывода мнемоники спросить 3 случайных слова, чтобы проверить запись")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	master, err := readMaster(*masterRef)
+	if err != nil {
+		return err
+	}
+	defer wipe(master)
+	mnemonic, err := walletMnemonic(master)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, tr("Мнемоника кошелька (24 слова). Она дает доступ ко всем ключам кошелька,"))
+	fmt.Fprintln(os.Stderr, tr("вводите ее только в аппаратный кошелек для сверки адресов."))
+	fmt.Println(mnemonic)
+	if *quiz {
+		return quizMnemonic(mnemonic)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// shredFile перезаписывает файл случайными данными и нулями, сбрасывает
+// запись на диск, переименовывает и удаляет его. На SSD и файловых системах
+// с копированием при записи (btrfs, APFS, ZFS) старые блоки могут уцелеть.
+func shredFile(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return errorf("%s: не является обычным файлом", path)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	for _, src := range []io.Reader{rand.Reader, zeroReader{}} {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			f.Close()
+			return err
+		}
+		if _, err := io.CopyN(f, src, size); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Случайное имя, чтобы не оставлять в каталоге след исходного имени
+	name := make([]byte, 8)
+	if _, err := rand.Read(name); err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(path), "."+hex.EncodeToString(name))
+	if err := os.Rename(path, tmp); err != nil {
+		return err
+	}
+	return os.Remove(tmp)
+}
+
+// zeroReader - бесконечный источник нулевых байт
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// wipeTargets возвращает файлы сеанса и временные файлы seedgen
+func wipeTargets() ([]string, error) {
+	var targets []string
+	dir, err := sessionDir()
+	if err != nil {
+		return nil, err
+	}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			targets = append(targets, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	temp, err := filepath.Glob(filepath.Join(os.TempDir(), tempFilePattern))
+	if err != nil {
+		return nil, err
+	}
+	return append(targets, temp...), nil
+}
+
+// runWipe удаляет следы сеанса: служебные файлы, буфер обмена и указанные файлы
+func runWipe(args []string) error {
+	fs := newFlagSet("wipe")
+	dryRun := fs.Bool("dry-run", false, "только показать, что будет удалено")
+	keepClipboard := fs.Bool("keep-clipboard", false, "не очищать буфер обмена")
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(tr("=== Очистка после церемонии ==="))
+	fmt.Println()
+
+	failed := 0
+	// Буфер проверяется до удаления файлов сеанса, где хранится его отметка
+	if !*keepClipboard && !*dryRun {
+		cleared, err := clearOwnClipboard()
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf(tr("❌ Буфер обмена: %v\n"), err)
+		case cleared:
+			fmt.Println(tr("✓ Буфер обмена очищен"))
+		default:
+			fmt.Println(tr("✓ Буфер обмена не содержит данных seedgen"))
+		}
+	}
+
+	targets, err := wipeTargets()
+	if err != nil {
+		return err
+	}
+	targets = append(targets, files...)
+	if len(targets) == 0 {
+		fmt.Println(tr("✓ Файлов сеанса и временных файлов нет"))
+	}
+	for _, path := range targets {
+		if *dryRun {
+			fmt.Printf(tr("  будет удален: %s\n"), path)
+			continue
+		}
+		if err := shredFile(path); err != nil {
+			failed++
+			fmt.Printf("❌ %v\n", err)
+			continue
+		}
+		fmt.Printf(tr("✓ Удален: %s\n"), path)
+	}
+
+	if len(files) > 0 && !*dryRun {
+		fmt.Println()
+		fmt.Println(tr("Примечание: на SSD и файловых системах с копированием при записи"))
+		fmt.Println(tr("перезапись не гарантирует уничтожения данных - используйте шифрование диска."))
+	}
+	if failed > 0 {
+		return errorf("не удалось очистить %d объектов", failed)
+	}
+	return nil
+}